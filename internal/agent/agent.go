@@ -13,12 +13,27 @@ import (
 	"google.golang.org/api/option"
 )
 
-// Agent wraps the Gemini client and model used for KYC discovery.
+// Agent runs the onboarding prompts against an LLM backend (Gemini or an
+// OpenAI-compatible endpoint).
 type Agent struct {
+	llm completer
+}
+
+// completer sends a system and user prompt to an LLM and returns its text reply
+type completer interface {
+	complete(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+	close() error
+}
+
+// geminiCompleter calls Google Gemini through the genai SDK
+type geminiCompleter struct {
 	client *genai.Client
 	model  *genai.GenerativeModel
 }
 
+// DefaultGeminiModel is used when no model is configured
+const DefaultGeminiModel = "gemini-2.5-flash-preview-09-2025"
+
 // KYCResponse is the structured JSON we expect from the LLM.
 type KYCResponse struct {
 	RequiredDocuments []string `json:"required_documents"`
@@ -29,16 +44,24 @@ type KYCResponse struct {
 // the caller receives a nil Agent and no error so that commands can
 // decide how to handle missing configuration.
 func NewAgent(ctx context.Context, apiKey string) (*Agent, error) {
+	return NewGeminiAgent(ctx, apiKey, DefaultGeminiModel)
+}
+
+// NewGeminiAgent is NewAgent with an explicit Gemini model name.
+func NewGeminiAgent(ctx context.Context, apiKey, modelName string) (*Agent, error) {
 	if apiKey == "" {
 		return nil, nil
 	}
+	if modelName == "" {
+		modelName = DefaultGeminiModel
+	}
 
 	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
-	model := client.GenerativeModel("gemini-2.5-flash-preview-09-2025")
+	model := client.GenerativeModel(modelName)
 	model.SafetySettings = []*genai.SafetySetting{
 		{
 			Category:  genai.HarmCategoryHarassment,
@@ -58,28 +81,53 @@ func NewAgent(ctx context.Context, apiKey string) (*Agent, error) {
 		},
 	}
 
-	return &Agent{
-		client: client,
-		model:  model,
-	}, nil
+	return &Agent{llm: &geminiCompleter{client: client, model: model}}, nil
+}
+
+func (g *geminiCompleter) complete(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	g.model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemPrompt)}}
+
+	resp, err := g.model.GenerateContent(ctx, genai.Text(userPrompt))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("no response from agent: %v", resp)
+	}
+
+	part := resp.Candidates[0].Content.Parts[0]
+	textPart, ok := part.(genai.Text)
+	if !ok {
+		return "", fmt.Errorf("unexpected response type from agent: %T", part)
+	}
+	return string(textPart), nil
+}
+
+func (g *geminiCompleter) close() error {
+	return g.client.Close()
 }
 
 // Close releases underlying resources.
 func (a *Agent) Close() {
-	if a == nil || a.client == nil {
+	if a == nil || a.llm == nil {
 		return
 	}
-	if err := a.client.Close(); err != nil {
-		log.Printf("warning: failed to close Gemini client: %v", err)
+	if err := a.llm.close(); err != nil {
+		log.Printf("warning: failed to close LLM client: %v", err)
 	}
 }
 
-// CallKYCAgent is the core "agentic" function.
-func (a *Agent) CallKYCAgent(ctx context.Context, naturePurpose string, products []string) (*dsl.KYCRequirements, error) {
-	if a == nil || a.model == nil {
-		return nil, fmt.Errorf("ai agent is not initialized")
+// complete guards against an unconfigured agent before calling the backend
+func (a *Agent) complete(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	if a == nil || a.llm == nil {
+		return "", fmt.Errorf("ai agent is not initialized")
 	}
+	return a.llm.complete(ctx, systemPrompt, userPrompt)
+}
 
+// CallKYCAgent is the core "agentic" function.
+func (a *Agent) CallKYCAgent(ctx context.Context, naturePurpose string, products []string) (*dsl.KYCRequirements, error) {
 	systemPrompt := `You are an expert KYC/AML Compliance Officer for a major global bank.
 Your job is to analyze a new client's "nature and purpose" and their "requested products" to determine the *minimum* required KYC documents and all relevant jurisdictions.
 
@@ -116,27 +164,15 @@ EXAMPLES:
 		)
 	}
 
-	a.model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemPrompt)}}
-
-	resp, err := a.model.GenerateContent(ctx, genai.Text(userPrompt))
+	textPart, err := a.complete(ctx, systemPrompt, userPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from agent: %v", resp)
-	}
-
-	part := resp.Candidates[0].Content.Parts[0]
-	textPart, ok := part.(genai.Text)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type from agent: %T", part)
+		return nil, err
 	}
 
 	log.Printf("AI Agent Raw Response: %s", textPart)
 
 	var kycResp KYCResponse
-	if uErr := json.Unmarshal([]byte(textPart), &kycResp); uErr != nil {
+	if uErr := json.Unmarshal([]byte(cleanJSONResponse(textPart)), &kycResp); uErr != nil {
 		return nil, fmt.Errorf("failed to parse agent's JSON response: %w (response was: %s)", uErr, textPart)
 	}
 
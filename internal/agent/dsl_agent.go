@@ -20,8 +20,6 @@ import (
 	"log"
 	"regexp"
 	"strings"
-
-	"github.com/google/generative-ai-go/genai"
 )
 
 // DSLTransformationRequest represents a request to transform DSL
@@ -42,10 +40,6 @@ type DSLTransformationResponse struct {
 
 // CallDSLTransformationAgent handles general DSL transformations using AI
 func (a *Agent) CallDSLTransformationAgent(ctx context.Context, request DSLTransformationRequest) (*DSLTransformationResponse, error) {
-	if a == nil || a.model == nil {
-		return nil, fmt.Errorf("ai agent is not initialized")
-	}
-
 	systemPrompt := `You are an expert DSL (Domain Specific Language) architect for financial onboarding workflows.
 Your role is to analyze existing DSL and transform it according to user instructions while maintaining correctness and consistency.
 
@@ -111,27 +105,15 @@ Please transform the DSL according to the instruction while moving toward the ta
 		request.TargetState,
 		jsonString(request.Context))
 
-	a.model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemPrompt)}}
-
-	resp, err := a.model.GenerateContent(ctx, genai.Text(userPrompt))
+	textPart, err := a.complete(ctx, systemPrompt, userPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from agent: %v", resp)
-	}
-
-	part := resp.Candidates[0].Content.Parts[0]
-	textPart, ok := part.(genai.Text)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type from agent: %T", part)
+		return nil, err
 	}
 
 	log.Printf("DSL Agent Raw Response: %s", textPart)
 
 	// Clean potential markdown-wrapped JSON using jsonv2's robust parsing
-	cleanedJSON := cleanJSONResponse(string(textPart))
+	cleanedJSON := cleanJSONResponse(textPart)
 
 	var transformResp DSLTransformationResponse
 	if uErr := json.Unmarshal([]byte(cleanedJSON), &transformResp); uErr != nil {
@@ -148,10 +130,6 @@ Please transform the DSL according to the instruction while moving toward the ta
 
 // CallDSLValidationAgent validates DSL correctness and suggests improvements
 func (a *Agent) CallDSLValidationAgent(ctx context.Context, dslToValidate string) (*DSLValidationResponse, error) {
-	if a == nil || a.model == nil {
-		return nil, fmt.Errorf("ai agent is not initialized")
-	}
-
 	systemPrompt := `You are an expert DSL validator for financial onboarding workflows.
 Your role is to analyze DSL for correctness, completeness, and best practices.
 
@@ -178,27 +156,15 @@ RESPONSE FORMAT:
 
 Provide a comprehensive validation assessment including errors, warnings, and suggestions for improvement.`, dslToValidate)
 
-	a.model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(systemPrompt)}}
-
-	resp, err := a.model.GenerateContent(ctx, genai.Text(userPrompt))
+	textPart, err := a.complete(ctx, systemPrompt, userPrompt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 || resp.Candidates[0] == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from agent: %v", resp)
-	}
-
-	part := resp.Candidates[0].Content.Parts[0]
-	textPart, ok := part.(genai.Text)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type from agent: %T", part)
+		return nil, err
 	}
 
 	log.Printf("DSL Validation Agent Raw Response: %s", textPart)
 
 	// Clean potential markdown-wrapped JSON using jsonv2's robust parsing
-	cleanedJSON := cleanJSONResponse(string(textPart))
+	cleanedJSON := cleanJSONResponse(textPart)
 
 	var validationResp DSLValidationResponse
 	if uErr := json.Unmarshal([]byte(cleanedJSON), &validationResp); uErr != nil {
@@ -321,3 +321,6 @@ func uniqueStrings(input []string) []string {
 
 	return result
 }
+
+// Close is a no-op; MockAgent holds no resources
+func (m *MockAgent) Close() {}
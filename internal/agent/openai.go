@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is the OpenAI API root; any compatible server (vLLM,
+// Ollama, LiteLLM, Azure proxies) can be targeted by overriding it.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// DefaultOpenAIModel is used when no model is configured
+const DefaultOpenAIModel = "gpt-4o-mini"
+
+// openAICompleter calls an OpenAI-compatible /chat/completions endpoint
+type openAICompleter struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAIAgent creates an agent backed by an OpenAI-compatible chat
+// completions API. An empty baseURL or model falls back to the OpenAI defaults.
+// The API key may be empty for local servers that do not require one.
+func NewOpenAIAgent(baseURL, apiKey, model string) *Agent {
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	return &Agent{llm: &openAICompleter{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}}
+}
+
+func (o *openAICompleter) complete(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	payload, err := json.Marshal(chatRequest{
+		Model: o.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to build chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call chat completions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read chat response: %w", err)
+	}
+
+	var chat chatResponse
+	if uErr := json.Unmarshal(body, &chat); uErr != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("failed to parse chat response: %w", uErr)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if chat.Error != nil && chat.Error.Message != "" {
+			return "", fmt.Errorf("chat completions returned %d: %s", resp.StatusCode, chat.Error.Message)
+		}
+		return "", fmt.Errorf("chat completions returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if len(chat.Choices) == 0 {
+		return "", fmt.Errorf("no response from agent: %s", strings.TrimSpace(string(body)))
+	}
+	return chat.Choices[0].Message.Content, nil
+}
+
+func (o *openAICompleter) close() error {
+	o.client.CloseIdleConnections()
+	return nil
+}
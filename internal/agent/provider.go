@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dsl-ob-poc/internal/dsl"
+)
+
+// Provider is the LLM-backed agent used by the CLI commands. Agent (Gemini or
+// an OpenAI-compatible endpoint) and MockAgent both implement it.
+type Provider interface {
+	CallKYCAgent(ctx context.Context, naturePurpose string, products []string) (*dsl.KYCRequirements, error)
+	CallDSLTransformationAgent(ctx context.Context, request DSLTransformationRequest) (*DSLTransformationResponse, error)
+	CallDSLValidationAgent(ctx context.Context, dslToValidate string) (*DSLValidationResponse, error)
+	Close()
+}
+
+var (
+	_ Provider = (*Agent)(nil)
+	_ Provider = (*MockAgent)(nil)
+)
+
+// ProviderType selects the LLM backend
+type ProviderType string
+
+const (
+	// GeminiProvider uses Google Gemini through the genai SDK
+	GeminiProvider ProviderType = "gemini"
+	// OpenAIProvider uses any OpenAI-compatible chat completions API
+	OpenAIProvider ProviderType = "openai"
+	// MockProvider returns canned responses without network access
+	MockProvider ProviderType = "mock"
+)
+
+// ProviderConfig holds configuration for provider creation
+type ProviderConfig struct {
+	Type    ProviderType
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// NewProvider creates the provider selected by config. Like NewAgent, a
+// Gemini or OpenAI provider without an API key yields a nil Provider and no
+// error so that commands can decide how to handle missing configuration;
+// OpenAI-compatible servers with a custom BaseURL may run without a key.
+func NewProvider(ctx context.Context, config ProviderConfig) (Provider, error) {
+	switch ProviderType(strings.ToLower(string(config.Type))) {
+	case GeminiProvider, "":
+		a, err := NewGeminiAgent(ctx, config.APIKey, config.Model)
+		if err != nil || a == nil {
+			return nil, err
+		}
+		return a, nil
+	case OpenAIProvider:
+		if config.APIKey == "" && config.BaseURL == "" {
+			return nil, nil
+		}
+		return NewOpenAIAgent(config.BaseURL, config.APIKey, config.Model), nil
+	case MockProvider:
+		return NewMockAgent(), nil
+	default:
+		return nil, &UnsupportedProviderError{Type: string(config.Type)}
+	}
+}
+
+// UnsupportedProviderError is returned when an unknown provider is requested
+type UnsupportedProviderError struct {
+	Type string
+}
+
+func (e *UnsupportedProviderError) Error() string {
+	return fmt.Sprintf("unsupported LLM provider: %s", e.Type)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		config  ProviderConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{"Gemini Without Key", ProviderConfig{Type: GeminiProvider}, true, false},
+		{"Default Without Key", ProviderConfig{}, true, false},
+		{"OpenAI Without Key", ProviderConfig{Type: OpenAIProvider}, true, false},
+		{"OpenAI Local Server", ProviderConfig{Type: OpenAIProvider, BaseURL: "http://localhost:11434/v1"}, false, false},
+		{"Mock", ProviderConfig{Type: MockProvider}, false, false},
+		{"Unknown", ProviderConfig{Type: "llama-local"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProvider(ctx, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (p == nil) != tt.wantNil {
+				t.Errorf("NewProvider() = %v, wantNil %v", p, tt.wantNil)
+			}
+		})
+	}
+
+	var unsupported *UnsupportedProviderError
+	if _, err := NewProvider(ctx, ProviderConfig{Type: "x"}); !errors.As(err, &unsupported) {
+		t.Errorf("expected UnsupportedProviderError, got %v", err)
+	}
+}
+
+func newChatServer(t *testing.T, reply string, status int) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Model != "test-model" || len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+			t.Errorf("unexpected request %+v", req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"error":{"message":"` + reply + `"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestOpenAIAgentKYC(t *testing.T) {
+	ts := newChatServer(t, "```json\n{\"required_documents\":[\"W9\"],\"jurisdictions\":[\"US\"]}\n```", http.StatusOK)
+
+	a := NewOpenAIAgent(ts.URL+"/v1/", "test-key", "test-model")
+	defer a.Close()
+
+	reqs, err := a.CallKYCAgent(context.Background(), "US-based hedge fund", []string{"CUSTODY"})
+	if err != nil {
+		t.Fatalf("CallKYCAgent failed: %v", err)
+	}
+	if len(reqs.Documents) != 1 || reqs.Documents[0] != "W9" || reqs.Jurisdictions[0] != "US" {
+		t.Errorf("unexpected requirements %+v", reqs)
+	}
+}
+
+func TestOpenAIAgentValidation(t *testing.T) {
+	ts := newChatServer(t, `{"is_valid":true,"validation_score":0.9,"errors":[],"warnings":[],"suggestions":[],"summary":"ok"}`, http.StatusOK)
+
+	a := NewOpenAIAgent(ts.URL+"/v1", "test-key", "test-model")
+	resp, err := a.CallDSLValidationAgent(context.Background(), `(case.create (cbu.id "CBU-1"))`)
+	if err != nil {
+		t.Fatalf("CallDSLValidationAgent failed: %v", err)
+	}
+	if !resp.IsValid || resp.Summary != "ok" {
+		t.Errorf("unexpected validation response %+v", resp)
+	}
+}
+
+func TestOpenAIAgentHTTPError(t *testing.T) {
+	ts := newChatServer(t, "rate limited", http.StatusTooManyRequests)
+
+	a := NewOpenAIAgent(ts.URL+"/v1", "test-key", "test-model")
+	_, err := a.CallKYCAgent(context.Background(), "fund", nil)
+	if err == nil {
+		t.Fatal("expected error for non-200 response")
+	}
+	if got := err.Error(); got != "chat completions returned 429: rate limited" {
+		t.Errorf("unexpected error %q", got)
+	}
+}
+
+func TestNilAgent(t *testing.T) {
+	var a *Agent
+	if _, err := a.CallKYCAgent(context.Background(), "fund", nil); err == nil {
+		t.Error("expected error from nil agent")
+	}
+	a.Close()
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/dsl"
+	"dsl-ob-poc/internal/orchestration"
+	"dsl-ob-poc/internal/runtime"
+)
+
+func (s *Server) handleHealth(r *http.Request) (int, any, error) {
+	return http.StatusOK, map[string]any{
+		"status":       "ok",
+		"orchestrator": s.orchestrator != nil,
+		"runtime":      s.engine != nil,
+	}, nil
+}
+
+// ---------- CBUs ----------
+
+// CBURequest is the body of POST and PUT /cbus
+type CBURequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	NaturePurpose string `json:"nature_purpose"`
+}
+
+func (s *Server) handleListCBUs(r *http.Request) (int, any, error) {
+	cbus, err := s.ds.ListCBUs(r.Context())
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"cbus": cbus, "count": len(cbus)}, nil
+}
+
+func (s *Server) handleCreateCBU(r *http.Request) (int, any, error) {
+	var req CBURequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return 0, nil, badRequest("name is required")
+	}
+
+	cbuID, err := s.ds.CreateCBU(r.Context(), req.Name, req.Description, req.NaturePurpose)
+	if err != nil {
+		return 0, nil, err
+	}
+	cbu, err := s.ds.GetCBUByID(r.Context(), cbuID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, cbu, nil
+}
+
+func (s *Server) handleGetCBU(r *http.Request) (int, any, error) {
+	cbu, err := s.ds.GetCBUByID(r.Context(), r.PathValue("cbuID"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, cbu, nil
+}
+
+func (s *Server) handleUpdateCBU(r *http.Request) (int, any, error) {
+	var req CBURequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+
+	cbuID := r.PathValue("cbuID")
+	if err := s.ds.UpdateCBU(r.Context(), cbuID, req.Name, req.Description, req.NaturePurpose); err != nil {
+		return 0, nil, err
+	}
+	cbu, err := s.ds.GetCBUByID(r.Context(), cbuID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, cbu, nil
+}
+
+func (s *Server) handleDeleteCBU(r *http.Request) (int, any, error) {
+	if err := s.ds.DeleteCBU(r.Context(), r.PathValue("cbuID")); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// ---------- DSL ----------
+
+// ValidateDSLRequest is the body of POST /dsl/validate
+type ValidateDSLRequest struct {
+	DSL string `json:"dsl"`
+}
+
+func (s *Server) handleLatestDSL(r *http.Request) (int, any, error) {
+	latest, err := s.ds.GetLatestDSLWithState(r.Context(), r.PathValue("cbuID"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, latest, nil
+}
+
+func (s *Server) handleDSLHistory(r *http.Request) (int, any, error) {
+	history, err := s.ds.GetDSLHistoryWithState(r.Context(), r.PathValue("cbuID"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{"versions": history, "count": len(history)}, nil
+}
+
+func (s *Server) handleDSLVersion(r *http.Request) (int, any, error) {
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		return 0, nil, badRequest("version must be a positive integer, got %q", r.PathValue("version"))
+	}
+	v, err := s.ds.GetDSLByVersion(r.Context(), r.PathValue("cbuID"), version)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, v, nil
+}
+
+func (s *Server) handleValidateDSL(r *http.Request) (int, any, error) {
+	var req ValidateDSLRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+	if strings.TrimSpace(req.DSL) == "" {
+		return 0, nil, badRequest("dsl is required")
+	}
+
+	vocab, err := dsl.NewVocabularyLoader(s.ds).LoadVocabulary(r.Context())
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Validation failures are a normal outcome, not a request error
+	body := map[string]any{"valid": true, "attribute_ids": dsl.ExtractAttributeIDs(req.DSL)}
+	if err := dsl.NewValidator(s.ds, vocab).Validate(r.Context(), req.DSL); err != nil {
+		body["valid"] = false
+		body["error"] = err.Error()
+	}
+	return http.StatusOK, body, nil
+}
+
+// ---------- Attributes ----------
+
+func (s *Server) handleGetAttribute(r *http.Request) (int, any, error) {
+	attr, err := s.ds.GetDictionaryAttributeByID(r.Context(), r.PathValue("attributeID"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, attr, nil
+}
+
+func (s *Server) handleResolveAttribute(r *http.Request) (int, any, error) {
+	cbuID, attributeID := r.PathValue("cbuID"), r.PathValue("attributeID")
+	value, provenance, state, err := s.ds.ResolveValueFor(r.Context(), cbuID, attributeID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]any{
+		"cbu_id":       cbuID,
+		"attribute_id": attributeID,
+		"value":        value,
+		"state":        state,
+		"provenance":   provenance,
+	}, nil
+}
+
+// ---------- Orchestration ----------
+
+// ExecuteInstructionRequest is the body of POST /orchestration/sessions/{id}/execute
+type ExecuteInstructionRequest struct {
+	Instruction string `json:"instruction"`
+}
+
+func (s *Server) requireOrchestrator() error {
+	if s.orchestrator == nil {
+		return unavailable("orchestration is not configured on this server")
+	}
+	return nil
+}
+
+func (s *Server) handleListSessions(r *http.Request) (int, any, error) {
+	if err := s.requireOrchestrator(); err != nil {
+		return 0, nil, err
+	}
+	ids := s.orchestrator.ListActiveSessions()
+	return http.StatusOK, map[string]any{"active_sessions": ids, "count": len(ids)}, nil
+}
+
+func (s *Server) handleCreateSession(r *http.Request) (int, any, error) {
+	if err := s.requireOrchestrator(); err != nil {
+		return 0, nil, err
+	}
+	var req orchestration.OrchestrationRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+	if req.CBUID == "" && req.EntityName == "" {
+		return 0, nil, badRequest("either cbu_id or entity_name is required")
+	}
+	if req.WorkflowType == "" {
+		req.WorkflowType = "ONBOARDING"
+	}
+	if req.InitialContext == nil {
+		req.InitialContext = make(map[string]interface{})
+	}
+
+	session, err := s.orchestrator.CreateOrchestrationSession(r.Context(), &req)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, session, nil
+}
+
+func (s *Server) handleSessionStatus(r *http.Request) (int, any, error) {
+	if err := s.requireOrchestrator(); err != nil {
+		return 0, nil, err
+	}
+	status, err := s.orchestrator.GetSessionStatus(r.PathValue("sessionID"))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, status, nil
+}
+
+func (s *Server) handleExecuteInstruction(r *http.Request) (int, any, error) {
+	if err := s.requireOrchestrator(); err != nil {
+		return 0, nil, err
+	}
+	var req ExecuteInstructionRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+	if strings.TrimSpace(req.Instruction) == "" {
+		return 0, nil, badRequest("instruction is required")
+	}
+
+	result, err := s.orchestrator.ExecuteInstruction(r.Context(), r.PathValue("sessionID"), req.Instruction)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, result, nil
+}
+
+// ---------- Runtime actions ----------
+
+// ExecuteActionRequest is the body of POST /actions/{id}/execute
+type ExecuteActionRequest struct {
+	CBUID           string         `json:"cbu_id"`
+	DSLVersionID    string         `json:"dsl_version_id,omitempty"`
+	Environment     string         `json:"environment,omitempty"`
+	AttributeValues map[string]any `json:"attribute_values,omitempty"`
+}
+
+func (s *Server) handleExecuteAction(r *http.Request) (int, any, error) {
+	if s.engine == nil {
+		return 0, nil, unavailable("runtime execution requires a PostgreSQL data store")
+	}
+	var req ExecuteActionRequest
+	if err := decodeBody(r, &req); err != nil {
+		return 0, nil, err
+	}
+	if req.CBUID == "" {
+		return 0, nil, badRequest("cbu_id is required")
+	}
+	if req.Environment == "" {
+		req.Environment = "development"
+	}
+	if req.DSLVersionID == "" {
+		latest, err := s.ds.GetLatestDSLWithState(r.Context(), req.CBUID)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.DSLVersionID = latest.VersionID
+	}
+
+	result, err := s.engine.ExecuteAction(r.Context(), &runtime.ExecutionRequest{
+		ActionID:        r.PathValue("actionID"),
+		CBUID:           req.CBUID,
+		DSLVersionID:    req.DSLVersionID,
+		Environment:     req.Environment,
+		AttributeValues: req.AttributeValues,
+		TriggerContext: map[string]interface{}{
+			"triggered_by": "api",
+			"remote_addr":  r.RemoteAddr,
+			"timestamp":    time.Now().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	status := http.StatusOK
+	if !result.Success {
+		status = http.StatusBadGateway
+	}
+	return status, result, nil
+}
@@ -0,0 +1,257 @@
+// Package api exposes onboarding, orchestration and runtime operations as a
+// versioned JSON REST API over datastore.DataStore.
+//
+// All routes live under /api/v1. Successful responses are JSON documents;
+// failures use the ErrorResponse body with a matching HTTP status. Internal
+// errors are logged and answered with a generic message.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/orchestration"
+	"dsl-ob-poc/internal/runtime"
+	"dsl-ob-poc/internal/store"
+)
+
+// APIPrefix is the path prefix of every versioned route
+const APIPrefix = "/api/v1"
+
+// maxBodyBytes limits request bodies to keep DSL uploads bounded
+const maxBodyBytes = 4 << 20
+
+// Config wires the server to its backends. Orchestrator and Engine are
+// optional; their routes answer 503 when they are nil.
+type Config struct {
+	DataStore    datastore.DataStore
+	Orchestrator *orchestration.Orchestrator
+	Engine       *runtime.ExecutionEngine
+	Logger       *log.Logger
+}
+
+// Server serves the REST API
+type Server struct {
+	ds           datastore.DataStore
+	orchestrator *orchestration.Orchestrator
+	engine       *runtime.ExecutionEngine
+	logger       *log.Logger
+	mux          *http.ServeMux
+}
+
+// ErrorResponse is the body of every non-2xx response
+type ErrorResponse struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// httpError carries an HTTP status alongside an error message
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+func (e *httpError) Unwrap() error { return e.err }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func unavailable(format string, args ...any) error {
+	return &httpError{status: http.StatusServiceUnavailable, err: fmt.Errorf(format, args...)}
+}
+
+// NewServer creates a server and registers all routes
+func NewServer(cfg Config) (*Server, error) {
+	if cfg.DataStore == nil {
+		return nil, errors.New("api: data store is required")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+
+	s := &Server{
+		ds:           cfg.DataStore,
+		orchestrator: cfg.Orchestrator,
+		engine:       cfg.Engine,
+		logger:       cfg.Logger,
+		mux:          http.NewServeMux(),
+	}
+	s.routes()
+	return s, nil
+}
+
+// Handler returns the server's root handler with logging and panic recovery
+func (s *Server) Handler() http.Handler {
+	return s.withLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := s.mux.Handler(r); pattern == "" {
+			// Unmatched: the mux answers 404 or 405 in plain text
+			w = &routeErrorWriter{ResponseWriter: w, route: r.Method + " " + r.URL.Path}
+		}
+		s.mux.ServeHTTP(w, r)
+	}))
+}
+
+// ListenAndServe serves on addr until ctx is cancelled, then shuts down gracefully
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (s *Server) routes() {
+	s.handle("GET /health", s.handleHealth)
+
+	// CBU CRUD
+	s.handle("GET /cbus", s.handleListCBUs)
+	s.handle("POST /cbus", s.handleCreateCBU)
+	s.handle("GET /cbus/{cbuID}", s.handleGetCBU)
+	s.handle("PUT /cbus/{cbuID}", s.handleUpdateCBU)
+	s.handle("DELETE /cbus/{cbuID}", s.handleDeleteCBU)
+
+	// DSL history and versions
+	s.handle("GET /cbus/{cbuID}/dsl", s.handleLatestDSL)
+	s.handle("GET /cbus/{cbuID}/dsl/versions", s.handleDSLHistory)
+	s.handle("GET /cbus/{cbuID}/dsl/versions/{version}", s.handleDSLVersion)
+	s.handle("POST /dsl/validate", s.handleValidateDSL)
+
+	// Attribute resolution
+	s.handle("GET /attributes/{attributeID}", s.handleGetAttribute)
+	s.handle("GET /cbus/{cbuID}/attributes/{attributeID}", s.handleResolveAttribute)
+
+	// Orchestration
+	s.handle("GET /orchestration/sessions", s.handleListSessions)
+	s.handle("POST /orchestration/sessions", s.handleCreateSession)
+	s.handle("GET /orchestration/sessions/{sessionID}", s.handleSessionStatus)
+	s.handle("POST /orchestration/sessions/{sessionID}/execute", s.handleExecuteInstruction)
+
+	// Runtime actions
+	s.handle("POST /actions/{actionID}/execute", s.handleExecuteAction)
+}
+
+// handlerFunc is an API handler: it returns the status and body to encode, or an error
+type handlerFunc func(r *http.Request) (int, any, error)
+
+func (s *Server) handle(pattern string, h handlerFunc) {
+	method, path, _ := strings.Cut(pattern, " ")
+	s.mux.HandleFunc(method+" "+APIPrefix+path, func(w http.ResponseWriter, r *http.Request) {
+		status, body, err := h(r)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+func (s *Server) withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if p := recover(); p != nil {
+				s.logger.Printf("panic serving %s %s: %v", r.Method, r.URL.Path, p)
+				s.writeError(rec, r, fmt.Errorf("panic: %v", p))
+			}
+			s.logger.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond))
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// routeErrorWriter replaces the mux's plain-text routing errors with an ErrorResponse
+type routeErrorWriter struct {
+	http.ResponseWriter
+	route string
+}
+
+func (w *routeErrorWriter) WriteHeader(status int) {
+	w.Header().Del("X-Content-Type-Options")
+	writeJSON(w.ResponseWriter, status, ErrorResponse{
+		Error:  fmt.Sprintf("%s: %s", strings.ToLower(http.StatusText(status)), w.route),
+		Status: status,
+	})
+}
+
+func (w *routeErrorWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body == nil || status == http.StatusNoContent {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers with the error's status. Client errors carry their
+// message; internal errors are logged and answered with a generic one so
+// database and driver details do not leak.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusForError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		s.logger.Printf("error serving %s %s: %v", r.Method, r.URL.Path, err)
+		message = "internal server error"
+	}
+	writeJSON(w, status, ErrorResponse{Error: message, Status: status})
+}
+
+// statusForError maps handler errors onto HTTP statuses
+func statusForError(err error) int {
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		return he.status
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, orchestration.ErrSessionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
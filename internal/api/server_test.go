@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/store"
+)
+
+// fakeStore implements the CBU and DSL parts of datastore.DataStore; other
+// methods panic through the nil embedded interface.
+type fakeStore struct {
+	datastore.DataStore
+	cbus     map[string]*store.CBU
+	versions map[string][]store.DSLVersionWithState
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		cbus: map[string]*store.CBU{
+			"CBU-1": {CBUID: "CBU-1", Name: "Alpha Fund", Description: "Hedge fund"},
+		},
+		versions: map[string][]store.DSLVersionWithState{
+			"CBU-1": {
+				{VersionID: "v1", CBUID: "CBU-1", DSLText: "(case.create (cbu.id \"CBU-1\"))", VersionNumber: 1},
+				{VersionID: "v2", CBUID: "CBU-1", DSLText: "(case.create (cbu.id \"CBU-1\"))\n(products.add \"CUSTODY\")", VersionNumber: 2},
+			},
+		},
+	}
+}
+
+func (f *fakeStore) ListCBUs(_ context.Context) ([]store.CBU, error) {
+	var out []store.CBU
+	for _, c := range f.cbus {
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) GetCBUByID(_ context.Context, id string) (*store.CBU, error) {
+	if c, ok := f.cbus[id]; ok {
+		return c, nil
+	}
+	return nil, store.NotFoundf("CBU not found: %s", id)
+}
+
+func (f *fakeStore) CreateCBU(_ context.Context, name, description, naturePurpose string) (string, error) {
+	id := fmt.Sprintf("CBU-%d", len(f.cbus)+1)
+	f.cbus[id] = &store.CBU{CBUID: id, Name: name, Description: description, NaturePurpose: naturePurpose}
+	return id, nil
+}
+
+func (f *fakeStore) DeleteCBU(_ context.Context, id string) error {
+	if _, ok := f.cbus[id]; !ok {
+		return store.NotFoundf("CBU not found: %s", id)
+	}
+	delete(f.cbus, id)
+	return nil
+}
+
+func (f *fakeStore) GetDSLHistoryWithState(_ context.Context, cbuID string) ([]store.DSLVersionWithState, error) {
+	return f.versions[cbuID], nil
+}
+
+func (f *fakeStore) GetDSLByVersion(_ context.Context, cbuID string, version int) (*store.DSLVersionWithState, error) {
+	for _, v := range f.versions[cbuID] {
+		if v.VersionNumber == version {
+			return &v, nil
+		}
+	}
+	return nil, store.NotFoundf("DSL version %d not found for CBU %s", version, cbuID)
+}
+
+func (f *fakeStore) ResolveValueFor(_ context.Context, _, attributeID string) (json.RawMessage, map[string]any, string, error) {
+	if attributeID == "INV.BROKEN" {
+		return nil, nil, "", fmt.Errorf("pq: relation \"attribute_values\" does not exist")
+	}
+	return json.RawMessage(`"Alpha Fund"`), map[string]any{"source": "test"}, "resolved", nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s, err := NewServer(Config{DataStore: newFakeStore(), Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doJSON(t *testing.T, method, url, body string) (int, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	var out map[string]any
+	if resp.StatusCode != http.StatusNoContent {
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: expected JSON content type, got %q", method, url, ct)
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+	}
+	return resp.StatusCode, out
+}
+
+func TestCBUEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	base := ts.URL + APIPrefix
+
+	status, body := doJSON(t, "GET", base+"/cbus/CBU-1", "")
+	if status != http.StatusOK || body["name"] != "Alpha Fund" {
+		t.Errorf("GET cbu: got %d %v", status, body)
+	}
+
+	status, body = doJSON(t, "POST", base+"/cbus", `{"name":"Beta Fund","nature_purpose":"Investment"}`)
+	if status != http.StatusCreated || body["cbu_id"] != "CBU-2" {
+		t.Errorf("POST cbus: got %d %v", status, body)
+	}
+
+	status, body = doJSON(t, "GET", base+"/cbus", "")
+	if status != http.StatusOK || body["count"] != 2.0 {
+		t.Errorf("GET cbus: got %d %v", status, body)
+	}
+
+	status, _ = doJSON(t, "DELETE", base+"/cbus/CBU-2", "")
+	if status != http.StatusNoContent {
+		t.Errorf("DELETE cbu: expected 204, got %d", status)
+	}
+}
+
+func TestCBUEndpointsAgainstMockStore(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"cbus.json", "roles.json", "entity_types.json", "entities.json", "entity_limited_companies.json",
+		"entity_partnerships.json", "cbu_entity_roles.json", "products.json", "services.json",
+		"prod_resources.json", "product_services.json", "service_resources.json", "dictionary.json", "dsl_ob.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	ds, err := datastore.NewDataStore(datastore.Config{Type: datastore.MockStore, MockDataPath: dir})
+	if err != nil {
+		t.Fatalf("NewDataStore failed: %v", err)
+	}
+	s, err := NewServer(Config{DataStore: ds, Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	base := ts.URL + APIPrefix
+
+	status, body := doJSON(t, "POST", base+"/cbus", `{"name":"Beta Fund"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("POST cbus: got %d %v", status, body)
+	}
+	cbuURL := base + "/cbus/" + body["cbu_id"].(string)
+
+	status, body = doJSON(t, "PUT", cbuURL, `{"name":"Beta Fund II"}`)
+	if status != http.StatusOK || body["name"] != "Beta Fund II" {
+		t.Errorf("PUT cbu: got %d %v", status, body)
+	}
+	if status, _ = doJSON(t, "DELETE", cbuURL, ""); status != http.StatusNoContent {
+		t.Errorf("DELETE cbu: expected 204, got %d", status)
+	}
+	if status, _ = doJSON(t, "GET", cbuURL, ""); status != http.StatusNotFound {
+		t.Errorf("GET deleted cbu: expected 404, got %d", status)
+	}
+	if status, _ = doJSON(t, "GET", base+"/cbus/"+"missing/dsl", ""); status != http.StatusNotFound {
+		t.Errorf("GET dsl of unknown cbu: expected 404, got %d", status)
+	}
+}
+
+func TestDSLAndAttributeEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	base := ts.URL + APIPrefix
+
+	status, body := doJSON(t, "GET", base+"/cbus/CBU-1/dsl/versions", "")
+	if status != http.StatusOK || body["count"] != 2.0 {
+		t.Errorf("GET versions: got %d %v", status, body)
+	}
+
+	status, body = doJSON(t, "GET", base+"/cbus/CBU-1/dsl/versions/2", "")
+	if status != http.StatusOK || body["version_id"] != "v2" {
+		t.Errorf("GET version 2: got %d %v", status, body)
+	}
+
+	status, body = doJSON(t, "GET", base+"/cbus/CBU-1/attributes/INV.LEGAL_NAME", "")
+	if status != http.StatusOK || body["value"] != "Alpha Fund" || body["state"] != "resolved" {
+		t.Errorf("GET attribute: got %d %v", status, body)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	ts := newTestServer(t)
+	base := ts.URL + APIPrefix
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"Missing CBU", "GET", "/cbus/CBU-404", "", http.StatusNotFound},
+		{"Missing Version", "GET", "/cbus/CBU-1/dsl/versions/9", "", http.StatusNotFound},
+		{"Bad Version", "GET", "/cbus/CBU-1/dsl/versions/latest", "", http.StatusBadRequest},
+		{"Unknown Field", "POST", "/cbus", `{"name":"x","owner":"y"}`, http.StatusBadRequest},
+		{"Missing Name", "POST", "/cbus", `{}`, http.StatusBadRequest},
+		{"No Orchestrator", "GET", "/orchestration/sessions", "", http.StatusServiceUnavailable},
+		{"No Runtime", "POST", "/actions/a1/execute", `{"cbu_id":"CBU-1"}`, http.StatusServiceUnavailable},
+		{"Unknown Route", "GET", "/nope", "", http.StatusNotFound},
+		{"Internal Error", "GET", "/cbus/CBU-1/attributes/INV.BROKEN", "", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := doJSON(t, tt.method, base+tt.path, tt.body)
+			if status != tt.status {
+				t.Errorf("expected %d, got %d (%v)", tt.status, status, body)
+			}
+			if body["error"] == "" || body["status"] != float64(tt.status) {
+				t.Errorf("expected JSON error body, got %v", body)
+			}
+			if msg, _ := body["error"].(string); strings.Contains(msg, "pq:") {
+				t.Errorf("expected internal error details to be hidden, got %q", msg)
+			}
+		})
+	}
+
+	t.Run("Method Not Allowed", func(t *testing.T) {
+		status, _ := doJSON(t, "PATCH", base+"/cbus/CBU-1", "")
+		if status != http.StatusMethodNotAllowed {
+			t.Errorf("expected 405, got %d", status)
+		}
+	})
+}
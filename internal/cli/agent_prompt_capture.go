@@ -21,7 +21,7 @@ const (
 )
 
 // RunAgentPromptCapture demonstrates AI agent capabilities with full prompt/response capture
-func RunAgentPromptCapture(ctx context.Context, ds datastore.DataStore, ai *agent.Agent, args []string) error {
+func RunAgentPromptCapture(ctx context.Context, ds datastore.DataStore, ai agent.Provider, args []string) error {
 	fs := flag.NewFlagSet("agent-prompt-capture", flag.ExitOnError)
 	cbuID := fs.String("cbu", "", "The CBU ID to test with (optional)")
 	testType := fs.String("type", "all", "Test type: kyc, transform, validate, or all")
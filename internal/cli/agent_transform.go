@@ -14,7 +14,7 @@ import (
 )
 
 // RunAgentTransform handles the 'agent-transform' command for AI-powered DSL transformations
-func RunAgentTransform(ctx context.Context, ds datastore.DataStore, ai *agent.Agent, args []string) error {
+func RunAgentTransform(ctx context.Context, ds datastore.DataStore, ai agent.Provider, args []string) error {
 	var finalDSL string
 	fs := flag.NewFlagSet("agent-transform", flag.ExitOnError)
 	cbuID := fs.String("cbu", "", "The CBU ID of the case to transform (required)")
@@ -31,18 +31,30 @@ func RunAgentTransform(ctx context.Context, ds datastore.DataStore, ai *agent.Ag
 	}
 
 	if ai == nil {
-		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY and try again")
+		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY (or LLM_PROVIDER and its API key) and try again")
 	}
 
 	log.Printf("🤖 Starting AI-powered DSL transformation for CBU: %s", *cbuID)
 	log.Printf("📝 Instruction: %s", *instruction)
 
 	// 2. Get the current onboarding session (for validation)
-	_, err := ds.GetOnboardingSession(ctx, *cbuID)
+	onboardingSession, err := ds.GetOnboardingSession(ctx, *cbuID)
 	if err != nil {
 		return fmt.Errorf("failed to get onboarding session for CBU %s: %w", *cbuID, err)
 	}
 
+	// A target state must be one the onboarding state machine can reach
+	if *targetState != "" {
+		parsed, parseErr := store.ParseOnboardingState(*targetState)
+		if parseErr != nil {
+			return fmt.Errorf("invalid --target-state: %w", parseErr)
+		}
+		if err := onboardingSession.CurrentState.ValidateTransition(parsed); err != nil {
+			return fmt.Errorf("invalid --target-state: %w", err)
+		}
+		*targetState = string(parsed)
+	}
+
 	currentDSLState, err := ds.GetLatestDSLWithState(ctx, *cbuID)
 	if err != nil {
 		return fmt.Errorf("failed to get current DSL for CBU %s: %w", *cbuID, err)
@@ -152,7 +164,7 @@ func RunAgentTransform(ctx context.Context, ds datastore.DataStore, ai *agent.Ag
 }
 
 // RunAgentValidate handles the 'agent-validate' command for AI-powered DSL validation
-func RunAgentValidate(ctx context.Context, ds datastore.DataStore, ai *agent.Agent, args []string) error {
+func RunAgentValidate(ctx context.Context, ds datastore.DataStore, ai agent.Provider, args []string) error {
 	fs := flag.NewFlagSet("agent-validate", flag.ExitOnError)
 	cbuID := fs.String("cbu", "", "The CBU ID of the case to validate (required)")
 	if err := fs.Parse(args); err != nil {
@@ -165,7 +177,7 @@ func RunAgentValidate(ctx context.Context, ds datastore.DataStore, ai *agent.Age
 	}
 
 	if ai == nil {
-		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY and try again")
+		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY (or LLM_PROVIDER and its API key) and try again")
 	}
 
 	log.Printf("🔍 Starting AI-powered DSL validation for CBU: %s", *cbuID)
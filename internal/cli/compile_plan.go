@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"dsl-ob-poc/internal/ir"
+)
+
+// RunCompilePlan handles the 'compile-plan' command: .dsl files are lowered into
+// IR plan JSON, plan or runbook JSON files are raised into canonical DSL.
+func RunCompilePlan(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("compile-plan", flag.ExitOnError)
+	file := fs.String("file", "", "Path to a .dsl file or an IR plan/runbook JSON file (required)")
+	output := fs.String("output", "", "Write the result to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("error: --file flag is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	var result string
+	if strings.HasSuffix(*file, ".json") {
+		plan, err := ir.ParsePlanOrRunbook(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse plan: %w", err)
+		}
+		if result, err = plan.ToDSL(); err != nil {
+			return fmt.Errorf("failed to raise plan to DSL: %w", err)
+		}
+	} else {
+		plan, err := ir.CompileDSL(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to compile DSL: %w", err)
+		}
+		if err := plan.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Compiled plan does not validate: %v\n", err)
+		}
+		out, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode plan: %w", err)
+		}
+		result = string(out) + "\n"
+	}
+
+	if *output == "" {
+		fmt.Print(result)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(result), 0o644); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	fmt.Printf("✅ Wrote %s\n", *output)
+	return nil
+}
@@ -15,7 +15,7 @@ import (
 )
 
 // RunDiscoverKYC handles the 'discover-kyc' command (new Step 3 powered by the AI agent).
-func RunDiscoverKYC(ctx context.Context, ds datastore.DataStore, ai *agent.Agent, args []string) error {
+func RunDiscoverKYC(ctx context.Context, ds datastore.DataStore, ai agent.Provider, args []string) error {
 	fs := flag.NewFlagSet("discover-kyc", flag.ExitOnError)
 	cbuID := fs.String("cbu", "", "The CBU ID of the case to discover (required)")
 	if err := fs.Parse(args); err != nil {
@@ -28,7 +28,7 @@ func RunDiscoverKYC(ctx context.Context, ds datastore.DataStore, ai *agent.Agent
 	}
 
 	if ai == nil {
-		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY and try again")
+		return fmt.Errorf("ai agent is not configured; set GEMINI_API_KEY (or LLM_PROVIDER and its API key) and try again")
 	}
 
 	log.Printf("Starting KYC discovery (Agent Step 3) for CBU: %s", *cbuID)
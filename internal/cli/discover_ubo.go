@@ -37,18 +37,19 @@ type UBODiscoveryResponse struct {
 
 // UBOResult represents an identified UBO
 type UBOResult struct {
-	ProperPersonID           string  `json:"proper_person_id"`
-	Name               string  `json:"name"`
-	RelationshipType   string  `json:"relationship_type"`
-	OwnershipPercent   float64 `json:"ownership_percent"`
-	ControlType        string  `json:"control_type,omitempty"`
-	VerificationStatus string  `json:"verification_status"`
-	ScreeningResult    string  `json:"screening_result"`
-	RiskRating         string  `json:"risk_rating"`
+	ProperPersonID     string   `json:"proper_person_id"`
+	Name               string   `json:"name"`
+	RelationshipType   string   `json:"relationship_type"`
+	OwnershipPercent   float64  `json:"ownership_percent"`
+	ControlType        string   `json:"control_type,omitempty"`
+	VerificationStatus string   `json:"verification_status"`
+	ScreeningResult    string   `json:"screening_result"`
+	RiskRating         string   `json:"risk_rating"`
+	OwnershipPaths     []string `json:"ownership_paths,omitempty"`
 }
 
 // RunDiscoverUBO executes the UBO discovery workflow
-func RunDiscoverUBO(ctx context.Context, ds datastore.DataStore, aiAgent *agent.Agent, args []string) error {
+func RunDiscoverUBO(ctx context.Context, ds datastore.DataStore, aiAgent agent.Provider, args []string) error {
 	// Check for entity-specific workflow flag
 	if len(args) > 0 && args[0] == "--entity-workflows" {
 		return RunEntityUBOWorkflowsDemo(ctx, ds, args[1:])
@@ -80,7 +81,7 @@ func RunDiscoverUBO(ctx context.Context, ds datastore.DataStore, aiAgent *agent.
 	}
 
 	// 4. Generate UBO discovery DSL workflow
-	uboDomain := ubo.NewUBODomain(ds)
+	uboDomain := ubo.NewUBODomainForCBU(ds, request.CBUID)
 	uboWorkflowDSL := uboDomain.GenerateSampleUBOWorkflow(request.EntityName, request.Jurisdiction)
 
 	// 5. If dry run, show what would be executed
@@ -312,6 +313,9 @@ func parseUBOResults(ubos map[string]interface{}) []UBOResult {
 			if controlType, ok := uboData["control_type"].(string); ok {
 				result.ControlType = controlType
 			}
+			if paths, ok := uboData["ownership_paths"].([]string); ok {
+				result.OwnershipPaths = paths
+			}
 
 			// Default values for verification and screening
 			result.VerificationStatus = "pending"
@@ -383,6 +387,9 @@ func displayUBOResults(response *UBODiscoveryResponse, verbose bool) {
 			if ubo.ControlType != "" {
 				fmt.Printf("   └─ Control: %s\n", ubo.ControlType)
 			}
+			for _, path := range ubo.OwnershipPaths {
+				fmt.Printf("   └─ Path: %s\n", path)
+			}
 			fmt.Printf("   └─ Status: Verification %s, Screening %s\n",
 				ubo.VerificationStatus, ubo.ScreeningResult)
 			fmt.Println()
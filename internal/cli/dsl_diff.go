@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/shared-dsl/diff"
+	"dsl-ob-poc/internal/store"
+)
+
+// RunDSLDiff handles the 'dsl-diff' command: a structural diff between two
+// DSL versions of a case, defaulting to the latest version and its predecessor.
+func RunDSLDiff(ctx context.Context, ds datastore.DataStore, args []string) error {
+	fs := flag.NewFlagSet("dsl-diff", flag.ExitOnError)
+	cbuID := fs.String("cbu", "", "The CBU ID of the case (required)")
+	from := fs.Int("from", 0, "Old version number (default: the version before --to)")
+	to := fs.Int("to", 0, "New version number (default: the latest version)")
+	asJSON := fs.Bool("json", false, "Print the diff as JSON")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *cbuID == "" {
+		fs.Usage()
+		return fmt.Errorf("error: --cbu flag is required")
+	}
+
+	newVersion, err := dslVersion(ctx, ds, *cbuID, *to)
+	if err != nil {
+		return err
+	}
+	fromVersion := *from
+	if fromVersion == 0 {
+		fromVersion = newVersion.VersionNumber - 1
+	}
+	if fromVersion < 1 {
+		return fmt.Errorf("version %d of CBU %s has no predecessor to diff against", newVersion.VersionNumber, *cbuID)
+	}
+	oldVersion, err := dslVersion(ctx, ds, *cbuID, fromVersion)
+	if err != nil {
+		return err
+	}
+
+	result, err := diff.Diff(oldVersion.DSLText, newVersion.DSLText)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode diff: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	fmt.Printf("--- CBU %s version %d (%s)\n", *cbuID, oldVersion.VersionNumber, oldVersion.OnboardingState)
+	fmt.Printf("+++ CBU %s version %d (%s)\n", *cbuID, newVersion.VersionNumber, newVersion.OnboardingState)
+	if result.Empty() {
+		fmt.Println("No semantic changes.")
+		return nil
+	}
+	fmt.Print(result.String())
+	return nil
+}
+
+// RunDSLMerge handles the 'dsl-merge' command: a three-way merge of two DSL
+// versions (or files) derived from a common base version.
+func RunDSLMerge(ctx context.Context, ds datastore.DataStore, args []string) error {
+	fs := flag.NewFlagSet("dsl-merge", flag.ExitOnError)
+	cbuID := fs.String("cbu", "", "The CBU ID of the case (required)")
+	base := fs.Int("base", 0, "Common ancestor version number")
+	ours := fs.Int("ours", 0, "Our version number (default: the latest version)")
+	theirs := fs.Int("theirs", 0, "Their version number")
+	baseFile := fs.String("base-file", "", "Read the base DSL from a file instead of a version")
+	oursFile := fs.String("ours-file", "", "Read our DSL from a file instead of a version")
+	theirsFile := fs.String("theirs-file", "", "Read their DSL from a file instead of a version")
+	prefer := fs.String("prefer", "", "Resolve conflicts with 'ours' or 'theirs'")
+	save := fs.Bool("save", false, "Store the merged DSL as a new version of the case")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *cbuID == "" {
+		fs.Usage()
+		return fmt.Errorf("error: --cbu flag is required")
+	}
+	strategy := diff.Strategy(*prefer)
+	if strategy != diff.StrategyNone && strategy != diff.PreferOurs && strategy != diff.PreferTheirs {
+		return fmt.Errorf("error: --prefer must be 'ours' or 'theirs'")
+	}
+
+	baseDSL, err := mergeInput(ctx, ds, *cbuID, "base", *base, *baseFile, false)
+	if err != nil {
+		return err
+	}
+	oursDSL, err := mergeInput(ctx, ds, *cbuID, "ours", *ours, *oursFile, true)
+	if err != nil {
+		return err
+	}
+	theirsDSL, err := mergeInput(ctx, ds, *cbuID, "theirs", *theirs, *theirsFile, false)
+	if err != nil {
+		return err
+	}
+
+	result, err := diff.Merge(baseDSL, oursDSL, theirsDSL, strategy)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range result.Conflicts {
+		fmt.Fprintf(os.Stderr, "⚠️  Conflict on %s\n", c.Key)
+		fmt.Fprintf(os.Stderr, "    base:   %s\n", orRemoved(c.Base))
+		fmt.Fprintf(os.Stderr, "    ours:   %s\n", orRemoved(c.Ours))
+		fmt.Fprintf(os.Stderr, "    theirs: %s\n", orRemoved(c.Theirs))
+	}
+	fmt.Println(result.DSL)
+
+	if !*save {
+		return nil
+	}
+	if len(result.Conflicts) > 0 && strategy == diff.StrategyNone {
+		return fmt.Errorf("refusing to save: %d unresolved conflicts (use --prefer=ours|theirs)", len(result.Conflicts))
+	}
+
+	latest, err := ds.GetLatestDSLWithState(ctx, *cbuID)
+	if err != nil {
+		return fmt.Errorf("failed to get latest DSL for CBU %s: %w", *cbuID, err)
+	}
+	versionID, err := ds.InsertDSLWithState(ctx, *cbuID, result.DSL, latest.OnboardingState)
+	if err != nil {
+		return fmt.Errorf("failed to save merged DSL: %w", err)
+	}
+	fmt.Printf("✅ Saved merged DSL as version %s (state: %s)\n", versionID, latest.OnboardingState)
+	return nil
+}
+
+// dslVersion loads a numbered DSL version, or the latest one when number is 0
+func dslVersion(ctx context.Context, ds datastore.DataStore, cbuID string, number int) (*store.DSLVersionWithState, error) {
+	if number == 0 {
+		v, err := ds.GetLatestDSLWithState(ctx, cbuID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest DSL for CBU %s: %w", cbuID, err)
+		}
+		return v, nil
+	}
+	v, err := ds.GetDSLByVersion(ctx, cbuID, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version %d for CBU %s: %w", number, cbuID, err)
+	}
+	return v, nil
+}
+
+// mergeInput reads one side of a merge from a file or a stored version
+func mergeInput(ctx context.Context, ds datastore.DataStore, cbuID, side string, number int, file string, latestByDefault bool) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s file: %w", side, err)
+		}
+		return string(data), nil
+	}
+	if number == 0 && !latestByDefault {
+		return "", fmt.Errorf("error: --%s or --%s-file is required", side, side)
+	}
+	v, err := dslVersion(ctx, ds, cbuID, number)
+	if err != nil {
+		return "", err
+	}
+	return v.DSLText, nil
+}
+
+func orRemoved(text string) string {
+	if text == "" {
+		return "(removed)"
+	}
+	return text
+}
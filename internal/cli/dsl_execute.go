@@ -113,8 +113,8 @@ func runDemoWorkflow(executor *dsl.DSLExecutor) error {
 
 	log.Printf("\n📝 Executing %d DSL Commands:\n", len(commands))
 
-	// Execute all commands
-	results, err := executor.ExecuteBatch(commands)
+	// Execute all commands as one document
+	results, err := executor.ExecuteDocument(strings.Join(commands, "\n\n"))
 	if err != nil {
 		return fmt.Errorf("demo execution failed: %w", err)
 	}
@@ -166,24 +166,17 @@ func executeFromFile(executor *dsl.DSLExecutor, filePath string) error {
 		return fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
 
-	// Split content into individual S-expressions
-	commands := parseDSLFile(string(content))
-
-	log.Printf("📝 Found %d DSL commands in file", len(commands))
-
-	// Execute all commands
-	results, err := executor.ExecuteBatch(commands)
+	// Parse and execute the whole document with the shared DSL parser
+	results, err := executor.ExecuteDocument(string(content))
 	if err != nil {
 		return fmt.Errorf("file execution failed: %w", err)
 	}
 
+	log.Printf("📝 Executed %d top-level DSL forms from file", len(results))
+
 	// Display results
 	for i, result := range results {
-		log.Printf("Command %d: %s - %s", i+1, result.Command,
-			map[bool]string{true: "✅ Success", false: "❌ Failed"}[result.Success])
-		if !result.Success {
-			log.Printf("  Error: %s", result.Error)
-		}
+		logExecutionResult(fmt.Sprintf("Command %d", i+1), result, "")
 	}
 
 	// Show final summary
@@ -291,47 +284,16 @@ func runInteractiveMode(executor *dsl.DSLExecutor) error {
 	return nil
 }
 
-// parseDSLFile parses a file containing multiple S-expressions
-func parseDSLFile(content string) []string {
-	var commands []string
-	var current strings.Builder
-	depth := 0
-	inQuotes := false
-
-	for _, char := range content {
-		switch char {
-		case '"':
-			inQuotes = !inQuotes
-			current.WriteRune(char)
-		case '(':
-			if !inQuotes {
-				depth++
-			}
-			current.WriteRune(char)
-		case ')':
-			if !inQuotes {
-				depth--
-			}
-			current.WriteRune(char)
-
-			// Complete S-expression found
-			if depth == 0 {
-				cmd := strings.TrimSpace(current.String())
-				if cmd != "" {
-					commands = append(commands, cmd)
-				}
-				current.Reset()
-			}
-		case '\n', '\r', '\t', ' ':
-			if depth > 0 {
-				current.WriteRune(char)
-			}
-		default:
-			current.WriteRune(char)
-		}
+// logExecutionResult logs a result and any nested results with their source positions
+func logExecutionResult(label string, result *dsl.ExecutionResult, indent string) {
+	log.Printf("%s%s: %s (line %d, col %d) - %s", indent, label, result.Command, result.Line, result.Column,
+		map[bool]string{true: "✅ Success", false: "❌ Failed"}[result.Success])
+	if !result.Success && len(result.Children) == 0 {
+		log.Printf("%s  Error: %s", indent, result.Error)
+	}
+	for i, child := range result.Children {
+		logExecutionResult(fmt.Sprintf("Form %d", i+1), child, indent+"  ")
 	}
-
-	return commands
 }
 
 // showHelp displays available commands
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"dsl-ob-poc/internal/domains/onboarding"
+	"dsl-ob-poc/internal/dsl"
+	"dsl-ob-poc/internal/shared-dsl/format"
+	"dsl-ob-poc/internal/shared-dsl/lint"
+	"dsl-ob-poc/internal/vocabulary"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// stdinName labels DSL read from standard input
+const stdinName = "<stdin>"
+
+// RunDSLFmt handles the 'dsl-fmt' command: rewrites DSL files (or standard
+// input) in canonical layout.
+func RunDSLFmt(args []string) error {
+	fs := flag.NewFlagSet("dsl-fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "Write the result back to each file instead of printing it")
+	check := fs.Bool("check", false, "List files that are not formatted and fail if there are any")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if *write && *check {
+		return fmt.Errorf("error: -w and --check cannot be combined")
+	}
+
+	inputs, err := readDSLInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	unformatted := 0
+	for _, in := range inputs {
+		out, err := format.Format(in.text)
+		if err != nil {
+			return fmt.Errorf("%s: %w", in.name, err)
+		}
+		switch {
+		case *check:
+			if out != in.text {
+				fmt.Println(in.name)
+				unformatted++
+			}
+		case *write && in.name != stdinName:
+			if out == in.text {
+				continue
+			}
+			if err := os.WriteFile(in.name, []byte(out), in.mode); err != nil {
+				return fmt.Errorf("failed to write %s: %w", in.name, err)
+			}
+		default:
+			fmt.Print(out)
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) are not formatted", unformatted)
+	}
+	return nil
+}
+
+// lintFinding is a lint issue together with the file it was found in
+type lintFinding struct {
+	File string `json:"file"`
+	lint.Issue
+}
+
+// RunDSLLint handles the 'dsl-lint' command: checks DSL files (or standard
+// input) against the vocabulary and the lint rules, failing on any error.
+func RunDSLLint(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("dsl-lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the issues as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	inputs, err := readDSLInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	cfg, err := lintConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	findings := make([]lintFinding, 0)
+	errorCount := 0
+	for _, in := range inputs {
+		for _, issue := range lint.Lint(in.text, cfg) {
+			findings = append(findings, lintFinding{File: in.name, Issue: issue})
+			if issue.Severity == lint.SeverityError {
+				errorCount++
+			}
+		}
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode issues: %w", err)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, f := range findings {
+			fmt.Printf("%s:%s\n", f.File, f.Issue)
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d lint error(s)", errorCount)
+	}
+	return nil
+}
+
+// dslInput is one document to format or lint
+type dslInput struct {
+	name string
+	text string
+	mode os.FileMode
+}
+
+// readDSLInputs reads the named files, or standard input when there are none
+func readDSLInputs(paths []string) ([]dslInput, error) {
+	if len(paths) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read standard input: %w", err)
+		}
+		return []dslInput{{name: stdinName, text: string(data)}}, nil
+	}
+
+	inputs := make([]dslInput, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		inputs = append(inputs, dslInput{name: path, text: string(data), mode: info.Mode().Perm()})
+	}
+	return inputs, nil
+}
+
+// lintConfig loads the approved and deprecated verbs from the vocabulary
+// tables when a database is configured, falling back to the built-in
+// onboarding vocabulary otherwise
+func lintConfig(ctx context.Context) (lint.Config, error) {
+	if connStr := os.Getenv("DB_CONN_STRING"); connStr != "" {
+		if dbx, err := sqlx.Open("postgres", connStr); err == nil {
+			defer dbx.Close()
+			repo := vocabulary.NewPostgresRepository(dbx)
+			if all, err := repo.GetAllApprovedVerbs(ctx); err == nil {
+				var verbs []string
+				for _, list := range all {
+					verbs = append(verbs, list...)
+				}
+				cfg := lint.Config{Vocabulary: dsl.NewVocabulary(verbs), Deprecated: make(map[string]string)}
+
+				deprecated := true
+				entries, err := repo.ListVerbRegistry(ctx, nil, nil, &deprecated)
+				if err != nil {
+					return lint.Config{}, fmt.Errorf("failed to load deprecated verbs: %w", err)
+				}
+				for _, e := range entries {
+					cfg.Deprecated[e.Verb] = ""
+					if e.ReplacementVerb != nil {
+						cfg.Deprecated[e.Verb] = *e.ReplacementVerb
+					}
+				}
+				return cfg, nil
+			}
+		}
+	}
+
+	static, err := dsl.NewVocabularyLoader(nil).LoadVocabulary(ctx)
+	if err != nil {
+		return lint.Config{}, fmt.Errorf("failed to load vocabulary: %w", err)
+	}
+	var verbs []string
+	for verb := range onboarding.NewDomain().GetVocabulary().Verbs {
+		verbs = append(verbs, verb)
+	}
+	return lint.Config{Vocabulary: vocabularies{static, dsl.NewVocabulary(verbs)}}, nil
+}
+
+// vocabularies accepts a verb approved by any of its members
+type vocabularies []lint.Vocabulary
+
+func (v vocabularies) IsValidVerb(verb string) bool {
+	for _, vocab := range v {
+		if vocab.IsValidVerb(verb) {
+			return true
+		}
+	}
+	return false
+}
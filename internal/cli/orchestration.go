@@ -7,6 +7,7 @@
 // Commands:
 // - orchestrate-create: Create a new orchestrated workflow
 // - orchestrate-execute: Execute an instruction across domains
+// - orchestrate-run: Run a session's execution plan across its domains
 // - orchestrate-status: Get orchestration session status
 // - orchestrate-list: List active orchestration sessions
 package cli
@@ -25,6 +26,7 @@ import (
 	"dsl-ob-poc/internal/domains/onboarding"
 	"dsl-ob-poc/internal/orchestration"
 	"dsl-ob-poc/internal/shared-dsl/session"
+	"dsl-ob-poc/internal/store"
 )
 
 // RunOrchestrationInitDB initializes orchestration session persistence tables
@@ -267,6 +269,77 @@ func RunOrchestrationExecute(ctx context.Context, dataStore datastore.DataStore,
 	return nil
 }
 
+// RunOrchestrationRun runs a session's execution plan, starting each domain
+// task as soon as its dependencies allow
+func RunOrchestrationRun(ctx context.Context, dataStore datastore.DataStore, args []string) error {
+	fs := flag.NewFlagSet("orchestrate-run", flag.ExitOnError)
+
+	sessionID := fs.String("session-id", "", "Orchestration session ID")
+	timeout := fs.Duration("timeout", 0, "Stop starting tasks after this long; unfinished tasks resume on the next run")
+	jsonOutput := fs.Bool("json", false, "Output result as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *sessionID == "" {
+		return fmt.Errorf("--session-id is required")
+	}
+
+	// Initialize orchestration system
+	orchestrator, err := initializeOrchestrator(dataStore)
+	if err != nil {
+		return fmt.Errorf("failed to initialize orchestrator: %w", err)
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	fmt.Printf("🚀 Running execution plan...\n")
+	fmt.Printf("   Session: %s\n", *sessionID)
+
+	result, err := orchestrator.ExecutePlan(ctx, *sessionID)
+	if result == nil {
+		return fmt.Errorf("plan execution failed: %w", err)
+	}
+
+	if *jsonOutput {
+		return outputJSON(result)
+	}
+
+	// Human-readable output
+	fmt.Printf("\n📋 Tasks: %d completed, %d failed, %d skipped, %d pending (%v)\n",
+		result.Completed, result.Failed, result.Skipped, result.Pending, result.Duration)
+	for _, stage := range result.Stages {
+		fmt.Printf("   %s [%s]: %v\n", stage.Name, stage.State, stage.Domains)
+	}
+	for _, task := range result.Tasks {
+		fmt.Printf("   • %s: %s", task.TaskID, task.Status)
+		if task.Error != "" {
+			fmt.Printf(" (%s)", task.Error)
+		}
+		fmt.Println()
+	}
+
+	if len(result.Warnings) > 0 {
+		fmt.Printf("\n⚠️ Warnings:\n")
+		for _, warning := range result.Warnings {
+			fmt.Printf("   • %s\n", warning)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("plan execution interrupted: %w", err)
+	}
+	if !result.Success() {
+		return fmt.Errorf("%d of %d tasks did not complete", len(result.Tasks)-result.Completed, len(result.Tasks))
+	}
+	return nil
+}
+
 // RunOrchestrationStatus shows the status of an orchestration session
 func RunOrchestrationStatus(ctx context.Context, dataStore datastore.DataStore, args []string) error {
 	fs := flag.NewFlagSet("orchestrate-status", flag.ExitOnError)
@@ -528,7 +601,10 @@ func initializeOrchestrator(dataStore datastore.DataStore) (*orchestration.Orche
 	// For now, we'll work with just the onboarding domain
 
 	// Create session manager
-	sessionManager := session.NewManager()
+	sessionManager, err := newSessionManager()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create persistent session store
 	sessionStore := orchestration.NewPersistentOrchestrationStore(dataStore)
@@ -540,6 +616,35 @@ func initializeOrchestrator(dataStore datastore.DataStore) (*orchestration.Orche
 	return orchestrator, nil
 }
 
+// newSessionManager creates the DSL session manager selected by
+// DSL_SESSION_STORE: "memory" (default), "file" (JSON files in
+// DSL_SESSION_DIR, default data/sessions) or "postgres" (DB_CONN_STRING).
+// The postgres connection lives as long as the process.
+func newSessionManager() (*session.Manager, error) {
+	switch kind := os.Getenv("DSL_SESSION_STORE"); kind {
+	case "", "memory":
+		return session.NewManager(), nil
+	case "file":
+		dir := os.Getenv("DSL_SESSION_DIR")
+		if dir == "" {
+			dir = "data/sessions"
+		}
+		fileStore, err := session.NewFileStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		return session.NewPersistentManager(fileStore), nil
+	case "postgres":
+		storeInstance, err := store.NewStore(os.Getenv("DB_CONN_STRING"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize session store: %w", err)
+		}
+		return session.NewPersistentManager(session.NewPostgresStore(storeInstance.DB())), nil
+	default:
+		return nil, fmt.Errorf("unknown DSL_SESSION_STORE %q (want memory, file or postgres)", kind)
+	}
+}
+
 // getActiveDomainNames extracts domain names from orchestration session
 func getActiveDomainNames(session *orchestration.OrchestrationSession) []string {
 	names := make([]string, 0, len(session.ActiveDomains))
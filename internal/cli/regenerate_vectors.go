@@ -4,40 +4,28 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"os"
 
 	"dsl-ob-poc/internal/datastore"
 	"dsl-ob-poc/internal/dictionary"
 )
 
 // RegenerateVectorsCommand regenerates vector embeddings for all dictionary attributes
-func RegenerateVectorsCommand(args []string) error {
+func RegenerateVectorsCommand(ctx context.Context, ds datastore.DataStore, args []string) error {
 	fs := flag.NewFlagSet("regenerate-vectors", flag.ExitOnError)
 
 	var (
 		attributeID = fs.String("attribute-id", "", "Regenerate vector for specific attribute ID (optional)")
 		validate    = fs.Bool("validate", false, "Validate vector integrity instead of regenerating")
 		stats       = fs.Bool("stats", false, "Show vector database statistics")
+		indexPath   = fs.String("index", dictionary.DefaultVectorIndexPath, "Path of the local vector index file (not stored in the database)")
 	)
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
-	// Initialize data store
-	ds, err := datastore.NewDataStore(datastore.Config{
-		Type:             datastore.PostgreSQLStore,
-		ConnectionString: os.Getenv("DB_CONN_STRING"),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to initialize data store: %w", err)
-	}
-	defer ds.Close()
-
-	ctx := context.Background()
-
 	// Initialize vector service
-	vectorService := dictionary.NewVectorService()
+	vectorService := dictionary.NewVectorService(dictionary.WithIndexPath(*indexPath))
 
 	switch {
 	case *stats:
@@ -71,12 +59,7 @@ func regenerateAllVectors(ctx context.Context, service *dictionary.VectorService
 	fmt.Println()
 	fmt.Println("✅ Vector regeneration completed successfully!")
 	fmt.Println()
-	fmt.Println("💡 Note: In production, this would call an embedding service like:")
-	fmt.Println("   • OpenAI Embeddings API")
-	fmt.Println("   • Sentence Transformers")
-	fmt.Println("   • Custom embedding models")
-	fmt.Println()
-	fmt.Println("📚 The vectors enable AI-powered semantic search for attributes")
+	fmt.Println("📚 The vectors enable semantic search for attributes")
 	fmt.Println("   based on their names, descriptions, and metadata.")
 
 	return nil
@@ -116,6 +99,7 @@ func showVectorStats(ctx context.Context, service *dictionary.VectorService, ds
 	fmt.Printf("   Total Attributes: %d\n", stats.TotalAttributes)
 	fmt.Printf("   With Vectors: %d\n", stats.AttributesWithVector)
 	fmt.Printf("   Without Vectors: %d\n", stats.AttributesWithoutVector)
+	fmt.Printf("   Indexed: %d (model %s)\n", stats.IndexedAttributes, stats.Model)
 
 	if stats.TotalAttributes > 0 {
 		coverage := float64(stats.AttributesWithVector) / float64(stats.TotalAttributes) * 100
@@ -130,12 +114,13 @@ func showVectorStats(ctx context.Context, service *dictionary.VectorService, ds
 }
 
 // SearchAttributesCommand searches for attributes using semantic similarity
-func SearchAttributesCommand(args []string) error {
+func SearchAttributesCommand(ctx context.Context, ds datastore.DataStore, args []string) error {
 	fs := flag.NewFlagSet("search-attributes", flag.ExitOnError)
 
 	var (
-		query = fs.String("query", "", "Search query text (required)")
-		limit = fs.Int("limit", 10, "Maximum number of results")
+		query     = fs.String("query", "", "Search query text (required)")
+		limit     = fs.Int("limit", 10, "Maximum number of results")
+		indexPath = fs.String("index", dictionary.DefaultVectorIndexPath, "Path of the local vector index file (not stored in the database)")
 	)
 
 	if err := fs.Parse(args); err != nil {
@@ -146,18 +131,7 @@ func SearchAttributesCommand(args []string) error {
 		return fmt.Errorf("search query is required (use -query flag)")
 	}
 
-	// Initialize data store
-	ds, err := datastore.NewDataStore(datastore.Config{
-		Type:             datastore.PostgreSQLStore,
-		ConnectionString: os.Getenv("DB_CONN_STRING"),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to initialize data store: %w", err)
-	}
-	defer ds.Close()
-
-	ctx := context.Background()
-	vectorService := dictionary.NewVectorService()
+	vectorService := dictionary.NewVectorService(dictionary.WithIndexPath(*indexPath))
 
 	fmt.Printf("🔍 Searching for attributes similar to: %s\n", *query)
 	fmt.Printf("📊 Limit: %d results\n", *limit)
@@ -181,8 +155,9 @@ func SearchAttributesCommand(args []string) error {
 	fmt.Printf("✅ Found %d similar attributes:\n", len(results))
 	fmt.Println()
 
-	for i, attr := range results {
-		fmt.Printf("%d. %s (ID: %s)\n", i+1, attr.Name, attr.AttributeID)
+	for i, match := range results {
+		attr := match.Attribute
+		fmt.Printf("%d. %s (ID: %s, score: %.3f)\n", i+1, attr.Name, attr.AttributeID, match.Score)
 		if attr.LongDescription != "" {
 			fmt.Printf("   Description: %s\n", attr.LongDescription)
 		}
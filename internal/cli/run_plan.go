@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/ir"
+)
+
+// RunPlan handles the 'run-plan' command: it loads an IR plan or runbook,
+// validates it and executes every step through the plan interpreter.
+func RunPlan(ctx context.Context, ds datastore.DataStore, args []string) error {
+	fs := flag.NewFlagSet("run-plan", flag.ExitOnError)
+	file := fs.String("file", "", "Path to an IR plan or runbook JSON file (required)")
+	cbuID := fs.String("cbu", "", "CBU ID used to resolve attributes and persist investor state (required)")
+	asJSON := fs.Bool("json", false, "Print the full run result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("error: --file flag is required")
+	}
+	if *cbuID == "" {
+		fs.Usage()
+		return fmt.Errorf("error: --cbu flag is required")
+	}
+
+	// Investor state is keyed by CBU, so the CBU must exist
+	if _, err := ds.GetCBUByID(ctx, *cbuID); err != nil {
+		return fmt.Errorf("failed to get CBU %s: %w", *cbuID, err)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	plan, err := ir.ParsePlanOrRunbook(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	log.Printf("Running plan %s (%d steps) for CBU %s", plan.PlanID, len(plan.Steps), *cbuID)
+
+	interp := ir.NewInterpreter(ds, *cbuID)
+	result, err := interp.Run(ctx, plan)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printRunResult(result)
+	}
+
+	if !result.Success {
+		return fmt.Errorf("plan %s did not complete", plan.PlanID)
+	}
+	return nil
+}
+
+func printRunResult(result *ir.RunResult) {
+	fmt.Printf("\n--- Plan Run: %s ---\n", result.PlanID)
+	for _, o := range result.Outcomes {
+		icon := map[ir.StepStatus]string{
+			ir.StepSucceeded: "✅",
+			ir.StepFailed:    "❌",
+			ir.StepSkipped:   "⏭️",
+			ir.StepDuplicate: "🔁",
+		}[o.Status]
+		fmt.Printf("%s %2d. %-28s %s", icon, o.Index+1, o.Op, o.Status)
+		if o.ToStatus != "" && o.FromStatus != o.ToStatus {
+			fmt.Printf("  %s → %s", o.FromStatus, o.ToStatus)
+		}
+		fmt.Println()
+		if o.Error != "" {
+			fmt.Printf("      Error: %s\n", o.Error)
+		}
+	}
+
+	fmt.Printf("\nInvestors:\n")
+	for id, inv := range result.Investors {
+		fmt.Printf("  %s  %-20s %s (units held: %.6f)\n", id, inv.LegalName, inv.Status, inv.TotalUnits())
+	}
+	if result.Opportunity != nil {
+		fmt.Printf("  (not adopted)  %-20s %s — no step referenced an investor_id, state not persisted\n", result.Opportunity.LegalName, result.Opportunity.Status)
+	}
+}
@@ -148,6 +148,15 @@ func ListActionsCommand(args []string) error {
 			fmt.Printf("   Endpoint: %s\n", action.ExecutionConfig.EndpointURL)
 			fmt.Printf("   Method: %s\n", action.ExecutionConfig.Method)
 			fmt.Printf("   Timeout: %ds\n", action.ExecutionConfig.TimeoutSeconds)
+
+			if expr, err := runtime.CompileTriggerConditions(action.TriggerConditions); err != nil {
+				fmt.Printf("   Trigger: invalid (%v)\n", err)
+			} else if expr != nil {
+				fmt.Printf("   Trigger: %s\n", expr)
+			}
+			if action.CompensatingActionID != nil {
+				fmt.Printf("   Compensated By: %s\n", *action.CompensatingActionID)
+			}
 		}
 		fmt.Println()
 	}
@@ -164,7 +173,7 @@ func ExecuteActionCommand(args []string) error {
 		cbuID        = fs.String("cbu-id", "", "CBU ID for execution context (required)")
 		dslVersionID = fs.String("dsl-version-id", "", "DSL version ID (optional, uses latest if not specified)")
 		environment  = fs.String("environment", "development", "Environment for execution")
-		async        = fs.Bool("async", false, "Execute asynchronously (don't wait for completion)")
+		async        = fs.Bool("async", false, "Enqueue the execution for runtime-worker instead of waiting for it")
 		verbose      = fs.Bool("verbose", false, "Show detailed execution information")
 	)
 
@@ -232,9 +241,13 @@ func ExecuteActionCommand(args []string) error {
 	fmt.Println()
 
 	if *async {
-		fmt.Println("⏳ Starting asynchronous execution...")
-		// In a real implementation, you'd start the execution in a goroutine
-		// and return immediately with the execution ID
+		jobID, err := engine.EnqueueAction(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue execution: %w", err)
+		}
+		fmt.Printf("⏳ Execution queued as job %s\n", jobID)
+		fmt.Println("   Run 'runtime-worker' to process it and 'runtime-jobs' to follow it")
+		return nil
 	}
 
 	startTime := time.Now()
@@ -350,6 +363,41 @@ func ListExecutionsCommand(args []string) error {
 		return nil
 	}
 
+	// Show the circuit breakers that are holding back calls, and which
+	// executions' endpoints they guard
+	breakers, err := repository.ListCircuitBreakerStates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list circuit breakers: %w", err)
+	}
+	breakerByURL := make(map[string]runtime.CircuitBreakerState)
+	tripped := 0
+	for _, breaker := range breakers {
+		breakerByURL[breaker.EndpointURL] = breaker
+		if breaker.State != runtime.CircuitClosed {
+			tripped++
+		}
+	}
+	if tripped > 0 || (*verbose && len(breakers) > 0) {
+		fmt.Println("⚡ Circuit Breakers")
+		for _, breaker := range breakers {
+			if breaker.State == runtime.CircuitClosed && !*verbose {
+				continue
+			}
+			fmt.Printf("   %s: %s", breaker.EndpointURL, breaker.State)
+			if breaker.ConsecutiveFailures > 0 {
+				fmt.Printf(" (%d consecutive failures)", breaker.ConsecutiveFailures)
+			}
+			if breaker.OpenedAt != nil && breaker.State != runtime.CircuitClosed {
+				fmt.Printf(" since %s", breaker.OpenedAt.Format(time.RFC3339))
+			}
+			fmt.Println()
+			if breaker.LastError != nil && *verbose {
+				fmt.Printf("      Last error: %s\n", *breaker.LastError)
+			}
+		}
+		fmt.Println()
+	}
+
 	for i, exec := range executions {
 		// Filter by status if specified
 		if *status != "" && string(exec.ExecutionStatus) != strings.ToUpper(*status) {
@@ -376,6 +424,12 @@ func ListExecutionsCommand(args []string) error {
 			fmt.Printf("   HTTP Status: %d\n", *exec.HTTPStatus)
 		}
 
+		if exec.Endpoint != nil {
+			if breaker, ok := breakerByURL[*exec.Endpoint]; ok && breaker.State != runtime.CircuitClosed {
+				fmt.Printf("   Circuit Breaker: %s\n", breaker.State)
+			}
+		}
+
 		if *verbose {
 			fmt.Printf("   Action ID: %s\n", exec.ActionID)
 			fmt.Printf("   CBU ID: %s\n", exec.CBUID)
@@ -437,6 +491,7 @@ func TriggerWorkflowCommand(args []string) error {
 		cbuID       = fs.String("cbu-id", "", "CBU ID to trigger workflow for (required)")
 		environment = fs.String("environment", "development", "Environment for execution")
 		dryRun      = fs.Bool("dry-run", false, "Show what actions would be triggered without executing")
+		sync        = fs.Bool("sync", false, "Execute the triggered actions in this process instead of enqueueing them")
 		verbose     = fs.Bool("verbose", false, "Show detailed information")
 	)
 
@@ -496,6 +551,22 @@ func TriggerWorkflowCommand(args []string) error {
 		return fmt.Errorf("failed to create execution engine: %w", err)
 	}
 
+	if !*sync {
+		jobIDs, err := engine.EnqueueActionsForDSLChange(ctx, *cbuID, latestDSL.VersionID, latestDSL.DSLText, *environment)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue actions: %w", err)
+		}
+		if len(jobIDs) == 0 {
+			fmt.Println("ℹ️  No actions were triggered for the current DSL state")
+			return nil
+		}
+		fmt.Printf("⏳ Queued %d action(s) for runtime-worker:\n", len(jobIDs))
+		for i, jobID := range jobIDs {
+			fmt.Printf("%d. Job ID: %s\n", i+1, jobID)
+		}
+		return nil
+	}
+
 	// Trigger actions based on current DSL state
 	results, err := engine.TriggerActionsForDSLChange(ctx, *cbuID, latestDSL.VersionID, latestDSL.DSLText, *environment)
 	if err != nil {
@@ -533,18 +604,35 @@ func TriggerWorkflowCommand(args []string) error {
 			}
 		}
 
+		if c := result.Compensation; c != nil {
+			if c.Success {
+				fmt.Printf("   ↩️  Compensated by execution %s\n", c.ExecutionID)
+			} else {
+				fmt.Printf("   ⚠️  Compensation FAILED\n")
+				if c.ErrorDetails != nil {
+					fmt.Printf("   Compensation Error: %s\n", *c.ErrorDetails)
+				}
+			}
+		}
+
 		fmt.Println()
 	}
 
 	// Show summary
-	successCount := 0
+	successCount, compensatedCount := 0, 0
 	for _, result := range results {
 		if result.Success {
 			successCount++
 		}
+		if result.Compensation != nil && result.Compensation.Success {
+			compensatedCount++
+		}
 	}
 
 	fmt.Printf("📊 Summary: %d/%d actions succeeded\n", successCount, len(results))
+	if compensatedCount > 0 {
+		fmt.Printf("↩️  %d completed action(s) rolled back after a failure\n", compensatedCount)
+	}
 
 	return nil
 }
@@ -563,6 +651,8 @@ func CreateActionCommand(args []string) error {
 		environment     = fs.String("environment", "development", "Environment")
 		timeout         = fs.Int("timeout", 300, "Timeout in seconds")
 		configFile      = fs.String("config-file", "", "JSON file with complete action configuration")
+		triggerExpr     = fs.String("trigger", "", "Trigger condition, e.g. 'state >= KYC_DISCOVERED && attr(jurisdiction) in [\"LU\", \"IE\"]'")
+		compensate      = fs.String("compensate", "", "ID of the action that undoes this one if a later triggered action fails")
 	)
 
 	if err := fs.Parse(args); err != nil {
@@ -595,6 +685,18 @@ func CreateActionCommand(args []string) error {
 		return fmt.Errorf("endpoint URL is required")
 	}
 
+	// Compile the trigger condition before anything is stored
+	triggerConditions := []byte("{}")
+	if *triggerExpr != "" {
+		triggerConditions, err = json.Marshal(runtime.TriggerConditions{Expression: triggerExpr})
+		if err != nil {
+			return fmt.Errorf("failed to encode trigger condition: %w", err)
+		}
+		if _, err := runtime.CompileTriggerConditions(triggerConditions); err != nil {
+			return err
+		}
+	}
+
 	// Get resource type ID if specified
 	var resourceTypeID *string
 	if *resourceType != "" {
@@ -605,6 +707,14 @@ func CreateActionCommand(args []string) error {
 		resourceTypeID = &rt.ResourceTypeID
 	}
 
+	var compensatingActionID *string
+	if *compensate != "" {
+		if _, err := repository.GetActionDefinition(ctx, *compensate); err != nil {
+			return fmt.Errorf("failed to find compensating action: %w", err)
+		}
+		compensatingActionID = compensate
+	}
+
 	// Create basic action definition
 	action := &runtime.ActionDefinition{
 		ActionName:  *name,
@@ -635,7 +745,8 @@ func CreateActionCommand(args []string) error {
 			InputMapping:  []runtime.AttributeMap{},
 			OutputMapping: []runtime.AttributeMap{},
 		},
-		TriggerConditions: []byte("{}"),
+		TriggerConditions: triggerConditions,
+		CompensatingActionID: compensatingActionID,
 		SuccessCriteria:   []byte(`{"http_status_codes": [200, 201, 202]}`),
 		FailureHandling:   []byte(`{"retry_on_codes": [500, 502, 503, 504]}`),
 		Active:            true,
@@ -666,7 +777,7 @@ func ManageCredentialsCommand(args []string) error {
 	fs := flag.NewFlagSet("manage-credentials", flag.ExitOnError)
 
 	var (
-		action      = fs.String("action", "list", "Action: list, create, update, delete, test")
+		action      = fs.String("action", "list", "Action: list, create, update, delete, test, rotate-key, audit")
 		name        = fs.String("name", "", "Credential name")
 		credType    = fs.String("type", "", "Credential type (api_key, bearer, basic, oauth2, custom)")
 		environment = fs.String("environment", "development", "Environment")
@@ -674,6 +785,11 @@ func ManageCredentialsCommand(args []string) error {
 		token       = fs.String("token", "", "Token value (for bearer type)")
 		username    = fs.String("username", "", "Username (for basic type)")
 		password    = fs.String("password", "", "Password (for basic type)")
+		tokenURL    = fs.String("token-url", "", "Token endpoint (for oauth2 type)")
+		clientID    = fs.String("client-id", "", "Client ID (for oauth2 type)")
+		secret      = fs.String("client-secret", "", "Client secret (for oauth2 type)")
+		scope       = fs.String("scope", "", "Requested scope (for oauth2 type, optional)")
+		limit       = fs.Int("limit", 50, "Number of access records to show (for audit)")
 		verbose     = fs.Bool("verbose", false, "Show detailed information")
 	)
 
@@ -718,6 +834,9 @@ func ManageCredentialsCommand(args []string) error {
 					fmt.Printf("   Expires: %s\n", cred.ExpiresAt.Format(time.RFC3339))
 				}
 				fmt.Printf("   Active: %t\n", cred.Active)
+				if cred.KeyVersion > 0 {
+					fmt.Printf("   Master Key Version: %d\n", cred.KeyVersion)
+				}
 			}
 			fmt.Println()
 		}
@@ -745,6 +864,11 @@ func ManageCredentialsCommand(args []string) error {
 				return fmt.Errorf("username and password are required for basic type")
 			}
 			err = credMgr.CreateBasicAuthCredentials(ctx, *name, *environment, *username, *password)
+		case "oauth2":
+			if *tokenURL == "" || *clientID == "" {
+				return fmt.Errorf("token-url and client-id are required for oauth2 type")
+			}
+			err = credMgr.CreateOAuth2ClientCredentials(ctx, *name, *environment, *tokenURL, *clientID, *secret, *scope)
 		default:
 			return fmt.Errorf("unsupported credential type: %s", *credType)
 		}
@@ -775,12 +899,45 @@ func ManageCredentialsCommand(args []string) error {
 
 		fmt.Printf("🧪 Testing Credential: %s\n", *name)
 
+		ctx = runtime.WithCredentialAccessor(ctx, "cli:manage-credentials")
 		if err := credMgr.TestCredentialConnection(ctx, *name, ""); err != nil {
 			return fmt.Errorf("credential test failed: %w", err)
 		}
 
 		fmt.Printf("✅ Credential test successful\n")
 
+	case "rotate-key":
+		fmt.Println("🔑 Re-encrypting credentials under the active master key")
+
+		count, err := credMgr.RotateMasterKey(ctx)
+		if err != nil {
+			return fmt.Errorf("master key rotation failed: %w", err)
+		}
+
+		fmt.Printf("✅ Re-encrypted %d credential(s); older master keys can now be retired\n", count)
+
+	case "audit":
+		accesses, err := credMgr.CredentialAccessLog(ctx, *name, *limit)
+		if err != nil {
+			return fmt.Errorf("failed to read credential access log: %w", err)
+		}
+
+		fmt.Printf("📜 Credential Access Log\n\n")
+
+		if len(accesses) == 0 {
+			fmt.Println("❌ No credential accesses recorded")
+			return nil
+		}
+
+		for _, access := range accesses {
+			fmt.Printf("%s  %-9s  %s (%s) by %s\n",
+				access.AccessedAt.Format(time.RFC3339), access.Outcome,
+				access.CredentialName, access.Backend, access.Accessor)
+			if access.Error != nil && *verbose {
+				fmt.Printf("   Error: %s\n", *access.Error)
+			}
+		}
+
 	default:
 		return fmt.Errorf("unsupported action: %s", *action)
 	}
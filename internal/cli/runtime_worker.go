@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/runtime"
+	"dsl-ob-poc/internal/store"
+)
+
+// RuntimeWorkerCommand processes queued action jobs until interrupted, or
+// until the queue has no due jobs left with --once
+func RuntimeWorkerCommand(args []string) error {
+	fs := flag.NewFlagSet("runtime-worker", flag.ExitOnError)
+
+	var (
+		owner = fs.String("owner", "", "Worker name recorded in job leases (default host-pid)")
+		lease = fs.Duration("lease", time.Minute, "How long a job stays leased without a heartbeat")
+		poll  = fs.Duration("poll", 2*time.Second, "How often an idle worker polls for due jobs")
+		once  = fs.Bool("once", false, "Process the jobs that are due now, then exit")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ds, err := datastore.NewDataStore(datastore.Config{
+		Type:             datastore.PostgreSQLStore,
+		ConnectionString: os.Getenv("DB_CONN_STRING"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize data store: %w", err)
+	}
+	defer ds.Close()
+
+	storeInstance, err := store.NewStore(os.Getenv("DB_CONN_STRING"))
+	if err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	defer storeInstance.Close()
+
+	engine, err := runtime.NewExecutionEngine(storeInstance.DB(), ds)
+	if err != nil {
+		return fmt.Errorf("failed to create execution engine: %w", err)
+	}
+
+	worker := runtime.NewWorker(ds, engine, runtime.WorkerConfig{
+		Owner:         *owner,
+		LeaseDuration: *lease,
+		PollInterval:  *poll,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		processed := 0
+		for {
+			ok, err := worker.RunOnce(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to process job: %w", err)
+			}
+			if !ok {
+				break
+			}
+			processed++
+		}
+		fmt.Printf("✅ Processed %d job(s)\n", processed)
+		return nil
+	}
+
+	log.Printf("⚙️  Runtime worker started (lease %s, poll %s)", *lease, *poll)
+	return worker.Run(ctx)
+}
+
+// RuntimeJobsCommand lists queued action jobs and requeues dead-lettered ones
+func RuntimeJobsCommand(args []string) error {
+	fs := flag.NewFlagSet("runtime-jobs", flag.ExitOnError)
+
+	var (
+		status  = fs.String("status", "", "Filter by job status (QUEUED, RUNNING, SUCCEEDED, DEAD)")
+		requeue = fs.String("requeue", "", "Job ID of a dead job to queue again")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ds, err := datastore.NewDataStore(datastore.Config{
+		Type:             datastore.PostgreSQLStore,
+		ConnectionString: os.Getenv("DB_CONN_STRING"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize data store: %w", err)
+	}
+	defer ds.Close()
+
+	ctx := context.Background()
+
+	if *requeue != "" {
+		if err := ds.RequeueJob(ctx, *requeue); err != nil {
+			return fmt.Errorf("failed to requeue job: %w", err)
+		}
+		fmt.Printf("🔁 Job %s queued again\n", *requeue)
+		return nil
+	}
+
+	jobs, err := ds.ListJobs(ctx, store.JobStatus(strings.ToUpper(*status)))
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("❌ No jobs found")
+		return nil
+	}
+
+	for i, job := range jobs {
+		fmt.Printf("%d. Job %s\n", i+1, job.JobID)
+		fmt.Printf("   Status: %s\n", job.Status)
+		fmt.Printf("   Action ID: %s\n", job.ActionID)
+		fmt.Printf("   CBU ID: %s\n", job.CBUID)
+		fmt.Printf("   Attempts: %d/%d\n", job.Attempts, job.MaxAttempts)
+		if job.Status == store.JobQueued {
+			fmt.Printf("   Next Run: %s\n", job.RunAt.Format(time.RFC3339))
+		}
+		if job.LeaseOwner != nil {
+			fmt.Printf("   Leased By: %s\n", *job.LeaseOwner)
+		}
+		if job.ExecutionID != nil {
+			fmt.Printf("   Execution ID: %s\n", *job.ExecutionID)
+		}
+		if job.LastError != nil {
+			fmt.Printf("   Last Error: %s\n", *job.LastError)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
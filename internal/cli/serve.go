@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"dsl-ob-poc/internal/api"
+	"dsl-ob-poc/internal/config"
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/runtime"
+	"dsl-ob-poc/internal/store"
+)
+
+// RunServe handles the 'serve' command: it exposes the data store, the
+// orchestrator and (with PostgreSQL) the runtime engine as a REST API.
+func RunServe(ctx context.Context, ds datastore.DataStore, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	orchestrator, err := initializeOrchestrator(ds)
+	if err != nil {
+		return fmt.Errorf("failed to initialize orchestrator: %w", err)
+	}
+
+	cfg := api.Config{DataStore: ds, Orchestrator: orchestrator}
+
+	// Runtime actions need direct SQL access, which mock mode does not have
+	if !config.IsMockMode() {
+		storeInstance, storeErr := store.NewStore(config.GetDataStoreConfig().ConnectionString)
+		if storeErr != nil {
+			return fmt.Errorf("failed to initialize store: %w", storeErr)
+		}
+		defer storeInstance.Close()
+
+		engine, engineErr := runtime.NewExecutionEngine(storeInstance.DB(), ds)
+		if engineErr != nil {
+			return fmt.Errorf("failed to create execution engine: %w", engineErr)
+		}
+		cfg.Engine = engine
+	} else {
+		log.Printf("⚠️  Runtime action execution is disabled in MOCK mode")
+	}
+
+	server, err := api.NewServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🌐 Serving API on %s%s", *addr, api.APIPrefix)
+	return server.ListenAndServe(ctx, *addr)
+}
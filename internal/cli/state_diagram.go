@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"dsl-ob-poc/internal/statemachine"
+)
+
+// RunStateDiagram handles the 'state-diagram' command: renders the onboarding
+// state machine as a Mermaid or Graphviz diagram.
+func RunStateDiagram(args []string) error {
+	fs := flag.NewFlagSet("state-diagram", flag.ExitOnError)
+	format := fs.String("format", "mermaid", "Diagram format: mermaid or dot")
+	output := fs.String("output", "", "Write the diagram to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	machine := statemachine.Onboarding()
+	var diagram string
+	switch *format {
+	case "mermaid":
+		diagram = machine.Mermaid()
+	case "dot", "graphviz":
+		diagram = machine.Graphviz()
+	default:
+		return fmt.Errorf("unknown diagram format %q (want mermaid or dot)", *format)
+	}
+
+	if *output == "" {
+		fmt.Print(diagram)
+		return nil
+	}
+	if err := os.WriteFile(*output, []byte(diagram), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+	fmt.Printf("Wrote %s state diagram to %s\n", *format, *output)
+	return nil
+}
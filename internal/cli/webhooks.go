@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/store"
+	"dsl-ob-poc/internal/webhooks"
+)
+
+// WebhookDispatcherCommand delivers DSL change events to webhook subscribers
+// until interrupted, or until no delivery is due with --once
+func WebhookDispatcherCommand(args []string) error {
+	fs := flag.NewFlagSet("webhook-dispatcher", flag.ExitOnError)
+
+	var (
+		owner   = fs.String("owner", "", "Dispatcher name recorded in delivery leases (default host-pid)")
+		lease   = fs.Duration("lease", time.Minute, "How long a delivery stays leased; must exceed --timeout")
+		poll    = fs.Duration("poll", 2*time.Second, "How often an idle dispatcher polls for due deliveries")
+		timeout = fs.Duration("timeout", 10*time.Second, "Timeout for each webhook request")
+		once    = fs.Bool("once", false, "Attempt the deliveries that are due now, then exit")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *lease <= *timeout {
+		return fmt.Errorf("--lease (%s) must exceed --timeout (%s)", *lease, *timeout)
+	}
+
+	ds, err := datastore.NewDataStore(datastore.Config{
+		Type:             datastore.PostgreSQLStore,
+		ConnectionString: os.Getenv("DB_CONN_STRING"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize data store: %w", err)
+	}
+	defer ds.Close()
+
+	dispatcher := webhooks.NewDispatcher(ds, webhooks.Config{
+		Owner:         *owner,
+		LeaseDuration: *lease,
+		PollInterval:  *poll,
+		Timeout:       *timeout,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		attempted := 0
+		for {
+			ok, err := dispatcher.RunOnce(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to process delivery: %w", err)
+			}
+			if !ok {
+				break
+			}
+			attempted++
+		}
+		fmt.Printf("✅ Attempted %d delivery(ies)\n", attempted)
+		return nil
+	}
+
+	log.Printf("📣 Webhook dispatcher started (lease %s, poll %s)", *lease, *poll)
+	return dispatcher.Run(ctx)
+}
+
+// ManageWebhooksCommand registers and removes webhook subscriptions and shows
+// DSL change events and their deliveries
+func ManageWebhooksCommand(args []string) error {
+	fs := flag.NewFlagSet("manage-webhooks", flag.ExitOnError)
+
+	var (
+		action      = fs.String("action", "list", "Action: list, add, remove, events, deliveries")
+		url         = fs.String("url", "", "Subscriber URL (for add)")
+		secret      = fs.String("secret", "", "HMAC signing secret (for add; generated if empty)")
+		cbuID       = fs.String("cbu-id", "", "Only deliver events for this CBU (for add), or only list its events")
+		events      = fs.String("events", "", "Comma-separated event types to deliver (for add; default all)")
+		maxAttempts = fs.Int("max-attempts", 8, "Delivery attempts before a delivery is dead-lettered (for add)")
+		id          = fs.String("id", "", "Subscription ID (for remove)")
+		status      = fs.String("status", "", "Filter deliveries by status (PENDING, DELIVERING, DELIVERED, DEAD)")
+		limit       = fs.Int("limit", 20, "Number of events to show (for events)")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ds, err := datastore.NewDataStore(datastore.Config{
+		Type:             datastore.PostgreSQLStore,
+		ConnectionString: os.Getenv("DB_CONN_STRING"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize data store: %w", err)
+	}
+	defer ds.Close()
+
+	ctx := context.Background()
+
+	switch *action {
+	case "add":
+		if *url == "" {
+			return fmt.Errorf("--url is required for add")
+		}
+		sub := &store.WebhookSubscription{URL: *url, Secret: *secret, CBUID: *cbuID, MaxAttempts: *maxAttempts}
+		for _, t := range strings.Split(*events, ",") {
+			switch t = strings.ToUpper(strings.TrimSpace(t)); store.DSLEventType(t) {
+			case "":
+			case store.EventDSLVersionCreated, store.EventOnboardingStateChanged:
+				sub.EventTypes = append(sub.EventTypes, store.DSLEventType(t))
+			default:
+				return fmt.Errorf("unknown event type %q (want %s or %s)", t, store.EventDSLVersionCreated, store.EventOnboardingStateChanged)
+			}
+		}
+		if sub.Secret == "" {
+			buf := make([]byte, 32)
+			if _, err := rand.Read(buf); err != nil {
+				return fmt.Errorf("failed to generate secret: %w", err)
+			}
+			sub.Secret = hex.EncodeToString(buf)
+		}
+
+		subscriptionID, err := ds.CreateWebhookSubscription(ctx, sub)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Webhook subscription %s created for %s\n", subscriptionID, sub.URL)
+		if *secret == "" {
+			fmt.Printf("   Signing Secret: %s (shown once; verify %s with it)\n", sub.Secret, webhooks.HeaderSignature)
+		}
+
+	case "remove":
+		if *id == "" {
+			return fmt.Errorf("--id is required for remove")
+		}
+		if err := ds.DeleteWebhookSubscription(ctx, *id); err != nil {
+			return err
+		}
+		fmt.Printf("🗑️  Webhook subscription %s removed\n", *id)
+
+	case "list":
+		subs, err := ds.ListWebhookSubscriptions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			fmt.Println("❌ No webhook subscriptions found")
+			return nil
+		}
+		for i, sub := range subs {
+			fmt.Printf("%d. Subscription %s\n", i+1, sub.SubscriptionID)
+			fmt.Printf("   URL: %s\n", sub.URL)
+			fmt.Printf("   CBU: %s\n", orAll(sub.CBUID))
+			types := make([]string, len(sub.EventTypes))
+			for j, t := range sub.EventTypes {
+				types[j] = string(t)
+			}
+			fmt.Printf("   Events: %s\n", orAll(strings.Join(types, ", ")))
+			fmt.Printf("   Max Attempts: %d\n", sub.MaxAttempts)
+			fmt.Printf("   Active: %t\n", sub.Active)
+			fmt.Println()
+		}
+
+	case "events":
+		changes, err := ds.ListDSLChangeEvents(ctx, *cbuID, *limit)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Println("❌ No DSL change events found")
+			return nil
+		}
+		for i, event := range changes {
+			fmt.Printf("%d. %s %s\n", i+1, event.EventType, event.EventID)
+			fmt.Printf("   CBU ID: %s (version %d)\n", event.CBUID, event.VersionNumber)
+			fmt.Printf("   State: %s → %s\n", orNone(string(event.OldState)), event.NewState)
+			fmt.Printf("   Changed Verbs: %s\n", orNone(strings.Join(event.ChangedVerbs, ", ")))
+			fmt.Printf("   Created: %s\n", event.CreatedAt.Format(time.RFC3339))
+			fmt.Println()
+		}
+
+	case "deliveries":
+		deliveries, err := ds.ListWebhookDeliveries(ctx, store.DeliveryStatus(strings.ToUpper(*status)))
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			fmt.Println("❌ No webhook deliveries found")
+			return nil
+		}
+		for i, delivery := range deliveries {
+			fmt.Printf("%d. Delivery %s\n", i+1, delivery.DeliveryID)
+			fmt.Printf("   Status: %s\n", delivery.Status)
+			fmt.Printf("   URL: %s\n", delivery.URL)
+			if delivery.Event != nil {
+				fmt.Printf("   Event: %s for CBU %s (version %d)\n", delivery.Event.EventType, delivery.Event.CBUID, delivery.Event.VersionNumber)
+			}
+			fmt.Printf("   Attempts: %d/%d\n", delivery.Attempts, delivery.MaxAttempts)
+			if delivery.Status == store.DeliveryPending {
+				fmt.Printf("   Next Attempt: %s\n", delivery.NextAttemptAt.Format(time.RFC3339))
+			}
+			if delivery.DeliveredAt != nil {
+				fmt.Printf("   Delivered: %s\n", delivery.DeliveredAt.Format(time.RFC3339))
+			}
+			if delivery.LastError != nil {
+				fmt.Printf("   Last Error: %s\n", *delivery.LastError)
+			}
+			fmt.Println()
+		}
+
+	default:
+		return fmt.Errorf("unknown action: %s", *action)
+	}
+
+	return nil
+}
+
+func orAll(s string) string {
+	if s == "" {
+		return "all"
+	}
+	return s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
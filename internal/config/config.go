@@ -21,6 +21,10 @@ func GetDataStoreConfig() datastore.Config {
 	case "mock":
 		config.Type = datastore.MockStore
 		config.MockDataPath = getMockDataPath()
+	case "memory":
+		config.Type = datastore.MemoryStore
+		config.MockDataPath = getMockDataPath()
+		config.SnapshotPath = os.Getenv("DSL_MEMORY_SNAPSHOT")
 	case "postgresql", "postgres", "db":
 		config.Type = datastore.PostgreSQLStore
 		config.ConnectionString = getConnectionString()
@@ -52,8 +56,17 @@ func getConnectionString() string {
 	return connStr
 }
 
-// IsMockMode returns true if running in mock mode
+// GetGrammarDomain returns the grammar domain DSL writes are validated against
+func GetGrammarDomain() string {
+	if domain := os.Getenv("DSL_GRAMMAR_DOMAIN"); domain != "" {
+		return domain
+	}
+	return "onboarding"
+}
+
+// IsMockMode returns true if running without a database, with either the
+// mock or the memory store
 func IsMockMode() bool {
 	storeType := os.Getenv("DSL_STORE_TYPE")
-	return strings.EqualFold(storeType, "mock")
+	return strings.EqualFold(storeType, "mock") || strings.EqualFold(storeType, "memory")
 }
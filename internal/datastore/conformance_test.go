@@ -0,0 +1,71 @@
+package datastore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/datastore/datastoretest"
+)
+
+// writeMockData writes the smallest mock data set both mock and memory mode
+// accept: empty catalogs and a single dictionary attribute
+func writeMockData(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"dictionary.json": `[{"attribute_id": "123e4567-e89b-12d3-a456-426614174000", "name": "entity.domicile", "group_id": "entity", "source": {"primary": "manual"}}]`,
+	}
+	for _, name := range []string{
+		"cbus.json", "roles.json", "entity_types.json", "entities.json", "cbu_entity_roles.json",
+		"entity_limited_companies.json", "entity_partnerships.json", "entity_proper_persons.json",
+		"products.json", "services.json", "prod_resources.json", "product_services.json",
+		"service_resources.json", "dsl_ob.json",
+	} {
+		files[name] = `[]`
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func openStore(t *testing.T, config datastore.Config) datastore.DataStore {
+	t.Helper()
+	ds, err := datastore.NewDataStore(config)
+	if err != nil {
+		t.Fatalf("NewDataStore(%s) failed: %v", config.Type, err)
+	}
+	t.Cleanup(func() { _ = ds.Close() })
+	return ds
+}
+
+func TestConformance_Memory(t *testing.T) {
+	datastoretest.Run(t, datastoretest.Harness{
+		Open: func(t *testing.T) datastore.DataStore {
+			return openStore(t, datastore.Config{Type: datastore.MemoryStore, MockDataPath: writeMockData(t)})
+		},
+	})
+}
+
+func TestConformance_Mock(t *testing.T) {
+	datastoretest.Run(t, datastoretest.Harness{
+		Open: func(t *testing.T) datastore.DataStore {
+			return openStore(t, datastore.Config{Type: datastore.MockStore, MockDataPath: writeMockData(t)})
+		},
+	})
+}
+
+func TestConformance_PostgreSQL(t *testing.T) {
+	connString := os.Getenv("TEST_DB_CONN_STRING")
+	if connString == "" {
+		t.Skip("TEST_DB_CONN_STRING not set")
+	}
+	ds := openStore(t, datastore.Config{Type: datastore.PostgreSQLStore, ConnectionString: connString})
+	datastoretest.Run(t, datastoretest.Harness{
+		Open: func(t *testing.T) datastore.DataStore { return ds },
+	})
+}
@@ -0,0 +1,460 @@
+// Package datastoretest is a conformance suite for datastore.DataStore
+// implementations. Every backend runs the same subtests, so a behaviour that
+// differs between PostgreSQL, mock and memory mode fails here instead of in
+// the CLI.
+package datastoretest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Harness describes the implementation under test
+type Harness struct {
+	// Open returns the store for one subtest. Stores may be shared between
+	// subtests (a PostgreSQL database is); the suite only relies on rows it
+	// creates itself, under unique names.
+	Open func(t *testing.T) datastore.DataStore
+
+	// Skip lists subtests the implementation deliberately does not satisfy,
+	// keyed by subtest name, with the reason reported in the test output
+	Skip map[string]string
+}
+
+// Run runs every conformance subtest against h
+func Run(t *testing.T, h Harness) {
+	subtests := []struct {
+		name string
+		run  func(t *testing.T, ds datastore.DataStore)
+	}{
+		{"CBUs", testCBUs},
+		{"Roles", testRoles},
+		{"DSLVersioning", testDSLVersioning},
+		{"StateTransitions", testStateTransitions},
+		{"AttributeValues", testAttributeValues},
+		{"InvestorStates", testInvestorStates},
+		{"OrchestrationSessions", testOrchestrationSessions},
+	}
+	for _, st := range subtests {
+		t.Run(st.name, func(t *testing.T) {
+			if reason, ok := h.Skip[st.name]; ok {
+				t.Skip(reason)
+			}
+			ds := h.Open(t)
+			st.run(t, ds)
+		})
+	}
+}
+
+// unique returns prefix with a random suffix, so rows created by repeated or
+// concurrent runs against one database never collide
+func unique(prefix string) string {
+	return prefix + "-" + uuid.New().String()[:8]
+}
+
+func createCBU(t *testing.T, ds datastore.DataStore) string {
+	t.Helper()
+	cbuID, err := ds.CreateCBU(context.Background(), unique("CBU"), "Conformance fund", "Conformance testing")
+	if err != nil {
+		t.Fatalf("CreateCBU failed: %v", err)
+	}
+	t.Cleanup(func() { _ = ds.DeleteCBU(context.Background(), cbuID) })
+	return cbuID
+}
+
+func requireNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("%s: expected store.ErrNotFound, got %v", op, err)
+	}
+}
+
+func testCBUs(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	name := unique("CBU")
+
+	cbuID, err := ds.CreateCBU(ctx, name, "Growth fund", "Long-only equity")
+	if err != nil {
+		t.Fatalf("CreateCBU failed: %v", err)
+	}
+	if _, err := ds.CreateCBU(ctx, name, "Duplicate", ""); err == nil {
+		t.Error("CreateCBU accepted a duplicate name")
+	}
+
+	byName, err := ds.GetCBUByName(ctx, name)
+	if err != nil || byName.CBUID != cbuID {
+		t.Fatalf("GetCBUByName = %+v, %v; want ID %s", byName, err, cbuID)
+	}
+	cbus, err := ds.ListCBUs(ctx)
+	if err != nil {
+		t.Fatalf("ListCBUs failed: %v", err)
+	}
+	if !containsCBU(cbus, cbuID) {
+		t.Errorf("ListCBUs does not include %s", cbuID)
+	}
+
+	// Empty fields are left unchanged
+	if err := ds.UpdateCBU(ctx, cbuID, "", "Income fund", ""); err != nil {
+		t.Fatalf("UpdateCBU failed: %v", err)
+	}
+	cbu, err := ds.GetCBUByID(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetCBUByID failed: %v", err)
+	}
+	if cbu.Name != name || cbu.Description != "Income fund" || cbu.NaturePurpose != "Long-only equity" {
+		t.Errorf("after partial update got %+v", cbu)
+	}
+	if err := ds.UpdateCBU(ctx, cbuID, "", "", ""); err == nil {
+		t.Error("UpdateCBU with no fields succeeded")
+	}
+
+	if err := ds.DeleteCBU(ctx, cbuID); err != nil {
+		t.Fatalf("DeleteCBU failed: %v", err)
+	}
+	_, err = ds.GetCBUByID(ctx, cbuID)
+	requireNotFound(t, "GetCBUByID after delete", err)
+	requireNotFound(t, "DeleteCBU twice", ds.DeleteCBU(ctx, cbuID))
+	requireNotFound(t, "UpdateCBU of deleted CBU", ds.UpdateCBU(ctx, cbuID, "", "x", ""))
+}
+
+func containsCBU(cbus []store.CBU, cbuID string) bool {
+	for _, cbu := range cbus {
+		if cbu.CBUID == cbuID {
+			return true
+		}
+	}
+	return false
+}
+
+func testRoles(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	name := unique("Role")
+
+	roleID, err := ds.CreateRole(ctx, name, "Manages the portfolio")
+	if err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if _, err := ds.CreateRole(ctx, name, "Duplicate"); err == nil {
+		t.Error("CreateRole accepted a duplicate name")
+	}
+
+	if err := ds.UpdateRole(ctx, roleID, "", "Advises the portfolio"); err != nil {
+		t.Fatalf("UpdateRole failed: %v", err)
+	}
+	role, err := ds.GetRoleByID(ctx, roleID)
+	if err != nil {
+		t.Fatalf("GetRoleByID failed: %v", err)
+	}
+	if role.Name != name || role.Description != "Advises the portfolio" {
+		t.Errorf("after partial update got %+v", role)
+	}
+
+	roles, err := ds.ListRoles(ctx)
+	if err != nil {
+		t.Fatalf("ListRoles failed: %v", err)
+	}
+	found := false
+	for _, r := range roles {
+		found = found || r.RoleID == roleID
+	}
+	if !found {
+		t.Errorf("ListRoles does not include %s", roleID)
+	}
+
+	if err := ds.DeleteRole(ctx, roleID); err != nil {
+		t.Fatalf("DeleteRole failed: %v", err)
+	}
+	_, err = ds.GetRoleByID(ctx, roleID)
+	requireNotFound(t, "GetRoleByID after delete", err)
+	requireNotFound(t, "DeleteRole twice", ds.DeleteRole(ctx, roleID))
+}
+
+func testDSLVersioning(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	cbuID := createCBU(t, ds)
+
+	_, err := ds.GetLatestDSL(ctx, cbuID)
+	requireNotFound(t, "GetLatestDSL without versions", err)
+
+	steps := []struct {
+		dsl   string
+		state store.OnboardingState
+	}{
+		{`(case.create (cbu.id "` + cbuID + `"))`, store.StateCreated},
+		{`(case.create (cbu.id "` + cbuID + `")) (products.add "CUSTODY")`, store.StateProductsAdded},
+		{`(case.create (cbu.id "` + cbuID + `")) (products.add "CUSTODY") (services.discover)`, store.StateServicesDiscovered},
+	}
+	var versionIDs []string
+	for _, step := range steps {
+		versionID, err := ds.InsertDSLWithState(ctx, cbuID, step.dsl, step.state)
+		if err != nil {
+			t.Fatalf("InsertDSLWithState(%s) failed: %v", step.state, err)
+		}
+		versionIDs = append(versionIDs, versionID)
+	}
+
+	history, err := ds.GetDSLHistoryWithState(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetDSLHistoryWithState failed: %v", err)
+	}
+	if len(history) != len(steps) {
+		t.Fatalf("GetDSLHistoryWithState returned %d versions, want %d", len(history), len(steps))
+	}
+	for i, v := range history {
+		if v.VersionID != versionIDs[i] || v.VersionNumber != i+1 || v.OnboardingState != steps[i].state || v.DSLText != steps[i].dsl {
+			t.Errorf("history[%d] = %+v; want version %d (%s) in state %s", i, v, i+1, versionIDs[i], steps[i].state)
+		}
+	}
+
+	plain, err := ds.GetDSLHistory(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetDSLHistory failed: %v", err)
+	}
+	if len(plain) != len(steps) || plain[0].VersionID != versionIDs[0] || plain[len(plain)-1].VersionID != versionIDs[len(versionIDs)-1] {
+		t.Errorf("GetDSLHistory is not oldest first: %+v", plain)
+	}
+
+	latestText, err := ds.GetLatestDSL(ctx, cbuID)
+	if err != nil || latestText != steps[2].dsl {
+		t.Errorf("GetLatestDSL = %q, %v; want the third version", latestText, err)
+	}
+	latest, err := ds.GetLatestDSLWithState(ctx, cbuID)
+	if err != nil || latest.VersionID != versionIDs[2] || latest.VersionNumber != 3 || latest.OnboardingState != store.StateServicesDiscovered {
+		t.Errorf("GetLatestDSLWithState = %+v, %v; want version 3", latest, err)
+	}
+
+	second, err := ds.GetDSLByVersion(ctx, cbuID, 2)
+	if err != nil || second.VersionID != versionIDs[1] {
+		t.Errorf("GetDSLByVersion(2) = %+v, %v; want %s", second, err, versionIDs[1])
+	}
+	_, err = ds.GetDSLByVersion(ctx, cbuID, 4)
+	requireNotFound(t, "GetDSLByVersion past the latest version", err)
+}
+
+func testStateTransitions(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	cbuID := createCBU(t, ds)
+
+	_, err := ds.GetOnboardingSession(ctx, cbuID)
+	requireNotFound(t, "GetOnboardingSession before create", err)
+	requireNotFound(t, "UpdateOnboardingState without a session",
+		ds.UpdateOnboardingState(ctx, cbuID, store.StateProductsAdded, uuid.New().String()))
+
+	session, err := ds.CreateOnboardingSession(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("CreateOnboardingSession failed: %v", err)
+	}
+	if session.CurrentState != store.StateCreated || session.CurrentVersion != 1 {
+		t.Errorf("new session = %+v; want CREATED at version 1", session)
+	}
+	if _, err := ds.CreateOnboardingSession(ctx, cbuID); err == nil {
+		t.Error("CreateOnboardingSession created a second session for the CBU")
+	}
+
+	if _, err := ds.InsertDSLWithState(ctx, cbuID, `(case.create)`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+	v2, err := ds.InsertDSLWithState(ctx, cbuID, `(case.create) (products.add "CUSTODY")`, store.StateProductsAdded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.UpdateOnboardingState(ctx, cbuID, store.StateProductsAdded, v2); err != nil {
+		t.Fatalf("UpdateOnboardingState failed: %v", err)
+	}
+	if err := ds.UpdateOnboardingState(ctx, cbuID, store.StateCompleted, v2); !errors.Is(err, store.ErrInvalidTransition) {
+		t.Errorf("PRODUCTS_ADDED -> COMPLETED: got %v; want ErrInvalidTransition", err)
+	}
+	if _, err := ds.InsertDSLWithState(ctx, cbuID, `(case.close)`, store.StateCompleted); !errors.Is(err, store.ErrInvalidTransition) {
+		t.Errorf("InsertDSLWithState PRODUCTS_ADDED -> COMPLETED: got %v; want ErrInvalidTransition", err)
+	}
+	history, err := ds.GetDSLHistoryWithState(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetDSLHistoryWithState failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Errorf("rejected insert left %d versions, want 2", len(history))
+	}
+
+	session, err = ds.GetOnboardingSession(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetOnboardingSession failed: %v", err)
+	}
+	if session.CurrentState != store.StateProductsAdded || session.CurrentVersion != 2 ||
+		session.LatestDSLVersionID == nil || *session.LatestDSLVersionID != v2 {
+		t.Errorf("after transition session = %+v; want PRODUCTS_ADDED at version 2 pointing at %s", session, v2)
+	}
+
+	events, err := ds.ListDSLChangeEvents(ctx, cbuID, 10)
+	if err != nil {
+		t.Fatalf("ListDSLChangeEvents failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("ListDSLChangeEvents returned %d events, want 3: %+v", len(events), events)
+	}
+	changed := events[0]
+	if changed.EventType != store.EventOnboardingStateChanged || changed.OldState != store.StateCreated ||
+		changed.NewState != store.StateProductsAdded || changed.VersionID != v2 || changed.VersionNumber != 2 {
+		t.Errorf("latest event = %+v; want the CREATED -> PRODUCTS_ADDED transition", changed)
+	}
+
+	sessions, err := ds.ListOnboardingSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListOnboardingSessions failed: %v", err)
+	}
+	found := false
+	for _, s := range sessions {
+		found = found || s.CBUID == cbuID
+	}
+	if !found {
+		t.Errorf("ListOnboardingSessions does not include %s", cbuID)
+	}
+}
+
+func testAttributeValues(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	attributes, err := ds.GetAllDictionaryAttributes(ctx)
+	if err != nil {
+		t.Fatalf("GetAllDictionaryAttributes failed: %v", err)
+	}
+	if len(attributes) == 0 {
+		t.Skip("the store has no dictionary attributes to hold values")
+	}
+	attributeID := attributes[0].AttributeID
+	cbuID := createCBU(t, ds)
+
+	upserts := []struct {
+		version int
+		value   string
+		state   string
+	}{
+		{1, `"LU"`, "pending"},
+		{2, `"IE"`, "resolved"},
+		{1, `"FR"`, "resolved"}, // replaces version 1; version 2 stays current
+	}
+	for _, u := range upserts {
+		source := map[string]any{"agent": "conformance", "version": float64(u.version)}
+		if err := ds.UpsertAttributeValue(ctx, cbuID, u.version, attributeID, json.RawMessage(u.value), u.state, source); err != nil {
+			t.Fatalf("UpsertAttributeValue(version %d) failed: %v", u.version, err)
+		}
+	}
+
+	value, source, state, err := ds.ResolveValueFor(ctx, cbuID, attributeID)
+	if err != nil {
+		t.Fatalf("ResolveValueFor failed: %v", err)
+	}
+	var got string
+	if err := json.Unmarshal(value, &got); err != nil || got != "IE" {
+		t.Errorf("ResolveValueFor value = %s; want the version 2 value \"IE\"", value)
+	}
+	if state != "resolved" || source["agent"] != "conformance" || source["version"] != float64(2) {
+		t.Errorf("ResolveValueFor state = %s, source = %v; want the version 2 provenance", state, source)
+	}
+}
+
+func testInvestorStates(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	cbuID := createCBU(t, ds)
+	investorID := uuid.NewString()
+
+	_, err := ds.GetInvestorState(ctx, cbuID, investorID)
+	requireNotFound(t, "GetInvestorState before save", err)
+
+	source := map[string]any{"type": "ir_plan", "step": float64(0)}
+	for _, status := range []string{"OPPORTUNITY", "KYC_PENDING"} {
+		state := json.RawMessage(`{"investor_id":"` + investorID + `","status":"` + status + `"}`)
+		if err := ds.SaveInvestorState(ctx, cbuID, investorID, state, source); err != nil {
+			t.Fatalf("SaveInvestorState(%s) failed: %v", status, err)
+		}
+	}
+
+	raw, err := ds.GetInvestorState(ctx, cbuID, investorID)
+	if err != nil {
+		t.Fatalf("GetInvestorState failed: %v", err)
+	}
+	var got struct {
+		InvestorID string `json:"investor_id"`
+		Status     string `json:"status"`
+	}
+	if err := json.Unmarshal(raw, &got); err != nil || got.InvestorID != investorID || got.Status != "KYC_PENDING" {
+		t.Errorf("GetInvestorState = %s; want the last saved state", raw)
+	}
+
+	_, err = ds.GetInvestorState(ctx, createCBU(t, ds), investorID)
+	requireNotFound(t, "GetInvestorState for another CBU", err)
+}
+
+func testOrchestrationSessions(t *testing.T, ds datastore.DataStore) {
+	ctx := context.Background()
+	cbuID := createCBU(t, ds)
+	sessionID := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := ds.LoadOrchestrationSession(ctx, sessionID)
+	requireNotFound(t, "LoadOrchestrationSession before save", err)
+	requireNotFound(t, "UpdateOrchestrationSessionDSL before save", ds.UpdateOrchestrationSessionDSL(ctx, sessionID, "(case.create)", 1))
+
+	session := &store.OrchestrationSessionData{
+		SessionID:     sessionID,
+		PrimaryDomain: "onboarding",
+		CBUID:         &cbuID,
+		Products:      []string{"CUSTODY"},
+		CurrentState:  "CREATED",
+		VersionNumber: 1,
+		SharedContext: map[string]interface{}{"jurisdiction": "LU"},
+		ExecutionPlan: map[string]interface{}{},
+		EntityRefs:    map[string]string{},
+		AttributeRefs: map[string]string{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		LastUsed:      now,
+	}
+	if err := ds.SaveOrchestrationSession(ctx, session); err != nil {
+		t.Fatalf("SaveOrchestrationSession failed: %v", err)
+	}
+	t.Cleanup(func() { _ = ds.DeleteOrchestrationSession(context.Background(), sessionID) })
+
+	// Changes to the caller's copy after saving are not persisted
+	session.SharedContext["jurisdiction"] = "IE"
+
+	if err := ds.UpdateOrchestrationSessionDSL(ctx, sessionID, "(case.create) (products.add \"CUSTODY\")", 2); err != nil {
+		t.Fatalf("UpdateOrchestrationSessionDSL failed: %v", err)
+	}
+	loaded, err := ds.LoadOrchestrationSession(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("LoadOrchestrationSession failed: %v", err)
+	}
+	if loaded.PrimaryDomain != "onboarding" || loaded.CBUID == nil || *loaded.CBUID != cbuID ||
+		len(loaded.Products) != 1 || loaded.SharedContext["jurisdiction"] != "LU" {
+		t.Errorf("loaded session = %+v; want the saved session", loaded)
+	}
+	if loaded.VersionNumber != 2 || loaded.UnifiedDSL != "(case.create) (products.add \"CUSTODY\")" {
+		t.Errorf("loaded session DSL = %q at version %d; want the updated DSL at version 2", loaded.UnifiedDSL, loaded.VersionNumber)
+	}
+
+	active, err := ds.ListActiveOrchestrationSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListActiveOrchestrationSessions failed: %v", err)
+	}
+	found := false
+	for _, id := range active {
+		found = found || id == sessionID
+	}
+	if !found {
+		t.Errorf("ListActiveOrchestrationSessions does not include %s", sessionID)
+	}
+
+	if err := ds.DeleteOrchestrationSession(ctx, sessionID); err != nil {
+		t.Fatalf("DeleteOrchestrationSession failed: %v", err)
+	}
+	_, err = ds.LoadOrchestrationSession(ctx, sessionID)
+	requireNotFound(t, "LoadOrchestrationSession after delete", err)
+	requireNotFound(t, "DeleteOrchestrationSession twice", ds.DeleteOrchestrationSession(ctx, sessionID))
+}
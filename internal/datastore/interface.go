@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	"dsl-ob-poc/internal/dictionary"
+	"dsl-ob-poc/internal/entities"
+	"dsl-ob-poc/internal/memstore"
 	"dsl-ob-poc/internal/mocks"
 	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
 )
 
 // DataStore defines the interface for all data access operations
@@ -45,10 +51,12 @@ type DataStore interface {
 	GetDictionaryAttributeByName(ctx context.Context, name string) (*dictionary.Attribute, error)
 	GetDictionaryAttributeByID(ctx context.Context, id string) (*dictionary.Attribute, error)
 	GetAttributesForDictionaryGroup(ctx context.Context, groupID string) ([]dictionary.Attribute, error)
+	UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error
 
 	// DSL Operations
 	GetLatestDSL(ctx context.Context, cbuID string) (string, error)
 	InsertDSL(ctx context.Context, cbuID, dslText string) (string, error)
+	InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error)
 	GetDSLHistory(ctx context.Context, cbuID string) ([]store.DSLVersion, error)
 
 	// Enhanced Onboarding State Management
@@ -56,6 +64,7 @@ type DataStore interface {
 	GetOnboardingSession(ctx context.Context, cbuID string) (*store.OnboardingSession, error)
 	UpdateOnboardingState(ctx context.Context, cbuID string, newState store.OnboardingState, dslVersionID string) error
 	InsertDSLWithState(ctx context.Context, cbuID, dslText string, state store.OnboardingState) (string, error)
+	InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error)
 	GetLatestDSLWithState(ctx context.Context, cbuID string) (*store.DSLVersionWithState, error)
 	GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]store.DSLVersionWithState, error)
 	GetDSLByVersion(ctx context.Context, cbuID string, versionNumber int) (*store.DSLVersionWithState, error)
@@ -65,12 +74,20 @@ type DataStore interface {
 	ResolveValueFor(ctx context.Context, cbuID, attributeID string) (json.RawMessage, map[string]any, string, error)
 	UpsertAttributeValue(ctx context.Context, cbuID string, dslVersion int, attributeID string, value json.RawMessage, state string, source map[string]any) error
 
+	// IR plan investor state (run-plan)
+	GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error)
+	SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error
+
 	// Export Operations (for mock data generation)
 	GetAllProducts(ctx context.Context) ([]store.Product, error)
 	GetAllServices(ctx context.Context) ([]store.Service, error)
 	GetAllDictionaryAttributes(ctx context.Context) ([]dictionary.Attribute, error)
 	GetAllDSLRecords(ctx context.Context) ([]store.DSLVersionWithState, error)
 
+	// Ownership Graph Operations (UBO calculation)
+	LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error)
+	SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error
+
 	// Product Requirements Operations (Phase 5)
 	GetProductRequirements(ctx context.Context, productID string) (*store.ProductRequirements, error)
 	GetEntityProductMapping(ctx context.Context, entityType, productID string) (*store.EntityProductMapping, error)
@@ -91,6 +108,27 @@ type DataStore interface {
 	DeleteOrchestrationSession(ctx context.Context, sessionID string) error
 	CleanupExpiredOrchestrationSessions(ctx context.Context) (int64, error)
 	UpdateOrchestrationSessionDSL(ctx context.Context, sessionID, dsl string, version int) error
+
+	// Runtime job queue: leased, retried and dead-lettered action executions
+	EnqueueJob(ctx context.Context, job *store.ActionJob) (string, error)
+	LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error)
+	HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error
+	CompleteJob(ctx context.Context, jobID, owner, executionID string) error
+	RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error
+	DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error
+	RequeueJob(ctx context.Context, jobID string) error
+	ListJobs(ctx context.Context, status store.JobStatus) ([]store.ActionJob, error)
+
+	// DSL change outbox and webhook deliveries
+	ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]store.DSLChangeEvent, error)
+	CreateWebhookSubscription(ctx context.Context, sub *store.WebhookSubscription) (string, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]store.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error
+	LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error
+	RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error
+	DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error
+	ListWebhookDeliveries(ctx context.Context, status store.DeliveryStatus) ([]store.WebhookDelivery, error)
 }
 
 // Phase 5 Product Requirements Types are now defined in the store package
@@ -104,6 +142,8 @@ const (
 	PostgreSQLStore Type = "postgresql"
 	// MockStore uses JSON mock data
 	MockStore Type = "mock"
+	// MemoryStore keeps every write in memory, seeded from the mock data
+	MemoryStore Type = "memory"
 )
 
 // Config holds configuration for data store creation
@@ -111,18 +151,31 @@ type Config struct {
 	Type             Type
 	ConnectionString string
 	MockDataPath     string
+	// SnapshotPath, for MemoryStore, is loaded at start if it exists (instead
+	// of the mock data) and written on Close
+	SnapshotPath string
+	// Validator, when set, checks every DSL version before it is persisted
+	Validator DSLValidator
 }
 
 // NewDataStore creates a new data store based on configuration
 func NewDataStore(config Config) (DataStore, error) {
+	var ds DataStore
+	var err error
 	switch config.Type {
 	case PostgreSQLStore:
-		return newPostgreSQLStore(config.ConnectionString)
+		ds, err = newPostgreSQLStore(config.ConnectionString)
 	case MockStore:
-		return newMockStore(config.MockDataPath)
+		ds, err = newMockStore(config.MockDataPath)
+	case MemoryStore:
+		ds, err = newMemoryStore(config.MockDataPath, config.SnapshotPath)
 	default:
 		return nil, &UnsupportedStoreTypeError{Type: string(config.Type)}
 	}
+	if err != nil || config.Validator == nil {
+		return ds, err
+	}
+	return WithDSLValidator(ds, config.Validator), nil
 }
 
 // newPostgreSQLStore creates a new PostgreSQL store adapter
@@ -140,6 +193,34 @@ func newMockStore(mockDataPath string) (DataStore, error) {
 	return &mockAdapter{store: mockStore}, nil
 }
 
+// newMemoryStore restores a memory store from snapshotPath if it exists,
+// otherwise seeds it from mockDataPath if that directory exists, otherwise
+// starts empty
+func newMemoryStore(mockDataPath, snapshotPath string) (DataStore, error) {
+	var mem *memstore.Store
+	var err error
+	switch {
+	case snapshotPath != "" && fileExists(snapshotPath):
+		mem, err = memstore.LoadSnapshot(snapshotPath)
+	case mockDataPath != "" && fileExists(mockDataPath):
+		mem, err = memstore.NewFromMockData(mockDataPath)
+	default:
+		mem = memstore.New()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if snapshotPath != "" {
+		mem.SaveSnapshotOnClose(snapshotPath)
+	}
+	return mem, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // UnsupportedStoreTypeError is returned when an unsupported store type is requested
 type UnsupportedStoreTypeError struct {
 	Type string
@@ -230,6 +311,10 @@ func (p *postgresAdapter) GetAttributesForDictionaryGroup(ctx context.Context, g
 	return p.store.GetAttributesForDictionaryGroup(ctx, groupID)
 }
 
+func (p *postgresAdapter) UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error {
+	return p.store.UpdateDictionaryAttributeVector(ctx, attributeID, vector)
+}
+
 func (p *postgresAdapter) GetLatestDSL(ctx context.Context, cbuID string) (string, error) {
 	return p.store.GetLatestDSL(ctx, cbuID)
 }
@@ -238,6 +323,10 @@ func (p *postgresAdapter) InsertDSL(ctx context.Context, cbuID, dslText string)
 	return p.store.InsertDSL(ctx, cbuID, dslText)
 }
 
+func (p *postgresAdapter) InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error) {
+	return p.store.InsertPlainDSL(ctx, cbuID, dslText, grammarVersion)
+}
+
 func (p *postgresAdapter) GetDSLHistory(ctx context.Context, cbuID string) ([]store.DSLVersion, error) {
 	return p.store.GetDSLHistory(ctx, cbuID)
 }
@@ -259,6 +348,10 @@ func (p *postgresAdapter) InsertDSLWithState(ctx context.Context, cbuID, dslText
 	return p.store.InsertDSLWithState(ctx, cbuID, dslText, state)
 }
 
+func (p *postgresAdapter) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	return p.store.InsertDSLWithGrammar(ctx, cbuID, dslText, state, grammarVersion)
+}
+
 func (p *postgresAdapter) GetLatestDSLWithState(ctx context.Context, cbuID string) (*store.DSLVersionWithState, error) {
 	return p.store.GetLatestDSLWithState(ctx, cbuID)
 }
@@ -283,6 +376,22 @@ func (p *postgresAdapter) UpsertAttributeValue(ctx context.Context, cbuID string
 	return p.store.UpsertAttributeValue(ctx, cbuID, dslVersion, attributeID, value, state, source)
 }
 
+func (p *postgresAdapter) GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	return p.store.GetInvestorState(ctx, cbuID, investorID)
+}
+
+func (p *postgresAdapter) SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error {
+	return p.store.SaveInvestorState(ctx, cbuID, investorID, state, source)
+}
+
+func (p *postgresAdapter) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	return p.store.LoadOwnershipGraph(ctx, cbuID)
+}
+
+func (p *postgresAdapter) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	return p.store.SaveCalculatedUBOs(ctx, cbuID, subjectEntityID, entries)
+}
+
 func (p *postgresAdapter) SeedCatalog(ctx context.Context) error {
 	return p.store.SeedCatalog(ctx)
 }
@@ -344,6 +453,74 @@ func (p *postgresAdapter) UpdateOrchestrationSessionDSL(ctx context.Context, ses
 	return p.store.UpdateOrchestrationSessionDSL(ctx, sessionID, dsl, version)
 }
 
+func (p *postgresAdapter) EnqueueJob(ctx context.Context, job *store.ActionJob) (string, error) {
+	return p.store.EnqueueJob(ctx, job)
+}
+
+func (p *postgresAdapter) LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error) {
+	return p.store.LeaseJob(ctx, owner, lease)
+}
+
+func (p *postgresAdapter) HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error {
+	return p.store.HeartbeatJob(ctx, jobID, owner, lease)
+}
+
+func (p *postgresAdapter) CompleteJob(ctx context.Context, jobID, owner, executionID string) error {
+	return p.store.CompleteJob(ctx, jobID, owner, executionID)
+}
+
+func (p *postgresAdapter) RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error {
+	return p.store.RetryJob(ctx, jobID, owner, runAt, lastError)
+}
+
+func (p *postgresAdapter) DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error {
+	return p.store.DeadLetterJob(ctx, jobID, owner, lastError)
+}
+
+func (p *postgresAdapter) RequeueJob(ctx context.Context, jobID string) error {
+	return p.store.RequeueJob(ctx, jobID)
+}
+
+func (p *postgresAdapter) ListJobs(ctx context.Context, status store.JobStatus) ([]store.ActionJob, error) {
+	return p.store.ListJobs(ctx, status)
+}
+
+func (p *postgresAdapter) ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]store.DSLChangeEvent, error) {
+	return p.store.ListDSLChangeEvents(ctx, cbuID, limit)
+}
+
+func (p *postgresAdapter) CreateWebhookSubscription(ctx context.Context, sub *store.WebhookSubscription) (string, error) {
+	return p.store.CreateWebhookSubscription(ctx, sub)
+}
+
+func (p *postgresAdapter) ListWebhookSubscriptions(ctx context.Context) ([]store.WebhookSubscription, error) {
+	return p.store.ListWebhookSubscriptions(ctx)
+}
+
+func (p *postgresAdapter) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	return p.store.DeleteWebhookSubscription(ctx, subscriptionID)
+}
+
+func (p *postgresAdapter) LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error) {
+	return p.store.LeaseWebhookDelivery(ctx, owner, lease)
+}
+
+func (p *postgresAdapter) CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error {
+	return p.store.CompleteWebhookDelivery(ctx, deliveryID, owner)
+}
+
+func (p *postgresAdapter) RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error {
+	return p.store.RetryWebhookDelivery(ctx, deliveryID, owner, nextAttemptAt, lastError)
+}
+
+func (p *postgresAdapter) DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error {
+	return p.store.DeadLetterWebhookDelivery(ctx, deliveryID, owner, lastError)
+}
+
+func (p *postgresAdapter) ListWebhookDeliveries(ctx context.Context, status store.DeliveryStatus) ([]store.WebhookDelivery, error) {
+	return p.store.ListWebhookDeliveries(ctx, status)
+}
+
 // Export Operations for postgres adapter
 func (p *postgresAdapter) GetAllProducts(ctx context.Context) ([]store.Product, error) {
 	return p.store.GetAllProducts(ctx)
@@ -442,6 +619,10 @@ func (m *mockAdapter) GetAttributesForDictionaryGroup(ctx context.Context, group
 	return m.store.GetAttributesForDictionaryGroup(ctx, groupID)
 }
 
+func (m *mockAdapter) UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error {
+	return m.store.UpdateDictionaryAttributeVector(ctx, attributeID, vector)
+}
+
 func (m *mockAdapter) GetLatestDSL(ctx context.Context, cbuID string) (string, error) {
 	return m.store.GetLatestDSL(ctx, cbuID)
 }
@@ -450,6 +631,10 @@ func (m *mockAdapter) InsertDSL(ctx context.Context, cbuID, dslText string) (str
 	return m.store.InsertDSL(ctx, cbuID, dslText)
 }
 
+func (m *mockAdapter) InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error) {
+	return m.store.InsertPlainDSL(ctx, cbuID, dslText, grammarVersion)
+}
+
 func (m *mockAdapter) GetDSLHistory(ctx context.Context, cbuID string) ([]store.DSLVersion, error) {
 	return m.store.GetDSLHistory(ctx, cbuID)
 }
@@ -462,6 +647,22 @@ func (m *mockAdapter) UpsertAttributeValue(ctx context.Context, cbuID string, ds
 	return m.store.UpsertAttributeValue(ctx, cbuID, dslVersion, attributeID, value, state, source)
 }
 
+func (m *mockAdapter) GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	return m.store.GetInvestorState(ctx, cbuID, investorID)
+}
+
+func (m *mockAdapter) SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error {
+	return m.store.SaveInvestorState(ctx, cbuID, investorID, state, source)
+}
+
+func (m *mockAdapter) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	return m.store.LoadOwnershipGraph(ctx, cbuID)
+}
+
+func (m *mockAdapter) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	return m.store.SaveCalculatedUBOs(ctx, cbuID, subjectEntityID, entries)
+}
+
 func (m *mockAdapter) SeedCatalog(ctx context.Context) error {
 	return nil // Mock store doesn't need seeding
 }
@@ -516,6 +717,10 @@ func (m *mockAdapter) InsertDSLWithState(ctx context.Context, cbuID, dslText str
 	return m.store.InsertDSLWithState(ctx, cbuID, dslText, state)
 }
 
+func (m *mockAdapter) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	return m.store.InsertDSLWithGrammar(ctx, cbuID, dslText, state, grammarVersion)
+}
+
 func (m *mockAdapter) GetLatestDSLWithState(ctx context.Context, cbuID string) (*store.DSLVersionWithState, error) {
 	return m.store.GetLatestDSLWithState(ctx, cbuID)
 }
@@ -572,3 +777,71 @@ func (m *mockAdapter) CleanupExpiredOrchestrationSessions(ctx context.Context) (
 func (m *mockAdapter) UpdateOrchestrationSessionDSL(ctx context.Context, sessionID, dsl string, version int) error {
 	return m.store.UpdateOrchestrationSessionDSL(ctx, sessionID, dsl, version)
 }
+
+func (m *mockAdapter) EnqueueJob(ctx context.Context, job *store.ActionJob) (string, error) {
+	return m.store.EnqueueJob(ctx, job)
+}
+
+func (m *mockAdapter) LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error) {
+	return m.store.LeaseJob(ctx, owner, lease)
+}
+
+func (m *mockAdapter) HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error {
+	return m.store.HeartbeatJob(ctx, jobID, owner, lease)
+}
+
+func (m *mockAdapter) CompleteJob(ctx context.Context, jobID, owner, executionID string) error {
+	return m.store.CompleteJob(ctx, jobID, owner, executionID)
+}
+
+func (m *mockAdapter) RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error {
+	return m.store.RetryJob(ctx, jobID, owner, runAt, lastError)
+}
+
+func (m *mockAdapter) DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error {
+	return m.store.DeadLetterJob(ctx, jobID, owner, lastError)
+}
+
+func (m *mockAdapter) RequeueJob(ctx context.Context, jobID string) error {
+	return m.store.RequeueJob(ctx, jobID)
+}
+
+func (m *mockAdapter) ListJobs(ctx context.Context, status store.JobStatus) ([]store.ActionJob, error) {
+	return m.store.ListJobs(ctx, status)
+}
+
+func (m *mockAdapter) ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]store.DSLChangeEvent, error) {
+	return m.store.ListDSLChangeEvents(ctx, cbuID, limit)
+}
+
+func (m *mockAdapter) CreateWebhookSubscription(ctx context.Context, sub *store.WebhookSubscription) (string, error) {
+	return m.store.CreateWebhookSubscription(ctx, sub)
+}
+
+func (m *mockAdapter) ListWebhookSubscriptions(ctx context.Context) ([]store.WebhookSubscription, error) {
+	return m.store.ListWebhookSubscriptions(ctx)
+}
+
+func (m *mockAdapter) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	return m.store.DeleteWebhookSubscription(ctx, subscriptionID)
+}
+
+func (m *mockAdapter) LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error) {
+	return m.store.LeaseWebhookDelivery(ctx, owner, lease)
+}
+
+func (m *mockAdapter) CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error {
+	return m.store.CompleteWebhookDelivery(ctx, deliveryID, owner)
+}
+
+func (m *mockAdapter) RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error {
+	return m.store.RetryWebhookDelivery(ctx, deliveryID, owner, nextAttemptAt, lastError)
+}
+
+func (m *mockAdapter) DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error {
+	return m.store.DeadLetterWebhookDelivery(ctx, deliveryID, owner, lastError)
+}
+
+func (m *mockAdapter) ListWebhookDeliveries(ctx context.Context, status store.DeliveryStatus) ([]store.WebhookDelivery, error) {
+	return m.store.ListWebhookDeliveries(ctx, status)
+}
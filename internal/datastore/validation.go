@@ -0,0 +1,62 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+
+	"dsl-ob-poc/internal/store"
+)
+
+// DSLValidator checks a DSL document before it is persisted and returns the
+// version of the grammar that accepted it
+type DSLValidator interface {
+	ValidateDSL(ctx context.Context, dslText string) (grammarVersion string, err error)
+}
+
+// validatingStore rejects DSL writes that fail validation and records the
+// grammar version alongside each accepted version
+type validatingStore struct {
+	DataStore
+	validator DSLValidator
+}
+
+// WithDSLValidator wraps ds so that every DSL write is validated by v first
+func WithDSLValidator(ds DataStore, v DSLValidator) DataStore {
+	return &validatingStore{DataStore: ds, validator: v}
+}
+
+func (s *validatingStore) InsertDSL(ctx context.Context, cbuID, dslText string) (string, error) {
+	return s.InsertPlainDSL(ctx, cbuID, dslText, "")
+}
+
+// InsertPlainDSL validates dslText and persists it without an onboarding
+// state, like InsertDSL, together with the validating grammar's version
+func (s *validatingStore) InsertPlainDSL(ctx context.Context, cbuID, dslText, _ string) (string, error) {
+	grammarVersion, err := s.validate(ctx, cbuID, dslText)
+	if err != nil {
+		return "", err
+	}
+	return s.DataStore.InsertPlainDSL(ctx, cbuID, dslText, grammarVersion)
+}
+
+func (s *validatingStore) InsertDSLWithState(ctx context.Context, cbuID, dslText string, state store.OnboardingState) (string, error) {
+	return s.InsertDSLWithGrammar(ctx, cbuID, dslText, state, "")
+}
+
+// InsertDSLWithGrammar validates dslText and persists it with the validating
+// grammar's version; any caller-supplied version is replaced
+func (s *validatingStore) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, _ string) (string, error) {
+	grammarVersion, err := s.validate(ctx, cbuID, dslText)
+	if err != nil {
+		return "", err
+	}
+	return s.DataStore.InsertDSLWithGrammar(ctx, cbuID, dslText, state, grammarVersion)
+}
+
+func (s *validatingStore) validate(ctx context.Context, cbuID, dslText string) (string, error) {
+	grammarVersion, err := s.validator.ValidateDSL(ctx, dslText)
+	if err != nil {
+		return "", fmt.Errorf("DSL for CBU %s rejected: %w", cbuID, err)
+	}
+	return grammarVersion, nil
+}
@@ -0,0 +1,170 @@
+package dictionary
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns text into a fixed-length vector. Implementations must be
+// deterministic for a given Model() so persisted vectors stay comparable.
+type Embedder interface {
+	// Model identifies the embedding model and its parameters
+	Model() string
+
+	// Dimensions is the length of every vector returned by Embed
+	Dimensions() int
+
+	// Embed returns the raw (term-frequency) vector for text
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// DefaultEmbeddingDimensions is the vector length used by NewVectorService
+const DefaultEmbeddingDimensions = 512
+
+// NGramEmbedder is a local embedder that hashes word tokens and character
+// trigrams into a fixed number of buckets (the "hashing trick"). Word tokens
+// capture vocabulary, trigrams tolerate morphology and naming styles such as
+// "legal-name" vs "LegalName". IDF weighting is applied by the VectorIndex.
+type NGramEmbedder struct {
+	dims int
+}
+
+// NewNGramEmbedder creates an n-gram embedder with the given number of buckets
+func NewNGramEmbedder(dims int) *NGramEmbedder {
+	if dims <= 0 {
+		dims = DefaultEmbeddingDimensions
+	}
+	return &NGramEmbedder{dims: dims}
+}
+
+func (e *NGramEmbedder) Model() string {
+	return fmt.Sprintf("ngram-tfidf-%d", e.dims)
+}
+
+func (e *NGramEmbedder) Dimensions() int {
+	return e.dims
+}
+
+func (e *NGramEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	vec := make([]float32, e.dims)
+	for _, token := range tokenize(text) {
+		e.add(vec, "w:"+token, 2.0)
+		padded := "^" + token + "$"
+		runes := []rune(padded)
+		for i := 0; i+3 <= len(runes); i++ {
+			e.add(vec, "c:"+string(runes[i:i+3]), 1.0)
+		}
+	}
+
+	// Sublinear term frequency keeps repeated words from dominating
+	for i, v := range vec {
+		if v > 0 {
+			vec[i] = 1 + float32(math.Log(float64(v)))
+		}
+	}
+	return vec, nil
+}
+
+func (e *NGramEmbedder) add(vec []float32, feature string, weight float32) {
+	h := fnv.New32a()
+	h.Write([]byte(feature))
+	vec[h.Sum32()%uint32(e.dims)] += weight
+}
+
+// tokenize lowercases text and splits it into words, also splitting
+// camelCase, snake_case, kebab-case and dotted identifiers.
+func tokenize(text string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+				flush()
+			}
+			current = append(current, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// ---------- Vector math and encoding ----------
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0 when
+// either vector is zero or their lengths differ.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func normalize(vec []float32) {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vec {
+		vec[i] /= norm
+	}
+}
+
+// EncodeVector serialises a vector for the dictionary.vector column as
+// "<model>:<base64 little-endian float32s>".
+func EncodeVector(model string, vec []float32) string {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return model + ":" + base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodeVector parses a value written by EncodeVector
+func DecodeVector(s string) (model string, vec []float32, err error) {
+	idx := strings.LastIndex(s, ":")
+	if idx <= 0 {
+		return "", nil, fmt.Errorf("vector has no model prefix")
+	}
+	buf, err := base64.StdEncoding.DecodeString(s[idx+1:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid vector encoding: %w", err)
+	}
+	if len(buf)%4 != 0 {
+		return "", nil, fmt.Errorf("invalid vector length %d", len(buf))
+	}
+	vec = make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return s[:idx], vec, nil
+}
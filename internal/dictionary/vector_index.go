@@ -0,0 +1,167 @@
+package dictionary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// VectorIndex is a brute-force nearest-neighbour index over attribute vectors.
+// Stored vectors are IDF-weighted and L2-normalised, so cosine similarity is a
+// dot product. The dictionary is small enough that exact search is fast.
+type VectorIndex struct {
+	Model      string        `json:"model"`
+	Dimensions int           `json:"dimensions"`
+	IDF        []float32     `json:"idf"`
+	BuiltAt    time.Time     `json:"built_at"`
+	Entries    []VectorEntry `json:"entries"`
+}
+
+// VectorEntry is one indexed attribute
+type VectorEntry struct {
+	AttributeID string    `json:"attribute_id"`
+	Name        string    `json:"name"`
+	Vector      []float32 `json:"vector"`
+}
+
+// SearchHit is a search result ordered by descending score
+type SearchHit struct {
+	AttributeID string  `json:"attribute_id"`
+	Name        string  `json:"name"`
+	Score       float64 `json:"score"`
+}
+
+// BuildVectorIndex embeds every attribute and derives IDF weights from the
+// resulting document frequencies.
+func BuildVectorIndex(ctx context.Context, embedder Embedder, attrs []Attribute) (*VectorIndex, error) {
+	idx := &VectorIndex{
+		Model:      embedder.Model(),
+		Dimensions: embedder.Dimensions(),
+		BuiltAt:    time.Now().UTC(),
+	}
+
+	raw := make([][]float32, len(attrs))
+	docFreq := make([]int, idx.Dimensions)
+	for i := range attrs {
+		vec, err := embedder.Embed(ctx, buildSearchText(&attrs[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed attribute %s: %w", attrs[i].AttributeID, err)
+		}
+		if len(vec) != idx.Dimensions {
+			return nil, fmt.Errorf("embedder returned %d dimensions, expected %d", len(vec), idx.Dimensions)
+		}
+		for d, v := range vec {
+			if v > 0 {
+				docFreq[d]++
+			}
+		}
+		raw[i] = vec
+	}
+
+	// Smoothed IDF: ln((1+N)/(1+df)) + 1
+	n := float64(len(attrs))
+	idx.IDF = make([]float32, idx.Dimensions)
+	for d, df := range docFreq {
+		idx.IDF[d] = float32(math.Log((1+n)/(1+float64(df))) + 1)
+	}
+
+	for i := range attrs {
+		idx.Entries = append(idx.Entries, VectorEntry{
+			AttributeID: attrs[i].AttributeID,
+			Name:        attrs[i].Name,
+			Vector:      idx.weight(raw[i]),
+		})
+	}
+	return idx, nil
+}
+
+// weight applies IDF and normalises a raw embedder vector in place
+func (idx *VectorIndex) weight(vec []float32) []float32 {
+	for d := range vec {
+		vec[d] *= idx.IDF[d]
+	}
+	normalize(vec)
+	return vec
+}
+
+// Search embeds query with embedder and returns the top-k entries by cosine similarity
+func (idx *VectorIndex) Search(ctx context.Context, embedder Embedder, query string, k int) ([]SearchHit, error) {
+	if embedder.Model() != idx.Model {
+		return nil, fmt.Errorf("index was built with model %s, not %s; run regenerate-vectors", idx.Model, embedder.Model())
+	}
+	vec, err := embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return idx.Nearest(idx.weight(vec), k), nil
+}
+
+// Nearest returns the top-k entries for an already weighted query vector.
+// Entries with zero similarity are not returned.
+func (idx *VectorIndex) Nearest(query []float32, k int) []SearchHit {
+	hits := make([]SearchHit, 0, len(idx.Entries))
+	for _, e := range idx.Entries {
+		score := CosineSimilarity(query, e.Vector)
+		if score > 0 {
+			hits = append(hits, SearchHit{AttributeID: e.AttributeID, Name: e.Name, Score: score})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].AttributeID < hits[j].AttributeID
+	})
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Entry returns the indexed entry for attributeID, if present
+func (idx *VectorIndex) Entry(attributeID string) (*VectorEntry, bool) {
+	for i := range idx.Entries {
+		if idx.Entries[i].AttributeID == attributeID {
+			return &idx.Entries[i], true
+		}
+	}
+	return nil, false
+}
+
+// SaveVectorIndex writes the index as JSON, creating parent directories
+func SaveVectorIndex(idx *VectorIndex, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to encode vector index: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write vector index: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadVectorIndex reads an index written by SaveVectorIndex
+func LoadVectorIndex(path string) (*VectorIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idx VectorIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse vector index %s: %w", path, err)
+	}
+	if len(idx.IDF) != idx.Dimensions {
+		return nil, fmt.Errorf("vector index %s is corrupt: %d IDF weights for %d dimensions", path, len(idx.IDF), idx.Dimensions)
+	}
+	return &idx, nil
+}
@@ -2,97 +2,173 @@ package dictionary
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"strings"
+	"time"
 )
 
 // DictionaryRepository defines the interface needed by VectorService
 type DictionaryRepository interface {
 	GetDictionaryAttributeByID(ctx context.Context, id string) (*Attribute, error)
 	GetAllDictionaryAttributes(ctx context.Context) ([]Attribute, error)
+	UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error
 }
 
+// DefaultVectorIndexPath is where regenerate-vectors persists the search index.
+//
+// The index is a local file and is not stored in the DataStore; only the
+// per-attribute vectors are written back to the dictionary. Another machine, or
+// a dictionary changed since the last regeneration, sees no index or a stale one.
+// Without an index file Search builds one in memory from the dictionary, so a
+// missing file costs a rebuild per search rather than wrong results; run
+// regenerate-vectors after dictionary changes to refresh a stale one.
+const DefaultVectorIndexPath = "data/vector_index.json"
+
 // VectorService provides vector generation and management for dictionary attributes
 // This enables AI-based semantic search and discovery of attributes
 type VectorService struct {
-	// In a real implementation, this would integrate with embedding services like:
-	// - OpenAI Embeddings API
-	// - Sentence Transformers
-	// - Custom embedding models
-	// For now, we'll use a simple hash-based approach for demonstration
+	embedder  Embedder
+	indexPath string
 }
 
-// NewVectorService creates a new vector service
-func NewVectorService() *VectorService {
-	return &VectorService{}
+// VectorOption configures a VectorService
+type VectorOption func(*VectorService)
+
+// WithEmbedder replaces the default local n-gram embedder
+func WithEmbedder(embedder Embedder) VectorOption {
+	return func(v *VectorService) {
+		v.embedder = embedder
+	}
 }
 
-// GenerateVector creates a vector representation for an attribute
-// In production, this would call an embedding service
-func (v *VectorService) GenerateVector(ctx context.Context, attr *Attribute) (string, error) {
-	// Create a text representation combining all searchable fields
-	searchText := v.buildSearchText(attr)
+// WithIndexPath sets where the vector index is persisted
+func WithIndexPath(path string) VectorOption {
+	return func(v *VectorService) {
+		v.indexPath = path
+	}
+}
+
+// NewVectorService creates a new vector service
+func NewVectorService(opts ...VectorOption) *VectorService {
+	v := &VectorService{
+		embedder:  NewNGramEmbedder(DefaultEmbeddingDimensions),
+		indexPath: DefaultVectorIndexPath,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
 
-	// For now, use a deterministic hash as a placeholder for actual embeddings
-	// In production, replace this with actual embedding API calls
-	vector := v.generateHashVector(searchText)
+// Embedder returns the embedder used by the service
+func (v *VectorService) Embedder() Embedder {
+	return v.embedder
+}
 
-	return vector, nil
+// GenerateVector creates an encoded, normalised vector for an attribute. It has
+// no corpus IDF weighting; RegenerateAllVectors stores IDF-weighted vectors.
+func (v *VectorService) GenerateVector(ctx context.Context, attr *Attribute) (string, error) {
+	return v.GenerateVectorForText(ctx, buildSearchText(attr))
 }
 
-// GenerateVectorForText creates a vector for arbitrary text (for search queries)
+// GenerateVectorForText creates an encoded vector for arbitrary text (for search queries)
 func (v *VectorService) GenerateVectorForText(ctx context.Context, text string) (string, error) {
-	return v.generateHashVector(text), nil
+	vec, err := v.embedder.Embed(ctx, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to embed text: %w", err)
+	}
+	normalize(vec)
+	return EncodeVector(v.embedder.Model(), vec), nil
 }
 
-// RegenerateAllVectors regenerates vectors for all attributes
+// RegenerateAllVectors rebuilds the index from every dictionary attribute,
+// persists it and writes each attribute's vector back to the repository.
 func (v *VectorService) RegenerateAllVectors(ctx context.Context, repo DictionaryRepository) error {
-	// This would be the function to call when regenerating the vector database
-
-	// Get all attributes (you'd need to implement this in your repository)
-	// For now, this is a placeholder implementation
+	attrs, err := repo.GetAllDictionaryAttributes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary attributes: %w", err)
+	}
 
-	fmt.Println("🔄 Starting vector regeneration for all dictionary attributes...")
+	fmt.Printf("🔄 Embedding %d attributes with %s...\n", len(attrs), v.embedder.Model())
 
-	// In a real implementation:
-	// 1. Fetch all attributes from the dictionary table
-	// 2. Generate vectors for each attribute
-	// 3. Update the vector field in the database
-	// 4. Handle batch processing for large datasets
+	idx, err := BuildVectorIndex(ctx, v.embedder, attrs)
+	if err != nil {
+		return err
+	}
+	if err := SaveVectorIndex(idx, v.indexPath); err != nil {
+		return err
+	}
+	fmt.Printf("💾 Saved vector index to %s\n", v.indexPath)
 
-	fmt.Println("⚡ Note: This is a placeholder implementation.")
-	fmt.Println("   In production, this would:")
-	fmt.Println("   1. Fetch all attributes from dictionary table")
-	fmt.Println("   2. Call embedding service (OpenAI, etc.) for each attribute")
-	fmt.Println("   3. Update vector field in database")
-	fmt.Println("   4. Handle rate limiting and batch processing")
+	for i, entry := range idx.Entries {
+		if err := repo.UpdateDictionaryAttributeVector(ctx, entry.AttributeID, EncodeVector(idx.Model, entry.Vector)); err != nil {
+			return fmt.Errorf("failed to store vector for attribute %s: %w", entry.AttributeID, err)
+		}
+		if (i+1)%50 == 0 || i == len(idx.Entries)-1 {
+			fmt.Printf("📊 Progress: %d/%d attributes processed\n", i+1, len(idx.Entries))
+		}
+	}
 
 	return nil
 }
 
-// SearchSimilarAttributes finds attributes similar to the given text
-func (v *VectorService) SearchSimilarAttributes(ctx context.Context, repo DictionaryRepository, searchText string, limit int) ([]*Attribute, error) {
-	// Generate vector for search text
-	searchVector, err := v.GenerateVectorForText(ctx, searchText)
+// Search returns the top attributes for searchText with their similarity scores.
+// When no index has been persisted yet, one is built in memory from repo.
+func (v *VectorService) Search(ctx context.Context, repo DictionaryRepository, searchText string, limit int) ([]SearchHit, error) {
+	idx, err := v.loadIndex(ctx, repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate search vector: %w", err)
+		return nil, err
 	}
+	return idx.Search(ctx, v.embedder, searchText, limit)
+}
 
-	// In production, this would use vector similarity search (cosine similarity, etc.)
-	// For now, we'll do simple text matching as a placeholder
+// AttributeMatch is an attribute found by SearchSimilarAttributes
+type AttributeMatch struct {
+	Attribute *Attribute
+	Score     float64
+}
 
-	fmt.Printf("🔍 Searching for attributes similar to: %s\n", searchText)
-	fmt.Printf("📊 Search vector (hash): %s\n", searchVector[:16]+"...")
+// SearchSimilarAttributes finds attributes similar to the given text, ordered by
+// descending score
+func (v *VectorService) SearchSimilarAttributes(ctx context.Context, repo DictionaryRepository, searchText string, limit int) ([]AttributeMatch, error) {
+	hits, err := v.Search(ctx, repo, searchText, limit)
+	if err != nil {
+		return nil, err
+	}
 
-	// Placeholder result - in production this would query the database
-	// using vector similarity functions
-	return []*Attribute{}, nil
+	results := make([]AttributeMatch, 0, len(hits))
+	for _, hit := range hits {
+		attr, err := repo.GetDictionaryAttributeByID(ctx, hit.AttributeID)
+		if err != nil {
+			// The index can outlive deleted attributes until the next regeneration
+			continue
+		}
+		results = append(results, AttributeMatch{Attribute: attr, Score: hit.Score})
+	}
+	return results, nil
+}
+
+// loadIndex reads the persisted index, building a transient one if none exists
+func (v *VectorService) loadIndex(ctx context.Context, repo DictionaryRepository) (*VectorIndex, error) {
+	idx, err := LoadVectorIndex(v.indexPath)
+	if err == nil {
+		return idx, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	attrs, err := repo.GetAllDictionaryAttributes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dictionary attributes: %w", err)
+	}
+	return BuildVectorIndex(ctx, v.embedder, attrs)
 }
 
 // buildSearchText creates a comprehensive text representation of an attribute
-func (v *VectorService) buildSearchText(attr *Attribute) string {
+func buildSearchText(attr *Attribute) string {
 	var parts []string
 
 	// Include the attribute name (most important)
@@ -130,23 +206,15 @@ func (v *VectorService) buildSearchText(attr *Attribute) string {
 		parts = append(parts, attr.Sink.Primary)
 	}
 
-	return strings.Join(parts, " ")
-}
-
-// generateHashVector creates a deterministic hash-based vector
-// This is a placeholder for actual embedding generation
-func (v *VectorService) generateHashVector(text string) string {
-	// Normalize the text
-	normalized := strings.ToLower(strings.TrimSpace(text))
+	// Include tags
+	parts = append(parts, attr.Tags...)
 
-	// Generate SHA256 hash
-	hash := sha256.Sum256([]byte(normalized))
-
-	// Convert to hex string (in production, this would be actual embeddings)
-	return hex.EncodeToString(hash[:])
+	return strings.Join(parts, " ")
 }
 
-// UpdateAttributeVector updates the vector for a specific attribute
+// UpdateAttributeVector re-embeds a single attribute, updates its entry in the
+// persisted index and stores the vector in the repository. IDF weights are kept
+// from the last full regeneration.
 func (v *VectorService) UpdateAttributeVector(ctx context.Context, repo DictionaryRepository, attributeID string) error {
 	// Get the attribute
 	attr, err := repo.GetDictionaryAttributeByID(ctx, attributeID)
@@ -154,17 +222,35 @@ func (v *VectorService) UpdateAttributeVector(ctx context.Context, repo Dictiona
 		return fmt.Errorf("failed to get attribute %s: %w", attributeID, err)
 	}
 
-	// Generate new vector
-	vector, err := v.GenerateVector(ctx, attr)
-	if err != nil {
-		return fmt.Errorf("failed to generate vector: %w", err)
+	idx, err := LoadVectorIndex(v.indexPath)
+	if err != nil || idx.Model != v.embedder.Model() {
+		// Without a compatible index there are no IDF weights to apply
+		vector, genErr := v.GenerateVector(ctx, attr)
+		if genErr != nil {
+			return fmt.Errorf("failed to generate vector: %w", genErr)
+		}
+		attr.Vector = vector
+	} else {
+		raw, embedErr := v.embedder.Embed(ctx, buildSearchText(attr))
+		if embedErr != nil {
+			return fmt.Errorf("failed to generate vector: %w", embedErr)
+		}
+		vec := idx.weight(raw)
+		if entry, ok := idx.Entry(attributeID); ok {
+			entry.Name = attr.Name
+			entry.Vector = vec
+		} else {
+			idx.Entries = append(idx.Entries, VectorEntry{AttributeID: attributeID, Name: attr.Name, Vector: vec})
+		}
+		if saveErr := SaveVectorIndex(idx, v.indexPath); saveErr != nil {
+			return saveErr
+		}
+		attr.Vector = EncodeVector(idx.Model, vec)
 	}
 
-	// Update the attribute with new vector
-	attr.Vector = vector
-
-	// Save back to database (you'd need to implement this in your repository)
-	// err = repo.UpdateDictionaryAttribute(ctx, attr)
+	if err := repo.UpdateDictionaryAttributeVector(ctx, attributeID, attr.Vector); err != nil {
+		return fmt.Errorf("failed to store vector: %w", err)
+	}
 	fmt.Printf("✅ Updated vector for attribute %s (%s)\n", attr.Name, attributeID)
 
 	return nil
@@ -188,28 +274,75 @@ func (v *VectorService) BatchUpdateVectors(ctx context.Context, repo DictionaryR
 	return nil
 }
 
-// ValidateVectorIntegrity checks if all attributes have valid vectors
+// ValidateVectorIntegrity checks that every attribute has a decodable vector
+// from the current model and that the persisted index covers all attributes.
 func (v *VectorService) ValidateVectorIntegrity(ctx context.Context, repo DictionaryRepository) error {
-	fmt.Println("🔍 Validating vector integrity...")
+	attrs, err := repo.GetAllDictionaryAttributes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary attributes: %w", err)
+	}
 
-	// In production, this would:
-	// 1. Query all attributes from dictionary table
-	// 2. Check which ones have missing or invalid vectors
-	// 3. Report statistics
-	// 4. Optionally regenerate missing vectors
+	var problems []string
+	for _, attr := range attrs {
+		if attr.Vector == "" {
+			problems = append(problems, fmt.Sprintf("%s: missing vector", attr.Name))
+			continue
+		}
+		model, vec, decodeErr := DecodeVector(attr.Vector)
+		switch {
+		case decodeErr != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", attr.Name, decodeErr))
+		case model != v.embedder.Model():
+			problems = append(problems, fmt.Sprintf("%s: vector from model %s", attr.Name, model))
+		case len(vec) != v.embedder.Dimensions():
+			problems = append(problems, fmt.Sprintf("%s: %d dimensions", attr.Name, len(vec)))
+		}
+	}
+
+	idx, err := LoadVectorIndex(v.indexPath)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("vector index: %v", err))
+	} else {
+		for _, attr := range attrs {
+			if _, ok := idx.Entry(attr.AttributeID); !ok {
+				problems = append(problems, fmt.Sprintf("%s: not in vector index", attr.Name))
+			}
+		}
+	}
 
-	fmt.Println("✅ Vector integrity validation completed")
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Printf("   ❌ %s\n", p)
+		}
+		return fmt.Errorf("%d vector integrity problems found; run regenerate-vectors", len(problems))
+	}
+
+	fmt.Printf("✅ All %d attribute vectors are valid\n", len(attrs))
 	return nil
 }
 
 // GetVectorStats returns statistics about the vector database
 func (v *VectorService) GetVectorStats(ctx context.Context, repo DictionaryRepository) (*VectorStats, error) {
-	return &VectorStats{
-		TotalAttributes:         0, // Would be populated from database
-		AttributesWithVector:    0,
-		AttributesWithoutVector: 0,
-		LastUpdate:              nil,
-	}, nil
+	attrs, err := repo.GetAllDictionaryAttributes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dictionary attributes: %w", err)
+	}
+
+	stats := &VectorStats{TotalAttributes: len(attrs), Model: v.embedder.Model()}
+	for _, attr := range attrs {
+		if attr.Vector != "" {
+			stats.AttributesWithVector++
+		}
+	}
+	stats.AttributesWithoutVector = stats.TotalAttributes - stats.AttributesWithVector
+
+	if idx, err := LoadVectorIndex(v.indexPath); err == nil {
+		builtAt := idx.BuiltAt.Format(time.RFC3339)
+		stats.LastUpdate = &builtAt
+		stats.IndexedAttributes = len(idx.Entries)
+	}
+
+	return stats, nil
 }
 
 // VectorStats provides statistics about vector coverage
@@ -217,9 +350,7 @@ type VectorStats struct {
 	TotalAttributes         int     `json:"total_attributes"`
 	AttributesWithVector    int     `json:"attributes_with_vector"`
 	AttributesWithoutVector int     `json:"attributes_without_vector"`
+	IndexedAttributes       int     `json:"indexed_attributes"`
+	Model                   string  `json:"model"`
 	LastUpdate              *string `json:"last_update,omitempty"`
 }
-
-// ==============================================================================
-// CLI Integration Functions - These would be called from CLI commands
-// ==============================================================================
@@ -0,0 +1,150 @@
+package dictionary
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+type memRepo struct {
+	attrs []Attribute
+}
+
+func (r *memRepo) GetDictionaryAttributeByID(_ context.Context, id string) (*Attribute, error) {
+	for i := range r.attrs {
+		if r.attrs[i].AttributeID == id {
+			attr := r.attrs[i]
+			return &attr, nil
+		}
+	}
+	return nil, fmt.Errorf("attribute not found: %s", id)
+}
+
+func (r *memRepo) GetAllDictionaryAttributes(_ context.Context) ([]Attribute, error) {
+	return r.attrs, nil
+}
+
+func (r *memRepo) UpdateDictionaryAttributeVector(_ context.Context, id, vector string) error {
+	for i := range r.attrs {
+		if r.attrs[i].AttributeID == id {
+			r.attrs[i].Vector = vector
+			return nil
+		}
+	}
+	return fmt.Errorf("attribute not found: %s", id)
+}
+
+func newTestRepo() *memRepo {
+	return &memRepo{attrs: []Attribute{
+		{AttributeID: "a1", Name: "entity.legal_name", LongDescription: "Registered legal name of the entity", Domain: "KYC"},
+		{AttributeID: "a2", Name: "entity.tax_id", LongDescription: "Tax identification number issued by the tax authority", Domain: "Tax"},
+		{AttributeID: "a3", Name: "individual.date_of_birth", LongDescription: "Date of birth of the natural person", Domain: "KYC", Mask: "date"},
+		{AttributeID: "a4", Name: "settlement.bank_account", LongDescription: "Bank account number for cash settlement", Domain: "Settlement"},
+		{AttributeID: "a5", Name: "entity.registration_number", LongDescription: "Company registration number from the registry", Domain: "KYC"},
+	}}
+}
+
+func TestVectorSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo()
+	svc := NewVectorService(WithIndexPath(filepath.Join(t.TempDir(), "index.json")))
+
+	if err := svc.RegenerateAllVectors(ctx, repo); err != nil {
+		t.Fatalf("RegenerateAllVectors failed: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"legal name", "a1"},
+		{"taxpayer identification", "a2"},
+		{"birth date", "a3"},
+		{"bank account number", "a4"},
+		{"LegalName", "a1"},
+	}
+	for _, tt := range tests {
+		hits, err := svc.Search(ctx, repo, tt.query, 3)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", tt.query, err)
+		}
+		if len(hits) == 0 || hits[0].AttributeID != tt.want {
+			t.Errorf("Search(%q): expected top hit %s, got %+v", tt.query, tt.want, hits)
+		}
+		for i := 1; i < len(hits); i++ {
+			if hits[i].Score > hits[i-1].Score {
+				t.Errorf("Search(%q): hits not ordered by score: %+v", tt.query, hits)
+			}
+		}
+	}
+
+	t.Run("Similar Attributes Carry Scores", func(t *testing.T) {
+		matches, err := svc.SearchSimilarAttributes(ctx, repo, "company registration", 2)
+		if err != nil {
+			t.Fatalf("SearchSimilarAttributes failed: %v", err)
+		}
+		if len(matches) == 0 || matches[0].Attribute.AttributeID != "a5" || matches[0].Score <= 0 {
+			t.Errorf("Expected a5 with a positive score first, got %+v", matches)
+		}
+	})
+
+	t.Run("Missing Index Is Rebuilt In Memory", func(t *testing.T) {
+		fresh := NewVectorService(WithIndexPath(filepath.Join(t.TempDir(), "absent.json")))
+		hits, err := fresh.Search(ctx, repo, "legal name", 1)
+		if err != nil || len(hits) == 0 || hits[0].AttributeID != "a1" {
+			t.Errorf("Expected search without an index file to work, got %+v (%v)", hits, err)
+		}
+	})
+
+	t.Run("Vectors Are Persisted", func(t *testing.T) {
+		stats, err := svc.GetVectorStats(ctx, repo)
+		if err != nil {
+			t.Fatalf("GetVectorStats failed: %v", err)
+		}
+		if stats.AttributesWithVector != 5 || stats.IndexedAttributes != 5 || stats.LastUpdate == nil {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if err := svc.ValidateVectorIntegrity(ctx, repo); err != nil {
+			t.Errorf("Expected vectors to validate: %v", err)
+		}
+	})
+
+	t.Run("Single Attribute Update", func(t *testing.T) {
+		repo.attrs[3].LongDescription = "IBAN for redemption payments"
+		if err := svc.UpdateAttributeVector(ctx, repo, "a4"); err != nil {
+			t.Fatalf("UpdateAttributeVector failed: %v", err)
+		}
+		hits, _ := svc.Search(ctx, repo, "redemption payments", 1)
+		if len(hits) == 0 || hits[0].AttributeID != "a4" {
+			t.Errorf("Expected updated attribute to be found, got %+v", hits)
+		}
+	})
+
+	t.Run("Missing Vector Fails Validation", func(t *testing.T) {
+		repo.attrs = append(repo.attrs, Attribute{AttributeID: "a6", Name: "entity.lei"})
+		if err := svc.ValidateVectorIntegrity(ctx, repo); err == nil {
+			t.Error("Expected validation error for attribute without vector")
+		}
+	})
+}
+
+func TestVectorEncoding(t *testing.T) {
+	vec := []float32{0.5, -0.25, 0, 1}
+	model, decoded, err := DecodeVector(EncodeVector("ngram-tfidf-4", vec))
+	if err != nil {
+		t.Fatalf("DecodeVector failed: %v", err)
+	}
+	if model != "ngram-tfidf-4" {
+		t.Errorf("Expected model prefix, got %s", model)
+	}
+	for i := range vec {
+		if decoded[i] != vec[i] {
+			t.Errorf("Component %d: expected %v, got %v", i, vec[i], decoded[i])
+		}
+	}
+
+	if _, _, err := DecodeVector("d41d8cd98f00b204e9800998ecf8427e"); err == nil {
+		t.Error("Expected legacy hash vectors to be rejected")
+	}
+}
@@ -17,7 +17,8 @@
 // - Notification and communication workflows
 // - External system integration and API management
 //
-// State Machine (8 states):
+// State Machine (8 states, the domain's names in the shared onboarding state
+// machine of package statemachine):
 // CREATE → PRODUCTS_ADDED → KYC_STARTED → SERVICES_DISCOVERED →
 // RESOURCES_PLANNED → ATTRIBUTES_BOUND → WORKFLOW_ACTIVE → COMPLETE
 package onboarding
@@ -31,6 +32,7 @@ import (
 
 	"dsl-ob-poc/internal/dictionary/repository"
 	registry "dsl-ob-poc/internal/domain-registry"
+	"dsl-ob-poc/internal/statemachine"
 )
 
 // Attribute UUID Constants - Real UUIDs from seeded dictionary
@@ -143,10 +145,7 @@ func (d *Domain) IsHealthy() bool                     { return d.healthy }
 func (d *Domain) GetMetrics() *registry.DomainMetrics { return d.metrics }
 
 func (d *Domain) GetValidStates() []string {
-	return []string{
-		"CREATE", "PRODUCTS_ADDED", "KYC_STARTED", "SERVICES_DISCOVERED",
-		"RESOURCES_PLANNED", "ATTRIBUTES_BOUND", "WORKFLOW_ACTIVE", "COMPLETE",
-	}
+	return statemachine.Onboarding().StatesIn(statemachine.VocabularyDomain)
 }
 
 func (d *Domain) GetInitialState() string {
@@ -258,29 +257,11 @@ func (d *Domain) ValidateStateTransition(from, to string) error {
 		return fmt.Errorf("invalid to state: %s", to)
 	}
 
-	// Define valid transitions for onboarding workflow
-	validTransitions := map[string][]string{
-		"CREATE":              {"PRODUCTS_ADDED"},
-		"PRODUCTS_ADDED":      {"KYC_STARTED"},
-		"KYC_STARTED":         {"SERVICES_DISCOVERED"},
-		"SERVICES_DISCOVERED": {"RESOURCES_PLANNED"},
-		"RESOURCES_PLANNED":   {"ATTRIBUTES_BOUND"},
-		"ATTRIBUTES_BOUND":    {"WORKFLOW_ACTIVE"},
-		"WORKFLOW_ACTIVE":     {"COMPLETE"},
-	}
-
-	allowedNextStates, exists := validTransitions[from]
-	if !exists {
-		return fmt.Errorf("no valid transitions from state: %s", from)
-	}
-
-	for _, allowedState := range allowedNextStates {
-		if allowedState == to {
-			return nil // Valid transition
-		}
+	// Transitions come from the shared onboarding state machine
+	if err := statemachine.Onboarding().CanTransition(from, to); err != nil {
+		return fmt.Errorf("invalid state transition from %s to %s", from, to)
 	}
-
-	return fmt.Errorf("invalid state transition from %s to %s", from, to)
+	return nil
 }
 
 // GenerateDSL creates DSL from natural language instructions
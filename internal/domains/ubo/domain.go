@@ -3,15 +3,21 @@ package ubo
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/entities"
+	"dsl-ob-poc/internal/shared-dsl/parser"
+
+	"github.com/google/uuid"
 )
 
 // UBODomain implements Ultimate Beneficial Ownership functionality for the DSL-as-State system
 type UBODomain struct {
 	datastore datastore.DataStore
+	cbuID     string
 }
 
 // NewUBODomain creates a new UBO domain instance
@@ -21,6 +27,16 @@ func NewUBODomain(ds datastore.DataStore) *UBODomain {
 	}
 }
 
+// NewUBODomainForCBU creates a UBO domain scoped to a CBU: its registered
+// relationships feed the ownership graph and calculated UBOs are stored
+// against it in the UBO registry.
+func NewUBODomainForCBU(ds datastore.DataStore, cbuID string) *UBODomain {
+	return &UBODomain{
+		datastore: ds,
+		cbuID:     cbuID,
+	}
+}
+
 // ExecuteDSL processes UBO DSL commands and returns the resulting state
 func (d *UBODomain) ExecuteDSL(ctx context.Context, dsl string) (map[string]interface{}, error) {
 	// Parse and execute UBO DSL commands
@@ -192,41 +208,185 @@ func (d *UBODomain) executeGetOwnershipStructure(ctx context.Context, dsl string
 	return result, nil
 }
 
-// executeResolveUBOs implements the core UBO identification algorithm
+// executeResolveUBOs implements the core UBO identification algorithm. It loads
+// the ownership graph from the datastore, locates the subject entity named in the
+// (ubo.resolve-ubos ...) form and calculates UBOs with the threshold given there.
 func (d *UBODomain) executeResolveUBOs(ctx context.Context, dsl string) (map[string]interface{}, error) {
-	result := map[string]interface{}{
-		"status": "resolved",
-		"ubos": []map[string]interface{}{
-			{
-				"proper_person_id":  "person-uuid-1",
-				"name":              "John Smith",
-				"relationship_type": "DIRECT_OWNERSHIP",
-				"total_ownership":   25.0,
-				"qualifying_reason": "OWNERSHIP_THRESHOLD",
-			},
-			{
-				"proper_person_id":  "person-uuid-2",
-				"name":              "Jane Doe",
-				"relationship_type": "CONTROL_PRONG",
-				"control_type":      "CEO",
-				"qualifying_reason": "SENIOR_MANAGING_OFFICIAL",
-			},
-		},
-		"threshold_applied":    25.0,
-		"regulatory_framework": "EU_5MLD",
-		"resolved_at":          time.Now(),
+	args, err := extractFormArgs(dsl, "ubo.resolve-ubos")
+	if err != nil {
+		return nil, err
 	}
 
-	// In a real implementation, this would:
-	// 1. Apply ownership threshold analysis
-	// 2. Recursively calculate indirect ownership
-	// 3. Identify control prong individuals
-	// 4. Consolidate and de-duplicate results
-	// 5. Apply jurisdiction-specific rules
+	framework := args["jurisdiction_rules"]
+	if framework == "" {
+		framework = args["regulatory_framework"]
+	}
+	req := CalculationRequest{RegulatoryFramework: framework}
+	if v, ok := args["ownership_threshold"]; ok {
+		if req.Threshold, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("invalid ownership_threshold %q: %w", v, err)
+		}
+	}
+	if v, ok := args["max_depth"]; ok {
+		if req.MaxDepth, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid max_depth %q: %w", v, err)
+		}
+	}
+
+	data, err := d.datastore.LoadOwnershipGraph(ctx, d.cbuID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ownership graph: %w", err)
+	}
+	graph := BuildOwnershipGraph(data)
+
+	subject, err := d.findSubjectEntity(graph, dsl, args)
+	if err != nil {
+		return nil, err
+	}
+	req.SubjectEntityID = subject.EntityID
+
+	calculation, err := graph.CalculateUBOs(req)
+	if err != nil {
+		return nil, err
+	}
+
+	persisted := false
+	if d.cbuID != "" {
+		cbuUUID, err := uuid.Parse(d.cbuID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CBU ID %q: %w", d.cbuID, err)
+		}
+		if err := d.datastore.SaveCalculatedUBOs(ctx, d.cbuID, calculation.SubjectEntityID, calculation.RegistryEntries(cbuUUID)); err != nil {
+			return nil, fmt.Errorf("failed to store calculated UBOs: %w", err)
+		}
+		persisted = true
+	}
+
+	ubos := make([]map[string]interface{}, 0, len(calculation.UBOs))
+	for _, u := range calculation.UBOs {
+		ubos = append(ubos, u.ToResultMap())
+	}
+	belowThreshold := make([]map[string]interface{}, 0, len(calculation.BelowThreshold))
+	for _, u := range calculation.BelowThreshold {
+		belowThreshold = append(belowThreshold, u.ToResultMap())
+	}
+
+	result := map[string]interface{}{
+		"status":               "resolved",
+		"subject_entity_id":    calculation.SubjectEntityID.String(),
+		"subject_name":         calculation.SubjectName,
+		"ubos":                 ubos,
+		"below_threshold":      belowThreshold,
+		"cycles_detected":      calculation.Cycles,
+		"cycles_unresolved":    calculation.CyclesUnresolved,
+		"threshold_applied":    calculation.Threshold,
+		"regulatory_framework": calculation.RegulatoryFramework,
+		"resolved_at":          calculation.CalculatedAt,
+		"persisted":            persisted,
+	}
 
 	return result, nil
 }
 
+// findSubjectEntity locates the subject of a resolve-ubos form. A literal entity_id
+// is used when present; @attr references cannot be resolved without a CBU, so the
+// entity is then matched by entity_name from the form or from ubo.collect-entity-data.
+func (d *UBODomain) findSubjectEntity(graph *OwnershipGraph, dsl string, args map[string]string) (*entities.Entity, error) {
+	if v := args["entity_id"]; v != "" && !strings.HasPrefix(v, "@attr{") {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid entity_id %q: %w", v, err)
+		}
+		if e, ok := graph.Entity(id); ok {
+			return e, nil
+		}
+		return nil, fmt.Errorf("subject entity not found in ownership graph: %s", v)
+	}
+
+	name := args["entity_name"]
+	if name == "" {
+		if collectArgs, err := extractFormArgs(dsl, "ubo.collect-entity-data"); err == nil {
+			name = collectArgs["entity_name"]
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("ubo.resolve-ubos requires an entity_id or entity_name")
+	}
+
+	return graph.FindEntityByName(name)
+}
+
+// extractFormArgs finds the first (verb ...) form in the DSL and returns its
+// (key value) arguments. Only the form itself is parsed, so list syntax used
+// elsewhere in UBO workflows does not affect extraction.
+func extractFormArgs(dsl, verb string) (map[string]string, error) {
+	formText, ok := extractFormText(dsl, verb)
+	if !ok {
+		return nil, fmt.Errorf("form %s not found in DSL", verb)
+	}
+
+	ast, err := parser.Parse(formText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", verb, err)
+	}
+	if len(ast.Root.Children) == 0 {
+		return nil, fmt.Errorf("form %s is empty", verb)
+	}
+
+	args := make(map[string]string)
+	for _, child := range ast.Root.Children[0].Children[1:] {
+		if child.Type != parser.ExpressionNode || len(child.Children) < 2 {
+			continue
+		}
+		args[child.Value] = child.Children[1].Value
+	}
+	return args, nil
+}
+
+// extractFormText returns the text of the first balanced (verb ...) form
+func extractFormText(dsl, verb string) (string, bool) {
+	start := strings.Index(dsl, "("+verb)
+	for start >= 0 {
+		next := start + len(verb) + 1
+		if next >= len(dsl) || !isVerbChar(dsl[next]) {
+			break
+		}
+		idx := strings.Index(dsl[next:], "("+verb)
+		if idx < 0 {
+			return "", false
+		}
+		start = next + idx
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	depth := 0
+	inString := false
+	for i := start; i < len(dsl); i++ {
+		switch ch := dsl[i]; {
+		case inString && ch == '\\':
+			i++
+		case ch == '"':
+			inString = !inString
+		case inString:
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return dsl[start : i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// isVerbChar reports whether a byte can continue a verb identifier
+func isVerbChar(b byte) bool {
+	return b == '-' || b == '.' || b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
 // executeVerifyIdentity implements UBO identity verification workflow
 func (d *UBODomain) executeVerifyIdentity(ctx context.Context, dsl string) (map[string]interface{}, error) {
 	result := map[string]interface{}{
@@ -0,0 +1,769 @@
+package ubo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/entities"
+
+	"github.com/google/uuid"
+)
+
+// Link types used on ownership graph edges
+const (
+	LinkTypeDirectShare         = "DIRECT_SHARE"
+	LinkTypePartnershipInterest = "PARTNERSHIP_INTEREST"
+	LinkTypeGeneralPartner      = "GENERAL_PARTNER"
+	LinkTypeTrustee             = "TRUSTEE"
+)
+
+// DefaultMaxDepth bounds how far the engine walks up an ownership chain
+const DefaultMaxDepth = 10
+
+// majorityControlPercentage is the holding above which an owner controls the owned entity
+const majorityControlPercentage = 50.0
+
+// thresholdEpsilon absorbs floating point error when multiplying percentages along a chain
+const thresholdEpsilon = 1e-9
+
+// singularEpsilon is the pivot below which the integrated ownership system has no solution
+const singularEpsilon = 1e-9
+
+// frameworkThresholds maps regulatory frameworks to their ownership thresholds (percent)
+var frameworkThresholds = map[string]float64{
+	"EU_5MLD":   25.0,
+	"EU_4MLD":   25.0,
+	"UK_PSC":    25.0,
+	"US_FINCEN": 25.0,
+	"US_CDD":    25.0,
+	"FATF":      25.0,
+}
+
+// ThresholdForFramework returns the ownership threshold for a regulatory framework,
+// falling back to 25% for unknown frameworks.
+func ThresholdForFramework(framework string) float64 {
+	if threshold, ok := frameworkThresholds[strings.ToUpper(framework)]; ok {
+		return threshold
+	}
+	return 25.0
+}
+
+// OwnershipLink is a directed edge in the ownership graph: Owner holds Percentage of Owned
+type OwnershipLink struct {
+	OwnerID    uuid.UUID `json:"owner_id"`
+	OwnedID    uuid.UUID `json:"owned_id"`
+	Percentage float64   `json:"percentage"`
+	LinkType   string    `json:"link_type"`
+}
+
+// OwnershipGraph is an in-memory view of the entity registry and the ownership,
+// partnership and trust relationships between entities.
+type OwnershipGraph struct {
+	entities     map[uuid.UUID]*entities.Entity
+	owners       map[uuid.UUID][]OwnershipLink // owned entity -> incoming ownership links
+	controllers  map[uuid.UUID][]OwnershipLink // partnership entity -> general partner links
+	trustParties map[uuid.UUID][]entities.TrustParty
+}
+
+// BuildOwnershipGraph builds an ownership graph from registry data. Partnership
+// interests and trust parties are attached to the entity whose ExternalID matches
+// their partnership/trust ID; rows that cannot be linked are ignored.
+func BuildOwnershipGraph(data *entities.OwnershipGraphData) *OwnershipGraph {
+	g := &OwnershipGraph{
+		entities:     make(map[uuid.UUID]*entities.Entity),
+		owners:       make(map[uuid.UUID][]OwnershipLink),
+		controllers:  make(map[uuid.UUID][]OwnershipLink),
+		trustParties: make(map[uuid.UUID][]entities.TrustParty),
+	}
+	if data == nil {
+		return g
+	}
+
+	byExternalID := make(map[string]uuid.UUID)
+	for i := range data.Entities {
+		e := data.Entities[i]
+		g.entities[e.EntityID] = &e
+		if e.ExternalID != nil && *e.ExternalID != "" {
+			byExternalID[*e.ExternalID] = e.EntityID
+		}
+	}
+
+	for i := range data.PartnershipInterests {
+		pi := &data.PartnershipInterests[i]
+		partnershipID, ok := byExternalID[pi.PartnershipID.String()]
+		if !ok || !pi.IsCurrentlyActive() {
+			continue
+		}
+		if pi.OwnershipPercentage != nil && *pi.OwnershipPercentage > 0 {
+			g.addOwnership(OwnershipLink{
+				OwnerID:    pi.EntityID,
+				OwnedID:    partnershipID,
+				Percentage: *pi.OwnershipPercentage,
+				LinkType:   LinkTypePartnershipInterest,
+			})
+		}
+		if pi.PartnerType == entities.PartnerTypeGeneral || pi.PartnerType == entities.PartnerTypeManaging {
+			g.controllers[partnershipID] = append(g.controllers[partnershipID], OwnershipLink{
+				OwnerID:  pi.EntityID,
+				OwnedID:  partnershipID,
+				LinkType: LinkTypeGeneralPartner,
+			})
+		}
+	}
+
+	for i := range data.TrustParties {
+		tp := data.TrustParties[i]
+		trustID, ok := byExternalID[tp.TrustID.String()]
+		if !ok || !tp.IsCurrentlyActive() {
+			continue
+		}
+		g.trustParties[trustID] = append(g.trustParties[trustID], tp)
+	}
+
+	// Registered direct ownership relationships act as shareholdings
+	for _, r := range data.UBORegistry {
+		if r.RelationshipType != entities.UBORelationshipDirectOwnership || r.OwnershipPercentage == nil {
+			continue
+		}
+		g.addOwnership(OwnershipLink{
+			OwnerID:    r.UBOProperPersonID,
+			OwnedID:    r.SubjectEntityID,
+			Percentage: *r.OwnershipPercentage,
+			LinkType:   LinkTypeDirectShare,
+		})
+	}
+
+	return g
+}
+
+// addOwnership records an ownership link, ignoring exact duplicates
+func (g *OwnershipGraph) addOwnership(link OwnershipLink) {
+	for _, existing := range g.owners[link.OwnedID] {
+		if existing.OwnerID == link.OwnerID && existing.LinkType == link.LinkType {
+			return
+		}
+	}
+	g.owners[link.OwnedID] = append(g.owners[link.OwnedID], link)
+}
+
+// Entity returns the entity with the given ID
+func (g *OwnershipGraph) Entity(id uuid.UUID) (*entities.Entity, bool) {
+	e, ok := g.entities[id]
+	return e, ok
+}
+
+// FindEntityByName returns the entity with the given name (case-insensitive).
+// Names are not unique in the registry, so a name shared by several entities
+// is an error rather than an arbitrary pick.
+func (g *OwnershipGraph) FindEntityByName(name string) (*entities.Entity, error) {
+	var matches []*entities.Entity
+	for _, e := range g.entities {
+		if strings.EqualFold(e.Name, name) {
+			matches = append(matches, e)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("subject entity not found in ownership graph: %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, 0, len(matches))
+		for _, e := range matches {
+			ids = append(ids, e.EntityID.String())
+		}
+		sort.Strings(ids)
+		return nil, fmt.Errorf("entity name %q is ambiguous, matching entities: %s", name, strings.Join(ids, ", "))
+	}
+}
+
+// entityName returns a display name for an entity ID
+func (g *OwnershipGraph) entityName(id uuid.UUID) string {
+	if e, ok := g.entities[id]; ok {
+		return e.Name
+	}
+	return id.String()
+}
+
+// isNaturalPerson reports whether the entity is a proper person
+func (g *OwnershipGraph) isNaturalPerson(id uuid.UUID) bool {
+	e, ok := g.entities[id]
+	return ok && e.IsNaturalPerson()
+}
+
+// isTrust reports whether the entity is a trust
+func (g *OwnershipGraph) isTrust(id uuid.UUID) bool {
+	e, ok := g.entities[id]
+	return ok && e.EntityType != nil && e.EntityType.Name == entities.EntityTypeTrust
+}
+
+// ============================================================================
+// CALCULATION
+// ============================================================================
+
+// CalculationRequest parameterises a UBO calculation
+type CalculationRequest struct {
+	SubjectEntityID     uuid.UUID
+	Threshold           float64 // percent; 0 uses the framework default
+	RegulatoryFramework string
+	MaxDepth            int // 0 uses DefaultMaxDepth
+}
+
+// PathStep is one hop in an ownership path
+type PathStep struct {
+	EntityID   uuid.UUID `json:"entity_id"`
+	EntityName string    `json:"entity_name"`
+	Percentage float64   `json:"percentage"` // holding in the next entity along the path
+	LinkType   string    `json:"link_type"`
+}
+
+// OwnershipPath explains how a person reaches the subject entity
+type OwnershipPath struct {
+	Steps     []PathStep `json:"steps"`
+	Subject   string     `json:"subject"`
+	Effective float64    `json:"effective_percentage"`
+	Control   bool       `json:"control,omitempty"`
+}
+
+// String renders the path as "Owner -[60.00%]-> HoldCo -[50.00%]-> Subject = 30.00%"
+func (p OwnershipPath) String() string {
+	var sb strings.Builder
+	for _, step := range p.Steps {
+		sb.WriteString(step.EntityName)
+		switch step.LinkType {
+		case LinkTypeGeneralPartner:
+			sb.WriteString(" -[GP]-> ")
+		case LinkTypeTrustee:
+			sb.WriteString(" -[TRUSTEE]-> ")
+		default:
+			sb.WriteString(fmt.Sprintf(" -[%.2f%%]-> ", step.Percentage))
+		}
+	}
+	sb.WriteString(p.Subject)
+	if p.Control {
+		sb.WriteString(" (control)")
+	} else {
+		sb.WriteString(fmt.Sprintf(" = %.2f%%", p.Effective))
+	}
+	return sb.String()
+}
+
+// CalculatedUBO is a natural person identified as an ultimate beneficial owner
+type CalculatedUBO struct {
+	ProperPersonID   uuid.UUID       `json:"proper_person_id"`
+	Name             string          `json:"name"`
+	RelationshipType string          `json:"relationship_type"`
+	QualifyingReason string          `json:"qualifying_reason"`
+	TotalOwnership   float64         `json:"total_ownership"`
+	ControlType      string          `json:"control_type,omitempty"`
+	Paths            []OwnershipPath `json:"paths"`
+}
+
+// UBOCalculation is the result of a UBO calculation for one subject entity
+type UBOCalculation struct {
+	SubjectEntityID     uuid.UUID       `json:"subject_entity_id"`
+	SubjectName         string          `json:"subject_name"`
+	Threshold           float64         `json:"threshold"`
+	RegulatoryFramework string          `json:"regulatory_framework"`
+	UBOs                []CalculatedUBO `json:"ubos"`
+	BelowThreshold      []CalculatedUBO `json:"below_threshold"`
+	Cycles              [][]string      `json:"cycles,omitempty"`
+	CyclesUnresolved    bool            `json:"cycles_unresolved,omitempty"`
+	CalculatedAt        time.Time       `json:"calculated_at"`
+}
+
+// personAccumulator collects ownership and control evidence for one natural person
+type personAccumulator struct {
+	id          uuid.UUID
+	total       float64
+	direct      bool
+	paths       []OwnershipPath
+	controlType string
+	trustRole   string
+}
+
+// calculation carries the state of a single CalculateUBOs run
+type calculation struct {
+	graph    *OwnershipGraph
+	subject  uuid.UUID
+	maxDepth int
+	persons  map[uuid.UUID]*personAccumulator
+	trusts   map[uuid.UUID]float64 // trust entity -> effective ownership in subject
+	applied  map[uuid.UUID]bool    // trusts whose parties have been applied
+	cycles   map[string][]string
+}
+
+// CalculateUBOs walks the ownership graph above the subject entity, multiplying
+// holdings along every chain, and returns the natural persons who meet the
+// ownership threshold, exercise control through general partner or majority
+// chains, or hold a role in a trust that owns or controls the subject.
+//
+// Total ownership is the integrated stake: the sum over every chain, including
+// chains that go around cross-holdings any number of times. It is solved as the
+// linear system x_n = sum over holdings of pct(n, m) * x_m with x_subject = 100,
+// so a person holding 80% of Beta, where Beta and Alpha hold 50% of each other
+// and Alpha holds the subject, has 0.8 * 0.5 / (1 - 0.25) = 53.3%. Explanatory
+// paths never repeat an entity, and every detected cycle is reported. A closed
+// loop that owns itself entirely has no solution: totals then fall back to the
+// sum of the acyclic paths and CyclesUnresolved is set.
+func (g *OwnershipGraph) CalculateUBOs(req CalculationRequest) (*UBOCalculation, error) {
+	subject, ok := g.entities[req.SubjectEntityID]
+	if !ok {
+		return nil, fmt.Errorf("subject entity not found in ownership graph: %s", req.SubjectEntityID)
+	}
+
+	threshold := req.Threshold
+	if threshold <= 0 {
+		threshold = ThresholdForFramework(req.RegulatoryFramework)
+	}
+	maxDepth := req.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	c := &calculation{
+		graph:    g,
+		subject:  subject.EntityID,
+		maxDepth: maxDepth,
+		persons:  make(map[uuid.UUID]*personAccumulator),
+		trusts:   make(map[uuid.UUID]float64),
+		applied:  make(map[uuid.UUID]bool),
+		cycles:   make(map[string][]string),
+	}
+
+	visited := map[uuid.UUID]bool{subject.EntityID: true}
+	c.walkOwnership(subject.EntityID, nil, 100.0, visited)
+	c.walkControl(subject.EntityID, nil, map[uuid.UUID]bool{subject.EntityID: true})
+
+	integrated, solved := g.integratedOwnership(subject.EntityID, maxDepth)
+	if solved {
+		for id, acc := range c.persons {
+			acc.total = integrated[id]
+		}
+		for id := range c.trusts {
+			c.trusts[id] = integrated[id]
+		}
+	}
+
+	if g.isTrust(subject.EntityID) {
+		c.trusts[subject.EntityID] = 100.0
+	}
+	for trustID, effective := range c.trusts {
+		if effective+thresholdEpsilon >= threshold || trustID == subject.EntityID {
+			c.applyTrustParties(trustID)
+		}
+	}
+
+	result := &UBOCalculation{
+		SubjectEntityID:     subject.EntityID,
+		SubjectName:         subject.Name,
+		Threshold:           threshold,
+		RegulatoryFramework: req.RegulatoryFramework,
+		CyclesUnresolved:    !solved,
+		CalculatedAt:        time.Now(),
+	}
+
+	for _, acc := range c.persons {
+		ubo := c.classify(acc, threshold)
+		if ubo.QualifyingReason == "" {
+			result.BelowThreshold = append(result.BelowThreshold, ubo)
+		} else {
+			result.UBOs = append(result.UBOs, ubo)
+		}
+	}
+	sortUBOs(result.UBOs)
+	sortUBOs(result.BelowThreshold)
+
+	cycleKeys := make([]string, 0, len(c.cycles))
+	for key := range c.cycles {
+		cycleKeys = append(cycleKeys, key)
+	}
+	sort.Strings(cycleKeys)
+	for _, key := range cycleKeys {
+		result.Cycles = append(result.Cycles, c.cycles[key])
+	}
+
+	return result, nil
+}
+
+// walkOwnership follows ownership links upwards from node. chain holds the links
+// from node down to the subject; effective is node's effective stake in the subject.
+func (c *calculation) walkOwnership(node uuid.UUID, chain []OwnershipLink, effective float64, visited map[uuid.UUID]bool) {
+	if len(chain) >= c.maxDepth {
+		return
+	}
+
+	for _, link := range c.graph.owners[node] {
+		if visited[link.OwnerID] {
+			c.recordCycle(link.OwnerID, append([]OwnershipLink{link}, chain...))
+			continue
+		}
+
+		path := append([]OwnershipLink{link}, chain...)
+		stake := effective * link.Percentage / 100.0
+
+		switch {
+		case c.graph.isNaturalPerson(link.OwnerID):
+			acc := c.person(link.OwnerID)
+			acc.total += stake
+			if len(path) == 1 {
+				acc.direct = true
+			}
+			acc.paths = append(acc.paths, c.describePath(path, stake, false))
+		case c.graph.isTrust(link.OwnerID):
+			c.trusts[link.OwnerID] += stake
+		}
+
+		visited[link.OwnerID] = true
+		c.walkOwnership(link.OwnerID, path, stake, visited)
+		delete(visited, link.OwnerID)
+	}
+}
+
+// integratedOwnership returns every entity's integrated stake in subject
+// (percent) over ownership links within maxDepth hops of it. ok is false when
+// the stakes cannot be resolved because the system is singular.
+func (g *OwnershipGraph) integratedOwnership(subject uuid.UUID, maxDepth int) (map[uuid.UUID]float64, bool) {
+	// Collect the owners above the subject, breadth first
+	index := make(map[uuid.UUID]int)
+	var nodes []uuid.UUID
+	frontier := []uuid.UUID{subject}
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var next []uuid.UUID
+		for _, owned := range frontier {
+			for _, link := range g.owners[owned] {
+				if _, seen := index[link.OwnerID]; seen || link.OwnerID == subject {
+					continue
+				}
+				index[link.OwnerID] = len(nodes)
+				nodes = append(nodes, link.OwnerID)
+				next = append(next, link.OwnerID)
+			}
+		}
+		frontier = next
+	}
+
+	// (I - A) x = b, where A holds stakes between the collected owners and b
+	// their direct stakes in the subject
+	n := len(nodes)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n+1)
+		m[i][i] = 1
+	}
+	for owned, links := range g.owners {
+		col, inside := index[owned]
+		if !inside && owned != subject {
+			continue
+		}
+		for _, link := range links {
+			row, ok := index[link.OwnerID]
+			if !ok {
+				continue
+			}
+			if owned == subject {
+				m[row][n] += link.Percentage
+			} else {
+				m[row][col] -= link.Percentage / 100.0
+			}
+		}
+	}
+
+	// Gaussian elimination with partial pivoting
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < singularEpsilon {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for r := 0; r < n; r++ {
+			if r == col || m[r][col] == 0 {
+				continue
+			}
+			factor := m[r][col] / m[col][col]
+			for k := col; k <= n; k++ {
+				m[r][k] -= factor * m[col][k]
+			}
+		}
+	}
+
+	stakes := make(map[uuid.UUID]float64, n)
+	for id, i := range index {
+		stakes[id] = m[i][n] / m[i][i]
+	}
+	return stakes, true
+}
+
+// walkControl follows control links upwards from node: general partner roles and
+// majority holdings. Natural persons at the top of a control chain control the subject.
+func (c *calculation) walkControl(node uuid.UUID, chain []OwnershipLink, visited map[uuid.UUID]bool) {
+	if len(chain) >= c.maxDepth {
+		return
+	}
+
+	links := append([]OwnershipLink{}, c.graph.controllers[node]...)
+	for _, link := range c.graph.owners[node] {
+		if link.Percentage > majorityControlPercentage {
+			links = append(links, link)
+		}
+	}
+
+	for _, link := range links {
+		if visited[link.OwnerID] {
+			continue
+		}
+		path := append([]OwnershipLink{link}, chain...)
+
+		switch {
+		case c.graph.isNaturalPerson(link.OwnerID):
+			if !chainHasGeneralPartner(path) && len(path) == 1 {
+				// A direct majority holding is already captured by the ownership prong
+				continue
+			}
+			acc := c.person(link.OwnerID)
+			if acc.controlType == "" {
+				acc.controlType = controlTypeFor(path)
+			}
+			acc.paths = append(acc.paths, c.describePath(path, 0, true))
+		case c.graph.isTrust(link.OwnerID):
+			c.applyTrustParties(link.OwnerID)
+		}
+
+		visited[link.OwnerID] = true
+		c.walkControl(link.OwnerID, path, visited)
+		delete(visited, link.OwnerID)
+	}
+}
+
+// applyTrustParties marks every natural-person party of a trust as a UBO.
+// Corporate trustees are looked through to the persons who control them.
+func (c *calculation) applyTrustParties(trustID uuid.UUID) {
+	if c.applied[trustID] {
+		return
+	}
+	c.applied[trustID] = true
+
+	for _, tp := range c.graph.trustParties[trustID] {
+		if c.graph.isNaturalPerson(tp.EntityID) {
+			acc := c.person(tp.EntityID)
+			if acc.trustRole == "" {
+				acc.trustRole = tp.PartyRole
+			}
+			continue
+		}
+
+		if tp.PartyRole != entities.TrustPartyRoleTrustee {
+			continue
+		}
+		trusteeLink := OwnershipLink{OwnerID: tp.EntityID, OwnedID: trustID, LinkType: LinkTypeTrustee}
+		c.walkControl(tp.EntityID, []OwnershipLink{trusteeLink}, map[uuid.UUID]bool{tp.EntityID: true, trustID: true})
+		for _, acc := range c.persons {
+			if acc.controlType != "" && acc.trustRole == "" && c.controlsVia(acc, tp.EntityID) {
+				acc.trustRole = entities.TrustPartyRoleTrustee
+			}
+		}
+	}
+}
+
+// controlsVia reports whether any of the person's control paths passes through entity
+func (c *calculation) controlsVia(acc *personAccumulator, entity uuid.UUID) bool {
+	for _, p := range acc.paths {
+		if !p.Control {
+			continue
+		}
+		for _, step := range p.Steps {
+			if step.EntityID == entity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// person returns the accumulator for a natural person, creating it if needed
+func (c *calculation) person(id uuid.UUID) *personAccumulator {
+	acc, ok := c.persons[id]
+	if !ok {
+		acc = &personAccumulator{id: id}
+		c.persons[id] = acc
+	}
+	return acc
+}
+
+// recordCycle records a circular holding, keyed by its member set
+func (c *calculation) recordCycle(start uuid.UUID, path []OwnershipLink) {
+	names := []string{c.graph.entityName(start)}
+	for _, link := range path[1:] {
+		if link.OwnerID == start {
+			break
+		}
+		names = append(names, c.graph.entityName(link.OwnerID))
+	}
+	names = append(names, c.graph.entityName(start))
+
+	members := append([]string{}, names[:len(names)-1]...)
+	sort.Strings(members)
+	c.cycles[strings.Join(members, "|")] = names
+}
+
+// describePath converts a chain of links into an explanatory ownership path
+func (c *calculation) describePath(path []OwnershipLink, effective float64, control bool) OwnershipPath {
+	steps := make([]PathStep, 0, len(path))
+	for _, link := range path {
+		steps = append(steps, PathStep{
+			EntityID:   link.OwnerID,
+			EntityName: c.graph.entityName(link.OwnerID),
+			Percentage: link.Percentage,
+			LinkType:   link.LinkType,
+		})
+	}
+	return OwnershipPath{
+		Steps:     steps,
+		Subject:   c.graph.entityName(path[len(path)-1].OwnedID),
+		Effective: effective,
+		Control:   control,
+	}
+}
+
+// classify decides whether and why an accumulated person qualifies as a UBO
+func (c *calculation) classify(acc *personAccumulator, threshold float64) CalculatedUBO {
+	ubo := CalculatedUBO{
+		ProperPersonID: acc.id,
+		Name:           c.graph.entityName(acc.id),
+		TotalOwnership: acc.total,
+		ControlType:    acc.controlType,
+		Paths:          acc.paths,
+	}
+
+	switch {
+	case acc.total+thresholdEpsilon >= threshold:
+		ubo.QualifyingReason = "OWNERSHIP_THRESHOLD"
+		if acc.direct && len(acc.paths) == 1 {
+			ubo.RelationshipType = entities.UBORelationshipDirectOwnership
+		} else {
+			ubo.RelationshipType = entities.UBORelationshipIndirectOwnership
+		}
+	case acc.trustRole != "":
+		ubo.RelationshipType, ubo.QualifyingReason = trustRelationship(acc.trustRole)
+	case acc.controlType != "":
+		ubo.RelationshipType = entities.UBORelationshipPartnershipControl
+		ubo.QualifyingReason = "ULTIMATE_CONTROL"
+	default:
+		ubo.RelationshipType = ownershipRelationship(acc)
+	}
+
+	return ubo
+}
+
+// ownershipRelationship describes a below-threshold holding
+func ownershipRelationship(acc *personAccumulator) string {
+	if acc.direct && len(acc.paths) == 1 {
+		return entities.UBORelationshipDirectOwnership
+	}
+	return entities.UBORelationshipIndirectOwnership
+}
+
+// trustRelationship maps a trust party role to a UBO relationship and qualifying reason
+func trustRelationship(role string) (string, string) {
+	switch role {
+	case entities.TrustPartyRoleSettlor:
+		return entities.UBORelationshipTrustSettlor, "TRUST_CREATOR"
+	case entities.TrustPartyRoleTrustee:
+		return entities.UBORelationshipTrustTrustee, "LEGAL_MANAGER"
+	case entities.TrustPartyRoleProtector:
+		return entities.UBORelationshipTrustProtector, "ULTIMATE_CONTROL"
+	default:
+		return entities.UBORelationshipTrustBeneficiary, "NAMED_BENEFICIARY"
+	}
+}
+
+// chainHasGeneralPartner reports whether a control chain includes a general partner link
+func chainHasGeneralPartner(path []OwnershipLink) bool {
+	for _, link := range path {
+		if link.LinkType == LinkTypeGeneralPartner {
+			return true
+		}
+	}
+	return false
+}
+
+// controlTypeFor names the kind of control exercised through a chain
+func controlTypeFor(path []OwnershipLink) string {
+	if chainHasGeneralPartner(path) {
+		return "GENERAL_PARTNER"
+	}
+	return "MAJORITY_CONTROL"
+}
+
+// sortUBOs orders UBOs by descending ownership, then by name
+func sortUBOs(ubos []CalculatedUBO) {
+	sort.SliceStable(ubos, func(i, j int) bool {
+		if ubos[i].TotalOwnership != ubos[j].TotalOwnership {
+			return ubos[i].TotalOwnership > ubos[j].TotalOwnership
+		}
+		return ubos[i].Name < ubos[j].Name
+	})
+}
+
+// RegistryEntries converts the identified UBOs into ubo_registry rows for a CBU
+func (r *UBOCalculation) RegistryEntries(cbuID uuid.UUID) []entities.UBORegistry {
+	entries := make([]entities.UBORegistry, 0, len(r.UBOs))
+	for _, ubo := range r.UBOs {
+		entry := entities.UBORegistry{
+			UBOID:              uuid.New(),
+			CBUID:              cbuID,
+			SubjectEntityID:    r.SubjectEntityID,
+			UBOProperPersonID:  ubo.ProperPersonID,
+			RelationshipType:   ubo.RelationshipType,
+			QualifyingReason:   ubo.QualifyingReason,
+			WorkflowType:       entities.UBOWorkflowRecursiveAnalysis,
+			VerificationStatus: entities.VerificationStatusPending,
+			ScreeningResult:    entities.ScreeningResultPending,
+			IdentifiedAt:       r.CalculatedAt,
+		}
+		if ubo.TotalOwnership > 0 {
+			ownership := ubo.TotalOwnership
+			entry.OwnershipPercentage = &ownership
+		}
+		if ubo.ControlType != "" {
+			controlType := ubo.ControlType
+			entry.ControlType = &controlType
+		}
+		if r.RegulatoryFramework != "" {
+			framework := r.RegulatoryFramework
+			entry.RegulatoryFramework = &framework
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ToResultMap renders a UBO for the domain's map-based execution results
+func (u CalculatedUBO) ToResultMap() map[string]interface{} {
+	paths := make([]string, 0, len(u.Paths))
+	for _, p := range u.Paths {
+		paths = append(paths, p.String())
+	}
+
+	result := map[string]interface{}{
+		"proper_person_id":  u.ProperPersonID.String(),
+		"name":              u.Name,
+		"relationship_type": u.RelationshipType,
+		"total_ownership":   u.TotalOwnership,
+		"qualifying_reason": u.QualifyingReason,
+		"ownership_paths":   paths,
+	}
+	if u.ControlType != "" {
+		result["control_type"] = u.ControlType
+	}
+	return result
+}
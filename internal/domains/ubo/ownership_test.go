@@ -0,0 +1,369 @@
+package ubo
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/entities"
+
+	"github.com/google/uuid"
+)
+
+// graphFixture builds ownership graph data for tests
+type graphFixture struct {
+	data entities.OwnershipGraphData
+}
+
+func (f *graphFixture) entity(name, entityType string) uuid.UUID {
+	id := uuid.New()
+	externalID := uuid.New().String()
+	f.data.Entities = append(f.data.Entities, entities.Entity{
+		EntityID:   id,
+		ExternalID: &externalID,
+		Name:       name,
+		EntityType: &entities.EntityType{Name: entityType},
+	})
+	return id
+}
+
+func (f *graphFixture) externalID(id uuid.UUID) uuid.UUID {
+	for _, e := range f.data.Entities {
+		if e.EntityID == id {
+			return uuid.MustParse(*e.ExternalID)
+		}
+	}
+	panic("unknown entity")
+}
+
+func (f *graphFixture) owns(owner, owned uuid.UUID, pct float64) {
+	f.data.UBORegistry = append(f.data.UBORegistry, entities.UBORegistry{
+		SubjectEntityID:     owned,
+		UBOProperPersonID:   owner,
+		RelationshipType:    entities.UBORelationshipDirectOwnership,
+		OwnershipPercentage: &pct,
+	})
+}
+
+func (f *graphFixture) partner(partner, partnership uuid.UUID, partnerType string, pct float64) {
+	interest := entities.PartnershipInterest{
+		PartnershipID: f.externalID(partnership),
+		EntityID:      partner,
+		PartnerType:   partnerType,
+		IsActive:      true,
+	}
+	if pct > 0 {
+		interest.OwnershipPercentage = &pct
+	}
+	f.data.PartnershipInterests = append(f.data.PartnershipInterests, interest)
+}
+
+func (f *graphFixture) trustParty(party, trust uuid.UUID, role string) {
+	f.data.TrustParties = append(f.data.TrustParties, entities.TrustParty{
+		TrustID:   f.externalID(trust),
+		EntityID:  party,
+		PartyRole: role,
+		IsActive:  true,
+	})
+}
+
+func findUBO(ubos []CalculatedUBO, name string) *CalculatedUBO {
+	for i := range ubos {
+		if ubos[i].Name == name {
+			return &ubos[i]
+		}
+	}
+	return nil
+}
+
+func TestCalculateUBOs_MultipliesIndirectOwnership(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Target Ltd", entities.EntityTypeLimitedCompany)
+	holdCo := f.entity("HoldCo Ltd", entities.EntityTypeLimitedCompany)
+	alice := f.entity("Alice", entities.EntityTypeProperPerson)
+	bob := f.entity("Bob", entities.EntityTypeProperPerson)
+
+	f.owns(holdCo, target, 50)
+	f.owns(alice, holdCo, 60)
+	f.owns(bob, target, 20)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{
+		SubjectEntityID:     target,
+		RegulatoryFramework: "EU_5MLD",
+	})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+
+	if result.Threshold != 25.0 {
+		t.Errorf("Expected framework threshold 25.0, got %.2f", result.Threshold)
+	}
+	if len(result.UBOs) != 1 {
+		t.Fatalf("Expected 1 UBO, got %d: %+v", len(result.UBOs), result.UBOs)
+	}
+
+	ubo := result.UBOs[0]
+	if ubo.Name != "Alice" || ubo.TotalOwnership != 30.0 {
+		t.Errorf("Expected Alice with 30%%, got %s with %.2f%%", ubo.Name, ubo.TotalOwnership)
+	}
+	if ubo.RelationshipType != entities.UBORelationshipIndirectOwnership {
+		t.Errorf("Expected INDIRECT_OWNERSHIP, got %s", ubo.RelationshipType)
+	}
+
+	expectedPath := "Alice -[60.00%]-> HoldCo Ltd -[50.00%]-> Target Ltd = 30.00%"
+	if len(ubo.Paths) != 1 || ubo.Paths[0].String() != expectedPath {
+		t.Errorf("Expected path %q, got %+v", expectedPath, ubo.Paths)
+	}
+
+	if below := findUBO(result.BelowThreshold, "Bob"); below == nil || below.TotalOwnership != 20.0 {
+		t.Errorf("Expected Bob below threshold with 20%%, got %+v", result.BelowThreshold)
+	}
+}
+
+func TestCalculateUBOs_SumsMultiplePaths(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Target Ltd", entities.EntityTypeLimitedCompany)
+	holdCo := f.entity("HoldCo Ltd", entities.EntityTypeLimitedCompany)
+	carol := f.entity("Carol", entities.EntityTypeProperPerson)
+
+	f.owns(carol, target, 15)
+	f.owns(holdCo, target, 40)
+	f.owns(carol, holdCo, 30)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{SubjectEntityID: target})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+
+	ubo := findUBO(result.UBOs, "Carol")
+	if ubo == nil {
+		t.Fatalf("Expected Carol to qualify, got %+v", result)
+	}
+	if ubo.TotalOwnership != 27.0 {
+		t.Errorf("Expected 27%% combined ownership, got %.2f%%", ubo.TotalOwnership)
+	}
+	if len(ubo.Paths) != 2 {
+		t.Errorf("Expected 2 ownership paths, got %d", len(ubo.Paths))
+	}
+}
+
+func TestCalculateUBOs_HandlesCrossHoldings(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Target Ltd", entities.EntityTypeLimitedCompany)
+	alpha := f.entity("Alpha Ltd", entities.EntityTypeLimitedCompany)
+	beta := f.entity("Beta Ltd", entities.EntityTypeLimitedCompany)
+	dana := f.entity("Dana", entities.EntityTypeProperPerson)
+
+	f.owns(alpha, target, 100)
+	f.owns(beta, alpha, 50)
+	f.owns(alpha, beta, 50)
+	f.owns(dana, beta, 80)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{SubjectEntityID: target})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+
+	if len(result.Cycles) != 1 {
+		t.Fatalf("Expected 1 cycle, got %v", result.Cycles)
+	}
+	cycle := strings.Join(result.Cycles[0], " -> ")
+	if !strings.Contains(cycle, "Alpha Ltd") || !strings.Contains(cycle, "Beta Ltd") {
+		t.Errorf("Expected Alpha/Beta cycle, got %s", cycle)
+	}
+
+	// 80% of Beta, which holds 50% of Alpha around a 25% round trip: 0.4 / 0.75
+	ubo := findUBO(result.UBOs, "Dana")
+	if ubo == nil || math.Abs(ubo.TotalOwnership-40.0/0.75) > 1e-6 {
+		t.Errorf("Expected Dana with 53.33%%, got %+v", ubo)
+	}
+	if result.CyclesUnresolved {
+		t.Error("Expected the cross-holding to be resolved")
+	}
+	if len(ubo.Paths) != 1 || ubo.Paths[0].Effective != 40.0 {
+		t.Errorf("Expected one acyclic explanatory path at 40%%, got %+v", ubo.Paths)
+	}
+}
+
+func TestCalculateUBOs_FlagsSelfOwnedLoop(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Target Ltd", entities.EntityTypeLimitedCompany)
+	alpha := f.entity("Alpha Ltd", entities.EntityTypeLimitedCompany)
+	beta := f.entity("Beta Ltd", entities.EntityTypeLimitedCompany)
+	erin := f.entity("Erin", entities.EntityTypeProperPerson)
+
+	// Alpha and Beta own each other entirely: the loop has no outside owner
+	f.owns(alpha, target, 60)
+	f.owns(beta, alpha, 100)
+	f.owns(alpha, beta, 100)
+	f.owns(erin, target, 40)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{SubjectEntityID: target})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+	if !result.CyclesUnresolved {
+		t.Error("Expected the self-owned loop to be flagged as unresolved")
+	}
+	if ubo := findUBO(result.UBOs, "Erin"); ubo == nil || ubo.TotalOwnership != 40.0 {
+		t.Errorf("Expected Erin with 40%% from acyclic paths, got %+v", ubo)
+	}
+}
+
+func TestFindEntityByName_RejectsAmbiguousNames(t *testing.T) {
+	f := &graphFixture{}
+	f.entity("Acme Ltd", entities.EntityTypeLimitedCompany)
+	f.entity("ACME LTD", entities.EntityTypeLimitedCompany)
+	unique := f.entity("Other Ltd", entities.EntityTypeLimitedCompany)
+	graph := BuildOwnershipGraph(&f.data)
+
+	if _, err := graph.FindEntityByName("acme ltd"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguity error, got %v", err)
+	}
+	if e, err := graph.FindEntityByName("other ltd"); err != nil || e.EntityID != unique {
+		t.Errorf("Expected Other Ltd, got %v, %v", e, err)
+	}
+}
+
+func TestCalculateUBOs_PartnershipGeneralPartnerControl(t *testing.T) {
+	f := &graphFixture{}
+	fund := f.entity("Alpha Fund LP", entities.EntityTypePartnership)
+	gpCo := f.entity("Alpha GP Ltd", entities.EntityTypeLimitedCompany)
+	erin := f.entity("Erin", entities.EntityTypeProperPerson)
+	frank := f.entity("Frank", entities.EntityTypeProperPerson)
+
+	f.partner(gpCo, fund, entities.PartnerTypeGeneral, 1)
+	f.partner(frank, fund, entities.PartnerTypeLimited, 10)
+	f.owns(erin, gpCo, 100)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{SubjectEntityID: fund})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+
+	ubo := findUBO(result.UBOs, "Erin")
+	if ubo == nil {
+		t.Fatalf("Expected Erin as control UBO, got %+v", result)
+	}
+	if ubo.RelationshipType != entities.UBORelationshipPartnershipControl || ubo.ControlType != "GENERAL_PARTNER" {
+		t.Errorf("Expected GP control, got %s/%s", ubo.RelationshipType, ubo.ControlType)
+	}
+	if findUBO(result.UBOs, "Frank") != nil {
+		t.Errorf("Frank (10%% LP) should not qualify")
+	}
+}
+
+func TestCalculateUBOs_TrustPartiesOfOwningTrust(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Target Ltd", entities.EntityTypeLimitedCompany)
+	trust := f.entity("Family Trust", entities.EntityTypeTrust)
+	settlor := f.entity("Grace", entities.EntityTypeProperPerson)
+	trusteeCo := f.entity("Trustees Ltd", entities.EntityTypeLimitedCompany)
+	director := f.entity("Henry", entities.EntityTypeProperPerson)
+
+	f.owns(trust, target, 40)
+	f.trustParty(settlor, trust, entities.TrustPartyRoleSettlor)
+	f.trustParty(trusteeCo, trust, entities.TrustPartyRoleTrustee)
+	f.owns(director, trusteeCo, 75)
+
+	result, err := BuildOwnershipGraph(&f.data).CalculateUBOs(CalculationRequest{SubjectEntityID: target})
+	if err != nil {
+		t.Fatalf("CalculateUBOs failed: %v", err)
+	}
+
+	if ubo := findUBO(result.UBOs, "Grace"); ubo == nil || ubo.RelationshipType != entities.UBORelationshipTrustSettlor {
+		t.Errorf("Expected Grace as trust settlor, got %+v", ubo)
+	}
+	if ubo := findUBO(result.UBOs, "Henry"); ubo == nil || ubo.RelationshipType != entities.UBORelationshipTrustTrustee {
+		t.Errorf("Expected Henry as trustee via corporate trustee, got %+v", ubo)
+	}
+}
+
+func TestCalculateUBOs_UnknownSubject(t *testing.T) {
+	_, err := BuildOwnershipGraph(&entities.OwnershipGraphData{}).CalculateUBOs(CalculationRequest{SubjectEntityID: uuid.New()})
+	if err == nil {
+		t.Fatal("Expected error for unknown subject entity")
+	}
+}
+
+// graphStore serves a fixed ownership graph through the DataStore interface
+// and records calculated UBOs
+type graphStore struct {
+	datastore.DataStore
+	data      *entities.OwnershipGraphData
+	loadedFor string
+	saved     []entities.UBORegistry
+}
+
+func (s *graphStore) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	s.loadedFor = cbuID
+	return s.data, nil
+}
+
+func (s *graphStore) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	s.saved = entries
+	return nil
+}
+
+func TestExecuteDSL_ResolveUBOsAppliesDSLThreshold(t *testing.T) {
+	f := &graphFixture{}
+	target := f.entity("Acme Holdings Ltd", entities.EntityTypeLimitedCompany)
+	alice := f.entity("Alice", entities.EntityTypeProperPerson)
+	bob := f.entity("Bob", entities.EntityTypeProperPerson)
+	f.owns(alice, target, 70)
+	f.owns(bob, target, 12)
+
+	cbuID := uuid.New()
+	ds := &graphStore{data: &f.data}
+	domain := NewUBODomainForCBU(ds, cbuID.String())
+	dsl := domain.GenerateSampleUBOWorkflow("Acme Holdings Ltd", "GB")
+	dsl = strings.Replace(dsl, "(ownership_threshold 25.0)", "(ownership_threshold 10.0)", 1)
+
+	result, err := domain.ExecuteDSL(context.Background(), dsl)
+	if err != nil {
+		t.Fatalf("ExecuteDSL failed: %v", err)
+	}
+
+	resolved, ok := result["ubos"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected ubos result map, got %T", result["ubos"])
+	}
+	if resolved["threshold_applied"] != 10.0 {
+		t.Errorf("Expected threshold 10.0 from DSL, got %v", resolved["threshold_applied"])
+	}
+
+	ubos := resolved["ubos"].([]map[string]interface{})
+	if len(ubos) != 2 {
+		t.Fatalf("Expected 2 UBOs at 10%% threshold, got %d", len(ubos))
+	}
+	if ubos[0]["name"] != "Alice" || ubos[0]["relationship_type"] != entities.UBORelationshipDirectOwnership {
+		t.Errorf("Expected Alice as direct owner first, got %v", ubos[0])
+	}
+	if paths := ubos[1]["ownership_paths"].([]string); len(paths) != 1 || paths[0] != "Bob -[12.00%]-> Acme Holdings Ltd = 12.00%" {
+		t.Errorf("Unexpected ownership path for Bob: %v", paths)
+	}
+	if ds.loadedFor != cbuID.String() {
+		t.Errorf("Expected graph scoped to CBU %s, got %q", cbuID, ds.loadedFor)
+	}
+	if len(ds.saved) != 2 || ds.saved[0].CBUID != cbuID || ds.saved[0].WorkflowType != entities.UBOWorkflowRecursiveAnalysis {
+		t.Errorf("Expected 2 calculated registry rows for the CBU, got %+v", ds.saved)
+	}
+	if resolved["persisted"] != true {
+		t.Errorf("Expected persisted result, got %v", resolved["persisted"])
+	}
+}
+
+func TestExtractFormText(t *testing.T) {
+	dsl := `(ubo.resolve-ubos-extended (x "1"))
+(ubo.resolve-ubos (entity_name "A (B) \"C\"") (ownership_threshold 25.0))`
+
+	form, ok := extractFormText(dsl, "ubo.resolve-ubos")
+	if !ok {
+		t.Fatal("Expected form to be found")
+	}
+	if !strings.HasPrefix(form, "(ubo.resolve-ubos (entity_name") || !strings.HasSuffix(form, "(ownership_threshold 25.0))") {
+		t.Errorf("Unexpected form extracted: %s", form)
+	}
+}
@@ -281,14 +281,16 @@ func AddDiscoveredServices(currentDSL string, plan ServiceDiscoveryPlan) (string
 
 	// Append (services.discover)
 	b.WriteString("(services.discover\n")
-	for product, services := range plan.ProductServices {
+	// Products and services are emitted in sorted order so that regenerating
+	// the same plan yields the same DSL text
+	for _, product := range sortedKeys(plan.ProductServices) {
 		b.WriteString(fmt.Sprintf("  (for.product %q\n", product))
 		// Use a map to de-duplicate service names
 		serviceNames := make(map[string]bool)
-		for _, service := range services {
+		for _, service := range plan.ProductServices[product] {
 			serviceNames[service.Name] = true
 		}
-		for serviceName := range serviceNames {
+		for _, serviceName := range sortedKeys(serviceNames) {
 			b.WriteString(fmt.Sprintf("    (service %q)\n", serviceName))
 		}
 		b.WriteString("  )\n")
@@ -350,7 +352,8 @@ func AddDiscoveredResources(currentDSL string, plan ResourceDiscoveryPlan) (stri
 		}
 	}
 
-	for _, resource := range allResources {
+	for _, resourceID := range sortedKeys(allResources) {
+		resource := allResources[resourceID]
 		b.WriteString(fmt.Sprintf("  (resource.create %q\n", resource.Name))
 		b.WriteString(fmt.Sprintf("    (owner %q)\n", resource.Owner))
 
@@ -369,7 +372,8 @@ func AddDiscoveredResources(currentDSL string, plan ResourceDiscoveryPlan) (stri
 func RenderBindings(assign map[string]string) string {
 	var b strings.Builder
 	b.WriteString("(values.bind\n")
-	for id, raw := range assign {
+	for _, id := range sortedKeys(assign) {
+		raw := assign[id]
 		// raw is a JSON literal; print as string if it's JSON string
 		var s string
 		if err := json.Unmarshal([]byte(raw), &s); err == nil {
@@ -382,6 +386,16 @@ func RenderBindings(assign map[string]string) string {
 	return b.String()
 }
 
+// sortedKeys returns the keys of a map in sorted order, for deterministic output
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // --- State 6: Populate Attributes ---
 
 // VarByAttrID creates canonical variable form
@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"dsl-ob-poc/internal/shared-dsl/format"
+	"dsl-ob-poc/internal/shared-dsl/parser"
 )
 
 // executor.go implements S-expression parsing and execution for DSL verbs with UUID attributes
@@ -33,6 +36,9 @@ type ExecutionResult struct {
 	Error       string                 `json:"error,omitempty"`
 	Variables   map[string]interface{} `json:"variables"`
 	StateChange *StateChange           `json:"state_change,omitempty"`
+	Line        int                    `json:"line,omitempty"`
+	Column      int                    `json:"column,omitempty"`
+	Children    []*ExecutionResult     `json:"children,omitempty"`
 }
 
 // StateChange represents a change in onboarding state
@@ -67,135 +73,156 @@ func NewDSLExecutor(cbuID string) *DSLExecutor {
 // S-Expression Parsing
 // =============================================================================
 
-// SExpression represents a parsed S-expression
+// SExpression represents a parsed S-expression. It is a typed view over a
+// shared-dsl/parser Node, so the executor sees exactly what validation sees.
 type SExpression struct {
 	Operator string        `json:"operator"`
 	Args     []interface{} `json:"args"`
 	Raw      string        `json:"raw"`
+	Line     int           `json:"line"`
+	Column   int           `json:"column"`
+
+	node *parser.Node
+}
+
+// AttributeRef is an @attr{uuid:name} reference appearing as an argument.
+// It is replaced by the bound value when the attribute has been bound.
+type AttributeRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
 }
 
 // ParseSExpression parses a single S-expression from a string
 func (e *DSLExecutor) ParseSExpression(input string) (*SExpression, error) {
-	// Clean and normalize the input
-	cleaned := strings.TrimSpace(input)
-	if !strings.HasPrefix(cleaned, "(") || !strings.HasSuffix(cleaned, ")") {
-		return nil, fmt.Errorf("invalid S-expression: must start with '(' and end with ')'")
+	ast, err := parser.Parse(input)
+	if err != nil {
+		return nil, err
 	}
 
-	// Remove outer parentheses
-	content := cleaned[1 : len(cleaned)-1]
-	content = strings.TrimSpace(content)
-
-	// Parse the operator and arguments
-	parts := e.tokenize(content)
-	if len(parts) == 0 {
+	switch len(ast.Root.Children) {
+	case 0:
 		return nil, fmt.Errorf("empty S-expression")
+	case 1:
+	default:
+		return nil, fmt.Errorf("expected a single S-expression, found %d", len(ast.Root.Children))
 	}
 
-	sexpr := &SExpression{
-		Operator: parts[0],
-		Args:     []interface{}{},
-		Raw:      input,
-	}
-
-	// Parse arguments
-	for i := 1; i < len(parts); i++ {
-		arg := e.parseArgument(parts[i])
-		sexpr.Args = append(sexpr.Args, arg)
-	}
-
+	sexpr := e.fromNode(ast.Root.Children[0])
+	sexpr.Raw = input
 	return sexpr, nil
 }
 
-// tokenize splits S-expression content into tokens, handling nested expressions
-func (e *DSLExecutor) tokenize(content string) []string {
-	var tokens []string
-	var current strings.Builder
-	depth := 0
-	inQuotes := false
-
-	for _, char := range content {
-		switch char {
-		case '"':
-			inQuotes = !inQuotes
-			current.WriteRune(char)
-		case '(':
-			if !inQuotes {
-				depth++
-			}
-			current.WriteRune(char)
-		case ')':
-			if !inQuotes {
-				depth--
-			}
-			current.WriteRune(char)
-		case ' ', '\t', '\n':
-			if !inQuotes && depth == 0 {
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
-			} else {
-				current.WriteRune(char)
-			}
-		default:
-			current.WriteRune(char)
-		}
+// ParseDocument parses a DSL document into its top-level S-expressions
+func (e *DSLExecutor) ParseDocument(input string) ([]*SExpression, error) {
+	ast, err := parser.Parse(input)
+	if err != nil {
+		return nil, err
 	}
 
-	if current.Len() > 0 {
-		tokens = append(tokens, current.String())
+	forms := make([]*SExpression, 0, len(ast.Root.Children))
+	for _, node := range ast.Root.Children {
+		forms = append(forms, e.fromNode(node))
 	}
-
-	return tokens
+	return forms, nil
 }
 
-// parseArgument parses a single argument, which can be a string, number, or nested S-expression
-func (e *DSLExecutor) parseArgument(arg string) interface{} {
-	arg = strings.TrimSpace(arg)
+// fromNode converts an expression node from the shared parser into an SExpression
+func (e *DSLExecutor) fromNode(node *parser.Node) *SExpression {
+	sexpr := &SExpression{
+		Operator: node.Value,
+		Args:     []interface{}{},
+		Raw:      FormatNode(node),
+		Line:     node.Line,
+		Column:   node.Column,
+		node:     node,
+	}
 
-	// Handle quoted strings
-	if strings.HasPrefix(arg, "\"") && strings.HasSuffix(arg, "\"") {
-		return strings.Trim(arg, "\"")
+	// Children[0] is the verb node
+	for _, child := range node.Children[1:] {
+		sexpr.Args = append(sexpr.Args, e.nodeValue(child))
 	}
 
-	// Handle nested S-expressions
-	if strings.HasPrefix(arg, "(") && strings.HasSuffix(arg, ")") {
-		nested, err := e.ParseSExpression(arg)
-		if err != nil {
-			return arg // Return as string if parsing fails
+	return sexpr
+}
+
+// nodeValue converts an argument node into its Go value
+func (e *DSLExecutor) nodeValue(node *parser.Node) interface{} {
+	switch node.Type {
+	case parser.ExpressionNode:
+		return e.fromNode(node)
+	case parser.NumberNode:
+		if num, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			if num == float64(int64(num)) {
+				return int64(num)
+			}
+			return num
+		}
+		return node.Value
+	case parser.BooleanNode:
+		return node.Value == "true"
+	case parser.AttributeNode:
+		return &AttributeRef{ID: node.AttributeID, Name: node.Name}
+	case parser.ListNode:
+		items := make([]interface{}, 0, len(node.Children))
+		for _, child := range node.Children {
+			items = append(items, e.nodeValue(child))
 		}
-		return nested
+		return items
+	default:
+		return node.Value
 	}
+}
 
-	// Handle numbers
-	if num, err := strconv.ParseFloat(arg, 64); err == nil {
-		if num == float64(int64(num)) {
-			return int64(num)
+// FormatNode renders a parser node back into S-expression text
+func FormatNode(node *parser.Node) string {
+	switch node.Type {
+	case parser.RootNode:
+		forms := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			forms = append(forms, FormatNode(child))
 		}
-		return num
+		return strings.Join(forms, "\n")
+	case parser.ExpressionNode:
+		parts := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			parts = append(parts, FormatNode(child))
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	case parser.StringNode:
+		return strconv.Quote(node.Value)
+	case parser.ListNode:
+		return format.Canonical(node)
+	default:
+		return node.Value
 	}
+}
 
-	// Handle booleans
-	if arg == "true" {
-		return true
-	}
-	if arg == "false" {
-		return false
+// resolveArgs replaces attribute references with values bound in the context,
+// recursing into nested expressions. Unbound references are left in place.
+func (e *DSLExecutor) resolveArgs(sexpr *SExpression) {
+	for i, arg := range sexpr.Args {
+		switch v := arg.(type) {
+		case *AttributeRef:
+			if value, ok := e.Context.Variables[v.ID]; ok {
+				sexpr.Args[i] = value
+			}
+		case *SExpression:
+			e.resolveArgs(v)
+		}
 	}
-
-	// Return as string
-	return arg
 }
 
 // =============================================================================
 // DSL Command Execution
 // =============================================================================
 
-// Execute parses and executes a DSL command
+// Execute parses and executes a DSL command. A document with several top-level
+// forms is executed in order and reported as a single result with Children.
 func (e *DSLExecutor) Execute(dslCommand string) (*ExecutionResult, error) {
-	// Parse the S-expression
-	sexpr, err := e.ParseSExpression(dslCommand)
+	forms, err := e.ParseDocument(dslCommand)
+	if err == nil && len(forms) == 0 {
+		err = fmt.Errorf("empty S-expression")
+	}
 	if err != nil {
 		return &ExecutionResult{
 			Success: false,
@@ -204,50 +231,142 @@ func (e *DSLExecutor) Execute(dslCommand string) (*ExecutionResult, error) {
 		}, err
 	}
 
-	// Execute the command based on operator
-	result := e.executeCommand(sexpr)
-
-	// Log the execution
-	e.Context.ExecutionLog = append(e.Context.ExecutionLog, dslCommand)
-	if !result.Success {
-		e.Context.ErrorLog = append(e.Context.ErrorLog, result.Error)
+	if len(forms) == 1 {
+		forms[0].Raw = dslCommand
+		return e.executeForm(forms[0]), nil
 	}
 
+	results := e.executeForms(forms)
+	result := &ExecutionResult{
+		Success:   true,
+		Command:   "document",
+		Children:  results,
+		Variables: e.Context.Variables,
+	}
+	for _, child := range results {
+		if !child.Success {
+			result.Success = false
+			result.Error = child.Error
+			break
+		}
+	}
 	return result, nil
 }
 
+// ExecuteDocument parses a DSL document with the shared parser and executes
+// every top-level form in order, stopping at the first failure. Each result
+// carries the source position of the form that produced it.
+func (e *DSLExecutor) ExecuteDocument(document string) ([]*ExecutionResult, error) {
+	forms, err := e.ParseDocument(document)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	}
+	return e.executeForms(forms), nil
+}
+
+// executeForms executes forms in order, stopping at the first failure
+func (e *DSLExecutor) executeForms(forms []*SExpression) []*ExecutionResult {
+	results := make([]*ExecutionResult, 0, len(forms))
+	for _, form := range forms {
+		result := e.executeForm(form)
+		results = append(results, result)
+		if !result.Success {
+			break
+		}
+	}
+	return results
+}
+
+// executeForm executes one form and records it in the execution log. Wrapper
+// forms (see DispatchedForms) execute their nested commands in order.
+func (e *DSLExecutor) executeForm(sexpr *SExpression) *ExecutionResult {
+	var result *ExecutionResult
+	if targets := DispatchedForms(sexpr.node); targets[0] != sexpr.node {
+		nested := make([]*SExpression, 0, len(targets))
+		for _, target := range targets {
+			nested = append(nested, e.fromNode(target))
+		}
+		result = &ExecutionResult{
+			Success:   true,
+			Command:   sexpr.Operator,
+			Children:  e.executeForms(nested),
+			Variables: e.Context.Variables,
+		}
+		for _, child := range result.Children {
+			if !child.Success {
+				result.Success = false
+				result.Error = child.Error
+				break
+			}
+		}
+	} else {
+		e.resolveArgs(sexpr)
+		result = e.executeCommand(sexpr)
+		e.Context.ExecutionLog = append(e.Context.ExecutionLog, sexpr.Raw)
+		if !result.Success {
+			e.Context.ErrorLog = append(e.Context.ErrorLog, result.Error)
+		}
+	}
+
+	result.Line = sexpr.Line
+	result.Column = sexpr.Column
+	return result
+}
+
+// DispatchedForms returns the forms the executor hands to command handlers when
+// it executes a top-level form: the form itself, or, for a wrapper form whose
+// verb has no handler but which contains command forms, e.g. a grouping
+// (workflow.sequence (case.create ...) (products.add ...)), those nested
+// command forms in order. The validator checks exactly these forms.
+func DispatchedForms(form *parser.Node) []*parser.Node {
+	if isCommand(form.Value) {
+		return []*parser.Node{form}
+	}
+
+	var nested []*parser.Node
+	for _, child := range form.Children[1:] {
+		if child.Type == parser.ExpressionNode && isCommand(child.Value) {
+			nested = append(nested, child)
+		}
+	}
+	if len(nested) == 0 {
+		return []*parser.Node{form}
+	}
+	return nested
+}
+
+// isCommand reports whether the executor has a handler for the operator
+func isCommand(operator string) bool {
+	_, ok := commandHandlers[operator]
+	return ok
+}
+
+// commandHandlers maps operators to their executors
+var commandHandlers = map[string]func(*DSLExecutor, *SExpression) *ExecutionResult{
+	"case.create":         (*DSLExecutor).executeCaseCreate,
+	"case.update":         (*DSLExecutor).executeCaseUpdate,
+	"case.approve":        (*DSLExecutor).executeCaseApprove,
+	"products.add":        (*DSLExecutor).executeProductsAdd,
+	"kyc.start":           (*DSLExecutor).executeKYCStart,
+	"services.discover":   (*DSLExecutor).executeServicesDiscover,
+	"resources.plan":      (*DSLExecutor).executeResourcesPlan,
+	"values.bind":         (*DSLExecutor).executeValuesBind,
+	"attributes.define":   (*DSLExecutor).executeAttributesDefine,
+	"workflow.transition": (*DSLExecutor).executeWorkflowTransition,
+	"tasks.create":        (*DSLExecutor).executeTasksCreate,
+}
+
 // executeCommand executes a parsed S-expression command
 func (e *DSLExecutor) executeCommand(sexpr *SExpression) *ExecutionResult {
-	switch sexpr.Operator {
-	case "case.create":
-		return e.executeCaseCreate(sexpr)
-	case "case.update":
-		return e.executeCaseUpdate(sexpr)
-	case "case.approve":
-		return e.executeCaseApprove(sexpr)
-	case "products.add":
-		return e.executeProductsAdd(sexpr)
-	case "kyc.start":
-		return e.executeKYCStart(sexpr)
-	case "services.discover":
-		return e.executeServicesDiscover(sexpr)
-	case "resources.plan":
-		return e.executeResourcesPlan(sexpr)
-	case "values.bind":
-		return e.executeValuesBind(sexpr)
-	case "attributes.define":
-		return e.executeAttributesDefine(sexpr)
-	case "workflow.transition":
-		return e.executeWorkflowTransition(sexpr)
-	case "tasks.create":
-		return e.executeTasksCreate(sexpr)
-	default:
+	handler, ok := commandHandlers[sexpr.Operator]
+	if !ok {
 		return &ExecutionResult{
 			Success: false,
 			Command: sexpr.Operator,
 			Error:   fmt.Sprintf("Unknown command: %s", sexpr.Operator),
 		}
 	}
+	return handler(e, sexpr)
 }
 
 // =============================================================================
@@ -309,10 +428,17 @@ func (e *DSLExecutor) executeCaseCreate(sexpr *SExpression) *ExecutionResult {
 func (e *DSLExecutor) executeProductsAdd(sexpr *SExpression) *ExecutionResult {
 	var products []string
 
-	// Parse product arguments
+	// Parse product arguments, given inline or as a list
 	for _, arg := range sexpr.Args {
-		if product, ok := arg.(string); ok {
-			products = append(products, product)
+		switch v := arg.(type) {
+		case string:
+			products = append(products, v)
+		case []interface{}:
+			for _, item := range v {
+				if product, ok := item.(string); ok {
+					products = append(products, product)
+				}
+			}
 		}
 	}
 
@@ -583,6 +709,8 @@ func (e *DSLExecutor) executeTasksCreate(sexpr *SExpression) *ExecutionResult {
 // =============================================================================
 
 // ExecuteBatch executes multiple DSL commands in sequence
+//
+// Deprecated: join the commands into one document and use ExecuteDocument.
 func (e *DSLExecutor) ExecuteBatch(commands []string) ([]*ExecutionResult, error) {
 	results := make([]*ExecutionResult, 0, len(commands))
 
@@ -138,6 +138,32 @@ func TestDSLExecutorCommandExecution(t *testing.T) {
 		}
 	})
 
+	t.Run("Execute Products Add With List", func(t *testing.T) {
+		dsl := `(products.add ["CUSTODY", "FUND_ACCOUNTING"])`
+
+		result, err := executor.Execute(dsl)
+		if err != nil {
+			t.Fatalf("Failed to execute DSL: %v", err)
+		}
+
+		if !result.Success {
+			t.Fatalf("Expected successful execution, got error: %s", result.Error)
+		}
+
+		productList, ok := executor.Context.Variables["products"].([]string)
+		if !ok || len(productList) != 2 || productList[0] != "CUSTODY" || productList[1] != "FUND_ACCOUNTING" {
+			t.Errorf("Expected list products to be stored, got %v", executor.Context.Variables["products"])
+		}
+
+		forms, err := executor.ParseDocument(`(products.add ["CUSTODY",   "FUND_ACCOUNTING"])`)
+		if err != nil {
+			t.Fatalf("Failed to parse DSL: %v", err)
+		}
+		if forms[0].Raw != dsl {
+			t.Errorf("Expected canonical list text %q, got %q", dsl, forms[0].Raw)
+		}
+	})
+
 	t.Run("Execute Values Bind", func(t *testing.T) {
 		attrID := GenerateTestUUID("test-attr")
 		dsl := `(values.bind (bind (attr-id "` + attrID + `") (value "CBU-1234")))`
@@ -482,6 +508,119 @@ func TestDSLExecutorJSONSerialization(t *testing.T) {
 	})
 }
 
+func TestDSLExecutorDocumentExecution(t *testing.T) {
+	t.Run("Multi-Form Document With Positions", func(t *testing.T) {
+		executor := NewDSLExecutor("CBU-1234")
+		document := `; onboarding document
+(case.create (cbu.id "CBU-1234") (nature-purpose "UCITS equity fund"))
+(products.add "CUSTODY" "FUND_ACCOUNTING")`
+
+		results, err := executor.ExecuteDocument(document)
+		if err != nil {
+			t.Fatalf("Failed to execute document: %v", err)
+		}
+
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+
+		if results[1].Command != "products.add" || results[1].Line != 3 || results[1].Column != 1 {
+			t.Errorf("Expected products.add at 3:1, got %s at %d:%d", results[1].Command, results[1].Line, results[1].Column)
+		}
+	})
+
+	t.Run("Nested Command Forms", func(t *testing.T) {
+		executor := NewDSLExecutor("CBU-1234")
+		document := `(workflow.sequence
+  (case.create (cbu.id "CBU-5678"))
+  (products.add "CUSTODY"))`
+
+		result, err := executor.Execute(document)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !result.Success || len(result.Children) != 2 {
+			t.Fatalf("Expected 2 successful nested results, got %+v", result)
+		}
+
+		if result.Children[1].Line != 3 {
+			t.Errorf("Expected nested products.add on line 3, got %d", result.Children[1].Line)
+		}
+
+		if executor.Context.CurrentCBU != "CBU-5678" {
+			t.Errorf("Expected nested case.create to set CBU, got %s", executor.Context.CurrentCBU)
+		}
+	})
+
+	t.Run("Attribute References Resolve To Bound Values", func(t *testing.T) {
+		executor := NewDSLExecutor("CBU-1234")
+		attrID := "8a5d1a77-e2f1-4b3c-9d8e-1f2a3b4c5d6e"
+		document := `(values.bind (bind (attr-id "` + attrID + `") (value "CBU-BOUND")))
+(case.create (cbu.id @attr{` + attrID + `:cbu.id}))`
+
+		results, err := executor.ExecuteDocument(document)
+		if err != nil {
+			t.Fatalf("Failed to execute document: %v", err)
+		}
+
+		if len(results) != 2 || !results[1].Success {
+			t.Fatalf("Expected case.create to succeed, got %+v", results)
+		}
+
+		if executor.Context.CurrentCBU != "CBU-BOUND" {
+			t.Errorf("Expected CBU resolved from attribute, got %s", executor.Context.CurrentCBU)
+		}
+	})
+
+	t.Run("Parse Errors Carry Positions", func(t *testing.T) {
+		executor := NewDSLExecutor("CBU-1234")
+
+		_, err := executor.ExecuteDocument("(case.create\n  (cbu.id \"CBU-1234\")")
+		if err == nil || !strings.Contains(err.Error(), "line") {
+			t.Errorf("Expected positioned parse error, got %v", err)
+		}
+	})
+}
+
+func TestValidatorChecksDispatchedForms(t *testing.T) {
+	validator := NewValidator(nil, NewVocabulary([]string{"case.create", "products.add"}))
+
+	tests := []struct {
+		name    string
+		dsl     string
+		wantErr string
+	}{
+		{"Wrapper Form", `(workflow.sequence
+  (case.create (cbu.id "CBU-1"))
+  (products.add "CUSTODY"))`, ""},
+		{"Unknown Nested Command", `(workflow.sequence
+  (case.create (cbu.id "CBU-1"))
+  (tasks.create (task.id "T-1")))`, "line 3, column 3: tasks.create"},
+		{"Unknown Top-Level Verb", `(case.explode (cbu.id "CBU-1"))`, "line 1, column 1: case.explode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.validateSemantics(tt.dsl)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected DSL to validate, got %v", err)
+				}
+				// ...and what validates also executes
+				results, execErr := NewDSLExecutor("CBU-1").ExecuteDocument(tt.dsl)
+				if execErr != nil || !results[0].Success {
+					t.Errorf("Expected validated DSL to execute, got %+v, %v", results, execErr)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 // Benchmark tests for performance validation
 func BenchmarkDSLExecutorParsing(b *testing.B) {
 	executor := NewDSLExecutor("CBU-1234")
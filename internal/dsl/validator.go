@@ -3,8 +3,8 @@ package dsl
 import (
 	"context"
 	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/shared-dsl/parser"
 	"fmt"
-	"strings"
 )
 
 // Validator provides methods for validating DSL.
@@ -39,39 +39,25 @@ func (v *Validator) Validate(ctx context.Context, dsl string) error {
 	return nil
 }
 
-// validateSyntax checks for basic syntactic correctness (e.g., balanced parentheses).
+// validateSyntax parses the DSL with the shared parser used by the executor,
+// so a document that validates is one the executor can run.
 func (v *Validator) validateSyntax(dsl string) error {
-	balance := 0
-	for _, r := range dsl {
-		if r == '(' {
-			balance++
-		} else if r == ')' {
-			balance--
-		}
-		if balance < 0 {
-			return fmt.Errorf("unbalanced parentheses")
-		}
-	}
-	if balance != 0 {
-		return fmt.Errorf("unbalanced parentheses")
-	}
-	return nil
+	_, err := parser.Parse(dsl)
+	return err
 }
 
-// validateSemantics checks if the verbs used in the DSL are valid.
+// validateSemantics checks the verbs of the forms the executor dispatches: every
+// top-level command, and the nested commands of wrapper forms.
 func (v *Validator) validateSemantics(dsl string) error {
-	lines := strings.Split(dsl, "\n")
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(trimmed, "(") {
-			parts := strings.SplitN(trimmed[1:], " ", 2)
-			verb := parts[0]
+	ast, err := parser.Parse(dsl)
+	if err != nil {
+		return err
+	}
 
-			if !v.vocabulary.IsValidVerb(verb) {
-				// More robustly ignore non-verb expressions
-				if !isNonVerb(verb) {
-					return fmt.Errorf("unknown verb: %s", verb)
-				}
+	for _, form := range ast.Root.Children {
+		for _, target := range DispatchedForms(form) {
+			if !v.vocabulary.IsValidVerb(target.Value) && !isNonVerb(target.Value) {
+				return fmt.Errorf("unknown verb at line %d, column %d: %s", target.Line, target.Column, target.Value)
 			}
 		}
 	}
@@ -90,13 +76,13 @@ func isNonVerb(token string) bool {
 
 // validateAttributes checks if the attribute UUIDs used in the DSL exist in the dictionary.
 func (v *Validator) validateAttributes(ctx context.Context, dsl string) error {
-    ids := ExtractAttributeIDs(dsl)
-    for _, id := range ids {
-        _, err := v.ds.GetDictionaryAttributeByID(ctx, id)
-        if err != nil {
-            return fmt.Errorf("attribute with ID %s not found in dictionary: %w", id, err)
-        }
-    }
+	ids := ExtractAttributeIDs(dsl)
+	for _, id := range ids {
+		_, err := v.ds.GetDictionaryAttributeByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("attribute with ID %s not found in dictionary: %w", id, err)
+		}
+	}
 
-    return nil
+	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 
 	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/statemachine"
 	"dsl-ob-poc/internal/store"
 )
 
@@ -25,7 +26,8 @@ const (
 	DSLStateSuspended DSLLifecycleState = "SUSPENDED" // DSL execution suspended
 )
 
-// DSLDomainState represents the business domain state
+// DSLDomainState represents the business domain state, named in the
+// lifecycle vocabulary of the shared onboarding state machine
 type DSLDomainState string
 
 const (
@@ -112,7 +114,7 @@ func (lm *DSLLifecycleManager) ExtendDSL(onboardingID, cbuID string, dslFragment
 	}
 
 	// Validate domain state transition
-	if err := lm.validateDomainStateTransition(currentDSL, newDomainState); err != nil {
+	if err := lm.validateDomainStateTransition(ctx, cbuID, newDomainState); err != nil {
 		return nil, fmt.Errorf("invalid domain state transition: %w", err)
 	}
 
@@ -277,74 +279,49 @@ func (lm *DSLLifecycleManager) validateLifecycleStateTransition(current, new DSL
 	return fmt.Errorf("invalid lifecycle transition from %s to %s", current, new)
 }
 
-// validateDomainStateTransition ensures valid business domain progression
-func (lm *DSLLifecycleManager) validateDomainStateTransition(currentDSL string, newState DSLDomainState) error {
-	// Parse current DSL to determine current domain state
-	// This is a simplified version - in practice, you'd parse the DSL
-
-	// For now, assume progression is valid
-	// In practice, parse DSL to determine current state and validate transitions
-	return nil
+// validateDomainStateTransition checks the move from the state of the latest
+// stored DSL version against the shared onboarding state machine. Extending
+// the DSL without leaving the stored state is always allowed, and versions
+// stored without a state are not checked.
+func (lm *DSLLifecycleManager) validateDomainStateTransition(ctx context.Context, cbuID string, newState DSLDomainState) error {
+	latest, err := lm.dataStore.GetLatestDSLWithState(ctx, cbuID)
+	if err != nil {
+		return fmt.Errorf("failed to get current domain state: %w", err)
+	}
+	if latest.OnboardingState == "" || latest.OnboardingState == lm.mapDomainStateToStoreState(newState) {
+		return nil
+	}
+	return statemachine.Onboarding().CanTransition(string(latest.OnboardingState), string(newState))
 }
 
 // determineLifecycleState maps domain state to appropriate lifecycle state
 func (lm *DSLLifecycleManager) determineLifecycleState(domainState DSLDomainState) DSLLifecycleState {
-	switch domainState {
-	case DomainStateCreated, DomainStateProductsAdded, DomainStateKYCDiscovered,
-		DomainStateServicesDiscovered, DomainStateResourcesDiscovered,
-		DomainStateAttributesPopulated:
-		return DSLStateCreating
-	case DomainStateValuesBound:
-		return DSLStateReady
-	case DomainStateCompleted:
-		return DSLStateExecuted
-	default:
+	lifecycleState, err := statemachine.Onboarding().Project(string(domainState), statemachine.ProjectionDSLLifecycle)
+	if err != nil {
 		return DSLStateCreating
 	}
+	return DSLLifecycleState(lifecycleState)
 }
 
-// mapDomainStateToStoreState maps domain states to store states
+// mapDomainStateToStoreState maps domain states to store states through the
+// state machine's store projection. The store has no state for DSL that is
+// complete but not yet executed, so VALUES_BOUND is stored as
+// ATTRIBUTES_POPULATED rather than COMPLETED: only execution completes an
+// onboarding. Reading the version back reports ATTRIBUTES_POPULATED.
 func (lm *DSLLifecycleManager) mapDomainStateToStoreState(domainState DSLDomainState) store.OnboardingState {
-	switch domainState {
-	case DomainStateCreated:
-		return store.StateCreated
-	case DomainStateProductsAdded:
-		return store.StateProductsAdded
-	case DomainStateKYCDiscovered:
-		return store.StateKYCDiscovered
-	case DomainStateServicesDiscovered:
-		return store.StateServicesDiscovered
-	case DomainStateResourcesDiscovered:
-		return store.StateResourcesDiscovered
-	case DomainStateAttributesPopulated:
-		return store.StateAttributesPopulated
-	case DomainStateValuesBound, DomainStateCompleted:
-		return store.StateCompleted
-	default:
+	storeState, err := store.ParseOnboardingState(string(domainState))
+	if err != nil {
 		return store.StateCreated
 	}
+	return storeState
 }
 
 // mapStoreStateToDomainState maps store states to domain states
 func (lm *DSLLifecycleManager) mapStoreStateToDomainState(storeState store.OnboardingState) DSLDomainState {
-	switch storeState {
-	case store.StateCreated:
-		return DomainStateCreated
-	case store.StateProductsAdded:
-		return DomainStateProductsAdded
-	case store.StateKYCDiscovered:
-		return DomainStateKYCDiscovered
-	case store.StateServicesDiscovered:
-		return DomainStateServicesDiscovered
-	case store.StateResourcesDiscovered:
-		return DomainStateResourcesDiscovered
-	case store.StateAttributesPopulated:
-		return DomainStateAttributesPopulated
-	case store.StateCompleted:
-		return DomainStateCompleted
-	default:
-		return DomainStateCreated
+	if name, ok := statemachine.Onboarding().NameIn(string(storeState), statemachine.VocabularyLifecycle); ok {
+		return DSLDomainState(name)
 	}
+	return DomainStateCreated
 }
 
 // generateInitialDSL creates domain-specific initial DSL
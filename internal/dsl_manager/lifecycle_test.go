@@ -0,0 +1,38 @@
+package dsl_manager
+
+import (
+	"context"
+	"testing"
+
+	"dsl-ob-poc/internal/memstore"
+	"dsl-ob-poc/internal/store"
+)
+
+func TestExtendDSL_ValuesBoundIsStoredAsAttributesPopulated(t *testing.T) {
+	ctx := context.Background()
+	ds := memstore.New()
+	cbuID, err := ds.CreateCBU(ctx, "Acme Fund", "test", "testing")
+	if err != nil {
+		t.Fatalf("CreateCBU failed: %v", err)
+	}
+	if _, err := ds.InsertDSLWithState(ctx, cbuID, `(case.create (cbu.id "x"))`, store.StateAttributesPopulated); err != nil {
+		t.Fatalf("InsertDSLWithState failed: %v", err)
+	}
+
+	lm := NewDSLLifecycleManager(ds)
+	snapshot, err := lm.ExtendDSL("onboarding-1", cbuID, `(values.bind (attr-id "a"))`, DomainStateValuesBound)
+	if err != nil {
+		t.Fatalf("ExtendDSL failed: %v", err)
+	}
+	if snapshot.LifecycleState != DSLStateReady {
+		t.Errorf("LifecycleState = %s, want %s", snapshot.LifecycleState, DSLStateReady)
+	}
+
+	latest, err := ds.GetLatestDSLWithState(ctx, cbuID)
+	if err != nil {
+		t.Fatalf("GetLatestDSLWithState failed: %v", err)
+	}
+	if latest.OnboardingState != store.StateAttributesPopulated {
+		t.Errorf("stored state = %s, want %s", latest.OnboardingState, store.StateAttributesPopulated)
+	}
+}
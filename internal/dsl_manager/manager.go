@@ -12,10 +12,12 @@ import (
 
 	"dsl-ob-poc/internal/datastore"
 	"dsl-ob-poc/internal/shared-dsl/session"
+	"dsl-ob-poc/internal/statemachine"
 	"dsl-ob-poc/internal/store"
 )
 
-// OnboardingState represents the structured onboarding progression states
+// OnboardingState represents the structured onboarding progression states,
+// named in the dsl_manager vocabulary of the shared onboarding state machine
 type OnboardingState string
 
 const (
@@ -77,6 +79,7 @@ type ResourceInstance struct {
 type DSLManager struct {
 	sessionManager *session.Manager
 	dataStore      datastore.DataStore
+	states         *statemachine.Machine
 
 	// Active onboarding processes
 	processes map[string]*OnboardingProcess
@@ -85,11 +88,17 @@ type DSLManager struct {
 
 // NewDSLManager creates a new DSL Manager with clean state machine
 func NewDSLManager(dataStore datastore.DataStore) *DSLManager {
-	return &DSLManager{
+	m := &DSLManager{
 		sessionManager: session.NewManager(),
 		dataStore:      dataStore,
 		processes:      make(map[string]*OnboardingProcess),
 	}
+	m.states = statemachine.Onboarding().WithHandlers(statemachine.Handlers{
+		Actions: map[string]statemachine.Action{
+			"record_completion": m.recordCompletion,
+		},
+	})
+	return m
 }
 
 // ============================================================================
@@ -171,7 +180,7 @@ func (m *DSLManager) AssociateCBU(onboardingID string, cbuID string, cbuInfo map
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateCBUAssociated); err != nil {
+	if err := m.validateStateTransition(process, StateCBUAssociated); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -223,7 +232,7 @@ func (m *DSLManager) SelectProducts(onboardingID string, products []string, sele
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateProductsSelected); err != nil {
+	if err := m.validateStateTransition(process, StateProductsSelected); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -275,7 +284,7 @@ func (m *DSLManager) DiscoverServices(onboardingID string) (*OnboardingProcess,
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateServicesDiscovered); err != nil {
+	if err := m.validateStateTransition(process, StateServicesDiscovered); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -345,7 +354,7 @@ func (m *DSLManager) DiscoverResources(onboardingID string) (*OnboardingProcess,
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateResourcesDiscovered); err != nil {
+	if err := m.validateStateTransition(process, StateResourcesDiscovered); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -417,7 +426,7 @@ func (m *DSLManager) CreateConsolidatedDataDictionary(onboardingID string) (*Onb
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateDataDictionaryCreated); err != nil {
+	if err := m.validateStateTransition(process, StateDataDictionaryCreated); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -498,7 +507,7 @@ func (m *DSLManager) PopulateAttributes(onboardingID string, attributeValues map
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateAttributesPopulated); err != nil {
+	if err := m.validateStateTransition(process, StateAttributesPopulated); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -548,7 +557,7 @@ func (m *DSLManager) PrepareResourceLifecycles(onboardingID string) (*Onboarding
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateResourceLifecyclesReady); err != nil {
+	if err := m.validateStateTransition(process, StateResourceLifecyclesReady); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -607,7 +616,7 @@ func (m *DSLManager) ProvisionResources(onboardingID string) (*OnboardingProcess
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateResourcesProvisioned); err != nil {
+	if err := m.validateStateTransition(process, StateResourcesProvisioned); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -682,7 +691,7 @@ func (m *DSLManager) CompleteOnboarding(onboardingID string, completionNotes str
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateOnboardingCompleted); err != nil {
+	if err := m.validateStateTransition(process, StateOnboardingCompleted); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -707,8 +716,6 @@ func (m *DSLManager) CompleteOnboarding(onboardingID string, completionNotes str
 	process.AccumulatedDSL += completionDSL
 	process.VersionNumber++
 	process.UpdatedAt = time.Now()
-	now := time.Now()
-	process.CompletedAt = &now
 
 	// Persist final DSL
 	ctx := context.Background()
@@ -735,7 +742,7 @@ func (m *DSLManager) ArchiveOnboarding(onboardingID string, archivalReason strin
 	}
 
 	// Validate state transition
-	if err := m.validateStateTransition(process.CurrentState, StateOnboardingArchived); err != nil {
+	if err := m.validateStateTransition(process, StateOnboardingArchived); err != nil {
 		return nil, fmt.Errorf("invalid state transition: %w", err)
 	}
 
@@ -814,109 +821,54 @@ func (m *DSLManager) GetProcessesByState(state OnboardingState) []*OnboardingPro
 	return processes
 }
 
-// ValidateStateTransition validates if a state transition is allowed
-func (m *DSLManager) validateStateTransition(currentState, newState OnboardingState) error {
-	validTransitions := map[OnboardingState][]OnboardingState{
-		StateOnboardingRequested:     {StateCBUAssociated, StateOnboardingFailed},
-		StateCBUAssociated:           {StateProductsSelected, StateOnboardingFailed},
-		StateProductsSelected:        {StateServicesDiscovered, StateOnboardingFailed},
-		StateServicesDiscovered:      {StateResourcesDiscovered, StateOnboardingFailed},
-		StateResourcesDiscovered:     {StateDataDictionaryCreated, StateOnboardingFailed},
-		StateDataDictionaryCreated:   {StateAttributesPopulated, StateOnboardingFailed},
-		StateAttributesPopulated:     {StateResourceLifecyclesReady, StateOnboardingFailed},
-		StateResourceLifecyclesReady: {StateResourcesProvisioned, StateOnboardingFailed, StateOnboardingSuspended},
-		StateResourcesProvisioned:    {StateOnboardingCompleted, StateOnboardingFailed},
-		StateOnboardingCompleted:     {StateOnboardingArchived},
-		StateOnboardingFailed:        {StateOnboardingRequested, StateOnboardingArchived},   // Can restart or archive
-		StateOnboardingSuspended:     {StateResourceLifecyclesReady, StateOnboardingFailed}, // Can resume or fail
-		StateOnboardingArchived:      {},                                                    // Terminal state
-	}
-
-	allowedTransitions, exists := validTransitions[currentState]
-	if !exists {
-		return fmt.Errorf("invalid current state: %s", currentState)
-	}
+// validateStateTransition takes a process to a new state through the shared
+// onboarding state machine, checking the data the transition requires and
+// running the entry actions of the new state
+func (m *DSLManager) validateStateTransition(process *OnboardingProcess, newState OnboardingState) error {
+	data := map[string]interface{}{
+		"onboarding_id": process.OnboardingID,
+		"cbu_id":        process.CBUID,
+		"products":      process.SelectedProducts,
+		"services":      process.DiscoveredServices,
+		"resources":     process.DiscoveredResources,
+	}
+	return m.states.Transition(context.Background(), string(process.CurrentState), string(newState), data)
+}
 
-	for _, allowed := range allowedTransitions {
-		if allowed == newState {
-			return nil
-		}
+// recordCompletion is the entry action stamping a completed process
+func (m *DSLManager) recordCompletion(ctx context.Context, from, to string, data map[string]interface{}) error {
+	onboardingID, _ := data["onboarding_id"].(string)
+	process, exists := m.processes[onboardingID]
+	if !exists {
+		return fmt.Errorf("onboarding process not found: %s", onboardingID)
 	}
-
-	return fmt.Errorf("invalid transition from %s to %s", currentState, newState)
+	now := time.Now()
+	process.CompletedAt = &now
+	return nil
 }
 
-// mapOnboardingStateToStoreState maps internal states to store states
+// mapOnboardingStateToStoreState maps internal states to the store states
+// they are persisted as
 func (m *DSLManager) mapOnboardingStateToStoreState(state OnboardingState) store.OnboardingState {
-	switch state {
-	case StateOnboardingRequested:
-		return store.StateCreated
-	case StateCBUAssociated:
-		return store.StateCreated
-	case StateProductsSelected:
-		return store.StateProductsAdded
-	case StateServicesDiscovered:
-		return store.StateServicesDiscovered
-	case StateResourcesDiscovered:
-		return store.StateResourcesDiscovered
-	case StateDataDictionaryCreated:
-		return store.StateResourcesDiscovered
-	case StateAttributesPopulated:
-		return store.StateAttributesPopulated
-	case StateResourceLifecyclesReady:
-		return store.StateAttributesPopulated
-	case StateResourcesProvisioned:
-		return store.StateCompleted
-	case StateOnboardingCompleted:
-		return store.StateCompleted
-	case StateOnboardingArchived:
-		return store.StateCompleted
-	default:
+	storeState, err := store.ParseOnboardingState(string(state))
+	if err != nil {
 		return store.StateCreated
 	}
+	return storeState
 }
 
 // GetStateTransitionPath returns the sequence of states from current to target
 func (m *DSLManager) GetStateTransitionPath(currentState, targetState OnboardingState) ([]OnboardingState, error) {
-	// Define the standard onboarding path
-	standardPath := []OnboardingState{
-		StateOnboardingRequested,
-		StateCBUAssociated,
-		StateProductsSelected,
-		StateServicesDiscovered,
-		StateResourcesDiscovered,
-		StateDataDictionaryCreated,
-		StateAttributesPopulated,
-		StateResourceLifecyclesReady,
-		StateResourcesProvisioned,
-		StateOnboardingCompleted,
-		StateOnboardingArchived,
-	}
-
-	// Find indices
-	currentIndex := -1
-	targetIndex := -1
-	for i, state := range standardPath {
-		if state == currentState {
-			currentIndex = i
-		}
-		if state == targetState {
-			targetIndex = i
-		}
+	names, err := m.states.PathIn(statemachine.VocabularyDSLManager, string(currentState), string(targetState))
+	if err != nil {
+		return nil, err
 	}
 
-	if currentIndex == -1 {
-		return nil, fmt.Errorf("current state not found in standard path: %s", currentState)
-	}
-	if targetIndex == -1 {
-		return nil, fmt.Errorf("target state not found in standard path: %s", targetState)
+	path := make([]OnboardingState, len(names))
+	for i, name := range names {
+		path[i] = OnboardingState(name)
 	}
-	if targetIndex <= currentIndex {
-		return nil, fmt.Errorf("target state must be after current state")
-	}
-
-	// Return the path from current to target
-	return standardPath[currentIndex : targetIndex+1], nil
+	return path, nil
 }
 
 // ExecuteFullOnboarding runs the complete onboarding sequence
@@ -235,6 +235,20 @@ type UBORegistry struct {
 	UBOPerson     *Entity `json:"ubo_person,omitempty"`
 }
 
+// ============================================================================
+// OWNERSHIP GRAPH DATA
+// ============================================================================
+
+// OwnershipGraphData bundles the registry rows needed to build an ownership graph.
+// Partnership interests and trust parties reference the entity-type tables, which
+// are linked back to the central registry through Entity.ExternalID.
+type OwnershipGraphData struct {
+	Entities             []Entity              `json:"entities"`
+	PartnershipInterests []PartnershipInterest `json:"partnership_interests"`
+	TrustParties         []TrustParty          `json:"trust_parties"`
+	UBORegistry          []UBORegistry         `json:"ubo_registry"`
+}
+
 // ============================================================================
 // ENTITY TYPE CONSTANTS
 // ============================================================================
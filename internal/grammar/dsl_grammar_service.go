@@ -8,6 +8,10 @@ import (
 	"dsl-ob-poc/internal/vocabulary"
 )
 
+// GrammarVersion is the version of the built-in default grammar. Rules stored
+// with an older version are upgraded by InitializeDefaultGrammar.
+const GrammarVersion = "1.2.0"
+
 // DSLGrammarService provides DSL grammar management and validation
 type DSLGrammarService struct {
 	repo vocabulary.GrammarRepository
@@ -28,7 +32,18 @@ func (s *DSLGrammarService) InitializeDefaultGrammar(ctx context.Context) error
 		// Check if rule already exists
 		existing, err := s.repo.GetGrammarRuleByName(ctx, rule.RuleName)
 		if err == nil && existing != nil {
-			// Rule exists, skip
+			if compareVersions(existing.Version, rule.Version) >= 0 {
+				// Rule exists and is current, skip
+				continue
+			}
+			existing.RuleDefinition = rule.RuleDefinition
+			existing.RuleType = rule.RuleType
+			existing.Description = rule.Description
+			existing.Version = rule.Version
+			existing.UpdatedAt = rule.UpdatedAt
+			if err := s.repo.UpdateGrammarRule(ctx, existing); err != nil {
+				return fmt.Errorf("failed to upgrade grammar rule %s: %w", rule.RuleName, err)
+			}
 			continue
 		}
 
@@ -52,6 +67,12 @@ func (s *DSLGrammarService) ValidateDSLForDomain(ctx context.Context, dsl, domai
 	return parser.ValidateDSL(ctx, dsl)
 }
 
+// DefaultGrammarRules returns the built-in grammar used to seed the
+// grammar_rules table and when no rules have been stored
+func DefaultGrammarRules() []*vocabulary.GrammarRule {
+	return (&DSLGrammarService{}).getDefaultGrammarRules()
+}
+
 // getDefaultGrammarRules returns the default set of DSL grammar rules
 func (s *DSLGrammarService) getDefaultGrammarRules() []*vocabulary.GrammarRule {
 	now := time.Now()
@@ -109,51 +130,91 @@ func (s *DSLGrammarService) getDefaultGrammarRules() []*vocabulary.GrammarRule {
 		},
 		{
 			RuleName:       "argument",
-			RuleDefinition: "string_literal | uuid_literal | identifier | number | s_expression",
+			RuleDefinition: "string_literal | attribute_ref | keyword | uuid_literal | number | identifier | list_literal | s_expression",
 			RuleType:       "production",
 			Description:    stringPtr("Argument to a verb call"),
 			Active:         true,
-			Version:        "1.0.0",
+			Version:        GrammarVersion,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		{
+			RuleName:       "list_literal",
+			RuleDefinition: `"[" list_element* "]"`,
+			RuleType:       "production",
+			Description:    stringPtr("List literal, e.g. [\"OFAC\", \"EU_SANCTIONS\"]"),
+			Active:         true,
+			Version:        GrammarVersion,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		{
+			RuleName:       "list_element",
+			RuleDefinition: `argument ","?`,
+			RuleType:       "production",
+			Description:    stringPtr("List element with an optional separating comma"),
+			Active:         true,
+			Version:        GrammarVersion,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		},
 		{
 			RuleName:       "identifier",
-			RuleDefinition: "[a-zA-Z][a-zA-Z0-9_.-]*",
+			RuleDefinition: `[\p{L}_][\p{L}\p{N}_.\-]*`,
 			RuleType:       "terminal",
-			Description:    stringPtr("Identifier: alphanumeric with dots, dashes, underscores"),
+			Description:    stringPtr("Identifier: letters, digits, dots, dashes, underscores"),
 			Active:         true,
-			Version:        "1.0.0",
+			Version:        GrammarVersion,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		},
 		{
 			RuleName:       "string_literal",
-			RuleDefinition: `"\"[^\"]*\""`,
+			RuleDefinition: `"(?:[^"\\]|\\.)*"`,
 			RuleType:       "terminal",
-			Description:    stringPtr("Quoted string literal"),
+			Description:    stringPtr("Quoted string literal with backslash escapes"),
 			Active:         true,
-			Version:        "1.0.0",
+			Version:        GrammarVersion,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		},
 		{
 			RuleName:       "uuid_literal",
-			RuleDefinition: "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}",
+			RuleDefinition: "[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}",
 			RuleType:       "terminal",
 			Description:    stringPtr("UUID literal"),
 			Active:         true,
-			Version:        "1.0.0",
+			Version:        GrammarVersion,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		},
 		{
 			RuleName:       "number",
-			RuleDefinition: "[0-9]+(\\.[0-9]+)?",
+			RuleDefinition: `-?[0-9]+(?:\.[0-9]+)?`,
 			RuleType:       "terminal",
-			Description:    stringPtr("Numeric literal (integer or decimal)"),
+			Description:    stringPtr("Numeric literal (optionally negative integer or decimal)"),
 			Active:         true,
-			Version:        "1.0.0",
+			Version:        GrammarVersion,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		{
+			RuleName:       "attribute_ref",
+			RuleDefinition: `@attr\{[^}\s]+\}`,
+			RuleType:       "terminal",
+			Description:    stringPtr("Attribute reference: @attr{uuid} or @attr{uuid:name}"),
+			Active:         true,
+			Version:        GrammarVersion,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		},
+		{
+			RuleName:       "keyword",
+			RuleDefinition: `:[\p{L}_][\p{L}\p{N}_.\-]*`,
+			RuleType:       "terminal",
+			Description:    stringPtr("Keyword argument name, e.g. :legal-name"),
+			Active:         true,
+			Version:        GrammarVersion,
 			CreatedAt:      now,
 			UpdatedAt:      now,
 		},
@@ -4,20 +4,26 @@ package grammar
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	"dsl-ob-poc/internal/vocabulary"
 )
 
 // EBNFParser provides EBNF grammar parsing and validation
 type EBNFParser struct {
-	repo   vocabulary.GrammarRepository
-	rules  map[string]*ParsedRule
-	mutex  sync.RWMutex
-	domain string
+	repo    vocabulary.GrammarRepository
+	rules   map[string]*ParsedRule
+	mutex   sync.RWMutex
+	domain  string
+	version string
 }
 
 // ParsedRule represents a compiled EBNF rule
@@ -25,22 +31,23 @@ type ParsedRule struct {
 	Name       string
 	Definition string
 	RuleType   string
+	Version    string
 	Compiled   *CompiledRule
 }
 
 // CompiledRule represents a compiled grammar rule for efficient parsing
 type CompiledRule struct {
-	Pattern     *regexp.Regexp
+	Pattern      *regexp.Regexp
 	Alternatives []Alternative
-	IsTerminal  bool
-	IsOptional  bool
-	IsRepeating bool
+	IsTerminal   bool
+	IsOptional   bool
+	IsRepeating  bool
 }
 
 // Alternative represents one alternative in a rule definition
 type Alternative struct {
-	Tokens   []Token
-	Action   string // Optional action to take when this alternative matches
+	Tokens []Token
+	Action string // Optional action to take when this alternative matches
 }
 
 // Token represents a single token in a grammar rule
@@ -82,15 +89,38 @@ type ParseResult struct {
 	AST       *ASTNode
 }
 
-// ParseError represents a parsing error
+// ParseError represents a parsing error. Line and Column are 1-based.
 type ParseError struct {
 	Position int
+	Line     int
+	Column   int
 	Expected string
 	Found    string
 	Rule     string
 	Message  string
 }
 
+func (e ParseError) String() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationError is returned by ValidateDSL when a document does not parse
+type ValidationError struct {
+	Domain string
+	Errors []ParseError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		msgs[i] = pe.String()
+	}
+	return fmt.Sprintf("DSL validation failed: %s", strings.Join(msgs, "; "))
+}
+
 // ASTNode represents a node in the Abstract Syntax Tree
 type ASTNode struct {
 	Type     string
@@ -111,9 +141,6 @@ func NewEBNFParser(repo vocabulary.GrammarRepository, domain string) *EBNFParser
 
 // LoadGrammar loads grammar rules from the database for the specified domain
 func (p *EBNFParser) LoadGrammar(ctx context.Context) error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
 	// Load domain-specific rules
 	domainRules, err := p.repo.GetActiveGrammarForDomain(ctx, p.domain)
 	if err != nil {
@@ -126,21 +153,85 @@ func (p *EBNFParser) LoadGrammar(ctx context.Context) error {
 		return fmt.Errorf("failed to load universal grammar: %w", err)
 	}
 
-	// Combine and compile rules
-	allRules := append(domainRules, universalRules...)
-	p.rules = make(map[string]*ParsedRule)
+	return p.loadRules(append(domainRules, universalRules...))
+}
+
+// loadRules compiles rules into the parser, replacing any loaded grammar
+func (p *EBNFParser) loadRules(allRules []*vocabulary.GrammarRule) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
 
+	p.rules = make(map[string]*ParsedRule)
 	for _, rule := range allRules {
+		if _, seen := p.rules[rule.RuleName]; seen {
+			continue // domain rules come first and shadow universal ones
+		}
 		parsed, err := p.parseRule(rule)
 		if err != nil {
 			return fmt.Errorf("failed to parse rule %s: %w", rule.RuleName, err)
 		}
 		p.rules[rule.RuleName] = parsed
 	}
+	p.version = grammarVersion(p.rules)
 
 	return nil
 }
 
+// Version identifies the loaded grammar: the highest rule version plus a
+// fingerprint of every rule definition, e.g. "1.2.0+4be2c01a".
+func (p *EBNFParser) Version() string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.version
+}
+
+// grammarVersion fingerprints a rule set so that any rule edit yields a new version
+func grammarVersion(rules map[string]*ParsedRule) string {
+	if len(rules) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(rules))
+	highest := ""
+	for name, rule := range rules {
+		names = append(names, name)
+		if compareVersions(rule.Version, highest) > 0 {
+			highest = rule.Version
+		}
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", name, rules[name].RuleType, rules[name].Definition)
+	}
+	if highest == "" {
+		highest = "0.0.0"
+	}
+	return highest + "+" + hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// compareVersions compares dotted numeric versions such as "1.0.0" and "1.10.2"
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
 // Parse parses input text against the loaded grammar rules
 func (p *EBNFParser) Parse(input string, startRule string) (*ParseResult, error) {
 	p.mutex.RLock()
@@ -157,30 +248,60 @@ func (p *EBNFParser) Parse(input string, startRule string) (*ParseResult, error)
 		}, nil
 	}
 
-	return p.parseWithRule(input, rule, 0)
+	st := &parseState{parser: p, input: input, farthest: -1}
+	end, ast, ok := st.matchRule(rule, 0)
+	if ok {
+		end = st.skipSpace(end)
+	}
+	if !ok || end < len(input) {
+		if end > st.farthest {
+			st.farthest = end
+			st.expected = map[string]bool{"end of input": true}
+		}
+		return &ParseResult{
+			Success:   false,
+			Rule:      startRule,
+			Remaining: input[st.farthest:],
+			Errors:    []ParseError{st.farthestError()},
+		}, nil
+	}
+
+	return &ParseResult{
+		Success:   true,
+		Matched:   input[:end],
+		Remaining: input[end:],
+		Rule:      startRule,
+		AST:       ast,
+	}, nil
 }
 
-// ValidateDSL validates DSL text against the grammar
+// ValidateDSL validates DSL text against the grammar. Syntax errors are
+// reported as a *ValidationError carrying line and column positions.
 func (p *EBNFParser) ValidateDSL(ctx context.Context, dsl string) error {
 	// Ensure grammar is loaded
-	if len(p.rules) == 0 {
+	p.mutex.RLock()
+	loaded := len(p.rules) > 0
+	p.mutex.RUnlock()
+	if !loaded {
 		if err := p.LoadGrammar(ctx); err != nil {
 			return fmt.Errorf("failed to load grammar: %w", err)
 		}
 	}
 
+	if strings.TrimSpace(dsl) == "" {
+		return &ValidationError{Domain: p.domain, Errors: []ParseError{{
+			Line: 1, Column: 1, Message: "DSL document is empty",
+		}}}
+	}
+
 	// Parse starting with the main DSL rule
-	result, err := p.Parse(strings.TrimSpace(dsl), "dsl_document")
+	result, err := p.Parse(dsl, "dsl_document")
 	if err != nil {
 		return fmt.Errorf("parse error: %w", err)
 	}
 
 	if !result.Success {
-		return fmt.Errorf("DSL validation failed: %v", result.Errors)
-	}
-
-	if strings.TrimSpace(result.Remaining) != "" {
-		return fmt.Errorf("unexpected content after parsing: %s", result.Remaining)
+		return &ValidationError{Domain: p.domain, Errors: result.Errors}
 	}
 
 	return nil
@@ -192,9 +313,16 @@ func (p *EBNFParser) parseRule(rule *vocabulary.GrammarRule) (*ParsedRule, error
 		Name:       rule.RuleName,
 		Definition: rule.RuleDefinition,
 		RuleType:   rule.RuleType,
+		Version:    rule.Version,
 	}
 
-	compiled, err := p.compileRule(rule.RuleDefinition)
+	var compiled *CompiledRule
+	var err error
+	if rule.RuleType == "terminal" {
+		compiled, err = p.compileTerminal(rule.RuleDefinition)
+	} else {
+		compiled, err = p.compileRule(rule.RuleDefinition)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -203,14 +331,21 @@ func (p *EBNFParser) parseRule(rule *vocabulary.GrammarRule) (*ParsedRule, error
 	return parsed, nil
 }
 
+// compileTerminal compiles a terminal rule, whose definition is a regular expression
+func (p *EBNFParser) compileTerminal(definition string) (*CompiledRule, error) {
+	pattern, err := regexp.Compile(`^(?:` + definition + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid terminal pattern %q: %w", definition, err)
+	}
+	return &CompiledRule{Pattern: pattern, IsTerminal: true}, nil
+}
+
 // compileRule compiles an EBNF rule definition into a CompiledRule
 func (p *EBNFParser) compileRule(definition string) (*CompiledRule, error) {
 	compiled := &CompiledRule{}
 
-	// Parse alternatives separated by |
-	alternatives := strings.Split(definition, "|")
-
-	for _, alt := range alternatives {
+	// Parse alternatives separated by | outside quoted literals
+	for _, alt := range splitAlternatives(definition) {
 		alt = strings.TrimSpace(alt)
 		if alt == "" {
 			continue
@@ -224,15 +359,42 @@ func (p *EBNFParser) compileRule(definition string) (*CompiledRule, error) {
 		compiled.Alternatives = append(compiled.Alternatives, alternative)
 	}
 
+	if len(compiled.Alternatives) == 0 {
+		return nil, fmt.Errorf("rule definition %q has no alternatives", definition)
+	}
 	return compiled, nil
 }
 
+// splitAlternatives splits a definition on top-level '|' characters
+func splitAlternatives(definition string) []string {
+	var alts []string
+	start, inQuote := 0, false
+	for i := 0; i < len(definition); i++ {
+		switch definition[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case '|':
+			if !inQuote {
+				alts = append(alts, definition[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, definition[start:])
+}
+
 // parseAlternative parses a single alternative in an EBNF rule
 func (p *EBNFParser) parseAlternative(alt string) (Alternative, error) {
 	alternative := Alternative{}
 
-	// Simple tokenization - this could be more sophisticated
-	tokens := p.tokenizeAlternative(alt)
+	tokens, err := p.tokenizeAlternative(alt)
+	if err != nil {
+		return alternative, err
+	}
 
 	for _, tokenStr := range tokens {
 		token, err := p.parseToken(tokenStr)
@@ -245,11 +407,39 @@ func (p *EBNFParser) parseAlternative(alt string) (Alternative, error) {
 	return alternative, nil
 }
 
-// tokenizeAlternative splits an alternative into tokens
-func (p *EBNFParser) tokenizeAlternative(alt string) []string {
-	// Simple space-based tokenization for now
-	// In a full implementation, this would handle quoted strings, groups, etc.
-	return strings.Fields(alt)
+// tokenizeAlternative splits an alternative into tokens on whitespace,
+// keeping quoted literals (which may contain spaces) intact
+func (p *EBNFParser) tokenizeAlternative(alt string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuote := false
+
+	for i := 0; i < len(alt); i++ {
+		c := alt[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(alt):
+			current.WriteByte(c)
+			i++
+			current.WriteByte(alt[i])
+		case c == '"':
+			inQuote = !inQuote
+			current.WriteByte(c)
+		case !inQuote && unicode.IsSpace(rune(c)):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated literal in %q", alt)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
 }
 
 // parseToken parses a single token string into a Token
@@ -257,23 +447,33 @@ func (p *EBNFParser) parseToken(tokenStr string) (Token, error) {
 	token := Token{}
 
 	// Handle repetition operators
-	if strings.HasSuffix(tokenStr, "*") {
-		token.Repeat = RepeatZeroOrMore
-		tokenStr = strings.TrimSuffix(tokenStr, "*")
-	} else if strings.HasSuffix(tokenStr, "+") {
-		token.Repeat = RepeatOneOrMore
-		tokenStr = strings.TrimSuffix(tokenStr, "+")
-	} else if strings.HasSuffix(tokenStr, "?") {
-		token.Repeat = RepeatOptional
-		tokenStr = strings.TrimSuffix(tokenStr, "?")
+	if len(tokenStr) > 1 {
+		switch tokenStr[len(tokenStr)-1] {
+		case '*':
+			token.Repeat = RepeatZeroOrMore
+		case '+':
+			token.Repeat = RepeatOneOrMore
+		case '?':
+			token.Repeat = RepeatOptional
+			token.Optional = true
+		}
+		if token.Repeat != RepeatNone {
+			tokenStr = tokenStr[:len(tokenStr)-1]
+		}
 	}
 
 	// Handle quoted literals
-	if strings.HasPrefix(tokenStr, `"`) && strings.HasSuffix(tokenStr, `"`) {
+	if len(tokenStr) >= 2 && strings.HasPrefix(tokenStr, `"`) && strings.HasSuffix(tokenStr, `"`) {
+		value, err := strconv.Unquote(tokenStr)
+		if err != nil {
+			value = tokenStr[1 : len(tokenStr)-1]
+		}
 		token.Type = TokenLiteral
-		token.Value = strings.Trim(tokenStr, `"`)
+		token.Value = value
 	} else {
-		// Assume it's a rule reference
+		if tokenStr == "" || strings.ContainsAny(tokenStr, `"()`) {
+			return token, fmt.Errorf("invalid token %q", tokenStr)
+		}
 		token.Type = TokenRuleRef
 		token.RuleRef = tokenStr
 	}
@@ -281,134 +481,211 @@ func (p *EBNFParser) parseToken(tokenStr string) (Token, error) {
 	return token, nil
 }
 
-// parseWithRule parses input using a specific rule
-func (p *EBNFParser) parseWithRule(input string, rule *ParsedRule, position int) (*ParseResult, error) {
-	for _, alt := range rule.Compiled.Alternatives {
-		result := p.tryAlternative(input, alt, position, rule.Name)
-		if result.Success {
-			return result, nil
+// parseState holds per-call parsing state. The grammar is a PEG: alternatives
+// are ordered and repetitions are greedy. Whitespace and ';' line comments
+// between tokens are skipped. The farthest failure is kept for error reporting.
+type parseState struct {
+	parser   *EBNFParser
+	input    string
+	depth    int
+	farthest int
+	expected map[string]bool
+	rule     string
+}
+
+// maxParseDepth bounds rule recursion so a left-recursive grammar cannot overflow the stack
+const maxParseDepth = 1000
+
+func (st *parseState) skipSpace(pos int) int {
+	for pos < len(st.input) {
+		c := st.input[pos]
+		switch {
+		case c == ';':
+			for pos < len(st.input) && st.input[pos] != '\n' {
+				pos++
+			}
+		case unicode.IsSpace(rune(c)):
+			pos++
+		default:
+			return pos
 		}
 	}
+	return pos
+}
 
-	return &ParseResult{
-		Success: false,
-		Errors: []ParseError{{
-			Position: position,
-			Rule:     rule.Name,
-			Message:  fmt.Sprintf("no alternative matched in rule '%s'", rule.Name),
-		}},
-	}, nil
+func (st *parseState) fail(pos int, expected, rule string) {
+	if pos > st.farthest {
+		st.farthest = pos
+		st.expected = make(map[string]bool)
+		st.rule = rule
+	}
+	if pos == st.farthest {
+		st.expected[expected] = true
+	}
 }
 
-// tryAlternative attempts to match an alternative against input
-func (p *EBNFParser) tryAlternative(input string, alt Alternative, position int, ruleName string) *ParseResult {
-	currentPos := position
-	currentInput := input[position:]
-	ast := &ASTNode{
-		Type:     ruleName,
-		Position: position,
+// matchRule matches rule at pos and returns the end position
+func (st *parseState) matchRule(rule *ParsedRule, pos int) (int, *ASTNode, bool) {
+	if st.depth >= maxParseDepth {
+		st.fail(pos, "shallower nesting", rule.Name)
+		return pos, nil, false
+	}
+	st.depth++
+	defer func() { st.depth-- }()
+
+	if rule.Compiled.IsTerminal {
+		start := st.skipSpace(pos)
+		loc := rule.Compiled.Pattern.FindStringIndex(st.input[start:])
+		if loc == nil || loc[1] == 0 {
+			st.fail(start, rule.Name, rule.Name)
+			return pos, nil, false
+		}
+		end := start + loc[1]
+		return end, &ASTNode{Type: rule.Name, Value: st.input[start:end], Position: start, Length: end - start}, true
 	}
 
-	for _, token := range alt.Tokens {
-		result := p.matchToken(currentInput, token, currentPos)
-		if !result.Success {
-			return &ParseResult{
-				Success: false,
-				Errors: result.Errors,
-			}
+	for _, alt := range rule.Compiled.Alternatives {
+		if end, ast, ok := st.matchAlternative(alt, pos, rule.Name); ok {
+			return end, ast, true
 		}
+	}
+	return pos, nil, false
+}
 
-		if result.AST != nil {
-			ast.Children = append(ast.Children, result.AST)
+func (st *parseState) matchAlternative(alt Alternative, pos int, ruleName string) (int, *ASTNode, bool) {
+	ast := &ASTNode{Type: ruleName, Position: st.skipSpace(pos)}
+	current := pos
+
+	for _, token := range alt.Tokens {
+		end, children, ok := st.matchRepeated(token, current, ruleName)
+		if !ok {
+			return pos, nil, false
 		}
+		ast.Children = append(ast.Children, children...)
+		current = end
+	}
 
-		matchedLen := len(result.Matched)
-		currentPos += matchedLen
-		currentInput = currentInput[matchedLen:]
+	ast.Length = current - ast.Position
+	if ast.Length < 0 {
+		ast.Length = 0
 	}
+	return current, ast, true
+}
 
-	ast.Length = currentPos - position
+// matchRepeated applies a token's repetition operator
+func (st *parseState) matchRepeated(token Token, pos int, ruleName string) (int, []*ASTNode, bool) {
+	var nodes []*ASTNode
+	current := pos
+	count := 0
 
-	return &ParseResult{
-		Success:   true,
-		Matched:   input[position:currentPos],
-		Remaining: currentInput,
-		Rule:      ruleName,
-		AST:       ast,
+	for {
+		end, node, ok := st.matchToken(token, current, ruleName)
+		if !ok {
+			break
+		}
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+		progressed := end > current
+		current = end
+		count++
+		// Stop after one match, or when a repetition stops consuming input
+		if token.Repeat == RepeatNone || token.Repeat == RepeatOptional || !progressed {
+			break
+		}
+	}
+
+	switch token.Repeat {
+	case RepeatNone, RepeatOneOrMore:
+		if count == 0 {
+			return pos, nil, false
+		}
 	}
+	return current, nodes, true
 }
 
-// matchToken attempts to match a single token against input
-func (p *EBNFParser) matchToken(input string, token Token, position int) *ParseResult {
+// matchToken attempts to match a single token at pos
+func (st *parseState) matchToken(token Token, pos int, ruleName string) (int, *ASTNode, bool) {
 	switch token.Type {
 	case TokenLiteral:
-		return p.matchLiteral(input, token.Value, position)
+		start := st.skipSpace(pos)
+		if strings.HasPrefix(st.input[start:], token.Value) {
+			end := start + len(token.Value)
+			return end, &ASTNode{Type: "literal", Value: token.Value, Position: start, Length: len(token.Value)}, true
+		}
+		st.fail(start, strconv.Quote(token.Value), ruleName)
+		return pos, nil, false
 	case TokenRuleRef:
-		rule, exists := p.rules[token.RuleRef]
+		rule, exists := st.parser.rules[token.RuleRef]
 		if !exists {
-			return &ParseResult{
-				Success: false,
-				Errors: []ParseError{{
-					Position: position,
-					Rule:     token.RuleRef,
-					Message:  fmt.Sprintf("referenced rule '%s' not found", token.RuleRef),
-				}},
-			}
+			st.fail(st.skipSpace(pos), fmt.Sprintf("rule '%s' (not defined)", token.RuleRef), ruleName)
+			return pos, nil, false
 		}
-		return p.parseWithRuleFromPosition(input, rule, position)
+		return st.matchRule(rule, pos)
 	default:
-		return &ParseResult{
-			Success: false,
-			Errors: []ParseError{{
-				Position: position,
-				Message:  fmt.Sprintf("unsupported token type: %v", token.Type),
-			}},
-		}
+		st.fail(pos, fmt.Sprintf("supported token type (got %v)", token.Type), ruleName)
+		return pos, nil, false
 	}
 }
 
-// matchLiteral matches a literal string
-func (p *EBNFParser) matchLiteral(input, literal string, position int) *ParseResult {
-	if strings.HasPrefix(input, literal) {
-		return &ParseResult{
-			Success: true,
-			Matched: literal,
-			Remaining: input[len(literal):],
-			AST: &ASTNode{
-				Type:     "literal",
-				Value:    literal,
-				Position: position,
-				Length:   len(literal),
-			},
-		}
+// farthestError describes the failure at the farthest position reached
+func (st *parseState) farthestError() ParseError {
+	pos := st.farthest
+	if pos < 0 {
+		pos = 0
 	}
+	line, column := lineColumn(st.input, pos)
 
-	return &ParseResult{
-		Success: false,
-		Errors: []ParseError{{
-			Position: position,
-			Expected: literal,
-			Found:    input[:min(len(input), 10)],
-			Message:  fmt.Sprintf("expected '%s'", literal),
-		}},
+	expected := make([]string, 0, len(st.expected))
+	for e := range st.expected {
+		expected = append(expected, e)
+	}
+	sort.Strings(expected)
+
+	found := "end of input"
+	if pos < len(st.input) {
+		found = strconv.Quote(snippet(st.input[pos:]))
+	}
+
+	message := fmt.Sprintf("unexpected %s", found)
+	if len(expected) > 0 {
+		message = fmt.Sprintf("expected %s, found %s", strings.Join(expected, " or "), found)
+	}
+
+	return ParseError{
+		Position: pos,
+		Line:     line,
+		Column:   column,
+		Expected: strings.Join(expected, " | "),
+		Found:    found,
+		Rule:     st.rule,
+		Message:  message,
 	}
 }
 
-// parseWithRuleFromPosition is a helper to parse from a specific position
-func (p *EBNFParser) parseWithRuleFromPosition(input string, rule *ParsedRule, position int) *ParseResult {
-	result, _ := p.parseWithRule(input, rule, 0)
-	if result.Success {
-		result.AST.Position = position
+// lineColumn converts a byte offset into a 1-based line and rune column
+func lineColumn(input string, pos int) (int, int) {
+	line, column := 1, 1
+	for _, r := range input[:pos] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
 	}
-	return result
+	return line, column
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// snippet returns the start of s up to the first whitespace, capped at 20 runes
+func snippet(s string) string {
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		s = s[:i]
 	}
-	return b
+	if r := []rune(s); len(r) > 20 {
+		s = string(r[:20]) + "…"
+	}
+	return s
 }
 
 // GetLoadedRules returns the names of all loaded rules
@@ -421,4 +698,4 @@ func (p *EBNFParser) GetLoadedRules() []string {
 		rules = append(rules, name)
 	}
 	return rules
-}
\ No newline at end of file
+}
@@ -0,0 +1,147 @@
+package grammar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/vocabulary"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Gate validates DSL documents against the active grammar of a domain before
+// they are persisted. It implements datastore.DSLValidator, so wrapping a data
+// store with it keeps unparseable text out of the DSL version history.
+type Gate struct {
+	repo   vocabulary.GrammarRepository
+	domain string
+}
+
+var _ datastore.DSLValidator = (*Gate)(nil)
+
+// NewGate creates a gate that reads the active grammar for domain from repo.
+// When the repository holds no active rules the built-in default grammar is used.
+func NewGate(repo vocabulary.GrammarRepository, domain string) *Gate {
+	return &Gate{repo: repo, domain: domain}
+}
+
+// NewDefaultGate creates a gate that validates against the built-in default grammar
+func NewDefaultGate(domain string) *Gate {
+	return NewGate(&staticGrammarRepository{rules: DefaultGrammarRules()}, domain)
+}
+
+// GateForDataStore returns the gate matching a data store configuration: the
+// grammar_rules table for PostgreSQL and the built-in grammar for mock data.
+// The returned function closes the grammar database connection.
+func GateForDataStore(cfg datastore.Config, domain string) (*Gate, func() error, error) {
+	if cfg.Type != datastore.PostgreSQLStore {
+		return NewDefaultGate(domain), func() error { return nil }, nil
+	}
+
+	db, err := sqlx.Open("postgres", cfg.ConnectionString)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open grammar database: %w", err)
+	}
+	return NewGate(vocabulary.NewPostgresRepository(db), domain), db.Close, nil
+}
+
+// Domain returns the grammar domain the gate validates against
+func (g *Gate) Domain() string {
+	return g.domain
+}
+
+// ValidateDSL parses dslText against the domain's active grammar, loaded
+// fresh so rule changes apply to the next write. It returns the version of
+// the grammar that accepted the document, or a *ValidationError with
+// line and column positions.
+func (g *Gate) ValidateDSL(ctx context.Context, dslText string) (string, error) {
+	parser := NewEBNFParser(g.repo, g.domain)
+	if err := parser.LoadGrammar(ctx); err != nil {
+		return "", err
+	}
+	if len(parser.GetLoadedRules()) == 0 {
+		if err := parser.loadRules(DefaultGrammarRules()); err != nil {
+			return "", err
+		}
+	}
+
+	if err := parser.ValidateDSL(ctx, dslText); err != nil {
+		return "", err
+	}
+	return parser.Version(), nil
+}
+
+// staticGrammarRepository serves a fixed rule set; only the read methods are supported
+type staticGrammarRepository struct {
+	rules []*vocabulary.GrammarRule
+}
+
+func (r *staticGrammarRepository) CreateGrammarRule(ctx context.Context, rule *vocabulary.GrammarRule) error {
+	return fmt.Errorf("static grammar is read-only")
+}
+
+func (r *staticGrammarRepository) GetGrammarRule(ctx context.Context, ruleID string) (*vocabulary.GrammarRule, error) {
+	for _, rule := range r.rules {
+		if rule.RuleID == ruleID {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("grammar rule not found: %s", ruleID)
+}
+
+func (r *staticGrammarRepository) GetGrammarRuleByName(ctx context.Context, ruleName string) (*vocabulary.GrammarRule, error) {
+	for _, rule := range r.rules {
+		if rule.RuleName == ruleName {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("grammar rule not found: %s", ruleName)
+}
+
+func (r *staticGrammarRepository) ListGrammarRules(ctx context.Context, domain *string, active *bool) ([]*vocabulary.GrammarRule, error) {
+	var out []*vocabulary.GrammarRule
+	for _, rule := range r.rules {
+		if domain != nil && (rule.Domain == nil || *rule.Domain != *domain) {
+			continue
+		}
+		if active != nil && rule.Active != *active {
+			continue
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+func (r *staticGrammarRepository) UpdateGrammarRule(ctx context.Context, rule *vocabulary.GrammarRule) error {
+	return fmt.Errorf("static grammar is read-only")
+}
+
+func (r *staticGrammarRepository) DeleteGrammarRule(ctx context.Context, ruleID string) error {
+	return fmt.Errorf("static grammar is read-only")
+}
+
+func (r *staticGrammarRepository) ValidateGrammarSyntax(ctx context.Context, ruleDefinition string) error {
+	if strings.TrimSpace(ruleDefinition) == "" {
+		return fmt.Errorf("rule definition is empty")
+	}
+	return nil
+}
+
+// GetActiveGrammarForDomain mirrors the PostgreSQL query: rules for the
+// domain plus universal rules, domain rules first
+func (r *staticGrammarRepository) GetActiveGrammarForDomain(ctx context.Context, domain string) ([]*vocabulary.GrammarRule, error) {
+	var domainRules, universal []*vocabulary.GrammarRule
+	for _, rule := range r.rules {
+		switch {
+		case !rule.Active:
+		case rule.Domain == nil:
+			universal = append(universal, rule)
+		case *rule.Domain == domain:
+			domainRules = append(domainRules, rule)
+		}
+	}
+	return append(domainRules, universal...), nil
+}
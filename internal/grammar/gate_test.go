@@ -0,0 +1,253 @@
+package grammar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/dictionary"
+	"dsl-ob-poc/internal/domains/ubo"
+	"dsl-ob-poc/internal/dsl"
+	"dsl-ob-poc/internal/ir"
+	"dsl-ob-poc/internal/store"
+	"dsl-ob-poc/internal/vocabulary"
+)
+
+func TestGateAcceptsDSL(t *testing.T) {
+	gate := NewDefaultGate("onboarding")
+
+	tests := []struct {
+		name string
+		dsl  string
+	}{
+		{"Case Create", `(case.create (cbu.id "CBU-1234") (nature-purpose "UCITS equity fund domiciled in LU"))`},
+		{"Multiple Forms With Comments", `; onboarding
+(case.create (cbu.id "CBU-1"))
+(products.add "CUSTODY" "FUND_ACCOUNTING") ; trailing comment
+(kyc.start (documents (document "W8BEN-E")) (jurisdictions (jurisdiction "LU")))`},
+		{"Keywords And Numbers", `(investor.start-opportunity :legal-name "Acme \"Capital\"" :amount -1500.25 :units 10)`},
+		{"Attribute Refs", `(values.bind (bind (attr-id @attr{8a5d1a77-e5a4-4a5e-9b2b-1b2c3d4e5f60:legal-name}) (value "x")))`},
+		{"UUID And Booleans", `(var (attr-id 8a5d1a77-e5a4-4a5e-9b2b-1b2c3d4e5f60) (required true))`},
+		{"Compound Expression", `((case.create (cbu.id "A")) (case.update (cbu.id "A")))`},
+		{"List Literals", `(ubo.identify (types ["CEO", "BOARD_MAJORITY"]) (empty []) (nested [["A" "B"] (c.d)]))`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := gate.ValidateDSL(context.Background(), tt.dsl)
+			if err != nil {
+				t.Fatalf("ValidateDSL failed: %v", err)
+			}
+			if !strings.HasPrefix(version, GrammarVersion+"+") {
+				t.Errorf("unexpected grammar version %q", version)
+			}
+		})
+	}
+}
+
+func TestGateRejectsDSLWithPosition(t *testing.T) {
+	gate := NewDefaultGate("onboarding")
+
+	tests := []struct {
+		name   string
+		dsl    string
+		line   int
+		column int
+	}{
+		{"Unclosed Expression", "(case.create\n  (cbu.id \"CBU-1\")", 2, 19},
+		{"Unterminated String", "(case.create (cbu.id \"CBU-1))", 1, 22},
+		{"Bare Text", "case.create", 1, 1},
+		{"Trailing Garbage", "(case.create)\n  ]", 2, 3},
+		{"Empty", "   ", 1, 1},
+		{"Unclosed List", "(ubo.identify\n  (types [\"CEO\", \"CFO\"))", 2, 23},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := gate.ValidateDSL(context.Background(), tt.dsl)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			pe := verr.Errors[0]
+			if pe.Line != tt.line || pe.Column != tt.column {
+				t.Errorf("expected error at %d:%d, got %d:%d (%s)", tt.line, tt.column, pe.Line, pe.Column, pe.Message)
+			}
+		})
+	}
+}
+
+func TestGrammarVersionTracksRules(t *testing.T) {
+	rules := DefaultGrammarRules()
+	base, err := NewGate(&staticGrammarRepository{rules: rules}, "onboarding").ValidateDSL(context.Background(), `(a.b)`)
+	if err != nil {
+		t.Fatalf("ValidateDSL failed: %v", err)
+	}
+
+	// Editing any rule definition produces a new grammar version
+	edited := DefaultGrammarRules()
+	for _, r := range edited {
+		if r.RuleName == "argument" {
+			r.RuleDefinition = "string_literal | s_expression"
+		}
+	}
+	changed, err := NewGate(&staticGrammarRepository{rules: edited}, "onboarding").ValidateDSL(context.Background(), `(a.b)`)
+	if err != nil {
+		t.Fatalf("ValidateDSL failed: %v", err)
+	}
+	if base == changed {
+		t.Errorf("expected grammar version to change, both were %q", base)
+	}
+
+	// ...and the edited grammar is enforced
+	if _, err := NewGate(&staticGrammarRepository{rules: edited}, "onboarding").ValidateDSL(context.Background(), `(a.b :kw 1)`); err == nil {
+		t.Error("expected edited grammar to reject keyword arguments")
+	}
+}
+
+func TestGateFallsBackToDefaultGrammar(t *testing.T) {
+	gate := NewGate(&staticGrammarRepository{rules: []*vocabulary.GrammarRule{}}, "onboarding")
+	if _, err := gate.ValidateDSL(context.Background(), `(case.create (cbu.id "CBU-1"))`); err != nil {
+		t.Errorf("expected built-in grammar to accept DSL, got %v", err)
+	}
+}
+
+// recordingStore captures DSL writes; other methods panic through the nil embedded interface
+type recordingStore struct {
+	datastore.DataStore
+	inserted []store.DSLVersionWithState
+}
+
+func (r *recordingStore) InsertDSLWithGrammar(_ context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	r.inserted = append(r.inserted, store.DSLVersionWithState{
+		CBUID: cbuID, DSLText: dslText, OnboardingState: state, GrammarVersion: grammarVersion,
+	})
+	return "v1", nil
+}
+
+func (r *recordingStore) InsertPlainDSL(_ context.Context, cbuID, dslText, grammarVersion string) (string, error) {
+	r.inserted = append(r.inserted, store.DSLVersionWithState{
+		CBUID: cbuID, DSLText: dslText, GrammarVersion: grammarVersion,
+	})
+	return "v2", nil
+}
+
+func TestValidatedDataStore(t *testing.T) {
+	rec := &recordingStore{}
+	ds := datastore.WithDSLValidator(rec, NewDefaultGate("onboarding"))
+	ctx := context.Background()
+
+	if _, err := ds.InsertDSLWithState(ctx, "CBU-1", `(case.create (cbu.id "CBU-1"))`, store.StateCreated); err != nil {
+		t.Fatalf("valid DSL rejected: %v", err)
+	}
+	if _, err := ds.InsertDSL(ctx, "CBU-1", `(case.create (cbu.id "CBU-1")`); err == nil {
+		t.Fatal("expected unparseable DSL to be rejected")
+	} else if !strings.Contains(err.Error(), "line 1, column 30") {
+		t.Errorf("expected positioned error, got %v", err)
+	}
+
+	// A plain insert keeps InsertDSL's semantics: no onboarding state is written
+	if _, err := ds.InsertDSL(ctx, "CBU-1", `(products.add "CUSTODY")`); err != nil {
+		t.Fatalf("valid DSL rejected: %v", err)
+	}
+
+	if len(rec.inserted) != 2 {
+		t.Fatalf("expected exactly two persisted versions, got %d", len(rec.inserted))
+	}
+	for _, v := range rec.inserted {
+		if !strings.HasPrefix(v.GrammarVersion, GrammarVersion+"+") {
+			t.Errorf("expected grammar version to be recorded, got %q", v.GrammarVersion)
+		}
+	}
+	if rec.inserted[0].OnboardingState != store.StateCreated || rec.inserted[1].OnboardingState != "" {
+		t.Errorf("unexpected states %q and %q", rec.inserted[0].OnboardingState, rec.inserted[1].OnboardingState)
+	}
+}
+
+// TestGateAcceptsInRepoDSL runs the shipped examples and every DSL generator
+// through the gate, so the default grammar keeps accepting what the app writes
+func TestGateAcceptsInRepoDSL(t *testing.T) {
+	docs := make(map[string]string)
+
+	paths, err := filepath.Glob("../../examples/*/*.dsl")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no example DSL found: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		docs[path] = string(data)
+	}
+
+	u := ubo.NewUBODomain(nil)
+	docs["ubo sample workflow"] = u.GenerateSampleUBOWorkflow("Acme Holdings", "LU")
+	docs["ubo trust workflow"] = u.GenerateTrustUBOWorkflow("Acme Trust", "JE")
+	docs["ubo partnership workflow"] = u.GeneratePartnershipUBOWorkflow("Acme Fund LP", "KY")
+	docs["ubo fincen control prong workflow"] = u.GenerateFinCenControlProngWorkflow("Acme Inc", "US")
+
+	onboarding := dsl.CreateCase("CBU-1234", "UCITS equity fund domiciled in LU")
+	steps := []func(string) (string, error){
+		func(s string) (string, error) {
+			return dsl.AddProducts(s, []*store.Product{{Name: "CUSTODY"}, {Name: "FUND_ACCOUNTING"}})
+		},
+		func(s string) (string, error) {
+			out, _, err := dsl.AddOrModifyKYCBlock(s, dsl.KYCRequirements{},
+				dsl.KYCRequirements{Documents: []string{"W8BEN-E"}, Jurisdictions: []string{"LU"}})
+			return out, err
+		},
+		func(s string) (string, error) {
+			return dsl.AddDiscoveredServices(s, dsl.ServiceDiscoveryPlan{
+				ProductServices: map[string][]store.Service{"CUSTODY": {{Name: "Settlement"}}},
+			})
+		},
+		func(s string) (string, error) {
+			return dsl.AddDiscoveredResources(s, dsl.ResourceDiscoveryPlan{
+				ServiceResources: map[string][]store.ProdResource{
+					"Settlement": {{ResourceID: "R1", Name: "CustodyAccount", Owner: "ops", DictionaryGroup: "custody"}},
+				},
+				ResourceAttributes: map[string][]dictionary.Attribute{
+					"custody": {{AttributeID: "8a5d1a77-e5a4-4a5e-9b2b-1b2c3d4e5f60"}},
+				},
+			})
+		},
+		func(s string) (string, error) {
+			return s + "\n\n" + dsl.RenderBindings(map[string]string{"8a5d1a77-e5a4-4a5e-9b2b-1b2c3d4e5f60": `"ACC-1"`}), nil
+		},
+		func(s string) (string, error) {
+			return dsl.AddPopulatedAttributes(s, []dsl.AttributeValue{{Name: "account", Value: "ACC-1"}})
+		},
+	}
+	for i, step := range steps {
+		if onboarding, err = step(onboarding); err != nil {
+			t.Fatalf("onboarding step %d failed: %v", i, err)
+		}
+	}
+	docs["onboarding builders"] = onboarding
+
+	planJSON, err := os.ReadFile("../../examples/hedge-fund-lifecycle/lifecycle-plan.json")
+	if err != nil {
+		t.Fatalf("failed to read lifecycle plan: %v", err)
+	}
+	plan, err := ir.ParsePlan(planJSON)
+	if err != nil {
+		t.Fatalf("failed to parse lifecycle plan: %v", err)
+	}
+	if docs["ir plan"], err = plan.ToDSL(); err != nil {
+		t.Fatalf("ToDSL failed: %v", err)
+	}
+
+	gate := NewDefaultGate("onboarding")
+	for name, text := range docs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := gate.ValidateDSL(context.Background(), text); err != nil {
+				t.Errorf("gate rejected in-repo DSL: %v", err)
+			}
+		})
+	}
+}
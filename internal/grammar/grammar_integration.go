@@ -113,7 +113,7 @@ func ValidateDSLCLI(ctx context.Context, ds datastore.DataStore, dsl, domain str
 	} else {
 		fmt.Println("❌ DSL validation failed!")
 		for _, err := range result.Errors {
-			fmt.Printf("   Error at position %d: %s\n", err.Position, err.Message)
+			fmt.Printf("   Error at line %d, column %d: %s\n", err.Line, err.Column, err.Message)
 		}
 	}
 
@@ -0,0 +1,492 @@
+package ir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/shared-dsl/parser"
+
+	"github.com/google/uuid"
+)
+
+// ---------- DSL <-> IR compiler ----------
+//
+// Lowering: each top-level (op :key value ...) form becomes a Step. Arguments may
+// be written as keywords (:legal-name "Acme") or as pairs ((legal-name "Acme")).
+// @attr{ID} becomes an AttrRef, (attr-ref @attr{ID} :required false ...) an AttrRef
+// with type/required/constraints/sources, (map :k v ...) an object, (list v ...)
+// an array and the bare identifier null a JSON null. An optional (plan.header ...)
+// form carries the plan version, ID and metadata.
+//
+// Raising: a Plan is printed back as canonical keyword-style DSL. Plans whose
+// object keys cannot be written as DSL keywords are rejected rather than printed
+// lossily.
+
+const (
+	headerVerb  = "plan.header"
+	mapVerb     = "map"
+	listVerb    = "list"
+	attrRefVerb = "attr-ref"
+	nullLiteral = "null"
+
+	idempotencyKeyword = "idempotency-key"
+	annotationsKeyword = "annotations"
+)
+
+// dslArgAliases maps DSL keyword names (kebab-case) onto IR arg names where they
+// differ by more than kebab/snake case. The "" entry applies to every op.
+var dslArgAliases = map[Op]map[string]string{
+	"": {
+		"investor": "investor_id",
+		"fund":     "fund_id",
+		"class":    "class_id",
+		"series":   "series_id",
+		"trade":    "trade_id",
+		"bank":     "bank_id",
+	},
+	OpDealNAV:            {"nav": "nav_per_share"},
+	OpBankSetInstruction: {"swift": "swift_bic", "account-num": "account_no"},
+	OpKYCApprove:         {"comments": "approval_notes"},
+	OpTaxCapture:         {"tin-value": "tin"},
+}
+
+// opArgTypes gives the typed args struct of each op, used to order raised arguments
+var opArgTypes = map[Op]reflect.Type{
+	OpInvestorStartOpportunity: reflect.TypeOf(InvestorStartOpportunityArgs{}),
+	OpInvestorRecordIndication: reflect.TypeOf(InvestorRecordIndicationArgs{}),
+	OpKYCBegin:                 reflect.TypeOf(KYCBeginArgs{}),
+	OpKYCCollectDoc:            reflect.TypeOf(KYCCollectDocArgs{}),
+	OpKYCScreen:                reflect.TypeOf(KYCScreenArgs{}),
+	OpKYCApprove:               reflect.TypeOf(KYCApproveArgs{}),
+	OpTaxCapture:               reflect.TypeOf(TaxCaptureArgs{}),
+	OpBankSetInstruction:       reflect.TypeOf(BankSetInstructionArgs{}),
+	OpSubscribeRequest:         reflect.TypeOf(SubscribeRequestArgs{}),
+	OpCashConfirm:              reflect.TypeOf(CashConfirmArgs{}),
+	OpDealNAV:                  reflect.TypeOf(DealNAVArgs{}),
+	OpSubscribeIssue:           reflect.TypeOf(SubscribeIssueArgs{}),
+	OpKYCRefreshSchedule:       reflect.TypeOf(KYCRefreshScheduleArgs{}),
+	OpScreenContinuous:         reflect.TypeOf(ScreenContinuousArgs{}),
+	OpRedeemRequest:            reflect.TypeOf(RedeemRequestArgs{}),
+	OpRedeemSettle:             reflect.TypeOf(RedeemSettleArgs{}),
+	OpOffboardClose:            reflect.TypeOf(OffboardCloseArgs{}),
+}
+
+// CompileDSL lowers an S-expression DSL document into a Plan. The plan is not
+// validated; call Plan.Validate before executing it.
+func CompileDSL(src string) (*Plan, error) {
+	ast, err := parser.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Version: "1.0.0"}
+	for _, form := range ast.Root.Children {
+		if form.Value == headerVerb {
+			if err := compileHeader(plan, form); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		step, err := compileStep(form)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, *step)
+	}
+
+	if len(plan.Steps) == 0 {
+		return nil, errors.New("DSL contains no steps")
+	}
+	if plan.PlanID == "" {
+		plan.PlanID = uuid.New().String()
+	}
+	if plan.CreatedAt.IsZero() {
+		plan.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	return plan, nil
+}
+
+func compileHeader(plan *Plan, form *parser.Node) error {
+	args, err := compileArgs(form, func(name string) string { return kebabToSnake(name) })
+	if err != nil {
+		return err
+	}
+	for name, value := range args {
+		switch name {
+		case "version":
+			plan.Version, _ = value.(string)
+		case "plan_id":
+			plan.PlanID, _ = value.(string)
+		case "created_at":
+			s, _ := value.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return fmt.Errorf("line %d: created-at must be RFC3339: %w", form.Line, err)
+			}
+			plan.CreatedAt = t
+		case "metadata":
+			m, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("line %d: metadata must be a (map ...)", form.Line)
+			}
+			plan.Metadata = m
+		default:
+			return fmt.Errorf("line %d: unknown plan.header field %q", form.Line, name)
+		}
+	}
+	return nil
+}
+
+func compileStep(form *parser.Node) (*Step, error) {
+	op := Op(form.Value)
+	if _, known := opArgTypes[op]; !known {
+		return nil, fmt.Errorf("line %d, column %d: unknown op %q", form.Line, form.Column, form.Value)
+	}
+
+	args, err := compileArgs(form, func(name string) string { return dslToArgName(op, name) })
+	if err != nil {
+		return nil, err
+	}
+
+	step := &Step{Op: op}
+	if v, ok := args[kebabToSnake(idempotencyKeyword)]; ok {
+		key, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("line %d: idempotency-key must be a string", form.Line)
+		}
+		step.IdempotencyKey = &key
+		delete(args, kebabToSnake(idempotencyKeyword))
+	}
+	if v, ok := args[annotationsKeyword]; ok {
+		m, isMap := v.(map[string]any)
+		if !isMap {
+			return nil, fmt.Errorf("line %d: annotations must be a (map ...)", form.Line)
+		}
+		step.Annotations = m
+		delete(args, annotationsKeyword)
+	}
+
+	if step.ArgsRaw, err = json.Marshal(args); err != nil {
+		return nil, fmt.Errorf("line %d: %w", form.Line, err)
+	}
+	return step, nil
+}
+
+// compileArgs reads the arguments of a form as keyword/value pairs or (name value)
+// pair expressions, naming each with argName.
+func compileArgs(form *parser.Node, argName func(string) string) (map[string]any, error) {
+	return compileArgList(form.Children[1:], argName)
+}
+
+func compileArgList(children []*parser.Node, argName func(string) string) (map[string]any, error) {
+	args := make(map[string]any)
+	for i := 0; i < len(children); i++ {
+		child := children[i]
+		var name string
+		var valueNode *parser.Node
+
+		switch {
+		case child.Type == parser.KeywordNode:
+			if i+1 >= len(children) || children[i+1].Type == parser.KeywordNode {
+				return nil, fmt.Errorf("line %d, column %d: keyword %s has no value", child.Line, child.Column, child.Value)
+			}
+			name = strings.TrimPrefix(child.Value, ":")
+			valueNode = children[i+1]
+			i++
+		case child.Type == parser.ExpressionNode && len(child.Children) == 2:
+			name = child.Value
+			valueNode = child.Children[1]
+		default:
+			return nil, fmt.Errorf("line %d, column %d: expected :keyword or (name value), got %s", child.Line, child.Column, child.Type)
+		}
+
+		value, err := compileValue(valueNode)
+		if err != nil {
+			return nil, err
+		}
+		key := argName(name)
+		if _, dup := args[key]; dup {
+			return nil, fmt.Errorf("line %d, column %d: duplicate argument %s", child.Line, child.Column, name)
+		}
+		args[key] = value
+	}
+	return args, nil
+}
+
+func compileValue(node *parser.Node) (any, error) {
+	switch node.Type {
+	case parser.IdentifierNode:
+		if node.Value == nullLiteral {
+			return nil, nil
+		}
+		return node.Value, nil
+	case parser.StringNode:
+		return node.Value, nil
+	case parser.NumberNode:
+		f, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d, column %d: invalid number %q", node.Line, node.Column, node.Value)
+		}
+		return f, nil
+	case parser.BooleanNode:
+		return node.Value == "true", nil
+	case parser.AttributeNode:
+		return map[string]any{"kind": "AttrRef", "id": node.AttributeID}, nil
+	case parser.ExpressionNode:
+		switch node.Value {
+		case mapVerb:
+			return compileArgs(node, kebabToSnake)
+		case attrRefVerb:
+			return compileAttrRef(node)
+		case listVerb:
+			items := make([]any, 0, len(node.Children)-1)
+			for _, child := range node.Children[1:] {
+				v, err := compileValue(child)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("line %d, column %d: unsupported value %s %q", node.Line, node.Column, node.Type, node.Value)
+}
+
+// compileAttrRef lowers (attr-ref @attr{ID} :type "..." :required false
+// :constraints (map ...) :sources (list (map ...) ...)).
+func compileAttrRef(node *parser.Node) (any, error) {
+	if len(node.Children) < 2 || node.Children[1].Type != parser.AttributeNode {
+		return nil, fmt.Errorf("line %d, column %d: attr-ref must start with @attr{ID}", node.Line, node.Column)
+	}
+	fields, err := compileArgList(node.Children[2:], kebabToSnake)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := map[string]any{"kind": "AttrRef", "id": node.Children[1].AttributeID}
+	for name, value := range fields {
+		var ok bool
+		switch name {
+		case "type":
+			_, ok = value.(string)
+		case "required":
+			_, ok = value.(bool)
+		case "constraints":
+			_, ok = value.(map[string]any)
+		case "sources":
+			var items []any
+			items, ok = value.([]any)
+			for _, item := range items {
+				if _, isMap := item.(map[string]any); !isMap {
+					ok = false
+				}
+			}
+		default:
+			return nil, fmt.Errorf("line %d, column %d: unknown attr-ref field %q", node.Line, node.Column, name)
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d, column %d: attr-ref field %q has the wrong type", node.Line, node.Column, name)
+		}
+		ref[name] = value
+	}
+	return ref, nil
+}
+
+func dslToArgName(op Op, name string) string {
+	if alias, ok := dslArgAliases[op][name]; ok {
+		return alias
+	}
+	if alias, ok := dslArgAliases[""][name]; ok {
+		return alias
+	}
+	return kebabToSnake(name)
+}
+
+func argToDSLName(op Op, arg string) string {
+	for _, table := range []map[string]string{dslArgAliases[op], dslArgAliases[""]} {
+		for dslName, argName := range table {
+			if argName == arg {
+				return dslName
+			}
+		}
+	}
+	return snakeToKebab(arg)
+}
+
+func kebabToSnake(s string) string { return strings.ReplaceAll(s, "-", "_") }
+func snakeToKebab(s string) string { return strings.ReplaceAll(s, "_", "-") }
+
+// ---------- Raising ----------
+
+// ToDSL prints the plan as canonical keyword-style DSL. Compiling the output
+// yields an equivalent plan.
+func (p *Plan) ToDSL() (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString("(" + headerVerb)
+	writeKeyword(&sb, "version", strconv.Quote(p.Version))
+	writeKeyword(&sb, "plan-id", strconv.Quote(p.PlanID))
+	if !p.CreatedAt.IsZero() {
+		writeKeyword(&sb, "created-at", strconv.Quote(p.CreatedAt.Format(time.RFC3339)))
+	}
+	if len(p.Metadata) > 0 {
+		metadata, err := formatValue(p.Metadata)
+		if err != nil {
+			return "", fmt.Errorf("metadata: %w", err)
+		}
+		writeKeyword(&sb, "metadata", metadata)
+	}
+	sb.WriteString(")\n")
+
+	for i, step := range p.Steps {
+		var args map[string]any
+		if err := json.Unmarshal(step.ArgsRaw, &args); err != nil {
+			return "", fmt.Errorf("step %d (%s): args must be an object: %w", i, step.Op, err)
+		}
+
+		sb.WriteString("\n(" + string(step.Op))
+		for _, name := range orderedArgNames(step.Op, args) {
+			if err := checkDSLKey(name); err != nil {
+				return "", fmt.Errorf("step %d (%s): %w", i, step.Op, err)
+			}
+			value, err := formatValue(args[name])
+			if err != nil {
+				return "", fmt.Errorf("step %d (%s): %s: %w", i, step.Op, name, err)
+			}
+			writeKeyword(&sb, argToDSLName(step.Op, name), value)
+		}
+		if step.IdempotencyKey != nil {
+			writeKeyword(&sb, idempotencyKeyword, strconv.Quote(*step.IdempotencyKey))
+		}
+		if len(step.Annotations) > 0 {
+			annotations, err := formatValue(step.Annotations)
+			if err != nil {
+				return "", fmt.Errorf("step %d (%s): annotations: %w", i, step.Op, err)
+			}
+			writeKeyword(&sb, annotationsKeyword, annotations)
+		}
+		sb.WriteString(")\n")
+	}
+
+	return sb.String(), nil
+}
+
+func writeKeyword(sb *strings.Builder, name, value string) {
+	sb.WriteString("\n  :" + name + " " + value)
+}
+
+// orderedArgNames orders args as declared in the op's typed args struct, followed
+// by any extra args alphabetically.
+func orderedArgNames(op Op, args map[string]any) []string {
+	names := make([]string, 0, len(args))
+	seen := make(map[string]bool)
+	if t, ok := opArgTypes[op]; ok {
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if _, present := args[tag]; present && !seen[tag] {
+				names = append(names, tag)
+				seen[tag] = true
+			}
+		}
+	}
+
+	var extra []string
+	for name := range args {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// checkDSLKey rejects object keys that would not survive the snake_case to
+// :kebab-case keyword mapping
+func checkDSLKey(key string) error {
+	if key == "" || strings.ContainsAny(key, "- \t\n()\":;@{}") {
+		return fmt.Errorf("key %q cannot be written as a DSL keyword", key)
+	}
+	return nil
+}
+
+func formatValue(v any) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return nullLiteral, nil
+	case string:
+		return strconv.Quote(val), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case []any:
+		return formatForm(listVerb, nil, val)
+	case map[string]any:
+		if kind, _ := val["kind"].(string); kind == "AttrRef" {
+			return formatAttrRef(val)
+		}
+		return formatForm(mapVerb, val, nil)
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", val)
+	}
+}
+
+// formatForm prints (verb :k v ...) for a map or (verb v ...) for a list
+func formatForm(verb string, fields map[string]any, items []any) (string, error) {
+	parts := []string{verb}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := checkDSLKey(k); err != nil {
+			return "", err
+		}
+		value, err := formatValue(fields[k])
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", k, err)
+		}
+		parts = append(parts, ":"+snakeToKebab(k), value)
+	}
+	for _, item := range items {
+		value, err := formatValue(item)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, value)
+	}
+	return "(" + strings.Join(parts, " ") + ")", nil
+}
+
+// formatAttrRef prints a bare @attr{ID}, or an (attr-ref ...) form when the
+// reference carries more than its ID
+func formatAttrRef(ref map[string]any) (string, error) {
+	id, _ := ref["id"].(string)
+	fields := make(map[string]any, len(ref))
+	for k, v := range ref {
+		switch k {
+		case "kind", "id":
+		case "type", "required", "constraints", "sources":
+			fields[k] = v
+		default:
+			return "", fmt.Errorf("AttrRef %s has unknown field %q", id, k)
+		}
+	}
+	if len(fields) == 0 {
+		return "@attr{" + id + "}", nil
+	}
+	form, err := formatForm(attrRefVerb, fields, nil)
+	if err != nil {
+		return "", fmt.Errorf("AttrRef %s: %w", id, err)
+	}
+	return "(" + attrRefVerb + " @attr{" + id + "} " + strings.TrimPrefix(form, "("+attrRefVerb+" "), nil
+}
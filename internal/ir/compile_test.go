@@ -0,0 +1,218 @@
+package ir
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stepArgs(t *testing.T, s Step) map[string]any {
+	t.Helper()
+	var args map[string]any
+	if err := json.Unmarshal(s.ArgsRaw, &args); err != nil {
+		t.Fatalf("Failed to decode args of %s: %v", s.Op, err)
+	}
+	return args
+}
+
+func assertPlansEqual(t *testing.T, want, got *Plan) {
+	t.Helper()
+	if want.Version != got.Version || want.PlanID != got.PlanID || !want.CreatedAt.Equal(got.CreatedAt) {
+		t.Errorf("Header mismatch: want %s/%s/%s, got %s/%s/%s",
+			want.Version, want.PlanID, want.CreatedAt, got.Version, got.PlanID, got.CreatedAt)
+	}
+	if !reflect.DeepEqual(want.Metadata, got.Metadata) {
+		t.Errorf("Metadata mismatch: want %v, got %v", want.Metadata, got.Metadata)
+	}
+	if len(want.Steps) != len(got.Steps) {
+		t.Fatalf("Expected %d steps, got %d", len(want.Steps), len(got.Steps))
+	}
+	for i := range want.Steps {
+		w, g := want.Steps[i], got.Steps[i]
+		if w.Op != g.Op {
+			t.Errorf("Step %d: expected op %s, got %s", i, w.Op, g.Op)
+		}
+		if !reflect.DeepEqual(stepArgs(t, w), stepArgs(t, g)) {
+			t.Errorf("Step %d (%s): args mismatch\nwant %s\ngot  %s", i, w.Op, w.ArgsRaw, g.ArgsRaw)
+		}
+		if !reflect.DeepEqual(w.IdempotencyKey, g.IdempotencyKey) {
+			t.Errorf("Step %d (%s): idempotency key mismatch", i, w.Op)
+		}
+		if !reflect.DeepEqual(w.Annotations, g.Annotations) {
+			t.Errorf("Step %d (%s): annotations mismatch: want %v, got %v", i, w.Op, w.Annotations, g.Annotations)
+		}
+	}
+}
+
+func TestPlanRoundTripsThroughDSL(t *testing.T) {
+	data, err := os.ReadFile("../../dsl/examples/corporate_subscription_example.json")
+	if err != nil {
+		t.Fatalf("Failed to read example IR: %v", err)
+	}
+	plan, err := ParsePlan(data)
+	if err != nil {
+		t.Fatalf("Failed to parse plan: %v", err)
+	}
+
+	src, err := plan.ToDSL()
+	if err != nil {
+		t.Fatalf("ToDSL failed: %v", err)
+	}
+	if !strings.Contains(src, ":legal-name @attr{INV.LEGAL_NAME}") {
+		t.Errorf("Expected AttrRef to be raised as @attr{}, got:\n%s", src)
+	}
+
+	compiled, err := CompileDSL(src)
+	if err != nil {
+		t.Fatalf("CompileDSL failed: %v\n%s", err, src)
+	}
+	assertPlansEqual(t, plan, compiled)
+
+	if err := compiled.Validate(); err != nil {
+		t.Errorf("Expected compiled plan to validate: %v", err)
+	}
+}
+
+func TestCompileLifecycleExample(t *testing.T) {
+	data, err := os.ReadFile("../../examples/hedge-fund-lifecycle/complete-lifecycle-example.dsl")
+	if err != nil {
+		t.Fatalf("Failed to read example DSL: %v", err)
+	}
+
+	plan, err := CompileDSL(string(data))
+	if err != nil {
+		t.Fatalf("CompileDSL failed: %v", err)
+	}
+	if len(plan.Steps) != 21 {
+		t.Errorf("Expected 21 steps, got %d", len(plan.Steps))
+	}
+	if err := plan.Validate(); err != nil {
+		t.Errorf("Expected compiled example to validate: %v", err)
+	}
+
+	t.Run("Aliases Map To IR Arg Names", func(t *testing.T) {
+		args := stepArgs(t, plan.Steps[1])
+		if args["investor_id"] != "a1b2c3d4-e5f6-4a5b-8c9d-0e1f2a3b4c5d" || args["ticket"] != 5000000.0 {
+			t.Errorf("Unexpected record-indication args: %v", args)
+		}
+		for _, s := range plan.Steps {
+			if s.Op == OpDealNAV {
+				if _, ok := stepArgs(t, s)["nav_per_share"]; !ok {
+					t.Errorf("Expected :nav to lower to nav_per_share, got %s", s.ArgsRaw)
+				}
+			}
+		}
+	})
+
+	t.Run("DSL Round Trip Is Stable", func(t *testing.T) {
+		src, err := plan.ToDSL()
+		if err != nil {
+			t.Fatalf("ToDSL failed: %v", err)
+		}
+		again, err := CompileDSL(src)
+		if err != nil {
+			t.Fatalf("Recompile failed: %v\n%s", err, src)
+		}
+		assertPlansEqual(t, plan, again)
+
+		src2, _ := again.ToDSL()
+		if src != src2 {
+			t.Errorf("Expected canonical DSL to be a fixed point:\n%s\n---\n%s", src, src2)
+		}
+	})
+}
+
+func TestRaiseKeepsNullsAndAttrRefFields(t *testing.T) {
+	plan := &Plan{
+		Version:   "1.0.0",
+		PlanID:    "8f1d2b7e-8f4b-4a2a-9d2b-7a2f3c1d9a01",
+		CreatedAt: time.Date(2025, 11, 4, 9, 0, 0, 0, time.UTC),
+		Metadata:  map[string]any{"parent_plan": nil},
+		Steps: []Step{{
+			Op: OpInvestorStartOpportunity,
+			ArgsRaw: json.RawMessage(`{"legal_name":{"kind":"AttrRef","id":"INV.LEGAL_NAME","type":"string","required":false,` +
+				`"constraints":{"max_length":200},"sources":[{"system":"crm","priority":1}]},` +
+				`"type":"CORPORATE","domicile":"US"}`),
+		}},
+	}
+
+	src, err := plan.ToDSL()
+	if err != nil {
+		t.Fatalf("ToDSL failed: %v", err)
+	}
+	for _, want := range []string{`:metadata (map :parent-plan null)`, `(attr-ref @attr{INV.LEGAL_NAME} :constraints (map :max-length 200)`, `:required false`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("Expected %s in raised DSL:\n%s", want, src)
+		}
+	}
+
+	compiled, err := CompileDSL(src)
+	if err != nil {
+		t.Fatalf("CompileDSL failed: %v\n%s", err, src)
+	}
+	assertPlansEqual(t, plan, compiled)
+	if err := compiled.Validate(); err != nil {
+		t.Errorf("Expected compiled plan to validate: %v", err)
+	}
+
+	t.Run("Keys That Cannot Round Trip Are Rejected", func(t *testing.T) {
+		bad := *plan
+		bad.Steps = []Step{{Op: OpKYCBegin, ArgsRaw: json.RawMessage(`{"investor_id":"a","annotations_x":{"needs-review":true}}`)}}
+		if _, err := bad.ToDSL(); err == nil || !strings.Contains(err.Error(), "needs-review") {
+			t.Errorf("Expected kebab-case key to be rejected, got %v", err)
+		}
+	})
+}
+
+func TestCompileDSLForms(t *testing.T) {
+	t.Run("Pair Form And AttrRefs", func(t *testing.T) {
+		plan, err := CompileDSL(`
+(plan.header :plan-id "p-1" :created-at "2025-01-01T00:00:00Z" :metadata (map :source "test"))
+(investor.start-opportunity
+  (legal-name @attr{INV.LEGAL_NAME})
+  (type "CORPORATE")
+  (address (map :line1 @attr{INV.ADDRESS.LINE1} :country "GB"))
+  (idempotency-key "opp-1"))`)
+		if err != nil {
+			t.Fatalf("CompileDSL failed: %v", err)
+		}
+		if plan.PlanID != "p-1" || plan.Metadata["source"] != "test" {
+			t.Errorf("Expected header to set plan fields, got %+v", plan)
+		}
+		step := plan.Steps[0]
+		if step.IdempotencyKey == nil || *step.IdempotencyKey != "opp-1" {
+			t.Errorf("Expected idempotency key opp-1, got %v", step.IdempotencyKey)
+		}
+		args := stepArgs(t, step)
+		ref := args["legal_name"].(map[string]any)
+		if ref["kind"] != "AttrRef" || ref["id"] != "INV.LEGAL_NAME" {
+			t.Errorf("Expected AttrRef, got %v", ref)
+		}
+		addr := args["address"].(map[string]any)
+		if addr["line1"].(map[string]any)["id"] != "INV.ADDRESS.LINE1" {
+			t.Errorf("Expected nested AttrRef, got %v", addr)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]string{
+			"unknown op":       `(fund.launch :fund "x")`,
+			"missing value":    `(kyc.begin :investor)`,
+			"duplicate arg":    `(kyc.begin :investor "a" :investor-id "b")`,
+			"bare argument":    `(kyc.begin "a")`,
+			"empty document":   `;; nothing here`,
+			"bad header field": `(plan.header :owner "x") (kyc.begin :investor "a")`,
+			"attr-ref no id":   `(kyc.begin :investor (attr-ref :required false))`,
+			"attr-ref field":   `(kyc.begin :investor (attr-ref @attr{INV.ID} :default "x"))`,
+			"attr-ref type":    `(kyc.begin :investor (attr-ref @attr{INV.ID} :required "no"))`,
+		}
+		for name, src := range cases {
+			if _, err := CompileDSL(src); err == nil {
+				t.Errorf("%s: expected error for %s", name, src)
+			}
+		}
+	})
+}
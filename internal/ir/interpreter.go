@@ -0,0 +1,858 @@
+package ir
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ---------- Interpreter ----------
+
+// Store is the subset of datastore.DataStore the interpreter needs: AttrRef
+// values are resolved through ResolveValueFor and investor state is kept in
+// its own per-CBU table, keyed by investor ID.
+type Store interface {
+	ResolveValueFor(ctx context.Context, cbuID, attributeID string) (json.RawMessage, map[string]any, string, error)
+	GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error)
+	SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error
+}
+
+// Investor lifecycle statuses (see HEDGE_FUND_INVESTOR.md)
+const (
+	InvestorStatusOpportunity      = "OPPORTUNITY"
+	InvestorStatusPrechecks        = "PRECHECKS"
+	InvestorStatusKYCPending       = "KYC_PENDING"
+	InvestorStatusKYCApproved      = "KYC_APPROVED"
+	InvestorStatusSubPendingCash   = "SUB_PENDING_CASH"
+	InvestorStatusFundedPendingNAV = "FUNDED_PENDING_NAV"
+	InvestorStatusIssued           = "ISSUED"
+	InvestorStatusActive           = "ACTIVE"
+	InvestorStatusRedeemPending    = "REDEEM_PENDING"
+	InvestorStatusRedeemed         = "REDEEMED"
+	InvestorStatusOffboarded       = "OFFBOARDED"
+)
+
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "SUCCEEDED"
+	StepFailed    StepStatus = "FAILED"
+	StepSkipped   StepStatus = "SKIPPED"   // not run because an earlier step failed
+	StepDuplicate StepStatus = "DUPLICATE" // idempotency key already applied
+)
+
+// KYC statuses, tracked separately from the lifecycle status because a
+// subscription can be placed while KYC is still in progress
+const (
+	KYCStatusPending  = "PENDING"
+	KYCStatusApproved = "APPROVED"
+)
+
+// InvestorState is the persisted state of one investor
+type InvestorState struct {
+	InvestorID       string             `json:"investor_id"`
+	LegalName        string             `json:"legal_name,omitempty"`
+	Type             string             `json:"type,omitempty"`
+	Domicile         string             `json:"domicile,omitempty"`
+	Status           string             `json:"status"`
+	KYCStatus        string             `json:"kyc_status,omitempty"`
+	RiskRating       string             `json:"risk_rating,omitempty"`
+	KYCRefreshDue    string             `json:"kyc_refresh_due,omitempty"`
+	Documents        []string           `json:"documents,omitempty"`
+	Screenings       []string           `json:"screenings,omitempty"`
+	TaxForm          string             `json:"tax_form,omitempty"`
+	FATCA            string             `json:"fatca,omitempty"`
+	CRS              string             `json:"crs,omitempty"`
+	BankInstructions map[string]string  `json:"bank_instructions,omitempty"` // currency -> SWIFT BIC
+	IndicatedTicket  float64            `json:"indicated_ticket,omitempty"`
+	PendingAmount    float64            `json:"pending_subscription_amount,omitempty"`
+	CashReceived     float64            `json:"cash_received,omitempty"`
+	Units            map[string]float64 `json:"units,omitempty"`                    // class -> units held
+	PendingRedeem    map[string]float64 `json:"pending_redemption_units,omitempty"` // class -> units
+	RedeemProceeds   float64            `json:"redemption_proceeds,omitempty"`
+	Monitoring       map[string]string  `json:"monitoring,omitempty"` // kyc_refresh / screening -> frequency
+	ClosedOn         string             `json:"closed_on,omitempty"`
+	AppliedKeys      []string           `json:"applied_keys,omitempty"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// TotalUnits returns the units held across all classes
+func (s *InvestorState) TotalUnits() float64 {
+	total := 0.0
+	for _, u := range s.Units {
+		total += u
+	}
+	return total
+}
+
+func (s *InvestorState) hasKey(key string) bool {
+	for _, k := range s.AppliedKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// StepOutcome records what happened to one plan step
+type StepOutcome struct {
+	Index          int            `json:"index"`
+	Op             Op             `json:"op"`
+	Status         StepStatus     `json:"status"`
+	InvestorID     string         `json:"investor_id,omitempty"`
+	FromStatus     string         `json:"from_status,omitempty"`
+	ToStatus       string         `json:"to_status,omitempty"`
+	IdempotencyKey string         `json:"idempotency_key,omitempty"`
+	Output         map[string]any `json:"output,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	StartedAt      time.Time      `json:"started_at"`
+	Duration       time.Duration  `json:"duration"`
+}
+
+// RunResult is the outcome of running a plan
+type RunResult struct {
+	PlanID    string                    `json:"plan_id"`
+	CBUID     string                    `json:"cbu_id"`
+	Success   bool                      `json:"success"`
+	Outcomes  []StepOutcome             `json:"outcomes"`
+	Investors map[string]*InvestorState `json:"investors"`
+	NAVs      map[string]float64        `json:"navs,omitempty"` // fund/class@date -> NAV per share
+	// Opportunity is an investor.start-opportunity without an investor_id that
+	// no later step adopted; it is not persisted.
+	Opportunity *InvestorState `json:"opportunity,omitempty"`
+}
+
+// Run holds the state of a single plan execution and is passed to handlers
+type Run struct {
+	interp    *Interpreter
+	result    *RunResult
+	attrCache map[string]any
+	// opportunity is the investor created by an investor.start-opportunity
+	// without an investor_id. It is held in memory until the first unknown
+	// investor_id adopts it, so no state is persisted under a made-up ID.
+	opportunity *InvestorState
+}
+
+// Handler executes one op. The step's args have had AttrRefs resolved. The
+// handler returns the investor it acted on (if any) and an output summary.
+type Handler func(ctx context.Context, run *Run, step *Step) (*InvestorState, map[string]any, error)
+
+// Interpreter executes validated IR plans against a Store
+type Interpreter struct {
+	store    Store
+	cbuID    string
+	handlers map[Op]Handler
+	now      func() time.Time
+}
+
+// NewInterpreter creates an interpreter with handlers for every Op. cbuID scopes
+// AttrRef resolution and investor state persistence.
+func NewInterpreter(store Store, cbuID string) *Interpreter {
+	in := &Interpreter{
+		store:    store,
+		cbuID:    cbuID,
+		handlers: make(map[Op]Handler),
+		now:      time.Now,
+	}
+	in.registerDefaultHandlers()
+	return in
+}
+
+// RegisterHandler installs or replaces the handler for an op
+func (in *Interpreter) RegisterHandler(op Op, h Handler) {
+	in.handlers[op] = h
+}
+
+// Run validates the plan and executes its steps in order. Execution stops at the
+// first failed step; later steps are reported as SKIPPED. Steps whose
+// idempotency key was already applied to the investor are reported as DUPLICATE.
+func (in *Interpreter) Run(ctx context.Context, plan *Plan) (*RunResult, error) {
+	if err := plan.Validate(); err != nil {
+		return nil, fmt.Errorf("plan validation failed: %w", err)
+	}
+
+	run := &Run{
+		interp:    in,
+		attrCache: make(map[string]any),
+		result: &RunResult{
+			PlanID:    plan.PlanID,
+			CBUID:     in.cbuID,
+			Success:   true,
+			Investors: make(map[string]*InvestorState),
+			NAVs:      make(map[string]float64),
+		},
+	}
+
+	for i := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return run.result, err
+		}
+
+		step := &plan.Steps[i]
+		if !run.result.Success {
+			run.result.Outcomes = append(run.result.Outcomes, StepOutcome{Index: i, Op: step.Op, Status: StepSkipped})
+			continue
+		}
+
+		outcome := run.executeStep(ctx, i, step)
+		run.result.Outcomes = append(run.result.Outcomes, outcome)
+		if outcome.Status == StepFailed {
+			run.result.Success = false
+		}
+	}
+
+	run.result.Opportunity = run.opportunity
+	return run.result, nil
+}
+
+func (r *Run) executeStep(ctx context.Context, index int, step *Step) StepOutcome {
+	outcome := StepOutcome{Index: index, Op: step.Op, StartedAt: r.interp.now()}
+	if step.IdempotencyKey != nil {
+		outcome.IdempotencyKey = *step.IdempotencyKey
+	}
+	fail := func(err error) StepOutcome {
+		outcome.Status = StepFailed
+		outcome.Error = err.Error()
+		outcome.Duration = r.interp.now().Sub(outcome.StartedAt)
+		return outcome
+	}
+
+	handler, ok := r.interp.handlers[step.Op]
+	if !ok {
+		return fail(fmt.Errorf("no handler for op %q", step.Op))
+	}
+
+	resolved, err := r.resolveStep(ctx, step)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Idempotency is tracked per investor so a re-run plan skips applied steps
+	if investorID := stepInvestorID(resolved); investorID != "" && outcome.IdempotencyKey != "" {
+		if inv, ok := r.knownInvestor(ctx, investorID); ok && inv.hasKey(outcome.IdempotencyKey) {
+			outcome.Status = StepDuplicate
+			outcome.InvestorID = investorID
+			outcome.ToStatus = inv.Status
+			outcome.Duration = r.interp.now().Sub(outcome.StartedAt)
+			return outcome
+		}
+	}
+
+	var from string
+	if investorID := stepInvestorID(resolved); investorID != "" {
+		if inv, ok := r.result.Investors[investorID]; ok {
+			from = inv.Status
+		}
+	}
+
+	inv, output, err := handler(ctx, r, resolved)
+	if err != nil {
+		return fail(err)
+	}
+
+	if inv != nil {
+		if outcome.IdempotencyKey != "" {
+			inv.AppliedKeys = append(inv.AppliedKeys, outcome.IdempotencyKey)
+		}
+		inv.UpdatedAt = r.interp.now()
+		if err := r.persist(ctx, inv, index, step.Op); err != nil {
+			return fail(err)
+		}
+		outcome.InvestorID = inv.InvestorID
+		outcome.FromStatus = from
+		outcome.ToStatus = inv.Status
+	}
+
+	outcome.Status = StepSucceeded
+	outcome.Output = output
+	outcome.Duration = r.interp.now().Sub(outcome.StartedAt)
+	return outcome
+}
+
+// ---------- AttrRef resolution ----------
+
+// resolveStep returns a copy of the step with every AttrRef in its args replaced
+// by the value resolved through the store.
+func (r *Run) resolveStep(ctx context.Context, step *Step) (*Step, error) {
+	var args any
+	if err := json.Unmarshal(step.ArgsRaw, &args); err != nil {
+		return nil, fmt.Errorf("decode args: %w", err)
+	}
+
+	resolvedArgs, err := r.resolveValue(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(resolvedArgs)
+	if err != nil {
+		return nil, fmt.Errorf("encode resolved args: %w", err)
+	}
+
+	resolved := *step
+	resolved.ArgsRaw = raw
+	return &resolved, nil
+}
+
+func (r *Run) resolveValue(ctx context.Context, v any) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		if kind, _ := val["kind"].(string); kind == "AttrRef" {
+			raw, _ := json.Marshal(val)
+			var ref AttrRef
+			if err := json.Unmarshal(raw, &ref); err != nil {
+				return nil, fmt.Errorf("decode AttrRef: %w", err)
+			}
+			return r.ResolveAttr(ctx, ref)
+		}
+		out := make(map[string]any, len(val))
+		for k, child := range val {
+			resolved, err := r.resolveValue(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, child := range val {
+			resolved, err := r.resolveValue(ctx, child)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// ResolveAttr resolves a late-bound attribute. AttrRefs are required unless they
+// set required=false, in which case an unresolved value becomes null.
+func (r *Run) ResolveAttr(ctx context.Context, ref AttrRef) (any, error) {
+	if v, ok := r.attrCache[ref.ID]; ok {
+		return v, nil
+	}
+
+	required := ref.Required == nil || *ref.Required
+	raw, _, state, err := r.interp.store.ResolveValueFor(ctx, r.interp.cbuID, ref.ID)
+	if err != nil {
+		if required {
+			return nil, fmt.Errorf("resolve attribute %s: %w", ref.ID, err)
+		}
+		return nil, nil
+	}
+
+	var value any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("decode attribute %s: %w", ref.ID, err)
+		}
+	}
+	if value == nil || state == "pending" {
+		if required {
+			return nil, fmt.Errorf("attribute %s is unresolved (state %q)", ref.ID, state)
+		}
+		return nil, nil
+	}
+
+	r.attrCache[ref.ID] = value
+	return value, nil
+}
+
+// ---------- Investor state ----------
+
+// Investor returns the state of an investor, loading persisted state from the
+// store on first use. Unknown investors adopt a pending opportunity if there is
+// one and otherwise return an error.
+func (r *Run) Investor(ctx context.Context, investorID string) (*InvestorState, error) {
+	if inv, ok := r.knownInvestor(ctx, investorID); ok {
+		return inv, nil
+	}
+
+	if r.opportunity != nil {
+		inv := r.opportunity
+		inv.InvestorID = investorID
+		r.result.Investors[investorID] = inv
+		r.opportunity = nil
+		return inv, nil
+	}
+
+	return nil, fmt.Errorf("investor %s not found; start an opportunity first", investorID)
+}
+
+// knownInvestor returns an investor already seen in this run or persisted by an
+// earlier one. Unlike Investor it never adopts the pending opportunity.
+func (r *Run) knownInvestor(ctx context.Context, investorID string) (*InvestorState, bool) {
+	if inv, ok := r.result.Investors[investorID]; ok {
+		return inv, true
+	}
+	if inv, ok := r.loadInvestor(ctx, investorID); ok {
+		r.result.Investors[investorID] = inv
+		return inv, true
+	}
+	return nil, false
+}
+
+// loadInvestor reads persisted investor state. Missing or unreadable state is
+// treated as a new investor.
+func (r *Run) loadInvestor(ctx context.Context, investorID string) (*InvestorState, bool) {
+	raw, err := r.interp.store.GetInvestorState(ctx, r.interp.cbuID, strings.ToUpper(investorID))
+	if err != nil || len(raw) == 0 || string(raw) == "null" {
+		return nil, false
+	}
+	var inv InvestorState
+	if err := json.Unmarshal(raw, &inv); err != nil || inv.InvestorID == "" {
+		return nil, false
+	}
+	return &inv, true
+}
+
+func (r *Run) persist(ctx context.Context, inv *InvestorState, index int, op Op) error {
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("encode investor state: %w", err)
+	}
+	source := map[string]any{
+		"type":    "ir_plan",
+		"plan_id": r.result.PlanID,
+		"step":    index,
+		"op":      string(op),
+	}
+	if err := r.interp.store.SaveInvestorState(ctx, r.interp.cbuID, strings.ToUpper(inv.InvestorID), payload, source); err != nil {
+		return fmt.Errorf("persist investor state: %w", err)
+	}
+	return nil
+}
+
+// Transition moves the investor to a new status if its current status is one of allowed
+func (r *Run) Transition(inv *InvestorState, to string, allowed ...string) error {
+	for _, from := range allowed {
+		if inv.Status == from {
+			inv.Status = to
+			return nil
+		}
+	}
+	return fmt.Errorf("investor %s cannot move from %s to %s", inv.InvestorID, inv.Status, to)
+}
+
+// stepInvestorID extracts investor_id from a step's args, if present
+func stepInvestorID(step *Step) string {
+	var probe struct {
+		InvestorID string `json:"investor_id"`
+	}
+	if err := json.Unmarshal(step.ArgsRaw, &probe); err != nil {
+		return ""
+	}
+	return probe.InvestorID
+}
+
+// ---------- Default handlers ----------
+
+func (in *Interpreter) registerDefaultHandlers() {
+	in.handlers[OpInvestorStartOpportunity] = handleStartOpportunity
+	in.handlers[OpInvestorRecordIndication] = handleRecordIndication
+	in.handlers[OpKYCBegin] = handleKYCBegin
+	in.handlers[OpKYCCollectDoc] = handleKYCCollectDoc
+	in.handlers[OpKYCScreen] = handleKYCScreen
+	in.handlers[OpKYCApprove] = handleKYCApprove
+	in.handlers[OpTaxCapture] = handleTaxCapture
+	in.handlers[OpBankSetInstruction] = handleBankSetInstruction
+	in.handlers[OpSubscribeRequest] = handleSubscribeRequest
+	in.handlers[OpCashConfirm] = handleCashConfirm
+	in.handlers[OpDealNAV] = handleDealNAV
+	in.handlers[OpSubscribeIssue] = handleSubscribeIssue
+	in.handlers[OpKYCRefreshSchedule] = handleKYCRefreshSchedule
+	in.handlers[OpScreenContinuous] = handleScreenContinuous
+	in.handlers[OpRedeemRequest] = handleRedeemRequest
+	in.handlers[OpRedeemSettle] = handleRedeemSettle
+	in.handlers[OpOffboardClose] = handleOffboardClose
+}
+
+// activeStatuses are the statuses in which an investor can still be serviced
+var activeStatuses = []string{
+	InvestorStatusOpportunity, InvestorStatusPrechecks, InvestorStatusKYCPending, InvestorStatusKYCApproved,
+	InvestorStatusSubPendingCash, InvestorStatusFundedPendingNAV, InvestorStatusIssued, InvestorStatusActive,
+	InvestorStatusRedeemPending, InvestorStatusRedeemed,
+}
+
+func (r *Run) decodeFor(ctx context.Context, step *Step, args any, investorID func() string) (*InvestorState, error) {
+	if err := step.DecodeArgs(args); err != nil {
+		return nil, err
+	}
+	if investorID == nil {
+		return nil, nil
+	}
+	inv, err := r.Investor(ctx, investorID())
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status == InvestorStatusOffboarded {
+		return nil, fmt.Errorf("investor %s is offboarded", inv.InvestorID)
+	}
+	// Issued units become an active holding once the investor is next serviced
+	if inv.Status == InvestorStatusIssued {
+		inv.Status = InvestorStatusActive
+	}
+	return inv, nil
+}
+
+// startOpportunityArgs extends the typed args with an optional investor_id so a
+// plan can name the investor up front.
+type startOpportunityArgs struct {
+	InvestorStartOpportunityArgs
+	InvestorID string `json:"investor_id,omitempty"`
+}
+
+func handleStartOpportunity(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a startOpportunityArgs
+	if err := step.DecodeArgs(&a); err != nil {
+		return nil, nil, err
+	}
+
+	var legalName, domicile string
+	if err := json.Unmarshal(a.LegalName, &legalName); err != nil {
+		return nil, nil, errors.New("legal_name did not resolve to a string")
+	}
+	if err := json.Unmarshal(a.Domicile, &domicile); err != nil {
+		return nil, nil, errors.New("domicile did not resolve to a string")
+	}
+
+	inv := &InvestorState{
+		LegalName: legalName,
+		Type:      a.Type,
+		Domicile:  domicile,
+		Status:    InvestorStatusOpportunity,
+	}
+	output := map[string]any{"legal_name": legalName, "type": a.Type, "domicile": domicile}
+
+	if a.InvestorID == "" {
+		if r.opportunity != nil {
+			return nil, nil, errors.New("previous opportunity has not been adopted by an investor_id yet")
+		}
+		r.opportunity = inv
+		return nil, output, nil
+	}
+
+	if !reUUID.MatchString(a.InvestorID) {
+		return nil, nil, errors.New("investor_id must be UUID")
+	}
+	if existing, ok := r.knownInvestor(ctx, a.InvestorID); ok {
+		return nil, nil, fmt.Errorf("investor %s already exists (status %s)", a.InvestorID, existing.Status)
+	}
+	inv.InvestorID = a.InvestorID
+	r.result.Investors[inv.InvestorID] = inv
+	return inv, output, nil
+}
+
+func handleRecordIndication(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a InvestorRecordIndicationArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.Status == InvestorStatusOpportunity {
+		inv.Status = InvestorStatusPrechecks
+	}
+	inv.IndicatedTicket = a.Ticket
+	return inv, map[string]any{"fund_id": a.FundID, "class_id": a.ClassID, "ticket": a.Ticket}, nil
+}
+
+func handleKYCBegin(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a KYCBeginArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.Transition(inv, InvestorStatusKYCPending, InvestorStatusOpportunity, InvestorStatusPrechecks); err != nil {
+		return nil, nil, err
+	}
+	inv.KYCStatus = KYCStatusPending
+	if a.RiskRating != nil {
+		inv.RiskRating = *a.RiskRating
+	}
+	return inv, map[string]any{"kyc": "started"}, nil
+}
+
+func handleKYCCollectDoc(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a KYCCollectDocArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.KYCStatus != KYCStatusPending {
+		return nil, nil, fmt.Errorf("documents can only be collected during KYC (status %s)", inv.Status)
+	}
+	inv.Documents = append(inv.Documents, a.DocType)
+	return inv, map[string]any{"doc_type": a.DocType, "documents": len(inv.Documents)}, nil
+}
+
+func handleKYCScreen(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a KYCScreenArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.KYCStatus != KYCStatusPending {
+		return nil, nil, fmt.Errorf("screening can only run during KYC (status %s)", inv.Status)
+	}
+	inv.Screenings = append(inv.Screenings, a.Provider)
+	return inv, map[string]any{"provider": a.Provider}, nil
+}
+
+func handleKYCApprove(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a KYCApproveArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.KYCStatus != KYCStatusPending {
+		return nil, nil, fmt.Errorf("investor %s has no KYC in progress (status %s)", inv.InvestorID, inv.Status)
+	}
+	// An investor that already subscribed keeps its lifecycle status
+	if inv.Status == InvestorStatusKYCPending {
+		inv.Status = InvestorStatusKYCApproved
+	}
+	inv.KYCStatus = KYCStatusApproved
+	inv.RiskRating = a.Risk
+	inv.KYCRefreshDue = a.RefreshDue
+	return inv, map[string]any{"risk": a.Risk, "refresh_due": a.RefreshDue}, nil
+}
+
+func handleTaxCapture(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a TaxCaptureArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	inv.TaxForm, inv.FATCA, inv.CRS = a.Form, a.FATCA, a.CRS
+	return inv, map[string]any{"form": a.Form, "fatca": a.FATCA, "crs": a.CRS}, nil
+}
+
+func handleBankSetInstruction(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a BankSetInstructionArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.BankInstructions == nil {
+		inv.BankInstructions = make(map[string]string)
+	}
+	inv.BankInstructions[a.Currency] = a.SWIFTBIC
+	return inv, map[string]any{"currency": a.Currency, "swift_bic": a.SWIFTBIC}, nil
+}
+
+func handleSubscribeRequest(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a SubscribeRequestArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	// Orders are accepted once KYC has started; the KYC status is reported so
+	// an order placed before approval is visible
+	if err := r.Transition(inv, InvestorStatusSubPendingCash, InvestorStatusKYCPending, InvestorStatusKYCApproved, InvestorStatusActive); err != nil {
+		return nil, nil, err
+	}
+	inv.PendingAmount += a.Amount
+	return inv, map[string]any{"class_id": a.ClassID, "amount": a.Amount, "currency": a.Currency, "trade_date": a.TradeDate, "kyc_status": inv.KYCStatus}, nil
+}
+
+func handleCashConfirm(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a CashConfirmArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.Transition(inv, InvestorStatusFundedPendingNAV, InvestorStatusSubPendingCash); err != nil {
+		return nil, nil, err
+	}
+	inv.CashReceived += a.Amount
+	return inv, map[string]any{"amount": a.Amount, "value_date": a.ValueDate}, nil
+}
+
+func handleDealNAV(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a DealNAVArgs
+	if _, err := r.decodeFor(ctx, step, &a, nil); err != nil {
+		return nil, nil, err
+	}
+	key := a.FundID
+	if a.ClassID != nil {
+		key += "/" + *a.ClassID
+	}
+	key += "@" + a.NAVDate
+	output := map[string]any{"fund_id": a.FundID, "nav_date": a.NAVDate}
+	if a.NAVPerShare != nil {
+		r.result.NAVs[key] = *a.NAVPerShare
+		output["nav_per_share"] = *a.NAVPerShare
+	}
+	return nil, output, nil
+}
+
+func handleSubscribeIssue(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a SubscribeIssueArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.Transition(inv, InvestorStatusIssued, InvestorStatusFundedPendingNAV); err != nil {
+		return nil, nil, err
+	}
+	if inv.Units == nil {
+		inv.Units = make(map[string]float64)
+	}
+	inv.Units[a.ClassID] += a.Units
+	inv.PendingAmount = 0
+	return inv, map[string]any{"class_id": a.ClassID, "units": a.Units, "nav_per_share": a.NAVPerShare}, nil
+}
+
+func handleKYCRefreshSchedule(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a KYCRefreshScheduleArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.Monitoring == nil {
+		inv.Monitoring = make(map[string]string)
+	}
+	inv.Monitoring["kyc_refresh"] = a.Frequency
+	inv.KYCRefreshDue = a.Next
+	return inv, map[string]any{"frequency": a.Frequency, "next": a.Next}, nil
+}
+
+func handleScreenContinuous(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a ScreenContinuousArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.Monitoring == nil {
+		inv.Monitoring = make(map[string]string)
+	}
+	inv.Monitoring["screening"] = a.Frequency
+	return inv, map[string]any{"frequency": a.Frequency}, nil
+}
+
+func handleRedeemRequest(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a RedeemRequestArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	held := inv.Units[a.ClassID] - inv.PendingRedeem[a.ClassID]
+	if a.Units > held+1e-9 {
+		return nil, nil, fmt.Errorf("cannot redeem %.6f units of class %s; %.6f available", a.Units, a.ClassID, held)
+	}
+	if err := r.Transition(inv, InvestorStatusRedeemPending, InvestorStatusActive, InvestorStatusRedeemPending); err != nil {
+		return nil, nil, err
+	}
+	if inv.PendingRedeem == nil {
+		inv.PendingRedeem = make(map[string]float64)
+	}
+	inv.PendingRedeem[a.ClassID] += a.Units
+	return inv, map[string]any{"class_id": a.ClassID, "units": a.Units, "notice_date": a.NoticeDate}, nil
+}
+
+// redeemSettleArgs extends the typed args with the units being settled, which
+// runbooks carry alongside the cash amount.
+type redeemSettleArgs struct {
+	RedeemSettleArgs
+	ClassID     *string  `json:"class_id,omitempty"`
+	Units       *float64 `json:"units,omitempty"`
+	NAVPerShare *float64 `json:"nav_per_share,omitempty"`
+}
+
+func handleRedeemSettle(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a redeemSettleArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv.Status != InvestorStatusRedeemPending {
+		return nil, nil, fmt.Errorf("investor %s has no pending redemption (status %s)", inv.InvestorID, inv.Status)
+	}
+
+	// Settle the named class, or every pending redemption when none is given.
+	// A unit count only makes sense against a single class.
+	if a.Units != nil && a.ClassID == nil && len(inv.PendingRedeem) > 1 {
+		return nil, nil, fmt.Errorf("investor %s has pending redemptions in %d classes; class_id is required with units", inv.InvestorID, len(inv.PendingRedeem))
+	}
+	settledUnits := 0.0
+	for classID, pending := range inv.PendingRedeem {
+		if a.ClassID != nil && *a.ClassID != classID {
+			continue
+		}
+		settled := pending
+		if a.Units != nil && *a.Units < pending {
+			settled = *a.Units
+		}
+		settledUnits += settled
+		inv.Units[classID] -= settled
+		inv.PendingRedeem[classID] -= settled
+		if inv.Units[classID] <= 1e-9 {
+			delete(inv.Units, classID)
+		}
+		if inv.PendingRedeem[classID] <= 1e-9 {
+			delete(inv.PendingRedeem, classID)
+		}
+	}
+	// Without a cash amount the proceeds are priced at the settlement NAV
+	amount := a.Amount
+	if amount == 0 && a.NAVPerShare != nil {
+		amount = settledUnits * *a.NAVPerShare
+	}
+	inv.RedeemProceeds += amount
+
+	switch {
+	case len(inv.PendingRedeem) > 0:
+		// Partially settled; remain pending
+	case inv.TotalUnits() <= 1e-9:
+		inv.Status = InvestorStatusRedeemed
+	default:
+		inv.Status = InvestorStatusActive
+	}
+	return inv, map[string]any{"amount": amount, "units": settledUnits, "settle_date": a.SettleDate, "units_remaining": inv.TotalUnits()}, nil
+}
+
+// offboardCloseArgs extends the typed args with the free-text note runbooks
+// carry when their reason is not one of the IR reason codes.
+type offboardCloseArgs struct {
+	OffboardCloseArgs
+	Note *string `json:"note,omitempty"`
+}
+
+func handleOffboardClose(ctx context.Context, r *Run, step *Step) (*InvestorState, map[string]any, error) {
+	var a offboardCloseArgs
+	inv, err := r.decodeFor(ctx, step, &a, func() string { return a.InvestorID })
+	if err != nil {
+		return nil, nil, err
+	}
+	// Cash in flight has to settle first; units still held stay on record and
+	// are reported so the closure can be reconciled
+	if inv.PendingAmount > 0 || len(inv.PendingRedeem) > 0 {
+		return nil, nil, fmt.Errorf("investor %s has an unsettled subscription or redemption", inv.InvestorID)
+	}
+	if err := r.Transition(inv, InvestorStatusOffboarded, activeStatuses...); err != nil {
+		return nil, nil, err
+	}
+	if a.ClosureDate != nil {
+		inv.ClosedOn = *a.ClosureDate
+	} else {
+		inv.ClosedOn = r.interp.now().Format("2006-01-02")
+	}
+	output := map[string]any{"closed_on": inv.ClosedOn}
+	if a.Reason != nil {
+		output["reason"] = *a.Reason
+	}
+	if a.Note != nil {
+		output["note"] = *a.Note
+	}
+	if units := inv.TotalUnits(); units > 1e-9 {
+		output["units_outstanding"] = units
+	}
+	return inv, output, nil
+}
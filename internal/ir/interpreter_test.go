@@ -0,0 +1,303 @@
+package ir
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// memStore is an in-memory Store for interpreter tests
+type memStore struct {
+	values    map[string]json.RawMessage
+	investors map[string]json.RawMessage
+}
+
+func newMemStore() *memStore {
+	return &memStore{values: make(map[string]json.RawMessage), investors: make(map[string]json.RawMessage)}
+}
+
+func (m *memStore) ResolveValueFor(_ context.Context, cbuID, attributeID string) (json.RawMessage, map[string]any, string, error) {
+	if v, ok := m.values[cbuID+"|"+attributeID]; ok {
+		return v, map[string]any{"type": "test"}, "resolved", nil
+	}
+	return json.RawMessage("null"), map[string]any{"reason": "no_resolver"}, "pending", nil
+}
+
+func (m *memStore) GetInvestorState(_ context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	if v, ok := m.investors[cbuID+"|"+investorID]; ok {
+		return v, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *memStore) SaveInvestorState(_ context.Context, cbuID, investorID string, state json.RawMessage, _ map[string]any) error {
+	m.investors[cbuID+"|"+investorID] = state
+	return nil
+}
+
+func (m *memStore) set(cbuID, attributeID string, v any) {
+	raw, _ := json.Marshal(v)
+	m.values[cbuID+"|"+attributeID] = raw
+}
+
+func TestInterpreterRunsSampleRunbook(t *testing.T) {
+	data, err := os.ReadFile("../../examples/runbook.sample.json")
+	if err != nil {
+		t.Fatalf("Failed to read runbook: %v", err)
+	}
+
+	plan, err := ParsePlanOrRunbook(data)
+	if err != nil {
+		t.Fatalf("Failed to convert runbook: %v", err)
+	}
+
+	store := newMemStore()
+	result, err := NewInterpreter(store, "CBU-HF-001").Run(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !result.Success {
+		for _, o := range result.Outcomes {
+			t.Logf("step %d %s: %s %s", o.Index, o.Op, o.Status, o.Error)
+		}
+		t.Fatal("Expected runbook to complete")
+	}
+
+	inv := result.Investors["aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"]
+	if inv == nil {
+		t.Fatal("Expected investor to be adopted from the opportunity")
+	}
+	if inv.Status != InvestorStatusOffboarded {
+		t.Errorf("Expected OFFBOARDED, got %s", inv.Status)
+	}
+	if inv.LegalName != "Acme LP" {
+		t.Errorf("Expected legal name from opportunity, got %q", inv.LegalName)
+	}
+
+	if inv.KYCStatus != KYCStatusPending {
+		t.Errorf("Expected KYC to still be pending, got %q", inv.KYCStatus)
+	}
+
+	var issued *StepOutcome
+	for i := range result.Outcomes {
+		if result.Outcomes[i].Op == OpSubscribeIssue {
+			issued = &result.Outcomes[i]
+		}
+	}
+	if issued == nil || issued.ToStatus != InvestorStatusIssued {
+		t.Errorf("Expected subscribe.issue to leave the investor ISSUED, got %+v", issued)
+	}
+
+	closed := result.Outcomes[len(result.Outcomes)-1]
+	if closed.Output["note"] != "End of mandate" || closed.Output["units_outstanding"] == nil {
+		t.Errorf("Expected closure note and outstanding units, got %v", closed.Output)
+	}
+
+	// Only the adopted investor's state is persisted
+	if len(store.investors) != 1 {
+		t.Errorf("Expected one persisted state, got %d", len(store.investors))
+	}
+	if _, ok := store.investors["CBU-HF-001|AAAAAAAA-BBBB-CCCC-DDDD-EEEEEEEEEEEE"]; !ok {
+		t.Error("Expected investor state to be persisted")
+	}
+}
+
+func TestInterpreterOpportunityIDs(t *testing.T) {
+	investorID := "2fd4e5a7-3e84-4d2b-9f1a-7f6d0a9b1234"
+	plan := func(args string) *Plan {
+		return &Plan{Version: "1.0.0", PlanID: "8f1d2b7e-8f4b-4a2a-9d2b-7a2f3c1d9a01", Steps: []Step{
+			{Op: OpInvestorStartOpportunity, ArgsRaw: json.RawMessage(args)},
+		}}
+	}
+
+	t.Run("Unadopted Opportunity Is Not Persisted", func(t *testing.T) {
+		store := newMemStore()
+		result, err := NewInterpreter(store, "CBU-1").Run(context.Background(), plan(`{"legal_name":"Acme LP","type":"CORPORATE","domicile":"US"}`))
+		if err != nil || !result.Success {
+			t.Fatalf("Expected run to succeed: %v %+v", err, result)
+		}
+		if result.Opportunity == nil || result.Opportunity.LegalName != "Acme LP" {
+			t.Errorf("Expected pending opportunity in result, got %+v", result.Opportunity)
+		}
+		if len(store.investors) != 0 || len(result.Investors) != 0 {
+			t.Errorf("Expected nothing persisted, got %d values and %d investors", len(store.investors), len(result.Investors))
+		}
+	})
+
+	t.Run("Explicit Investor ID Is Persisted", func(t *testing.T) {
+		store := newMemStore()
+		args := `{"investor_id":"` + investorID + `","legal_name":"Acme LP","type":"CORPORATE","domicile":"US"}`
+		result, err := NewInterpreter(store, "CBU-1").Run(context.Background(), plan(args))
+		if err != nil || !result.Success {
+			t.Fatalf("Expected run to succeed: %v %+v", err, result)
+		}
+		if result.Outcomes[0].InvestorID != investorID || result.Opportunity != nil {
+			t.Errorf("Expected investor %s, got %+v", investorID, result.Outcomes[0])
+		}
+		if _, ok := store.investors["CBU-1|"+strings.ToUpper(investorID)]; !ok {
+			t.Error("Expected investor state to be persisted")
+		}
+
+		// Starting the same investor again is rejected
+		again, err := NewInterpreter(store, "CBU-1").Run(context.Background(), plan(args))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if again.Success || !strings.Contains(again.Outcomes[0].Error, "already exists") {
+			t.Errorf("Expected duplicate investor to fail, got %+v", again.Outcomes[0])
+		}
+	})
+}
+
+func TestInterpreterResolvesAttrRefs(t *testing.T) {
+	data, err := os.ReadFile("../../dsl/examples/corporate_subscription_example.json")
+	if err != nil {
+		t.Fatalf("Failed to read example IR: %v", err)
+	}
+	plan, err := ParsePlan(data)
+	if err != nil {
+		t.Fatalf("Failed to parse plan: %v", err)
+	}
+
+	t.Run("Unresolved Required AttrRef Fails Step", func(t *testing.T) {
+		result, err := NewInterpreter(newMemStore(), "CBU-1").Run(context.Background(), plan)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result.Success {
+			t.Fatal("Expected failure for unresolved AttrRefs")
+		}
+		if result.Outcomes[0].Status != StepFailed || !strings.Contains(result.Outcomes[0].Error, "is unresolved") {
+			t.Errorf("Expected first step to fail on an unresolved AttrRef, got %+v", result.Outcomes[0])
+		}
+		if result.Outcomes[1].Status != StepSkipped {
+			t.Errorf("Expected later steps to be skipped, got %s", result.Outcomes[1].Status)
+		}
+	})
+
+	t.Run("Resolved AttrRefs Bind Values", func(t *testing.T) {
+		store := newMemStore()
+		store.set("CBU-1", "INV.LEGAL_NAME", "Alpha Holdings Ltd")
+		store.set("CBU-1", "INV.LEI", "5493001KJTIIGC8Y1R12")
+		store.set("CBU-1", "INV.ADDRESS.LINE1", "1 Fleet Street")
+		store.set("CBU-1", "INV.ADDRESS.CITY", "London")
+
+		result, err := NewInterpreter(store, "CBU-1").Run(context.Background(), plan)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result.Outcomes[0].Status != StepSucceeded {
+			t.Fatalf("Expected opportunity to succeed, got %+v", result.Outcomes[0])
+		}
+		if name := result.Outcomes[0].Output["legal_name"]; name != "Alpha Holdings Ltd" {
+			t.Errorf("Expected resolved legal name, got %v", name)
+		}
+	})
+}
+
+func TestInterpreterGuardsAndIdempotency(t *testing.T) {
+	investorID := "2fd4e5a7-3e84-4d2b-9f1a-7f6d0a9b1234"
+	classID := "a6b3b7e1-2c1f-4d5e-8a90-1b2c3d4e5f60"
+	key := "sub-request-0001"
+
+	newPlan := func(steps ...Step) *Plan {
+		return &Plan{Version: "1.0.0", PlanID: "8f1d2b7e-8f4b-4a2a-9d2b-7a2f3c1d9a01", Steps: steps}
+	}
+	step := func(op Op, args string, idem *string) Step {
+		return Step{Op: op, ArgsRaw: json.RawMessage(args), IdempotencyKey: idem}
+	}
+
+	opportunity := step(OpInvestorStartOpportunity, `{"legal_name":"Acme LP","type":"CORPORATE","domicile":"US"}`, nil)
+	kycBegin := step(OpKYCBegin, `{"investor_id":"`+investorID+`"}`, nil)
+	subscribe := step(OpSubscribeRequest, `{"investor_id":"`+investorID+`","class_id":"`+classID+`","amount":1000,"trade_date":"2025-11-28","currency":"USD"}`, &key)
+
+	t.Run("Subscription Requires KYC", func(t *testing.T) {
+		result, err := NewInterpreter(newMemStore(), "CBU-1").Run(context.Background(), newPlan(opportunity, subscribe))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		last := result.Outcomes[1]
+		if last.Status != StepFailed || !strings.Contains(last.Error, "OPPORTUNITY") {
+			t.Errorf("Expected guard failure from OPPORTUNITY, got %+v", last)
+		}
+	})
+
+	t.Run("Approval After Subscription Keeps Status", func(t *testing.T) {
+		approve := step(OpKYCApprove, `{"investor_id":"`+investorID+`","risk":"LOW","refresh_due":"2026-11-01"}`, nil)
+		result, err := NewInterpreter(newMemStore(), "CBU-1").Run(context.Background(), newPlan(opportunity, kycBegin, subscribe, approve))
+		if err != nil || !result.Success {
+			t.Fatalf("Expected run to succeed: %v %+v", err, result)
+		}
+		inv := result.Investors[investorID]
+		if inv.Status != InvestorStatusSubPendingCash || inv.KYCStatus != KYCStatusApproved {
+			t.Errorf("Expected SUB_PENDING_CASH with KYC approved, got %s/%s", inv.Status, inv.KYCStatus)
+		}
+	})
+
+	t.Run("Re-running Applied Step Is A Duplicate", func(t *testing.T) {
+		store := newMemStore()
+		approve := step(OpKYCApprove, `{"investor_id":"`+investorID+`","risk":"LOW","refresh_due":"2026-11-01"}`, nil)
+
+		first, err := NewInterpreter(store, "CBU-1").Run(context.Background(), newPlan(opportunity, kycBegin, approve, subscribe))
+		if err != nil || !first.Success {
+			t.Fatalf("Expected first run to succeed: %v %+v", err, first)
+		}
+
+		second, err := NewInterpreter(store, "CBU-1").Run(context.Background(), newPlan(subscribe))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if second.Outcomes[0].Status != StepDuplicate {
+			t.Errorf("Expected DUPLICATE, got %s (%s)", second.Outcomes[0].Status, second.Outcomes[0].Error)
+		}
+	})
+
+	t.Run("Unit Settlement Across Classes Needs A Class", func(t *testing.T) {
+		store := newMemStore()
+		otherClass := "b7c4c8f2-3d2a-4e6f-9ba1-2c3d4e5f6a71"
+		raw, _ := json.Marshal(InvestorState{
+			InvestorID:    investorID,
+			Status:        InvestorStatusRedeemPending,
+			Units:         map[string]float64{classID: 100, otherClass: 50},
+			PendingRedeem: map[string]float64{classID: 40, otherClass: 20},
+		})
+		store.investors["CBU-1|"+strings.ToUpper(investorID)] = raw
+
+		settle := step(OpRedeemSettle, `{"investor_id":"`+investorID+`","amount":400,"settle_date":"2026-01-15","units":10}`, nil)
+		result, err := NewInterpreter(store, "CBU-1").Run(context.Background(), newPlan(settle))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if out := result.Outcomes[0]; out.Status != StepFailed || !strings.Contains(out.Error, "class_id is required") {
+			t.Fatalf("Expected ambiguous settlement to fail, got %+v", out)
+		}
+
+		settle = step(OpRedeemSettle, `{"investor_id":"`+investorID+`","amount":400,"settle_date":"2026-01-15","units":10,"class_id":"`+classID+`"}`, nil)
+		result, err = NewInterpreter(store, "CBU-1").Run(context.Background(), newPlan(settle))
+		if err != nil || !result.Success {
+			t.Fatalf("Expected class-scoped settlement to succeed: %v %+v", err, result)
+		}
+		inv := result.Investors[investorID]
+		if inv.PendingRedeem[classID] != 30 || inv.PendingRedeem[otherClass] != 20 {
+			t.Errorf("Expected only %s to settle, got pending %v", classID, inv.PendingRedeem)
+		}
+	})
+
+	t.Run("Invalid Plan Is Rejected", func(t *testing.T) {
+		data, err := os.ReadFile("../../examples/bad.runbook.json")
+		if err != nil {
+			t.Fatalf("Failed to read bad runbook: %v", err)
+		}
+		plan, err := ParsePlanOrRunbook(data)
+		if err != nil {
+			t.Fatalf("Failed to convert runbook: %v", err)
+		}
+		if _, err := NewInterpreter(newMemStore(), "CBU-1").Run(context.Background(), plan); err == nil {
+			t.Error("Expected validation error for bad runbook")
+		}
+	})
+}
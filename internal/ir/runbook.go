@@ -0,0 +1,166 @@
+package ir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ---------- Runbook format ----------
+
+// Runbook is the authoring format used by examples/runbook.sample.json: a list
+// of verbs with free-form params. It is lowered into a Plan before execution.
+type Runbook struct {
+	RunbookID string        `json:"runbook_id,omitempty"`
+	AsOf      time.Time     `json:"as_of,omitempty"`
+	Steps     []RunbookStep `json:"steps"`
+}
+
+type RunbookStep struct {
+	Verb   string         `json:"verb"`
+	Params map[string]any `json:"params"`
+}
+
+// runbookParamAliases maps runbook param names onto IR arg names. The "" entry
+// applies to every op; per-op entries take precedence.
+var runbookParamAliases = map[Op]map[string]string{
+	"": {
+		"investor_type":    "type",
+		"share_class_id":   "class_id",
+		"nav_per_unit":     "nav_per_share",
+		"swift":            "swift_bic",
+		"account_number":   "account_no",
+		"beneficiary_name": "account_name",
+		"form_type":        "form",
+	},
+	OpTaxCapture:         {"effective_date": "form_signed_date"},
+	OpBankSetInstruction: {"effective_date": "active_from"},
+	OpSubscribeRequest:   {"dealing_date": "trade_date"},
+	OpRedeemRequest:      {"dealing_date": "trade_date"},
+	OpDealNAV:            {"dealing_date": "nav_date"},
+	OpSubscribeIssue:     {"value_date": "settlement_date"},
+	OpRedeemSettle:       {"value_date": "settle_date"},
+}
+
+// runbookIdempotencyParam is lifted out of params into Step.IdempotencyKey
+const runbookIdempotencyParam = "event_key"
+
+// taxStatusUndocumented is the FATCA/CRS status of an account without a
+// self-certified classification
+const taxStatusUndocumented = "UNDOCUMENTED"
+
+// offboardReasons are the reason codes offboard.close accepts
+var offboardReasons = map[string]bool{
+	"VOLUNTARY": true, "INVOLUNTARY": true, "REGULATORY": true, "DECEASED": true, "MERGED": true,
+}
+
+func ParseRunbook(data []byte) (*Runbook, error) {
+	var r Runbook
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ToPlan lowers the runbook into an IR Plan, renaming params to IR arg names.
+// The resulting plan still has to pass Plan.Validate.
+func (r *Runbook) ToPlan() (*Plan, error) {
+	if len(r.Steps) == 0 {
+		return nil, errors.New("runbook steps must be non-empty")
+	}
+
+	plan := &Plan{
+		Version:   "1.0.0",
+		PlanID:    r.RunbookID,
+		CreatedAt: r.AsOf,
+		Metadata:  map[string]any{"source": "runbook"},
+	}
+	if plan.PlanID == "" {
+		plan.PlanID = uuid.New().String()
+	}
+	if plan.CreatedAt.IsZero() {
+		plan.CreatedAt = time.Now().UTC()
+	}
+
+	for i, rs := range r.Steps {
+		op := Op(rs.Verb)
+		args := make(map[string]any, len(rs.Params))
+		var key *string
+		for name, value := range rs.Params {
+			if name == runbookIdempotencyParam {
+				if s, ok := value.(string); ok {
+					key = &s
+				}
+				continue
+			}
+			args[runbookArgName(op, name)] = value
+		}
+		lowerRunbookArgs(op, args, plan.CreatedAt)
+
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i, rs.Verb, err)
+		}
+		plan.Steps = append(plan.Steps, Step{Op: op, ArgsRaw: raw, IdempotencyKey: key})
+	}
+
+	return plan, nil
+}
+
+// lowerRunbookArgs fills in what the runbook leaves implicit but the IR
+// requires: tax classifications default to undocumented, a redemption notice is
+// given on the runbook's as-of date, and a free-text closure reason is kept as a
+// note rather than a reason code.
+func lowerRunbookArgs(op Op, args map[string]any, asOf time.Time) {
+	switch op {
+	case OpTaxCapture:
+		for _, name := range []string{"fatca", "crs"} {
+			if _, ok := args[name]; !ok {
+				args[name] = taxStatusUndocumented
+			}
+		}
+	case OpRedeemRequest:
+		if _, ok := args["notice_date"]; !ok {
+			args["notice_date"] = asOf.Format("2006-01-02")
+		}
+	case OpOffboardClose:
+		if reason, ok := args["reason"].(string); ok && !offboardReasons[reason] {
+			delete(args, "reason")
+			args["note"] = reason
+		}
+	}
+}
+
+func runbookArgName(op Op, name string) string {
+	if alias, ok := runbookParamAliases[op][name]; ok {
+		return alias
+	}
+	if alias, ok := runbookParamAliases[""][name]; ok {
+		return alias
+	}
+	return name
+}
+
+// ParsePlanOrRunbook accepts either a Plan document (with "op" steps) or a
+// Runbook document (with "verb" steps) and returns a Plan.
+func ParsePlanOrRunbook(data []byte) (*Plan, error) {
+	var probe struct {
+		Steps []map[string]json.RawMessage `json:"steps"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if len(probe.Steps) > 0 {
+		if _, isRunbook := probe.Steps[0]["verb"]; isRunbook {
+			r, err := ParseRunbook(data)
+			if err != nil {
+				return nil, err
+			}
+			return r.ToPlan()
+		}
+	}
+	return ParsePlan(data)
+}
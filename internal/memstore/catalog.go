@@ -0,0 +1,545 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"dsl-ob-poc/internal/dictionary"
+	"dsl-ob-poc/internal/entities"
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// CBU CRUD Operations
+
+// ListCBUs returns all CBUs ordered by name
+func (s *Store) ListCBUs(ctx context.Context) ([]store.CBU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cbus := append([]store.CBU(nil), s.data.CBUs...)
+	sort.SliceStable(cbus, func(i, j int) bool { return cbus[i].Name < cbus[j].Name })
+	return cbus, nil
+}
+
+func (s *Store) GetCBUByID(ctx context.Context, cbuID string) (*store.CBU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cbu := range s.data.CBUs {
+		if cbu.CBUID == cbuID {
+			return &cbu, nil
+		}
+	}
+	return nil, store.NotFoundf("CBU not found: %s", cbuID)
+}
+
+func (s *Store) GetCBUByName(ctx context.Context, name string) (*store.CBU, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cbu := range s.data.CBUs {
+		if cbu.Name == name {
+			return &cbu, nil
+		}
+	}
+	return nil, store.NotFoundf("CBU not found: %s", name)
+}
+
+// CreateCBU adds a CBU; names are unique, as in the cbus table
+func (s *Store) CreateCBU(ctx context.Context, name, description, naturePurpose string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cbu := range s.data.CBUs {
+		if cbu.Name == name {
+			return "", fmt.Errorf("failed to create CBU: name %q already exists", name)
+		}
+	}
+	cbu := store.CBU{CBUID: uuid.New().String(), Name: name, Description: description, NaturePurpose: naturePurpose}
+	s.data.CBUs = append(s.data.CBUs, cbu)
+	return cbu.CBUID, nil
+}
+
+// UpdateCBU changes the non-empty fields of a CBU
+func (s *Store) UpdateCBU(ctx context.Context, cbuID, name, description, naturePurpose string) error {
+	if name == "" && description == "" && naturePurpose == "" {
+		return fmt.Errorf("no fields to update")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.CBUs {
+		cbu := &s.data.CBUs[i]
+		if cbu.CBUID != cbuID {
+			continue
+		}
+		if name != "" {
+			cbu.Name = name
+		}
+		if description != "" {
+			cbu.Description = description
+		}
+		if naturePurpose != "" {
+			cbu.NaturePurpose = naturePurpose
+		}
+		return nil
+	}
+	return store.NotFoundf("CBU not found: %s", cbuID)
+}
+
+func (s *Store) DeleteCBU(ctx context.Context, cbuID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, cbu := range s.data.CBUs {
+		if cbu.CBUID == cbuID {
+			s.data.CBUs = append(s.data.CBUs[:i:i], s.data.CBUs[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("CBU not found: %s", cbuID)
+}
+
+// Role CRUD Operations
+
+// ListRoles returns all roles ordered by name
+func (s *Store) ListRoles(ctx context.Context) ([]store.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := append([]store.Role(nil), s.data.Roles...)
+	sort.SliceStable(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (s *Store) GetRoleByID(ctx context.Context, roleID string) (*store.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, role := range s.data.Roles {
+		if role.RoleID == roleID {
+			return &role, nil
+		}
+	}
+	return nil, store.NotFoundf("role not found: %s", roleID)
+}
+
+// CreateRole adds a role; names are unique, as in the roles table
+func (s *Store) CreateRole(ctx context.Context, name, description string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, role := range s.data.Roles {
+		if role.Name == name {
+			return "", fmt.Errorf("failed to create role: name %q already exists", name)
+		}
+	}
+	role := store.Role{RoleID: uuid.New().String(), Name: name, Description: description}
+	s.data.Roles = append(s.data.Roles, role)
+	return role.RoleID, nil
+}
+
+// UpdateRole changes the non-empty fields of a role
+func (s *Store) UpdateRole(ctx context.Context, roleID, name, description string) error {
+	if name == "" && description == "" {
+		return fmt.Errorf("no fields to update")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Roles {
+		role := &s.data.Roles[i]
+		if role.RoleID != roleID {
+			continue
+		}
+		if name != "" {
+			role.Name = name
+		}
+		if description != "" {
+			role.Description = description
+		}
+		return nil
+	}
+	return store.NotFoundf("role not found: %s", roleID)
+}
+
+func (s *Store) DeleteRole(ctx context.Context, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, role := range s.data.Roles {
+		if role.RoleID == roleID {
+			s.data.Roles = append(s.data.Roles[:i:i], s.data.Roles[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("role not found: %s", roleID)
+}
+
+// Product, Service and Resource Operations
+
+func (s *Store) GetProductByName(ctx context.Context, name string) (*store.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, product := range s.data.Products {
+		if product.Name == name {
+			return &product, nil
+		}
+	}
+	return nil, store.NotFoundf("product '%s' not found in catalog", name)
+}
+
+func (s *Store) GetServicesForProduct(ctx context.Context, productID string) ([]store.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var services []store.Service
+	for _, relation := range s.data.ProductServices {
+		if relation.ProductID != productID {
+			continue
+		}
+		for _, service := range s.data.Services {
+			if service.ServiceID == relation.ServiceID {
+				services = append(services, service)
+				break
+			}
+		}
+	}
+	return services, nil
+}
+
+func (s *Store) GetServiceByName(ctx context.Context, name string) (*store.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, service := range s.data.Services {
+		if service.Name == name {
+			return &service, nil
+		}
+	}
+	return nil, store.NotFoundf("service '%s' not found in catalog", name)
+}
+
+func (s *Store) GetResourcesForService(ctx context.Context, serviceID string) ([]store.ProdResource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resources []store.ProdResource
+	for _, relation := range s.data.ServiceResources {
+		if relation.ServiceID != serviceID {
+			continue
+		}
+		for _, resource := range s.data.Resources {
+			if resource.ResourceID == relation.ResourceID {
+				resources = append(resources, resource)
+				break
+			}
+		}
+	}
+	return resources, nil
+}
+
+func (s *Store) GetAllProducts(ctx context.Context) ([]store.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]store.Product(nil), s.data.Products...), nil
+}
+
+func (s *Store) GetAllServices(ctx context.Context) ([]store.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]store.Service(nil), s.data.Services...), nil
+}
+
+// Dictionary Operations
+
+func (s *Store) GetDictionaryAttributeByName(ctx context.Context, name string) (*dictionary.Attribute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attr := range s.data.Dictionary {
+		if attr.Name == name {
+			return &attr, nil
+		}
+	}
+	return nil, store.NotFoundf("attribute not found: %s", name)
+}
+
+func (s *Store) GetDictionaryAttributeByID(ctx context.Context, id string) (*dictionary.Attribute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attr := s.data.attribute(id); attr != nil {
+		found := *attr
+		return &found, nil
+	}
+	return nil, store.NotFoundf("attribute not found: %s", id)
+}
+
+func (s *Store) GetAttributesForDictionaryGroup(ctx context.Context, groupID string) ([]dictionary.Attribute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var attributes []dictionary.Attribute
+	for _, attr := range s.data.Dictionary {
+		if attr.GroupID == groupID {
+			attributes = append(attributes, attr)
+		}
+	}
+	return attributes, nil
+}
+
+func (s *Store) UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attr := s.data.attribute(attributeID); attr != nil {
+		attr.Vector = vector
+		return nil
+	}
+	return store.NotFoundf("attribute not found: %s", attributeID)
+}
+
+func (s *Store) GetAllDictionaryAttributes(ctx context.Context) ([]dictionary.Attribute, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]dictionary.Attribute(nil), s.data.Dictionary...), nil
+}
+
+// attribute returns the dictionary entry with the given ID; the caller holds
+// s.mu
+func (t *tables) attribute(id string) *dictionary.Attribute {
+	for i := range t.Dictionary {
+		if t.Dictionary[i].AttributeID == id {
+			return &t.Dictionary[i]
+		}
+	}
+	return nil
+}
+
+// Product Requirements Operations (Phase 5)
+
+func (s *Store) GetProductRequirements(ctx context.Context, productID string) (*store.ProductRequirements, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, req := range s.data.ProductRequirements {
+		if req.ProductID == productID {
+			req.ProductName = s.data.productName(productID)
+			return &req, nil
+		}
+	}
+	return nil, store.NotFoundf("product requirements not found for product %s", productID)
+}
+
+// ListProductRequirements returns all requirements, newest first
+func (s *Store) ListProductRequirements(ctx context.Context) ([]store.ProductRequirements, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requirements []store.ProductRequirements
+	for i := len(s.data.ProductRequirements) - 1; i >= 0; i-- {
+		req := s.data.ProductRequirements[i]
+		req.ProductName = s.data.productName(req.ProductID)
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
+
+// CreateProductRequirements adds requirements for a catalog product that has
+// none yet
+func (s *Store) CreateProductRequirements(ctx context.Context, req *store.ProductRequirements) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.productName(req.ProductID) == "" {
+		return fmt.Errorf("failed to create product requirements: %w", store.NotFoundf("product not found: %s", req.ProductID))
+	}
+	for _, existing := range s.data.ProductRequirements {
+		if existing.ProductID == req.ProductID {
+			return fmt.Errorf("failed to create product requirements: product %s already has requirements", req.ProductID)
+		}
+	}
+
+	created := *req
+	created.CreatedAt = time.Now()
+	created.UpdatedAt = created.CreatedAt
+	s.data.ProductRequirements = append(s.data.ProductRequirements, created)
+	return nil
+}
+
+// UpdateProductRequirements replaces a product's requirements, keeping their
+// creation time
+func (s *Store) UpdateProductRequirements(ctx context.Context, req *store.ProductRequirements) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.ProductRequirements {
+		existing := &s.data.ProductRequirements[i]
+		if existing.ProductID != req.ProductID {
+			continue
+		}
+		createdAt := existing.CreatedAt
+		*existing = *req
+		existing.CreatedAt = createdAt
+		existing.UpdatedAt = time.Now()
+		return nil
+	}
+	return store.NotFoundf("product requirements not found for product %s", req.ProductID)
+}
+
+func (s *Store) GetEntityProductMapping(ctx context.Context, entityType, productID string) (*store.EntityProductMapping, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mapping := range s.data.EntityProductMappings {
+		if mapping.EntityType == entityType && mapping.ProductID == productID {
+			return &mapping, nil
+		}
+	}
+	return nil, store.NotFoundf("entity-product mapping not found for %s-%s", entityType, productID)
+}
+
+// CreateEntityProductMapping adds the mapping for an entity type and product
+// that has none yet
+func (s *Store) CreateEntityProductMapping(ctx context.Context, mapping *store.EntityProductMapping) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.productName(mapping.ProductID) == "" {
+		return fmt.Errorf("failed to create entity-product mapping: %w", store.NotFoundf("product not found: %s", mapping.ProductID))
+	}
+	for _, existing := range s.data.EntityProductMappings {
+		if existing.EntityType == mapping.EntityType && existing.ProductID == mapping.ProductID {
+			return fmt.Errorf("failed to create entity-product mapping: %s-%s already exists", mapping.EntityType, mapping.ProductID)
+		}
+	}
+
+	created := *mapping
+	created.CreatedAt = time.Now()
+	s.data.EntityProductMappings = append(s.data.EntityProductMappings, created)
+	return nil
+}
+
+// productName returns the catalog name of a product, or "" if there is no
+// such product; the caller holds s.mu
+func (t *tables) productName(productID string) string {
+	for _, product := range t.Products {
+		if product.ProductID == productID {
+			return product.Name
+		}
+	}
+	return ""
+}
+
+// Catalog Seeding
+
+// SeedCatalog does nothing: a memory store's catalog comes from the mock
+// data it was seeded with, or from its snapshot
+func (s *Store) SeedCatalog(ctx context.Context) error {
+	return nil
+}
+
+// SeedProductRequirements does nothing; use CreateProductRequirements and
+// CreateEntityProductMapping to add requirements
+func (s *Store) SeedProductRequirements(ctx context.Context) error {
+	return nil
+}
+
+// InitDB does nothing: a memory store has no schema
+func (s *Store) InitDB(ctx context.Context) error {
+	return nil
+}
+
+// Ownership Graph Operations
+
+// LoadOwnershipGraph returns the entity registry as ownership graph data.
+// Entities whose IDs are not UUIDs cannot be linked to interests and are
+// skipped. Only the CBU's registered (not calculated) UBO relationships are
+// included.
+func (s *Store) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := &entities.OwnershipGraphData{
+		PartnershipInterests: append([]entities.PartnershipInterest(nil), s.data.PartnershipInterests...),
+		TrustParties:         append([]entities.TrustParty(nil), s.data.TrustParties...),
+	}
+	for _, r := range s.data.UBORegistry {
+		if r.CBUID.String() == cbuID && r.WorkflowType != entities.UBOWorkflowRecursiveAnalysis {
+			data.UBORegistry = append(data.UBORegistry, r)
+		}
+	}
+
+	typesByID := make(map[string]store.EntityType, len(s.data.EntityTypes))
+	for _, et := range s.data.EntityTypes {
+		typesByID[et.EntityTypeID] = et
+	}
+	for _, e := range s.data.Entities {
+		entityID, err := uuid.Parse(e.EntityID)
+		if err != nil {
+			continue
+		}
+		entityTypeID, _ := uuid.Parse(e.EntityTypeID)
+
+		entity := entities.Entity{
+			EntityID:     entityID,
+			EntityTypeID: entityTypeID,
+			Name:         e.Name,
+		}
+		if e.ExternalID != "" {
+			externalID := e.ExternalID
+			entity.ExternalID = &externalID
+		}
+		if et, ok := typesByID[e.EntityTypeID]; ok {
+			entity.EntityType = &entities.EntityType{
+				EntityTypeID: entityTypeID,
+				Name:         et.Name,
+				Description:  et.Description,
+				TableName:    et.TableName,
+			}
+		}
+		data.Entities = append(data.Entities, entity)
+	}
+
+	return data, nil
+}
+
+// SaveCalculatedUBOs replaces the calculated UBO rows of a CBU's subject
+// entity, keeping registered relationships with the same subject, person and
+// type
+func (s *Store) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type relationship struct {
+		subject, person uuid.UUID
+		kind            string
+	}
+	var kept []entities.UBORegistry
+	registered := make(map[relationship]bool)
+	for _, r := range s.data.UBORegistry {
+		if r.CBUID.String() == cbuID && r.SubjectEntityID == subjectEntityID && r.WorkflowType == entities.UBOWorkflowRecursiveAnalysis {
+			continue
+		}
+		kept = append(kept, r)
+		registered[relationship{r.SubjectEntityID, r.UBOProperPersonID, r.RelationshipType}] = true
+	}
+	for _, e := range entries {
+		if !registered[relationship{e.SubjectEntityID, e.UBOProperPersonID, e.RelationshipType}] {
+			kept = append(kept, e)
+		}
+	}
+	s.data.UBORegistry = kept
+	return nil
+}
@@ -0,0 +1,411 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// DSL Operations
+
+// GetLatestDSL returns the text of the CBU's most recent DSL version
+func (s *Store) GetLatestDSL(ctx context.Context, cbuID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if latest := s.data.latestVersion(cbuID); latest != nil {
+		return latest.DSLText, nil
+	}
+	return "", store.NotFoundf("no DSL found for CBU_ID: %s", cbuID)
+}
+
+// InsertDSL records a DSL version without an onboarding state
+func (s *Store) InsertDSL(ctx context.Context, cbuID, dslText string) (string, error) {
+	return s.InsertPlainDSL(ctx, cbuID, dslText, "")
+}
+
+// InsertPlainDSL records a DSL version without an onboarding state, along
+// with the grammar version that validated it. Like the store, it writes no
+// outbox event.
+func (s *Store) InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.insertVersion(cbuID, dslText, "", grammarVersion).VersionID, nil
+}
+
+// GetDSLHistory returns the CBU's DSL versions, oldest first
+func (s *Store) GetDSLHistory(ctx context.Context, cbuID string) ([]store.DSLVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []store.DSLVersion
+	for _, version := range s.data.DSLVersions {
+		if version.CBUID == cbuID {
+			history = append(history, store.DSLVersion{
+				VersionID: version.VersionID,
+				CreatedAt: version.CreatedAt,
+				DSLText:   version.DSLText,
+			})
+		}
+	}
+	return history, nil
+}
+
+// InsertDSLWithState records a DSL version and a DSL_VERSION_CREATED outbox
+// event
+func (s *Store) InsertDSLWithState(ctx context.Context, cbuID, dslText string, state store.OnboardingState) (string, error) {
+	return s.InsertDSLWithGrammar(ctx, cbuID, dslText, state, "")
+}
+
+// InsertDSLWithGrammar records a DSL version, the grammar version that
+// validated it and a DSL_VERSION_CREATED outbox event, rejecting states the
+// CBU's onboarding session cannot move to
+func (s *Store) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session := s.data.onboardingSession(cbuID); session != nil {
+		if err := session.CurrentState.ValidateTransition(state); err != nil {
+			return "", err
+		}
+	}
+
+	previous := s.data.latestVersion(cbuID)
+	event := store.DSLChangeEvent{
+		EventType:    store.EventDSLVersionCreated,
+		CBUID:        cbuID,
+		NewState:     state,
+		ChangedVerbs: store.ChangedVerbs("", dslText),
+	}
+	if previous != nil {
+		event.OldState = previous.OnboardingState
+		event.ChangedVerbs = store.ChangedVerbs(previous.DSLText, dslText)
+	}
+
+	version := s.data.insertVersion(cbuID, dslText, state, grammarVersion)
+	event.VersionID = version.VersionID
+	event.VersionNumber = version.VersionNumber
+	s.data.recordDSLChange(event)
+	return version.VersionID, nil
+}
+
+func (s *Store) GetLatestDSLWithState(ctx context.Context, cbuID string) (*store.DSLVersionWithState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if latest := s.data.latestVersion(cbuID); latest != nil {
+		found := *latest
+		return &found, nil
+	}
+	return nil, store.NotFoundf("no DSL found for CBU: %s", cbuID)
+}
+
+// GetDSLHistoryWithState returns the CBU's DSL versions in version order
+func (s *Store) GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]store.DSLVersionWithState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []store.DSLVersionWithState
+	for _, version := range s.data.DSLVersions {
+		if version.CBUID == cbuID {
+			history = append(history, version)
+		}
+	}
+	return history, nil
+}
+
+func (s *Store) GetDSLByVersion(ctx context.Context, cbuID string, versionNumber int) (*store.DSLVersionWithState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, version := range s.data.DSLVersions {
+		if version.CBUID == cbuID && version.VersionNumber == versionNumber {
+			return &version, nil
+		}
+	}
+	return nil, store.NotFoundf("no DSL version %d found for CBU: %s", versionNumber, cbuID)
+}
+
+// GetAllDSLRecords returns every DSL version ordered by CBU and version
+func (s *Store) GetAllDSLRecords(ctx context.Context) ([]store.DSLVersionWithState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := append([]store.DSLVersionWithState(nil), s.data.DSLVersions...)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].CBUID != records[j].CBUID {
+			return records[i].CBUID < records[j].CBUID
+		}
+		return records[i].VersionNumber < records[j].VersionNumber
+	})
+	for i := range records {
+		if records[i].OnboardingState == "" {
+			records[i].OnboardingState = store.StateCreated
+		}
+	}
+	return records, nil
+}
+
+// insertVersion appends the CBU's next DSL version; the caller holds s.mu
+func (t *tables) insertVersion(cbuID, dslText string, state store.OnboardingState, grammarVersion string) store.DSLVersionWithState {
+	version := store.DSLVersionWithState{
+		VersionID:       uuid.New().String(),
+		CBUID:           cbuID,
+		DSLText:         dslText,
+		OnboardingState: state,
+		VersionNumber:   t.nextVersionNumber(cbuID),
+		GrammarVersion:  grammarVersion,
+		CreatedAt:       time.Now(),
+	}
+	t.DSLVersions = append(t.DSLVersions, version)
+	return version
+}
+
+// latestVersion returns the CBU's highest-numbered DSL version, or nil; the
+// caller holds s.mu
+func (t *tables) latestVersion(cbuID string) *store.DSLVersionWithState {
+	var latest *store.DSLVersionWithState
+	for i := range t.DSLVersions {
+		version := &t.DSLVersions[i]
+		if version.CBUID == cbuID && (latest == nil || version.VersionNumber > latest.VersionNumber) {
+			latest = version
+		}
+	}
+	return latest
+}
+
+func (t *tables) nextVersionNumber(cbuID string) int {
+	if latest := t.latestVersion(cbuID); latest != nil {
+		return latest.VersionNumber + 1
+	}
+	return 1
+}
+
+// Onboarding State Management
+
+// CreateOnboardingSession starts the CBU's onboarding session in the
+// CREATED state; a CBU has at most one
+func (s *Store) CreateOnboardingSession(ctx context.Context, cbuID string) (*store.OnboardingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data.onboardingSession(cbuID) != nil {
+		return nil, fmt.Errorf("failed to create onboarding session: CBU %s already has one", cbuID)
+	}
+
+	now := time.Now()
+	session := store.OnboardingSession{
+		OnboardingID:   uuid.New().String(),
+		CBUID:          cbuID,
+		CurrentState:   store.StateCreated,
+		CurrentVersion: 1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	s.data.OnboardingSessions = append(s.data.OnboardingSessions, session)
+	return &session, nil
+}
+
+func (s *Store) GetOnboardingSession(ctx context.Context, cbuID string) (*store.OnboardingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session := s.data.onboardingSession(cbuID); session != nil {
+		found := *session
+		return &found, nil
+	}
+	return nil, store.NotFoundf("no onboarding session found for CBU: %s", cbuID)
+}
+
+// UpdateOnboardingState moves the CBU's session to newState at the given DSL
+// version and records an ONBOARDING_STATE_CHANGED outbox event; moves the
+// onboarding state machine does not allow fail with store.ErrInvalidTransition
+func (s *Store) UpdateOnboardingState(ctx context.Context, cbuID string, newState store.OnboardingState, dslVersionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := s.data.onboardingSession(cbuID)
+	if session == nil {
+		return store.NotFoundf("no onboarding session found for CBU: %s", cbuID)
+	}
+
+	var version *store.DSLVersionWithState
+	previousText := ""
+	for i := range s.data.DSLVersions {
+		if candidate := &s.data.DSLVersions[i]; candidate.VersionID == dslVersionID {
+			version = candidate
+			break
+		}
+	}
+	if version == nil {
+		return store.NotFoundf("DSL version not found: %s", dslVersionID)
+	}
+	for _, candidate := range s.data.DSLVersions {
+		if candidate.CBUID == version.CBUID && candidate.VersionNumber == version.VersionNumber-1 {
+			previousText = candidate.DSLText
+			break
+		}
+	}
+
+	oldState := session.CurrentState
+	if err := oldState.ValidateTransition(newState); err != nil {
+		return err
+	}
+	session.CurrentState = newState
+	session.CurrentVersion++
+	session.LatestDSLVersionID = &dslVersionID
+	session.UpdatedAt = time.Now()
+
+	s.data.recordDSLChange(store.DSLChangeEvent{
+		EventType:     store.EventOnboardingStateChanged,
+		CBUID:         cbuID,
+		VersionID:     dslVersionID,
+		VersionNumber: version.VersionNumber,
+		OldState:      oldState,
+		NewState:      newState,
+		ChangedVerbs:  store.ChangedVerbs(previousText, version.DSLText),
+	})
+	return nil
+}
+
+// ListOnboardingSessions returns all onboarding sessions, most recently
+// updated first
+func (s *Store) ListOnboardingSessions(ctx context.Context) ([]store.OnboardingSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := append([]store.OnboardingSession(nil), s.data.OnboardingSessions...)
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt) })
+	return sessions, nil
+}
+
+// onboardingSession returns the CBU's session, or nil; the caller holds s.mu
+func (t *tables) onboardingSession(cbuID string) *store.OnboardingSession {
+	for i := range t.OnboardingSessions {
+		if t.OnboardingSessions[i].CBUID == cbuID {
+			return &t.OnboardingSessions[i]
+		}
+	}
+	return nil
+}
+
+// Attribute Value Operations
+
+// ResolveValueFor returns the value stored for the attribute at the CBU's
+// highest DSL version. An attribute with no stored value is pending, as in
+// the store.
+func (s *Store) ResolveValueFor(ctx context.Context, cbuID, attributeID string) (json.RawMessage, map[string]any, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *attributeValue
+	for i := range s.data.AttributeValues {
+		av := &s.data.AttributeValues[i]
+		if av.CBUID == cbuID && av.AttributeID == attributeID && (latest == nil || av.DSLVersion > latest.DSLVersion) {
+			latest = av
+		}
+	}
+	if latest != nil {
+		source := make(map[string]any, len(latest.Source))
+		for k, v := range latest.Source {
+			source[k] = v
+		}
+		return append(json.RawMessage(nil), latest.Value...), source, latest.State, nil
+	}
+
+	if s.data.attribute(attributeID) == nil {
+		return nil, nil, "", store.NotFoundf("attribute not found: %s", attributeID)
+	}
+	return json.RawMessage(`null`), map[string]any{"reason": "no_resolver"}, "pending", nil
+}
+
+// UpsertAttributeValue stores the value of an attribute at a DSL version,
+// replacing any value already stored for that version. Unlike the
+// attribute_values table, it does not check the attribute is in the
+// dictionary.
+func (s *Store) UpsertAttributeValue(ctx context.Context, cbuID string, dslVersion int, attributeID string, value json.RawMessage, state string, source map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Round-trip the source so later changes by the caller are not seen
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+	var stored map[string]any
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+
+	av := attributeValue{
+		CBUID:       cbuID,
+		DSLVersion:  dslVersion,
+		AttributeID: attributeID,
+		Value:       append(json.RawMessage(nil), value...),
+		State:       state,
+		Source:      stored,
+		ObservedAt:  time.Now(),
+	}
+	for i, existing := range s.data.AttributeValues {
+		if existing.CBUID == cbuID && existing.DSLVersion == dslVersion && existing.AttributeID == attributeID {
+			s.data.AttributeValues[i] = av
+			return nil
+		}
+	}
+	s.data.AttributeValues = append(s.data.AttributeValues, av)
+	return nil
+}
+
+// IR Investor State Operations
+
+// GetInvestorState returns the state document kept for an investor of a CBU
+func (s *Store) GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, is := range s.data.InvestorStates {
+		if is.CBUID == cbuID && is.InvestorID == investorID {
+			return append(json.RawMessage(nil), is.State...), nil
+		}
+	}
+	return nil, store.NotFoundf("investor %s has no state for CBU %s", investorID, cbuID)
+}
+
+// SaveInvestorState stores an investor's state document, replacing the
+// previous one
+func (s *Store) SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+	var stored map[string]any
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+
+	is := investorState{
+		CBUID:      cbuID,
+		InvestorID: investorID,
+		State:      append(json.RawMessage(nil), state...),
+		Source:     stored,
+		UpdatedAt:  time.Now(),
+	}
+	for i, existing := range s.data.InvestorStates {
+		if existing.CBUID == cbuID && existing.InvestorID == investorID {
+			s.data.InvestorStates[i] = is
+			return nil
+		}
+	}
+	s.data.InvestorStates = append(s.data.InvestorStates, is)
+	return nil
+}
@@ -0,0 +1,356 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Runtime job queue (same lease semantics as the action_jobs table)
+
+func (s *Store) EnqueueJob(ctx context.Context, job *store.ActionJob) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	queued := store.ActionJob{
+		JobID:       uuid.New().String(),
+		ActionID:    job.ActionID,
+		CBUID:       job.CBUID,
+		Payload:     append([]byte(nil), job.Payload...),
+		Status:      store.JobQueued,
+		MaxAttempts: max(job.MaxAttempts, 1),
+		RunAt:       job.RunAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if queued.RunAt.IsZero() {
+		queued.RunAt = now
+	}
+	s.data.Jobs = append(s.data.Jobs, queued)
+	return queued.JobID, nil
+}
+
+// LeaseJob claims the job that has been due longest, including running jobs
+// whose lease has expired
+func (s *Store) LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due *store.ActionJob
+	for i := range s.data.Jobs {
+		job := &s.data.Jobs[i]
+		ready := (job.Status == store.JobQueued && !job.RunAt.After(now)) ||
+			(job.Status == store.JobRunning && job.LeaseExpiresAt != nil && job.LeaseExpiresAt.Before(now))
+		if ready && (due == nil || job.RunAt.Before(due.RunAt)) {
+			due = job
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	expires := now.Add(lease)
+	due.Status = store.JobRunning
+	due.LeaseOwner = &owner
+	due.LeaseExpiresAt = &expires
+	due.Attempts++
+	due.UpdatedAt = now
+
+	leased := *due
+	return &leased, nil
+}
+
+func (s *Store) HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error {
+	return s.updateLeasedJob(jobID, owner, "heartbeat", func(job *store.ActionJob) {
+		expires := time.Now().Add(lease)
+		job.LeaseExpiresAt = &expires
+	})
+}
+
+func (s *Store) CompleteJob(ctx context.Context, jobID, owner, executionID string) error {
+	return s.updateLeasedJob(jobID, owner, "complete", func(job *store.ActionJob) {
+		job.Status = store.JobSucceeded
+		job.LastError = nil
+		job.ExecutionID = nil
+		if executionID != "" {
+			job.ExecutionID = &executionID
+		}
+		job.LeaseOwner, job.LeaseExpiresAt = nil, nil
+	})
+}
+
+func (s *Store) RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error {
+	return s.updateLeasedJob(jobID, owner, "retry", func(job *store.ActionJob) {
+		job.Status = store.JobQueued
+		job.RunAt = runAt
+		job.LastError = &lastError
+		job.LeaseOwner, job.LeaseExpiresAt = nil, nil
+	})
+}
+
+func (s *Store) DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error {
+	return s.updateLeasedJob(jobID, owner, "dead-letter", func(job *store.ActionJob) {
+		job.Status = store.JobDead
+		job.LastError = &lastError
+		job.LeaseOwner, job.LeaseExpiresAt = nil, nil
+	})
+}
+
+func (s *Store) RequeueJob(ctx context.Context, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Jobs {
+		if job := &s.data.Jobs[i]; job.JobID == jobID && job.Status == store.JobDead {
+			job.Status = store.JobQueued
+			job.Attempts = 0
+			job.RunAt = time.Now()
+			job.UpdatedAt = job.RunAt
+			return nil
+		}
+	}
+	return store.NotFoundf("dead job not found: %s", jobID)
+}
+
+func (s *Store) ListJobs(ctx context.Context, status store.JobStatus) ([]store.ActionJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []store.ActionJob
+	for _, job := range s.data.Jobs {
+		if status == "" || job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// updateLeasedJob applies update to owner's running job, returning
+// store.ErrLeaseLost when owner no longer holds it
+func (s *Store) updateLeasedJob(jobID, owner, op string, update func(job *store.ActionJob)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Jobs {
+		job := &s.data.Jobs[i]
+		if job.JobID != jobID {
+			continue
+		}
+		if job.Status != store.JobRunning || job.LeaseOwner == nil || *job.LeaseOwner != owner {
+			break
+		}
+		update(job)
+		job.UpdatedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("failed to %s job %s: %w", op, jobID, store.ErrLeaseLost)
+}
+
+// DSL change outbox and webhook deliveries (same semantics as the
+// dsl_change_events and webhook_deliveries tables)
+
+// recordDSLChange appends event to the outbox and queues a delivery for
+// every matching active subscription; the caller holds s.mu
+func (t *tables) recordDSLChange(event store.DSLChangeEvent) {
+	now := time.Now()
+	event.EventID = uuid.New().String()
+	event.CreatedAt = now
+	t.Events = append(t.Events, event)
+
+	for _, sub := range t.Subscriptions {
+		if !sub.Active || !sub.Matches(&event) {
+			continue
+		}
+		t.Deliveries = append(t.Deliveries, store.WebhookDelivery{
+			DeliveryID:     uuid.New().String(),
+			EventID:        event.EventID,
+			SubscriptionID: sub.SubscriptionID,
+			Status:         store.DeliveryPending,
+			MaxAttempts:    sub.MaxAttempts,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+}
+
+// ListDSLChangeEvents returns up to limit events, newest first, for one CBU
+// or all of them
+func (s *Store) ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]store.DSLChangeEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []store.DSLChangeEvent
+	for i := len(s.data.Events) - 1; i >= 0 && len(events) < limit; i-- {
+		if event := s.data.Events[i]; cbuID == "" || event.CBUID == cbuID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *Store) CreateWebhookSubscription(ctx context.Context, sub *store.WebhookSubscription) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := subscription{WebhookSubscription: *sub, Secret: sub.Secret}
+	created.SubscriptionID = uuid.New().String()
+	created.EventTypes = append([]store.DSLEventType(nil), sub.EventTypes...)
+	created.MaxAttempts = max(sub.MaxAttempts, 1)
+	created.Active = true
+	created.CreatedAt = time.Now()
+	s.data.Subscriptions = append(s.data.Subscriptions, created)
+	return created.SubscriptionID, nil
+}
+
+func (s *Store) ListWebhookSubscriptions(ctx context.Context) ([]store.WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]store.WebhookSubscription, 0, len(s.data.Subscriptions))
+	for _, sub := range s.data.Subscriptions {
+		listed := sub.WebhookSubscription
+		listed.Secret = sub.Secret
+		subs = append(subs, listed)
+	}
+	return subs, nil
+}
+
+// DeleteWebhookSubscription removes a subscription and its deliveries
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.data.Subscriptions {
+		if sub.SubscriptionID != subscriptionID {
+			continue
+		}
+		s.data.Subscriptions = append(s.data.Subscriptions[:i:i], s.data.Subscriptions[i+1:]...)
+		var kept []store.WebhookDelivery
+		for _, delivery := range s.data.Deliveries {
+			if delivery.SubscriptionID != subscriptionID {
+				kept = append(kept, delivery)
+			}
+		}
+		s.data.Deliveries = kept
+		return nil
+	}
+	return store.NotFoundf("webhook subscription not found: %s", subscriptionID)
+}
+
+// LeaseWebhookDelivery claims the delivery that has been due longest,
+// including deliveries whose lease has expired mid-attempt
+func (s *Store) LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var due *store.WebhookDelivery
+	for i := range s.data.Deliveries {
+		delivery := &s.data.Deliveries[i]
+		ready := (delivery.Status == store.DeliveryPending && !delivery.NextAttemptAt.After(now)) ||
+			(delivery.Status == store.DeliveryDelivering && delivery.LeaseExpiresAt != nil && delivery.LeaseExpiresAt.Before(now))
+		if ready && (due == nil || delivery.NextAttemptAt.Before(due.NextAttemptAt)) {
+			due = delivery
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	expires := now.Add(lease)
+	due.Status = store.DeliveryDelivering
+	due.LeaseOwner = &owner
+	due.LeaseExpiresAt = &expires
+	due.Attempts++
+	due.UpdatedAt = now
+
+	leased := s.data.withEventAndSubscription(*due)
+	return &leased, nil
+}
+
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error {
+	return s.updateLeasedDelivery(deliveryID, owner, "complete", func(delivery *store.WebhookDelivery) {
+		now := time.Now()
+		delivery.Status = store.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+	})
+}
+
+func (s *Store) RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error {
+	return s.updateLeasedDelivery(deliveryID, owner, "retry", func(delivery *store.WebhookDelivery) {
+		delivery.Status = store.DeliveryPending
+		delivery.NextAttemptAt = nextAttemptAt
+		delivery.LastError = &lastError
+	})
+}
+
+func (s *Store) DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error {
+	return s.updateLeasedDelivery(deliveryID, owner, "dead-letter", func(delivery *store.WebhookDelivery) {
+		delivery.Status = store.DeliveryDead
+		delivery.LastError = &lastError
+	})
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, status store.DeliveryStatus) ([]store.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []store.WebhookDelivery
+	for _, delivery := range s.data.Deliveries {
+		if status == "" || delivery.Status == status {
+			deliveries = append(deliveries, s.data.withEventAndSubscription(delivery))
+		}
+	}
+	return deliveries, nil
+}
+
+// updateLeasedDelivery applies update to owner's delivery in progress,
+// returning store.ErrLeaseLost when owner no longer holds it
+func (s *Store) updateLeasedDelivery(deliveryID, owner, op string, update func(delivery *store.WebhookDelivery)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.data.Deliveries {
+		delivery := &s.data.Deliveries[i]
+		if delivery.DeliveryID != deliveryID {
+			continue
+		}
+		if delivery.Status != store.DeliveryDelivering || delivery.LeaseOwner == nil || *delivery.LeaseOwner != owner {
+			break
+		}
+		update(delivery)
+		delivery.LeaseOwner = nil
+		delivery.LeaseExpiresAt = nil
+		delivery.UpdatedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("failed to %s webhook delivery %s: %w", op, deliveryID, store.ErrLeaseLost)
+}
+
+// withEventAndSubscription fills in the event and target of a delivery, as
+// the joins in the store do; the caller holds s.mu
+func (t *tables) withEventAndSubscription(delivery store.WebhookDelivery) store.WebhookDelivery {
+	for i := range t.Events {
+		if t.Events[i].EventID == delivery.EventID {
+			event := t.Events[i]
+			delivery.Event = &event
+			break
+		}
+	}
+	for _, sub := range t.Subscriptions {
+		if sub.SubscriptionID == delivery.SubscriptionID {
+			delivery.URL = sub.URL
+			delivery.Secret = sub.Secret
+			break
+		}
+	}
+	return delivery
+}
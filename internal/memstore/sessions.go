@@ -0,0 +1,146 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+// Orchestration session persistence
+
+// SaveOrchestrationSession inserts or replaces a session and extends its
+// expiry to 24 hours from now. A replaced session keeps its creation time.
+func (s *Store) SaveOrchestrationSession(ctx context.Context, session *store.OrchestrationSessionData) error {
+	saved, err := copySession(session)
+	if err != nil {
+		return fmt.Errorf("failed to save orchestration session: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := orchestrationSession{Data: *saved, ExpiresAt: time.Now().Add(orchestrationSessionTTL)}
+	if existing := s.data.orchestrationSession(session.SessionID); existing != nil {
+		row.Data.CreatedAt = existing.Data.CreatedAt
+		*existing = row
+		return nil
+	}
+	s.data.OrchestrationSessions = append(s.data.OrchestrationSessions, row)
+	return nil
+}
+
+// LoadOrchestrationSession returns an unexpired session and marks it used
+func (s *Store) LoadOrchestrationSession(ctx context.Context, sessionID string) (*store.OrchestrationSessionData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := s.data.orchestrationSession(sessionID)
+	if row == nil || !row.ExpiresAt.After(time.Now()) {
+		return nil, store.NotFoundf("orchestration session not found: %s", sessionID)
+	}
+	loaded, err := copySession(&row.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orchestration session: %w", err)
+	}
+	row.Data.LastUsed = time.Now()
+	return loaded, nil
+}
+
+// ListActiveOrchestrationSessions returns the IDs of unexpired sessions,
+// most recently used first
+func (s *Store) ListActiveOrchestrationSessions(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var active []orchestrationSession
+	for _, row := range s.data.OrchestrationSessions {
+		if row.ExpiresAt.After(now) {
+			active = append(active, row)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Data.LastUsed.After(active[j].Data.LastUsed) })
+
+	ids := make([]string, 0, len(active))
+	for _, row := range active {
+		ids = append(ids, row.Data.SessionID)
+	}
+	return ids, nil
+}
+
+func (s *Store) DeleteOrchestrationSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, row := range s.data.OrchestrationSessions {
+		if row.Data.SessionID == sessionID {
+			s.data.OrchestrationSessions = append(s.data.OrchestrationSessions[:i:i], s.data.OrchestrationSessions[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("session not found: %s", sessionID)
+}
+
+// CleanupExpiredOrchestrationSessions removes expired sessions and returns
+// how many were removed
+func (s *Store) CleanupExpiredOrchestrationSessions(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var kept []orchestrationSession
+	for _, row := range s.data.OrchestrationSessions {
+		if row.ExpiresAt.After(now) {
+			kept = append(kept, row)
+		}
+	}
+	removed := int64(len(s.data.OrchestrationSessions) - len(kept))
+	s.data.OrchestrationSessions = kept
+	return removed, nil
+}
+
+// UpdateOrchestrationSessionDSL sets the unified DSL and version of a session
+func (s *Store) UpdateOrchestrationSessionDSL(ctx context.Context, sessionID, dsl string, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := s.data.orchestrationSession(sessionID)
+	if row == nil {
+		return store.NotFoundf("session not found: %s", sessionID)
+	}
+	now := time.Now()
+	row.Data.UnifiedDSL = dsl
+	row.Data.VersionNumber = version
+	row.Data.UpdatedAt = now
+	row.Data.LastUsed = now
+	return nil
+}
+
+// orchestrationSession returns the stored session, expired or not, or nil;
+// the caller holds s.mu
+func (t *tables) orchestrationSession(sessionID string) *orchestrationSession {
+	for i := range t.OrchestrationSessions {
+		if t.OrchestrationSessions[i].Data.SessionID == sessionID {
+			return &t.OrchestrationSessions[i]
+		}
+	}
+	return nil
+}
+
+// copySession deep-copies a session through its JSON form, as storing it in
+// PostgreSQL would, so callers never share maps with the store
+func copySession(session *store.OrchestrationSessionData) (*store.OrchestrationSessionData, error) {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	var copied store.OrchestrationSessionData
+	if err := json.Unmarshal(raw, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
@@ -0,0 +1,305 @@
+// Package memstore is a complete in-memory implementation of
+// datastore.DataStore. Unlike mocks.MockStore, every write is kept and read
+// back (CBUs, roles, DSL versions, onboarding and orchestration sessions,
+// attribute values, product requirements, jobs and webhook deliveries), so
+// demos and tests can run multi-step onboarding without PostgreSQL.
+//
+// A store starts empty or seeded from the mock JSON files, and its whole
+// state can be saved to and restored from a snapshot file, which lets
+// separate CLI invocations carry on where the previous one stopped.
+package memstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dsl-ob-poc/internal/dictionary"
+	"dsl-ob-poc/internal/entities"
+	"dsl-ob-poc/internal/mocks"
+	"dsl-ob-poc/internal/store"
+)
+
+// snapshotVersion is bumped whenever the snapshot layout changes
+// incompatibly
+const snapshotVersion = 1
+
+// Store keeps every table in memory. It is safe for concurrent use.
+type Store struct {
+	mu   sync.Mutex
+	data tables
+
+	// snapshotPath, when set, is written by Close
+	snapshotPath string
+}
+
+// tables is the full state of a store, and the layout of a snapshot file
+type tables struct {
+	Version int `json:"version"`
+
+	// Catalog
+	CBUs             []store.CBU                     `json:"cbus"`
+	Roles            []store.Role                    `json:"roles"`
+	Products         []store.Product                 `json:"products"`
+	Services         []store.Service                 `json:"services"`
+	Resources        []store.ProdResource            `json:"resources"`
+	ProductServices  []mocks.ProductServiceRelation  `json:"product_services"`
+	ServiceResources []mocks.ServiceResourceRelation `json:"service_resources"`
+	Dictionary       []dictionary.Attribute          `json:"dictionary"`
+
+	// Entities and ownership
+	EntityTypes          []store.EntityType             `json:"entity_types"`
+	Entities             []store.Entity                 `json:"entities"`
+	CBUEntityRoles       []store.CBUEntityRole          `json:"cbu_entity_roles"`
+	PartnershipInterests []entities.PartnershipInterest `json:"partnership_interests"`
+	TrustParties         []entities.TrustParty          `json:"trust_parties"`
+	UBORegistry          []entities.UBORegistry         `json:"ubo_registry"`
+
+	// Onboarding
+	DSLVersions           []store.DSLVersionWithState  `json:"dsl_versions"`
+	OnboardingSessions    []store.OnboardingSession    `json:"onboarding_sessions"`
+	AttributeValues       []attributeValue             `json:"attribute_values"`
+	InvestorStates        []investorState              `json:"investor_states"`
+	ProductRequirements   []store.ProductRequirements  `json:"product_requirements"`
+	EntityProductMappings []store.EntityProductMapping `json:"entity_product_mappings"`
+	OrchestrationSessions []orchestrationSession       `json:"orchestration_sessions"`
+
+	// Runtime job queue, DSL change outbox and webhook deliveries
+	Jobs          []store.ActionJob       `json:"jobs"`
+	Events        []store.DSLChangeEvent  `json:"events"`
+	Subscriptions []subscription          `json:"subscriptions"`
+	Deliveries    []store.WebhookDelivery `json:"deliveries"`
+}
+
+// attributeValue is a row of attribute_values
+type attributeValue struct {
+	CBUID       string          `json:"cbu_id"`
+	DSLVersion  int             `json:"dsl_version"`
+	AttributeID string          `json:"attribute_id"`
+	Value       json.RawMessage `json:"value"`
+	State       string          `json:"state"`
+	Source      map[string]any  `json:"source"`
+	ObservedAt  time.Time       `json:"observed_at"`
+}
+
+// investorState is a row of ir_investor_states
+type investorState struct {
+	CBUID      string          `json:"cbu_id"`
+	InvestorID string          `json:"investor_id"`
+	State      json.RawMessage `json:"state"`
+	Source     map[string]any  `json:"source"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// orchestrationSession is a row of orchestration_sessions; sessions expire
+// 24 hours after they are last saved, as in PostgreSQL
+type orchestrationSession struct {
+	Data      store.OrchestrationSessionData `json:"data"`
+	ExpiresAt time.Time                      `json:"expires_at"`
+}
+
+// subscription keeps the signing secret, which store.WebhookSubscription
+// leaves out of its JSON form
+type subscription struct {
+	store.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// orchestrationSessionTTL matches SaveOrchestrationSession in the store
+const orchestrationSessionTTL = 24 * time.Hour
+
+// New creates an empty store
+func New() *Store {
+	return &Store{data: tables{Version: snapshotVersion}}
+}
+
+// NewFromMockData creates a store seeded from the mock JSON files in dir, the
+// same files mocks.MockStore reads
+func NewFromMockData(dir string) (*Store, error) {
+	s := New()
+	if err := s.data.seed(mocks.NewJSONDataLoader(dir)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadSnapshot creates a store from a snapshot written by SaveSnapshot
+func LoadSnapshot(path string) (*Store, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	s := New()
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	if s.data.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, want %d", path, s.data.Version, snapshotVersion)
+	}
+	return s, nil
+}
+
+// SaveSnapshotOnClose makes Close write the store's state to path
+func (s *Store) SaveSnapshotOnClose(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshotPath = path
+}
+
+// SaveSnapshot writes the store's whole state to path. The file is replaced
+// atomically, so a crash never leaves a partial snapshot behind.
+func (s *Store) SaveSnapshot(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveSnapshot(path)
+}
+
+func (s *Store) saveSnapshot(path string) error {
+	raw, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Close writes the snapshot requested with SaveSnapshotOnClose, if any
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshotPath == "" {
+		return nil
+	}
+	return s.saveSnapshot(s.snapshotPath)
+}
+
+// seed fills t from the mock JSON files. The catalog files are required, as
+// for mocks.MockStore; the ownership files are optional.
+func (t *tables) seed(loader *mocks.JSONDataLoader) error {
+	var err error
+
+	if t.CBUs, err = loader.LoadCBUs(); err != nil {
+		return fmt.Errorf("failed to load CBUs: %w", err)
+	}
+	if t.Roles, err = loader.LoadRoles(); err != nil {
+		return fmt.Errorf("failed to load roles: %w", err)
+	}
+	if t.EntityTypes, err = loader.LoadEntityTypes(); err != nil {
+		return fmt.Errorf("failed to load entity types: %w", err)
+	}
+	if t.Entities, err = loader.LoadEntities(); err != nil {
+		return fmt.Errorf("failed to load entities: %w", err)
+	}
+	if t.CBUEntityRoles, err = loader.LoadCBUEntityRoles(); err != nil {
+		return fmt.Errorf("failed to load CBU entity roles: %w", err)
+	}
+	if t.Products, err = loader.LoadProducts(); err != nil {
+		return fmt.Errorf("failed to load products: %w", err)
+	}
+	if t.Services, err = loader.LoadServices(); err != nil {
+		return fmt.Errorf("failed to load services: %w", err)
+	}
+	if t.Resources, err = loader.LoadProdResources(); err != nil {
+		return fmt.Errorf("failed to load prod resources: %w", err)
+	}
+	if t.ProductServices, err = loader.LoadProductServices(); err != nil {
+		return fmt.Errorf("failed to load product services: %w", err)
+	}
+	if t.ServiceResources, err = loader.LoadServiceResources(); err != nil {
+		return fmt.Errorf("failed to load service resources: %w", err)
+	}
+	if t.PartnershipInterests, err = loader.LoadPartnershipInterests(); err != nil {
+		return fmt.Errorf("failed to load partnership interests: %w", err)
+	}
+	if t.TrustParties, err = loader.LoadTrustParties(); err != nil {
+		return fmt.Errorf("failed to load trust parties: %w", err)
+	}
+	if t.UBORegistry, err = loader.LoadUBORegistry(); err != nil {
+		return fmt.Errorf("failed to load UBO registry: %w", err)
+	}
+
+	attributes, err := loader.LoadDictionary()
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary: %w", err)
+	}
+	for _, attr := range attributes {
+		t.Dictionary = append(t.Dictionary, dictionary.Attribute{
+			AttributeID:     attr.AttributeID,
+			Name:            attr.Name,
+			LongDescription: attr.LongDescription,
+			GroupID:         attr.GroupID,
+			Mask:            attr.Mask,
+			Domain:          attr.Domain,
+			Vector:          attr.Vector,
+			Source:          attr.Source.SourceMetadata,
+			Sink:            attr.Sink.SinkMetadata,
+		})
+	}
+
+	values, err := loader.LoadAttributeValues()
+	if err != nil {
+		return fmt.Errorf("failed to load attribute values: %w", err)
+	}
+	for _, av := range values {
+		var source map[string]any
+		_ = json.Unmarshal([]byte(av.Source), &source)
+		observedAt, _ := time.Parse(time.RFC3339, av.ObservedAt)
+		t.AttributeValues = append(t.AttributeValues, attributeValue{
+			CBUID:       av.CBUID,
+			DSLVersion:  av.DSLVersion,
+			AttributeID: av.AttributeID,
+			Value:       json.RawMessage(av.Value),
+			State:       av.State,
+			Source:      source,
+			ObservedAt:  observedAt,
+		})
+	}
+
+	// Seeded DSL records become numbered versions in creation order
+	records, err := loader.LoadDSLRecords()
+	if err != nil {
+		return fmt.Errorf("failed to load DSL records: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].CreatedAt < records[j].CreatedAt })
+	for _, record := range records {
+		createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+		if err != nil {
+			createdAt = time.Now()
+		}
+		t.DSLVersions = append(t.DSLVersions, store.DSLVersionWithState{
+			VersionID:       record.VersionID,
+			CBUID:           record.CBUID,
+			DSLText:         record.DSLText,
+			OnboardingState: store.StateCreated,
+			VersionNumber:   t.nextVersionNumber(record.CBUID),
+			CreatedAt:       createdAt,
+		})
+	}
+
+	return nil
+}
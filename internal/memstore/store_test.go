@@ -0,0 +1,198 @@
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+func TestStore_MultiStepOnboarding(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	cbuID, err := s.CreateCBU(ctx, "CBU-1234", "Aviva Investors Global Fund", "UCITS equity fund")
+	if err != nil {
+		t.Fatalf("CreateCBU failed: %v", err)
+	}
+	if _, err := s.CreateCBU(ctx, "CBU-1234", "duplicate", ""); err == nil {
+		t.Error("expected duplicate CBU name to be rejected")
+	}
+	if err := s.UpdateCBU(ctx, cbuID, "", "Aviva Global Fund", ""); err != nil {
+		t.Fatalf("UpdateCBU failed: %v", err)
+	}
+	cbu, err := s.GetCBUByID(ctx, cbuID)
+	if err != nil || cbu.Description != "Aviva Global Fund" || cbu.NaturePurpose != "UCITS equity fund" {
+		t.Fatalf("GetCBUByID = %+v, %v", cbu, err)
+	}
+
+	if _, err := s.CreateOnboardingSession(ctx, cbuID); err != nil {
+		t.Fatalf("CreateOnboardingSession failed: %v", err)
+	}
+	v1, err := s.InsertDSLWithState(ctx, cbuID, `(case.create (cbu.id "CBU-1234"))`, store.StateCreated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := s.InsertDSLWithState(ctx, cbuID, `(case.create (cbu.id "CBU-1234")) (products.add "CUSTODY")`, store.StateProductsAdded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateOnboardingState(ctx, cbuID, store.StateProductsAdded, v2); err != nil {
+		t.Fatalf("UpdateOnboardingState failed: %v", err)
+	}
+	if err := s.UpdateOnboardingState(ctx, cbuID, store.StateCompleted, "missing-version"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown DSL version, got %v", err)
+	}
+
+	session, err := s.GetOnboardingSession(ctx, cbuID)
+	if err != nil || session.CurrentState != store.StateProductsAdded || session.CurrentVersion != 2 || *session.LatestDSLVersionID != v2 {
+		t.Fatalf("GetOnboardingSession = %+v, %v", session, err)
+	}
+
+	history, _ := s.GetDSLHistoryWithState(ctx, cbuID)
+	if len(history) != 2 || history[0].VersionID != v1 || history[1].VersionNumber != 2 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	latest, _ := s.GetLatestDSL(ctx, cbuID)
+	if latest != history[1].DSLText {
+		t.Errorf("GetLatestDSL = %q", latest)
+	}
+
+	if err := s.UpsertAttributeValue(ctx, cbuID, 2, "attr-1", json.RawMessage(`"LU"`), "resolved", map[string]any{"type": "agent"}); err != nil {
+		t.Fatal(err)
+	}
+	value, source, state, err := s.ResolveValueFor(ctx, cbuID, "attr-1")
+	if err != nil || string(value) != `"LU"` || state != "resolved" || source["type"] != "agent" {
+		t.Errorf("ResolveValueFor = %s, %v, %s, %v", value, source, state, err)
+	}
+
+	events, _ := s.ListDSLChangeEvents(ctx, cbuID, 10)
+	if len(events) != 3 || events[0].EventType != store.EventOnboardingStateChanged || events[0].OldState != store.StateCreated {
+		t.Errorf("unexpected outbox events: %+v", events)
+	}
+}
+
+func TestStore_OrchestrationSessions(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	data := &store.OrchestrationSessionData{
+		SessionID:     "orch-1",
+		PrimaryDomain: "onboarding",
+		CurrentState:  "CREATED",
+		SharedContext: map[string]interface{}{"jurisdiction": "LU"},
+		CreatedAt:     time.Now(),
+	}
+	if err := s.SaveOrchestrationSession(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	data.SharedContext["jurisdiction"] = "changed after save"
+
+	if err := s.UpdateOrchestrationSessionDSL(ctx, "orch-1", "(case.create)", 2); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.LoadOrchestrationSession(ctx, "orch-1")
+	if err != nil {
+		t.Fatalf("LoadOrchestrationSession failed: %v", err)
+	}
+	if loaded.UnifiedDSL != "(case.create)" || loaded.VersionNumber != 2 || loaded.SharedContext["jurisdiction"] != "LU" {
+		t.Errorf("unexpected session: %+v", loaded)
+	}
+
+	if err := s.DeleteOrchestrationSession(ctx, "orch-1"); err != nil {
+		t.Fatalf("DeleteOrchestrationSession failed: %v", err)
+	}
+	if _, err := s.LoadOrchestrationSession(ctx, "orch-1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestStore_SnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "snapshot.json")
+
+	s := New()
+	s.SaveSnapshotOnClose(path)
+	if _, err := s.CreateWebhookSubscription(ctx, &store.WebhookSubscription{URL: "http://crm.local/hooks", Secret: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	cbuID, _ := s.CreateCBU(ctx, "CBU-5678", "Blackrock US Debt Fund", "")
+	if _, err := s.InsertDSLWithState(ctx, cbuID, `(case.create)`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restored, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if cbu, err := restored.GetCBUByName(ctx, "CBU-5678"); err != nil || cbu.CBUID != cbuID {
+		t.Errorf("CBU not restored: %+v, %v", cbu, err)
+	}
+	if v, err := restored.GetLatestDSLWithState(ctx, cbuID); err != nil || v.VersionNumber != 1 {
+		t.Errorf("DSL not restored: %+v, %v", v, err)
+	}
+
+	// The restored subscription still signs with its secret
+	deliveries, _ := restored.ListWebhookDeliveries(ctx, store.DeliveryPending)
+	if len(deliveries) != 1 || deliveries[0].Secret != "s3cret" || deliveries[0].Event == nil {
+		t.Errorf("delivery not restored with its secret and event: %+v", deliveries)
+	}
+}
+
+func TestNewFromMockData(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cbus.json":              `[{"cbu_id": "cbu-1", "name": "CBU-1234"}]`,
+		"roles.json":             `[{"role_id": "role-1", "name": "Investment Manager"}]`,
+		"entity_types.json":      `[]`,
+		"entities.json":          `[]`,
+		"cbu_entity_roles.json":  `[]`,
+		"products.json":          `[{"product_id": "prod-1", "name": "CUSTODY"}]`,
+		"services.json":          `[{"service_id": "svc-1", "name": "Safekeeping"}]`,
+		"prod_resources.json":    `[]`,
+		"product_services.json":  `[{"product_id": "prod-1", "service_id": "svc-1"}]`,
+		"service_resources.json": `[]`,
+		"dictionary.json":        `[{"attribute_id": "attr-1", "name": "entity.legal_name", "group_id": "entity", "source": {"primary": "registry"}}]`,
+		"dsl_ob.json": `[
+			{"version_id": "v-2", "cbu_id": "cbu-1", "dsl_text": "(b)", "created_at": "2024-02-01T00:00:00Z"},
+			{"version_id": "v-1", "cbu_id": "cbu-1", "dsl_text": "(a)", "created_at": "2024-01-01T00:00:00Z"}
+		]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewFromMockData(dir)
+	if err != nil {
+		t.Fatalf("NewFromMockData failed: %v", err)
+	}
+	ctx := context.Background()
+
+	if services, _ := s.GetServicesForProduct(ctx, "prod-1"); len(services) != 1 || services[0].Name != "Safekeeping" {
+		t.Errorf("unexpected services: %+v", services)
+	}
+	if attr, err := s.GetDictionaryAttributeByName(ctx, "entity.legal_name"); err != nil || attr.Source.Primary != "registry" {
+		t.Errorf("dictionary not seeded: %+v, %v", attr, err)
+	}
+	if v, err := s.GetDSLByVersion(ctx, "cbu-1", 2); err != nil || v.VersionID != "v-2" {
+		t.Errorf("seeded DSL not numbered in creation order: %+v, %v", v, err)
+	}
+
+	// Seeded data takes writes like anything else
+	if err := s.DeleteRole(ctx, "role-1"); err != nil {
+		t.Fatal(err)
+	}
+	if roles, _ := s.ListRoles(ctx); len(roles) != 0 {
+		t.Errorf("expected role to be deleted, got %+v", roles)
+	}
+}
@@ -0,0 +1,157 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Runtime job queue methods (in-memory implementations mirroring the
+// lease semantics of the action_jobs table)
+
+func (m *MockStore) EnqueueJob(ctx context.Context, job *store.ActionJob) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	queued := store.ActionJob{
+		JobID:       uuid.New().String(),
+		ActionID:    job.ActionID,
+		CBUID:       job.CBUID,
+		Payload:     append([]byte(nil), job.Payload...),
+		Status:      store.JobQueued,
+		MaxAttempts: max(job.MaxAttempts, 1),
+		RunAt:       job.RunAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if queued.RunAt.IsZero() {
+		queued.RunAt = now
+	}
+	m.jobs = append(m.jobs, queued)
+	return queued.JobID, nil
+}
+
+func (m *MockStore) LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due *store.ActionJob
+	for i := range m.jobs {
+		job := &m.jobs[i]
+		ready := (job.Status == store.JobQueued && !job.RunAt.After(now)) ||
+			(job.Status == store.JobRunning && job.LeaseExpiresAt != nil && job.LeaseExpiresAt.Before(now))
+		if ready && (due == nil || job.RunAt.Before(due.RunAt)) {
+			due = job
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	expires := now.Add(lease)
+	due.Status = store.JobRunning
+	due.LeaseOwner = &owner
+	due.LeaseExpiresAt = &expires
+	due.Attempts++
+	due.UpdatedAt = now
+
+	leased := *due
+	return &leased, nil
+}
+
+func (m *MockStore) HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error {
+	return m.updateLeasedJob(jobID, owner, "heartbeat", func(job *store.ActionJob) {
+		expires := time.Now().Add(lease)
+		job.LeaseExpiresAt = &expires
+	})
+}
+
+func (m *MockStore) CompleteJob(ctx context.Context, jobID, owner, executionID string) error {
+	return m.updateLeasedJob(jobID, owner, "complete", func(job *store.ActionJob) {
+		job.Status = store.JobSucceeded
+		job.LastError = nil
+		job.ExecutionID = nil
+		if executionID != "" {
+			job.ExecutionID = &executionID
+		}
+		releaseLease(job)
+	})
+}
+
+func (m *MockStore) RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error {
+	return m.updateLeasedJob(jobID, owner, "retry", func(job *store.ActionJob) {
+		job.Status = store.JobQueued
+		job.RunAt = runAt
+		job.LastError = &lastError
+		releaseLease(job)
+	})
+}
+
+func (m *MockStore) DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error {
+	return m.updateLeasedJob(jobID, owner, "dead-letter", func(job *store.ActionJob) {
+		job.Status = store.JobDead
+		job.LastError = &lastError
+		releaseLease(job)
+	})
+}
+
+func (m *MockStore) RequeueJob(ctx context.Context, jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.jobs {
+		if job := &m.jobs[i]; job.JobID == jobID && job.Status == store.JobDead {
+			job.Status = store.JobQueued
+			job.Attempts = 0
+			job.RunAt = time.Now()
+			job.UpdatedAt = job.RunAt
+			return nil
+		}
+	}
+	return store.NotFoundf("dead job not found: %s", jobID)
+}
+
+func (m *MockStore) ListJobs(ctx context.Context, status store.JobStatus) ([]store.ActionJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []store.ActionJob
+	for _, job := range m.jobs {
+		if status == "" || job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// updateLeasedJob applies update to owner's running job, returning
+// store.ErrLeaseLost when owner no longer holds it
+func (m *MockStore) updateLeasedJob(jobID, owner, op string, update func(job *store.ActionJob)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.jobs {
+		job := &m.jobs[i]
+		if job.JobID != jobID {
+			continue
+		}
+		if job.Status != store.JobRunning || job.LeaseOwner == nil || *job.LeaseOwner != owner {
+			break
+		}
+		update(job)
+		job.UpdatedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("failed to %s job %s: %w", op, jobID, store.ErrLeaseLost)
+}
+
+func releaseLease(job *store.ActionJob) {
+	job.LeaseOwner = nil
+	job.LeaseExpiresAt = nil
+}
@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+func TestMockStore_JobLeases(t *testing.T) {
+	m := NewMockStore("../../data/mocks")
+	ctx := context.Background()
+
+	jobID, err := m.EnqueueJob(ctx, &store.ActionJob{ActionID: "action-1", CBUID: "cbu-1", Payload: []byte(`{}`), MaxAttempts: 2})
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+
+	job, err := m.LeaseJob(ctx, "worker-a", time.Minute)
+	if err != nil || job == nil || job.JobID != jobID {
+		t.Fatalf("expected to lease %s, got %+v (%v)", jobID, job, err)
+	}
+	if job.Status != store.JobRunning || job.Attempts != 1 {
+		t.Errorf("expected a running first attempt, got %s attempt %d", job.Status, job.Attempts)
+	}
+	if again, _ := m.LeaseJob(ctx, "worker-b", time.Minute); again != nil {
+		t.Fatalf("a leased job must not be leased twice, got %+v", again)
+	}
+	if err := m.HeartbeatJob(ctx, jobID, "worker-b", time.Minute); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost for a foreign heartbeat, got %v", err)
+	}
+
+	// A retry scheduled in the future is not due yet
+	if err := m.RetryJob(ctx, jobID, "worker-a", time.Now().Add(time.Hour), "HTTP 503"); err != nil {
+		t.Fatalf("RetryJob failed: %v", err)
+	}
+	if due, _ := m.LeaseJob(ctx, "worker-a", time.Minute); due != nil {
+		t.Fatalf("expected no due job before run_at, got %+v", due)
+	}
+
+	// An expired lease is reclaimed by the next worker
+	m.jobs[0].RunAt = time.Now()
+	if _, err := m.LeaseJob(ctx, "worker-a", -time.Second); err != nil {
+		t.Fatalf("LeaseJob failed: %v", err)
+	}
+	reclaimed, _ := m.LeaseJob(ctx, "worker-b", time.Minute)
+	if reclaimed == nil || *reclaimed.LeaseOwner != "worker-b" || reclaimed.Attempts != 3 {
+		t.Fatalf("expected worker-b to reclaim the job on attempt 3, got %+v", reclaimed)
+	}
+	if err := m.CompleteJob(ctx, jobID, "worker-a", "exec-1"); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost for the expired owner, got %v", err)
+	}
+
+	if err := m.DeadLetterJob(ctx, jobID, "worker-b", "out of attempts"); err != nil {
+		t.Fatalf("DeadLetterJob failed: %v", err)
+	}
+	dead, _ := m.ListJobs(ctx, store.JobDead)
+	if len(dead) != 1 || *dead[0].LastError != "out of attempts" || dead[0].LeaseOwner != nil {
+		t.Fatalf("expected one dead job without a lease, got %+v", dead)
+	}
+
+	if err := m.RequeueJob(ctx, jobID); err != nil {
+		t.Fatalf("RequeueJob failed: %v", err)
+	}
+	if err := m.RequeueJob(ctx, jobID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when requeueing a live job, got %v", err)
+	}
+	requeued, _ := m.LeaseJob(ctx, "worker-a", time.Minute)
+	if requeued == nil || requeued.Attempts != 1 {
+		t.Fatalf("expected the requeued job with fresh attempts, got %+v", requeued)
+	}
+	if err := m.CompleteJob(ctx, jobID, "worker-a", "exec-1"); err != nil {
+		t.Fatalf("CompleteJob failed: %v", err)
+	}
+	if done, _ := m.ListJobs(ctx, store.JobSucceeded); len(done) != 1 || *done[0].ExecutionID != "exec-1" {
+		t.Errorf("expected the job to succeed with exec-1, got %+v", done)
+	}
+}
@@ -0,0 +1,203 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// DSL change outbox and webhook delivery methods (in-memory implementations
+// mirroring the dsl_change_events and webhook_deliveries tables)
+
+// recordDSLChange appends event to the outbox and queues a delivery for every
+// matching subscription; the caller holds m.mu
+func (m *MockStore) recordDSLChange(event store.DSLChangeEvent) {
+	now := time.Now()
+	event.EventID = uuid.New().String()
+	event.CreatedAt = now
+	m.dslEvents = append(m.dslEvents, event)
+
+	for _, sub := range m.subscriptions {
+		if !sub.Matches(&event) {
+			continue
+		}
+		m.deliveries = append(m.deliveries, store.WebhookDelivery{
+			DeliveryID:     uuid.New().String(),
+			EventID:        event.EventID,
+			SubscriptionID: sub.SubscriptionID,
+			Status:         store.DeliveryPending,
+			MaxAttempts:    sub.MaxAttempts,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+}
+
+func (m *MockStore) ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]store.DSLChangeEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []store.DSLChangeEvent
+	for i := len(m.dslEvents) - 1; i >= 0 && len(events) < limit; i-- {
+		if event := m.dslEvents[i]; cbuID == "" || event.CBUID == cbuID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *MockStore) CreateWebhookSubscription(ctx context.Context, sub *store.WebhookSubscription) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	created := *sub
+	created.SubscriptionID = uuid.New().String()
+	created.EventTypes = append([]store.DSLEventType(nil), sub.EventTypes...)
+	created.MaxAttempts = max(sub.MaxAttempts, 1)
+	created.Active = true
+	created.CreatedAt = time.Now()
+	m.subscriptions = append(m.subscriptions, created)
+	return created.SubscriptionID, nil
+}
+
+func (m *MockStore) ListWebhookSubscriptions(ctx context.Context) ([]store.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]store.WebhookSubscription(nil), m.subscriptions...), nil
+}
+
+func (m *MockStore) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, sub := range m.subscriptions {
+		if sub.SubscriptionID != subscriptionID {
+			continue
+		}
+		m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+		kept := m.deliveries[:0]
+		for _, delivery := range m.deliveries {
+			if delivery.SubscriptionID != subscriptionID {
+				kept = append(kept, delivery)
+			}
+		}
+		m.deliveries = kept
+		return nil
+	}
+	return store.NotFoundf("webhook subscription not found: %s", subscriptionID)
+}
+
+func (m *MockStore) LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var due *store.WebhookDelivery
+	for i := range m.deliveries {
+		delivery := &m.deliveries[i]
+		ready := (delivery.Status == store.DeliveryPending && !delivery.NextAttemptAt.After(now)) ||
+			(delivery.Status == store.DeliveryDelivering && delivery.LeaseExpiresAt != nil && delivery.LeaseExpiresAt.Before(now))
+		if ready && (due == nil || delivery.NextAttemptAt.Before(due.NextAttemptAt)) {
+			due = delivery
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	expires := now.Add(lease)
+	due.Status = store.DeliveryDelivering
+	due.LeaseOwner = &owner
+	due.LeaseExpiresAt = &expires
+	due.Attempts++
+	due.UpdatedAt = now
+
+	leased := m.withEventAndSubscription(*due)
+	return &leased, nil
+}
+
+func (m *MockStore) CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error {
+	return m.updateLeasedDelivery(deliveryID, owner, "complete", func(delivery *store.WebhookDelivery) {
+		now := time.Now()
+		delivery.Status = store.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+	})
+}
+
+func (m *MockStore) RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error {
+	return m.updateLeasedDelivery(deliveryID, owner, "retry", func(delivery *store.WebhookDelivery) {
+		delivery.Status = store.DeliveryPending
+		delivery.NextAttemptAt = nextAttemptAt
+		delivery.LastError = &lastError
+	})
+}
+
+func (m *MockStore) DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error {
+	return m.updateLeasedDelivery(deliveryID, owner, "dead-letter", func(delivery *store.WebhookDelivery) {
+		delivery.Status = store.DeliveryDead
+		delivery.LastError = &lastError
+	})
+}
+
+func (m *MockStore) ListWebhookDeliveries(ctx context.Context, status store.DeliveryStatus) ([]store.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deliveries []store.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if status == "" || delivery.Status == status {
+			deliveries = append(deliveries, m.withEventAndSubscription(delivery))
+		}
+	}
+	return deliveries, nil
+}
+
+// updateLeasedDelivery applies update to owner's delivery in progress,
+// returning store.ErrLeaseLost when owner no longer holds it
+func (m *MockStore) updateLeasedDelivery(deliveryID, owner, op string, update func(delivery *store.WebhookDelivery)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.deliveries {
+		delivery := &m.deliveries[i]
+		if delivery.DeliveryID != deliveryID {
+			continue
+		}
+		if delivery.Status != store.DeliveryDelivering || delivery.LeaseOwner == nil || *delivery.LeaseOwner != owner {
+			break
+		}
+		update(delivery)
+		delivery.LeaseOwner = nil
+		delivery.LeaseExpiresAt = nil
+		delivery.UpdatedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("failed to %s webhook delivery %s: %w", op, deliveryID, store.ErrLeaseLost)
+}
+
+// withEventAndSubscription fills in the event and target of a delivery, as
+// the joins in the store do; the caller holds m.mu
+func (m *MockStore) withEventAndSubscription(delivery store.WebhookDelivery) store.WebhookDelivery {
+	for i := range m.dslEvents {
+		if m.dslEvents[i].EventID == delivery.EventID {
+			event := m.dslEvents[i]
+			delivery.Event = &event
+			break
+		}
+	}
+	for _, sub := range m.subscriptions {
+		if sub.SubscriptionID == delivery.SubscriptionID {
+			delivery.URL = sub.URL
+			delivery.Secret = sub.Secret
+			break
+		}
+	}
+	return delivery
+}
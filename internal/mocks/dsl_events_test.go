@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+func TestMockStore_DSLChangeOutbox(t *testing.T) {
+	m := NewMockStore(emptyMockData(t))
+	ctx := context.Background()
+
+	subID, err := m.CreateWebhookSubscription(ctx, &store.WebhookSubscription{URL: "http://crm.local/hooks", CBUID: "cbu-1"})
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription failed: %v", err)
+	}
+
+	if _, err := m.CreateOnboardingSession(ctx, "cbu-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.InsertDSLWithState(ctx, "cbu-1", `(case.create (cbu.id "cbu-1"))`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+	versionID, err := m.InsertDSLWithState(ctx, "cbu-1", `(case.create (cbu.id "cbu-1")) (products.add "CUSTODY")`, store.StateProductsAdded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateOnboardingState(ctx, "cbu-1", store.StateProductsAdded, versionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.InsertDSLWithState(ctx, "cbu-2", `(case.create (cbu.id "cbu-2"))`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := m.ListDSLChangeEvents(ctx, "cbu-1", 10)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected 3 events for cbu-1, got %d (%v)", len(events), err)
+	}
+	latest := events[0]
+	if latest.EventType != store.EventOnboardingStateChanged || latest.VersionNumber != 2 || latest.NewState != store.StateProductsAdded {
+		t.Errorf("unexpected latest event: %+v", latest)
+	}
+	if created := events[1]; created.OldState != store.StateCreated || len(created.ChangedVerbs) != 1 || created.ChangedVerbs[0] != "products.add" {
+		t.Errorf("unexpected version event: %+v", created)
+	}
+
+	// Only cbu-1's events are queued for the subscription
+	deliveries, _ := m.ListWebhookDeliveries(ctx, store.DeliveryPending)
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 pending deliveries, got %d", len(deliveries))
+	}
+	if deliveries[0].URL != "http://crm.local/hooks" || deliveries[0].Event == nil || deliveries[0].Event.CBUID != "cbu-1" {
+		t.Errorf("delivery is missing its event or target: %+v", deliveries[0])
+	}
+
+	leased, err := m.LeaseWebhookDelivery(ctx, "dispatcher-a", time.Minute)
+	if err != nil || leased == nil {
+		t.Fatalf("LeaseWebhookDelivery = %+v, %v", leased, err)
+	}
+	if err := m.CompleteWebhookDelivery(ctx, leased.DeliveryID, "dispatcher-b"); !errors.Is(err, store.ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost for a foreign completion, got %v", err)
+	}
+
+	if err := m.DeleteWebhookSubscription(ctx, subID); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, _ := m.ListWebhookDeliveries(ctx, ""); len(remaining) != 0 {
+		t.Errorf("expected deliveries to go with their subscription, got %d", len(remaining))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"dsl-ob-poc/internal/entities"
 	"dsl-ob-poc/internal/store"
 )
 
@@ -139,6 +140,33 @@ func (j *JSONDataLoader) LoadIndividuals() ([]store.Individual, error) {
 	return individuals, nil
 }
 
+// LoadPartnershipInterests loads partnership interest mock data from JSON
+func (j *JSONDataLoader) LoadPartnershipInterests() ([]entities.PartnershipInterest, error) {
+	var interests []entities.PartnershipInterest
+	if err := j.loadJSONFile("partnership_interests.json", &interests, false); err != nil {
+		return nil, err
+	}
+	return interests, nil
+}
+
+// LoadTrustParties loads trust party mock data from JSON
+func (j *JSONDataLoader) LoadTrustParties() ([]entities.TrustParty, error) {
+	var parties []entities.TrustParty
+	if err := j.loadJSONFile("trust_parties.json", &parties, false); err != nil {
+		return nil, err
+	}
+	return parties, nil
+}
+
+// LoadUBORegistry loads registered UBO relationships from JSON
+func (j *JSONDataLoader) LoadUBORegistry() ([]entities.UBORegistry, error) {
+	var registry []entities.UBORegistry
+	if err := j.loadJSONFile("ubo_registry.json", &registry, false); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
 // LoadCBUEntityRoles loads CBU entity role relationships from JSON
 func (j *JSONDataLoader) LoadCBUEntityRoles() ([]store.CBUEntityRole, error) {
 	filePath := filepath.Join(j.basePath, "cbu_entity_roles.json")
@@ -4,10 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"dsl-ob-poc/internal/dictionary"
+	"dsl-ob-poc/internal/entities"
 	"dsl-ob-poc/internal/store"
+
+	"github.com/google/uuid"
 )
 
 // MockStore implements a disconnected store using JSON mock data
@@ -32,10 +37,34 @@ type MockStore struct {
 	attributeValues  []AttributeValue
 	dslRecords       []DSLRecord
 
+	// Ownership graph data (optional files)
+	partnershipInterests []entities.PartnershipInterest
+	trustParties         []entities.TrustParty
+	uboRegistry          []entities.UBORegistry
+
 	// In-memory tracking for dynamic DSL versions
 	dynamicDSLVersions []store.DSLVersionWithState
 	versionCounter     int
 
+	// In-memory orchestration sessions
+	orchestrationSessions []orchestrationSession
+
+	// In-memory runtime job queue
+	jobs []store.ActionJob
+
+	// In-memory onboarding sessions, keyed by CBU ID
+	onboardingSessions map[string]*store.OnboardingSession
+
+	// In-memory DSL change outbox and webhook deliveries
+	dslEvents     []store.DSLChangeEvent
+	subscriptions []store.WebhookSubscription
+	deliveries    []store.WebhookDelivery
+
+	// In-memory IR plan investor state, keyed by CBU and investor ID
+	investorStates map[string]json.RawMessage
+
+	// mu serializes access; the store is shared by concurrent HTTP handlers
+	mu     sync.Mutex
 	loaded bool
 }
 
@@ -119,24 +148,77 @@ func (m *MockStore) loadData() error {
 		return fmt.Errorf("failed to load DSL records: %w", err)
 	}
 
+	if m.partnershipInterests, err = m.loader.LoadPartnershipInterests(); err != nil {
+		return fmt.Errorf("failed to load partnership interests: %w", err)
+	}
+
+	if m.trustParties, err = m.loader.LoadTrustParties(); err != nil {
+		return fmt.Errorf("failed to load trust parties: %w", err)
+	}
+
+	if m.uboRegistry, err = m.loader.LoadUBORegistry(); err != nil {
+		return fmt.Errorf("failed to load UBO registry: %w", err)
+	}
+
 	m.loaded = true
 	return nil
 }
 
 // Close does nothing for mock store
 func (m *MockStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return nil
+}
+
+// SaveCalculatedUBOs replaces the calculated UBO rows of a CBU's subject entity,
+// keeping registered relationships with the same subject, person and type
+func (m *MockStore) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	type relationship struct {
+		subject, person uuid.UUID
+		kind            string
+	}
+	kept := m.uboRegistry[:0]
+	registered := make(map[relationship]bool)
+	for _, r := range m.uboRegistry {
+		if r.CBUID.String() == cbuID && r.SubjectEntityID == subjectEntityID && r.WorkflowType == entities.UBOWorkflowRecursiveAnalysis {
+			continue
+		}
+		kept = append(kept, r)
+		registered[relationship{r.SubjectEntityID, r.UBOProperPersonID, r.RelationshipType}] = true
+	}
+	for _, e := range entries {
+		if !registered[relationship{e.SubjectEntityID, e.UBOProperPersonID, e.RelationshipType}] {
+			kept = append(kept, e)
+		}
+	}
+	m.uboRegistry = kept
 	return nil
 }
 
 // CBU CRUD Operations
 func (m *MockStore) ListCBUs(ctx context.Context) ([]store.CBU, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
-	return m.cbus, nil
+	return append([]store.CBU(nil), m.cbus...), nil
 }
 
 func (m *MockStore) GetCBUByID(ctx context.Context, cbuID string) (*store.CBU, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -146,10 +228,13 @@ func (m *MockStore) GetCBUByID(ctx context.Context, cbuID string) (*store.CBU, e
 			return &cbu, nil
 		}
 	}
-	return nil, fmt.Errorf("CBU not found: %s", cbuID)
+	return nil, store.NotFoundf("CBU not found: %s", cbuID)
 }
 
 func (m *MockStore) GetCBUByName(ctx context.Context, name string) (*store.CBU, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -159,18 +244,24 @@ func (m *MockStore) GetCBUByName(ctx context.Context, name string) (*store.CBU,
 			return &cbu, nil
 		}
 	}
-	return nil, fmt.Errorf("CBU not found: %s", name)
+	return nil, store.NotFoundf("CBU not found: %s", name)
 }
 
 // Role CRUD Operations
 func (m *MockStore) ListRoles(ctx context.Context) ([]store.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
-	return m.roles, nil
+	return append([]store.Role(nil), m.roles...), nil
 }
 
 func (m *MockStore) GetRoleByID(ctx context.Context, roleID string) (*store.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -180,11 +271,14 @@ func (m *MockStore) GetRoleByID(ctx context.Context, roleID string) (*store.Role
 			return &role, nil
 		}
 	}
-	return nil, fmt.Errorf("role not found: %s", roleID)
+	return nil, store.NotFoundf("role not found: %s", roleID)
 }
 
 // Product Operations
 func (m *MockStore) GetProductByName(ctx context.Context, name string) (*store.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -194,11 +288,18 @@ func (m *MockStore) GetProductByName(ctx context.Context, name string) (*store.P
 			return &product, nil
 		}
 	}
-	return nil, fmt.Errorf("product not found: %s", name)
+	return nil, store.NotFoundf("product not found: %s", name)
 }
 
 // Service Operations
 func (m *MockStore) GetServicesForProduct(ctx context.Context, productID string) ([]store.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.servicesForProduct(productID)
+}
+
+func (m *MockStore) servicesForProduct(productID string) ([]store.Service, error) {
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -226,6 +327,9 @@ func (m *MockStore) GetServicesForProduct(ctx context.Context, productID string)
 }
 
 func (m *MockStore) GetServiceByName(ctx context.Context, name string) (*store.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -235,11 +339,14 @@ func (m *MockStore) GetServiceByName(ctx context.Context, name string) (*store.S
 			return &service, nil
 		}
 	}
-	return nil, fmt.Errorf("service not found: %s", name)
+	return nil, store.NotFoundf("service not found: %s", name)
 }
 
 // Resource Operations
 func (m *MockStore) GetResourcesForService(ctx context.Context, serviceID string) ([]store.ProdResource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -266,39 +373,147 @@ func (m *MockStore) GetResourcesForService(ctx context.Context, serviceID string
 	return resources, nil
 }
 
-// Orchestration session methods (mock implementations)
+// Orchestration session methods: sessions live in memory for the lifetime
+// of the store and expire like database sessions
+
+// orchestrationSessionTTL matches the expiry the database store sets on save
+const orchestrationSessionTTL = 24 * time.Hour
+
+type orchestrationSession struct {
+	data      store.OrchestrationSessionData
+	expiresAt time.Time
+}
+
+// SaveOrchestrationSession inserts or replaces a session and extends its expiry
 func (m *MockStore) SaveOrchestrationSession(ctx context.Context, session *store.OrchestrationSessionData) error {
-	// In mock mode, just return success - sessions are not persisted
+	saved, err := copyOrchestrationSession(session)
+	if err != nil {
+		return fmt.Errorf("failed to save orchestration session: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := orchestrationSession{data: *saved, expiresAt: time.Now().Add(orchestrationSessionTTL)}
+	if existing := m.orchestrationSession(session.SessionID); existing != nil {
+		row.data.CreatedAt = existing.data.CreatedAt
+		*existing = row
+		return nil
+	}
+	m.orchestrationSessions = append(m.orchestrationSessions, row)
 	return nil
 }
 
 func (m *MockStore) LoadOrchestrationSession(ctx context.Context, sessionID string) (*store.OrchestrationSessionData, error) {
-	// In mock mode, return error as sessions are not persisted
-	return nil, fmt.Errorf("orchestration session not found: %s", sessionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := m.orchestrationSession(sessionID)
+	if row == nil || !row.expiresAt.After(time.Now()) {
+		return nil, store.NotFoundf("orchestration session not found: %s", sessionID)
+	}
+	loaded, err := copyOrchestrationSession(&row.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load orchestration session: %w", err)
+	}
+	row.data.LastUsed = time.Now()
+	return loaded, nil
 }
 
 func (m *MockStore) ListActiveOrchestrationSessions(ctx context.Context) ([]string, error) {
-	// In mock mode, return empty list
-	return []string{}, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var active []orchestrationSession
+	for _, row := range m.orchestrationSessions {
+		if row.expiresAt.After(now) {
+			active = append(active, row)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool { return active[i].data.LastUsed.After(active[j].data.LastUsed) })
+
+	ids := make([]string, 0, len(active))
+	for _, row := range active {
+		ids = append(ids, row.data.SessionID)
+	}
+	return ids, nil
 }
 
 func (m *MockStore) DeleteOrchestrationSession(ctx context.Context, sessionID string) error {
-	// In mock mode, return error as sessions don't exist
-	return fmt.Errorf("session not found: %s", sessionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, row := range m.orchestrationSessions {
+		if row.data.SessionID == sessionID {
+			m.orchestrationSessions = append(m.orchestrationSessions[:i:i], m.orchestrationSessions[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("session not found: %s", sessionID)
 }
 
 func (m *MockStore) CleanupExpiredOrchestrationSessions(ctx context.Context) (int64, error) {
-	// In mock mode, return 0 cleaned sessions
-	return 0, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var kept []orchestrationSession
+	for _, row := range m.orchestrationSessions {
+		if row.expiresAt.After(now) {
+			kept = append(kept, row)
+		}
+	}
+	removed := int64(len(m.orchestrationSessions) - len(kept))
+	m.orchestrationSessions = kept
+	return removed, nil
 }
 
 func (m *MockStore) UpdateOrchestrationSessionDSL(ctx context.Context, sessionID, dsl string, version int) error {
-	// In mock mode, return error as sessions don't exist
-	return fmt.Errorf("session not found: %s", sessionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := m.orchestrationSession(sessionID)
+	if row == nil {
+		return store.NotFoundf("session not found: %s", sessionID)
+	}
+	now := time.Now()
+	row.data.UnifiedDSL = dsl
+	row.data.VersionNumber = version
+	row.data.UpdatedAt = now
+	row.data.LastUsed = now
+	return nil
+}
+
+// orchestrationSession returns the stored session, expired or not, or nil
+func (m *MockStore) orchestrationSession(sessionID string) *orchestrationSession {
+	for i := range m.orchestrationSessions {
+		if m.orchestrationSessions[i].data.SessionID == sessionID {
+			return &m.orchestrationSessions[i]
+		}
+	}
+	return nil
+}
+
+// copyOrchestrationSession deep-copies a session through its JSON form, so
+// callers never share maps with the store
+func copyOrchestrationSession(session *store.OrchestrationSessionData) (*store.OrchestrationSessionData, error) {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	var copied store.OrchestrationSessionData
+	if err := json.Unmarshal(raw, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
 }
 
 // Additional helper methods for mock testing
 func (m *MockStore) GetAllProducts(ctx context.Context) ([]store.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -306,6 +521,9 @@ func (m *MockStore) GetAllProducts(ctx context.Context) ([]store.Product, error)
 }
 
 func (m *MockStore) GetServicesForProducts(ctx context.Context, productNames []string) ([]store.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -325,7 +543,7 @@ func (m *MockStore) GetServicesForProducts(ctx context.Context, productNames []s
 
 		if productID != "" {
 			// Get services for this product
-			services, err := m.GetServicesForProduct(ctx, productID)
+			services, err := m.servicesForProduct(productID)
 			if err != nil {
 				continue // Skip if error
 			}
@@ -345,6 +563,9 @@ func (m *MockStore) GetServicesForProducts(ctx context.Context, productNames []s
 
 // Dictionary Operations
 func (m *MockStore) GetDictionaryAttributeByName(ctx context.Context, name string) (*dictionary.Attribute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -361,10 +582,13 @@ func (m *MockStore) GetDictionaryAttributeByName(ctx context.Context, name strin
 			}, nil
 		}
 	}
-	return nil, fmt.Errorf("attribute not found: %s", name)
+	return nil, store.NotFoundf("attribute not found: %s", name)
 }
 
 func (m *MockStore) GetDictionaryAttributeByID(ctx context.Context, id string) (*dictionary.Attribute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -381,10 +605,31 @@ func (m *MockStore) GetDictionaryAttributeByID(ctx context.Context, id string) (
 			}, nil
 		}
 	}
-	return nil, fmt.Errorf("attribute not found: %s", id)
+	return nil, store.NotFoundf("attribute not found: %s", id)
+}
+
+// UpdateDictionaryAttributeVector records the vector in memory
+func (m *MockStore) UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	for i := range m.dictionary {
+		if m.dictionary[i].AttributeID == attributeID {
+			m.dictionary[i].Vector = vector
+			return nil
+		}
+	}
+	return store.NotFoundf("attribute not found: %s", attributeID)
 }
 
 func (m *MockStore) GetAttributesForDictionaryGroup(ctx context.Context, groupID string) ([]dictionary.Attribute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -408,66 +653,131 @@ func (m *MockStore) GetAttributesForDictionaryGroup(ctx context.Context, groupID
 
 // DSL Operations
 func (m *MockStore) GetLatestDSL(ctx context.Context, cbuID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return "", err
 	}
 
-	var latest DSLRecord
-	var found bool
-
-	for _, record := range m.dslRecords {
-		if record.CBUID == cbuID {
-			if !found {
-				latest = record
-				found = true
-			} else {
-				// Simple string comparison for mock - in real DB this would use timestamp
-				if record.CreatedAt > latest.CreatedAt {
-					latest = record
-				}
-			}
-		}
-	}
-
-	if !found {
-		return "", fmt.Errorf("no DSL found for CBU: %s", cbuID)
+	versions := m.versions(cbuID)
+	if len(versions) == 0 {
+		return "", store.NotFoundf("no DSL found for CBU: %s", cbuID)
 	}
-
-	return latest.DSLText, nil
+	return versions[len(versions)-1].DSLText, nil
 }
 
+// InsertDSL records a DSL version without an onboarding state
 func (m *MockStore) InsertDSL(ctx context.Context, cbuID, dslText string) (string, error) {
-	// For mock store, we don't actually insert - just return a mock version ID
-	return fmt.Sprintf("mock-version-%d", time.Now().Unix()), nil
+	return m.InsertPlainDSL(ctx, cbuID, dslText, "")
+}
+
+// InsertPlainDSL records a DSL version without an onboarding state, along
+// with the grammar version that validated it. Like the database store, it
+// writes no outbox event.
+func (m *MockStore) InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.versionCounter++
+	versionID := fmt.Sprintf("mock-version-dynamic-%d", m.versionCounter)
+	m.dynamicDSLVersions = append(m.dynamicDSLVersions, store.DSLVersionWithState{
+		VersionID:      versionID,
+		CBUID:          cbuID,
+		DSLText:        dslText,
+		VersionNumber:  m.getNextVersionNumber(cbuID),
+		GrammarVersion: grammarVersion,
+		CreatedAt:      time.Now(),
+	})
+	return versionID, nil
 }
 
 // ResolveValueFor provides mock attribute value resolution
 func (m *MockStore) ResolveValueFor(ctx context.Context, cbuID, attributeID string) (json.RawMessage, map[string]any, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, nil, "", err
 	}
 
-	// Find matching attribute value
-	for _, av := range m.attributeValues {
-		if av.CBUID == cbuID && av.AttributeID == attributeID {
-			value := json.RawMessage(av.Value)
-
-			var source map[string]any
-			if err := json.Unmarshal([]byte(av.Source), &source); err != nil {
-				source = map[string]any{"type": "mock", "error": err.Error()}
-			}
-
-			return value, source, av.State, nil
+	// The value recorded for the latest DSL version wins
+	var current *AttributeValue
+	for i, av := range m.attributeValues {
+		if av.CBUID == cbuID && av.AttributeID == attributeID && (current == nil || av.DSLVersion > current.DSLVersion) {
+			current = &m.attributeValues[i]
+		}
+	}
+	if current != nil {
+		var source map[string]any
+		if err := json.Unmarshal([]byte(current.Source), &source); err != nil {
+			source = map[string]any{"type": "mock", "error": err.Error()}
 		}
+		return json.RawMessage(current.Value), source, current.State, nil
 	}
 
 	// Return pending state with null value if not found
 	return json.RawMessage("null"), map[string]any{"reason": "no_resolver", "type": "mock"}, "pending", nil
 }
 
-// UpsertAttributeValue is a no-op for mock store
+// UpsertAttributeValue records the value in memory so later ResolveValueFor calls see it
 func (m *MockStore) UpsertAttributeValue(ctx context.Context, cbuID string, dslVersion int, attributeID string, value json.RawMessage, state string, source map[string]any) error {
-	// For mock store, we don't actually upsert
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	sourceJSON, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+
+	av := AttributeValue{
+		CBUID:       cbuID,
+		DSLVersion:  dslVersion,
+		AttributeID: attributeID,
+		Value:       string(value),
+		State:       state,
+		Source:      string(sourceJSON),
+		ObservedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for i, existing := range m.attributeValues {
+		if existing.CBUID == cbuID && existing.DSLVersion == dslVersion && existing.AttributeID == attributeID {
+			av.AVID = existing.AVID
+			m.attributeValues[i] = av
+			return nil
+		}
+	}
+
+	av.AVID = fmt.Sprintf("mock-av-%d", len(m.attributeValues)+1)
+	m.attributeValues = append(m.attributeValues, av)
+	return nil
+}
+
+// GetInvestorState returns the investor state saved in this process
+func (m *MockStore) GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.investorStates[cbuID+"|"+investorID]
+	if !ok {
+		return nil, store.NotFoundf("investor %s has no state for CBU %s", investorID, cbuID)
+	}
+	return append(json.RawMessage(nil), state...), nil
+}
+
+// SaveInvestorState records the investor state in memory
+func (m *MockStore) SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.investorStates == nil {
+		m.investorStates = make(map[string]json.RawMessage)
+	}
+	m.investorStates[cbuID+"|"+investorID] = append(json.RawMessage(nil), state...)
 	return nil
 }
 
@@ -476,6 +786,9 @@ func (m *MockStore) UpsertAttributeValue(ctx context.Context, cbuID string, dslV
 
 // GetEntitiesForCBU returns entities associated with a CBU
 func (m *MockStore) GetEntitiesForCBU(ctx context.Context, cbuID string) ([]store.Entity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -495,101 +808,408 @@ func (m *MockStore) GetEntitiesForCBU(ctx context.Context, cbuID string) ([]stor
 	return entities, nil
 }
 
+// LoadOwnershipGraph converts the mock entity registry into ownership graph data.
+// Entities whose IDs are not UUIDs cannot be linked to interests and are skipped.
+// Only the CBU's registered (not calculated) UBO relationships are included.
+func (m *MockStore) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return nil, err
+	}
+
+	var registry []entities.UBORegistry
+	for _, r := range m.uboRegistry {
+		if r.CBUID.String() == cbuID && r.WorkflowType != entities.UBOWorkflowRecursiveAnalysis {
+			registry = append(registry, r)
+		}
+	}
+
+	typesByID := make(map[string]store.EntityType, len(m.entityTypes))
+	for _, et := range m.entityTypes {
+		typesByID[et.EntityTypeID] = et
+	}
+
+	data := &entities.OwnershipGraphData{
+		PartnershipInterests: m.partnershipInterests,
+		TrustParties:         m.trustParties,
+		UBORegistry:          registry,
+	}
+
+	for _, e := range m.entities {
+		entityID, err := uuid.Parse(e.EntityID)
+		if err != nil {
+			continue
+		}
+		entityTypeID, _ := uuid.Parse(e.EntityTypeID)
+
+		entity := entities.Entity{
+			EntityID:     entityID,
+			EntityTypeID: entityTypeID,
+			Name:         e.Name,
+		}
+		if e.ExternalID != "" {
+			externalID := e.ExternalID
+			entity.ExternalID = &externalID
+		}
+		if et, ok := typesByID[e.EntityTypeID]; ok {
+			entity.EntityType = &entities.EntityType{
+				EntityTypeID: entityTypeID,
+				Name:         et.Name,
+				Description:  et.Description,
+				TableName:    et.TableName,
+			}
+		}
+		data.Entities = append(data.Entities, entity)
+	}
+
+	return data, nil
+}
+
 // CBU CRUD Operations
+
+// CreateCBU adds a CBU; names are unique, as in the database
 func (m *MockStore) CreateCBU(ctx context.Context, name, description, naturePurpose string) (string, error) {
-	// For mock store, we don't actually create - just return a mock CBU ID
-	return fmt.Sprintf("mock-cbu-%d", time.Now().Unix()), nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return "", err
+	}
+
+	for _, cbu := range m.cbus {
+		if cbu.Name == name {
+			return "", fmt.Errorf("failed to create CBU: name %q already exists", name)
+		}
+	}
+	cbu := store.CBU{CBUID: uuid.New().String(), Name: name, Description: description, NaturePurpose: naturePurpose}
+	m.cbus = append(m.cbus, cbu)
+	return cbu.CBUID, nil
 }
 
+// UpdateCBU changes the non-empty fields of a CBU
 func (m *MockStore) UpdateCBU(ctx context.Context, cbuID, name, description, naturePurpose string) error {
-	// For mock store, we don't actually update
-	return nil
+	if name == "" && description == "" && naturePurpose == "" {
+		return fmt.Errorf("no fields to update")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	for i := range m.cbus {
+		cbu := &m.cbus[i]
+		if cbu.CBUID != cbuID {
+			continue
+		}
+		if name != "" {
+			cbu.Name = name
+		}
+		if description != "" {
+			cbu.Description = description
+		}
+		if naturePurpose != "" {
+			cbu.NaturePurpose = naturePurpose
+		}
+		return nil
+	}
+	return store.NotFoundf("CBU not found: %s", cbuID)
 }
 
 func (m *MockStore) DeleteCBU(ctx context.Context, cbuID string) error {
-	// For mock store, we don't actually delete
-	return nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	for i := range m.cbus {
+		if m.cbus[i].CBUID == cbuID {
+			m.cbus = append(m.cbus[:i:i], m.cbus[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("CBU not found: %s", cbuID)
 }
 
 // Role CRUD Operations
+
+// CreateRole adds a role; names are unique, as in the database
 func (m *MockStore) CreateRole(ctx context.Context, name, description string) (string, error) {
-	// For mock store, we don't actually create - just return a mock role ID
-	return fmt.Sprintf("mock-role-%d", time.Now().Unix()), nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return "", err
+	}
+
+	for _, role := range m.roles {
+		if role.Name == name {
+			return "", fmt.Errorf("failed to create role: name %q already exists", name)
+		}
+	}
+	role := store.Role{RoleID: uuid.New().String(), Name: name, Description: description}
+	m.roles = append(m.roles, role)
+	return role.RoleID, nil
 }
 
+// UpdateRole changes the non-empty fields of a role
 func (m *MockStore) UpdateRole(ctx context.Context, roleID, name, description string) error {
-	// For mock store, we don't actually update
-	return nil
+	if name == "" && description == "" {
+		return fmt.Errorf("no fields to update")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	for i := range m.roles {
+		role := &m.roles[i]
+		if role.RoleID != roleID {
+			continue
+		}
+		if name != "" {
+			role.Name = name
+		}
+		if description != "" {
+			role.Description = description
+		}
+		return nil
+	}
+	return store.NotFoundf("role not found: %s", roleID)
 }
 
 func (m *MockStore) DeleteRole(ctx context.Context, roleID string) error {
-	// For mock store, we don't actually delete
-	return nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+
+	for i := range m.roles {
+		if m.roles[i].RoleID == roleID {
+			m.roles = append(m.roles[:i:i], m.roles[i+1:]...)
+			return nil
+		}
+	}
+	return store.NotFoundf("role not found: %s", roleID)
 }
 
 // DSL History Operation
 func (m *MockStore) GetDSLHistory(ctx context.Context, cbuID string) ([]store.DSLVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
 
 	var history []store.DSLVersion
-	for _, record := range m.dslRecords {
-		if record.CBUID == cbuID {
-			// Convert mock DSLRecord to store.DSLVersion
-			// Parse time string (assuming RFC3339 format)
-			createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
-			if err != nil {
-				createdAt = time.Now() // fallback
-			}
+	for _, v := range m.versions(cbuID) {
+		history = append(history, store.DSLVersion{VersionID: v.VersionID, DSLText: v.DSLText, CreatedAt: v.CreatedAt})
+	}
+	return history, nil
+}
 
-			version := store.DSLVersion{
-				VersionID: record.VersionID,
-				DSLText:   record.DSLText,
-				CreatedAt: createdAt,
-			}
-			history = append(history, version)
+// versions returns a CBU's DSL versions, oldest first: the mock data records
+// in file order, numbered from 1, followed by the versions inserted since
+// the store was created
+func (m *MockStore) versions(cbuID string) []store.DSLVersionWithState {
+	var versions []store.DSLVersionWithState
+	for _, record := range m.dslRecords {
+		if record.CBUID != cbuID {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+		if err != nil {
+			createdAt = time.Now() // fallback
+		}
+		versions = append(versions, store.DSLVersionWithState{
+			VersionID:       record.VersionID,
+			CBUID:           record.CBUID,
+			DSLText:         record.DSLText,
+			OnboardingState: store.StateCreated, // Default state for mock data
+			VersionNumber:   len(versions) + 1,
+			CreatedAt:       createdAt,
+		})
+	}
+	for _, dslVersion := range m.dynamicDSLVersions {
+		if dslVersion.CBUID == cbuID {
+			versions = append(versions, dslVersion)
 		}
 	}
-
-	return history, nil
+	return versions
 }
 
-// Enhanced Onboarding State Management for mock adapter
+// Onboarding State Management
+
+// CreateOnboardingSession starts the CBU's onboarding session in the
+// CREATED state; a CBU has at most one
 func (m *MockStore) CreateOnboardingSession(ctx context.Context, cbuID string) (*store.OnboardingSession, error) {
-	// For mock store, create a mock onboarding session
-	session := &store.OnboardingSession{
-		OnboardingID:       fmt.Sprintf("mock-onboarding-%d", time.Now().Unix()),
-		CBUID:              cbuID,
-		CurrentState:       store.StateCreated,
-		CurrentVersion:     1,
-		LatestDSLVersionID: nil,
-		CreatedAt:          time.Now(),
-		UpdatedAt:          time.Now(),
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return nil, err
 	}
-	return session, nil
+	if m.onboardingSession(cbuID) != nil {
+		return nil, fmt.Errorf("failed to create onboarding session: CBU %s already has one", cbuID)
+	}
+
+	now := time.Now()
+	session := &store.OnboardingSession{
+		OnboardingID:   uuid.New().String(),
+		CBUID:          cbuID,
+		CurrentState:   store.StateCreated,
+		CurrentVersion: 1,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	m.onboardingSessions[cbuID] = session
+	found := *session
+	return &found, nil
 }
 
 func (m *MockStore) GetOnboardingSession(ctx context.Context, cbuID string) (*store.OnboardingSession, error) {
-	// For mock store, return a mock onboarding session
-	session := &store.OnboardingSession{
-		OnboardingID:       "mock-onboarding-session",
-		CBUID:              cbuID,
-		CurrentState:       store.StateCreated,
-		CurrentVersion:     1,
-		LatestDSLVersionID: nil,
-		CreatedAt:          time.Now().Add(-24 * time.Hour),
-		UpdatedAt:          time.Now(),
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return nil, err
+	}
+	if session := m.onboardingSession(cbuID); session != nil {
+		found := *session
+		return &found, nil
 	}
-	return session, nil
+	return nil, store.NotFoundf("no onboarding session found for CBU: %s", cbuID)
 }
 
+// UpdateOnboardingState moves the CBU's session to newState at the given DSL
+// version and records an ONBOARDING_STATE_CHANGED outbox event; moves the
+// onboarding state machine does not allow fail with store.ErrInvalidTransition
 func (m *MockStore) UpdateOnboardingState(ctx context.Context, cbuID string, newState store.OnboardingState, dslVersionID string) error {
-	// For mock store, we don't actually update - just return success
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.loadData(); err != nil {
+		return err
+	}
+	session := m.onboardingSession(cbuID)
+	if session == nil {
+		return store.NotFoundf("no onboarding session found for CBU: %s", cbuID)
+	}
+
+	var version *store.DSLVersionWithState
+	previousText := ""
+	for _, candidate := range m.versions(cbuID) {
+		if candidate.VersionID == dslVersionID {
+			version = &candidate
+			break
+		}
+		previousText = candidate.DSLText
+	}
+	if version == nil {
+		return store.NotFoundf("DSL version not found: %s", dslVersionID)
+	}
+
+	oldState := session.CurrentState
+	if err := oldState.ValidateTransition(newState); err != nil {
+		return err
+	}
+	session.CurrentState = newState
+	session.CurrentVersion++
+	session.LatestDSLVersionID = &dslVersionID
+	session.UpdatedAt = time.Now()
+
+	m.recordDSLChange(store.DSLChangeEvent{
+		EventType:     store.EventOnboardingStateChanged,
+		CBUID:         cbuID,
+		VersionID:     dslVersionID,
+		VersionNumber: version.VersionNumber,
+		OldState:      oldState,
+		NewState:      newState,
+		ChangedVerbs:  store.ChangedVerbs(previousText, version.DSLText),
+	})
 	return nil
 }
 
+// onboardingSession returns the CBU's session, or nil. A CBU with DSL in the
+// mock data but no session yet gets one in the CREATED state at its latest
+// mock version, so that commands run against the mock data in separate
+// processes find a session. The caller holds m.mu and has loaded the data.
+func (m *MockStore) onboardingSession(cbuID string) *store.OnboardingSession {
+	if session, ok := m.onboardingSessions[cbuID]; ok {
+		return session
+	}
+	if m.onboardingSessions == nil {
+		m.onboardingSessions = make(map[string]*store.OnboardingSession)
+	}
+
+	var session *store.OnboardingSession
+	for _, record := range m.dslRecords {
+		if record.CBUID != cbuID {
+			continue
+		}
+		if session == nil {
+			createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+			if err != nil {
+				createdAt = time.Now() // fallback
+			}
+			session = &store.OnboardingSession{
+				OnboardingID: "mock-onboarding-" + cbuID,
+				CBUID:        cbuID,
+				CurrentState: store.StateCreated,
+				CreatedAt:    createdAt,
+				UpdatedAt:    createdAt,
+			}
+		}
+		versionID := record.VersionID
+		session.CurrentVersion++
+		session.LatestDSLVersionID = &versionID
+	}
+	if session != nil {
+		m.onboardingSessions[cbuID] = session
+	}
+	return session
+}
+
+// InsertDSLWithState records a DSL version in memory, unlike InsertDSL
 func (m *MockStore) InsertDSLWithState(ctx context.Context, cbuID, dslText string, state store.OnboardingState) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertDSLVersion(cbuID, dslText, state, "")
+}
+
+// InsertDSLWithGrammar records a DSL version in memory along with the grammar version that validated it
+func (m *MockStore) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertDSLVersion(cbuID, dslText, state, grammarVersion)
+}
+
+// insertDSLVersion records a DSL version and its outbox event, rejecting
+// states the CBU's onboarding session cannot move to; the caller holds m.mu
+func (m *MockStore) insertDSLVersion(cbuID, dslText string, state store.OnboardingState, grammarVersion string) (string, error) {
+	if err := m.loadData(); err != nil {
+		return "", err
+	}
+	if session := m.onboardingSession(cbuID); session != nil {
+		if err := session.CurrentState.ValidateTransition(state); err != nil {
+			return "", err
+		}
+	}
+
 	// Increment version counter
 	m.versionCounter++
 
@@ -603,155 +1223,98 @@ func (m *MockStore) InsertDSLWithState(ctx context.Context, cbuID, dslText strin
 		DSLText:         dslText,
 		OnboardingState: state,
 		VersionNumber:   m.getNextVersionNumber(cbuID),
+		GrammarVersion:  grammarVersion,
 		CreatedAt:       time.Now(),
 	}
 
+	event := store.DSLChangeEvent{
+		EventType:     store.EventDSLVersionCreated,
+		CBUID:         cbuID,
+		VersionID:     versionID,
+		VersionNumber: dslVersion.VersionNumber,
+		NewState:      state,
+		ChangedVerbs:  store.ChangedVerbs("", dslText),
+	}
+	for i := len(m.dynamicDSLVersions) - 1; i >= 0; i-- {
+		if previous := m.dynamicDSLVersions[i]; previous.CBUID == cbuID {
+			event.OldState = previous.OnboardingState
+			event.ChangedVerbs = store.ChangedVerbs(previous.DSLText, dslText)
+			break
+		}
+	}
+
 	// Store in memory
 	m.dynamicDSLVersions = append(m.dynamicDSLVersions, dslVersion)
+	m.recordDSLChange(event)
 
 	return versionID, nil
 }
 
 func (m *MockStore) GetLatestDSLWithState(ctx context.Context, cbuID string) (*store.DSLVersionWithState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
 
-	// Find the latest DSL record for this CBU
-	var latest DSLRecord
-	var found bool
-
-	for _, record := range m.dslRecords {
-		if record.CBUID == cbuID {
-			if !found {
-				latest = record
-				found = true
-			} else {
-				// Simple string comparison for mock - in real DB this would use timestamp
-				if record.CreatedAt > latest.CreatedAt {
-					latest = record
-				}
-			}
-		}
-	}
-
-	if !found {
-		return nil, fmt.Errorf("no DSL found for CBU: %s", cbuID)
-	}
-
-	// Parse time string
-	createdAt, err := time.Parse(time.RFC3339, latest.CreatedAt)
-	if err != nil {
-		createdAt = time.Now() // fallback
+	versions := m.versions(cbuID)
+	if len(versions) == 0 {
+		return nil, store.NotFoundf("no DSL found for CBU: %s", cbuID)
 	}
-
-	// Create DSLVersionWithState from mock data
-	dslVersion := &store.DSLVersionWithState{
-		VersionID:       latest.VersionID,
-		CBUID:           latest.CBUID,
-		DSLText:         latest.DSLText,
-		OnboardingState: store.StateCreated, // Default state for mock
-		VersionNumber:   1,                  // Default version for mock
-		CreatedAt:       createdAt,
-	}
-
-	return dslVersion, nil
+	latest := versions[len(versions)-1]
+	return &latest, nil
 }
 
 func (m *MockStore) GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]store.DSLVersionWithState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
+	return m.versions(cbuID), nil
+}
 
-	var history []store.DSLVersionWithState
-
-	// First add static mock data
-	version := 1
-	for _, record := range m.dslRecords {
-		if record.CBUID == cbuID {
-			// Parse time string
-			createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
-			if err != nil {
-				createdAt = time.Now()
-			}
+func (m *MockStore) GetDSLByVersion(ctx context.Context, cbuID string, versionNumber int) (*store.DSLVersionWithState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-			history = append(history, store.DSLVersionWithState{
-				VersionID:       record.VersionID,
-				CBUID:           record.CBUID,
-				DSLText:         record.DSLText,
-				OnboardingState: store.StateCreated,
-				VersionNumber:   version,
-				CreatedAt:       createdAt,
-			})
-			version++
-		}
+	if err := m.loadData(); err != nil {
+		return nil, err
 	}
 
-	// Add dynamic DSL versions created during runtime
-	for _, dslVersion := range m.dynamicDSLVersions {
-		if dslVersion.CBUID == cbuID {
-			history = append(history, dslVersion)
+	for _, v := range m.versions(cbuID) {
+		if v.VersionNumber == versionNumber {
+			return &v, nil
 		}
 	}
-
-	return history, nil
+	return nil, store.NotFoundf("no DSL version %d found for CBU: %s", versionNumber, cbuID)
 }
 
-func (m *MockStore) GetDSLByVersion(ctx context.Context, cbuID string, versionNumber int) (*store.DSLVersionWithState, error) {
+// ListOnboardingSessions returns all onboarding sessions, most recently
+// updated first
+func (m *MockStore) ListOnboardingSessions(ctx context.Context) ([]store.OnboardingSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
-
-	// For mock store, just return the first record matching CBU if version exists
-	currentVersion := 1
 	for _, record := range m.dslRecords {
-		if record.CBUID == cbuID {
-			if currentVersion == versionNumber {
-				// Parse time string
-				createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
-				if err != nil {
-					createdAt = time.Now() // fallback
-				}
-
-				dslVersion := &store.DSLVersionWithState{
-					VersionID:       record.VersionID,
-					CBUID:           record.CBUID,
-					DSLText:         record.DSLText,
-					OnboardingState: store.StateCreated, // Default state for mock
-					VersionNumber:   versionNumber,
-					CreatedAt:       createdAt,
-				}
-				return dslVersion, nil
-			}
-			currentVersion++
-		}
+		m.onboardingSession(record.CBUID)
 	}
 
-	return nil, fmt.Errorf("no DSL version %d found for CBU: %s", versionNumber, cbuID)
-}
-
-func (m *MockStore) ListOnboardingSessions(ctx context.Context) ([]store.OnboardingSession, error) {
-	// For mock store, return a list of mock sessions
-	sessions := []store.OnboardingSession{
-		{
-			OnboardingID:       "mock-session-1",
-			CBUID:              "CBU-1234",
-			CurrentState:       store.StateCreated,
-			CurrentVersion:     1,
-			LatestDSLVersionID: nil,
-			CreatedAt:          time.Now().Add(-24 * time.Hour),
-			UpdatedAt:          time.Now(),
-		},
-		{
-			OnboardingID:       "mock-session-2",
-			CBUID:              "CBU-5678",
-			CurrentState:       store.StateProductsAdded,
-			CurrentVersion:     2,
-			LatestDSLVersionID: nil,
-			CreatedAt:          time.Now().Add(-12 * time.Hour),
-			UpdatedAt:          time.Now().Add(-1 * time.Hour),
-		},
+	sessions := make([]store.OnboardingSession, 0, len(m.onboardingSessions))
+	for _, session := range m.onboardingSessions {
+		sessions = append(sessions, *session)
 	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if !sessions[i].UpdatedAt.Equal(sessions[j].UpdatedAt) {
+			return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+		}
+		return sessions[i].CBUID < sessions[j].CBUID
+	})
 	return sessions, nil
 }
 
@@ -782,6 +1345,9 @@ func (m *MockStore) getNextVersionNumber(cbuID string) int {
 
 // GetAllServices returns all services from mock data
 func (m *MockStore) GetAllServices(ctx context.Context) ([]store.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -790,6 +1356,9 @@ func (m *MockStore) GetAllServices(ctx context.Context) ([]store.Service, error)
 
 // GetAllDictionaryAttributes returns all dictionary attributes from mock data
 func (m *MockStore) GetAllDictionaryAttributes(ctx context.Context) ([]dictionary.Attribute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -817,6 +1386,9 @@ func (m *MockStore) GetAllDictionaryAttributes(ctx context.Context) ([]dictionar
 
 // GetAllDSLRecords returns all DSL records with state information from mock data
 func (m *MockStore) GetAllDSLRecords(ctx context.Context) ([]store.DSLVersionWithState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if err := m.loadData(); err != nil {
 		return nil, err
 	}
@@ -2,7 +2,15 @@ package mocks
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+
+	"dsl-ob-poc/internal/store"
 )
 
 func TestMockStore_DisconnectedOperation(t *testing.T) {
@@ -105,3 +113,71 @@ func TestMockStore_DisconnectedOperation(t *testing.T) {
 	t.Logf("Latest DSL length: %d characters", len(dsl))
 	t.Logf("Resolved value: %s, state: %s", string(value), state)
 }
+
+// emptyMockData writes an empty mock data set to a temporary directory
+func emptyMockData(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{
+		"cbus.json", "roles.json", "entity_types.json", "entities.json", "entity_limited_companies.json",
+		"entity_partnerships.json", "cbu_entity_roles.json", "products.json", "services.json",
+		"prod_resources.json", "product_services.json", "service_resources.json", "dictionary.json", "dsl_ob.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestMockStore_ConcurrentAttributeValues(t *testing.T) {
+	mockStore := NewMockStore(emptyMockData(t))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			attributeID := fmt.Sprintf("INV.STATE.%d", i)
+			if err := mockStore.UpsertAttributeValue(ctx, "CBU-1", 0, attributeID, json.RawMessage(`{"status":"ACTIVE"}`), "resolved", nil); err != nil {
+				t.Errorf("Failed to upsert value: %v", err)
+				return
+			}
+			if _, _, state, err := mockStore.ResolveValueFor(ctx, "CBU-1", attributeID); err != nil || state != "resolved" {
+				t.Errorf("Expected resolved value for %s, got %q (%v)", attributeID, state, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMockStore_CBUWrites(t *testing.T) {
+	mockStore := NewMockStore(emptyMockData(t))
+	ctx := context.Background()
+
+	cbuID, err := mockStore.CreateCBU(ctx, "Beta Fund", "Hedge fund", "Investment")
+	if err != nil {
+		t.Fatalf("Failed to create CBU: %v", err)
+	}
+	if cbu, err := mockStore.GetCBUByID(ctx, cbuID); err != nil || cbu.Name != "Beta Fund" {
+		t.Fatalf("Expected created CBU, got %+v (%v)", cbu, err)
+	}
+
+	if err := mockStore.UpdateCBU(ctx, cbuID, "Beta Fund II", "Hedge fund", "Investment"); err != nil {
+		t.Fatalf("Failed to update CBU: %v", err)
+	}
+	if cbu, _ := mockStore.GetCBUByID(ctx, cbuID); cbu.Name != "Beta Fund II" {
+		t.Errorf("Expected updated name, got %q", cbu.Name)
+	}
+
+	if err := mockStore.DeleteCBU(ctx, cbuID); err != nil {
+		t.Fatalf("Failed to delete CBU: %v", err)
+	}
+	if _, err := mockStore.GetCBUByID(ctx, cbuID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := mockStore.UpdateCBU(ctx, cbuID, "x", "", ""); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound updating a deleted CBU, got %v", err)
+	}
+}
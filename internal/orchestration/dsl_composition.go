@@ -751,11 +751,19 @@ func (ce *DSLCompositionEngine) validateComposedDSL(masterDSL string, components
 func (ce *DSLCompositionEngine) orderComponentsByExecution(components map[string]string, execPlan *CompositionExecutionPlan) []string {
 	var orderedComponents []string
 
+	// Component names are visited in sorted order so that the master DSL is
+	// the same on every composition of the same request
+	names := make([]string, 0, len(components))
+	for componentName := range components {
+		names = append(names, componentName)
+	}
+	sort.Strings(names)
+
 	// Add components in execution order
 	for _, stage := range execPlan.Stages {
 		for _, domain := range stage.Domains {
 			// Map domain names to component names
-			for componentName := range components {
+			for _, componentName := range names {
 				if strings.Contains(componentName, domain) ||
 					strings.Contains(domain, componentName) ||
 					componentName == "entity" ||
@@ -767,7 +775,7 @@ func (ce *DSLCompositionEngine) orderComponentsByExecution(components map[string
 	}
 
 	// Add any remaining components
-	for componentName := range components {
+	for _, componentName := range names {
 		found := false
 		for _, existing := range orderedComponents {
 			if existing == componentName {
@@ -694,11 +694,52 @@ func (ove *OrchestrationVerbExecutor) executeWorkflowCoordinateParallel(ctx cont
 		Success:       true,
 	}
 
+	if timeout, ok := params["timeout"].(string); ok && timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	// Run the session's execution plan across its domains
+	run, err := ove.orchestrator.ExecutePlan(ctx, execCtx.SessionID)
+	if run == nil {
+		return nil, fmt.Errorf("parallel execution failed: %w", err)
+	}
+
+	status := "COMPLETED"
+	switch {
+	case err != nil:
+		status = "INTERRUPTED"
+		result.Success = false
+		result.Errors = append(result.Errors, fmt.Sprintf("parallel execution interrupted: %v", err))
+	case !run.Success():
+		status = "FAILED"
+		result.Success = false
+		result.Errors = append(result.Errors, run.Errors...)
+	}
+	result.Warnings = append(result.Warnings, run.Warnings...)
+	result.ResultData["plan_execution"] = run
+
+	for _, task := range run.Tasks {
+		result.DomainUpdates[task.Domain] = fmt.Sprintf("Task %s: %s", task.TaskID, task.Status)
+		if task.Status == TaskStatusPending {
+			result.NextActions = append(result.NextActions, fmt.Sprintf("resume_%s", task.Domain))
+		}
+	}
+
 	dslFragment := fmt.Sprintf(`; Parallel workflows coordinated
 (workflow.parallel.coordinated
+  (tasks.completed %d)
+  (tasks.failed %d)
+  (tasks.skipped %d)
+  (tasks.pending %d)
   (coordinated.at "%s")
-  (status "INITIATED")
-)`, time.Now().Format(time.RFC3339))
+  (status "%s")
+)`, run.Completed, run.Failed, run.Skipped, run.Pending, time.Now().Format(time.RFC3339), status)
 
 	result.GeneratedDSL = dslFragment
 	return result, nil
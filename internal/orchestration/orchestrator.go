@@ -20,6 +20,7 @@ package orchestration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -32,6 +33,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrSessionNotFound is returned for unknown orchestration session IDs
+var ErrSessionNotFound = errors.New("orchestration session not found")
+
 // Orchestrator coordinates multi-domain DSL workflows
 type Orchestrator struct {
 	registry       *registry.Registry
@@ -55,6 +59,9 @@ type Orchestrator struct {
 	// Configuration
 	config *OrchestratorConfig
 
+	// Runs the tasks of an execution plan; see SetTaskRunner
+	taskRunner TaskRunner
+
 	// Metrics
 	metrics   *OrchestratorMetrics
 	startTime time.Time
@@ -87,7 +94,8 @@ type OrchestrationSession struct {
 	EntityRefs    map[string]string `json:"entity_refs"`    // entity_type -> UUID
 	AttributeRefs map[string]string `json:"attribute_refs"` // attr_name -> attr_id (UUID)
 
-	mu sync.RWMutex
+	mu          sync.RWMutex
+	planRunning bool // ExecutePlan is working through PendingTasks
 }
 
 // DomainSession represents a domain-specific session within orchestration
@@ -173,6 +181,7 @@ type OrchestrationTask struct {
 	CompletedAt  *time.Time             `json:"completed_at,omitempty"`
 	Error        string                 `json:"error,omitempty"`
 	GeneratedDSL string                 `json:"generated_dsl,omitempty"`
+	Resources    map[string]string      `json:"resources,omitempty"` // resource ID -> wait condition reached
 }
 
 // StateTransition tracks state changes in the orchestration
@@ -191,6 +200,7 @@ type OrchestratorConfig struct {
 	SessionTimeout        time.Duration `json:"session_timeout"`
 	EnableOptimization    bool          `json:"enable_optimization"`
 	EnableParallelExec    bool          `json:"enable_parallel_execution"`
+	MaxParallelTasks      int           `json:"max_parallel_tasks"`      // Tasks run at once by ExecutePlan
 	MaxDomainDepth        int           `json:"max_domain_depth"`        // Max dependency depth
 	ContextPropagationTTL time.Duration `json:"context_propagation_ttl"` // How long context is valid
 }
@@ -332,6 +342,7 @@ func DefaultOrchestratorConfig() *OrchestratorConfig {
 		SessionTimeout:        24 * time.Hour,
 		EnableOptimization:    true,
 		EnableParallelExec:    true,
+		MaxParallelTasks:      DefaultMaxParallelTasks,
 		MaxDomainDepth:        5,
 		ContextPropagationTTL: 1 * time.Hour,
 	}
@@ -759,7 +770,7 @@ func (o *Orchestrator) GetOrchestrationSession(sessionID string) (*Orchestration
 		return session, nil
 	}
 
-	return nil, fmt.Errorf("orchestration session not found: %s", sessionID)
+	return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
 }
 
 // ExecuteInstruction processes a natural language instruction across domains
@@ -826,7 +837,6 @@ func (o *Orchestrator) accumulateDSL(ctx context.Context, session *Orchestration
 	}
 
 	session.mu.Lock()
-	defer session.mu.Unlock()
 
 	// Store domain-specific DSL
 	session.DomainDSL[domainName] = dsl
@@ -846,8 +856,10 @@ func (o *Orchestrator) accumulateDSL(ctx context.Context, session *Orchestration
 		domainSession.ContributedDSL = dsl
 		domainSession.LastActivity = time.Now()
 	}
+	session.mu.Unlock()
 
-	// Persist changes if store is available
+	// Persist changes if store is available; SaveSession takes the session's
+	// read lock itself
 	if o.sessionStore != nil {
 		if err := o.sessionStore.SaveSession(ctx, session); err != nil {
 			return fmt.Errorf("failed to persist DSL accumulation: %w", err)
@@ -1296,15 +1308,18 @@ func (o *Orchestrator) GetSessionStatus(sessionID string) (*SessionStatus, error
 		LastUsed:       session.LastUsed,
 		VersionNumber:  session.VersionNumber,
 		ActiveDomains:  make([]DomainStatus, 0),
-		PendingTasks:   len(session.PendingTasks),
+		PendingTasks:   0,
 		CompletedTasks: 0,
 		UnifiedDSLSize: len(session.UnifiedDSL),
 	}
 
-	// Count completed tasks
+	// Count completed and outstanding tasks
 	for _, task := range session.PendingTasks {
-		if task.Status == TaskStatusCompleted {
+		switch task.Status {
+		case TaskStatusCompleted:
 			status.CompletedTasks++
+		case TaskStatusPending, TaskStatusScheduled, TaskStatusRunning:
+			status.PendingTasks++
 		}
 	}
 
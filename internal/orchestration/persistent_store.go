@@ -139,6 +139,17 @@ func (s *PersistentOrchestrationStore) convertToSessionData(session *Orchestrati
 		json.Unmarshal(planData, &sessionData.ExecutionPlan)
 	}
 
+	// Plan tasks travel with the plan so ExecutePlan can resume after a restart
+	if len(session.PendingTasks) > 0 {
+		if sessionData.ExecutionPlan == nil {
+			sessionData.ExecutionPlan = make(map[string]interface{})
+		}
+		taskData, _ := json.Marshal(session.PendingTasks)
+		var tasks []interface{}
+		json.Unmarshal(taskData, &tasks)
+		sessionData.ExecutionPlan["tasks"] = tasks
+	}
+
 	// Convert DomainSessions
 	for domainName, domainSession := range session.ActiveDomains {
 		domainData := store.DomainSessionData{
@@ -239,6 +250,12 @@ func (s *PersistentOrchestrationStore) convertFromSessionData(sessionData *store
 		session.ExecutionPlan = &executionPlan
 	}
 
+	// Restore plan tasks and their progress
+	if tasks, ok := sessionData.ExecutionPlan["tasks"]; ok {
+		taskData, _ := json.Marshal(tasks)
+		json.Unmarshal(taskData, &session.PendingTasks)
+	}
+
 	// Convert DomainSessions
 	for _, domainData := range sessionData.DomainSessions {
 		domainSession := &DomainSession{
@@ -0,0 +1,702 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	registry "dsl-ob-poc/internal/domain-registry"
+)
+
+// DefaultMaxParallelTasks is how many plan tasks ExecutePlan runs at once
+// when the configuration does not say
+const DefaultMaxParallelTasks = 4
+
+// Conditions a ResourceDep waits for, in the order a resource reaches them
+const (
+	WaitConditionCreated   = "created"
+	WaitConditionValidated = "validated"
+	WaitConditionApproved  = "approved"
+)
+
+var waitConditionRank = map[string]int{
+	WaitConditionCreated:   1,
+	WaitConditionValidated: 2,
+	WaitConditionApproved:  3,
+}
+
+// ErrPlanRunning is returned by ExecutePlan while another call is already
+// working through the same session's plan
+var ErrPlanRunning = errors.New("execution plan is already running")
+
+// TaskResult is what running one plan task produced
+type TaskResult struct {
+	DSL       string            // DSL contributed to the task's domain
+	State     string            // Domain state after the task; empty leaves it unchanged
+	Resources map[string]string // Resource ID -> wait condition it reached
+}
+
+// TaskRunner runs one task of an execution plan. It must return once ctx is
+// cancelled; an error returned after cancellation leaves the task pending
+// for the next ExecutePlan call.
+type TaskRunner func(ctx context.Context, session *OrchestrationSession, task OrchestrationTask) (*TaskResult, error)
+
+// PlanExecutionResult summarises an ExecutePlan call
+type PlanExecutionResult struct {
+	SessionID string              `json:"session_id"`
+	Tasks     []OrchestrationTask `json:"tasks"`
+	Stages    []ExecutionStage    `json:"stages"`
+	Completed int                 `json:"completed"`
+	Failed    int                 `json:"failed"`
+	Skipped   int                 `json:"skipped"`
+	Pending   int                 `json:"pending"` // Left for a later run, e.g. after cancellation
+	Errors    []string            `json:"errors,omitempty"`
+	Warnings  []string            `json:"warnings,omitempty"`
+	StartTime time.Time           `json:"start_time"`
+	EndTime   time.Time           `json:"end_time"`
+	Duration  time.Duration       `json:"duration"`
+}
+
+// Success reports whether every task of the plan has completed
+func (r *PlanExecutionResult) Success() bool {
+	return r.Completed == len(r.Tasks)
+}
+
+// SetTaskRunner replaces how ExecutePlan runs tasks; nil restores the
+// default, which asks each task's domain to generate DSL for the task's
+// instruction
+func (o *Orchestrator) SetTaskRunner(runner TaskRunner) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.taskRunner = runner
+}
+
+// ExecutePlan runs the session's execution plan as a graph of tasks, one per
+// domain and stage. A task starts once the tasks it depends on have completed
+// and every ResourceDep targeting its domain has reached its wait condition;
+// up to MaxParallelTasks run at once (one when parallel execution is
+// disabled). A failed task skips the tasks that depend on it while
+// independent branches carry on. Plans waiting for a condition the task
+// runner never reports, such as approval under the default runner, are
+// rejected before anything runs. Task and stage progress is saved to the
+// session store as tasks finish, so a call cancelled through ctx, or one
+// interrupted by a restart, resumes where it stopped.
+func (o *Orchestrator) ExecutePlan(ctx context.Context, sessionID string) (*PlanExecutionResult, error) {
+	session, err := o.GetOrchestrationSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.RLock()
+	runner := o.taskRunner
+	o.mu.RUnlock()
+	reachable := WaitConditionApproved
+	if runner == nil {
+		runner = o.runDomainTask
+		reachable = WaitConditionValidated
+	}
+
+	if err := preparePlan(session, reachable); err != nil {
+		return nil, err
+	}
+	defer func() {
+		session.mu.Lock()
+		session.planRunning = false
+		session.mu.Unlock()
+	}()
+
+	s := &planScheduler{
+		o:       o,
+		session: session,
+		runner:  runner,
+		limit:   o.maxParallelTasks(),
+		index:   make(map[string]int, len(session.PendingTasks)),
+		reached: make(map[string]string),
+		result:  &PlanExecutionResult{SessionID: sessionID, StartTime: time.Now()},
+	}
+	return s.run(ctx)
+}
+
+// maxParallelTasks returns how many plan tasks may run at once
+func (o *Orchestrator) maxParallelTasks() int {
+	if !o.config.EnableParallelExec {
+		return 1
+	}
+	if o.config.MaxParallelTasks > 0 {
+		return o.config.MaxParallelTasks
+	}
+	return DefaultMaxParallelTasks
+}
+
+// preparePlan builds the session's tasks on first use, checks the task graph
+// against the furthest wait condition the runner reports and claims the plan
+// for one ExecutePlan call
+func preparePlan(session *OrchestrationSession, reachable string) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.planRunning {
+		return fmt.Errorf("session %s: %w", session.SessionID, ErrPlanRunning)
+	}
+	if session.ExecutionPlan == nil {
+		return fmt.Errorf("session %s has no execution plan", session.SessionID)
+	}
+
+	if len(session.PendingTasks) == 0 {
+		tasks, err := planTasks(session)
+		if err != nil {
+			return err
+		}
+		session.PendingTasks = tasks
+	}
+	if err := checkTaskGraph(session.PendingTasks, session.ExecutionPlan.ResourceDependencies, reachable); err != nil {
+		return fmt.Errorf("invalid execution plan for session %s: %w", session.SessionID, err)
+	}
+
+	// Tasks that were running when an earlier call stopped start over
+	for i := range session.PendingTasks {
+		task := &session.PendingTasks[i]
+		if task.Status == TaskStatusRunning || task.Status == TaskStatusScheduled {
+			task.Status = TaskStatusPending
+			task.StartedAt = nil
+		}
+	}
+
+	session.planRunning = true
+	return nil
+}
+
+// planTasks turns the stages of the session's plan into tasks, one per
+// domain and stage. A domain listed in several stages runs once per stage,
+// each run after the one before it. A task depends on every task of the
+// stages listed as its stage's prerequisites and on one task of each domain
+// its domain depends on: that domain's latest task in the same or an earlier
+// stage, or its first task when it only appears in later stages.
+func planTasks(session *OrchestrationSession) ([]OrchestrationTask, error) {
+	plan := session.ExecutionPlan
+	now := time.Now()
+
+	domainTasks := make(map[string][]int)   // domain -> task positions, in stage order
+	stageTasks := make(map[string][]string) // stage name -> task IDs
+	var stageOf []int                       // task position -> stage position
+	var tasks []OrchestrationTask
+	for position, stage := range plan.Stages {
+		for _, domain := range stage.Domains {
+			taskID := stage.Name + "/" + domain
+			for _, id := range stageTasks[stage.Name] {
+				if id == taskID {
+					return nil, fmt.Errorf("domain %s appears more than once in stage %s of the execution plan", domain, stage.Name)
+				}
+			}
+			domainTasks[domain] = append(domainTasks[domain], len(tasks))
+			stageTasks[stage.Name] = append(stageTasks[stage.Name], taskID)
+			stageOf = append(stageOf, position)
+			tasks = append(tasks, OrchestrationTask{
+				TaskID: taskID,
+				Domain: domain,
+				Verb:   "workflow.execute.subdomain",
+				Parameters: map[string]interface{}{
+					"stage":       stage.Name,
+					"instruction": fmt.Sprintf("execute %s workflow", domain),
+				},
+				Status:      TaskStatusPending,
+				ScheduledAt: now,
+			})
+		}
+	}
+
+	prerequisites := make(map[string][]string, len(plan.Stages))
+	for _, stage := range plan.Stages {
+		prerequisites[stage.Name] = stage.Prerequisites
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		deps := make(map[string]bool)
+		addDomain := func(domain string) {
+			if domain == task.Domain {
+				return
+			}
+			positions := domainTasks[domain]
+			if len(positions) == 0 {
+				return
+			}
+			pick := positions[0]
+			for _, position := range positions {
+				if stageOf[position] <= stageOf[i] {
+					pick = position
+				}
+			}
+			deps[tasks[pick].TaskID] = true
+		}
+
+		// Each run of a domain follows its run in the previous stage
+		for _, position := range domainTasks[task.Domain] {
+			if stageOf[position] < stageOf[i] {
+				deps[tasks[position].TaskID] = true
+			}
+		}
+		for _, domain := range plan.Dependencies[task.Domain] {
+			addDomain(domain)
+		}
+		if domainSession, ok := session.ActiveDomains[task.Domain]; ok {
+			for _, domain := range domainSession.Dependencies {
+				addDomain(domain)
+			}
+		}
+		// Composed plans list prerequisite domains rather than stages
+		for _, prerequisite := range prerequisites[taskStage(*task)] {
+			if ids, ok := stageTasks[prerequisite]; ok {
+				for _, id := range ids {
+					if id != task.TaskID {
+						deps[id] = true
+					}
+				}
+				continue
+			}
+			addDomain(prerequisite)
+		}
+
+		task.Dependencies = make([]string, 0, len(deps))
+		for id := range deps {
+			task.Dependencies = append(task.Dependencies, id)
+		}
+		sort.Strings(task.Dependencies)
+	}
+
+	return tasks, nil
+}
+
+// checkTaskGraph rejects unknown dependencies, unknown wait conditions,
+// wait conditions beyond the furthest one the runner reports and cycles,
+// counting a resource dependency as an edge from every task of the source
+// domain to every task of the target domain
+func checkTaskGraph(tasks []OrchestrationTask, resources []ResourceDep, reachable string) error {
+	byDomain := make(map[string][]string)
+	known := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		known[task.TaskID] = true
+		byDomain[task.Domain] = append(byDomain[task.Domain], task.TaskID)
+	}
+
+	edges := make(map[string][]string) // task ID -> task IDs it waits for
+	for _, task := range tasks {
+		for _, dep := range task.Dependencies {
+			if !known[dep] {
+				return fmt.Errorf("task %s depends on unknown task %s", task.TaskID, dep)
+			}
+			edges[task.TaskID] = append(edges[task.TaskID], dep)
+		}
+	}
+	for _, dep := range resources {
+		rank, ok := waitConditionRank[waitCondition(dep)]
+		if !ok {
+			return fmt.Errorf("resource %s has unknown wait condition %q", dep.ResourceID, dep.WaitCondition)
+		}
+		if rank > waitConditionRank[reachable] {
+			return fmt.Errorf("resource %s waits to be %s, but the task runner reports at most %s", dep.ResourceID, waitCondition(dep), reachable)
+		}
+		for _, target := range byDomain[dep.TargetDomain] {
+			edges[target] = append(edges[target], byDomain[dep.SourceDomain]...)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int, len(tasks))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch marks[id] {
+		case visiting:
+			return fmt.Errorf("dependency cycle through task %s", id)
+		case done:
+			return nil
+		}
+		marks[id] = visiting
+		for _, next := range edges[id] {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		marks[id] = done
+		return nil
+	}
+	for _, task := range tasks {
+		if err := visit(task.TaskID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planScheduler drives one ExecutePlan call. Only its run loop changes task
+// and stage state, always under the session lock; runners work on copies.
+type planScheduler struct {
+	o       *Orchestrator
+	session *OrchestrationSession
+	runner  TaskRunner
+	limit   int
+	index   map[string]int    // task ID -> position in session.PendingTasks
+	reached map[string]string // resource ID -> furthest wait condition reached
+	result  *PlanExecutionResult
+}
+
+// taskOutcome carries a runner's answer back to the run loop
+type taskOutcome struct {
+	taskID string
+	result *TaskResult
+	err    error
+}
+
+func (s *planScheduler) run(ctx context.Context) (*PlanExecutionResult, error) {
+	s.session.mu.Lock()
+	for i, task := range s.session.PendingTasks {
+		s.index[task.TaskID] = i
+		if task.Status == TaskStatusCompleted {
+			s.recordResources(task.Resources)
+		}
+	}
+	s.session.mu.Unlock()
+
+	outcomes := make(chan taskOutcome)
+	running := 0
+	for {
+		if ctx.Err() == nil {
+			running += s.startReady(ctx, outcomes, s.limit-running)
+		}
+		if running == 0 {
+			break
+		}
+		outcome := <-outcomes
+		running--
+		s.finish(ctx, outcome)
+	}
+
+	if ctx.Err() == nil {
+		s.skipStuck()
+	}
+	s.persist(ctx)
+	return s.summarise(), ctx.Err()
+}
+
+// startReady skips tasks that can no longer run, then starts up to slots
+// tasks whose dependencies and wait conditions are met. It returns how many
+// tasks it started.
+func (s *planScheduler) startReady(ctx context.Context, outcomes chan<- taskOutcome, slots int) int {
+	s.session.mu.Lock()
+	tasks := s.session.PendingTasks
+
+	for changed := true; changed; {
+		changed = false
+		for i := range tasks {
+			if tasks[i].Status != TaskStatusPending {
+				continue
+			}
+			if reason := s.blockedBy(tasks[i]); reason != "" {
+				s.skip(&tasks[i], reason)
+				changed = true
+			}
+		}
+	}
+
+	var started []OrchestrationTask
+	for i := range tasks {
+		if len(started) >= slots {
+			break
+		}
+		if tasks[i].Status != TaskStatusPending || !s.ready(tasks[i]) {
+			continue
+		}
+		now := time.Now()
+		tasks[i].Status = TaskStatusRunning
+		tasks[i].StartedAt = &now
+		tasks[i].Error = ""
+		started = append(started, tasks[i])
+	}
+	s.updateStages()
+	s.session.mu.Unlock()
+
+	for _, task := range started {
+		go func(task OrchestrationTask) {
+			result, err := s.runner(ctx, s.session, task)
+			outcomes <- taskOutcome{taskID: task.TaskID, result: result, err: err}
+		}(task)
+	}
+	return len(started)
+}
+
+// finish records a runner's outcome, accumulates the DSL it produced and
+// saves the session's progress
+func (s *planScheduler) finish(ctx context.Context, outcome taskOutcome) {
+	s.session.mu.Lock()
+	task := &s.session.PendingTasks[s.index[outcome.taskID]]
+	now := time.Now()
+	var dsl string
+
+	switch {
+	case outcome.err != nil && ctx.Err() != nil:
+		// Interrupted: leave the task for the next run
+		task.Status = TaskStatusPending
+		task.StartedAt = nil
+	case outcome.err != nil:
+		task.Status = TaskStatusFailed
+		task.CompletedAt = &now
+		task.Error = outcome.err.Error()
+		s.result.Errors = append(s.result.Errors, fmt.Sprintf("task %s failed: %v", task.TaskID, outcome.err))
+	default:
+		task.Status = TaskStatusCompleted
+		task.CompletedAt = &now
+		if outcome.result != nil {
+			dsl = outcome.result.DSL
+			task.GeneratedDSL = outcome.result.DSL
+			task.Resources = outcome.result.Resources
+			s.recordResources(task.Resources)
+			if domainSession, exists := s.session.ActiveDomains[task.Domain]; exists {
+				if outcome.result.State != "" {
+					domainSession.State = outcome.result.State
+				}
+				domainSession.LastActivity = now
+			}
+		}
+	}
+	domain := task.Domain
+	s.updateStages()
+	s.session.mu.Unlock()
+
+	if dsl == "" {
+		s.persist(ctx)
+		return
+	}
+	// accumulateDSL saves the session, task progress included
+	if err := s.o.accumulateDSL(context.WithoutCancel(ctx), s.session, domain, dsl); err != nil {
+		s.result.Warnings = append(s.result.Warnings, fmt.Sprintf("DSL accumulation warning: %v", err))
+	}
+}
+
+// skipStuck skips tasks still pending once nothing is running; the graph
+// check rules this out, so it only guards against hand-edited tasks
+func (s *planScheduler) skipStuck() {
+	s.session.mu.Lock()
+	defer s.session.mu.Unlock()
+	for i := range s.session.PendingTasks {
+		if s.session.PendingTasks[i].Status == TaskStatusPending {
+			s.skip(&s.session.PendingTasks[i], "blocked: dependencies can never be met")
+		}
+	}
+	s.updateStages()
+}
+
+// persist saves the session, even when ctx has been cancelled, so progress
+// made before the cancellation is kept
+func (s *planScheduler) persist(ctx context.Context) {
+	if s.o.sessionStore == nil {
+		return
+	}
+	if err := s.o.sessionStore.SaveSession(context.WithoutCancel(ctx), s.session); err != nil {
+		s.result.Warnings = append(s.result.Warnings, fmt.Sprintf("failed to persist plan progress: %v", err))
+	}
+}
+
+func (s *planScheduler) summarise() *PlanExecutionResult {
+	s.session.mu.RLock()
+	defer s.session.mu.RUnlock()
+
+	result := s.result
+	result.Tasks = append([]OrchestrationTask(nil), s.session.PendingTasks...)
+	result.Stages = append([]ExecutionStage(nil), s.session.ExecutionPlan.Stages...)
+	for _, task := range result.Tasks {
+		switch task.Status {
+		case TaskStatusCompleted:
+			result.Completed++
+		case TaskStatusFailed:
+			result.Failed++
+		case TaskStatusSkipped:
+			result.Skipped++
+		default:
+			result.Pending++
+		}
+	}
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	return result
+}
+
+// blockedBy explains why a pending task can never run, or returns ""
+func (s *planScheduler) blockedBy(task OrchestrationTask) string {
+	for _, dep := range task.Dependencies {
+		status := s.session.PendingTasks[s.index[dep]].Status
+		if status == TaskStatusFailed || status == TaskStatusSkipped {
+			return fmt.Sprintf("dependency %s %s", dep, strings.ToLower(string(status)))
+		}
+	}
+	for _, dep := range s.session.ExecutionPlan.ResourceDependencies {
+		if dep.TargetDomain == task.Domain && !s.satisfied(dep) && s.domainFinished(dep.SourceDomain) {
+			return fmt.Sprintf("resource %s from %s never became %s", dep.ResourceID, dep.SourceDomain, waitCondition(dep))
+		}
+	}
+	return ""
+}
+
+// ready reports whether a pending task's dependencies have completed and its
+// domain's wait conditions are met
+func (s *planScheduler) ready(task OrchestrationTask) bool {
+	for _, dep := range task.Dependencies {
+		if s.session.PendingTasks[s.index[dep]].Status != TaskStatusCompleted {
+			return false
+		}
+	}
+	for _, dep := range s.session.ExecutionPlan.ResourceDependencies {
+		if dep.TargetDomain == task.Domain && !s.satisfied(dep) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *planScheduler) skip(task *OrchestrationTask, reason string) {
+	now := time.Now()
+	task.Status = TaskStatusSkipped
+	task.CompletedAt = &now
+	task.Error = reason
+	s.result.Warnings = append(s.result.Warnings, fmt.Sprintf("task %s skipped: %s", task.TaskID, reason))
+}
+
+// satisfied reports whether a resource has reached the condition a
+// dependency waits for; reaching a later condition satisfies earlier ones
+func (s *planScheduler) satisfied(dep ResourceDep) bool {
+	reached, ok := s.reached[dep.ResourceID]
+	return ok && waitConditionRank[reached] >= waitConditionRank[waitCondition(dep)]
+}
+
+// domainFinished reports whether no task of a domain can still report
+// resource states
+func (s *planScheduler) domainFinished(domain string) bool {
+	for _, task := range s.session.PendingTasks {
+		if task.Domain == domain && (task.Status == TaskStatusPending || task.Status == TaskStatusRunning) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *planScheduler) recordResources(resources map[string]string) {
+	for id, condition := range resources {
+		if waitConditionRank[condition] > waitConditionRank[s.reached[id]] {
+			s.reached[id] = condition
+		}
+	}
+}
+
+// updateStages derives each stage's state from its tasks
+func (s *planScheduler) updateStages() {
+	counts := make(map[string]map[TaskStatus]int)
+	for _, task := range s.session.PendingTasks {
+		stage := taskStage(task)
+		if counts[stage] == nil {
+			counts[stage] = make(map[TaskStatus]int)
+		}
+		counts[stage][task.Status]++
+	}
+
+	stages := s.session.ExecutionPlan.Stages
+	for i := range stages {
+		c, ok := counts[stages[i].Name]
+		if !ok {
+			continue
+		}
+		total := 0
+		for _, n := range c {
+			total += n
+		}
+		switch {
+		case c[TaskStatusRunning] > 0 || c[TaskStatusScheduled] > 0:
+			stages[i].State = ExecutionStateRunning
+		case c[TaskStatusPending] == total:
+			stages[i].State = ExecutionStatePending
+		case c[TaskStatusPending] > 0:
+			stages[i].State = ExecutionStateRunning
+		case c[TaskStatusCompleted] == total:
+			stages[i].State = ExecutionStateCompleted
+		case c[TaskStatusSkipped] == total:
+			stages[i].State = ExecutionStateSkipped
+		default:
+			stages[i].State = ExecutionStateFailed
+		}
+	}
+}
+
+// runDomainTask is the default TaskRunner: the task's domain generates DSL
+// for the task's instruction. Resources the domain is the source of are
+// reported as validated when the generated DSL is valid and as created
+// otherwise; approval has to come from a runner that tracks it, so plans
+// waiting for approval are rejected under this runner.
+func (o *Orchestrator) runDomainTask(ctx context.Context, session *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+	domain, err := o.registry.Get(task.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("domain not found: %w", err)
+	}
+
+	instruction, _ := task.Parameters["instruction"].(string)
+	if instruction == "" {
+		instruction = fmt.Sprintf("execute %s workflow", task.Domain)
+	}
+
+	session.mu.RLock()
+	domainSession, exists := session.ActiveDomains[task.Domain]
+	if !exists {
+		session.mu.RUnlock()
+		return nil, fmt.Errorf("domain session not found: %s", task.Domain)
+	}
+	genReq := &registry.GenerationRequest{
+		Instruction:   instruction,
+		SessionID:     domainSession.SessionID,
+		CurrentDomain: task.Domain,
+		Context:       o.buildDomainContext(session, task.Domain),
+		ExistingDSL:   session.DomainDSL[task.Domain],
+		Timestamp:     time.Now(),
+	}
+	var produced []string
+	for _, dep := range session.ExecutionPlan.ResourceDependencies {
+		if dep.SourceDomain == task.Domain {
+			produced = append(produced, dep.ResourceID)
+		}
+	}
+	session.mu.RUnlock()
+
+	response, err := domain.GenerateDSL(ctx, genReq)
+	if err != nil {
+		return nil, fmt.Errorf("domain generation failed: %w", err)
+	}
+
+	condition := WaitConditionCreated
+	if response.IsValid {
+		condition = WaitConditionValidated
+	}
+	resources := make(map[string]string, len(produced))
+	for _, id := range produced {
+		resources[id] = condition
+	}
+
+	return &TaskResult{DSL: response.DSL, State: response.ToState, Resources: resources}, nil
+}
+
+// taskStage returns the plan stage a task was built from
+func taskStage(task OrchestrationTask) string {
+	stage, _ := task.Parameters["stage"].(string)
+	return stage
+}
+
+// waitCondition returns the condition a dependency waits for; "created"
+// when unset
+func waitCondition(dep ResourceDep) string {
+	if dep.WaitCondition == "" {
+		return WaitConditionCreated
+	}
+	return dep.WaitCondition
+}
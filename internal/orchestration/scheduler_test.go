@@ -0,0 +1,343 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/memstore"
+	"dsl-ob-poc/internal/shared-dsl/session"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addPlanSession registers a session that runs the given stages
+func addPlanSession(t *testing.T, o *Orchestrator, stages []ExecutionStage, resources []ResourceDep) *OrchestrationSession {
+	t.Helper()
+
+	sess := &OrchestrationSession{
+		SessionID:     "plan-" + t.Name(),
+		CreatedAt:     time.Now(),
+		LastUsed:      time.Now(),
+		ActiveDomains: make(map[string]*DomainSession),
+		SharedContext: &SharedContext{AttributeValues: make(map[string]interface{}), Data: make(map[string]interface{})},
+		ExecutionPlan: &ExecutionPlan{
+			Stages:               stages,
+			Dependencies:         make(map[string][]string),
+			ResourceDependencies: resources,
+			CreatedAt:            time.Now(),
+		},
+		DomainDSL:     make(map[string]string),
+		CurrentState:  "CREATED",
+		EntityRefs:    make(map[string]string),
+		AttributeRefs: make(map[string]string),
+	}
+	for _, stage := range stages {
+		for _, domain := range stage.Domains {
+			sess.ActiveDomains[domain] = &DomainSession{Domain: domain, SessionID: domain + "-session", State: "CREATED"}
+		}
+	}
+
+	o.mu.Lock()
+	o.sessions[sess.SessionID] = sess
+	o.mu.Unlock()
+	return sess
+}
+
+func stage(name string, prerequisites []string, domains ...string) ExecutionStage {
+	return ExecutionStage{Name: name, Domains: domains, Prerequisites: prerequisites, State: ExecutionStatePending}
+}
+
+func taskByDomain(t *testing.T, result *PlanExecutionResult, domain string) OrchestrationTask {
+	t.Helper()
+	for _, task := range result.Tasks {
+		if task.Domain == domain {
+			return task
+		}
+	}
+	t.Fatalf("no task for domain %s", domain)
+	return OrchestrationTask{}
+}
+
+func TestExecutePlan_RunsStagesInDependencyOrder(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "onboarding"),
+		stage("stage_2", []string{"stage_1"}, "kyc", "ubo"),
+		stage("stage_3", []string{"stage_2"}, "compliance"),
+	}, nil)
+
+	// kyc and ubo only finish once both are running, so the plan only
+	// completes if the scheduler really runs them side by side
+	var mu sync.Mutex
+	var order []string
+	bothRunning := make(chan struct{})
+	var arrived int32
+	o.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		if task.Domain == "kyc" || task.Domain == "ubo" {
+			if atomic.AddInt32(&arrived, 1) == 2 {
+				close(bothRunning)
+			}
+			select {
+			case <-bothRunning:
+			case <-time.After(2 * time.Second):
+				return nil, errors.New("kyc and ubo did not run in parallel")
+			}
+		}
+		mu.Lock()
+		order = append(order, task.Domain)
+		mu.Unlock()
+		return &TaskResult{DSL: "(" + task.Domain + ".done)", State: "COMPLETED"}, nil
+	})
+
+	result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.True(t, result.Success(), "errors: %v", result.Errors)
+	assert.Equal(t, 4, result.Completed)
+
+	require.Len(t, order, 4)
+	assert.Equal(t, "onboarding", order[0])
+	assert.ElementsMatch(t, []string{"kyc", "ubo"}, order[1:3])
+	assert.Equal(t, "compliance", order[3])
+
+	assert.Equal(t, []string{"stage_2/kyc", "stage_2/ubo"}, taskByDomain(t, result, "compliance").Dependencies)
+	for _, st := range result.Stages {
+		assert.Equal(t, ExecutionStateCompleted, st.State, st.Name)
+	}
+	assert.Equal(t, "COMPLETED", sess.ActiveDomains["kyc"].State)
+	assert.Equal(t, "(kyc.done)", sess.DomainDSL["kyc"])
+	assert.Contains(t, sess.UnifiedDSL, "(compliance.done)")
+}
+
+func TestExecutePlan_DomainInSeveralStages(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "kyc"),
+		stage("stage_2", []string{"stage_1"}, "custody"),
+		stage("stage_3", []string{"stage_2"}, "kyc"),
+	}, nil)
+
+	var mu sync.Mutex
+	var order []string
+	o.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		mu.Lock()
+		order = append(order, task.TaskID)
+		mu.Unlock()
+		return &TaskResult{}, nil
+	})
+
+	result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.True(t, result.Success(), "errors: %v", result.Errors)
+	assert.Equal(t, []string{"stage_1/kyc", "stage_2/custody", "stage_3/kyc"}, order)
+	assert.Equal(t, []string{"stage_1/kyc", "stage_2/custody"}, result.Tasks[2].Dependencies)
+}
+
+func TestExecutePlan_BoundsConcurrency(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	o.config.MaxParallelTasks = 2
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "onboarding", "kyc", "ubo", "compliance", "custody", "trading"),
+	}, nil)
+
+	var inFlight, peak int32
+	o.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return &TaskResult{}, nil
+	})
+
+	result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, 6, result.Completed)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+
+	// With parallel execution disabled tasks run one at a time
+	o.config.EnableParallelExec = false
+	sess.PendingTasks = nil
+	atomic.StoreInt32(&peak, 0)
+	_, err = o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+}
+
+func TestExecutePlan_FailureSkipsDependents(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "kyc", "custody"),
+		stage("stage_2", nil, "compliance", "trading"),
+	}, nil)
+	sess.ExecutionPlan.Dependencies["compliance"] = []string{"kyc"}
+	sess.ExecutionPlan.Dependencies["trading"] = []string{"custody"}
+
+	o.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		if task.Domain == "kyc" {
+			return nil, errors.New("screening service unavailable")
+		}
+		return &TaskResult{}, nil
+	})
+
+	result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.False(t, result.Success())
+	assert.Equal(t, 2, result.Completed)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.Skipped)
+
+	assert.Equal(t, "screening service unavailable", taskByDomain(t, result, "kyc").Error)
+	compliance := taskByDomain(t, result, "compliance")
+	assert.Equal(t, TaskStatusSkipped, compliance.Status)
+	assert.Contains(t, compliance.Error, "stage_1/kyc failed")
+	assert.Equal(t, TaskStatusCompleted, taskByDomain(t, result, "trading").Status)
+
+	assert.Equal(t, ExecutionStateFailed, result.Stages[0].State)
+	assert.Equal(t, ExecutionStateFailed, result.Stages[1].State)
+}
+
+func TestExecutePlan_WaitConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		reported string
+		want     TaskStatus
+	}{
+		{"condition reached", WaitConditionApproved, TaskStatusCompleted},
+		{"condition never reached", WaitConditionValidated, TaskStatusSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := setupTestOrchestrator(t)
+			sess := addPlanSession(t, o, []ExecutionStage{
+				stage("stage_1", nil, "kyc", "custody"),
+			}, []ResourceDep{
+				{SourceDomain: "kyc", TargetDomain: "custody", ResourceType: "kyc_profile", ResourceID: "kyc-profile", WaitCondition: WaitConditionApproved},
+			})
+
+			var kycDone atomic.Bool
+			o.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+				if task.Domain == "kyc" {
+					kycDone.Store(true)
+					return &TaskResult{Resources: map[string]string{"kyc-profile": tt.reported}}, nil
+				}
+				if !kycDone.Load() {
+					return nil, errors.New("custody started before the KYC profile was approved")
+				}
+				return &TaskResult{}, nil
+			})
+
+			result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+			require.NoError(t, err)
+			custody := taskByDomain(t, result, "custody")
+			assert.Equal(t, tt.want, custody.Status, custody.Error)
+			if tt.want == TaskStatusSkipped {
+				assert.Contains(t, custody.Error, "never became approved")
+			}
+		})
+	}
+}
+
+func TestExecutePlan_RejectsCycles(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "kyc", "custody"),
+	}, []ResourceDep{
+		{SourceDomain: "custody", TargetDomain: "kyc", ResourceID: "account", WaitCondition: WaitConditionCreated},
+	})
+	sess.ExecutionPlan.Dependencies["custody"] = []string{"kyc"}
+
+	_, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+}
+
+func TestExecutePlan_CancellationResumesFromPersistedProgress(t *testing.T) {
+	newOrchestrator := func(sessionStore *PersistentOrchestrationStore) *Orchestrator {
+		o := setupTestOrchestrator(t)
+		return NewPersistentOrchestrator(o.registry, session.NewManager(), sessionStore, o.config)
+	}
+	sessionStore := NewPersistentOrchestrationStore(memstore.New())
+
+	first := newOrchestrator(sessionStore)
+	sess := addPlanSession(t, first, []ExecutionStage{
+		stage("stage_1", nil, "kyc"),
+		stage("stage_2", []string{"stage_1"}, "custody"),
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		if task.Domain == "custody" {
+			cancel()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &TaskResult{DSL: "(kyc.done)"}, nil
+	})
+
+	result, err := first.ExecutePlan(ctx, sess.SessionID)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, result.Completed)
+	assert.Equal(t, 1, result.Pending)
+	assert.Equal(t, ExecutionStatePending, result.Stages[1].State)
+
+	// A fresh orchestrator picks the plan up from the store and only runs
+	// what is left
+	second := newOrchestrator(sessionStore)
+	var ran []string
+	second.SetTaskRunner(func(ctx context.Context, s *OrchestrationSession, task OrchestrationTask) (*TaskResult, error) {
+		ran = append(ran, task.Domain)
+		return &TaskResult{}, nil
+	})
+
+	result, err = second.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	assert.True(t, result.Success())
+	assert.Equal(t, []string{"custody"}, ran)
+	assert.Equal(t, ExecutionStateCompleted, result.Stages[0].State)
+}
+
+func TestExecutePlan_DefaultRunnerUsesDomains(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "kyc"),
+		stage("stage_2", nil, "custody"),
+	}, []ResourceDep{
+		{SourceDomain: "kyc", TargetDomain: "custody", ResourceID: "kyc-profile", WaitCondition: WaitConditionValidated},
+	})
+
+	result, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.NoError(t, err)
+	require.True(t, result.Success(), "errors: %v, warnings: %v", result.Errors, result.Warnings)
+
+	kyc := taskByDomain(t, result, "kyc")
+	assert.Equal(t, map[string]string{"kyc-profile": WaitConditionValidated}, kyc.Resources)
+	assert.Contains(t, sess.DomainDSL["custody"], "execute custody workflow")
+
+	_, err = o.ExecutePlan(context.Background(), "no-such-session")
+	assert.Error(t, err)
+}
+
+func TestExecutePlan_DefaultRunnerRejectsApproval(t *testing.T) {
+	o := setupTestOrchestrator(t)
+	sess := addPlanSession(t, o, []ExecutionStage{
+		stage("stage_1", nil, "kyc"),
+		stage("stage_2", nil, "custody"),
+	}, []ResourceDep{
+		{SourceDomain: "kyc", TargetDomain: "custody", ResourceID: "kyc-profile", WaitCondition: WaitConditionApproved},
+	})
+
+	_, err := o.ExecutePlan(context.Background(), sess.SessionID)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reports at most validated")
+	assert.Empty(t, sess.DomainDSL["kyc"])
+}
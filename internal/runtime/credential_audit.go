@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Credential access outcomes
+const (
+	CredentialAccessGranted  = "GRANTED"
+	CredentialAccessNotFound = "NOT_FOUND"
+	CredentialAccessExpired  = "EXPIRED"
+	CredentialAccessError    = "ERROR"
+)
+
+// CredentialAccess is the audit record of one GetCredentials call
+type CredentialAccess struct {
+	CredentialName string    `json:"credential_name" db:"credential_name"`
+	Backend        string    `json:"backend" db:"backend"`
+	Accessor       string    `json:"accessor" db:"accessor"`
+	Outcome        string    `json:"outcome" db:"outcome"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	AccessedAt     time.Time `json:"accessed_at" db:"accessed_at"`
+}
+
+// CredentialAuditor records credential accesses
+type CredentialAuditor interface {
+	RecordAccess(ctx context.Context, access *CredentialAccess) error
+}
+
+type credentialAccessorKey struct{}
+
+// WithCredentialAccessor names who reads credentials under ctx, e.g. the
+// action execution that authenticates with them; it is recorded with every
+// access
+func WithCredentialAccessor(ctx context.Context, accessor string) context.Context {
+	return context.WithValue(ctx, credentialAccessorKey{}, accessor)
+}
+
+func credentialAccessor(ctx context.Context) string {
+	if accessor, ok := ctx.Value(credentialAccessorKey{}).(string); ok && accessor != "" {
+		return accessor
+	}
+	return "unattributed"
+}
+
+// sqlCredentialAuditor writes to the credential_access_log table
+type sqlCredentialAuditor struct {
+	db *sql.DB
+}
+
+func (a *sqlCredentialAuditor) RecordAccess(ctx context.Context, access *CredentialAccess) error {
+	query := `
+		INSERT INTO credential_access_log (
+			credential_name, backend, accessor, outcome, error, accessed_at
+		) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := a.db.ExecContext(ctx, query,
+		access.CredentialName, access.Backend, access.Accessor, access.Outcome, access.Error, access.AccessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record credential access: %w", err)
+	}
+	return nil
+}
+
+// ListAccess returns the latest accesses to a credential, or to all
+// credentials when name is empty, newest first
+func (a *sqlCredentialAuditor) ListAccess(ctx context.Context, name string, limit int) ([]CredentialAccess, error) {
+	query := `
+		SELECT credential_name, backend, accessor, outcome, error, accessed_at
+		FROM credential_access_log
+		WHERE $1 = '' OR credential_name = $1
+		ORDER BY accessed_at DESC
+		LIMIT $2`
+
+	rows, err := a.db.QueryContext(ctx, query, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credential accesses: %w", err)
+	}
+	defer rows.Close()
+
+	var accesses []CredentialAccess
+	for rows.Next() {
+		var access CredentialAccess
+		if err := rows.Scan(&access.CredentialName, &access.Backend, &access.Accessor,
+			&access.Outcome, &access.Error, &access.AccessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan credential access: %w", err)
+		}
+		accesses = append(accesses, access)
+	}
+	return accesses, rows.Err()
+}
+
+// logCredentialAuditor writes accesses to the process log, for backends
+// used without a database
+type logCredentialAuditor struct{}
+
+func (logCredentialAuditor) RecordAccess(ctx context.Context, access *CredentialAccess) error {
+	msg := ""
+	if access.Error != nil {
+		msg = ": " + *access.Error
+	}
+	log.Printf("credential access: %s via %s by %s: %s%s",
+		access.CredentialName, access.Backend, access.Accessor, access.Outcome, msg)
+	return nil
+}
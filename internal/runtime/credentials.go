@@ -2,215 +2,127 @@ package runtime
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"time"
 )
 
-// CredentialManager handles secure credential storage and retrieval
+// CredentialManager handles secure credential storage and retrieval. Where
+// credentials are kept is up to its SecretBackend, selected with
+// CREDENTIALS_BACKEND; every GetCredentials call is audited.
 type CredentialManager struct {
-	db         *sql.DB
-	encryptKey []byte // 32-byte key for AES-256
+	backend SecretBackend
+	auditor CredentialAuditor
 }
 
-// NewCredentialManager creates a new credential manager
+// NewCredentialManager creates a credential manager for the backend named by
+// CREDENTIALS_BACKEND (postgres by default), auditing accesses to the
+// credential_access_log table
 func NewCredentialManager(db *sql.DB) (*CredentialManager, error) {
-	// Get encryption key from environment or generate one
-	keyString := os.Getenv("CREDENTIALS_ENCRYPTION_KEY")
-	if keyString == "" {
-		return nil, fmt.Errorf("CREDENTIALS_ENCRYPTION_KEY environment variable not set")
+	backend, err := secretBackendFromEnv(db)
+	if err != nil {
+		return nil, err
 	}
 
-	// Use SHA256 to ensure we have exactly 32 bytes for AES-256
-	hasher := sha256.New()
-	hasher.Write([]byte(keyString))
-	encryptKey := hasher.Sum(nil)
+	var auditor CredentialAuditor = logCredentialAuditor{}
+	if db != nil {
+		auditor = &sqlCredentialAuditor{db: db}
+	}
+	return NewCredentialManagerWithBackend(backend, auditor), nil
+}
 
-	return &CredentialManager{
-		db:         db,
-		encryptKey: encryptKey,
-	}, nil
+// NewCredentialManagerWithBackend creates a credential manager over an
+// explicit backend and auditor
+func NewCredentialManagerWithBackend(backend SecretBackend, auditor CredentialAuditor) *CredentialManager {
+	return &CredentialManager{backend: backend, auditor: auditor}
 }
 
 // StoreCredentials stores encrypted credentials in the vault
 func (cm *CredentialManager) StoreCredentials(ctx context.Context, name, credType, environment string, credentials map[string]interface{}) error {
-	// Serialize credentials to JSON
-	credentialsJSON, err := json.Marshal(credentials)
-	if err != nil {
-		return fmt.Errorf("failed to marshal credentials: %w", err)
-	}
-
-	// Encrypt the credentials
-	encryptedData, err := cm.encrypt(credentialsJSON)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt credentials: %w", err)
-	}
-
-	// Store in database
-	query := `
-		INSERT INTO credentials_vault (
-			credential_name, credential_type, encrypted_data, environment, created_at, active
-		) VALUES ($1, $2, $3, $4, NOW(), true)
-		ON CONFLICT (credential_name) DO UPDATE SET
-			credential_type = EXCLUDED.credential_type,
-			encrypted_data = EXCLUDED.encrypted_data,
-			environment = EXCLUDED.environment,
-			created_at = NOW(),
-			active = true`
-
-	_, err = cm.db.ExecContext(ctx, query, name, credType, encryptedData, environment)
-	if err != nil {
-		return fmt.Errorf("failed to store credentials: %w", err)
-	}
-
-	return nil
+	return cm.backend.Store(ctx, &StoredCredential{
+		CredentialInfo: CredentialInfo{Name: name, Type: credType, Environment: environment, Active: true},
+		Data:           credentials,
+	})
 }
 
-// GetCredentials retrieves and decrypts credentials from the vault
+// GetCredentials retrieves and decrypts credentials from the vault. The
+// access is audited whatever its outcome, and refused if it cannot be.
 func (cm *CredentialManager) GetCredentials(ctx context.Context, name string) (map[string]interface{}, error) {
-	query := `
-		SELECT encrypted_data, expires_at
-		FROM credentials_vault
-		WHERE credential_name = $1 AND active = true`
-
-	var encryptedData []byte
-	var expiresAt sql.NullTime
-
-	err := cm.db.QueryRowContext(ctx, query, name).Scan(&encryptedData, &expiresAt)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("credentials '%s' not found", name)
+	cred, err := cm.backend.Load(ctx, name)
+
+	outcome := CredentialAccessGranted
+	switch {
+	case errors.Is(err, ErrCredentialNotFound):
+		outcome = CredentialAccessNotFound
+		err = fmt.Errorf("credentials '%s' not found", name)
+	case err != nil:
+		outcome = CredentialAccessError
+	case cred.ExpiresAt != nil && cred.ExpiresAt.Before(time.Now()):
+		// Check if credentials have expired
+		outcome = CredentialAccessExpired
+		err = fmt.Errorf("credentials '%s' have expired", name)
+	}
+
+	access := &CredentialAccess{
+		CredentialName: name,
+		Backend:        cm.backend.Name(),
+		Accessor:       credentialAccessor(ctx),
+		Outcome:        outcome,
+		AccessedAt:     time.Now().UTC(),
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+		access.Error = stringPtr(err.Error())
 	}
-
-	// Check if credentials have expired
-	if expiresAt.Valid && expiresAt.Time.Before(time.Now()) {
-		return nil, fmt.Errorf("credentials '%s' have expired", name)
+	if auditErr := cm.auditor.RecordAccess(ctx, access); auditErr != nil {
+		return nil, fmt.Errorf("credentials '%s' withheld: %w", name, auditErr)
 	}
 
-	// Decrypt the credentials
-	credentialsJSON, err := cm.decrypt(encryptedData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
-	}
-
-	// Deserialize credentials
-	var credentials map[string]interface{}
-	if err := json.Unmarshal(credentialsJSON, &credentials); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal credentials: %w", err)
+		return nil, err
 	}
-
-	return credentials, nil
+	return cred.Data, nil
 }
 
 // ListCredentials lists available credentials (names only for security)
 func (cm *CredentialManager) ListCredentials(ctx context.Context, environment string) ([]CredentialInfo, error) {
-	query := `
-		SELECT credential_name, credential_type, environment, created_at, expires_at, active
-		FROM credentials_vault
-		WHERE environment = $1 OR environment = 'all'
-		ORDER BY credential_name`
-
-	rows, err := cm.db.QueryContext(ctx, query, environment)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list credentials: %w", err)
-	}
-	defer rows.Close()
-
-	var credentials []CredentialInfo
-	for rows.Next() {
-		var cred CredentialInfo
-		err := rows.Scan(
-			&cred.Name, &cred.Type, &cred.Environment,
-			&cred.CreatedAt, &cred.ExpiresAt, &cred.Active,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan credential info: %w", err)
-		}
-		credentials = append(credentials, cred)
-	}
-
-	return credentials, rows.Err()
+	return cm.backend.List(ctx, environment)
 }
 
 // DeleteCredentials removes credentials from the vault
 func (cm *CredentialManager) DeleteCredentials(ctx context.Context, name string) error {
-	query := `DELETE FROM credentials_vault WHERE credential_name = $1`
-
-	result, err := cm.db.ExecContext(ctx, query, name)
-	if err != nil {
-		return fmt.Errorf("failed to delete credentials: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
+	err := cm.backend.Delete(ctx, name)
+	if errors.Is(err, ErrCredentialNotFound) {
 		return fmt.Errorf("credentials '%s' not found", name)
 	}
-
-	return nil
+	return err
 }
 
-
-
-// encrypt encrypts data using AES-256-GCM
-func (cm *CredentialManager) encrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(cm.encryptKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+// RotateMasterKey re-encrypts every stored credential under the active
+// master key version (the highest in CREDENTIALS_ENCRYPTION_KEYS), after
+// which older master keys can be removed. It returns the number of
+// credentials re-encrypted.
+func (cm *CredentialManager) RotateMasterKey(ctx context.Context) (int, error) {
+	rotator, ok := cm.backend.(KeyRotator)
+	if !ok {
+		return 0, fmt.Errorf("the %s credential backend does not encrypt with a master key", cm.backend.Name())
 	}
-
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
-	return ciphertext, nil
+	return rotator.ReencryptAll(ctx)
 }
 
-// decrypt decrypts data using AES-256-GCM
-func (cm *CredentialManager) decrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(cm.encryptKey)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+// CredentialAccessLog returns the latest audited accesses to a credential,
+// or to all credentials when name is empty
+func (cm *CredentialManager) CredentialAccessLog(ctx context.Context, name string, limit int) ([]CredentialAccess, error) {
+	lister, ok := cm.auditor.(interface {
+		ListAccess(ctx context.Context, name string, limit int) ([]CredentialAccess, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("credential accesses are only logged, not stored, without a database")
 	}
+	return lister.ListAccess(ctx, name, limit)
+}
 
-	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return nil, fmt.Errorf("ciphertext too short")
-	}
 
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	return plaintext, nil
-}
 
 // CredentialInfo represents metadata about stored credentials
 type CredentialInfo struct {
@@ -220,6 +132,9 @@ type CredentialInfo struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 	Active      bool       `json:"active"`
+	// KeyVersion is the master key version the credential is encrypted
+	// under; zero for backends that do not encrypt
+	KeyVersion int `json:"key_version,omitempty"`
 }
 
 // ==============================================================================
@@ -251,6 +166,24 @@ func (cm *CredentialManager) CreateBasicAuthCredentials(ctx context.Context, nam
 	return cm.StoreCredentials(ctx, name, "basic", environment, credentials)
 }
 
+// CreateOAuth2ClientCredentials creates OAuth2 credentials for the
+// client-credentials grant; the HTTP client obtains and refreshes the access
+// token from tokenURL and keeps the latest one in the vault
+func (cm *CredentialManager) CreateOAuth2ClientCredentials(ctx context.Context, name, environment, tokenURL, clientID, clientSecret, scope string) error {
+	credentials := map[string]interface{}{
+		"token_url":     tokenURL,
+		"client_id":     clientID,
+		"client_secret": clientSecret,
+	}
+	if scope != "" {
+		credentials["scope"] = scope
+	}
+	if err := cm.ValidateCredentials("oauth2", credentials); err != nil {
+		return err
+	}
+	return cm.StoreCredentials(ctx, name, "oauth2", environment, credentials)
+}
+
 
 
 // ==============================================================================
@@ -276,8 +209,17 @@ func (cm *CredentialManager) ValidateCredentials(credType string, credentials ma
 			return fmt.Errorf("password field required for Basic auth credentials")
 		}
 	case "oauth2":
-		if _, ok := credentials["access_token"]; !ok {
-			return fmt.Errorf("access_token field required for OAuth2 credentials")
+		_, hasToken := credentials["access_token"]
+		_, hasTokenURL := credentials["token_url"]
+		if !hasToken && !hasTokenURL {
+			return fmt.Errorf("access_token or token_url field required for OAuth2 credentials")
+		}
+		if hasTokenURL {
+			_, hasClientID := credentials["client_id"]
+			_, hasRefreshToken := credentials["refresh_token"]
+			if !hasClientID && !hasRefreshToken {
+				return fmt.Errorf("client_id or refresh_token field required with token_url for OAuth2 credentials")
+			}
 		}
 	case "custom":
 		// Custom credentials can have any structure
@@ -315,25 +257,21 @@ func (cm *CredentialManager) TestCredentialConnection(ctx context.Context, name,
 // RotateCredentials creates new credentials and marks old ones for deletion
 func (cm *CredentialManager) RotateCredentials(ctx context.Context, name string, newCredentials map[string]interface{}) error {
 	// Get existing credential info
-	_ = []CredentialInfo{}
-	query := `
-		SELECT credential_type, environment
-		FROM credentials_vault
-		WHERE credential_name = $1 AND active = true`
-
-	var credType, environment string
-	err := cm.db.QueryRowContext(ctx, query, name).Scan(&credType, &environment)
+	info, err := cm.backend.Info(ctx, name)
+	if err == nil && !info.Active {
+		err = ErrCredentialNotFound
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get existing credential info: %w", err)
 	}
 
 	// Validate new credentials
-	if err := cm.ValidateCredentials(credType, newCredentials); err != nil {
+	if err := cm.ValidateCredentials(info.Type, newCredentials); err != nil {
 		return fmt.Errorf("new credentials validation failed: %w", err)
 	}
 
-	// Create new credentials (will overwrite existing due to ON CONFLICT)
-	if err := cm.StoreCredentials(ctx, name, credType, environment, newCredentials); err != nil {
+	// Create new credentials (replacing the existing ones)
+	if err := cm.StoreCredentials(ctx, name, info.Type, info.Environment, newCredentials); err != nil {
 		return fmt.Errorf("failed to store new credentials: %w", err)
 	}
 
@@ -342,22 +280,12 @@ func (cm *CredentialManager) RotateCredentials(ctx context.Context, name string,
 
 // GetCredentialsMetadata returns metadata about credentials without the actual secret data
 func (cm *CredentialManager) GetCredentialsMetadata(ctx context.Context, name string) (*CredentialInfo, error) {
-	query := `
-		SELECT credential_name, credential_type, environment, created_at, expires_at, active
-		FROM credentials_vault
-		WHERE credential_name = $1`
-
-	info := &CredentialInfo{}
-	err := cm.db.QueryRowContext(ctx, query, name).Scan(
-		&info.Name, &info.Type, &info.Environment,
-		&info.CreatedAt, &info.ExpiresAt, &info.Active,
-	)
-
-	if errors.Is(err, sql.ErrNoRows) {
+	info, err := cm.backend.Info(ctx, name)
+	if errors.Is(err, ErrCredentialNotFound) {
 		return nil, fmt.Errorf("credentials '%s' not found", name)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get credential metadata: %w", err)
+		return nil, err
 	}
 
 	return info, nil
@@ -0,0 +1,450 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling an endpoint whose circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// breakerRefreshInterval is how often a closed breaker re-reads the
+// persisted state, so that it opens when another process opened it
+const breakerRefreshInterval = 5 * time.Second
+
+// ErrRateLimited is returned when an endpoint's rate limit would hold a
+// request back for longer than the limit's max_wait_seconds
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// endpointGuards rate-limits and circuit-breaks the requests the engine
+// sends to resource type endpoints. Limits are per process; breaker state
+// is persisted so that other processes (and list-executions) see it.
+type endpointGuards struct {
+	store  breakerStore
+	now    func() time.Time
+	mu     sync.Mutex
+	guards map[string]*endpointGuard
+}
+
+// breakerStore persists circuit breaker state; Repository implements it
+type breakerStore interface {
+	GetCircuitBreakerState(ctx context.Context, endpointID string) (*CircuitBreakerState, error)
+	SaveCircuitBreakerState(ctx context.Context, state *CircuitBreakerState) error
+}
+
+func newEndpointGuards(store breakerStore) *endpointGuards {
+	return &endpointGuards{store: store, now: time.Now, guards: make(map[string]*endpointGuard)}
+}
+
+// endpointGuard holds the limiter and breaker of one endpoint; either may be
+// nil when the endpoint does not configure it
+type endpointGuard struct {
+	endpointID string
+	config     string // rate_limit and circuit_breaker the guard was built from
+	limiter    *tokenBucket
+	maxWait    time.Duration
+	breaker    *circuitBreaker
+}
+
+// forEndpoint returns the guard of endpoint, or nil when it has neither a
+// rate limit nor a circuit breaker
+func (g *endpointGuards) forEndpoint(ctx context.Context, endpoint *ResourceTypeEndpoint) (*endpointGuard, error) {
+	if g == nil || endpoint == nil || (len(endpoint.RateLimit) == 0 && len(endpoint.CircuitBreaker) == 0) {
+		return nil, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	config := string(endpoint.RateLimit) + "|" + string(endpoint.CircuitBreaker)
+	guard, ok := g.guards[endpoint.EndpointID]
+	if ok && guard.config == config {
+		return guard, nil
+	}
+
+	rateLimit, breakerConfig, err := parseEndpointPolicies(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	next := &endpointGuard{endpointID: endpoint.EndpointID, config: config}
+	if rateLimit != nil {
+		next.limiter = newTokenBucket(*rateLimit, g.now())
+		next.maxWait = time.Duration(rateLimit.MaxWaitSeconds) * time.Second
+	}
+	if breakerConfig != nil {
+		if ok && guard.breaker != nil {
+			// A configuration change keeps the breaker's state
+			next.breaker = guard.breaker
+			next.breaker.reconfigure(*breakerConfig)
+		} else {
+			next.breaker = newCircuitBreaker(endpoint.EndpointID, *breakerConfig)
+			next.breaker.refreshedAt = g.now()
+			if g.store != nil {
+				if saved, err := g.store.GetCircuitBreakerState(ctx, endpoint.EndpointID); err != nil {
+					log.Printf("circuit breaker: failed to load state of endpoint %s: %v", endpoint.EndpointID, err)
+				} else if saved != nil {
+					next.breaker.restore(saved)
+				}
+			}
+		}
+	}
+	g.guards[endpoint.EndpointID] = next
+	return next, nil
+}
+
+// parseEndpointPolicies decodes and defaults an endpoint's rate_limit and
+// circuit_breaker configuration
+func parseEndpointPolicies(endpoint *ResourceTypeEndpoint) (*RateLimitConfig, *CircuitBreakerConfig, error) {
+	var rateLimit *RateLimitConfig
+	if len(endpoint.RateLimit) > 0 && string(endpoint.RateLimit) != "null" {
+		rateLimit = &RateLimitConfig{}
+		if err := json.Unmarshal(endpoint.RateLimit, rateLimit); err != nil {
+			return nil, nil, fmt.Errorf("invalid rate_limit for endpoint %s: %w", endpoint.EndpointID, err)
+		}
+		if rateLimit.RequestsPerSecond <= 0 {
+			return nil, nil, fmt.Errorf("invalid rate_limit for endpoint %s: requests_per_second must be positive", endpoint.EndpointID)
+		}
+		if rateLimit.Burst <= 0 {
+			rateLimit.Burst = int(math.Max(1, math.Ceil(rateLimit.RequestsPerSecond)))
+		}
+		if rateLimit.MaxWaitSeconds <= 0 {
+			rateLimit.MaxWaitSeconds = 30
+		}
+	}
+
+	var breaker *CircuitBreakerConfig
+	if len(endpoint.CircuitBreaker) > 0 && string(endpoint.CircuitBreaker) != "null" {
+		breaker = &CircuitBreakerConfig{}
+		if err := json.Unmarshal(endpoint.CircuitBreaker, breaker); err != nil {
+			return nil, nil, fmt.Errorf("invalid circuit_breaker for endpoint %s: %w", endpoint.EndpointID, err)
+		}
+		if breaker.FailureThreshold <= 0 {
+			breaker.FailureThreshold = 5
+		}
+		if breaker.OpenSeconds <= 0 {
+			breaker.OpenSeconds = 30
+		}
+		if breaker.HalfOpenProbes <= 0 {
+			breaker.HalfOpenProbes = 1
+		}
+	}
+	return rateLimit, breaker, nil
+}
+
+// permit is a request the guard has let through; done reports its outcome
+type permit struct {
+	guards *endpointGuards
+	guard  *endpointGuard
+	probe  bool
+}
+
+// acquire waits for the endpoint's rate limit and checks its breaker. It
+// fails with ErrCircuitOpen or ErrRateLimited rather than calling an
+// endpoint that is down or saturated.
+func (g *endpointGuards) acquire(ctx context.Context, guard *endpointGuard) (*permit, error) {
+	if guard == nil {
+		return &permit{}, nil
+	}
+
+	p := &permit{guards: g, guard: guard}
+	if guard.breaker != nil {
+		g.refresh(ctx, guard.breaker)
+		probe, changed, err := guard.breaker.allow(g.now())
+		if changed {
+			g.persist(ctx, guard.breaker)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.probe = probe
+	}
+
+	if guard.limiter != nil {
+		delay, ok := guard.limiter.reserve(g.now(), guard.maxWait)
+		if !ok {
+			p.release()
+			return nil, fmt.Errorf("%w for endpoint %s: no slot within %s", ErrRateLimited, guard.endpointID, guard.maxWait)
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				p.release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return p, nil
+}
+
+// release gives back a half-open probe slot that was not used
+func (p *permit) release() {
+	if p.guard != nil && p.guard.breaker != nil && p.probe {
+		p.guard.breaker.cancelProbe()
+	}
+}
+
+// done records the outcome of the request. Transport errors, 5xx and 429
+// responses count against the breaker; other responses show the endpoint
+// is up, even if the action itself failed.
+func (p *permit) done(ctx context.Context, response *APIResponse, err error) {
+	if p.guard == nil || p.guard.breaker == nil {
+		return
+	}
+	var failure error
+	switch {
+	case response == nil || response.StatusCode == 0:
+		failure = err
+		if failure == nil {
+			failure = errors.New("no response")
+		}
+	case response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests:
+		failure = fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	if p.guard.breaker.record(p.guards.now(), p.probe, failure) {
+		p.guards.persist(ctx, p.guard.breaker)
+	}
+}
+
+// refresh re-reads the persisted state of a closed breaker once per
+// breakerRefreshInterval and opens it if another process opened it
+func (g *endpointGuards) refresh(ctx context.Context, breaker *circuitBreaker) {
+	if g.store == nil || !breaker.refreshDue(g.now()) {
+		return
+	}
+	saved, err := g.store.GetCircuitBreakerState(ctx, breaker.endpointID)
+	if err != nil {
+		log.Printf("circuit breaker: failed to load state of endpoint %s: %v", breaker.endpointID, err)
+		return
+	}
+	if saved != nil {
+		breaker.adopt(saved)
+	}
+}
+
+func (g *endpointGuards) persist(ctx context.Context, breaker *circuitBreaker) {
+	if g.store == nil {
+		return
+	}
+	state := breaker.snapshot(g.now())
+	if err := g.store.SaveCircuitBreakerState(ctx, state); err != nil {
+		log.Printf("circuit breaker: failed to save state of endpoint %s: %v", state.EndpointID, err)
+	}
+}
+
+// ==============================================================================
+// Token Bucket
+// ==============================================================================
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(cfg RateLimitConfig, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: cfg.RequestsPerSecond, burst: float64(cfg.Burst), tokens: float64(cfg.Burst), last: now}
+}
+
+// reserve takes a token, returning how long the caller must wait before it
+// is available; it takes nothing when that would be longer than maxWait
+func (b *tokenBucket) reserve(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return 0, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// ==============================================================================
+// Circuit Breaker
+// ==============================================================================
+
+// circuitBreaker opens after FailureThreshold consecutive failures, rejects
+// requests for OpenSeconds, then lets HalfOpenProbes requests through: their
+// success closes it again, a failure reopens it.
+type circuitBreaker struct {
+	mu             sync.Mutex
+	endpointID     string
+	cfg            CircuitBreakerConfig
+	state          CircuitState
+	failures       int
+	openedAt       time.Time
+	probesInFlight int
+	probeSuccesses int
+	lastError      string
+	refreshedAt    time.Time // when the persisted state was last read
+}
+
+func newCircuitBreaker(endpointID string, cfg CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{endpointID: endpointID, cfg: cfg, state: CircuitClosed}
+}
+
+func (b *circuitBreaker) reconfigure(cfg CircuitBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+}
+
+// restore resumes from persisted state. A half-open breaker's probes died
+// with the process that sent them, so it resumes as open and probes again
+// once its open period has passed.
+func (b *circuitBreaker) restore(saved *CircuitBreakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.restoreLocked(saved)
+}
+
+func (b *circuitBreaker) restoreLocked(saved *CircuitBreakerState) {
+	b.failures = saved.ConsecutiveFailures
+	if saved.LastError != nil {
+		b.lastError = *saved.LastError
+	}
+	if saved.State != CircuitClosed && saved.OpenedAt != nil {
+		b.state = CircuitOpen
+		b.openedAt = *saved.OpenedAt
+	}
+}
+
+// refreshDue reports whether a closed breaker should re-read the persisted
+// state, marking it as read so that concurrent callers do not all do so
+func (b *circuitBreaker) refreshDue(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != CircuitClosed || now.Sub(b.refreshedAt) < breakerRefreshInterval {
+		return false
+	}
+	b.refreshedAt = now
+	return true
+}
+
+// adopt takes over an open state persisted by another process. Closed
+// states are ignored: their failure counts were made by that process's
+// requests, not this one's.
+func (b *circuitBreaker) adopt(saved *CircuitBreakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitClosed && saved.State != CircuitClosed {
+		b.restoreLocked(saved)
+	}
+}
+
+// allow reports whether a request may be sent and whether it is a
+// half-open probe; changed is set when the breaker moved to half-open
+func (b *circuitBreaker) allow(now time.Time) (probe, changed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		reopen := b.openedAt.Add(time.Duration(b.cfg.OpenSeconds) * time.Second)
+		if now.Before(reopen) {
+			return false, false, fmt.Errorf("%w for endpoint %s until %s (%d consecutive failures, last: %s)",
+				ErrCircuitOpen, b.endpointID, reopen.Format(time.RFC3339), b.failures, b.lastError)
+		}
+		b.state = CircuitHalfOpen
+		b.probesInFlight = 0
+		b.probeSuccesses = 0
+		changed = true
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probesInFlight+b.probeSuccesses >= b.cfg.HalfOpenProbes {
+			return false, changed, fmt.Errorf("%w for endpoint %s: half-open, waiting for probe requests", ErrCircuitOpen, b.endpointID)
+		}
+		b.probesInFlight++
+		return true, changed, nil
+	}
+	return false, changed, nil
+}
+
+func (b *circuitBreaker) cancelProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+}
+
+// record counts the outcome of a request and reports whether the state
+// changed
+func (b *circuitBreaker) record(now time.Time, probe bool, failure error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe && b.probesInFlight > 0 {
+		b.probesInFlight--
+	}
+	before := b.state
+
+	if failure != nil {
+		b.failures++
+		b.lastError = failure.Error()
+		if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.cfg.FailureThreshold) {
+			b.state = CircuitOpen
+			b.openedAt = now
+		}
+		// Failures are persisted while they accumulate so that the count
+		// shown by list-executions is current
+		return true
+	}
+
+	switch b.state {
+	case CircuitHalfOpen:
+		if probe {
+			b.probeSuccesses++
+		}
+		if b.probeSuccesses >= b.cfg.HalfOpenProbes {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+	case CircuitClosed:
+		if b.failures == 0 {
+			return false
+		}
+		b.failures = 0
+		return true
+	}
+	return b.state != before
+}
+
+func (b *circuitBreaker) snapshot(now time.Time) *CircuitBreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := &CircuitBreakerState{
+		EndpointID:          b.endpointID,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		UpdatedAt:           now,
+	}
+	if b.state != CircuitClosed {
+		openedAt := b.openedAt
+		state.OpenedAt = &openedAt
+	}
+	if b.lastError != "" {
+		state.LastError = stringPtr(b.lastError)
+	}
+	return state
+}
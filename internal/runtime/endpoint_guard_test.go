@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryBreakerStore is an in-memory breakerStore
+type memoryBreakerStore struct {
+	states map[string]CircuitBreakerState
+}
+
+func (m *memoryBreakerStore) GetCircuitBreakerState(ctx context.Context, endpointID string) (*CircuitBreakerState, error) {
+	state, ok := m.states[endpointID]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (m *memoryBreakerStore) SaveCircuitBreakerState(ctx context.Context, state *CircuitBreakerState) error {
+	m.states[state.EndpointID] = *state
+	return nil
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	store := &memoryBreakerStore{states: map[string]CircuitBreakerState{}}
+	guards := newEndpointGuards(store)
+	guards.now = clock.now
+
+	endpoint := &ResourceTypeEndpoint{
+		EndpointID:     "ep-custody",
+		CircuitBreaker: json.RawMessage(`{"failure_threshold": 3, "open_seconds": 60}`),
+	}
+	call := func(status int) error {
+		guard, err := guards.forEndpoint(ctx, endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		permit, err := guards.acquire(ctx, guard)
+		if err != nil {
+			return err
+		}
+		permit.done(ctx, &APIResponse{StatusCode: status}, nil)
+		return nil
+	}
+
+	// A 4xx shows the endpoint is up, so only the last three failures count
+	for _, status := range []int{503, 400, 502, 500, 429} {
+		if err := call(status); err != nil {
+			t.Fatalf("call(%d) rejected: %v", status, err)
+		}
+	}
+	if got := store.states["ep-custody"]; got.State != CircuitOpen || got.ConsecutiveFailures != 3 {
+		t.Fatalf("persisted state = %+v, want OPEN after 3 failures", got)
+	}
+	if err := call(200); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call while open: err = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the open period one probe goes through; its failure reopens
+	clock.advance(61 * time.Second)
+	if err := call(503); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if err := call(200); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call after failed probe: err = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful probe closes the breaker
+	clock.advance(61 * time.Second)
+	if err := call(201); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if got := store.states["ep-custody"]; got.State != CircuitClosed || got.ConsecutiveFailures != 0 {
+		t.Fatalf("persisted state = %+v, want CLOSED", got)
+	}
+
+	// Another process picks up an open breaker from the store
+	store.states["ep-custody"] = CircuitBreakerState{EndpointID: "ep-custody", State: CircuitOpen, ConsecutiveFailures: 9, OpenedAt: &clock.t}
+	other := newEndpointGuards(store)
+	other.now = clock.now
+	guard, _ := other.forEndpoint(ctx, endpoint)
+	if _, err := other.acquire(ctx, guard); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("restored breaker: err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreaker_SharedStoreOpensOtherGuards(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{t: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+	store := &memoryBreakerStore{states: map[string]CircuitBreakerState{}}
+	endpoint := &ResourceTypeEndpoint{
+		EndpointID:     "ep-custody",
+		CircuitBreaker: json.RawMessage(`{"failure_threshold": 2, "open_seconds": 60}`),
+	}
+
+	// Two processes, each with its own guards, both with a closed breaker
+	first, second := newEndpointGuards(store), newEndpointGuards(store)
+	first.now, second.now = clock.now, clock.now
+	call := func(guards *endpointGuards, status int) error {
+		guard, err := guards.forEndpoint(ctx, endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		permit, err := guards.acquire(ctx, guard)
+		if err != nil {
+			return err
+		}
+		permit.done(ctx, &APIResponse{StatusCode: status}, nil)
+		return nil
+	}
+	if err := call(second, 200); err != nil {
+		t.Fatalf("second guard rejected a call while closed: %v", err)
+	}
+
+	// The first process trips the breaker
+	for _, status := range []int{503, 503} {
+		if err := call(first, status); err != nil {
+			t.Fatalf("first guard rejected call(%d): %v", status, err)
+		}
+	}
+
+	// The second sees it once its refresh interval has passed
+	clock.advance(breakerRefreshInterval)
+	if err := call(second, 200); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second guard after the first opened: err = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestHalfOpen_AllowsOnlyConfiguredProbes(t *testing.T) {
+	b := newCircuitBreaker("ep", CircuitBreakerConfig{FailureThreshold: 1, OpenSeconds: 1, HalfOpenProbes: 1})
+	now := time.Now()
+	b.record(now, false, errors.New("timeout"))
+
+	now = now.Add(2 * time.Second)
+	probe, changed, err := b.allow(now)
+	if err != nil || !probe || !changed {
+		t.Fatalf("first allow = %t, %t, %v; want a probe", probe, changed, err)
+	}
+	if _, _, err := b.allow(now); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second allow while probing: err = %v", err)
+	}
+	b.cancelProbe()
+	if probe, _, err := b.allow(now); err != nil || !probe {
+		t.Fatalf("allow after a cancelled probe = %t, %v", probe, err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(RateLimitConfig{RequestsPerSecond: 2, Burst: 2}, start)
+
+	for i := 0; i < 2; i++ {
+		if wait, ok := b.reserve(start, time.Second); !ok || wait != 0 {
+			t.Fatalf("burst request %d: wait %s, ok %t", i, wait, ok)
+		}
+	}
+	if wait, ok := b.reserve(start, time.Second); !ok || wait != 500*time.Millisecond {
+		t.Fatalf("third request: wait %s, ok %t; want 500ms", wait, ok)
+	}
+	if _, ok := b.reserve(start, 500*time.Millisecond); ok {
+		t.Fatal("fourth request should exceed the max wait")
+	}
+	if wait, ok := b.reserve(start.Add(2*time.Second), time.Second); !ok || wait != 0 {
+		t.Fatalf("after refill: wait %s, ok %t", wait, ok)
+	}
+
+	guards := newEndpointGuards(nil)
+	_, err := guards.forEndpoint(context.Background(), &ResourceTypeEndpoint{EndpointID: "ep", RateLimit: json.RawMessage(`{"requests_per_second": 0}`)})
+	if err == nil {
+		t.Fatal("expected an error for a zero rate")
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
+	"log"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ type ExecutionEngine struct {
 	httpClient        *HTTPClient
 	attributeResolver *AttributeResolver
 	credentialMgr     *CredentialManager
+	guards            *endpointGuards
 }
 
 // NewExecutionEngine creates a new execution engine
@@ -30,7 +32,12 @@ func NewExecutionEngine(db *sql.DB, dataStore datastore.DataStore) (*ExecutionEn
 		return nil, fmt.Errorf("failed to create credential manager: %w", err)
 	}
 
+	fixtures, err := FixtureConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	httpClient := NewHTTPClient(credentialMgr)
+	httpClient.UseFixtures(fixtures)
 	attributeResolver := NewAttributeResolver(dataStore)
 
 	return &ExecutionEngine{
@@ -39,11 +46,24 @@ func NewExecutionEngine(db *sql.DB, dataStore datastore.DataStore) (*ExecutionEn
 		httpClient:        httpClient,
 		attributeResolver: attributeResolver,
 		credentialMgr:     credentialMgr,
+		guards:            newEndpointGuards(repository),
 	}, nil
 }
 
-// ExecuteAction executes a single action based on execution request
+// ExecuteAction executes a single action based on execution request,
+// retrying transient failures in-line according to the action's RetryConfig
 func (ee *ExecutionEngine) ExecuteAction(ctx context.Context, req *ExecutionRequest) (*ExecutionResult, error) {
+	return ee.execute(ctx, req, 1, true)
+}
+
+// ExecuteAttempt makes a single attempt at req without sleeping between
+// retries; attempt is the 1-based attempt number. A transient failure is
+// flagged as Retryable so that the job queue can schedule the next attempt.
+func (ee *ExecutionEngine) ExecuteAttempt(ctx context.Context, req *ExecutionRequest, attempt int) (*ExecutionResult, error) {
+	return ee.execute(ctx, req, attempt, false)
+}
+
+func (ee *ExecutionEngine) execute(ctx context.Context, req *ExecutionRequest, attempt int, inlineRetries bool) (*ExecutionResult, error) {
 	startTime := time.Now()
 
 	// Get action definition
@@ -61,6 +81,7 @@ func (ee *ExecutionEngine) ExecuteAction(ctx context.Context, req *ExecutionRequ
 		TriggerContext:  mustMarshalJSON(req.TriggerContext),
 		TraceID:         req.TraceID,
 		SpanID:          req.SpanID,
+		RetryCount:      attempt - 1,
 	}
 
 	// Generate idempotency key and correlation ID
@@ -69,6 +90,7 @@ func (ee *ExecutionEngine) ExecuteAction(ctx context.Context, req *ExecutionRequ
 	}
 
 	// Check for existing execution with same idempotency key
+	resumed := false
 	if execution.IdempotencyKey != nil {
 		existing, err := ee.repository.GetActionExecutionByIdempotencyKey(ctx, *execution.IdempotencyKey)
 		if err == nil {
@@ -76,17 +98,23 @@ func (ee *ExecutionEngine) ExecuteAction(ctx context.Context, req *ExecutionRequ
 			if existing.ExecutionStatus == ExecutionStatusCompleted {
 				return ee.buildExecutionResult(existing, time.Since(startTime)), nil
 			}
-			// If still running or failed, continue with new execution
+			// If still running or failed, retry it under the same record:
+			// the idempotency key admits only one execution per action and CBU
+			execution.ExecutionID = existing.ExecutionID
+			execution.StartedAt = existing.StartedAt
+			resumed = true
 		}
 	}
 
 	// Create execution record in database
-	if err := ee.repository.CreateActionExecution(ctx, execution); err != nil {
-		return nil, fmt.Errorf("failed to create execution record: %w", err)
+	if !resumed {
+		if err := ee.repository.CreateActionExecution(ctx, execution); err != nil {
+			return nil, fmt.Errorf("failed to create execution record: %w", err)
+		}
 	}
 
 	// Execute with comprehensive tracking
-	result := ee.executeWithTracking(ctx, execution, actionDef, req)
+	result := ee.executeWithTracking(ctx, execution, actionDef, req, inlineRetries)
 
 	// Update execution record with final result
 	ee.updateExecutionRecord(ctx, execution, result)
@@ -95,7 +123,7 @@ func (ee *ExecutionEngine) ExecuteAction(ctx context.Context, req *ExecutionRequ
 }
 
 // executeWithTracking executes action with comprehensive tracking and retry logic
-func (ee *ExecutionEngine) executeWithTracking(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, req *ExecutionRequest) *ExecutionResult {
+func (ee *ExecutionEngine) executeWithTracking(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, req *ExecutionRequest, inlineRetries bool) *ExecutionResult {
 	startTime := time.Now()
 
 	// Update status to running
@@ -138,39 +166,49 @@ func (ee *ExecutionEngine) executeWithTracking(ctx context.Context, execution *A
 	execution.RequestPayload = mustMarshalJSON(requestPayload)
 
 	// Execute with retry logic
-	return ee.executeWithRetry(ctx, execution, actionDef, requestPayload, time.Since(startTime))
+	return ee.executeWithRetry(ctx, execution, actionDef, requestPayload, time.Since(startTime), inlineRetries)
 }
 
-// executeWithRetry executes the action with retry logic
-func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, requestPayload map[string]interface{}, baseDuration time.Duration) *ExecutionResult {
+// executeWithRetry executes the action with retry logic. Without
+// inlineRetries a single attempt is made and a failure that would have been
+// retried is marked Retryable instead.
+func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, requestPayload map[string]interface{}, baseDuration time.Duration, inlineRetries bool) *ExecutionResult {
+	ctx = WithCredentialAccessor(ctx, fmt.Sprintf("action:%s execution:%s", actionDef.ActionID, execution.ExecutionID))
 	maxRetries := actionDef.ExecutionConfig.RetryConfig.MaxRetries
+	if !inlineRetries {
+		maxRetries = 0
+	}
 	var lastResponse *APIResponse
 	var lastErr error
+	retryable := false
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		attemptStart := time.Now()
+		retryable = false
 
 		// Create execution attempt record
 		attemptRecord := &ActionExecutionAttempt{
 			ExecutionID:    execution.ExecutionID,
-			AttemptNo:      attempt + 1,
+			AttemptNo:      execution.RetryCount + 1,
 			Status:         ExecutionStatusRunning,
 			RequestPayload: mustMarshalJSON(requestPayload),
 		}
 
 		// Resolve endpoint URL
-		endpointURL, err := ee.resolveEndpointURL(ctx, actionDef)
+		endpointURL, endpoint, err := ee.resolveEndpoint(ctx, actionDef)
 		if err != nil {
 			lastErr = err
 			continue
 		}
 
 		attemptRecord.EndpointURL = &endpointURL
+		execution.Endpoint = &endpointURL
 
 		// Build API request
 		apiRequest := &APIRequest{
 			Method:         actionDef.ExecutionConfig.Method,
 			URL:            endpointURL,
+			Environment:    actionDef.Environment,
 			Body:           requestPayload,
 			Headers:        make(map[string]string),
 			Authentication: actionDef.ExecutionConfig.Authentication,
@@ -181,8 +219,24 @@ func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *Acti
 			SpanID:         execution.SpanID,
 		}
 
+		// Wait for the endpoint's rate limit and check its circuit breaker.
+		// An endpoint that is down is not called at all; the job queue
+		// retries the execution once the breaker lets requests through.
+		guard, err := ee.guards.forEndpoint(ctx, endpoint)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		permit, err := ee.guards.acquire(ctx, guard)
+		if err != nil {
+			lastErr = err
+			retryable = errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrRateLimited)
+			break
+		}
+
 		// Execute HTTP request
 		response, err := ee.httpClient.Execute(ctx, apiRequest)
+		permit.done(ctx, response, err)
 		lastResponse = response
 		lastErr = err
 
@@ -211,13 +265,30 @@ func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *Acti
 		// Check if we should retry
 		if err == nil && response != nil {
 			// Validate response against success criteria
-			var successCriteria SuccessCriteria
-			if err := json.Unmarshal(actionDef.SuccessCriteria, &successCriteria); err == nil {
-				if err := ee.httpClient.ValidateResponse(response, successCriteria); err == nil {
-					// Success! Process response and return
-					return ee.processSuccessfulResponse(ctx, execution, actionDef, response, baseDuration+time.Since(attemptStart))
+			successCriteria, err := parseSuccessCriteria(actionDef.SuccessCriteria)
+			if err != nil {
+				err = &ResponseValidationError{Err: err}
+			} else {
+				err = ee.httpClient.ValidateResponse(response, successCriteria)
+			}
+			var outputs map[string]any
+			if err == nil {
+				outputs, err = mapOutputs(ctx, ee.attributeResolver.transformer, responseDocument(response), actionDef.AttributeMapping.OutputMapping)
+				if err != nil {
+					err = &ResponseValidationError{Err: err}
 				}
 			}
+			if err == nil {
+				// Success! Process response and return
+				return ee.processSuccessfulResponse(ctx, execution, actionDef, response, outputs, baseDuration+time.Since(attemptStart))
+			}
+
+			// A body that breaks the contract fails the same way every time
+			lastErr = err
+			if IsResponseValidationError(err) {
+				retryable = false
+				break
+			}
 		}
 
 		// Check if we should retry based on failure handling
@@ -226,8 +297,9 @@ func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *Acti
 			_ = json.Unmarshal(actionDef.FailureHandling, &failureHandling)
 		}
 
-		shouldRetry := ee.httpClient.ShouldRetry(response, failureHandling, attempt, maxRetries)
-		if !shouldRetry {
+		// A request that could not be built (nil response) is not transient
+		retryable = response != nil && ee.httpClient.ShouldRetry(response, failureHandling, 0, 1)
+		if !retryable || attempt >= maxRetries {
 			break
 		}
 
@@ -235,7 +307,7 @@ func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *Acti
 		delay := ee.httpClient.CalculateBackoffDelay(attempt, actionDef.ExecutionConfig.RetryConfig)
 		time.Sleep(delay)
 
-		execution.RetryCount = attempt + 1
+		execution.RetryCount++
 	}
 
 	// All retries exhausted - return failure
@@ -246,50 +318,73 @@ func (ee *ExecutionEngine) executeWithRetry(ctx context.Context, execution *Acti
 		errorMsg = fmt.Sprintf("execution failed: %s", *lastResponse.Error)
 	}
 
-	return ee.buildFailureResult(execution.ExecutionID, errorMsg, baseDuration)
+	result := ee.buildFailureResult(execution.ExecutionID, errorMsg, baseDuration)
+	result.Retryable = retryable
+	return result
 }
 
-// processSuccessfulResponse processes a successful API response
-func (ee *ExecutionEngine) processSuccessfulResponse(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, response *APIResponse, duration time.Duration) *ExecutionResult {
-	// Extract result attributes from response
-	resultAttributes := make(map[string]interface{})
+// processSuccessfulResponse records the mapped outputs of a successful API
+// response as resolved attribute values, with the execution and response
+// path they came from as provenance. An output that cannot be stored fails the
+// execution as retryable
+func (ee *ExecutionEngine) processSuccessfulResponse(ctx context.Context, execution *ActionExecution, actionDef *ActionDefinition, response *APIResponse, outputs map[string]any, duration time.Duration) *ExecutionResult {
+	// Get latest DSL version for this CBU
+	dslVersion, versionErr := ee.dataStore.GetLatestDSLWithState(ctx, execution.CBUID)
 
+	var storeErrs []error
 	for _, mapping := range actionDef.AttributeMapping.OutputMapping {
-		// Simple JSONPath-like extraction (in production, use a proper JSONPath library)
-		value := ee.extractFromResponse(response.Body, mapping.APIResponsePath)
-		if value != nil {
-			resultAttributes[mapping.AttributeName] = value
-
-			// Store the result attribute in the database if attribute ID is provided
-			if mapping.DSLAttributeID != "" {
-				valueJSON, _ := json.Marshal(value)
-				// Get latest DSL version for this CBU
-				if dslVersion, err := ee.dataStore.GetLatestDSLWithState(ctx, execution.CBUID); err == nil {
-					_ = ee.dataStore.UpsertAttributeValue(ctx, execution.CBUID, dslVersion.VersionNumber, mapping.DSLAttributeID, valueJSON, "resolved", map[string]any{
-						"source":       "api_response",
-						"execution_id": execution.ExecutionID,
-						"endpoint":     response.Headers["endpoint"],
-						"resolved_at":  time.Now().Format(time.RFC3339),
-					})
-				}
-			}
+		value, ok := outputs[outputName(mapping)]
+		// Store the result attribute in the database if attribute ID is provided
+		if !ok || mapping.DSLAttributeID == "" || versionErr != nil {
+			continue
+		}
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		source := map[string]any{
+			"source":        "api_response",
+			"action_id":     actionDef.ActionID,
+			"execution_id":  execution.ExecutionID,
+			"endpoint":      deref(execution.Endpoint),
+			"http_status":   response.StatusCode,
+			"response_path": mapping.APIResponsePath,
+			"resolved_at":   time.Now().Format(time.RFC3339),
+		}
+		if mapping.Transformation != "" {
+			source["transformation"] = mapping.Transformation
+		}
+		if err := ee.dataStore.UpsertAttributeValue(ctx, execution.CBUID, dslVersion.VersionNumber, mapping.DSLAttributeID, valueJSON, "resolved", source); err != nil {
+			log.Printf("execution %s: failed to store attribute %s: %v", execution.ExecutionID, mapping.DSLAttributeID, err)
+			storeErrs = append(storeErrs, fmt.Errorf("attribute %s: %w", mapping.DSLAttributeID, err))
 		}
 	}
 
-	return &ExecutionResult{
+	result := &ExecutionResult{
 		ExecutionID:      execution.ExecutionID,
 		Success:          true,
 		HTTPStatus:       &response.StatusCode,
 		ResponsePayload:  response.Body,
-		ResultAttributes: resultAttributes,
+		ResultAttributes: outputs,
 		DurationMS:       int(duration.Milliseconds()),
 		IdempotencyKey:   execution.IdempotencyKey,
 		CorrelationID:    execution.CorrelationID,
 	}
+	// Outputs that were not stored fail the execution; the write is
+	// transient, and the idempotency key makes the retried call safe
+	if len(storeErrs) > 0 {
+		errorMsg := fmt.Sprintf("failed to store result attributes: %v", errors.Join(storeErrs...))
+		result.Success = false
+		result.ErrorDetails = &errorMsg
+		result.Retryable = true
+	}
+	return result
 }
 
-// resolveEndpointURL resolves the endpoint URL for the action
-func (ee *ExecutionEngine) resolveEndpointURL(ctx context.Context, actionDef *ActionDefinition) (string, error) {
+// resolveEndpoint resolves the endpoint URL for the action, along with the
+// resource type endpoint it was looked up from (nil for a literal URL or a
+// fallback)
+func (ee *ExecutionEngine) resolveEndpoint(ctx context.Context, actionDef *ActionDefinition) (string, *ResourceTypeEndpoint, error) {
 	endpointURL := actionDef.ExecutionConfig.EndpointURL
 
 	// Handle LOOKUP: prefix for dynamic endpoint resolution
@@ -302,9 +397,9 @@ func (ee *ExecutionEngine) resolveEndpointURL(ctx context.Context, actionDef *Ac
 			if err != nil {
 				// Fall back to configured fallback URL
 				if actionDef.ExecutionConfig.EndpointLookupFallback != "" {
-					return actionDef.ExecutionConfig.EndpointLookupFallback, nil
+					return actionDef.ExecutionConfig.EndpointLookupFallback, nil, nil
 				}
-				return "", fmt.Errorf("failed to resolve resource type endpoint: %w", err)
+				return "", nil, fmt.Errorf("failed to resolve resource type endpoint: %w", err)
 			}
 
 			// Get the create endpoint for this resource type
@@ -312,52 +407,18 @@ func (ee *ExecutionEngine) resolveEndpointURL(ctx context.Context, actionDef *Ac
 			if err != nil {
 				// Fall back to configured fallback URL
 				if actionDef.ExecutionConfig.EndpointLookupFallback != "" {
-					return actionDef.ExecutionConfig.EndpointLookupFallback, nil
+					return actionDef.ExecutionConfig.EndpointLookupFallback, nil, nil
 				}
-				return "", fmt.Errorf("failed to get resource type endpoint: %w", err)
+				return "", nil, fmt.Errorf("failed to get resource type endpoint: %w", err)
 			}
 
-			return endpoint.EndpointURL, nil
-		}
-
-		return "", fmt.Errorf("unsupported lookup key: %s", lookupKey)
-	}
-
-	return endpointURL, nil
-}
-
-// extractFromResponse extracts a value from API response using JSONPath-like syntax
-func (ee *ExecutionEngine) extractFromResponse(responseBody map[string]interface{}, path string) interface{} {
-	// Simple JSONPath implementation - in production use a proper library
-	if path == "" {
-		return nil
-	}
-
-	// Remove leading $ if present
-	path = strings.TrimPrefix(path, "$.")
-
-	// Split path into parts
-	parts := strings.Split(path, ".")
-	var current interface{} = responseBody
-
-	for _, part := range parts {
-		if current == nil {
-			return nil
+			return endpoint.EndpointURL, endpoint, nil
 		}
 
-		switch v := current.(type) {
-		case map[string]interface{}:
-			if val, exists := v[part]; exists {
-				current = val
-			} else {
-				return nil
-			}
-		default:
-			return nil
-		}
+		return "", nil, fmt.Errorf("unsupported lookup key: %s", lookupKey)
 	}
 
-	return current
+	return endpointURL, nil, nil
 }
 
 // generateExecutionMetadata generates idempotency key and correlation ID
@@ -511,119 +572,85 @@ func intPtr(i int) *int {
 // Action Triggering and Discovery
 // ==============================================================================
 
-// TriggerActionsForDSLChange finds and triggers actions based on DSL state changes
+// TriggerActionsForDSLChange finds and triggers actions based on DSL state
+// changes, executing them in order as one saga before returning. If an
+// action fails the remaining ones are not run and the completed ones are
+// rolled back by their compensating actions (see ExecutionResult.Compensation).
+// EnqueueActionsForDSLChange hands them to the job queue instead.
 func (ee *ExecutionEngine) TriggerActionsForDSLChange(ctx context.Context, cbuID, dslVersionID, dslContent, environment string) ([]*ExecutionResult, error) {
-	// Parse DSL to extract verbs that were used
-	verbs := ee.extractVerbsFromDSL(dslContent)
+	actions := ee.triggeredActions(ctx, cbuID, dslVersionID, dslContent, environment)
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	_, results, err := runSaga(ctx, ee.repository, ee, cbuID, dslVersionID, actions)
+	return results, err
+}
 
-	var results []*ExecutionResult
+// EnqueueActionsForDSLChange finds the actions triggered by a DSL state
+// change and enqueues one job per action for runtime workers, returning the
+// job IDs
+func (ee *ExecutionEngine) EnqueueActionsForDSLChange(ctx context.Context, cbuID, dslVersionID, dslContent, environment string) ([]string, error) {
+	var jobIDs []string
+
+	for _, action := range ee.triggeredActions(ctx, cbuID, dslVersionID, dslContent, environment) {
+		jobID, err := ee.EnqueueAction(ctx, action.request)
+		if err != nil {
+			return jobIDs, err
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+
+	return jobIDs, nil
+}
+
+// triggeredActions builds an execution request for every action whose verb
+// pattern and trigger conditions match the DSL
+func (ee *ExecutionEngine) triggeredActions(ctx context.Context, cbuID, dslVersionID, dslContent, environment string) []sagaAction {
+	// The trigger environment holds the verbs the parsed DSL uses
+	env := ee.newTriggerEnv(ctx, cbuID, dslVersionID, dslContent, environment)
+
+	var triggered []sagaAction
 
 	// For each verb, find matching action definitions
-	for _, verb := range verbs {
+	for _, verb := range env.Verbs() {
 		actions, err := ee.repository.GetActionDefinitionsByVerbPattern(ctx, verb, environment)
 		if err != nil {
 			continue // Skip if no actions found for this verb
 		}
 
-		// Execute each matching action
 		for _, actionDef := range actions {
 			// Check trigger conditions
-			if ee.evaluateTriggerConditions(ctx, actionDef, dslContent, cbuID) {
-				req := &ExecutionRequest{
-					ActionID:     actionDef.ActionID,
-					CBUID:        cbuID,
-					DSLVersionID: dslVersionID,
-					Environment:  environment,
-					TriggerContext: map[string]interface{}{
-						"triggered_by": "dsl_change",
-						"verb":         verb,
-						"dsl_content":  dslContent,
-						"triggered_at": time.Now().Format(time.RFC3339),
+			if ee.evaluateTriggerConditions(actionDef, env) {
+				triggered = append(triggered, sagaAction{
+					request: &ExecutionRequest{
+						ActionID:     actionDef.ActionID,
+						CBUID:        cbuID,
+						DSLVersionID: dslVersionID,
+						Environment:  environment,
+						TriggerContext: map[string]interface{}{
+							"triggered_by": "dsl_change",
+							"verb":         verb,
+							"dsl_content":  dslContent,
+							"triggered_at": time.Now().Format(time.RFC3339),
+						},
 					},
-				}
-
-				result, err := ee.ExecuteAction(ctx, req)
-				if err != nil {
-					// Log error but continue with other actions
-					result = &ExecutionResult{
-						Success:      false,
-						ErrorDetails: stringPtr(err.Error()),
-					}
-				}
-
-				results = append(results, result)
+					compensatingActionID: actionDef.CompensatingActionID,
+				})
 			}
 		}
 	}
 
-	return results, nil
-}
-
-// extractVerbsFromDSL extracts verb patterns from DSL content
-func (ee *ExecutionEngine) extractVerbsFromDSL(dslContent string) []string {
-	// Simple regex to extract verbs like "resources.create", "kyc.start", etc.
-	verbPattern := `\(([a-zA-Z_]+\.[a-zA-Z_]+)`
-	re, _ := regexp.Compile(verbPattern)
-	matches := re.FindAllStringSubmatch(dslContent, -1)
-
-	var verbs []string
-	seen := make(map[string]bool)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			verb := match[1]
-			if !seen[verb] {
-				verbs = append(verbs, verb)
-				seen[verb] = true
-			}
-		}
-	}
-
-	return verbs
+	return triggered
 }
 
 // evaluateTriggerConditions checks if trigger conditions are met
-func (ee *ExecutionEngine) evaluateTriggerConditions(ctx context.Context, actionDef *ActionDefinition, dslContent, cbuID string) bool {
-	if len(actionDef.TriggerConditions) == 0 {
-		return true // No conditions means always trigger
-	}
-
-	var conditions TriggerConditions
-	if err := json.Unmarshal(actionDef.TriggerConditions, &conditions); err != nil {
-		return false
-	}
-
-	// Check domain condition
-	if conditions.Domain != nil && *conditions.Domain != "" {
-		// This would need to be checked against the CBU's domain or session context
-		// For now, assume it matches - placeholder for domain checking logic
-		return true
-	}
-
-	// Check state condition
-	if conditions.State != nil && *conditions.State != "" {
-		// Check if DSL content indicates we're in the required state
-		// This is a simplified check - in production you'd have more sophisticated state detection
-		if !strings.Contains(dslContent, strings.ToLower(*conditions.State)) {
-			return false
-		}
-	}
-
-	// Check attribute requirements
-	if len(conditions.AttributeRequirements) > 0 {
-		for _, requiredAttr := range conditions.AttributeRequirements {
-			// Check if the required attribute is available in DSL or can be resolved
-			// This is a simplified check
-			if !strings.Contains(dslContent, requiredAttr) {
-				// Try to resolve from database
-				if _, _, _, err := ee.dataStore.ResolveValueFor(ctx, cbuID, requiredAttr); err != nil {
-					return false // Required attribute not available
-				}
-			}
-		}
+func (ee *ExecutionEngine) evaluateTriggerConditions(actionDef *ActionDefinition, env *triggerEnv) bool {
+	expr, err := CompileTriggerConditions(actionDef.TriggerConditions)
+	if err != nil {
+		return false // Conditions stored before they were validated
 	}
-
-	return true
+	return expr == nil || expr.Eval(env)
 }
 
 func stringPtr(s string) *string {
@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/memstore"
+	"dsl-ob-poc/internal/store"
+)
+
+// failingValueStore is a DataStore whose attribute value writes fail
+type failingValueStore struct {
+	datastore.DataStore
+}
+
+func (failingValueStore) UpsertAttributeValue(context.Context, string, int, string, json.RawMessage, string, map[string]any) error {
+	return errors.New("disk full")
+}
+
+func TestProcessSuccessfulResponse_FailsWhenOutputsAreNotStored(t *testing.T) {
+	ctx := context.Background()
+	ds := memstore.New()
+	cbuID, err := ds.CreateCBU(ctx, "Acme Fund", "test", "testing")
+	if err != nil {
+		t.Fatalf("CreateCBU failed: %v", err)
+	}
+	if _, err := ds.InsertDSLWithState(ctx, cbuID, `(case.create (cbu.id "x"))`, store.StateCreated); err != nil {
+		t.Fatalf("InsertDSLWithState failed: %v", err)
+	}
+
+	ee := &ExecutionEngine{dataStore: failingValueStore{ds}}
+	actionDef := &ActionDefinition{ActionID: "action-1"}
+	actionDef.AttributeMapping.OutputMapping = []AttributeMap{{DSLAttributeID: "attr-1", APIResponsePath: "$.account_id"}}
+	execution := &ActionExecution{ExecutionID: "exec-1", CBUID: cbuID}
+	response := &APIResponse{StatusCode: 200}
+
+	result := ee.processSuccessfulResponse(ctx, execution, actionDef, response, map[string]any{"attr-1": "ACC-1"}, 0)
+	if result.Success || !result.Retryable {
+		t.Fatalf("Expected a retryable failure, got %+v", result)
+	}
+	if result.ErrorDetails == nil || !strings.Contains(*result.ErrorDetails, "attr-1") {
+		t.Errorf("Expected the failed attribute in the error, got %v", result.ErrorDetails)
+	}
+}
@@ -0,0 +1,455 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FixtureMode selects how the runtime HTTP client treats outgoing requests
+type FixtureMode string
+
+const (
+	// FixtureModeLive sends requests to the real endpoint
+	FixtureModeLive FixtureMode = "live"
+	// FixtureModeRecord sends requests to the real endpoint and saves each
+	// request/response pair, with secrets redacted, as a fixture
+	FixtureModeRecord FixtureMode = "record"
+	// FixtureModeReplay answers requests from saved fixtures without any
+	// network access
+	FixtureModeReplay FixtureMode = "replay"
+)
+
+// ErrFixtureNotFound is returned in replay mode for a request that has no
+// recorded fixture
+var ErrFixtureNotFound = errors.New("no recorded fixture")
+
+const redacted = "[REDACTED]"
+
+// fixtureReplayHeader marks a response answered from a fixture. Its secrets
+// were redacted when it was recorded, so they must not be stored.
+const fixtureReplayHeader = "X-Runtime-Fixture-Replay"
+
+// isReplayed reports whether resp was answered from a fixture
+func isReplayed(resp *http.Response) bool {
+	return resp.Header.Get(fixtureReplayHeader) != ""
+}
+
+// FixtureConfig selects a fixture mode per environment. Fixtures for an
+// environment are kept in Dir/<environment>, with characters that are not
+// safe in a file name replaced.
+type FixtureConfig struct {
+	Dir string
+	// Modes maps an environment to its mode; "*" applies to environments
+	// that are not listed. Environments without a mode run live.
+	Modes map[string]FixtureMode
+}
+
+// FixtureConfigFromEnv reads the fixture configuration from
+// RUNTIME_HTTP_FIXTURES and RUNTIME_HTTP_FIXTURES_DIR (default
+// data/fixtures). RUNTIME_HTTP_FIXTURES is either a mode for every
+// environment ("replay") or a list of environment=mode pairs
+// ("test=replay,staging=record").
+func FixtureConfigFromEnv() (FixtureConfig, error) {
+	cfg := FixtureConfig{Dir: os.Getenv("RUNTIME_HTTP_FIXTURES_DIR")}
+	if cfg.Dir == "" {
+		cfg.Dir = "data/fixtures"
+	}
+	modes, err := ParseFixtureModes(os.Getenv("RUNTIME_HTTP_FIXTURES"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid RUNTIME_HTTP_FIXTURES: %w", err)
+	}
+	cfg.Modes = modes
+	return cfg, nil
+}
+
+// ParseFixtureModes parses a fixture mode specification, see
+// FixtureConfigFromEnv
+func ParseFixtureModes(spec string) (map[string]FixtureMode, error) {
+	modes := make(map[string]FixtureMode)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		env, mode, found := strings.Cut(part, "=")
+		if !found {
+			env, mode = "*", env
+		}
+		env = strings.TrimSpace(env)
+		m := FixtureMode(strings.ToLower(strings.TrimSpace(mode)))
+		switch m {
+		case FixtureModeLive, FixtureModeRecord, FixtureModeReplay:
+		default:
+			return nil, fmt.Errorf("unknown fixture mode %q for environment %s", mode, env)
+		}
+		if env == "" {
+			return nil, fmt.Errorf("missing environment in %q", part)
+		}
+		modes[env] = m
+	}
+	return modes, nil
+}
+
+// ModeFor returns the fixture mode of an environment
+func (c FixtureConfig) ModeFor(environment string) FixtureMode {
+	if mode, ok := c.Modes[environment]; ok {
+		return mode
+	}
+	if mode, ok := c.Modes["*"]; ok {
+		return mode
+	}
+	return FixtureModeLive
+}
+
+// enabled reports whether any environment records or replays
+func (c FixtureConfig) enabled() bool {
+	for _, mode := range c.Modes {
+		if mode != FixtureModeLive {
+			return true
+		}
+	}
+	return false
+}
+
+type environmentKey struct{}
+
+// withEnvironment tags ctx with the environment whose fixture mode applies
+// to requests made under it
+func withEnvironment(ctx context.Context, environment string) context.Context {
+	if environment == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, environmentKey{}, environment)
+}
+
+func environmentFrom(ctx context.Context) string {
+	env, _ := ctx.Value(environmentKey{}).(string)
+	return env
+}
+
+// fixture is the file format of a recorded request key. Repeated requests
+// with the same key are kept in order, so that a sequence such as a 503
+// followed by a 200 replays the way it was recorded.
+type fixture struct {
+	Interactions []fixtureInteraction `json:"interactions"`
+}
+
+type fixtureInteraction struct {
+	Request    fixtureRequest  `json:"request"`
+	Response   fixtureResponse `json:"response"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+type fixtureRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+type fixtureResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+}
+
+// fixtureTransport records or replays requests according to the fixture
+// mode of the environment in the request context, passing everything else
+// to the wrapped transport
+type fixtureTransport struct {
+	base   http.RoundTripper
+	config FixtureConfig
+
+	mu       sync.Mutex
+	recorded map[string]bool // fixture files started by this process
+	replayed map[string]int  // interactions served per fixture file
+}
+
+func newFixtureTransport(base http.RoundTripper, config FixtureConfig) *fixtureTransport {
+	return &fixtureTransport{
+		base:     base,
+		config:   config,
+		recorded: make(map[string]bool),
+		replayed: make(map[string]int),
+	}
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	environment := environmentFrom(req.Context())
+	mode := t.config.ModeFor(environment)
+	if mode == FixtureModeLive {
+		return t.base.RoundTrip(req)
+	}
+
+	recordedReq, err := redactRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	// The environment names a directory, so it must not escape Dir
+	dir := strings.Trim(unsafeFileChars.ReplaceAllString(environment, "_"), "._")
+	if dir == "" {
+		dir = "default"
+	}
+	path := filepath.Join(t.config.Dir, dir, fixtureName(recordedReq))
+
+	if mode == FixtureModeReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, recordedReq, path)
+}
+
+func (t *fixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	f, err := readFixture(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(f.Interactions) == 0) {
+		return nil, fmt.Errorf("%w for %s %s (%s)", ErrFixtureNotFound, req.Method, redactURL(req.URL), path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	i := min(t.replayed[path], len(f.Interactions)-1)
+	t.replayed[path]++
+	t.mu.Unlock()
+
+	recorded := f.Interactions[i].Response
+	header := make(http.Header, len(recorded.Headers))
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+	// Redaction can change the body's length
+	header.Del("Content-Length")
+	header.Set(fixtureReplayHeader, "true")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *fixtureTransport) record(req *http.Request, recordedReq fixtureRequest, path string) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction := fixtureInteraction{
+		Request: recordedReq,
+		Response: fixtureResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    redactHeaders(resp.Header),
+			Body:       redactBody(body, resp.Header.Get("Content-Type")),
+		},
+		RecordedAt: time.Now().UTC(),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The first recording of a key in this process replaces what an
+	// earlier run left behind; later ones are appended
+	var f fixture
+	if t.recorded[path] {
+		if f, err = readFixture(path); err != nil {
+			return nil, err
+		}
+	}
+	f.Interactions = append(f.Interactions, interaction)
+	if err := writeFixture(path, f); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+	t.recorded[path] = true
+	return resp, nil
+}
+
+func readFixture(path string) (fixture, error) {
+	var f fixture
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func writeFixture(path string, f fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fixtureName derives the file a request is recorded in from its method,
+// redacted URL and redacted body. Headers are left out: trace, correlation
+// and idempotency headers differ on every run.
+func fixtureName(req fixtureRequest) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL + "\n" + req.Body))
+	u, _ := url.Parse(req.URL)
+	slug := strings.ToLower(req.Method)
+	if u != nil {
+		slug += "_" + u.Host + u.Path
+	}
+	slug = strings.Trim(unsafeFileChars.ReplaceAllString(slug, "_"), "_")
+	if len(slug) > 80 {
+		slug = slug[:80]
+	}
+	return slug + "_" + hex.EncodeToString(sum[:6]) + ".json"
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ---------------------------------------------------------------------------
+// Redaction
+// ---------------------------------------------------------------------------
+
+// sensitiveNameParts mark header, query parameter and body field names whose
+// values are secrets
+var sensitiveNameParts = []string{
+	"auth", "token", "secret", "password", "passwd", "cookie", "signature",
+	"api-key", "api_key", "apikey", "client_assertion",
+}
+
+func isSensitiveName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactRequest returns the recorded form of req, restoring its body so that
+// it can still be sent
+func redactRequest(req *http.Request) (fixtureRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return fixtureRequest{}, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return fixtureRequest{
+		Method:  req.Method,
+		URL:     redactURL(req.URL),
+		Headers: redactHeaders(req.Header),
+		Body:    redactBody(body, req.Header.Get("Content-Type")),
+	}, nil
+}
+
+func redactURL(u *url.URL) string {
+	clean := *u
+	if clean.User != nil {
+		clean.User = url.User(redacted)
+	}
+	if clean.RawQuery != "" {
+		query := clean.Query()
+		for name, values := range query {
+			if isSensitiveName(name) {
+				for i := range values {
+					values[i] = redacted
+				}
+			}
+		}
+		clean.RawQuery = query.Encode()
+	}
+	return clean.String()
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if isSensitiveName(name) {
+			out[name] = redacted
+		} else {
+			out[name] = strings.Join(values, ", ")
+		}
+	}
+	return out
+}
+
+// redactBody masks secret fields of JSON and form bodies. Other bodies are
+// kept as they are.
+func redactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for name := range form {
+				if isSensitiveName(name) {
+					form[name] = []string{redacted}
+				}
+			}
+			return form.Encode()
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return string(body)
+	}
+	out, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for name, child := range val {
+			if isSensitiveName(name) {
+				if _, scalar := child.(string); scalar || child == nil {
+					val[name] = redacted
+					continue
+				}
+			}
+			val[name] = redactValue(child)
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = redactValue(child)
+		}
+	}
+	return v
+}
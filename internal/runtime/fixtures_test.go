@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFixtures_RecordThenReplayOffline(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if r.Header.Get("X-API-Key") != "live-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error": "busy"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"account_id": "ACC-1", "session_token": "s3cr3t"}`))
+	}))
+
+	dir := t.TempDir()
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"custody": {"api_key": "live-key"},
+	}}
+	request := func() *APIRequest {
+		return &APIRequest{
+			Method:         "POST",
+			URL:            server.URL + "/accounts?api_key=live-key&region=eu",
+			Environment:    "test",
+			Body:           map[string]interface{}{"cbu_id": "CBU-1", "password": "hunter2"},
+			Authentication: map[string]interface{}{"type": "api_key", "credentials_ref": "custody"},
+		}
+	}
+	ctx := context.Background()
+
+	recorder := newHTTPClient(creds)
+	recorder.UseFixtures(FixtureConfig{Dir: dir, Modes: map[string]FixtureMode{"test": FixtureModeRecord}})
+	for _, want := range []int{503, 201} {
+		resp, err := recorder.Execute(ctx, request())
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("record: status = %d, want %d", resp.StatusCode, want)
+		}
+	}
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d fixture files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	for _, secret := range []string{"live-key", "hunter2", "s3cr3t", "session=abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture contains secret %q:\n%s", secret, data)
+		}
+	}
+
+	replayer := newHTTPClient(creds)
+	replayer.UseFixtures(FixtureConfig{Dir: dir, Modes: map[string]FixtureMode{"*": FixtureModeReplay}})
+	for _, want := range []int{503, 201, 201} {
+		resp, err := replayer.Execute(ctx, request())
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("replay: status = %d, want %d", resp.StatusCode, want)
+		}
+	}
+	resp, _ := replayer.Execute(ctx, request())
+	if resp.Body["account_id"] != "ACC-1" {
+		t.Errorf("replayed body = %v", resp.Body)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("server saw %d requests, want 2", calls.Load())
+	}
+
+	other := request()
+	other.Body["cbu_id"] = "CBU-2"
+	if _, err := replayer.Execute(ctx, other); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("unrecorded request: err = %v, want ErrFixtureNotFound", err)
+	}
+}
+
+func TestFixtures_ReplayedTokenIsNotRotated(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	api := newAPIServer(t, func(token string) bool { return token == "tok-1" })
+	dir := t.TempDir()
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {"token_url": tokens.URL, "client_id": "client", "client_secret": "secret"},
+	}}
+	request := oauth2Request(api.URL)
+	request.Environment = "test"
+
+	recorder := newHTTPClient(creds)
+	recorder.UseFixtures(FixtureConfig{Dir: dir, Modes: map[string]FixtureMode{"test": FixtureModeRecord}})
+	if _, err := recorder.Execute(context.Background(), request); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if creds.rotation != 1 {
+		t.Fatalf("rotations after recording = %d, want 1", creds.rotation)
+	}
+
+	// Replay starts from credentials without a stored token, so it grants
+	delete(creds.creds["partner"], "access_token")
+	delete(creds.creds["partner"], "refresh_token")
+	delete(creds.creds["partner"], "expires_at")
+	replayer := newHTTPClient(creds)
+	replayer.UseFixtures(FixtureConfig{Dir: dir, Modes: map[string]FixtureMode{"test": FixtureModeReplay}})
+	if _, err := replayer.Execute(context.Background(), request); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if creds.rotation != 1 {
+		t.Errorf("replayed token was rotated into the vault: %v", creds.creds["partner"])
+	}
+}
+
+func TestFixtures_EnvironmentStaysInsideDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	client := newHTTPClient(&fakeCredentials{})
+	client.UseFixtures(FixtureConfig{Dir: dir, Modes: map[string]FixtureMode{"*": FixtureModeRecord}})
+	if _, err := client.Execute(context.Background(), &APIRequest{Method: "GET", URL: server.URL, Environment: "../../escape"}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d fixture files inside %s, want 1", len(files), dir)
+	}
+}
+
+func TestParseFixtureModes(t *testing.T) {
+	modes, err := ParseFixtureModes("record, test=replay ,production=live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := FixtureConfig{Modes: modes}
+	for env, want := range map[string]FixtureMode{
+		"test":        FixtureModeReplay,
+		"production":  FixtureModeLive,
+		"development": FixtureModeRecord,
+	} {
+		if got := cfg.ModeFor(env); got != want {
+			t.Errorf("ModeFor(%s) = %s, want %s", env, got, want)
+		}
+	}
+	if (FixtureConfig{}).ModeFor("test") != FixtureModeLive {
+		t.Error("an empty config should run live")
+	}
+
+	for _, spec := range []string{"rewind", "test=", "=replay"} {
+		if _, err := ParseFixtureModes(spec); err == nil {
+			t.Errorf("ParseFixtureModes(%q) succeeded", spec)
+		}
+	}
+}
@@ -11,17 +11,25 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"dsl-ob-poc/internal/runtime/jsonpath"
+	"dsl-ob-poc/internal/runtime/jsonschema"
 )
 
 // HTTPClient provides HTTP API calling capabilities with authentication
 type HTTPClient struct {
 	client        *http.Client
-	credentialMgr *CredentialManager
+	credentialMgr credentialStore
+	oauth2        *oauth2TokenSource
 }
 
 // NewHTTPClient creates a new HTTP client for runtime API calls
 func NewHTTPClient(credentialMgr *CredentialManager) *HTTPClient {
-	return &HTTPClient{
+	return newHTTPClient(credentialMgr)
+}
+
+func newHTTPClient(credentialMgr credentialStore) *HTTPClient {
+	c := &HTTPClient{
 		client: &http.Client{
 			Timeout: 30 * time.Second, // Default timeout
 			Transport: &http.Transport{
@@ -36,12 +44,26 @@ func NewHTTPClient(credentialMgr *CredentialManager) *HTTPClient {
 		},
 		credentialMgr: credentialMgr,
 	}
+	c.oauth2 = newOAuth2TokenSource(c.client, credentialMgr)
+	return c
+}
+
+// UseFixtures records requests to, or replays them from, fixture files for
+// the environments config selects; other environments stay live. Token
+// requests for OAuth2 credentials are recorded and replayed along with the
+// API calls they authorize.
+func (c *HTTPClient) UseFixtures(config FixtureConfig) {
+	if !config.enabled() {
+		return
+	}
+	c.client.Transport = newFixtureTransport(c.client.Transport, config)
 }
 
 // APIRequest represents an HTTP API request to be executed
 type APIRequest struct {
 	Method         string                 `json:"method"`
 	URL            string                 `json:"url"`
+	Environment    string                 `json:"environment,omitempty"`
 	Headers        map[string]string      `json:"headers"`
 	Body           map[string]interface{} `json:"body"`
 	Authentication map[string]interface{} `json:"authentication"`
@@ -65,23 +87,7 @@ type APIResponse struct {
 // Execute performs an HTTP API request with authentication and observability
 func (c *HTTPClient) Execute(ctx context.Context, apiReq *APIRequest) (*APIResponse, error) {
 	startTime := time.Now()
-
-	// Create HTTP request
-	httpReq, err := c.createHTTPRequest(ctx, apiReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	// Apply authentication
-	if err := c.applyAuthentication(ctx, httpReq, apiReq.Authentication); err != nil {
-		return nil, fmt.Errorf("failed to apply authentication: %w", err)
-	}
-
-	// Apply headers
-	c.applyHeaders(httpReq, apiReq)
-
-	// Apply observability headers
-	c.applyObservabilityHeaders(httpReq, apiReq)
+	ctx = withEnvironment(ctx, apiReq.Environment)
 
 	// Set timeout if specified
 	if apiReq.TimeoutSeconds > 0 {
@@ -89,11 +95,27 @@ func (c *HTTPClient) Execute(ctx context.Context, apiReq *APIRequest) (*APIRespo
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
-		httpReq = httpReq.WithContext(ctx)
+	}
+
+	httpReq, err := c.prepareRequest(ctx, apiReq)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute request
 	httpResp, err := c.client.Do(httpReq)
+
+	// An OAuth2 token can be revoked or expire early; get another and try
+	// once more, keeping the 401 if no other token can be had
+	if err == nil && httpResp.StatusCode == http.StatusUnauthorized && isOAuth2Auth(apiReq.Authentication) {
+		credentialsRef, _ := apiReq.Authentication["credentials_ref"].(string)
+		c.oauth2.Invalidate(credentialsRef, strings.TrimPrefix(httpReq.Header.Get("Authorization"), "Bearer "))
+		if retryReq, prepErr := c.prepareRequest(ctx, apiReq); prepErr == nil {
+			httpResp.Body.Close()
+			httpResp, err = c.client.Do(retryReq)
+		}
+	}
+
 	if err != nil {
 		duration := time.Since(startTime)
 		errorMsg := err.Error()
@@ -149,6 +171,28 @@ func (c *HTTPClient) Execute(ctx context.Context, apiReq *APIRequest) (*APIRespo
 	return apiResp, nil
 }
 
+// prepareRequest creates an authenticated HTTP request ready to send
+func (c *HTTPClient) prepareRequest(ctx context.Context, apiReq *APIRequest) (*http.Request, error) {
+	// Create HTTP request
+	httpReq, err := c.createHTTPRequest(ctx, apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	// Apply authentication
+	if err := c.applyAuthentication(ctx, httpReq, apiReq.Authentication); err != nil {
+		return nil, fmt.Errorf("failed to apply authentication: %w", err)
+	}
+
+	// Apply headers
+	c.applyHeaders(httpReq, apiReq)
+
+	// Apply observability headers
+	c.applyObservabilityHeaders(httpReq, apiReq)
+
+	return httpReq, nil
+}
+
 // createHTTPRequest creates an HTTP request from APIRequest
 func (c *HTTPClient) createHTTPRequest(ctx context.Context, apiReq *APIRequest) (*http.Request, error) {
 	var bodyReader io.Reader
@@ -293,27 +337,29 @@ func (c *HTTPClient) applyBasicAuth(ctx context.Context, req *http.Request, auth
 	return nil
 }
 
-// applyOAuth2Auth applies OAuth2 authentication
+// applyOAuth2Auth applies OAuth2 authentication. Tokens are cached per
+// credential and, when the credential has a token_url, obtained with the
+// refresh-token or client-credentials grant once they expire.
 func (c *HTTPClient) applyOAuth2Auth(ctx context.Context, req *http.Request, authConfig map[string]interface{}) error {
 	credentialsRef, ok := authConfig["credentials_ref"].(string)
 	if !ok {
 		return fmt.Errorf("credentials_ref not specified for OAuth2 auth")
 	}
 
-	credentials, err := c.credentialMgr.GetCredentials(ctx, credentialsRef)
+	accessToken, err := c.oauth2.Token(ctx, credentialsRef)
 	if err != nil {
-		return fmt.Errorf("failed to get OAuth2 credentials: %w", err)
-	}
-
-	accessToken, ok := credentials["access_token"].(string)
-	if !ok {
-		return fmt.Errorf("access_token not found in credentials")
+		return err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 	return nil
 }
 
+func isOAuth2Auth(authConfig map[string]interface{}) bool {
+	authType, _ := authConfig["type"].(string)
+	return strings.EqualFold(authType, "oauth2")
+}
+
 // applyCustomAuth applies custom authentication
 func (c *HTTPClient) applyCustomAuth(ctx context.Context, req *http.Request, authConfig map[string]interface{}) error {
 	credentialsRef, ok := authConfig["credentials_ref"].(string)
@@ -391,7 +437,10 @@ func (c *HTTPClient) extractHeaders(resp *http.Response) map[string]string {
 	return headers
 }
 
-// ValidateResponse checks if the response meets success criteria
+// ValidateResponse checks if the response meets success criteria. A status
+// outside the accepted codes is a plain error; a body that breaks the
+// response schema, required outputs or response validation expression is a
+// *ResponseValidationError.
 func (c *HTTPClient) ValidateResponse(resp *APIResponse, criteria SuccessCriteria) error {
 	// Check HTTP status codes
 	if len(criteria.HTTPStatusCodes) > 0 {
@@ -407,25 +456,38 @@ func (c *HTTPClient) ValidateResponse(resp *APIResponse, criteria SuccessCriteri
 		}
 	}
 
-	// Check response validation (JSONPath-like expression)
+	doc := responseDocument(resp)
+
+	// Check the body against the response schema
+	if len(criteria.ResponseSchema) > 0 {
+		schema, err := jsonschema.Compile(criteria.ResponseSchema)
+		if err != nil {
+			return &ResponseValidationError{Err: fmt.Errorf("invalid response_schema: %w", err)}
+		}
+		if err := schema.Validate(doc); err != nil {
+			return &ResponseValidationError{Err: err}
+		}
+	}
+
+	// Check response validation (<path> == <value>)
 	if criteria.ResponseValidation != nil {
-		// This is a simplified validation - in production you'd use a proper JSONPath library
-		validation := *criteria.ResponseValidation
-		if strings.Contains(validation, "$.status == 'CREATED'") {
-			if status, ok := resp.Body["status"].(string); !ok || status != "CREATED" {
-				return fmt.Errorf("response validation failed: status is not 'CREATED'")
-			}
+		check, err := compileResponseCheck(*criteria.ResponseValidation)
+		if err != nil {
+			return &ResponseValidationError{Err: err}
+		}
+		if err := check.check(doc); err != nil {
+			return &ResponseValidationError{Err: err}
 		}
-		// Add more validation logic as needed
 	}
 
 	// Check required outputs are present
-	if len(criteria.RequiredOutputs) > 0 {
-		for _, requiredOutput := range criteria.RequiredOutputs {
-			// Simple check for presence in response body
-			if _, exists := resp.Body[requiredOutput]; !exists {
-				return fmt.Errorf("required output '%s' not found in response", requiredOutput)
-			}
+	for _, requiredOutput := range criteria.RequiredOutputs {
+		path, err := jsonpath.Compile(requiredOutput)
+		if err != nil {
+			return &ResponseValidationError{Err: fmt.Errorf("invalid required output %q: %w", requiredOutput, err)}
+		}
+		if value, ok := path.Get(doc); !ok || value == nil {
+			return &ResponseValidationError{Err: fmt.Errorf("required output '%s' not found in response", requiredOutput)}
 		}
 	}
 
@@ -463,6 +525,11 @@ func (c *HTTPClient) ShouldRetry(resp *APIResponse, failureConfig FailureHandlin
 
 // CalculateBackoffDelay calculates the delay before next retry
 func (c *HTTPClient) CalculateBackoffDelay(retryCount int, config RetryConfig) time.Duration {
+	return backoffDelay(retryCount, config)
+}
+
+// backoffDelay is the delay before retry number retryCount+1 under config
+func backoffDelay(retryCount int, config RetryConfig) time.Duration {
 	baseDelay := time.Duration(config.BaseDelayMS) * time.Millisecond
 
 	switch strings.ToLower(config.BackoffStrategy) {
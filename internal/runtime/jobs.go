@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+// jobPayload is what an action job carries: the request to execute and the
+// action's retry schedule at the time it was enqueued
+type jobPayload struct {
+	Request *ExecutionRequest `json:"request"`
+	Retry   RetryConfig       `json:"retry"`
+}
+
+// EnqueueAction queues req for a runtime worker instead of executing it, and
+// returns the job ID. The job is attempted up to MaxRetries+1 times.
+func (ee *ExecutionEngine) EnqueueAction(ctx context.Context, req *ExecutionRequest) (string, error) {
+	actionDef, err := ee.repository.GetActionDefinition(ctx, req.ActionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get action definition: %w", err)
+	}
+
+	retry := actionDef.ExecutionConfig.RetryConfig
+	payload, err := json.Marshal(jobPayload{Request: req, Retry: retry})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	jobID, err := ee.dataStore.EnqueueJob(ctx, &store.ActionJob{
+		ActionID:    req.ActionID,
+		CBUID:       req.CBUID,
+		Payload:     payload,
+		MaxAttempts: retry.MaxRetries + 1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue action %s: %w", req.ActionID, err)
+	}
+	return jobID, nil
+}
+
+// JobQueue is the part of the data store a worker leases jobs from;
+// datastore.DataStore satisfies it
+type JobQueue interface {
+	LeaseJob(ctx context.Context, owner string, lease time.Duration) (*store.ActionJob, error)
+	HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error
+	CompleteJob(ctx context.Context, jobID, owner, executionID string) error
+	RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error
+	DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error
+}
+
+// AttemptExecutor makes a single attempt at an execution request;
+// ExecutionEngine implements it
+type AttemptExecutor interface {
+	ExecuteAttempt(ctx context.Context, req *ExecutionRequest, attempt int) (*ExecutionResult, error)
+}
+
+// WorkerConfig configures a runtime worker; zero values select the defaults
+type WorkerConfig struct {
+	// Owner identifies the worker in job leases (default host-pid)
+	Owner string
+	// LeaseDuration is how long a leased job stays claimed without a
+	// heartbeat (default 1m); heartbeats are sent every third of it
+	LeaseDuration time.Duration
+	// PollInterval is how long an idle worker waits before polling again
+	// (default 2s)
+	PollInterval time.Duration
+}
+
+// Worker leases queued action jobs and executes them one attempt at a time,
+// scheduling retries with the action's backoff and dead-lettering jobs that
+// fail permanently or run out of attempts
+type Worker struct {
+	queue    JobQueue
+	executor AttemptExecutor
+	config   WorkerConfig
+}
+
+// NewWorker creates a worker that takes jobs from queue and runs them with
+// executor
+func NewWorker(queue JobQueue, executor AttemptExecutor, config WorkerConfig) *Worker {
+	if config.Owner == "" {
+		host, _ := os.Hostname()
+		config.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	return &Worker{queue: queue, executor: executor, config: config}
+}
+
+// Run processes jobs until ctx is cancelled. Errors from individual jobs are
+// logged and do not stop the worker.
+func (w *Worker) Run(ctx context.Context) error {
+	for {
+		processed, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("runtime-worker %s: %v", w.config.Owner, err)
+		}
+		if processed && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.config.PollInterval):
+		}
+	}
+}
+
+// RunOnce leases and processes at most one due job, reporting whether there
+// was one
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.queue.LeaseJob(ctx, w.config.Owner, w.config.LeaseDuration)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+	return true, w.process(ctx, job)
+}
+
+// process makes one attempt at a leased job and records the outcome
+func (w *Worker) process(ctx context.Context, job *store.ActionJob) error {
+	var payload jobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || payload.Request == nil {
+		return w.queue.DeadLetterJob(ctx, job.JobID, w.config.Owner, fmt.Sprintf("invalid job payload: %v", err))
+	}
+
+	// A worker that died on the final attempt leaves the job leased with no
+	// attempts to spare
+	if job.Attempts > job.MaxAttempts {
+		return w.queue.DeadLetterJob(ctx, job.JobID, w.config.Owner, fmt.Sprintf("lease expired on final attempt: %s", deref(job.LastError)))
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	heartbeatDone := make(chan error, 1)
+	go func() { heartbeatDone <- w.heartbeat(attemptCtx, cancel, job.JobID) }()
+
+	result, err := w.executor.ExecuteAttempt(attemptCtx, payload.Request, job.Attempts)
+	cancel()
+	if hbErr := <-heartbeatDone; hbErr != nil {
+		// Another worker owns the job now; its outcome is not ours to record
+		return hbErr
+	}
+
+	var lastError string
+	retryable := true
+	switch {
+	case err != nil:
+		lastError = err.Error()
+	case result.Success:
+		return w.queue.CompleteJob(ctx, job.JobID, w.config.Owner, result.ExecutionID)
+	default:
+		lastError = deref(result.ErrorDetails)
+		retryable = result.Retryable
+	}
+
+	if !retryable || job.Attempts >= job.MaxAttempts {
+		return w.queue.DeadLetterJob(ctx, job.JobID, w.config.Owner, lastError)
+	}
+	runAt := time.Now().Add(backoffDelay(job.Attempts-1, payload.Retry))
+	return w.queue.RetryJob(ctx, job.JobID, w.config.Owner, runAt, lastError)
+}
+
+// heartbeat extends the job's lease until ctx is done. If the lease is lost
+// it cancels the attempt and returns the error.
+func (w *Worker) heartbeat(ctx context.Context, cancel context.CancelFunc, jobID string) error {
+	ticker := time.NewTicker(w.config.LeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := w.queue.HeartbeatJob(ctx, jobID, w.config.Owner, w.config.LeaseDuration)
+			if errors.Is(err, store.ErrLeaseLost) {
+				cancel()
+				return err
+			}
+			if err != nil {
+				log.Printf("runtime-worker %s: heartbeat for job %s failed: %v", w.config.Owner, jobID, err)
+			}
+		}
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
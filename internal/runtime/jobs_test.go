@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/mocks"
+	"dsl-ob-poc/internal/store"
+)
+
+// scriptedExecutor returns its results in order, one per attempt
+type scriptedExecutor struct {
+	results  []*ExecutionResult
+	attempts []int
+}
+
+func (e *scriptedExecutor) ExecuteAttempt(ctx context.Context, req *ExecutionRequest, attempt int) (*ExecutionResult, error) {
+	e.attempts = append(e.attempts, attempt)
+	result := e.results[0]
+	e.results = e.results[1:]
+	if result == nil {
+		return nil, errors.New("failed to create execution record")
+	}
+	return result, nil
+}
+
+func enqueueTestJob(t *testing.T, queue *mocks.MockStore, retry RetryConfig) string {
+	t.Helper()
+	payload, _ := json.Marshal(jobPayload{Request: &ExecutionRequest{ActionID: "action-1", CBUID: "cbu-1"}, Retry: retry})
+	jobID, err := queue.EnqueueJob(context.Background(), &store.ActionJob{
+		ActionID:    "action-1",
+		CBUID:       "cbu-1",
+		Payload:     payload,
+		MaxAttempts: retry.MaxRetries + 1,
+	})
+	if err != nil {
+		t.Fatalf("EnqueueJob failed: %v", err)
+	}
+	return jobID
+}
+
+func onlyJob(t *testing.T, queue *mocks.MockStore) store.ActionJob {
+	t.Helper()
+	jobs, _ := queue.ListJobs(context.Background(), "")
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job, got %d", len(jobs))
+	}
+	return jobs[0]
+}
+
+func TestWorker_CompletesJob(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	enqueueTestJob(t, queue, RetryConfig{MaxRetries: 2})
+	executor := &scriptedExecutor{results: []*ExecutionResult{{ExecutionID: "exec-1", Success: true}}}
+	worker := NewWorker(queue, executor, WorkerConfig{Owner: "worker-1"})
+
+	processed, err := worker.RunOnce(context.Background())
+	if !processed || err != nil {
+		t.Fatalf("expected one job processed, got %v (%v)", processed, err)
+	}
+	job := onlyJob(t, queue)
+	if job.Status != store.JobSucceeded || job.ExecutionID == nil || *job.ExecutionID != "exec-1" {
+		t.Errorf("expected a succeeded job for exec-1, got %+v", job)
+	}
+	if processed, _ := worker.RunOnce(context.Background()); processed {
+		t.Error("expected an empty queue")
+	}
+}
+
+func TestWorker_SchedulesRetryWithBackoff(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	enqueueTestJob(t, queue, RetryConfig{MaxRetries: 3, BackoffStrategy: "exponential", BaseDelayMS: 60000})
+	executor := &scriptedExecutor{results: []*ExecutionResult{
+		{Success: false, ErrorDetails: stringPtr("execution failed: HTTP 503"), Retryable: true},
+	}}
+	worker := NewWorker(queue, executor, WorkerConfig{Owner: "worker-1"})
+
+	before := time.Now()
+	if _, err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	job := onlyJob(t, queue)
+	if job.Status != store.JobQueued || job.Attempts != 1 || *job.LastError != "execution failed: HTTP 503" {
+		t.Fatalf("expected the job requeued after attempt 1, got %+v", job)
+	}
+	if delay := job.RunAt.Sub(before); delay < time.Minute || delay > time.Minute+time.Second {
+		t.Errorf("expected the first retry a base delay (1m) away, got %s", delay)
+	}
+
+	// The retry is not due yet, so the worker finds nothing to do
+	if processed, _ := worker.RunOnce(context.Background()); processed {
+		t.Error("expected the retry to wait for its backoff")
+	}
+}
+
+func TestWorker_DeadLettersAfterLastAttempt(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	enqueueTestJob(t, queue, RetryConfig{MaxRetries: 1, BackoffStrategy: "fixed", BaseDelayMS: 1})
+	executor := &scriptedExecutor{results: []*ExecutionResult{
+		{Success: false, ErrorDetails: stringPtr("execution failed: HTTP 503"), Retryable: true},
+		nil,
+	}}
+	worker := NewWorker(queue, executor, WorkerConfig{Owner: "worker-1"})
+
+	if _, err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	job := onlyJob(t, queue)
+	if job.Status != store.JobDead || *job.LastError != "failed to create execution record" {
+		t.Errorf("expected the job dead-lettered after its last attempt, got %+v", job)
+	}
+	if len(executor.attempts) != 2 || executor.attempts[1] != 2 {
+		t.Errorf("expected attempts 1 and 2, got %v", executor.attempts)
+	}
+}
+
+func TestWorker_DeadLettersPermanentFailure(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	enqueueTestJob(t, queue, RetryConfig{MaxRetries: 5, BaseDelayMS: 10})
+	executor := &scriptedExecutor{results: []*ExecutionResult{
+		{Success: false, ErrorDetails: stringPtr("execution failed: HTTP 400")},
+	}}
+	worker := NewWorker(queue, executor, WorkerConfig{Owner: "worker-1"})
+
+	if _, err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if job := onlyJob(t, queue); job.Status != store.JobDead || job.Attempts != 1 {
+		t.Errorf("expected a non-retryable failure to dead-letter at once, got %+v", job)
+	}
+}
+
+func TestWorker_DeadLettersJobWhoseFinalLeaseExpired(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	enqueueTestJob(t, queue, RetryConfig{})
+	// A worker that leased the only attempt and died
+	if _, err := queue.LeaseJob(context.Background(), "crashed-worker", -time.Second); err != nil {
+		t.Fatalf("LeaseJob failed: %v", err)
+	}
+	executor := &scriptedExecutor{}
+	worker := NewWorker(queue, executor, WorkerConfig{Owner: "worker-1"})
+
+	if _, err := worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if job := onlyJob(t, queue); job.Status != store.JobDead {
+		t.Errorf("expected the job dead-lettered, got %+v", job)
+	}
+	if len(executor.attempts) != 0 {
+		t.Errorf("expected no further attempt, got %v", executor.attempts)
+	}
+}
+
+// stolenLeaseExecutor loses its job's lease mid-attempt and waits for the
+// worker to cancel it
+type stolenLeaseExecutor struct {
+	queue *mocks.MockStore
+	jobID string
+}
+
+func (e *stolenLeaseExecutor) ExecuteAttempt(ctx context.Context, req *ExecutionRequest, attempt int) (*ExecutionResult, error) {
+	if err := e.queue.RetryJob(context.Background(), e.jobID, "worker-1", time.Now(), "reclaimed"); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Second):
+		return &ExecutionResult{Success: true}, nil
+	}
+}
+
+func TestWorker_CancelsAttemptWhenLeaseIsLost(t *testing.T) {
+	queue := mocks.NewMockStore("")
+	jobID := enqueueTestJob(t, queue, RetryConfig{MaxRetries: 1})
+	worker := NewWorker(queue, &stolenLeaseExecutor{queue: queue, jobID: jobID}, WorkerConfig{Owner: "worker-1", LeaseDuration: 30 * time.Millisecond})
+
+	_, err := worker.RunOnce(context.Background())
+	if !errors.Is(err, store.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if job := onlyJob(t, queue); job.Status != store.JobQueued || *job.LastError != "reclaimed" {
+		t.Errorf("expected the new owner's outcome to stand, got %+v", job)
+	}
+}
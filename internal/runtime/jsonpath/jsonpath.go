@@ -0,0 +1,435 @@
+// Package jsonpath evaluates the JSONPath expressions that map API responses
+// onto attributes. It supports the subset that output mappings need:
+//
+//	$                the whole document
+//	.name, ['name']  an object member
+//	[0], [-1]        an array element, counting from the end when negative
+//	[1:3], [:2]      an array slice
+//	.*, [*]          every member or element
+//	..name           name at any depth below
+//	[0,2], ['a','b'] a union of indexes or names
+//
+// A path without a leading $ is taken relative to the document, so the
+// dotted paths of older mappings ("account.id") keep working. Filter and
+// script expressions are not supported.
+package jsonpath
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath expression
+type Path struct {
+	src      string
+	segments []segment
+	definite bool
+}
+
+type segmentKind int
+
+const (
+	segName segmentKind = iota
+	segIndex
+	segSlice
+	segWildcard
+	segDescend // recursive descent to the names in the segment
+)
+
+type segment struct {
+	kind    segmentKind
+	names   []string
+	indexes []int
+	// slice bounds; hasStart/hasEnd report whether they were given
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+// Error is a compile error with the byte offset it was found at
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid JSONPath at offset %d: %s", e.Pos, e.Msg)
+}
+
+// Compile parses src
+func Compile(src string) (*Path, error) {
+	expr := strings.TrimSpace(src)
+	if expr == "" {
+		return nil, &Error{Pos: 0, Msg: "empty path"}
+	}
+	offset := 0
+	if expr[0] == '$' {
+		expr = expr[1:]
+		offset = 1
+	} else if expr[0] != '.' && expr[0] != '[' {
+		expr = "." + expr
+		offset = -1
+	}
+
+	p := &pathParser{src: expr, offset: offset}
+	segments, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	definite := true
+	for _, seg := range segments {
+		if seg.kind == segSlice || seg.kind == segWildcard || seg.kind == segDescend ||
+			len(seg.names) > 1 || len(seg.indexes) > 1 {
+			definite = false
+		}
+	}
+	return &Path{src: src, segments: segments, definite: definite}, nil
+}
+
+// String returns the source the path was compiled from
+func (p *Path) String() string { return p.src }
+
+// Definite reports whether the path selects at most one value, i.e. uses no
+// wildcard, slice, union or recursive descent
+func (p *Path) Definite() bool { return p.definite }
+
+// Query returns every value the path selects in doc, in document order
+func (p *Path) Query(doc any) []any {
+	current := []any{doc}
+	for _, seg := range p.segments {
+		var next []any
+		for _, v := range current {
+			next = seg.apply(v, next)
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+	return current
+}
+
+// Get returns the value of a definite path, or every selected value as a
+// slice for an indefinite one. ok is false when a definite path selects
+// nothing; an indefinite path always succeeds, possibly with an empty slice.
+func (p *Path) Get(doc any) (value any, ok bool) {
+	values := p.Query(doc)
+	if !p.definite {
+		if values == nil {
+			values = []any{}
+		}
+		return values, true
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func (seg *segment) apply(v any, out []any) []any {
+	switch seg.kind {
+	case segName:
+		if obj, ok := v.(map[string]any); ok {
+			for _, name := range seg.names {
+				if child, ok := obj[name]; ok {
+					out = append(out, child)
+				}
+			}
+		}
+	case segIndex:
+		if arr, ok := v.([]any); ok {
+			for _, i := range seg.indexes {
+				if i < 0 {
+					i += len(arr)
+				}
+				if i >= 0 && i < len(arr) {
+					out = append(out, arr[i])
+				}
+			}
+		}
+	case segSlice:
+		if arr, ok := v.([]any); ok {
+			start, end := 0, len(arr)
+			if seg.hasStart {
+				start = clampIndex(seg.start, len(arr))
+			}
+			if seg.hasEnd {
+				end = clampIndex(seg.end, len(arr))
+			}
+			for i := start; i < end; i++ {
+				out = append(out, arr[i])
+			}
+		}
+	case segWildcard:
+		out = appendChildren(v, out)
+	case segDescend:
+		out = seg.descend(v, out)
+	}
+	return out
+}
+
+// descend selects the segment's names in v and everything below it
+func (seg *segment) descend(v any, out []any) []any {
+	if obj, ok := v.(map[string]any); ok {
+		for _, name := range seg.names {
+			if name == "*" {
+				out = appendChildren(v, out)
+			} else if child, ok := obj[name]; ok {
+				out = append(out, child)
+			}
+		}
+	}
+	for _, child := range appendChildren(v, nil) {
+		out = seg.descend(child, out)
+	}
+	return out
+}
+
+// appendChildren appends the members of an object in key order, or the
+// elements of an array
+func appendChildren(v any, out []any) []any {
+	switch c := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(c) {
+			out = append(out, c[k])
+		}
+	case []any:
+		out = append(out, c...)
+	}
+	return out
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// ---------------------------------------------------------------------------
+// Parser
+// ---------------------------------------------------------------------------
+
+type pathParser struct {
+	src    string
+	pos    int
+	offset int // added to pos in errors, to point into the original source
+}
+
+func (p *pathParser) errorf(format string, args ...any) error {
+	return &Error{Pos: max(0, p.pos+p.offset), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *pathParser) parse() ([]segment, error) {
+	var segments []segment
+	for p.pos < len(p.src) {
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], ".."):
+			p.pos += 2
+			seg := segment{kind: segDescend}
+			if p.pos < len(p.src) && p.src[p.pos] == '[' {
+				inner, err := p.parseBracket()
+				if err != nil {
+					return nil, err
+				}
+				if inner.kind == segWildcard {
+					seg.names = []string{"*"}
+				} else if inner.kind == segName {
+					seg.names = inner.names
+				} else {
+					return nil, p.errorf("recursive descent takes member names")
+				}
+			} else {
+				name, err := p.parseName()
+				if err != nil {
+					return nil, err
+				}
+				seg.names = []string{name}
+			}
+			segments = append(segments, seg)
+		case p.src[p.pos] == '.':
+			p.pos++
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			if name == "*" {
+				segments = append(segments, segment{kind: segWildcard})
+			} else {
+				segments = append(segments, segment{kind: segName, names: []string{name}})
+			}
+		case p.src[p.pos] == '[':
+			seg, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, seg)
+		default:
+			return nil, p.errorf("unexpected %q", p.src[p.pos])
+		}
+	}
+	return segments, nil
+}
+
+func (p *pathParser) parseName() (string, error) {
+	if p.pos < len(p.src) && p.src[p.pos] == '*' {
+		p.pos++
+		return "*", nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '.' && p.src[p.pos] != '[' {
+		c := p.src[p.pos]
+		if c == ' ' || c == ']' || c == '\'' || c == '"' || c == '(' || c == ')' || c == '?' || c == '@' {
+			return "", p.errorf("unexpected %q in member name", c)
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a member name")
+	}
+	return p.src[start:p.pos], nil
+}
+
+// parseBracket parses [...] with p.pos on the opening bracket
+func (p *pathParser) parseBracket() (segment, error) {
+	p.pos++ // [
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return segment{}, p.errorf("unterminated [")
+	}
+
+	var seg segment
+	switch c := p.src[p.pos]; {
+	case c == '*':
+		p.pos++
+		seg = segment{kind: segWildcard}
+	case c == '\'' || c == '"':
+		seg.kind = segName
+		for {
+			name, err := p.parseQuoted()
+			if err != nil {
+				return segment{}, err
+			}
+			seg.names = append(seg.names, name)
+			if !p.acceptComma() {
+				break
+			}
+		}
+	case c == '?' || c == '(':
+		return segment{}, p.errorf("filter and script expressions are not supported")
+	default:
+		first, hasFirst, err := p.parseInt()
+		if err != nil {
+			return segment{}, err
+		}
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ':' {
+			p.pos++
+			seg = segment{kind: segSlice, start: first, hasStart: hasFirst}
+			seg.end, seg.hasEnd, err = p.parseInt()
+			if err != nil {
+				return segment{}, err
+			}
+			p.skipSpace()
+			if p.pos < len(p.src) && p.src[p.pos] == ':' {
+				return segment{}, p.errorf("slice steps are not supported")
+			}
+			break
+		}
+		if !hasFirst {
+			return segment{}, p.errorf("expected an index, name or *")
+		}
+		seg = segment{kind: segIndex, indexes: []int{first}}
+		for p.acceptComma() {
+			i, ok, err := p.parseInt()
+			if err != nil {
+				return segment{}, err
+			}
+			if !ok {
+				return segment{}, p.errorf("expected an index")
+			}
+			seg.indexes = append(seg.indexes, i)
+		}
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+		return segment{}, p.errorf("expected ]")
+	}
+	p.pos++
+	return seg, nil
+}
+
+func (p *pathParser) parseQuoted() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || (p.src[p.pos] != '\'' && p.src[p.pos] != '"') {
+		return "", p.errorf("expected a quoted name")
+	}
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseInt parses an optional signed integer, reporting whether there was one
+func (p *pathParser) parseInt() (int, bool, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos < len(p.src) && p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false, nil
+	}
+	text := p.src[start:p.pos]
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		p.pos = start
+		return 0, false, p.errorf("invalid index %q", text)
+	}
+	return n, true, nil
+}
+
+func (p *pathParser) acceptComma() bool {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == ',' {
+		p.pos++
+		p.skipSpace()
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
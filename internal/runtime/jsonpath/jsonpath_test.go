@@ -0,0 +1,97 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const doc = `{
+	"status": "CREATED",
+	"account": {"id": "ACC-1", "currency": "EUR"},
+	"sub.key": 7,
+	"accounts": [
+		{"id": "A", "balance": 10, "tags": ["x"]},
+		{"id": "B", "balance": 20},
+		{"id": "C", "balance": 30, "owner": {"id": "O-1"}}
+	]
+}`
+
+func decode(t *testing.T) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestGet(t *testing.T) {
+	v := decode(t)
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"$", v, true},
+		{"$.status", "CREATED", true},
+		{"status", "CREATED", true},
+		{"account.id", "ACC-1", true},
+		{"$['account']['currency']", "EUR", true},
+		{`$["sub.key"]`, 7.0, true},
+		{"$.accounts[0].id", "A", true},
+		{"$.accounts[-1].id", "C", true},
+		{"$.accounts[3].id", nil, false},
+		{"$.missing", nil, false},
+		{"$.status.deeper", nil, false},
+		{"$.accounts[*].id", []any{"A", "B", "C"}, true},
+		{"$.accounts.*.balance", []any{10.0, 20.0, 30.0}, true},
+		{"$.accounts[1:].id", []any{"B", "C"}, true},
+		{"$.accounts[:-1].id", []any{"A", "B"}, true},
+		{"$.accounts[0,2].id", []any{"A", "C"}, true},
+		{"$.accounts[*].owner.id", []any{"O-1"}, true},
+		{"$..owner.id", []any{"O-1"}, true},
+		{"$.accounts[*].missing", []any{}, true},
+		{"$.account['id','currency']", []any{"ACC-1", "EUR"}, true},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.path)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.path, err)
+			continue
+		}
+		got, ok := p.Get(v)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %#v, %t; want %#v, %t", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRecursiveDescent(t *testing.T) {
+	p, err := Compile("$..id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := p.Query(decode(t))
+	want := []any{"ACC-1", "A", "B", "C", "O-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("$..id = %v, want %v", got, want)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"$.",
+		"$[",
+		"$[?(@.id == 'A')]",
+		"$.accounts[0:2:1]",
+		"$['unterminated]",
+		"$.a b",
+		"$..[0]",
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%q) succeeded", src)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+// Package jsonschema validates API responses against the JSON Schema an
+// action definition declares for them. It implements the validation
+// keywords of JSON Schema (2020-12) that response contracts use:
+//
+//	type, enum, const
+//	properties, required, additionalProperties, minProperties, maxProperties
+//	items, minItems, maxItems, uniqueItems
+//	minLength, maxLength, pattern, format (date, date-time, email, uuid, uri)
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//	allOf, anyOf, oneOf, not
+//
+// Annotations ($schema, $id, title, description, default, examples,
+// deprecated, readOnly, writeOnly) are accepted and ignored. Any other
+// keyword, $ref included, is rejected when the schema is compiled, so a
+// stored schema never silently validates less than it says.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	always *bool // true and false schemas
+
+	types     []string
+	enum      []any
+	constant  any
+	hasConst  bool
+	format    string
+	pattern   *regexp.Regexp
+	minLength *int
+	maxLength *int
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+}
+
+// ValidationError lists every way an instance fails a schema
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Violation is one failed keyword at an instance location, given as a JSON
+// Pointer ("" for the whole document)
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		path := v.Path
+		if path == "" {
+			path = "/"
+		}
+		msgs = append(msgs, path+": "+v.Message)
+	}
+	return "response does not match schema: " + strings.Join(msgs, "; ")
+}
+
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+}
+
+var knownTypes = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+var knownFormats = []string{"date", "date-time", "email", "uuid", "uri"}
+
+// Compile parses a schema document
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var doc any
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	return compile(doc, "#")
+}
+
+func compile(doc any, at string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON Schema at %s: expected an object or boolean", at)
+	}
+
+	s := &Schema{}
+	for _, key := range sortedKeys(obj) {
+		value := obj[key]
+		where := at + "/" + key
+		var err error
+		switch key {
+		case "type":
+			s.types, err = typeList(value, where)
+		case "enum":
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: expected an array", where)
+			}
+			s.enum = list
+		case "const":
+			s.constant, s.hasConst = value, true
+		case "format":
+			f, ok := value.(string)
+			if !ok || !slices.Contains(knownFormats, f) {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: format must be one of %v", where, knownFormats)
+			}
+			s.format = f
+		case "pattern":
+			p, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: expected a string", where)
+			}
+			if s.pattern, err = regexp.Compile(p); err != nil {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: %w", where, err)
+			}
+		case "minLength":
+			s.minLength, err = count(value, where)
+		case "maxLength":
+			s.maxLength, err = count(value, where)
+		case "minimum":
+			s.minimum, err = number(value, where)
+		case "maximum":
+			s.maximum, err = number(value, where)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = number(value, where)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = number(value, where)
+		case "multipleOf":
+			if s.multipleOf, err = number(value, where); err == nil && *s.multipleOf <= 0 {
+				err = fmt.Errorf("invalid JSON Schema at %s: must be positive", where)
+			}
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: expected an object", where)
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for _, name := range sortedKeys(props) {
+				if s.properties[name], err = compile(props[name], where+"/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: expected an array of names", where)
+			}
+			for _, item := range list {
+				name, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid JSON Schema at %s: expected an array of names", where)
+				}
+				s.required = append(s.required, name)
+			}
+		case "additionalProperties":
+			s.additionalProperties, err = compile(value, where)
+		case "minProperties":
+			s.minProperties, err = count(value, where)
+		case "maxProperties":
+			s.maxProperties, err = count(value, where)
+		case "items":
+			s.items, err = compile(value, where)
+		case "minItems":
+			s.minItems, err = count(value, where)
+		case "maxItems":
+			s.maxItems, err = count(value, where)
+		case "uniqueItems":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: expected a boolean", where)
+			}
+			s.uniqueItems = b
+		case "allOf", "anyOf", "oneOf":
+			var list []*Schema
+			if list, err = schemaList(value, where); err == nil {
+				switch key {
+				case "allOf":
+					s.allOf = list
+				case "anyOf":
+					s.anyOf = list
+				default:
+					s.oneOf = list
+				}
+			}
+		case "not":
+			s.not, err = compile(value, where)
+		default:
+			if !annotations[key] {
+				return nil, fmt.Errorf("invalid JSON Schema at %s: unsupported keyword %q", at, key)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func typeList(value any, at string) ([]string, error) {
+	var names []any
+	switch v := value.(type) {
+	case string:
+		names = []any{v}
+	case []any:
+		names = v
+	default:
+		return nil, fmt.Errorf("invalid JSON Schema at %s: expected a type name or array of names", at)
+	}
+	var types []string
+	for _, n := range names {
+		name, ok := n.(string)
+		if !ok || !slices.Contains(knownTypes, name) {
+			return nil, fmt.Errorf("invalid JSON Schema at %s: unknown type %v", at, n)
+		}
+		types = append(types, name)
+	}
+	return types, nil
+}
+
+func schemaList(value any, at string) ([]*Schema, error) {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("invalid JSON Schema at %s: expected a non-empty array of schemas", at)
+	}
+	schemas := make([]*Schema, 0, len(list))
+	for i, item := range list {
+		s, err := compile(item, at+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, nil
+}
+
+func number(value any, at string) (*float64, error) {
+	if n, ok := toFloat(value); ok {
+		return &n, nil
+	}
+	return nil, fmt.Errorf("invalid JSON Schema at %s: expected a number", at)
+}
+
+func count(value any, at string) (*int, error) {
+	n, ok := toFloat(value)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("invalid JSON Schema at %s: expected a non-negative integer", at)
+	}
+	i := int(n)
+	return &i, nil
+}
+
+// Validate checks a decoded JSON instance (as produced by encoding/json)
+// against the schema, returning a *ValidationError listing every violation
+func (s *Schema) Validate(instance any) error {
+	var violations []Violation
+	s.validate(instance, "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *Schema) valid(instance any) bool {
+	var violations []Violation
+	s.validate(instance, "", &violations)
+	return len(violations) == 0
+}
+
+func (s *Schema) validate(v any, path string, out *[]Violation) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			fail("no value is allowed here")
+		}
+		return
+	}
+
+	if len(s.types) > 0 {
+		matched := false
+		for _, t := range s.types {
+			if hasType(v, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(s.types, " or "), typeOf(v))
+			return
+		}
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return equal(e, v) }) {
+		fail("value %s is not one of the allowed values", render(v))
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		fail("value %s is not %s", render(v), render(s.constant))
+	}
+
+	switch value := v.(type) {
+	case string:
+		s.validateString(value, fail)
+	case map[string]any:
+		s.validateObject(value, path, out, fail)
+	case []any:
+		s.validateArray(value, path, out, fail)
+	default:
+		if n, ok := toFloat(v); ok {
+			s.validateNumber(n, fail)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, out)
+	}
+	if s.anyOf != nil && !slices.ContainsFunc(s.anyOf, func(sub *Schema) bool { return sub.valid(v) }) {
+		fail("value matches none of the anyOf schemas")
+	}
+	if s.oneOf != nil {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(v) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			fail("value matches %d of the oneOf schemas, expected exactly 1", matches)
+		}
+	}
+	if s.not != nil && s.not.valid(v) {
+		fail("value must not match the schema under not")
+	}
+}
+
+func (s *Schema) validateString(value string, fail func(string, ...any)) {
+	length := utf8.RuneCountInString(value)
+	if s.minLength != nil && length < *s.minLength {
+		fail("string is shorter than %d characters", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		fail("string is longer than %d characters", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		fail("string does not match pattern %q", s.pattern.String())
+	}
+	if s.format != "" && !validFormat(s.format, value) {
+		fail("string is not a valid %s", s.format)
+	}
+}
+
+func (s *Schema) validateNumber(n float64, fail func(string, ...any)) {
+	if s.minimum != nil && n < *s.minimum {
+		fail("%v is less than the minimum %v", n, *s.minimum)
+	}
+	if s.maximum != nil && n > *s.maximum {
+		fail("%v is greater than the maximum %v", n, *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		fail("%v is not greater than %v", n, *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		fail("%v is not less than %v", n, *s.exclusiveMaximum)
+	}
+	if s.multipleOf != nil {
+		if q := n / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("%v is not a multiple of %v", n, *s.multipleOf)
+		}
+	}
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string, out *[]Violation, fail func(string, ...any)) {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			fail("missing required property %q", name)
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		fail("object has fewer than %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		fail("object has more than %d properties", *s.maxProperties)
+	}
+	for _, name := range sortedKeys(obj) {
+		child := path + "/" + escapePointer(name)
+		if sub, ok := s.properties[name]; ok {
+			sub.validate(obj[name], child, out)
+		} else if s.additionalProperties != nil {
+			s.additionalProperties.validate(obj[name], child, out)
+		}
+	}
+}
+
+func (s *Schema) validateArray(arr []any, path string, out *[]Violation, fail func(string, ...any)) {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		fail("array has fewer than %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		fail("array has more than %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+	outer:
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					fail("items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			s.items.validate(item, path+"/"+strconv.Itoa(i), out)
+		}
+	}
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "null":
+		return v == nil
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		n, ok := toFloat(v)
+		return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+	}
+	return false
+}
+
+func typeOf(v any) string {
+	for _, t := range []string{"null", "boolean", "object", "array", "string", "integer", "number"} {
+		if hasType(v, t) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equal compares JSON values, treating numbers by value
+func equal(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	switch x := a.(type) {
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			yv, ok := y[k]
+			if !ok || !equal(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func validFormat(format, value string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	case "uuid":
+		_, err := uuid.Parse(value)
+		return err == nil && len(value) == 36
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+func render(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(b) > 64 {
+		return string(b[:61]) + "..."
+	}
+	return string(b)
+}
+
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const accountSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"title": "Custody account created",
+	"type": "object",
+	"required": ["account_id", "status"],
+	"properties": {
+		"account_id": {"type": "string", "pattern": "^ACC-[0-9]+$"},
+		"status": {"enum": ["CREATED", "PENDING"]},
+		"opened_on": {"type": "string", "format": "date"},
+		"balance": {"type": "number", "minimum": 0},
+		"sub_accounts": {
+			"type": "array",
+			"maxItems": 3,
+			"uniqueItems": true,
+			"items": {"type": "object", "required": ["currency"], "properties": {"currency": {"type": "string", "minLength": 3, "maxLength": 3}}}
+		},
+		"owner": {"anyOf": [{"type": "null"}, {"type": "string", "format": "email"}]}
+	},
+	"additionalProperties": false
+}`
+
+func decode(t *testing.T, src string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile(json.RawMessage(accountSchema))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	valid := []string{
+		`{"account_id": "ACC-1", "status": "CREATED"}`,
+		`{"account_id": "ACC-22", "status": "PENDING", "opened_on": "2024-03-01", "balance": 0,
+		  "sub_accounts": [{"currency": "EUR"}, {"currency": "USD"}], "owner": null}`,
+		`{"account_id": "ACC-3", "status": "CREATED", "owner": "ops@example.com"}`,
+	}
+	for _, src := range valid {
+		if err := schema.Validate(decode(t, src)); err != nil {
+			t.Errorf("Validate(%s): %v", src, err)
+		}
+	}
+
+	invalid := map[string]string{
+		`{"status": "CREATED"}`:                                                                                    `/: missing required property "account_id"`,
+		`{"account_id": "X-1", "status": "CREATED"}`:                                                               `/account_id: string does not match pattern`,
+		`{"account_id": "ACC-1", "status": "CLOSED"}`:                                                              `/status: value "CLOSED" is not one of the allowed values`,
+		`{"account_id": "ACC-1", "status": "CREATED", "extra": 1}`:                                                 `/extra: no value is allowed here`,
+		`{"account_id": 1, "status": "CREATED"}`:                                                                   `/account_id: expected string, got integer`,
+		`{"account_id": "ACC-1", "status": "CREATED", "balance": -1}`:                                              `/balance: -1 is less than the minimum 0`,
+		`{"account_id": "ACC-1", "status": "CREATED", "opened_on": "01/03/2024"}`:                                  `/opened_on: string is not a valid date`,
+		`{"account_id": "ACC-1", "status": "CREATED", "sub_accounts": [{"currency": "EURO"}]}`:                     `/sub_accounts/0/currency: string is longer than 3 characters`,
+		`{"account_id": "ACC-1", "status": "CREATED", "sub_accounts": [{"currency": "EUR"}, {"currency": "EUR"}]}`: `/sub_accounts: items 0 and 1 are equal`,
+		`{"account_id": "ACC-1", "status": "CREATED", "owner": "nobody"}`:                                          `/owner: value matches none of the anyOf schemas`,
+		`[]`: `/: expected object, got array`,
+	}
+	for src, want := range invalid {
+		err := schema.Validate(decode(t, src))
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Validate(%s) = %v, want a ValidationError", src, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%s) = %q, want it to contain %q", src, err, want)
+		}
+	}
+}
+
+func TestValidate_Combinators(t *testing.T) {
+	schema, err := Compile(json.RawMessage(`{
+		"oneOf": [{"type": "integer", "multipleOf": 5}, {"type": "integer", "multipleOf": 3}],
+		"not": {"const": 30}
+	}`))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	for v, ok := range map[string]bool{"5": true, "9": true, "15": false, "30": false, "7": false, "5.5": false} {
+		if got := schema.Validate(decode(t, v)) == nil; got != ok {
+			t.Errorf("Validate(%s) valid = %t, want %t", v, got, ok)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		`{"$ref": "#/$defs/account"}`,
+		`{"type": "decimal"}`,
+		`{"properties": {"id": {"minLength": -1}}}`,
+		`{"pattern": "("}`,
+		`{"format": "iban"}`,
+		`{"anyOf": []}`,
+		`[]`,
+		`{`,
+	} {
+		if _, err := Compile(json.RawMessage(src)); err == nil {
+			t.Errorf("Compile(%s) succeeded", src)
+		}
+	}
+}
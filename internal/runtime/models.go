@@ -59,11 +59,32 @@ type ResourceTypeEndpoint struct {
 	Authentication  json.RawMessage `json:"authentication" db:"authentication"`
 	TimeoutSeconds  int             `json:"timeout_seconds" db:"timeout_seconds"`
 	RetryConfig     json.RawMessage `json:"retry_config" db:"retry_config"`
+	RateLimit       json.RawMessage `json:"rate_limit,omitempty" db:"rate_limit"`           // RateLimitConfig
+	CircuitBreaker  json.RawMessage `json:"circuit_breaker,omitempty" db:"circuit_breaker"` // CircuitBreakerConfig
 	Environment     string          `json:"environment" db:"environment"`
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// RateLimitConfig limits how fast the engine calls an endpoint. Burst
+// defaults to the per-second rate; an attempt that would wait longer than
+// MaxWaitSeconds (default 30) for a slot fails as retryable instead.
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst,omitempty"`
+	MaxWaitSeconds    int     `json:"max_wait_seconds,omitempty"`
+}
+
+// CircuitBreakerConfig stops calls to an endpoint after FailureThreshold
+// (default 5) consecutive failures. After OpenSeconds (default 30) it lets
+// HalfOpenProbes (default 1) requests through; their success closes the
+// breaker and a failure opens it again.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	OpenSeconds      int `json:"open_seconds,omitempty"`
+	HalfOpenProbes   int `json:"half_open_probes,omitempty"`
+}
+
 // ActionDefinition represents a DSL verb to API endpoint mapping
 type ActionDefinition struct {
 	ActionID          string           `json:"action_id" db:"action_id"`
@@ -77,11 +98,14 @@ type ActionDefinition struct {
 	AttributeMapping  AttributeMapping `json:"attribute_mapping" db:"attribute_mapping"`
 	SuccessCriteria   json.RawMessage  `json:"success_criteria" db:"success_criteria"`
 	FailureHandling   json.RawMessage  `json:"failure_handling" db:"failure_handling"`
-	Active            bool             `json:"active" db:"active"`
-	Version           int              `json:"version" db:"version"`
-	Environment       string           `json:"environment" db:"environment"`
-	CreatedAt         time.Time        `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time        `json:"updated_at" db:"updated_at"`
+	// CompensatingActionID names the action that undoes this one when a
+	// later action triggered by the same DSL change fails
+	CompensatingActionID *string   `json:"compensating_action_id,omitempty" db:"compensating_action_id"`
+	Active               bool      `json:"active" db:"active"`
+	Version              int       `json:"version" db:"version"`
+	Environment          string    `json:"environment" db:"environment"`
+	CreatedAt            time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // ExecutionConfig holds configuration for how to execute an action
@@ -128,7 +152,7 @@ type AttributeMapping struct {
 type AttributeMap struct {
 	DSLAttributeID  string `json:"dsl_attribute_id"`
 	APIParameter    string `json:"api_parameter,omitempty"`
-	APIResponsePath string `json:"api_response_path,omitempty"`
+	APIResponsePath string `json:"api_response_path,omitempty"` // JSONPath, see package jsonpath
 	AttributeName   string `json:"attribute_name,omitempty"`
 	Transformation  string `json:"transformation,omitempty"`
 }
@@ -189,8 +213,12 @@ type CredentialVault struct {
 	Active         bool       `json:"active" db:"active"`
 }
 
-// TriggerConditions represents conditions that must be met to trigger an action
+// TriggerConditions represents conditions that must be met to trigger an action.
+// Expression is a trigger predicate (see package trigger); Domain, State and
+// AttributeRequirements are shorthands for domain(...), state == ... and
+// has(...) clauses.
 type TriggerConditions struct {
+	Expression            *string        `json:"expression,omitempty"`
 	Domain                *string        `json:"domain,omitempty"`
 	State                 *string        `json:"state,omitempty"`
 	AttributeRequirements []string       `json:"attribute_requirements,omitempty"`
@@ -199,9 +227,15 @@ type TriggerConditions struct {
 
 // SuccessCriteria represents criteria for determining execution success
 type SuccessCriteria struct {
-	HTTPStatusCodes    []int    `json:"http_status_codes"`
-	ResponseValidation *string  `json:"response_validation,omitempty"`
-	RequiredOutputs    []string `json:"required_outputs,omitempty"`
+	HTTPStatusCodes []int `json:"http_status_codes"`
+	// ResponseSchema is a JSON Schema the response body must match
+	// (see package jsonschema for the supported keywords)
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+	// ResponseValidation is a check of the form <path> == <value>,
+	// e.g. $.status == 'CREATED'
+	ResponseValidation *string `json:"response_validation,omitempty"`
+	// RequiredOutputs are JSONPath expressions that must select a value
+	RequiredOutputs []string `json:"required_outputs,omitempty"`
 }
 
 // FailureHandling represents how to handle execution failures
@@ -234,4 +268,79 @@ type ExecutionResult struct {
 	DurationMS       int            `json:"duration_ms"`
 	IdempotencyKey   *string        `json:"idempotency_key,omitempty"`
 	CorrelationID    *string        `json:"correlation_id,omitempty"`
+	// Retryable marks a failed single attempt that the action's failure
+	// handling would retry
+	Retryable bool `json:"retryable,omitempty"`
+	// Compensation is the result of the compensating action that undid this
+	// execution after a later action of its saga failed
+	Compensation *ExecutionResult `json:"compensation,omitempty"`
+}
+
+// SagaStatus represents where a multi-action saga is
+type SagaStatus string
+
+const (
+	SagaStatusRunning            SagaStatus = "RUNNING"
+	SagaStatusCompleted          SagaStatus = "COMPLETED"
+	SagaStatusCompensating       SagaStatus = "COMPENSATING"
+	SagaStatusCompensated        SagaStatus = "COMPENSATED"
+	SagaStatusCompensationFailed SagaStatus = "COMPENSATION_FAILED"
+)
+
+// SagaStepStatus represents the outcome of one action in a saga
+type SagaStepStatus string
+
+const (
+	SagaStepRunning            SagaStepStatus = "RUNNING"
+	SagaStepCompleted          SagaStepStatus = "COMPLETED"
+	SagaStepFailed             SagaStepStatus = "FAILED"
+	SagaStepCompensated        SagaStepStatus = "COMPENSATED"
+	SagaStepCompensationFailed SagaStepStatus = "COMPENSATION_FAILED"
+	SagaStepNotCompensable     SagaStepStatus = "NOT_COMPENSABLE"
+)
+
+// CircuitState represents the state of an endpoint's circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "CLOSED"
+	CircuitOpen     CircuitState = "OPEN"
+	CircuitHalfOpen CircuitState = "HALF_OPEN"
+)
+
+// CircuitBreakerState is the persisted state of an endpoint's circuit breaker
+type CircuitBreakerState struct {
+	EndpointID          string       `json:"endpoint_id" db:"endpoint_id"`
+	EndpointURL         string       `json:"endpoint_url,omitempty" db:"endpoint_url"`
+	State               CircuitState `json:"state" db:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures" db:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty" db:"opened_at"`
+	LastError           *string      `json:"last_error,omitempty" db:"last_error"`
+	UpdatedAt           time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// Saga is the log of the actions triggered together by one DSL change
+type Saga struct {
+	SagaID       string     `json:"saga_id" db:"saga_id"`
+	CBUID        string     `json:"cbu_id" db:"cbu_id"`
+	DSLVersionID string     `json:"dsl_version_id" db:"dsl_version_id"`
+	Status       SagaStatus `json:"status" db:"status"`
+	Error        *string    `json:"error,omitempty" db:"error"`
+	Steps        []SagaStep `json:"steps"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SagaStep records one forward execution of a saga and its compensation
+type SagaStep struct {
+	SagaID                  string         `json:"saga_id" db:"saga_id"`
+	StepNo                  int            `json:"step_no" db:"step_no"`
+	ActionID                string         `json:"action_id" db:"action_id"`
+	ExecutionID             *string        `json:"execution_id,omitempty" db:"execution_id"`
+	CompensatingActionID    *string        `json:"compensating_action_id,omitempty" db:"compensating_action_id"`
+	CompensationExecutionID *string        `json:"compensation_execution_id,omitempty" db:"compensation_execution_id"`
+	Status                  SagaStepStatus `json:"status" db:"status"`
+	Error                   *string        `json:"error,omitempty" db:"error"`
+	CreatedAt               time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time      `json:"updated_at" db:"updated_at"`
 }
@@ -0,0 +1,286 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauth2ExpirySkew is how long before its stated expiry a token is treated
+// as expired, so that it does not lapse between being applied and arriving
+const oauth2ExpirySkew = 30 * time.Second
+
+// credentialStore is the part of CredentialManager the HTTP client needs
+type credentialStore interface {
+	GetCredentials(ctx context.Context, name string) (map[string]interface{}, error)
+	RotateCredentials(ctx context.Context, name string, newCredentials map[string]interface{}) error
+}
+
+// oauth2Token is an access token and when it stops being usable; a zero
+// expiry never expires
+type oauth2Token struct {
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+	// replayed is set for a token answered from a fixture, whose values
+	// are redacted placeholders
+	replayed bool
+}
+
+func (t *oauth2Token) valid(now time.Time) bool {
+	return t != nil && t.accessToken != "" && (t.expiresAt.IsZero() || now.Add(oauth2ExpirySkew).Before(t.expiresAt))
+}
+
+// oauth2TokenSource caches OAuth2 tokens per credential and obtains new ones
+// from the credential's token endpoint. Each credential has its own lock so
+// that concurrent calls wait for one grant rather than each making their own.
+type oauth2TokenSource struct {
+	client      *http.Client
+	credentials credentialStore
+
+	mu      sync.Mutex
+	entries map[string]*oauth2Entry
+}
+
+type oauth2Entry struct {
+	mu       sync.Mutex
+	token    *oauth2Token
+	rejected string // last token an API answered with 401
+}
+
+func newOAuth2TokenSource(client *http.Client, credentials credentialStore) *oauth2TokenSource {
+	return &oauth2TokenSource{
+		client:      client,
+		credentials: credentials,
+		entries:     make(map[string]*oauth2Entry),
+	}
+}
+
+func (ts *oauth2TokenSource) entry(credentialsRef string) *oauth2Entry {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	e, ok := ts.entries[credentialsRef]
+	if !ok {
+		e = &oauth2Entry{}
+		ts.entries[credentialsRef] = e
+	}
+	return e
+}
+
+// Token returns a usable access token for the credential. The cached token is
+// used while it is valid; otherwise the stored credential is consulted, and a
+// new token is granted when it holds no valid one either.
+func (ts *oauth2TokenSource) Token(ctx context.Context, credentialsRef string) (string, error) {
+	e := ts.entry(credentialsRef)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	rejected := e.rejected
+	if e.token.valid(now) && e.token.accessToken != rejected {
+		return e.token.accessToken, nil
+	}
+
+	credentials, err := ts.credentials.GetCredentials(ctx, credentialsRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth2 credentials: %w", err)
+	}
+
+	stored := storedOAuth2Token(credentials)
+	if stored.valid(now) && stored.accessToken != rejected {
+		e.token = stored
+		return stored.accessToken, nil
+	}
+
+	tokenURL, _ := credentials["token_url"].(string)
+	if tokenURL == "" {
+		if stored.accessToken == "" {
+			return "", fmt.Errorf("access_token not found in credentials")
+		}
+		return "", fmt.Errorf("OAuth2 access token for '%s' is no longer valid and no token_url is configured", credentialsRef)
+	}
+
+	// A cached refresh token may be newer than the stored one if rotation failed
+	refreshToken := stored.refreshToken
+	if e.token != nil && e.token.refreshToken != "" {
+		refreshToken = e.token.refreshToken
+	}
+
+	token, err := ts.grant(ctx, tokenURL, credentials, refreshToken)
+	if err != nil {
+		return "", err
+	}
+	if token.refreshToken == "" {
+		token.refreshToken = refreshToken
+	}
+	e.token = token
+	e.rejected = ""
+
+	// A replayed token is a redacted placeholder and must not replace the
+	// stored one
+	if token.replayed {
+		return token.accessToken, nil
+	}
+
+	// The vault keeps the latest token so that other processes and restarts
+	// can use it; a failed rotation only costs them a grant of their own
+	rotated := make(map[string]interface{}, len(credentials)+3)
+	for k, v := range credentials {
+		rotated[k] = v
+	}
+	rotated["access_token"] = token.accessToken
+	if token.refreshToken != "" {
+		rotated["refresh_token"] = token.refreshToken
+	}
+	if token.expiresAt.IsZero() {
+		delete(rotated, "expires_at")
+	} else {
+		rotated["expires_at"] = token.expiresAt.UTC().Format(time.RFC3339)
+	}
+	if err := ts.credentials.RotateCredentials(ctx, credentialsRef, rotated); err != nil {
+		log.Printf("oauth2: failed to rotate credentials '%s': %v", credentialsRef, err)
+	}
+
+	return token.accessToken, nil
+}
+
+// Invalidate records that an API rejected accessToken, so the next Token call
+// obtains another one. It is a no-op if the credential has moved on to a
+// different token since, as another call may already have refreshed it.
+func (ts *oauth2TokenSource) Invalidate(credentialsRef, accessToken string) {
+	e := ts.entry(credentialsRef)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token == nil || e.token.accessToken == accessToken {
+		e.rejected = accessToken
+	}
+}
+
+// grant obtains a token with the refresh-token grant when a refresh token is
+// available, falling back to the client-credentials grant when that fails
+// and the credential has a client ID
+func (ts *oauth2TokenSource) grant(ctx context.Context, tokenURL string, credentials map[string]interface{}, refreshToken string) (*oauth2Token, error) {
+	clientID, _ := credentials["client_id"].(string)
+
+	if refreshToken != "" {
+		token, err := ts.requestToken(ctx, tokenURL, credentials, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+		})
+		if err == nil || clientID == "" {
+			return token, err
+		}
+		log.Printf("oauth2: refresh token grant failed, falling back to client credentials: %v", err)
+	}
+
+	if clientID == "" {
+		return nil, fmt.Errorf("client_id not found in OAuth2 credentials")
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if scope, ok := credentials["scope"].(string); ok && scope != "" {
+		form.Set("scope", scope)
+	}
+	if audience, ok := credentials["audience"].(string); ok && audience != "" {
+		form.Set("audience", audience)
+	}
+	return ts.requestToken(ctx, tokenURL, credentials, form)
+}
+
+// requestToken posts a grant to the token endpoint. The client authenticates
+// with client_secret_post unless the credential sets auth_style to "basic".
+func (ts *oauth2TokenSource) requestToken(ctx context.Context, tokenURL string, credentials map[string]interface{}, form url.Values) (*oauth2Token, error) {
+	clientID, _ := credentials["client_id"].(string)
+	clientSecret, _ := credentials["client_secret"].(string)
+	authStyle, _ := credentials["auth_style"].(string)
+
+	useBasic := strings.EqualFold(authStyle, "basic") && clientID != ""
+	if !useBasic && clientID != "" {
+		form.Set("client_id", clientID)
+		if clientSecret != "" {
+			form.Set("client_secret", clientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	grantType := form.Get("grant_type")
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s grant failed: %w", grantType, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResp struct {
+		AccessToken      string      `json:"access_token"`
+		TokenType        string      `json:"token_type"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		RefreshToken     string      `json:"refresh_token"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokenResp.Error != "" {
+		if tokenResp.Error != "" {
+			return nil, fmt.Errorf("%s grant rejected with HTTP %d: %s %s", grantType, resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%s grant rejected with HTTP %d", grantType, resp.StatusCode)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	// Recording redacts token_type along with the tokens
+	replayed := isReplayed(resp)
+	if tokenResp.TokenType != "" && !replayed && !strings.EqualFold(tokenResp.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported token type: %s", tokenResp.TokenType)
+	}
+
+	token := &oauth2Token{accessToken: tokenResp.AccessToken, refreshToken: tokenResp.RefreshToken, replayed: replayed}
+	if tokenResp.ExpiresIn != "" {
+		seconds, err := tokenResp.ExpiresIn.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_in %q in token response", tokenResp.ExpiresIn)
+		}
+		if seconds > 0 {
+			token.expiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return token, nil
+}
+
+// storedOAuth2Token reads the token kept in a credential. A token with an
+// unparseable expires_at is treated as expired.
+func storedOAuth2Token(credentials map[string]interface{}) *oauth2Token {
+	token := &oauth2Token{}
+	token.accessToken, _ = credentials["access_token"].(string)
+	token.refreshToken, _ = credentials["refresh_token"].(string)
+	if expiresAt, ok := credentials["expires_at"].(string); ok && expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			t = time.Unix(0, 0)
+		}
+		token.expiresAt = t
+	}
+	return token
+}
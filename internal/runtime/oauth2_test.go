@@ -0,0 +1,222 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCredentials is an in-memory credentialStore
+type fakeCredentials struct {
+	mu       sync.Mutex
+	creds    map[string]map[string]interface{}
+	rotation int
+}
+
+func (f *fakeCredentials) GetCredentials(ctx context.Context, name string) (map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.creds[name]
+	if !ok {
+		return nil, fmt.Errorf("credentials '%s' not found", name)
+	}
+	out := make(map[string]interface{}, len(c))
+	for k, v := range c {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (f *fakeCredentials) RotateCredentials(ctx context.Context, name string, newCredentials map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.creds[name] = newCredentials
+	f.rotation++
+	return nil
+}
+
+// tokenServer issues tok-1, tok-2, ... and records the grants it was asked for
+type tokenServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	grants []string
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse token request: %v", err)
+		}
+		ts.mu.Lock()
+		grant := r.PostForm.Get("grant_type")
+		if grant == "client_credentials" && (r.PostForm.Get("client_id") != "client" || r.PostForm.Get("client_secret") != "secret") {
+			ts.mu.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client"}`)
+			return
+		}
+		if grant == "refresh_token" && r.PostForm.Get("refresh_token") != "refresh-1" {
+			ts.mu.Unlock()
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		ts.grants = append(ts.grants, grant)
+		n := len(ts.grants)
+		ts.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"Bearer","expires_in":%d,"refresh_token":"refresh-1"}`, n, expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) Grants() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]string(nil), ts.grants...)
+}
+
+// newAPIServer accepts bearer tokens for which accept returns true
+func newAPIServer(t *testing.T, accept func(token string) bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		if len(token) < 7 || !accept(token[7:]) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token[7:])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func oauth2Request(url string) *APIRequest {
+	return &APIRequest{
+		Method:         http.MethodGet,
+		URL:            url,
+		Authentication: map[string]interface{}{"type": "oauth2", "credentials_ref": "partner"},
+	}
+}
+
+func TestOAuth2_ClientCredentialsGrantIsCached(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	api := newAPIServer(t, func(token string) bool { return token == "tok-1" })
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {"token_url": tokens.URL, "client_id": "client", "client_secret": "secret"},
+	}}
+	client := newHTTPClient(creds)
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Execute(context.Background(), oauth2Request(api.URL))
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("call %d: status = %d, want 200", i, resp.StatusCode)
+		}
+	}
+
+	if got := tokens.Grants(); len(got) != 1 || got[0] != "client_credentials" {
+		t.Fatalf("grants = %v, want one client_credentials grant", got)
+	}
+	stored := creds.creds["partner"]
+	if stored["access_token"] != "tok-1" || stored["refresh_token"] != "refresh-1" || stored["client_id"] != "client" {
+		t.Fatalf("rotated credentials = %v", stored)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, stored["expires_at"].(string))
+	if err != nil || time.Until(expiresAt) < 59*time.Minute {
+		t.Fatalf("expires_at = %v (%v), want about an hour from now", stored["expires_at"], err)
+	}
+}
+
+func TestOAuth2_ExpiredStoredTokenIsRefreshed(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	api := newAPIServer(t, func(token string) bool { return token == "tok-1" })
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {
+			"token_url":     tokens.URL,
+			"access_token":  "stale",
+			"expires_at":    time.Now().Add(-time.Minute).Format(time.RFC3339),
+			"refresh_token": "refresh-1",
+		},
+	}}
+
+	resp, err := newHTTPClient(creds).Execute(context.Background(), oauth2Request(api.URL))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := tokens.Grants(); len(got) != 1 || got[0] != "refresh_token" {
+		t.Fatalf("grants = %v, want one refresh_token grant", got)
+	}
+}
+
+func TestOAuth2_RefreshesOnUnauthorized(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	// tok-1 was revoked before its expiry
+	api := newAPIServer(t, func(token string) bool { return token == "tok-2" })
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {"token_url": tokens.URL, "client_id": "client", "client_secret": "secret"},
+	}}
+	client := newHTTPClient(creds)
+
+	resp, err := client.Execute(context.Background(), oauth2Request(api.URL))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body["token"] != "tok-2" {
+		t.Fatalf("response = %d %v, want 200 with tok-2", resp.StatusCode, resp.Body)
+	}
+	if creds.creds["partner"]["access_token"] != "tok-2" || creds.rotation != 2 {
+		t.Fatalf("stored token = %v after %d rotations, want tok-2 after 2", creds.creds["partner"]["access_token"], creds.rotation)
+	}
+
+	// The refreshed token is cached for later calls
+	if _, err := client.Execute(context.Background(), oauth2Request(api.URL)); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := tokens.Grants(); len(got) != 2 {
+		t.Fatalf("grants = %v, want 2", got)
+	}
+}
+
+func TestOAuth2_UnauthorizedWithoutTokenEndpoint(t *testing.T) {
+	calls := 0
+	api := newAPIServer(t, func(token string) bool { calls++; return false })
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {"access_token": "static"},
+	}}
+
+	resp, err := newHTTPClient(creds).Execute(context.Background(), oauth2Request(api.URL))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || calls != 1 {
+		t.Fatalf("status = %d after %d calls, want the first 401", resp.StatusCode, calls)
+	}
+}
+
+func TestOAuth2_GrantRejected(t *testing.T) {
+	tokens := newTokenServer(t, 3600)
+	creds := &fakeCredentials{creds: map[string]map[string]interface{}{
+		"partner": {"token_url": tokens.URL, "client_id": "client", "client_secret": "wrong"},
+	}}
+
+	_, err := newHTTPClient(creds).Execute(context.Background(), oauth2Request("http://127.0.0.1:1"))
+	if err == nil {
+		t.Fatal("Execute succeeded with a rejected client secret")
+	}
+	if creds.rotation != 0 {
+		t.Fatalf("credentials rotated %d times after a failed grant", creds.rotation)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 // Repository provides data access for runtime execution components
@@ -128,7 +130,7 @@ func (r *Repository) GetResourceTypeEndpoint(ctx context.Context, resourceTypeID
 	query := `
 		SELECT endpoint_id, resource_type_id, lifecycle_action, endpoint_url,
 			   method, authentication, timeout_seconds, retry_config,
-			   environment, created_at, updated_at
+			   rate_limit, circuit_breaker, environment, created_at, updated_at
 		FROM resource_type_endpoints
 		WHERE resource_type_id = $1 AND lifecycle_action = $2 AND environment = $3`
 
@@ -136,7 +138,8 @@ func (r *Repository) GetResourceTypeEndpoint(ctx context.Context, resourceTypeID
 	err := r.db.QueryRowContext(ctx, query, resourceTypeID, lifecycleAction, environment).Scan(
 		&endpoint.EndpointID, &endpoint.ResourceTypeID, &endpoint.LifecycleAction,
 		&endpoint.EndpointURL, &endpoint.Method, &endpoint.Authentication,
-		&endpoint.TimeoutSeconds, &endpoint.RetryConfig, &endpoint.Environment,
+		&endpoint.TimeoutSeconds, &endpoint.RetryConfig,
+		&endpoint.RateLimit, &endpoint.CircuitBreaker, &endpoint.Environment,
 		&endpoint.CreatedAt, &endpoint.UpdatedAt,
 	)
 
@@ -152,7 +155,7 @@ func (r *Repository) GetResourceTypeEndpointByName(ctx context.Context, resource
 	query := `
 		SELECT rte.endpoint_id, rte.resource_type_id, rte.lifecycle_action, rte.endpoint_url,
 			   rte.method, rte.authentication, rte.timeout_seconds, rte.retry_config,
-			   rte.environment, rte.created_at, rte.updated_at
+			   rte.rate_limit, rte.circuit_breaker, rte.environment, rte.created_at, rte.updated_at
 		FROM resource_type_endpoints rte
 		JOIN resource_types rt ON rte.resource_type_id = rt.resource_type_id
 		WHERE rt.resource_type_name = $1 AND rte.lifecycle_action = $2
@@ -162,7 +165,8 @@ func (r *Repository) GetResourceTypeEndpointByName(ctx context.Context, resource
 	err := r.db.QueryRowContext(ctx, query, resourceTypeName, lifecycleAction, environment).Scan(
 		&endpoint.EndpointID, &endpoint.ResourceTypeID, &endpoint.LifecycleAction,
 		&endpoint.EndpointURL, &endpoint.Method, &endpoint.Authentication,
-		&endpoint.TimeoutSeconds, &endpoint.RetryConfig, &endpoint.Environment,
+		&endpoint.TimeoutSeconds, &endpoint.RetryConfig,
+		&endpoint.RateLimit, &endpoint.CircuitBreaker, &endpoint.Environment,
 		&endpoint.CreatedAt, &endpoint.UpdatedAt,
 	)
 
@@ -177,8 +181,16 @@ func (r *Repository) GetResourceTypeEndpointByName(ctx context.Context, resource
 // Action Definition Operations
 // ==============================================================================
 
-// CreateActionDefinition creates a new action definition
+// CreateActionDefinition creates a new action definition, rejecting trigger
+// conditions, response schemas and response paths that do not compile
 func (r *Repository) CreateActionDefinition(ctx context.Context, action *ActionDefinition) error {
+	if _, err := CompileTriggerConditions(action.TriggerConditions); err != nil {
+		return err
+	}
+	if err := ValidateActionContract(action); err != nil {
+		return err
+	}
+
 	// Serialize JSON fields
 	executionConfigJSON, err := json.Marshal(action.ExecutionConfig)
 	if err != nil {
@@ -194,17 +206,17 @@ func (r *Repository) CreateActionDefinition(ctx context.Context, action *ActionD
 		INSERT INTO actions_registry (
 			action_id, action_name, verb_pattern, action_type, resource_type_id,
 			domain, trigger_conditions, execution_config, attribute_mapping,
-			success_criteria, failure_handling, active, version, environment,
+			success_criteria, failure_handling, compensating_action_id, active, version, environment,
 			created_at, updated_at
 		) VALUES (
-			COALESCE(NULLIF($1, ''), uuid_generate_v4()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW()
+			COALESCE(NULLIF($1, ''), uuid_generate_v4()), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NOW(), NOW()
 		) RETURNING action_id, created_at, updated_at`
 
 	err = r.db.QueryRowContext(ctx, query,
 		action.ActionID, action.ActionName, action.VerbPattern, action.ActionType,
 		action.ResourceTypeID, action.Domain, action.TriggerConditions,
 		executionConfigJSON, attributeMappingJSON, action.SuccessCriteria,
-		action.FailureHandling, action.Active, action.Version, action.Environment,
+		action.FailureHandling, action.CompensatingActionID, action.Active, action.Version, action.Environment,
 	).Scan(&action.ActionID, &action.CreatedAt, &action.UpdatedAt)
 
 	return err
@@ -215,7 +227,7 @@ func (r *Repository) GetActionDefinition(ctx context.Context, actionID string) (
 	query := `
 		SELECT action_id, action_name, verb_pattern, action_type, resource_type_id,
 			   domain, trigger_conditions, execution_config, attribute_mapping,
-			   success_criteria, failure_handling, active, version, environment,
+			   success_criteria, failure_handling, compensating_action_id, active, version, environment,
 			   created_at, updated_at
 		FROM actions_registry
 		WHERE action_id = $1`
@@ -227,7 +239,7 @@ func (r *Repository) GetActionDefinition(ctx context.Context, actionID string) (
 		&action.ActionID, &action.ActionName, &action.VerbPattern, &action.ActionType,
 		&action.ResourceTypeID, &action.Domain, &action.TriggerConditions,
 		&executionConfigJSON, &attributeMappingJSON, &action.SuccessCriteria,
-		&action.FailureHandling, &action.Active, &action.Version, &action.Environment,
+		&action.FailureHandling, &action.CompensatingActionID, &action.Active, &action.Version, &action.Environment,
 		&action.CreatedAt, &action.UpdatedAt,
 	)
 
@@ -254,7 +266,7 @@ func (r *Repository) GetActionDefinitionsByVerbPattern(ctx context.Context, verb
 	query := `
 		SELECT action_id, action_name, verb_pattern, action_type, resource_type_id,
 			   domain, trigger_conditions, execution_config, attribute_mapping,
-			   success_criteria, failure_handling, active, version, environment,
+			   success_criteria, failure_handling, compensating_action_id, active, version, environment,
 			   created_at, updated_at
 		FROM actions_registry
 		WHERE verb_pattern = $1 AND environment = $2 AND active = true
@@ -275,7 +287,7 @@ func (r *Repository) GetActionDefinitionsByVerbPattern(ctx context.Context, verb
 			&action.ActionID, &action.ActionName, &action.VerbPattern, &action.ActionType,
 			&action.ResourceTypeID, &action.Domain, &action.TriggerConditions,
 			&executionConfigJSON, &attributeMappingJSON, &action.SuccessCriteria,
-			&action.FailureHandling, &action.Active, &action.Version, &action.Environment,
+			&action.FailureHandling, &action.CompensatingActionID, &action.Active, &action.Version, &action.Environment,
 			&action.CreatedAt, &action.UpdatedAt,
 		)
 		if err != nil {
@@ -461,6 +473,201 @@ func (r *Repository) ListActionExecutionsByCBU(ctx context.Context, cbuID string
 	return executions, rows.Err()
 }
 
+// ==============================================================================
+// Saga Log Operations
+// ==============================================================================
+
+// CreateSaga starts the log of a saga in RUNNING status
+func (r *Repository) CreateSaga(ctx context.Context, saga *Saga) error {
+	query := `
+		INSERT INTO action_sagas (cbu_id, dsl_version_id, status)
+		VALUES ($1, NULLIF($2, '')::uuid, $3)
+		RETURNING saga_id, created_at, updated_at`
+
+	saga.Status = SagaStatusRunning
+	return r.db.QueryRowContext(ctx, query, saga.CBUID, saga.DSLVersionID, saga.Status).
+		Scan(&saga.SagaID, &saga.CreatedAt, &saga.UpdatedAt)
+}
+
+// UpdateSaga records a saga's status and error
+func (r *Repository) UpdateSaga(ctx context.Context, saga *Saga) error {
+	query := `
+		UPDATE action_sagas SET status = $2, error = $3, updated_at = NOW()
+		WHERE saga_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, saga.SagaID, saga.Status, saga.Error)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("saga %s not found", saga.SagaID)
+	}
+	return nil
+}
+
+// SaveSagaStep inserts or updates a saga step
+func (r *Repository) SaveSagaStep(ctx context.Context, step *SagaStep) error {
+	query := `
+		INSERT INTO action_saga_steps (
+			saga_id, step_no, action_id, execution_id, compensating_action_id,
+			compensation_execution_id, status, error
+		) VALUES ($1, $2, $3, NULLIF($4, '')::uuid, $5, NULLIF($6, '')::uuid, $7, $8)
+		ON CONFLICT (saga_id, step_no) DO UPDATE SET
+			execution_id = EXCLUDED.execution_id,
+			compensation_execution_id = EXCLUDED.compensation_execution_id,
+			status = EXCLUDED.status,
+			error = EXCLUDED.error,
+			updated_at = NOW()
+		RETURNING created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		step.SagaID, step.StepNo, step.ActionID, deref(step.ExecutionID),
+		step.CompensatingActionID, deref(step.CompensationExecutionID),
+		step.Status, step.Error,
+	).Scan(&step.CreatedAt, &step.UpdatedAt)
+}
+
+// ListSagasByCBU retrieves the most recent sagas for a CBU with their steps
+func (r *Repository) ListSagasByCBU(ctx context.Context, cbuID string, limit int) ([]*Saga, error) {
+	query := `
+		SELECT saga_id, cbu_id, COALESCE(dsl_version_id::text, ''), status, error, created_at, updated_at
+		FROM action_sagas
+		WHERE cbu_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, cbuID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sagas []*Saga
+	byID := make(map[string]*Saga)
+	for rows.Next() {
+		saga := &Saga{}
+		if err := rows.Scan(&saga.SagaID, &saga.CBUID, &saga.DSLVersionID, &saga.Status,
+			&saga.Error, &saga.CreatedAt, &saga.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, saga)
+		byID[saga.SagaID] = saga
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(sagas) == 0 {
+		return sagas, nil
+	}
+
+	ids := make([]string, 0, len(sagas))
+	for _, saga := range sagas {
+		ids = append(ids, saga.SagaID)
+	}
+
+	stepRows, err := r.db.QueryContext(ctx, `
+		SELECT saga_id, step_no, action_id, execution_id, compensating_action_id,
+			   compensation_execution_id, status, error, created_at, updated_at
+		FROM action_saga_steps
+		WHERE saga_id = ANY($1::uuid[])
+		ORDER BY saga_id, step_no`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer stepRows.Close()
+
+	for stepRows.Next() {
+		var step SagaStep
+		if err := stepRows.Scan(&step.SagaID, &step.StepNo, &step.ActionID, &step.ExecutionID,
+			&step.CompensatingActionID, &step.CompensationExecutionID, &step.Status, &step.Error,
+			&step.CreatedAt, &step.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if saga, ok := byID[step.SagaID]; ok {
+			saga.Steps = append(saga.Steps, step)
+		}
+	}
+
+	return sagas, stepRows.Err()
+}
+
+// ==============================================================================
+// Circuit Breaker Operations
+// ==============================================================================
+
+// GetCircuitBreakerState returns the persisted breaker state of an endpoint,
+// or nil if its breaker has never changed state
+func (r *Repository) GetCircuitBreakerState(ctx context.Context, endpointID string) (*CircuitBreakerState, error) {
+	query := `
+		SELECT endpoint_id, state, consecutive_failures, opened_at, last_error, updated_at
+		FROM endpoint_circuit_breakers
+		WHERE endpoint_id = $1`
+
+	state := &CircuitBreakerState{}
+	err := r.db.QueryRowContext(ctx, query, endpointID).Scan(
+		&state.EndpointID, &state.State, &state.ConsecutiveFailures,
+		&state.OpenedAt, &state.LastError, &state.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SaveCircuitBreakerState creates or updates the breaker state of an endpoint
+func (r *Repository) SaveCircuitBreakerState(ctx context.Context, state *CircuitBreakerState) error {
+	query := `
+		INSERT INTO endpoint_circuit_breakers (
+			endpoint_id, state, consecutive_failures, opened_at, last_error, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (endpoint_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			consecutive_failures = EXCLUDED.consecutive_failures,
+			opened_at = EXCLUDED.opened_at,
+			last_error = EXCLUDED.last_error,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := r.db.ExecContext(ctx, query, state.EndpointID, state.State, state.ConsecutiveFailures,
+		state.OpenedAt, state.LastError, state.UpdatedAt)
+	return err
+}
+
+// ListCircuitBreakerStates returns the breaker state of every endpoint that
+// has one, with the endpoint's URL
+func (r *Repository) ListCircuitBreakerStates(ctx context.Context) ([]CircuitBreakerState, error) {
+	query := `
+		SELECT cb.endpoint_id, rte.endpoint_url, cb.state, cb.consecutive_failures,
+			   cb.opened_at, cb.last_error, cb.updated_at
+		FROM endpoint_circuit_breakers cb
+		JOIN resource_type_endpoints rte ON rte.endpoint_id = cb.endpoint_id
+		ORDER BY rte.endpoint_url`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []CircuitBreakerState
+	for rows.Next() {
+		var state CircuitBreakerState
+		if err := rows.Scan(&state.EndpointID, &state.EndpointURL, &state.State, &state.ConsecutiveFailures,
+			&state.OpenedAt, &state.LastError, &state.UpdatedAt); err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	return states, rows.Err()
+}
+
 // ==============================================================================
 // Utility Operations
 // ==============================================================================
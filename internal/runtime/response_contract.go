@@ -0,0 +1,190 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"dsl-ob-poc/internal/runtime/jsonpath"
+	"dsl-ob-poc/internal/runtime/jsonschema"
+)
+
+// ResponseValidationError reports a response whose status was accepted but
+// whose body breaks the action's contract: the response schema, a required
+// output, the response validation expression or an output mapping (or whose
+// success criteria cannot be read). Sending the same request again would get
+// the same answer, so it is not retried.
+type ResponseValidationError struct {
+	Err error
+}
+
+func (e *ResponseValidationError) Error() string { return e.Err.Error() }
+
+func (e *ResponseValidationError) Unwrap() error { return e.Err }
+
+// IsResponseValidationError reports whether err is, or wraps, a
+// ResponseValidationError
+func IsResponseValidationError(err error) bool {
+	var rv *ResponseValidationError
+	return errors.As(err, &rv)
+}
+
+// ValidateActionContract checks the parts of an action definition that are
+// interpreted against responses: the success criteria with their response
+// schema and expressions, and the JSONPath of every output mapping
+func ValidateActionContract(action *ActionDefinition) error {
+	criteria, err := parseSuccessCriteria(action.SuccessCriteria)
+	if err != nil {
+		return err
+	}
+	if len(criteria.ResponseSchema) > 0 {
+		if _, err := jsonschema.Compile(criteria.ResponseSchema); err != nil {
+			return fmt.Errorf("invalid response_schema: %w", err)
+		}
+	}
+	if criteria.ResponseValidation != nil {
+		if _, err := compileResponseCheck(*criteria.ResponseValidation); err != nil {
+			return err
+		}
+	}
+	for _, output := range criteria.RequiredOutputs {
+		if _, err := jsonpath.Compile(output); err != nil {
+			return fmt.Errorf("invalid required output %q: %w", output, err)
+		}
+	}
+	for _, mapping := range action.AttributeMapping.OutputMapping {
+		if mapping.APIResponsePath == "" {
+			continue
+		}
+		if _, err := jsonpath.Compile(mapping.APIResponsePath); err != nil {
+			return fmt.Errorf("invalid api_response_path for %s: %w", outputName(mapping), err)
+		}
+	}
+	return nil
+}
+
+// parseSuccessCriteria decodes an action's success criteria; none at all
+// accepts any response
+func parseSuccessCriteria(raw json.RawMessage) (SuccessCriteria, error) {
+	var criteria SuccessCriteria
+	if len(raw) == 0 || string(raw) == "null" {
+		return criteria, nil
+	}
+	if err := json.Unmarshal(raw, &criteria); err != nil {
+		return criteria, fmt.Errorf("invalid success criteria: %w", err)
+	}
+	return criteria, nil
+}
+
+// responseDocument is the decoded JSON body of a response. Unlike Body it
+// keeps top-level arrays and scalars; a body that is not JSON is represented
+// by Body's raw_response wrapper.
+func responseDocument(resp *APIResponse) any {
+	var doc any
+	if resp.RawBody != "" && json.Unmarshal([]byte(resp.RawBody), &doc) == nil {
+		return doc
+	}
+	if resp.Body == nil {
+		return map[string]any{}
+	}
+	return resp.Body
+}
+
+// responseCheck is a compiled response_validation expression of the form
+// "<path> == <literal>" or "<path> != <literal>", e.g. $.status == 'CREATED'
+type responseCheck struct {
+	path    *jsonpath.Path
+	negate  bool
+	literal any
+	src     string
+}
+
+var responseCheckPattern = regexp.MustCompile(`^\s*(\S+)\s*(==|!=)\s*(.+?)\s*$`)
+
+func compileResponseCheck(src string) (*responseCheck, error) {
+	m := responseCheckPattern.FindStringSubmatch(src)
+	if m == nil {
+		return nil, fmt.Errorf("invalid response_validation %q: expected <path> == <value> or <path> != <value>", src)
+	}
+	path, err := jsonpath.Compile(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid response_validation %q: %w", src, err)
+	}
+
+	var literal any
+	raw := m[3]
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		literal = raw[1 : len(raw)-1]
+	} else if err := json.Unmarshal([]byte(raw), &literal); err != nil {
+		return nil, fmt.Errorf("invalid response_validation %q: %s is not a string, number, boolean or null", src, raw)
+	}
+	return &responseCheck{path: path, negate: m[2] == "!=", literal: literal, src: src}, nil
+}
+
+func (c *responseCheck) check(doc any) error {
+	value, _ := c.path.Get(doc)
+	if reflect.DeepEqual(value, c.literal) == c.negate {
+		return fmt.Errorf("response validation failed: %s (got %v)", c.src, value)
+	}
+	return nil
+}
+
+// mapOutputs evaluates an action's output mappings against a response
+// document. A path that selects nothing leaves its attribute out. Indefinite
+// paths (wildcards, slices, recursive descent) yield arrays, and a
+// transformation of an array value applies to each element.
+func mapOutputs(ctx context.Context, transformer *AttributeTransformer, doc any, mappings []AttributeMap) (map[string]any, error) {
+	outputs := make(map[string]any)
+	for _, mapping := range mappings {
+		if mapping.APIResponsePath == "" {
+			continue
+		}
+		path, err := jsonpath.Compile(mapping.APIResponsePath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid api_response_path for %s: %w", outputName(mapping), err)
+		}
+		value, ok := path.Get(doc)
+		if !ok || value == nil {
+			continue
+		}
+
+		if mapping.Transformation != "" {
+			if value, err = transformValue(ctx, transformer, value, mapping.Transformation); err != nil {
+				return nil, fmt.Errorf("output %s at %s: %w", outputName(mapping), mapping.APIResponsePath, err)
+			}
+		}
+		outputs[outputName(mapping)] = value
+	}
+	return outputs, nil
+}
+
+func transformValue(ctx context.Context, transformer *AttributeTransformer, value any, transformation string) (any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return transformer.Transform(ctx, value, transformation)
+	}
+	out := make([]any, len(items))
+	for i, item := range items {
+		v, err := transformer.Transform(ctx, item, transformation)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// outputName is the key a mapped output is reported under
+func outputName(mapping AttributeMap) string {
+	if mapping.AttributeName != "" {
+		return mapping.AttributeName
+	}
+	if mapping.DSLAttributeID != "" {
+		return mapping.DSLAttributeID
+	}
+	return strings.TrimPrefix(mapping.APIResponsePath, "$.")
+}
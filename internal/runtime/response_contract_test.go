@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValidateResponse_Contract(t *testing.T) {
+	client := NewHTTPClient(nil)
+	validation := "$.status == 'CREATED'"
+	criteria := SuccessCriteria{
+		HTTPStatusCodes:    []int{201},
+		RequiredOutputs:    []string{"$.account.id"},
+		ResponseValidation: &validation,
+		ResponseSchema: json.RawMessage(`{
+			"type": "object",
+			"required": ["status", "account"],
+			"properties": {"account": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}}}}
+		}`),
+	}
+	response := func(status int, body string) *APIResponse {
+		resp := &APIResponse{StatusCode: status, RawBody: body}
+		_ = json.Unmarshal([]byte(body), &resp.Body)
+		return resp
+	}
+
+	if err := client.ValidateResponse(response(201, `{"status": "CREATED", "account": {"id": "ACC-1"}}`), criteria); err != nil {
+		t.Fatalf("valid response rejected: %v", err)
+	}
+
+	err := client.ValidateResponse(response(500, `{}`), criteria)
+	if err == nil || IsResponseValidationError(err) {
+		t.Errorf("bad status: got %v, want a plain error", err)
+	}
+
+	for name, body := range map[string]string{
+		"schema":     `{"status": "CREATED", "account": {"id": 42}}`,
+		"validation": `{"status": "PENDING", "account": {"id": "ACC-1"}}`,
+		"required":   `{"status": "CREATED", "account": {"id": null}}`,
+		"not json":   `<html>oops</html>`,
+	} {
+		err := client.ValidateResponse(response(201, body), criteria)
+		if !IsResponseValidationError(err) {
+			t.Errorf("%s: got %v, want a ResponseValidationError", name, err)
+		}
+	}
+}
+
+func TestCompileResponseCheck(t *testing.T) {
+	doc := map[string]any{"status": "CREATED", "count": 2.0, "active": true}
+	tests := []struct {
+		src  string
+		pass bool
+	}{
+		{"$.status == 'CREATED'", true},
+		{"status == \"CREATED\"", true},
+		{"$.status != 'CREATED'", false},
+		{"$.count == 2", true},
+		{"$.active == false", false},
+		{"$.missing == null", true},
+	}
+	for _, tt := range tests {
+		check, err := compileResponseCheck(tt.src)
+		if err != nil {
+			t.Errorf("compileResponseCheck(%q): %v", tt.src, err)
+			continue
+		}
+		if got := check.check(doc) == nil; got != tt.pass {
+			t.Errorf("%q passed = %t, want %t", tt.src, got, tt.pass)
+		}
+	}
+
+	for _, src := range []string{"$.status", "$.status == CREATED", "$[ == 'x'"} {
+		if _, err := compileResponseCheck(src); err == nil {
+			t.Errorf("compileResponseCheck(%q) succeeded", src)
+		}
+	}
+}
+
+func TestMapOutputs(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"account": {"id": "acc-1", "currency": " eur "},
+		"holders": [{"country": "gb"}, {"country": "lu"}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	mappings := []AttributeMap{
+		{DSLAttributeID: "attr-account", APIResponsePath: "$.account.id", Transformation: "uppercase"},
+		{AttributeName: "currency", APIResponsePath: "account.currency", Transformation: "trim"},
+		{AttributeName: "countries", APIResponsePath: "$.holders[*].country", Transformation: "uppercase"},
+		{AttributeName: "absent", APIResponsePath: "$.account.iban"},
+		{AttributeName: "request_only", APIParameter: "lei"},
+	}
+
+	outputs, err := mapOutputs(context.Background(), NewAttributeTransformer(), doc, mappings)
+	if err != nil {
+		t.Fatalf("mapOutputs: %v", err)
+	}
+	want := map[string]any{
+		"attr-account": "ACC-1",
+		"currency":     "eur",
+		"countries":    []any{"GB", "LU"},
+	}
+	if !reflect.DeepEqual(outputs, want) {
+		t.Fatalf("outputs = %#v, want %#v", outputs, want)
+	}
+
+	_, err = mapOutputs(context.Background(), NewAttributeTransformer(), doc, []AttributeMap{
+		{AttributeName: "currency", APIResponsePath: "$.account.currency", Transformation: "integer"},
+	})
+	if err == nil {
+		t.Fatal("expected a transformation error")
+	}
+}
+
+func TestValidateActionContract(t *testing.T) {
+	action := func(criteria string, path string) *ActionDefinition {
+		return &ActionDefinition{
+			SuccessCriteria:  json.RawMessage(criteria),
+			AttributeMapping: AttributeMapping{OutputMapping: []AttributeMap{{AttributeName: "id", APIResponsePath: path}}},
+		}
+	}
+
+	if err := ValidateActionContract(action(`{"http_status_codes": [200], "response_schema": {"type": "object"}}`, "$.id")); err != nil {
+		t.Fatalf("valid contract rejected: %v", err)
+	}
+	if err := ValidateActionContract(action(``, "$.id")); err != nil {
+		t.Fatalf("empty success criteria rejected: %v", err)
+	}
+
+	for name, a := range map[string]*ActionDefinition{
+		"schema":     action(`{"response_schema": {"type": "decimal"}}`, "$.id"),
+		"validation": action(`{"response_validation": "$.status"}`, "$.id"),
+		"required":   action(`{"required_outputs": ["$["]}`, "$.id"),
+		"mapping":    action(`{}`, "$.accounts[?(@.id)]"),
+		"bad json":   action(`{"http_status_codes": "200"}`, "$.id"),
+	} {
+		if err := ValidateActionContract(a); err == nil {
+			t.Errorf("%s: invalid contract accepted", name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// sagaLog records sagas and their steps; Repository implements it
+type sagaLog interface {
+	CreateSaga(ctx context.Context, saga *Saga) error
+	UpdateSaga(ctx context.Context, saga *Saga) error
+	SaveSagaStep(ctx context.Context, step *SagaStep) error
+}
+
+// sagaExecutor runs the actions of a saga; ExecutionEngine implements it
+type sagaExecutor interface {
+	ExecuteAction(ctx context.Context, req *ExecutionRequest) (*ExecutionResult, error)
+	cancelExecution(ctx context.Context, executionID string) error
+}
+
+// sagaAction is one forward step of a saga and the action that undoes it
+type sagaAction struct {
+	request              *ExecutionRequest
+	compensatingActionID *string
+}
+
+// runSaga executes actions in order. When one fails the rest are not run,
+// and the completed steps are compensated in reverse order with their
+// compensating actions; steps without one are logged as NOT_COMPENSABLE and
+// left in place. It returns the result of every forward step that ran, with
+// the compensation attached to the steps that were undone. Failures to write
+// the saga log are logged and do not stop the saga once it has started.
+func runSaga(ctx context.Context, sagas sagaLog, executor sagaExecutor, cbuID, dslVersionID string, actions []sagaAction) (*Saga, []*ExecutionResult, error) {
+	saga := &Saga{CBUID: cbuID, DSLVersionID: dslVersionID}
+	if err := sagas.CreateSaga(ctx, saga); err != nil {
+		return nil, nil, fmt.Errorf("failed to create saga: %w", err)
+	}
+
+	saveStep := func(step *SagaStep) {
+		if err := sagas.SaveSagaStep(ctx, step); err != nil {
+			log.Printf("saga %s: failed to record step %d: %v", saga.SagaID, step.StepNo, err)
+		}
+	}
+	saveSaga := func() {
+		if err := sagas.UpdateSaga(ctx, saga); err != nil {
+			log.Printf("saga %s: failed to record status %s: %v", saga.SagaID, saga.Status, err)
+		}
+	}
+
+	var results []*ExecutionResult
+	var failure *string
+
+	for i, action := range actions {
+		step := SagaStep{
+			SagaID:               saga.SagaID,
+			StepNo:               i + 1,
+			ActionID:             action.request.ActionID,
+			CompensatingActionID: action.compensatingActionID,
+			Status:               SagaStepRunning,
+		}
+		saveStep(&step)
+
+		result, err := executor.ExecuteAction(ctx, action.request)
+		if err != nil {
+			result = &ExecutionResult{
+				Success:      false,
+				ErrorDetails: stringPtr(err.Error()),
+			}
+		}
+		results = append(results, result)
+
+		if result.ExecutionID != "" {
+			step.ExecutionID = stringPtr(result.ExecutionID)
+		}
+		if result.Success {
+			step.Status = SagaStepCompleted
+		} else {
+			step.Status = SagaStepFailed
+			step.Error = stringPtr(fmt.Sprintf("action %s failed: %s", action.request.ActionID, deref(result.ErrorDetails)))
+			failure = step.Error
+		}
+		saga.Steps = append(saga.Steps, step)
+		saveStep(&saga.Steps[i])
+
+		if !result.Success {
+			break
+		}
+	}
+
+	if failure == nil {
+		saga.Status = SagaStatusCompleted
+		saveSaga()
+		return saga, results, nil
+	}
+
+	saga.Status = SagaStatusCompensating
+	saga.Error = failure
+	saveSaga()
+
+	compensationFailed := false
+	for i := len(saga.Steps) - 1; i >= 0; i-- {
+		step := &saga.Steps[i]
+		if step.Status != SagaStepCompleted {
+			continue
+		}
+		if step.CompensatingActionID == nil {
+			step.Status = SagaStepNotCompensable
+			saveStep(step)
+			continue
+		}
+
+		forward := actions[i].request
+		attributes := make(map[string]any, len(forward.AttributeValues)+len(results[i].ResultAttributes))
+		for k, v := range forward.AttributeValues {
+			attributes[k] = v
+		}
+		for k, v := range results[i].ResultAttributes {
+			attributes[k] = v
+		}
+
+		compensation, err := executor.ExecuteAction(ctx, &ExecutionRequest{
+			ActionID:        *step.CompensatingActionID,
+			CBUID:           forward.CBUID,
+			DSLVersionID:    forward.DSLVersionID,
+			Environment:     forward.Environment,
+			AttributeValues: attributes,
+			TraceID:         forward.TraceID,
+			SpanID:          forward.SpanID,
+			TriggerContext: map[string]any{
+				"triggered_by":          "saga_compensation",
+				"saga_id":               saga.SagaID,
+				"compensates_action":    forward.ActionID,
+				"compensates_execution": deref(step.ExecutionID),
+				"triggered_at":          time.Now().Format(time.RFC3339),
+			},
+		})
+		if err != nil {
+			compensation = &ExecutionResult{
+				Success:      false,
+				ErrorDetails: stringPtr(err.Error()),
+			}
+		}
+		results[i].Compensation = compensation
+
+		if compensation.ExecutionID != "" {
+			step.CompensationExecutionID = stringPtr(compensation.ExecutionID)
+		}
+		if compensation.Success {
+			step.Status = SagaStepCompensated
+			// A compensated execution no longer counts as done, so that the
+			// action runs again when the DSL change is retried
+			if step.ExecutionID != nil {
+				if err := executor.cancelExecution(ctx, *step.ExecutionID); err != nil {
+					log.Printf("saga %s: failed to cancel compensated execution %s: %v", saga.SagaID, *step.ExecutionID, err)
+				}
+			}
+		} else {
+			step.Status = SagaStepCompensationFailed
+			step.Error = stringPtr(fmt.Sprintf("compensating action %s failed: %s", *step.CompensatingActionID, deref(compensation.ErrorDetails)))
+			compensationFailed = true
+		}
+		saveStep(step)
+	}
+
+	saga.Status = SagaStatusCompensated
+	if compensationFailed {
+		saga.Status = SagaStatusCompensationFailed
+	}
+	saveSaga()
+	return saga, results, nil
+}
+
+// cancelExecution marks a completed execution as cancelled once its effects
+// have been compensated
+func (ee *ExecutionEngine) cancelExecution(ctx context.Context, executionID string) error {
+	execution, err := ee.repository.GetActionExecution(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	execution.ExecutionStatus = ExecutionStatusCancelled
+	return ee.repository.UpdateActionExecution(ctx, execution)
+}
@@ -0,0 +1,161 @@
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeSagaLog struct {
+	saga  *Saga
+	steps map[int]SagaStep
+}
+
+func (f *fakeSagaLog) CreateSaga(ctx context.Context, saga *Saga) error {
+	saga.SagaID = "saga-1"
+	saga.Status = SagaStatusRunning
+	f.saga = saga
+	f.steps = make(map[int]SagaStep)
+	return nil
+}
+
+func (f *fakeSagaLog) UpdateSaga(ctx context.Context, saga *Saga) error { return nil }
+
+func (f *fakeSagaLog) SaveSagaStep(ctx context.Context, step *SagaStep) error {
+	f.steps[step.StepNo] = *step
+	return nil
+}
+
+// scriptedSagaExecutor succeeds for every action except those in fail, and
+// records the order in which actions ran
+type scriptedSagaExecutor struct {
+	fail      map[string]bool
+	ran       []string
+	requests  map[string]*ExecutionRequest
+	cancelled []string
+}
+
+func (e *scriptedSagaExecutor) ExecuteAction(ctx context.Context, req *ExecutionRequest) (*ExecutionResult, error) {
+	e.ran = append(e.ran, req.ActionID)
+	if e.requests == nil {
+		e.requests = make(map[string]*ExecutionRequest)
+	}
+	e.requests[req.ActionID] = req
+	if e.fail[req.ActionID] {
+		return &ExecutionResult{ExecutionID: "exec-" + req.ActionID, ErrorDetails: stringPtr("HTTP 500")}, nil
+	}
+	return &ExecutionResult{
+		ExecutionID:      "exec-" + req.ActionID,
+		Success:          true,
+		ResultAttributes: map[string]any{req.ActionID + "_id": "id-" + req.ActionID},
+	}, nil
+}
+
+func (e *scriptedSagaExecutor) cancelExecution(ctx context.Context, executionID string) error {
+	e.cancelled = append(e.cancelled, executionID)
+	return nil
+}
+
+// sagaActions builds saga actions from "action" or "action:compensation" specs
+func sagaActions(specs ...string) []sagaAction {
+	var actions []sagaAction
+	for _, spec := range specs {
+		actionID, compensation, compensable := strings.Cut(spec, ":")
+		a := sagaAction{request: &ExecutionRequest{ActionID: actionID, CBUID: "cbu-1", DSLVersionID: "v1"}}
+		if compensable {
+			a.compensatingActionID = &compensation
+		}
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+func TestRunSaga_AllSucceed(t *testing.T) {
+	sagas := &fakeSagaLog{}
+	executor := &scriptedSagaExecutor{}
+
+	saga, results, err := runSaga(context.Background(), sagas, executor, "cbu-1", "v1",
+		sagaActions("account:close-account", "custody:close-custody"))
+	if err != nil {
+		t.Fatalf("runSaga: %v", err)
+	}
+	if saga.Status != SagaStatusCompleted {
+		t.Fatalf("status = %s, want COMPLETED", saga.Status)
+	}
+	if len(results) != 2 || results[0].Compensation != nil || results[1].Compensation != nil {
+		t.Fatalf("results = %+v, want two uncompensated results", results)
+	}
+	if want := []string{"account", "custody"}; !reflect.DeepEqual(executor.ran, want) {
+		t.Fatalf("ran %v, want %v", executor.ran, want)
+	}
+}
+
+func TestRunSaga_CompensatesInReverseOrder(t *testing.T) {
+	sagas := &fakeSagaLog{}
+	executor := &scriptedSagaExecutor{fail: map[string]bool{"settlement": true}}
+
+	saga, results, err := runSaga(context.Background(), sagas, executor, "cbu-1", "v1",
+		sagaActions("account:close-account", "notify", "custody:close-custody", "settlement:undo-settlement", "never-run"))
+	if err != nil {
+		t.Fatalf("runSaga: %v", err)
+	}
+
+	want := []string{"account", "notify", "custody", "settlement", "close-custody", "close-account"}
+	if !reflect.DeepEqual(executor.ran, want) {
+		t.Fatalf("ran %v, want %v", executor.ran, want)
+	}
+	if saga.Status != SagaStatusCompensated {
+		t.Fatalf("status = %s, want COMPENSATED", saga.Status)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4 (the action after the failure is not run)", len(results))
+	}
+	if results[0].Compensation == nil || !results[0].Compensation.Success || results[1].Compensation != nil {
+		t.Fatalf("compensations = %+v, %+v", results[0].Compensation, results[1].Compensation)
+	}
+
+	wantSteps := map[int]SagaStepStatus{
+		1: SagaStepCompensated,
+		2: SagaStepNotCompensable,
+		3: SagaStepCompensated,
+		4: SagaStepFailed,
+	}
+	for no, status := range wantSteps {
+		if got := sagas.steps[no].Status; got != status {
+			t.Errorf("step %d status = %s, want %s", no, got, status)
+		}
+	}
+	if got := deref(sagas.steps[3].CompensationExecutionID); got != "exec-close-custody" {
+		t.Errorf("step 3 compensation execution = %q", got)
+	}
+	if want := []string{"exec-custody", "exec-account"}; !reflect.DeepEqual(executor.cancelled, want) {
+		t.Errorf("cancelled %v, want %v", executor.cancelled, want)
+	}
+
+	// The compensation sees what the forward action produced
+	comp := executor.requests["close-custody"]
+	if comp.AttributeValues["custody_id"] != "id-custody" || comp.TriggerContext["compensates_execution"] != "exec-custody" {
+		t.Errorf("compensation request = %+v", comp)
+	}
+}
+
+func TestRunSaga_CompensationFailure(t *testing.T) {
+	sagas := &fakeSagaLog{}
+	executor := &scriptedSagaExecutor{fail: map[string]bool{"custody": true, "close-account": true}}
+
+	saga, results, err := runSaga(context.Background(), sagas, executor, "cbu-1", "v1",
+		sagaActions("account:close-account", "custody:close-custody"))
+	if err != nil {
+		t.Fatalf("runSaga: %v", err)
+	}
+	if saga.Status != SagaStatusCompensationFailed {
+		t.Fatalf("status = %s, want COMPENSATION_FAILED", saga.Status)
+	}
+	if sagas.steps[1].Status != SagaStepCompensationFailed || results[0].Compensation.Success {
+		t.Fatalf("step 1 = %+v", sagas.steps[1])
+	}
+	if len(executor.cancelled) != 0 {
+		t.Fatalf("cancelled %v after a failed compensation", executor.cancelled)
+	}
+}
@@ -0,0 +1,698 @@
+package runtime
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCredentialNotFound is returned by secret backends for a credential
+// that is not stored, or not active
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// SecretBackend stores credentials for CredentialManager. Backends that
+// keep credentials at rest encrypt them with envelope encryption: each
+// credential under its own data key, wrapped with a versioned master key.
+type SecretBackend interface {
+	// Name identifies the backend in audit records
+	Name() string
+	// Store creates or replaces a credential
+	Store(ctx context.Context, cred *StoredCredential) error
+	// Load returns an active credential with its decrypted data
+	Load(ctx context.Context, name string) (*StoredCredential, error)
+	// Info returns a credential's metadata without decrypting it
+	Info(ctx context.Context, name string) (*CredentialInfo, error)
+	// List returns the metadata of the credentials of an environment
+	List(ctx context.Context, environment string) ([]CredentialInfo, error)
+	// Delete removes a credential
+	Delete(ctx context.Context, name string) error
+}
+
+// KeyRotator is implemented by backends that encrypt under a master key
+type KeyRotator interface {
+	// ReencryptAll re-encrypts every stored credential that is not under the
+	// active master key version and returns how many were re-encrypted
+	ReencryptAll(ctx context.Context) (int, error)
+}
+
+// StoredCredential is a credential with its decrypted data
+type StoredCredential struct {
+	CredentialInfo
+	Data map[string]interface{}
+}
+
+// secretBackendFromEnv selects the backend named by CREDENTIALS_BACKEND:
+// postgres (default), file or env
+func secretBackendFromEnv(db *sql.DB) (SecretBackend, error) {
+	kind := strings.ToLower(os.Getenv("CREDENTIALS_BACKEND"))
+	if kind == "env" {
+		return &envSecretBackend{}, nil
+	}
+
+	keys, err := keyringFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "", "postgres", "postgresql", "db":
+		if db == nil {
+			return nil, fmt.Errorf("postgres credential backend requires a database connection")
+		}
+		return &postgresSecretBackend{db: db, keys: keys}, nil
+	case "file":
+		path := os.Getenv("CREDENTIALS_VAULT_FILE")
+		if path == "" {
+			path = "data/credentials.vault"
+		}
+		return newFileSecretBackend(path, keys), nil
+	default:
+		return nil, fmt.Errorf("unknown CREDENTIALS_BACKEND %q (expected postgres, file or env)", kind)
+	}
+}
+
+// ==============================================================================
+// Master Keys and Envelope Encryption
+// ==============================================================================
+
+// keyring holds the master keys by version. Credentials are sealed under the
+// active (highest) version and opened under whichever version sealed them,
+// so a new master key can be introduced before the old one is retired.
+type keyring struct {
+	keys   map[int][]byte
+	active int
+}
+
+// keyringFromEnv reads CREDENTIALS_ENCRYPTION_KEYS, a list of
+// version:secret pairs ("1:old-secret,2:new-secret"), falling back to
+// CREDENTIALS_ENCRYPTION_KEY as version 1
+func keyringFromEnv() (*keyring, error) {
+	if spec := os.Getenv("CREDENTIALS_ENCRYPTION_KEYS"); spec != "" {
+		return parseKeyring(spec)
+	}
+	secret := os.Getenv("CREDENTIALS_ENCRYPTION_KEY")
+	if secret == "" {
+		return nil, fmt.Errorf("CREDENTIALS_ENCRYPTION_KEY environment variable not set")
+	}
+	return newKeyring(map[int]string{1: secret}), nil
+}
+
+func parseKeyring(spec string) (*keyring, error) {
+	secrets := make(map[int]string)
+	for _, part := range strings.Split(spec, ",") {
+		version, secret, found := strings.Cut(strings.TrimSpace(part), ":")
+		v, err := strconv.Atoi(version)
+		if !found || err != nil || v < 1 || secret == "" {
+			return nil, fmt.Errorf("invalid CREDENTIALS_ENCRYPTION_KEYS entry for version %q: expected <version>:<secret>", version)
+		}
+		if _, dup := secrets[v]; dup {
+			return nil, fmt.Errorf("duplicate master key version %d", v)
+		}
+		secrets[v] = secret
+	}
+	return newKeyring(secrets), nil
+}
+
+// newKeyring derives a 32-byte AES-256 key from each secret with SHA-256
+func newKeyring(secrets map[int]string) *keyring {
+	k := &keyring{keys: make(map[int][]byte, len(secrets))}
+	for version, secret := range secrets {
+		sum := sha256.Sum256([]byte(secret))
+		k.keys[version] = sum[:]
+		k.active = max(k.active, version)
+	}
+	return k
+}
+
+// seal encrypts plaintext under a fresh data key and wraps the data key with
+// the active master key
+func (k *keyring) seal(plaintext []byte) (ciphertext, wrappedKey []byte, version int, err error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, 0, err
+	}
+	if ciphertext, err = gcmSeal(dataKey, plaintext); err != nil {
+		return nil, nil, 0, err
+	}
+	if wrappedKey, err = gcmSeal(k.keys[k.active], dataKey); err != nil {
+		return nil, nil, 0, err
+	}
+	return ciphertext, wrappedKey, k.active, nil
+}
+
+// open decrypts a sealed credential. Credentials stored before envelope
+// encryption have no wrapped key and are encrypted with the master key
+// directly.
+func (k *keyring) open(ciphertext, wrappedKey []byte, version int) ([]byte, error) {
+	if version == 0 {
+		version = 1
+	}
+	masterKey, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("master key version %d is not configured", version)
+	}
+	if len(wrappedKey) == 0 {
+		return gcmOpen(masterKey, ciphertext)
+	}
+	dataKey, err := gcmOpen(masterKey, wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return gcmOpen(dataKey, ciphertext)
+}
+
+// current reports whether a credential sealed under version with wrappedKey
+// needs no re-encryption
+func (k *keyring) current(wrappedKey []byte, version int) bool {
+	return len(wrappedKey) > 0 && version == k.active
+}
+
+// gcmSeal encrypts data using AES-256-GCM, prefixing the nonce
+func gcmSeal(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// gcmOpen decrypts data sealed by gcmSeal
+func gcmOpen(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func (k *keyring) sealCredential(data map[string]interface{}) (ciphertext, wrappedKey []byte, version int, err error) {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+	ciphertext, wrappedKey, version, err = k.seal(plaintext)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	return ciphertext, wrappedKey, version, nil
+}
+
+func (k *keyring) openCredential(ciphertext, wrappedKey []byte, version int) (map[string]interface{}, error) {
+	plaintext, err := k.open(ciphertext, wrappedKey, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credentials: %w", err)
+	}
+	return data, nil
+}
+
+// ==============================================================================
+// PostgreSQL Backend
+// ==============================================================================
+
+// postgresSecretBackend keeps credentials in the credentials_vault table
+type postgresSecretBackend struct {
+	db   *sql.DB
+	keys *keyring
+}
+
+func (b *postgresSecretBackend) Name() string { return "postgres" }
+
+func (b *postgresSecretBackend) Store(ctx context.Context, cred *StoredCredential) error {
+	ciphertext, wrappedKey, version, err := b.keys.sealCredential(cred.Data)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO credentials_vault (
+			credential_name, credential_type, encrypted_data, wrapped_key, key_version, environment, created_at, active
+		) VALUES ($1, $2, $3, $4, $5, $6, NOW(), true)
+		ON CONFLICT (credential_name) DO UPDATE SET
+			credential_type = EXCLUDED.credential_type,
+			encrypted_data = EXCLUDED.encrypted_data,
+			wrapped_key = EXCLUDED.wrapped_key,
+			key_version = EXCLUDED.key_version,
+			environment = EXCLUDED.environment,
+			created_at = NOW(),
+			active = true`
+
+	_, err = b.db.ExecContext(ctx, query, cred.Name, cred.Type, ciphertext, wrappedKey, version, cred.Environment)
+	if err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+	return nil
+}
+
+func (b *postgresSecretBackend) Load(ctx context.Context, name string) (*StoredCredential, error) {
+	query := `
+		SELECT credential_name, credential_type, environment, created_at, expires_at, active,
+		       key_version, encrypted_data, wrapped_key
+		FROM credentials_vault
+		WHERE credential_name = $1 AND active = true`
+
+	cred := &StoredCredential{}
+	var ciphertext, wrappedKey []byte
+	err := b.db.QueryRowContext(ctx, query, name).Scan(
+		&cred.Name, &cred.Type, &cred.Environment, &cred.CreatedAt, &cred.ExpiresAt, &cred.Active,
+		&cred.KeyVersion, &ciphertext, &wrappedKey,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+
+	if cred.Data, err = b.keys.openCredential(ciphertext, wrappedKey, cred.KeyVersion); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+func (b *postgresSecretBackend) Info(ctx context.Context, name string) (*CredentialInfo, error) {
+	query := `
+		SELECT credential_name, credential_type, environment, created_at, expires_at, active, key_version
+		FROM credentials_vault
+		WHERE credential_name = $1`
+
+	info := &CredentialInfo{}
+	err := b.db.QueryRowContext(ctx, query, name).Scan(
+		&info.Name, &info.Type, &info.Environment,
+		&info.CreatedAt, &info.ExpiresAt, &info.Active, &info.KeyVersion,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential metadata: %w", err)
+	}
+	return info, nil
+}
+
+func (b *postgresSecretBackend) List(ctx context.Context, environment string) ([]CredentialInfo, error) {
+	query := `
+		SELECT credential_name, credential_type, environment, created_at, expires_at, active, key_version
+		FROM credentials_vault
+		WHERE environment = $1 OR environment = 'all'
+		ORDER BY credential_name`
+
+	rows, err := b.db.QueryContext(ctx, query, environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []CredentialInfo
+	for rows.Next() {
+		var cred CredentialInfo
+		err := rows.Scan(
+			&cred.Name, &cred.Type, &cred.Environment,
+			&cred.CreatedAt, &cred.ExpiresAt, &cred.Active, &cred.KeyVersion,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan credential info: %w", err)
+		}
+		credentials = append(credentials, cred)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (b *postgresSecretBackend) Delete(ctx context.Context, name string) error {
+	result, err := b.db.ExecContext(ctx, `DELETE FROM credentials_vault WHERE credential_name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete credentials: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// ReencryptAll re-encrypts, in one transaction, every row that is not
+// sealed under the active master key, including inactive ones
+func (b *postgresSecretBackend) ReencryptAll(ctx context.Context) (int, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT credential_name, key_version, encrypted_data, wrapped_key
+		FROM credentials_vault
+		WHERE key_version <> $1 OR wrapped_key IS NULL
+		FOR UPDATE`, b.keys.active)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select credentials for re-encryption: %w", err)
+	}
+
+	type sealed struct {
+		name                   string
+		version                int
+		ciphertext, wrappedKey []byte
+	}
+	var stale []sealed
+	for rows.Next() {
+		var s sealed
+		if err := rows.Scan(&s.name, &s.version, &s.ciphertext, &s.wrappedKey); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		stale = append(stale, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, s := range stale {
+		data, err := b.keys.openCredential(s.ciphertext, s.wrappedKey, s.version)
+		if err != nil {
+			return 0, fmt.Errorf("credential '%s': %w", s.name, err)
+		}
+		ciphertext, wrappedKey, version, err := b.keys.sealCredential(data)
+		if err != nil {
+			return 0, fmt.Errorf("credential '%s': %w", s.name, err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE credentials_vault
+			SET encrypted_data = $2, wrapped_key = $3, key_version = $4
+			WHERE credential_name = $1`, s.name, ciphertext, wrappedKey, version)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt credential '%s': %w", s.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit re-encryption: %w", err)
+	}
+	return len(stale), nil
+}
+
+// ==============================================================================
+// Encrypted File Backend
+// ==============================================================================
+
+// fileSecretBackend keeps credentials in a local JSON vault file. Metadata
+// is stored in the clear, like the columns of credentials_vault; the
+// credential data is sealed.
+type fileSecretBackend struct {
+	path string
+	keys *keyring
+	mu   sync.Mutex
+}
+
+type fileVault struct {
+	Credentials map[string]*fileVaultEntry `json:"credentials"`
+}
+
+type fileVaultEntry struct {
+	CredentialInfo
+	EncryptedData []byte `json:"encrypted_data"`
+	WrappedKey    []byte `json:"wrapped_key"`
+}
+
+func newFileSecretBackend(path string, keys *keyring) *fileSecretBackend {
+	return &fileSecretBackend{path: path, keys: keys}
+}
+
+func (b *fileSecretBackend) Name() string { return "file" }
+
+func (b *fileSecretBackend) read() (*fileVault, error) {
+	vault := &fileVault{Credentials: make(map[string]*fileVaultEntry)}
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return vault, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential vault: %w", err)
+	}
+	if err := json.Unmarshal(data, vault); err != nil {
+		return nil, fmt.Errorf("invalid credential vault %s: %w", b.path, err)
+	}
+	if vault.Credentials == nil {
+		vault.Credentials = make(map[string]*fileVaultEntry)
+	}
+	return vault, nil
+}
+
+func (b *fileSecretBackend) write(vault *fileVault) error {
+	data, err := json.MarshalIndent(vault, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o700); err != nil {
+		return fmt.Errorf("failed to write credential vault: %w", err)
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write credential vault: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return fmt.Errorf("failed to write credential vault: %w", err)
+	}
+	return nil
+}
+
+func (b *fileSecretBackend) Store(ctx context.Context, cred *StoredCredential) error {
+	ciphertext, wrappedKey, version, err := b.keys.sealCredential(cred.Data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vault, err := b.read()
+	if err != nil {
+		return err
+	}
+	entry := &fileVaultEntry{CredentialInfo: cred.CredentialInfo, EncryptedData: ciphertext, WrappedKey: wrappedKey}
+	entry.CreatedAt = time.Now().UTC()
+	entry.Active = true
+	entry.KeyVersion = version
+	if previous, ok := vault.Credentials[cred.Name]; ok {
+		entry.ExpiresAt = previous.ExpiresAt
+	}
+	vault.Credentials[cred.Name] = entry
+	return b.write(vault)
+}
+
+func (b *fileSecretBackend) Load(ctx context.Context, name string) (*StoredCredential, error) {
+	b.mu.Lock()
+	vault, err := b.read()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := vault.Credentials[name]
+	if !ok || !entry.Active {
+		return nil, ErrCredentialNotFound
+	}
+	data, err := b.keys.openCredential(entry.EncryptedData, entry.WrappedKey, entry.KeyVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &StoredCredential{CredentialInfo: entry.CredentialInfo, Data: data}, nil
+}
+
+func (b *fileSecretBackend) Info(ctx context.Context, name string) (*CredentialInfo, error) {
+	b.mu.Lock()
+	vault, err := b.read()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := vault.Credentials[name]
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	info := entry.CredentialInfo
+	return &info, nil
+}
+
+func (b *fileSecretBackend) List(ctx context.Context, environment string) ([]CredentialInfo, error) {
+	b.mu.Lock()
+	vault, err := b.read()
+	b.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var credentials []CredentialInfo
+	for _, entry := range vault.Credentials {
+		if entry.Environment == environment || entry.Environment == "all" {
+			credentials = append(credentials, entry.CredentialInfo)
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool { return credentials[i].Name < credentials[j].Name })
+	return credentials, nil
+}
+
+func (b *fileSecretBackend) Delete(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vault, err := b.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := vault.Credentials[name]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(vault.Credentials, name)
+	return b.write(vault)
+}
+
+// ReencryptAll rewrites the vault with every credential sealed under the
+// active master key
+func (b *fileSecretBackend) ReencryptAll(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	vault, err := b.read()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for name, entry := range vault.Credentials {
+		if b.keys.current(entry.WrappedKey, entry.KeyVersion) {
+			continue
+		}
+		data, err := b.keys.openCredential(entry.EncryptedData, entry.WrappedKey, entry.KeyVersion)
+		if err != nil {
+			return 0, fmt.Errorf("credential '%s': %w", name, err)
+		}
+		if entry.EncryptedData, entry.WrappedKey, entry.KeyVersion, err = b.keys.sealCredential(data); err != nil {
+			return 0, fmt.Errorf("credential '%s': %w", name, err)
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, b.write(vault)
+}
+
+// ==============================================================================
+// Environment Backend
+// ==============================================================================
+
+// envCredentialPrefix starts the environment variables envSecretBackend
+// reads, e.g. RUNTIME_CREDENTIAL_CUSTODY_API_KEY for custody_api_key
+const envCredentialPrefix = "RUNTIME_CREDENTIAL_"
+
+// envSecretBackend reads credentials from environment variables holding a
+// JSON object of credential fields, with an optional credential_type member
+// (default custom). It is read-only and serves every environment.
+type envSecretBackend struct{}
+
+func (b *envSecretBackend) Name() string { return "env" }
+
+func envCredentialVar(name string) string {
+	var sb strings.Builder
+	sb.WriteString(envCredentialPrefix)
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+func (b *envSecretBackend) Store(ctx context.Context, cred *StoredCredential) error {
+	return fmt.Errorf("the env credential backend is read-only; set %s instead", envCredentialVar(cred.Name))
+}
+
+func (b *envSecretBackend) Delete(ctx context.Context, name string) error {
+	return fmt.Errorf("the env credential backend is read-only; unset %s instead", envCredentialVar(name))
+}
+
+func (b *envSecretBackend) Load(ctx context.Context, name string) (*StoredCredential, error) {
+	value, ok := os.LookupEnv(envCredentialVar(name))
+	if !ok {
+		return nil, ErrCredentialNotFound
+	}
+	return parseEnvCredential(name, value)
+}
+
+func (b *envSecretBackend) Info(ctx context.Context, name string) (*CredentialInfo, error) {
+	cred, err := b.Load(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &cred.CredentialInfo, nil
+}
+
+func (b *envSecretBackend) List(ctx context.Context, environment string) ([]CredentialInfo, error) {
+	var credentials []CredentialInfo
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, envCredentialPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(key, envCredentialPrefix))
+		cred, err := parseEnvCredential(name, value)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, cred.CredentialInfo)
+	}
+	sort.Slice(credentials, func(i, j int) bool { return credentials[i].Name < credentials[j].Name })
+	return credentials, nil
+}
+
+func parseEnvCredential(name, value string) (*StoredCredential, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object: %w", envCredentialVar(name), err)
+	}
+	credType, _ := data["credential_type"].(string)
+	delete(data, "credential_type")
+	if credType == "" {
+		credType = "custom"
+	}
+	return &StoredCredential{
+		CredentialInfo: CredentialInfo{Name: name, Type: credType, Environment: "all", Active: true},
+		Data:           data,
+	}, nil
+}
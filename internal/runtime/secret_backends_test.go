@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recordingAuditor struct {
+	accesses []CredentialAccess
+	err      error
+}
+
+func (a *recordingAuditor) RecordAccess(ctx context.Context, access *CredentialAccess) error {
+	a.accesses = append(a.accesses, *access)
+	return a.err
+}
+
+func TestFileSecretBackend_MasterKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.vault")
+	v1 := newKeyring(map[int]string{1: "old-secret"})
+
+	cm := NewCredentialManagerWithBackend(newFileSecretBackend(path, v1), &recordingAuditor{})
+	if err := cm.CreateAPIKeyCredentials(ctx, "custody", "test", "key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.CreateBasicAuthCredentials(ctx, "kyc", "test", "svc", "pw"); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "key-1") || strings.Contains(string(raw), `"pw"`) {
+		t.Fatalf("vault file holds plaintext secrets:\n%s", raw)
+	}
+
+	// Introduce master key 2 and re-encrypt
+	both, err := parseKeyring("1:old-secret, 2:new-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm = NewCredentialManagerWithBackend(newFileSecretBackend(path, both), &recordingAuditor{})
+	if n, err := cm.RotateMasterKey(ctx); err != nil || n != 2 {
+		t.Fatalf("RotateMasterKey = %d, %v; want 2", n, err)
+	}
+	if n, err := cm.RotateMasterKey(ctx); err != nil || n != 0 {
+		t.Fatalf("second RotateMasterKey = %d, %v; want 0", n, err)
+	}
+
+	// Retire master key 1
+	cm = NewCredentialManagerWithBackend(newFileSecretBackend(path, newKeyring(map[int]string{2: "new-secret"})), &recordingAuditor{})
+	creds, err := cm.GetCredentials(ctx, "custody")
+	if err != nil {
+		t.Fatalf("GetCredentials after rotation: %v", err)
+	}
+	if creds["api_key"] != "key-1" {
+		t.Errorf("api_key = %v", creds["api_key"])
+	}
+	info, err := cm.GetCredentialsMetadata(ctx, "kyc")
+	if err != nil || info.KeyVersion != 2 {
+		t.Errorf("metadata = %+v, %v; want key version 2", info, err)
+	}
+}
+
+func TestKeyring_OpensLegacyCiphertext(t *testing.T) {
+	// Credentials stored before envelope encryption were sealed with the
+	// SHA-256 of CREDENTIALS_ENCRYPTION_KEY and have no wrapped key
+	key := sha256.Sum256([]byte("legacy"))
+	ciphertext, err := gcmSeal(key[:], []byte(`{"token":"t"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := newKeyring(map[int]string{1: "legacy", 2: "current"})
+	data, err := k.openCredential(ciphertext, nil, 1)
+	if err != nil || data["token"] != "t" {
+		t.Fatalf("openCredential = %v, %v", data, err)
+	}
+	if k.current(nil, 1) {
+		t.Error("a legacy credential should be re-encrypted on rotation")
+	}
+
+	if _, err := newKeyring(map[int]string{2: "current"}).openCredential(ciphertext, nil, 1); err == nil {
+		t.Error("expected an error for a retired master key version")
+	}
+	for _, spec := range []string{"old", "0:x", "1:", "1:a,1:b"} {
+		if _, err := parseKeyring(spec); err == nil {
+			t.Errorf("parseKeyring(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestGetCredentials_AuditsEveryAccess(t *testing.T) {
+	t.Setenv(envCredentialVar("custody-api"), `{"credential_type": "api_key", "api_key": "k"}`)
+	ctx := WithCredentialAccessor(context.Background(), "action:a1 execution:e1")
+	auditor := &recordingAuditor{}
+	cm := NewCredentialManagerWithBackend(&envSecretBackend{}, auditor)
+
+	creds, err := cm.GetCredentials(ctx, "custody-api")
+	if err != nil || creds["api_key"] != "k" {
+		t.Fatalf("GetCredentials = %v, %v", creds, err)
+	}
+	if _, err := cm.GetCredentials(context.Background(), "missing"); err == nil {
+		t.Fatal("expected an error for a missing credential")
+	}
+
+	want := []struct{ name, accessor, outcome string }{
+		{"custody-api", "action:a1 execution:e1", CredentialAccessGranted},
+		{"missing", "unattributed", CredentialAccessNotFound},
+	}
+	if len(auditor.accesses) != len(want) {
+		t.Fatalf("recorded %d accesses, want %d", len(auditor.accesses), len(want))
+	}
+	for i, w := range want {
+		got := auditor.accesses[i]
+		if got.CredentialName != w.name || got.Accessor != w.accessor || got.Outcome != w.outcome || got.Backend != "env" {
+			t.Errorf("access %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// An access that cannot be audited is refused
+	auditor.err = errors.New("audit log unavailable")
+	if _, err := cm.GetCredentials(ctx, "custody-api"); err == nil {
+		t.Error("expected credentials to be withheld when the audit fails")
+	}
+
+	if err := cm.StoreCredentials(ctx, "custody-api", "api_key", "test", map[string]interface{}{"api_key": "x"}); err == nil {
+		t.Error("the env backend should be read-only")
+	}
+	if _, err := cm.RotateMasterKey(ctx); err == nil {
+		t.Error("the env backend has no master key to rotate")
+	}
+}
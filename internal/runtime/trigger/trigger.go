@@ -0,0 +1,702 @@
+// Package trigger implements the predicate language of action trigger
+// conditions. A condition such as
+//
+//	state >= KYC_DISCOVERED && attr(jurisdiction) in ["LU", "IE"]
+//
+// is compiled and type-checked once, when the action definition is stored,
+// and evaluated against an Env every time the DSL of a CBU changes.
+//
+// The language is deliberately small: boolean operators (&&, ||, !),
+// comparisons (==, !=, <, <=, >, >=), list membership (in, not in), string,
+// number and boolean literals, onboarding state constants and four built-in
+// functions:
+//
+//	attr(name)    the resolved value of an attribute, by name or ID
+//	has(name)     whether the attribute has a resolved value
+//	uses(verb)    whether the DSL contains a form with this verb
+//	domain(name)  whether the DSL uses a verb of this domain (kyc, case, ...)
+//
+// There is no assignment, iteration or user-defined function, evaluation
+// only reads from the Env, and expressions are bounded in length and
+// nesting, so a stored condition can do nothing but answer true or false.
+package trigger
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dsl-ob-poc/internal/store"
+)
+
+// Limits that keep stored conditions cheap to evaluate
+const (
+	MaxLength = 2048
+	MaxDepth  = 32
+)
+
+// States lists the onboarding states in progression order; state
+// comparisons with <, <=, > and >= use this order
+var States = []store.OnboardingState{
+	store.StateCreated,
+	store.StateProductsAdded,
+	store.StateKYCDiscovered,
+	store.StateServicesDiscovered,
+	store.StateResourcesDiscovered,
+	store.StateAttributesPopulated,
+	store.StateCompleted,
+}
+
+// Env supplies the facts a condition is evaluated against
+type Env interface {
+	// State is the CBU's current onboarding state, or "" if unknown
+	State() string
+	// Verbs lists the verbs of the forms in the DSL
+	Verbs() []string
+	// Attribute returns the resolved value of an attribute given by name
+	// or ID, or nil when it has none
+	Attribute(name string) any
+}
+
+// Expr is a compiled condition
+type Expr struct {
+	src  string
+	root node
+}
+
+// String returns the source the expression was compiled from
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates the condition against env
+func (e *Expr) Eval(env Env) bool {
+	v, _ := e.root.eval(env).(bool)
+	return v
+}
+
+// Error is a compile error with the byte offset it was found at
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("trigger condition: %s at offset %d", e.Msg, e.Pos)
+}
+
+// Compile parses and type-checks src, which must be a boolean expression
+func Compile(src string) (*Expr, error) {
+	if len(src) > MaxLength {
+		return nil, &Error{Pos: MaxLength, Msg: fmt.Sprintf("condition is longer than %d bytes", MaxLength)}
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if root.typ() != typeBool {
+		return nil, &Error{Pos: 0, Msg: "condition must be a boolean expression"}
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// Quote renders name as an argument for attr, has, uses or domain
+func Quote(name string) string {
+	return strconv.Quote(name)
+}
+
+// ==============================================================================
+// Lexer
+// ==============================================================================
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators, longest first so that "<=" wins over "<"
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, &Error{Pos: i, Msg: "unterminated string"}
+			}
+			text, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: "invalid string literal"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			end := i + 1
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[i:end], pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(src) && isIdentPart(src[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, text: "end of condition", pos: len(src)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// isIdentPart admits the dots and dashes of verbs and attribute names
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9' || c == '.' || c == '-'
+}
+
+// ==============================================================================
+// Parser and type checker
+// ==============================================================================
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if tok := p.peek(); tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if tok := p.peek(); !p.accept(tokOp, text) {
+		return &Error{Pos: tok.pos, Msg: fmt.Sprintf("expected %q, found %q", text, tok.text)}
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return &Error{Pos: p.peek().pos, Msg: fmt.Sprintf("condition is nested deeper than %d", MaxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) leave() { p.depth-- }
+
+// parseOr parses or = and { "||" and }
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokOp, "||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+}
+
+// parseAnd parses and = unary { "&&" unary }
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if !p.accept(tokOp, "&&") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireBool(tok, left, right); err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+}
+
+// parseUnary parses unary = "!" unary | comparison
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+	if !p.accept(tokOp, "!") {
+		return p.parseComparison()
+	}
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if err := requireBool(tok, operand); err != nil {
+		return nil, err
+	}
+	return &notNode{operand: operand}, nil
+}
+
+// parseComparison parses comparison = operand [ op operand | ["not"] "in" list ]
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	negate := false
+	if tok.kind == tokIdent && tok.text == "not" {
+		p.next()
+		negate = true
+		if in := p.peek(); in.kind != tokIdent || in.text != "in" {
+			return nil, &Error{Pos: in.pos, Msg: fmt.Sprintf("expected \"in\" after \"not\", found %q", in.text)}
+		}
+	}
+	if p.accept(tokIdent, "in") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list, ok := right.(*listNode)
+		if !ok {
+			return nil, &Error{Pos: tok.pos, Msg: "\"in\" must be followed by a list literal"}
+		}
+		if left.typ() == typeList || left.typ() == typeBool {
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("cannot test %s for list membership", left.typ())}
+		}
+		if err := checkStateOperands(tok, left, list.items...); err != nil {
+			return nil, err
+		}
+		return &inNode{negate: negate, left: left, list: list}, nil
+	}
+
+	if tok.kind != tokOp || !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, tok.text) {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.typ() == typeList || right.typ() == typeList {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("cannot compare lists with %s", tok.text)}
+	}
+	ordered := tok.text != "==" && tok.text != "!="
+	if ordered && (left.typ() == typeBool || right.typ() == typeBool) {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("cannot order booleans with %s", tok.text)}
+	}
+	if err := checkStateOperands(tok, left, right); err != nil {
+		return nil, err
+	}
+	return &compareNode{op: tok.text, left: left, right: right}, nil
+}
+
+// parseOperand parses a literal, list, state constant, function call or
+// parenthesised expression
+func (p *parser) parseOperand() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString:
+		return &literalNode{value: tok.text, t: typeValue}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return &literalNode{value: f, t: typeValue}, nil
+	case tokIdent:
+		return p.parseIdentifier(tok)
+	case tokOp:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+}
+
+func (p *parser) parseIdentifier(tok token) (node, error) {
+	switch tok.text {
+	case "true", "false":
+		return &literalNode{value: tok.text == "true", t: typeBool}, nil
+	case "state":
+		return &stateNode{}, nil
+	}
+
+	if fn, ok := functions[tok.text]; ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		argTok := p.next()
+		if argTok.kind != tokIdent && argTok.kind != tokString || argTok.text == "" {
+			return nil, &Error{Pos: argTok.pos, Msg: fmt.Sprintf("%s expects a name, found %q", tok.text, argTok.text)}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &callNode{name: tok.text, fn: fn, arg: argTok.text}, nil
+	}
+
+	if stateIndex(tok.text) >= 0 {
+		return &literalNode{value: tok.text, t: typeState}, nil
+	}
+	if strings.ToUpper(tok.text) == tok.text {
+		return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unknown onboarding state %s", tok.text)}
+	}
+	return nil, &Error{Pos: tok.pos, Msg: fmt.Sprintf("unknown identifier %s", tok.text)}
+}
+
+// parseList parses the rest of [ literal { "," literal } ]
+func (p *parser) parseList() (node, error) {
+	list := &listNode{}
+	if p.accept(tokOp, "]") {
+		return list, nil
+	}
+	for {
+		tok := p.peek()
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := item.(*literalNode); !ok {
+			return nil, &Error{Pos: tok.pos, Msg: "list elements must be literals"}
+		}
+		list.items = append(list.items, item)
+		if !p.accept(tokOp, ",") {
+			return list, p.expect("]")
+		}
+	}
+}
+
+func requireBool(op token, operands ...node) error {
+	for _, n := range operands {
+		if n.typ() != typeBool {
+			return &Error{Pos: op.pos, Msg: fmt.Sprintf("%s needs boolean operands, found %s", op.text, n.typ())}
+		}
+	}
+	return nil
+}
+
+// checkStateOperands rejects comparing the onboarding state with anything
+// but another state, and string literals that name no state
+func checkStateOperands(op token, left node, right ...node) error {
+	operands := append([]node{left}, right...)
+	hasState := slices.ContainsFunc(operands, func(n node) bool { return n.typ() == typeState })
+	if !hasState {
+		return nil
+	}
+	for _, n := range operands {
+		if n.typ() == typeState {
+			continue
+		}
+		lit, ok := n.(*literalNode)
+		if s, isString := lit.valueString(); ok && isString && stateIndex(s) >= 0 {
+			lit.t = typeState
+			continue
+		}
+		return &Error{Pos: op.pos, Msg: fmt.Sprintf("cannot compare an onboarding state with %s", n.typ())}
+	}
+	return nil
+}
+
+func stateIndex(state string) int {
+	return slices.Index(States, store.OnboardingState(state))
+}
+
+// ==============================================================================
+// Evaluation
+// ==============================================================================
+
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeValue
+	typeState
+	typeList
+)
+
+func (t valueType) String() string {
+	return [...]string{"a boolean", "a value", "an onboarding state", "a list"}[t]
+}
+
+type node interface {
+	typ() valueType
+	eval(env Env) any
+}
+
+type function struct {
+	t    valueType
+	call func(env Env, arg string) any
+}
+
+var functions = map[string]function{
+	"attr": {typeValue, func(env Env, name string) any { return env.Attribute(name) }},
+	"has":  {typeBool, func(env Env, name string) any { return env.Attribute(name) != nil }},
+	"uses": {typeBool, func(env Env, verb string) any { return slices.Contains(env.Verbs(), verb) }},
+	"domain": {typeBool, func(env Env, domain string) any {
+		// Verbs are namespaced by domain, as in kyc.start or case.create
+		return slices.ContainsFunc(env.Verbs(), func(verb string) bool { return strings.HasPrefix(verb, domain+".") })
+	}},
+}
+
+type literalNode struct {
+	value any
+	t     valueType
+}
+
+func (n *literalNode) typ() valueType { return n.t }
+func (n *literalNode) eval(Env) any   { return n.value }
+
+func (n *literalNode) valueString() (string, bool) {
+	if n == nil {
+		return "", false
+	}
+	s, ok := n.value.(string)
+	return s, ok
+}
+
+type stateNode struct{}
+
+func (stateNode) typ() valueType   { return typeState }
+func (stateNode) eval(env Env) any { return env.State() }
+
+type callNode struct {
+	name string
+	fn   function
+	arg  string
+}
+
+func (n *callNode) typ() valueType   { return n.fn.t }
+func (n *callNode) eval(env Env) any { return n.fn.call(env, n.arg) }
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) typ() valueType { return typeList }
+func (n *listNode) eval(Env) any   { return nil }
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) typ() valueType { return typeBool }
+
+func (n *logicalNode) eval(env Env) any {
+	left, _ := n.left.eval(env).(bool)
+	if left == n.or {
+		return left
+	}
+	right, _ := n.right.eval(env).(bool)
+	return right
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) typ() valueType { return typeBool }
+
+func (n *notNode) eval(env Env) any {
+	v, _ := n.operand.eval(env).(bool)
+	return !v
+}
+
+type inNode struct {
+	negate bool
+	left   node
+	list   *listNode
+}
+
+func (n *inNode) typ() valueType { return typeBool }
+
+func (n *inNode) eval(env Env) any {
+	v := n.left.eval(env)
+	found := false
+	if v != nil {
+		for _, item := range n.list.items {
+			if equal(v, item.eval(env)) {
+				found = true
+				break
+			}
+		}
+	}
+	return found != n.negate
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) typ() valueType { return typeBool }
+
+func (n *compareNode) eval(env Env) any {
+	left, right := n.left.eval(env), n.right.eval(env)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	var cmp int
+	if n.left.typ() == typeState || n.right.typ() == typeState {
+		l, r := stateIndex(fmt.Sprint(left)), stateIndex(fmt.Sprint(right))
+		if l < 0 || r < 0 {
+			return false // an unknown state is not ordered
+		}
+		cmp = l - r
+	} else {
+		var ok bool
+		if cmp, ok = compare(left, right); !ok {
+			return false
+		}
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// equal compares values as decoded from JSON; a missing value (nil) equals
+// nothing
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	ab, aok := a.(bool)
+	bb, bok := b.(bool)
+	return aok && bok && ab == bb
+}
+
+// compare orders two numbers or two strings
+func compare(a, b any) (int, bool) {
+	if af, ok := toNumber(a); ok {
+		if bf, ok := toNumber(b); ok {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(as, bs), true
+	}
+	return 0, false
+}
+
+func toNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package trigger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeEnv is an Env backed by literal facts
+type fakeEnv struct {
+	state      string
+	verbs      []string
+	attributes map[string]any
+}
+
+func (e fakeEnv) State() string             { return e.state }
+func (e fakeEnv) Verbs() []string           { return e.verbs }
+func (e fakeEnv) Attribute(name string) any { return e.attributes[name] }
+
+func TestEval(t *testing.T) {
+	env := fakeEnv{
+		state: "SERVICES_DISCOVERED",
+		verbs: []string{"case.create", "kyc.start", "services.discover"},
+		attributes: map[string]any{
+			"jurisdiction": "LU",
+			"aum":          float64(250000000),
+			"pep":          false,
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`state >= KYC_DISCOVERED && attr(jurisdiction) in ["LU","IE"]`, true},
+		{`state >= RESOURCES_DISCOVERED`, false},
+		{`state < COMPLETED`, true},
+		{`state == "SERVICES_DISCOVERED"`, true},
+		{`state in [CREATED, PRODUCTS_ADDED]`, false},
+		{`attr(jurisdiction) not in ["US"]`, true},
+		{`attr(aum) > 100000000 && attr(aum) <= 250000000`, true},
+		{`attr(pep) == false`, true},
+		{`attr("jurisdiction") == "lu"`, false},
+		{`has(jurisdiction) && !has(missing)`, true},
+		{`attr(missing) == "LU" || attr(missing) in ["LU"]`, false},
+		{`attr(missing) != "LU"`, true},
+		{`attr(missing) < 10`, false},
+		{`uses(kyc.start) && !uses(ubo.calculate)`, true},
+		{`domain(services) && !domain(ubo)`, true},
+		{`domain(kyc.start)`, false},
+		{`(true || false) && !(false)`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Eval(env); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEval_UnknownStateIsUnordered(t *testing.T) {
+	expr, err := Compile(`state >= CREATED || state == CREATED`)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if expr.Eval(fakeEnv{}) {
+		t.Error("expected a CBU without an onboarding state not to match")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+		pos     int
+	}{
+		{`attr(jurisdiction)`, "condition must be a boolean expression", 0},
+		{`state >= KYC_DONE`, "unknown onboarding state KYC_DONE", 9},
+		{`status == "x"`, "unknown identifier status", 0},
+		{`has(x) && attr(y)`, "&& needs boolean operands, found a value", 7},
+		{`state > attr(x)`, "cannot compare an onboarding state with a value", 6},
+		{`state == "kyc"`, "cannot compare an onboarding state with a value", 6},
+		{`attr(x) in "LU"`, "\"in\" must be followed by a list literal", 8},
+		{`attr(x) in [attr(y)]`, "list elements must be literals", 12},
+		{`true > false`, "cannot order booleans with >", 5},
+		{`has(x) &&`, "unexpected \"end of condition\"", 9},
+		{`exec("rm -rf /")`, "unknown identifier exec", 0},
+		{`has(x) ; has(y)`, "unexpected character ';'", 7},
+		{`attr("x) == 1`, "unterminated string", 5},
+		{`attr(x) not [1]`, "expected \"in\" after \"not\", found \"[\"", 12},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		var compileErr *Error
+		if !errors.As(err, &compileErr) {
+			t.Errorf("Compile(%q): expected a compile error, got %v", tt.expr, err)
+			continue
+		}
+		if compileErr.Msg != tt.message || compileErr.Pos != tt.pos {
+			t.Errorf("Compile(%q): got %q at %d, want %q at %d", tt.expr, compileErr.Msg, compileErr.Pos, tt.message, tt.pos)
+		}
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	if _, err := Compile(strings.Repeat("(", MaxDepth+1) + "true" + strings.Repeat(")", MaxDepth+1)); err == nil {
+		t.Error("expected deeply nested conditions to be rejected")
+	}
+	if _, err := Compile(strings.Repeat("true && ", MaxLength/8) + "true"); err == nil {
+		t.Error("expected overlong conditions to be rejected")
+	}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dsl-ob-poc/internal/runtime/trigger"
+	"dsl-ob-poc/internal/shared-dsl/parser"
+
+	"github.com/google/uuid"
+)
+
+// CompileTriggerConditions compiles an action's trigger conditions into one
+// predicate: the expression together with the domain, state and
+// attribute_requirements shorthands, all of which must hold. Conditions with
+// none of them compile to nil, which always triggers.
+func CompileTriggerConditions(raw json.RawMessage) (*trigger.Expr, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var conditions TriggerConditions
+	if err := json.Unmarshal(raw, &conditions); err != nil {
+		return nil, fmt.Errorf("invalid trigger conditions: %w", err)
+	}
+
+	var clauses []string
+	if conditions.Expression != nil && strings.TrimSpace(*conditions.Expression) != "" {
+		// Compile on its own first so that error offsets match what was written
+		if _, err := trigger.Compile(*conditions.Expression); err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, "("+*conditions.Expression+")")
+	}
+	if conditions.Domain != nil && *conditions.Domain != "" {
+		clauses = append(clauses, "domain("+trigger.Quote(*conditions.Domain)+")")
+	}
+	if conditions.State != nil && *conditions.State != "" {
+		clauses = append(clauses, "state == "+trigger.Quote(strings.ToUpper(*conditions.State)))
+	}
+	for _, attr := range conditions.AttributeRequirements {
+		clauses = append(clauses, "has("+trigger.Quote(attr)+")")
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	expr, err := trigger.Compile(strings.Join(clauses, " && "))
+	if err != nil {
+		return nil, fmt.Errorf("invalid trigger conditions: %w", err)
+	}
+	return expr, nil
+}
+
+// triggerEnv evaluates trigger conditions for one DSL change: verbs come
+// from the parsed DSL, the state from the CBU's onboarding session, and
+// attributes through the engine's resolver, each resolved at most once
+type triggerEnv struct {
+	ctx        context.Context
+	ee         *ExecutionEngine
+	state      string
+	verbs      []string
+	resolution *AttributeResolutionContext
+	attributes map[string]any
+}
+
+func (ee *ExecutionEngine) newTriggerEnv(ctx context.Context, cbuID, dslVersionID, dslContent, environment string) *triggerEnv {
+	env := &triggerEnv{
+		ctx: ctx,
+		ee:  ee,
+		resolution: &AttributeResolutionContext{
+			CBUID:        cbuID,
+			DSLVersionID: dslVersionID,
+			DSLContent:   dslContent,
+			Environment:  environment,
+		},
+		attributes: make(map[string]any),
+	}
+
+	if session, err := ee.dataStore.GetOnboardingSession(ctx, cbuID); err == nil {
+		env.state = string(session.CurrentState)
+	}
+
+	// A document that does not parse uses no verbs
+	if ast, err := parser.Parse(dslContent); err == nil {
+		env.verbs = ast.ExtractVerbs()
+	}
+
+	return env
+}
+
+func (env *triggerEnv) State() string { return env.state }
+
+func (env *triggerEnv) Verbs() []string { return env.verbs }
+
+func (env *triggerEnv) Attribute(name string) any {
+	if value, ok := env.attributes[name]; ok {
+		return value
+	}
+
+	var value any
+	attributeID := name
+	if _, err := uuid.Parse(name); err != nil {
+		attributeID = ""
+		if attr, err := env.ee.dataStore.GetDictionaryAttributeByName(env.ctx, name); err == nil {
+			attributeID = attr.AttributeID
+		}
+	}
+	if attributeID != "" {
+		if resolved, err := env.ee.attributeResolver.ResolveAttribute(env.ctx, attributeID, env.resolution); err == nil {
+			value = resolved.Value
+		}
+	}
+
+	env.attributes[name] = value
+	return value
+}
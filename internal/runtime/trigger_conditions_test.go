@@ -0,0 +1,69 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// triggerFacts is a trigger.Env backed by literal facts
+type triggerFacts struct {
+	state      string
+	verbs      []string
+	attributes map[string]any
+}
+
+func (f triggerFacts) State() string             { return f.state }
+func (f triggerFacts) Verbs() []string           { return f.verbs }
+func (f triggerFacts) Attribute(name string) any { return f.attributes[name] }
+
+func TestCompileTriggerConditions(t *testing.T) {
+	raw := json.RawMessage(`{
+		"expression": "attr(jurisdiction) in [\"LU\", \"IE\"]",
+		"domain": "kyc",
+		"state": "kyc_discovered",
+		"attribute_requirements": ["legal-name"]
+	}`)
+	expr, err := CompileTriggerConditions(raw)
+	if err != nil {
+		t.Fatalf("CompileTriggerConditions failed: %v", err)
+	}
+	want := `(attr(jurisdiction) in ["LU", "IE"]) && domain("kyc") && state == "KYC_DISCOVERED" && has("legal-name")`
+	if expr.String() != want {
+		t.Errorf("got %s, want %s", expr, want)
+	}
+
+	facts := triggerFacts{
+		state:      "KYC_DISCOVERED",
+		verbs:      []string{"kyc.start"},
+		attributes: map[string]any{"jurisdiction": "LU", "legal-name": "Acme"},
+	}
+	if !expr.Eval(facts) {
+		t.Error("expected the conditions to hold")
+	}
+	// A DSL that merely mentions the state or attribute in text no longer matches
+	facts.state = "CREATED"
+	if expr.Eval(facts) {
+		t.Error("expected the state clause to fail")
+	}
+}
+
+func TestCompileTriggerConditions_Empty(t *testing.T) {
+	for _, raw := range []string{``, `{}`, `{"expression": "  "}`} {
+		expr, err := CompileTriggerConditions(json.RawMessage(raw))
+		if err != nil || expr != nil {
+			t.Errorf("%q: expected no condition, got %v (%v)", raw, expr, err)
+		}
+	}
+}
+
+func TestCompileTriggerConditions_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"expression": "state >= KYC_DONE"}`,
+		`{"state": "kyc"}`,
+		`{"expression": 42}`,
+	} {
+		if _, err := CompileTriggerConditions(json.RawMessage(raw)); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
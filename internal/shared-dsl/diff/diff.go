@@ -0,0 +1,471 @@
+// Package diff compares DSL documents structurally.
+//
+// Documents are parsed with the shared parser and compared form by form, so
+// reordering whitespace or comments produces no change, nor does reordering
+// repeated nested forms such as (document ...) entries, while edits are
+// reported as added, removed or changed top-level forms, argument changes
+// inside a form, and attribute binding changes ((bind (attr-id ...) (value ...))).
+//
+// Forms are aligned in two passes: identical forms are matched in document
+// order (longest common subsequence), then the remaining forms are paired by
+// identity key - the verb plus its first literal argument, e.g.
+// resource.create:"CustodyAccount" - so an edited form is reported as changed
+// rather than as a removal and an addition.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dsl-ob-poc/internal/shared-dsl/format"
+	"dsl-ob-poc/internal/shared-dsl/parser"
+)
+
+// Kind classifies a change
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Result is the structured difference between two DSL documents
+type Result struct {
+	Forms    []FormChange    `json:"forms"`
+	Bindings []BindingChange `json:"bindings"`
+}
+
+// FormChange describes a top-level form that was added, removed or changed
+type FormChange struct {
+	Kind    Kind        `json:"kind"`
+	Verb    string      `json:"verb"`
+	Key     string      `json:"key"`
+	Old     string      `json:"old,omitempty"`
+	New     string      `json:"new,omitempty"`
+	OldLine int         `json:"old_line,omitempty"`
+	NewLine int         `json:"new_line,omitempty"`
+	Args    []ArgChange `json:"args,omitempty"`
+}
+
+// ArgChange describes an argument change inside a changed form. Name is the
+// nested form's verb ("cbu.id"), the keyword (":legal-name"), or empty for
+// positional values.
+type ArgChange struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// BindingChange describes an attribute whose bound value was added, removed or changed
+type BindingChange struct {
+	Kind        Kind   `json:"kind"`
+	AttributeID string `json:"attribute_id"`
+	Old         string `json:"old,omitempty"`
+	New         string `json:"new,omitempty"`
+}
+
+// Empty reports whether the documents are semantically identical
+func (r *Result) Empty() bool {
+	return len(r.Forms) == 0 && len(r.Bindings) == 0
+}
+
+// form is a parsed top-level expression with its alignment data
+type form struct {
+	node *parser.Node
+	verb string
+	key  string
+	text string // canonical single-line rendering
+	norm string // rendering used for comparison, see normalized
+	src  string // original text, with the comments preceding and trailing it
+	lead string // the comments preceding the form
+}
+
+func parseForms(src string) ([]form, error) {
+	ast, err := parser.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	lineStarts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	forms := make([]form, 0, len(ast.Root.Children))
+	prevEnd := 0
+	for i, n := range ast.Root.Children {
+		start := lineStarts[n.Line-1] + n.Column - 1
+		end := formEnd(src, start)
+		if i == len(ast.Root.Children)-1 {
+			end = len(src)
+		}
+		forms = append(forms, form{
+			node: n, verb: n.Value, key: identityKey(n), text: format.Canonical(n), norm: normalized(n),
+			src: strings.TrimSpace(src[prevEnd:end]), lead: strings.TrimSpace(src[prevEnd:start]),
+		})
+		prevEnd = end
+	}
+	return forms, nil
+}
+
+// formEnd returns the offset just past the form starting at start, including
+// a comment that follows it on the same line
+func formEnd(src string, start int) int {
+	depth := 0
+	i := start
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '"':
+			for i++; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case ';':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			i++
+			break
+		}
+	}
+
+	rest := i
+	for rest < len(src) && (src[rest] == ' ' || src[rest] == '\t') {
+		rest++
+	}
+	if rest < len(src) && src[rest] == ';' {
+		for rest < len(src) && src[rest] != '\n' {
+			rest++
+		}
+		return rest
+	}
+	return i
+}
+
+// identityKey is the verb plus its first literal argument, if any
+func identityKey(n *parser.Node) string {
+	if len(n.Children) > 1 {
+		switch first := n.Children[1]; first.Type {
+		case parser.StringNode, parser.IdentifierNode, parser.NumberNode, parser.AttributeNode:
+			return n.Value + ":" + format.Canonical(first)
+		}
+	}
+	return n.Value
+}
+
+// align matches forms of a to forms of b. The result has one entry per form
+// of a holding the index of its counterpart in b, or -1.
+func align(a, b []form) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Pass 1: longest common subsequence of identical forms
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].norm == b[j].norm {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	usedB := make([]bool, len(b))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].norm == b[j].norm:
+			match[i], usedB[j] = j, true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	// Pass 2: pair remaining forms with the same identity key, in order
+	for i := range a {
+		if match[i] >= 0 {
+			continue
+		}
+		for j := range b {
+			if !usedB[j] && b[j].key == a[i].key {
+				match[i], usedB[j] = j, true
+				break
+			}
+		}
+	}
+	return match
+}
+
+// Diff compares two DSL documents
+func Diff(oldDSL, newDSL string) (*Result, error) {
+	oldForms, err := parseForms(oldDSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old DSL: %w", err)
+	}
+	newForms, err := parseForms(newDSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new DSL: %w", err)
+	}
+
+	result := &Result{Forms: []FormChange{}, Bindings: diffBindings(bindings(oldForms), bindings(newForms))}
+
+	match := align(oldForms, newForms)
+	matchedNew := make([]bool, len(newForms))
+	for i, j := range match {
+		o := oldForms[i]
+		if j < 0 {
+			result.Forms = append(result.Forms, FormChange{
+				Kind: Removed, Verb: o.verb, Key: o.key, Old: o.text, OldLine: o.node.Line,
+			})
+			continue
+		}
+		matchedNew[j] = true
+		if n := newForms[j]; n.norm != o.norm {
+			result.Forms = append(result.Forms, FormChange{
+				Kind: Changed, Verb: n.verb, Key: n.key, Old: o.text, New: n.text,
+				OldLine: o.node.Line, NewLine: n.node.Line, Args: diffArgs(o.node, n.node),
+			})
+		}
+	}
+	for j, n := range newForms {
+		if !matchedNew[j] {
+			result.Forms = append(result.Forms, FormChange{
+				Kind: Added, Verb: n.verb, Key: n.key, New: n.text, NewLine: n.node.Line,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// namedArgs groups a form's arguments by name, keeping their order
+func namedArgs(n *parser.Node) (names []string, values map[string][]string) {
+	values = make(map[string][]string)
+	add := func(name, value string) {
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+
+	args := n.Children[1:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg.Type == parser.KeywordNode && i+1 < len(args):
+			add(arg.Value, normalized(args[i+1]))
+			i++
+		case arg.Type == parser.ExpressionNode:
+			add(arg.Value, normalizedArgs(arg))
+		default:
+			add("", normalized(arg))
+		}
+	}
+	return names, values
+}
+
+func diffArgs(oldNode, newNode *parser.Node) []ArgChange {
+	oldNames, oldValues := namedArgs(oldNode)
+	newNames, newValues := namedArgs(newNode)
+
+	names := oldNames
+	for _, name := range newNames {
+		if _, ok := oldValues[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	var changes []ArgChange
+	for _, name := range names {
+		ov, nv := oldValues[name], newValues[name]
+		// A single value on both sides is an in-place change; otherwise
+		// repeated arguments are compared as multisets
+		if len(ov) == 1 && len(nv) == 1 {
+			if ov[0] != nv[0] {
+				changes = append(changes, ArgChange{Kind: Changed, Name: name, Old: ov[0], New: nv[0]})
+			}
+			continue
+		}
+		removed, added := multisetDiff(ov, nv)
+		for _, v := range removed {
+			changes = append(changes, ArgChange{Kind: Removed, Name: name, Old: v})
+		}
+		for _, v := range added {
+			changes = append(changes, ArgChange{Kind: Added, Name: name, New: v})
+		}
+	}
+	return changes
+}
+
+// multisetDiff returns the values only in a and only in b, counting duplicates
+func multisetDiff(a, b []string) (onlyA, onlyB []string) {
+	counts := make(map[string]int)
+	for _, v := range b {
+		counts[v]++
+	}
+	for _, v := range a {
+		if counts[v] > 0 {
+			counts[v]--
+		} else {
+			onlyA = append(onlyA, v)
+		}
+	}
+	counts = make(map[string]int)
+	for _, v := range a {
+		counts[v]++
+	}
+	for _, v := range b {
+		if counts[v] > 0 {
+			counts[v]--
+		} else {
+			onlyB = append(onlyB, v)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// bindings collects attribute values bound anywhere in the document by
+// (bind (attr-id "...") (value ...)) forms; later bindings win
+func bindings(forms []form) map[string]string {
+	out := make(map[string]string)
+	var walk func(n *parser.Node)
+	walk = func(n *parser.Node) {
+		if n.Type != parser.ExpressionNode {
+			return
+		}
+		if n.Value == "bind" {
+			var attrID, value string
+			var hasValue bool
+			for _, c := range n.Children[1:] {
+				if c.Type != parser.ExpressionNode || len(c.Children) < 2 {
+					continue
+				}
+				switch c.Value {
+				case "attr-id":
+					attrID = literalValue(c.Children[1])
+				case "value":
+					value, hasValue = canonicalArgs(c), true
+				}
+			}
+			if attrID != "" && hasValue {
+				out[attrID] = value
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	for _, f := range forms {
+		walk(f.node)
+	}
+	return out
+}
+
+// literalValue returns the raw value of a literal, or the attribute ID of a reference
+func literalValue(n *parser.Node) string {
+	if n.Type == parser.AttributeNode {
+		return n.AttributeID
+	}
+	return n.Value
+}
+
+func diffBindings(oldB, newB map[string]string) []BindingChange {
+	ids := make([]string, 0, len(oldB)+len(newB))
+	for id := range oldB {
+		ids = append(ids, id)
+	}
+	for id := range newB {
+		if _, ok := oldB[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	changes := []BindingChange{}
+	for _, id := range ids {
+		ov, inOld := oldB[id]
+		nv, inNew := newB[id]
+		switch {
+		case !inOld:
+			changes = append(changes, BindingChange{Kind: Added, AttributeID: id, New: nv})
+		case !inNew:
+			changes = append(changes, BindingChange{Kind: Removed, AttributeID: id, Old: ov})
+		case ov != nv:
+			changes = append(changes, BindingChange{Kind: Changed, AttributeID: id, Old: ov, New: nv})
+		}
+	}
+	return changes
+}
+
+// String renders the result as a human-readable report
+func (r *Result) String() string {
+	if r.Empty() {
+		return "No semantic changes.\n"
+	}
+
+	var sb strings.Builder
+	symbols := map[Kind]string{Added: "+", Removed: "-", Changed: "~"}
+
+	if len(r.Forms) > 0 {
+		fmt.Fprintf(&sb, "Forms (%d changed):\n", len(r.Forms))
+		for _, f := range r.Forms {
+			switch f.Kind {
+			case Added:
+				fmt.Fprintf(&sb, "  + %s (line %d)\n      %s\n", f.Key, f.NewLine, f.New)
+			case Removed:
+				fmt.Fprintf(&sb, "  - %s (line %d)\n      %s\n", f.Key, f.OldLine, f.Old)
+			case Changed:
+				fmt.Fprintf(&sb, "  ~ %s (line %d -> %d)\n", f.Key, f.OldLine, f.NewLine)
+				for _, a := range f.Args {
+					name := a.Name
+					if name == "" {
+						name = "value"
+					}
+					switch a.Kind {
+					case Changed:
+						fmt.Fprintf(&sb, "      ~ %s: %s -> %s\n", name, a.Old, a.New)
+					case Added:
+						fmt.Fprintf(&sb, "      + %s: %s\n", name, a.New)
+					case Removed:
+						fmt.Fprintf(&sb, "      - %s: %s\n", name, a.Old)
+					}
+				}
+			}
+		}
+	}
+
+	if len(r.Bindings) > 0 {
+		fmt.Fprintf(&sb, "Attribute bindings (%d changed):\n", len(r.Bindings))
+		for _, b := range r.Bindings {
+			switch b.Kind {
+			case Changed:
+				fmt.Fprintf(&sb, "  %s %s: %s -> %s\n", symbols[b.Kind], b.AttributeID, b.Old, b.New)
+			case Added:
+				fmt.Fprintf(&sb, "  %s %s: %s\n", symbols[b.Kind], b.AttributeID, b.New)
+			case Removed:
+				fmt.Fprintf(&sb, "  %s %s: %s\n", symbols[b.Kind], b.AttributeID, b.Old)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,301 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseDSL = `(case.create
+  (cbu.id "CBU-1")
+  (nature-purpose "UCITS equity fund domiciled in LU"))
+
+(products.add "CUSTODY" "FUND_ACCOUNTING")
+
+(resources.plan
+  (resource.create "CustodyAccount" (owner "ops")))`
+
+func TestDiffIgnoresLayoutAndComments(t *testing.T) {
+	reformatted := `; reformatted
+(case.create (cbu.id "CBU-1") (nature-purpose "UCITS equity fund domiciled in LU"))
+(products.add "CUSTODY"   "FUND_ACCOUNTING")
+(resources.plan (resource.create "CustodyAccount" (owner "ops")))`
+
+	result, err := Diff(baseDSL, reformatted)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !result.Empty() {
+		t.Errorf("expected no changes, got:\n%s", result)
+	}
+}
+
+func TestDiffRepeatedChildrenAsMultiset(t *testing.T) {
+	oldDSL := `(kyc.start (documents (document "W8BEN-E") (document "PASSPORT")) (jurisdictions (jurisdiction "LU")))`
+	reordered := `(kyc.start (documents (document "PASSPORT") (document "W8BEN-E")) (jurisdictions (jurisdiction "LU")))`
+
+	result, err := Diff(oldDSL, reordered)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !result.Empty() {
+		t.Errorf("expected reordered documents to be unchanged, got:\n%s", result)
+	}
+
+	edited := `(kyc.start (documents (document "PASSPORT") (document "W9")) (jurisdictions (jurisdiction "LU")))`
+	result, err = Diff(oldDSL, edited)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(result.Forms) != 1 || len(result.Forms[0].Args) != 1 || result.Forms[0].Args[0].Name != "documents" {
+		t.Errorf("expected one documents change, got:\n%s", result)
+	}
+}
+
+func TestDiffForms(t *testing.T) {
+	newDSL := `(case.create
+  (cbu.id "CBU-1")
+  (nature-purpose "UCITS bond fund domiciled in LU"))
+
+(products.add "CUSTODY" "TRANSFER_AGENT")
+
+(kyc.start (documents (document "W8BEN-E")) (jurisdictions (jurisdiction "LU")))`
+
+	result, err := Diff(baseDSL, newDSL)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	byKey := make(map[string]FormChange)
+	for _, f := range result.Forms {
+		byKey[f.Key] = f
+	}
+	if len(result.Forms) != 4 {
+		t.Fatalf("expected 4 form changes, got %d:\n%s", len(result.Forms), result)
+	}
+
+	caseChange := byKey["case.create"]
+	if caseChange.Kind != Changed || len(caseChange.Args) != 1 || caseChange.Args[0].Name != "nature-purpose" {
+		t.Errorf("unexpected case.create change: %+v", caseChange)
+	}
+	if caseChange.OldLine != 1 || caseChange.NewLine != 1 {
+		t.Errorf("unexpected lines: %+v", caseChange)
+	}
+
+	products := byKey[`products.add:"CUSTODY"`]
+	if products.Kind != Changed || len(products.Args) != 2 ||
+		products.Args[0].Kind != Removed || products.Args[0].Old != `"FUND_ACCOUNTING"` ||
+		products.Args[1].Kind != Added || products.Args[1].New != `"TRANSFER_AGENT"` {
+		t.Errorf("unexpected products.add change: %+v", products)
+	}
+
+	if byKey["resources.plan"].Kind != Removed {
+		t.Errorf("expected resources.plan to be removed")
+	}
+	if kyc := byKey["kyc.start"]; kyc.Kind != Added || kyc.NewLine != 7 {
+		t.Errorf("unexpected kyc.start change: %+v", kyc)
+	}
+}
+
+func TestDiffKeywordArgsAndBindings(t *testing.T) {
+	oldDSL := `(investor.start-opportunity :legal-name "Acme" :domicile "LU")
+(values.bind
+  (bind (attr-id "A1") (value "Acme"))
+  (bind (attr-id "A2") (value 10)))`
+	newDSL := `(investor.start-opportunity :legal-name "Acme Capital" :domicile "LU" :type "FUND")
+(values.bind
+  (bind (attr-id "A1") (value "Acme Capital"))
+  (bind (attr-id "A3") (value true)))`
+
+	result, err := Diff(oldDSL, newDSL)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	var investor *FormChange
+	for i := range result.Forms {
+		if result.Forms[i].Verb == "investor.start-opportunity" {
+			investor = &result.Forms[i]
+		}
+	}
+	if investor == nil || investor.Kind != Changed || len(investor.Args) != 2 {
+		t.Fatalf("unexpected investor change: %+v", investor)
+	}
+	if a := investor.Args[0]; a.Name != ":legal-name" || a.Old != `"Acme"` || a.New != `"Acme Capital"` {
+		t.Errorf("unexpected keyword change: %+v", a)
+	}
+	if a := investor.Args[1]; a.Kind != Added || a.Name != ":type" {
+		t.Errorf("unexpected keyword addition: %+v", a)
+	}
+
+	want := []BindingChange{
+		{Kind: Changed, AttributeID: "A1", Old: `"Acme"`, New: `"Acme Capital"`},
+		{Kind: Removed, AttributeID: "A2", Old: "10"},
+		{Kind: Added, AttributeID: "A3", New: "true"},
+	}
+	if len(result.Bindings) != len(want) {
+		t.Fatalf("expected %d binding changes, got %+v", len(want), result.Bindings)
+	}
+	for i, b := range want {
+		if result.Bindings[i] != b {
+			t.Errorf("binding %d: expected %+v, got %+v", i, b, result.Bindings[i])
+		}
+	}
+}
+
+func TestMergeDisjointEdits(t *testing.T) {
+	// Onboarding adds KYC, the custody domain edits products
+	ours := baseDSL + "\n\n(kyc.start (documents (document \"W8BEN-E\")))"
+	theirs := strings.Replace(baseDSL, `"FUND_ACCOUNTING"`, `"FUND_ACCOUNTING" "TRANSFER_AGENT"`, 1) +
+		"\n\n(services.plan (service \"Settlement\"))"
+
+	result, err := Merge(baseDSL, ours, theirs, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", result.Conflicts)
+	}
+
+	for _, want := range []string{`"TRANSFER_AGENT"`, `(kyc.start`, `(services.plan`, `(nature-purpose "UCITS equity fund domiciled in LU")`} {
+		if !strings.Contains(result.DSL, want) {
+			t.Errorf("merged DSL missing %s:\n%s", want, result.DSL)
+		}
+	}
+	if strings.Index(result.DSL, "(kyc.start") > strings.Index(result.DSL, "(services.plan") {
+		t.Errorf("expected our additions before theirs:\n%s", result.DSL)
+	}
+
+	// The merged document has exactly the union of both sides' changes
+	d, err := Diff(baseDSL, result.DSL)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(d.Forms) != 3 {
+		t.Errorf("expected 3 changes relative to base, got:\n%s", d)
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	ours := strings.Replace(baseDSL, "UCITS equity fund", "UCITS bond fund", 1)
+	theirs := strings.Replace(baseDSL, "UCITS equity fund", "AIF equity fund", 1)
+
+	tests := []struct {
+		strategy Strategy
+		want     string
+	}{
+		{StrategyNone, "UCITS bond fund"},
+		{PreferOurs, "UCITS bond fund"},
+		{PreferTheirs, "AIF equity fund"},
+	}
+
+	for _, tt := range tests {
+		result, err := Merge(baseDSL, ours, theirs, tt.strategy)
+		if err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		if len(result.Conflicts) != 1 || result.Conflicts[0].Key != "case.create" {
+			t.Errorf("strategy %q: expected one case.create conflict, got %+v", tt.strategy, result.Conflicts)
+		}
+		if !strings.Contains(result.DSL, tt.want) {
+			t.Errorf("strategy %q: expected %q in merged DSL:\n%s", tt.strategy, tt.want, result.DSL)
+		}
+	}
+}
+
+func TestMergeRemovalAndIdenticalAdds(t *testing.T) {
+	removed := strings.Replace(baseDSL, `(products.add "CUSTODY" "FUND_ACCOUNTING")`, "", 1)
+	added := `(resource.create "Ledger" (owner "fa"))`
+
+	result, err := Merge(baseDSL, removed+"\n"+added, baseDSL+"\n"+added, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", result.Conflicts)
+	}
+	if strings.Contains(result.DSL, "products.add") {
+		t.Errorf("expected products.add removal to be kept:\n%s", result.DSL)
+	}
+	if strings.Count(result.DSL, `"Ledger"`) != 1 {
+		t.Errorf("expected identical additions once:\n%s", result.DSL)
+	}
+
+	// Different additions with the same identity key conflict
+	result, err = Merge(baseDSL, baseDSL+"\n"+added, baseDSL+"\n"+`(resource.create "Ledger" (owner "ops"))`, PreferTheirs)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || !strings.Contains(result.DSL, `(owner "ops")`) || strings.Contains(result.DSL, `(owner "fa")`) {
+		t.Errorf("expected resolved add conflict, got %+v:\n%s", result.Conflicts, result.DSL)
+	}
+}
+
+func TestMergeDifferentlyPlacedIdenticalAdds(t *testing.T) {
+	added := `(kyc.modify (add-documents (document "W9")))`
+	ours := strings.Replace(baseDSL, "\n\n(products.add", "\n\n"+added+"\n\n(products.add", 1)
+	theirs := baseDSL + "\n\n" + added
+
+	result, err := Merge(baseDSL, ours, theirs, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %+v", result.Conflicts)
+	}
+	if n := strings.Count(result.DSL, "(kyc.modify"); n != 1 {
+		t.Errorf("expected the shared addition once, got %d:\n%s", n, result.DSL)
+	}
+
+	// Both sides appending the same form twice keeps two copies
+	result, err = Merge(baseDSL, baseDSL+"\n"+added+"\n"+added, theirs+"\n"+added, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if n := strings.Count(result.DSL, "(kyc.modify"); n != 2 {
+		t.Errorf("expected two additions, got %d:\n%s", n, result.DSL)
+	}
+}
+
+func TestMergeKeepsCommentsOfUnchangedForms(t *testing.T) {
+	base := `; Case opened by onboarding
+(case.create
+  (cbu.id "CBU-1")) ; created from the CRM
+
+; Products agreed with the client
+(products.add "CUSTODY")`
+	ours := base + "\n(kyc.start (documents (document \"W8BEN-E\")))"
+	theirs := strings.Replace(base, `(products.add "CUSTODY")`, `(products.add "CUSTODY" "FUND_ACCOUNTING")`, 1)
+
+	result, err := Merge(base, ours, theirs, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	want := `; Case opened by onboarding
+(case.create
+  (cbu.id "CBU-1")) ; created from the CRM
+
+; Products agreed with the client
+(products.add "CUSTODY" "FUND_ACCOUNTING")
+
+(kyc.start (documents (document "W8BEN-E")))`
+	if result.DSL != want {
+		t.Errorf("unexpected merge:\n%s\nwant:\n%s", result.DSL, want)
+	}
+}
+
+func TestPretty(t *testing.T) {
+	result, err := Merge(`(a.b)`, `(a.b)`, `(a.b) (investor.start-opportunity :legal-name "Acme Capital Partners" :domicile "LU" :type "CORPORATE" :source "referral")`, StrategyNone)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	want := `(a.b)
+
+(investor.start-opportunity
+  :legal-name "Acme Capital Partners"
+  :domicile "LU"
+  :type "CORPORATE"
+  :source "referral")`
+	if result.DSL != want {
+		t.Errorf("unexpected layout:\n%s\nwant:\n%s", result.DSL, want)
+	}
+}
@@ -0,0 +1,232 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"dsl-ob-poc/internal/shared-dsl/format"
+)
+
+// Strategy decides how Merge resolves conflicting edits
+type Strategy string
+
+const (
+	// StrategyNone records conflicts and keeps our side in the merged DSL
+	StrategyNone Strategy = ""
+	// PreferOurs resolves conflicts with our side
+	PreferOurs Strategy = "ours"
+	// PreferTheirs resolves conflicts with their side
+	PreferTheirs Strategy = "theirs"
+)
+
+// Conflict is a form that both sides edited differently. An empty Ours or
+// Theirs means that side removed the form.
+type Conflict struct {
+	Key    string `json:"key"`
+	Base   string `json:"base,omitempty"`
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+}
+
+// MergeResult is the merged document and the conflicts found while merging
+type MergeResult struct {
+	DSL       string     `json:"dsl"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// Merge performs a three-way merge of two documents derived from base.
+//
+// Forms changed on one side only take that side's version; forms changed
+// identically on both sides are taken once. Forms added by either side are
+// placed after the base form that precedes them on that side, ours before
+// theirs; a form both sides added is taken once, from ours, even when the
+// sides placed it differently. A conflict is recorded when both sides change
+// or remove the same base form differently, or add different forms with the
+// same identity key; strategy picks the side written to the merged DSL.
+// Unchanged forms keep our original text and comments; changed and added
+// forms are rendered with format.Pretty below the comments that preceded them.
+func Merge(base, ours, theirs string, strategy Strategy) (*MergeResult, error) {
+	baseForms, err := parseForms(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base DSL: %w", err)
+	}
+	ourForms, err := parseForms(ours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse our DSL: %w", err)
+	}
+	theirForms, err := parseForms(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse their DSL: %w", err)
+	}
+
+	result := &MergeResult{Conflicts: []Conflict{}}
+	matchOurs := align(baseForms, ourForms)
+	matchTheirs := align(baseForms, theirForms)
+
+	ourAdds := additions(matchOurs, ourForms)
+	theirAdds := additions(matchTheirs, theirForms)
+	dropDuplicateAdds(ourAdds, theirAdds)
+	result.Conflicts = append(result.Conflicts, addConflicts(ourAdds, theirAdds, strategy)...)
+
+	var out []string
+	emitAdds := func(anchor int) {
+		for _, f := range ourAdds[anchor] {
+			out = append(out, f.rendered())
+		}
+		for _, f := range theirAdds[anchor] {
+			out = append(out, f.rendered())
+		}
+	}
+
+	emitAdds(-1)
+	for i, b := range baseForms {
+		ours := counterpart(matchOurs[i], ourForms)
+		theirs := counterpart(matchTheirs[i], theirForms)
+		ourChanged, theirChanged := ours.norm != b.norm, theirs.norm != b.norm
+
+		var chosen form
+		switch {
+		case !ourChanged && !theirChanged:
+			out = append(out, ours.src)
+		case !ourChanged:
+			chosen = theirs
+		case !theirChanged, ours.norm == theirs.norm:
+			chosen = ours
+		default:
+			result.Conflicts = append(result.Conflicts, Conflict{Key: b.key, Base: b.text, Ours: ours.text, Theirs: theirs.text})
+			chosen = ours
+			if strategy == PreferTheirs {
+				chosen = theirs
+			}
+		}
+		if chosen.node != nil {
+			out = append(out, chosen.rendered())
+		}
+		emitAdds(i)
+	}
+
+	result.DSL = strings.Join(out, "\n\n")
+	return result, nil
+}
+
+// rendered lays out f with format.Pretty, below the comments that preceded it
+func (f form) rendered() string {
+	if f.lead == "" {
+		return format.Pretty(f.node)
+	}
+	return f.lead + "\n" + format.Pretty(f.node)
+}
+
+// counterpart returns the matched form, or the zero form when it was removed
+func counterpart(j int, forms []form) form {
+	if j < 0 {
+		return form{}
+	}
+	return forms[j]
+}
+
+// additions groups one side's unmatched forms by the index of the base form
+// preceding them on that side (-1 for the start of the document)
+func additions(match []int, side []form) map[int][]form {
+	baseFor := make([]int, len(side))
+	for j := range baseFor {
+		baseFor[j] = -1
+	}
+	for i, j := range match {
+		if j >= 0 {
+			baseFor[j] = i
+		}
+	}
+
+	adds := make(map[int][]form)
+	anchor := -1
+	for j, f := range side {
+		if baseFor[j] >= 0 {
+			anchor = baseFor[j]
+			continue
+		}
+		adds[anchor] = append(adds[anchor], f)
+	}
+	return adds
+}
+
+// dropDuplicateAdds removes from theirAdds each form that ours also added,
+// wherever either side placed it, so the addition is emitted once from ours
+func dropDuplicateAdds(ourAdds, theirAdds map[int][]form) {
+	counts := make(map[string]int)
+	for _, forms := range ourAdds {
+		for _, f := range forms {
+			counts[f.norm]++
+		}
+	}
+	for _, anchor := range anchors(theirAdds) {
+		kept := theirAdds[anchor][:0]
+		for _, f := range theirAdds[anchor] {
+			if counts[f.norm] > 0 {
+				counts[f.norm]--
+				continue
+			}
+			kept = append(kept, f)
+		}
+		theirAdds[anchor] = kept
+	}
+}
+
+// anchors returns the keys of adds in document order
+func anchors(adds map[int][]form) []int {
+	keys := make([]int, 0, len(adds))
+	for anchor := range adds {
+		keys = append(keys, anchor)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// addConflicts reports forms both sides added under the same literal identity
+// key with different content, and drops the losing side's form. Keys made of
+// a bare verb are not identities: both sides may append, say, a kyc.modify.
+func addConflicts(ourAdds, theirAdds map[int][]form, strategy Strategy) []Conflict {
+	ours := make(map[string]form)
+	for _, forms := range ourAdds {
+		for _, f := range forms {
+			if strings.Contains(f.key, ":") {
+				ours[f.key] = f
+			}
+		}
+	}
+
+	var conflicts []Conflict
+	for _, anchor := range anchors(theirAdds) {
+		forms := theirAdds[anchor]
+		kept := forms[:0]
+		for _, f := range forms {
+			our, ok := ours[f.key]
+			if !ok || our.norm == f.norm {
+				if ok {
+					continue // identical addition on both sides, emitted from ours
+				}
+				kept = append(kept, f)
+				continue
+			}
+			conflicts = append(conflicts, Conflict{Key: f.key, Ours: our.text, Theirs: f.text})
+			if strategy == PreferTheirs {
+				replaceForm(ourAdds, f)
+			}
+		}
+		theirAdds[anchor] = kept
+	}
+	return conflicts
+}
+
+// replaceForm swaps the form in adds that has f's key for f
+func replaceForm(adds map[int][]form, f form) {
+	for _, forms := range adds {
+		for i := range forms {
+			if forms[i].key == f.key {
+				forms[i] = f
+				return
+			}
+		}
+	}
+}
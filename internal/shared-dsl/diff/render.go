@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"dsl-ob-poc/internal/shared-dsl/format"
+	"dsl-ob-poc/internal/shared-dsl/parser"
+)
+
+// normalized renders a node like format.Canonical, except that nested forms
+// sharing a verb with a sibling are sorted among themselves: repeated children
+// such as (document ...) entries are a multiset, so their order is not a change
+func normalized(n *parser.Node) string {
+	if n.Type != parser.ExpressionNode || len(n.Children) <= 1 {
+		return format.Canonical(n)
+	}
+	return "(" + n.Value + " " + normalizedArgs(n) + ")"
+}
+
+// normalizedArgs renders an expression's arguments for normalized
+func normalizedArgs(n *parser.Node) string {
+	args := n.Children[1:]
+	parts := make([]string, len(args))
+	positions := make(map[string][]int)
+	for i, a := range args {
+		parts[i] = normalized(a)
+		if a.Type == parser.ExpressionNode {
+			positions[a.Value] = append(positions[a.Value], i)
+		}
+	}
+
+	for _, idx := range positions {
+		if len(idx) < 2 {
+			continue
+		}
+		group := make([]string, len(idx))
+		for k, i := range idx {
+			group[k] = parts[i]
+		}
+		sort.Strings(group)
+		for k, i := range idx {
+			parts[i] = group[k]
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// canonicalArgs renders an expression's arguments without the verb
+func canonicalArgs(n *parser.Node) string {
+	parts := make([]string, 0, len(n.Children)-1)
+	for _, c := range n.Children[1:] {
+		parts = append(parts, format.Canonical(c))
+	}
+	return strings.Join(parts, " ")
+}
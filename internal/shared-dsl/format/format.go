@@ -0,0 +1,455 @@
+// Package format renders DSL in the repository's canonical layout.
+//
+// DSL produced by the generators, the composition engine and the agent
+// differs in whitespace, quoting and line breaks even when it means the
+// same thing, which shows up as noise between stored versions. Format
+// reprints a document through the shared parser so that equivalent input
+// yields byte-identical output:
+//
+//   - forms that fit in Width columns, or whose arguments are all plain
+//     values, stay on one line; longer forms keep their leading values on
+//     the first line and put each remaining argument on its own line,
+//     indented two spaces, with keyword/value pairs kept together
+//   - lists that do not fit put one element per line; a list that ends a
+//     form opens on the form's first line
+//   - strings are re-quoted, list elements are separated by ", "
+//   - a single blank line between arguments of a form is kept
+//   - top-level forms are separated by exactly one blank line
+//   - comments are kept: whole-line comments above the code they precede,
+//     trailing comments at the end of the line they followed
+//
+// Argument order is never changed, since it can be significant.
+package format
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"dsl-ob-poc/internal/shared-dsl/parser"
+)
+
+// Width is the line length above which a form is broken across lines
+const Width = 100
+
+// Format parses src and returns it in canonical layout. Formatting is
+// idempotent: Format(Format(src)) == Format(src).
+func Format(src string) (string, error) {
+	ast, err := parser.Parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	p := newPrinter(src)
+	forms := ast.Root.Children
+	for i, form := range forms {
+		if i > 0 {
+			p.flushTrailing()
+			p.blankLine(0)
+		}
+		if p.flush(p.offset(form), 0) && blankLineBefore(src, p.offset(form)) {
+			p.blankLine(0)
+		}
+		p.node(form, 0)
+	}
+	p.flush(len(src)+1, 0)
+
+	out := strings.TrimRight(p.out.String(), "\n")
+	if out == "" {
+		return "", nil
+	}
+	return out + "\n", nil
+}
+
+// Canonical renders a node on a single line with normalized spacing and quoting
+func Canonical(n *parser.Node) string {
+	switch n.Type {
+	case parser.ExpressionNode:
+		if len(n.Children) <= 1 {
+			return "(" + n.Value + ")"
+		}
+		return "(" + n.Value + " " + joinCanonical(n.Children[1:], " ") + ")"
+	case parser.ListNode:
+		return "[" + joinCanonical(n.Children, ", ") + "]"
+	case parser.StringNode:
+		return quote(n.Value)
+	case parser.AttributeNode:
+		if n.Name != "" {
+			return "@attr{" + n.AttributeID + ":" + n.Name + "}"
+		}
+		return "@attr{" + n.AttributeID + "}"
+	default:
+		return n.Value
+	}
+}
+
+func joinCanonical(nodes []*parser.Node, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, c := range nodes {
+		parts[i] = Canonical(c)
+	}
+	return strings.Join(parts, sep)
+}
+
+// stringEscaper escapes exactly the sequences the parser unescapes, so
+// non-ASCII text is written as is
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quote(s string) string {
+	return `"` + stringEscaper.Replace(s) + `"`
+}
+
+// isAtom reports whether n is a single value rather than a form, a list or
+// a keyword
+func isAtom(n *parser.Node) bool {
+	switch n.Type {
+	case parser.ExpressionNode, parser.ListNode, parser.KeywordNode:
+		return false
+	default:
+		return true
+	}
+}
+
+// allAtoms reports whether every node is an atom; such forms stay on one
+// line however long they are, since breaking them only moves the length
+func allAtoms(nodes []*parser.Node) bool {
+	for _, n := range nodes {
+		if !isAtom(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pretty renders a single node in canonical layout, without comments
+func Pretty(n *parser.Node) string {
+	p := newPrinter("")
+	p.node(n, 0)
+	return p.out.String()
+}
+
+// comment is a ';' comment found in the source
+type comment struct {
+	offset      int
+	text        string
+	trailing    bool // code precedes it on the same line
+	blankBefore bool // a blank line separates it from the code above
+}
+
+// printer writes nodes and the comments between them. Indentation is owed
+// rather than written when a line is started, so blank lines carry no
+// trailing spaces.
+type printer struct {
+	out        strings.Builder
+	src        string
+	lineStarts []int
+	comments   []comment
+	next       int // index of the first comment not yet written
+	pad        int // indentation owed to the current line, -1 once written
+	col        int // columns written on the current line
+	newlines   int // consecutive newlines at the end of out
+}
+
+func newPrinter(src string) *printer {
+	p := &printer{src: src, lineStarts: []int{0}}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+	p.comments = scanComments(src)
+	return p
+}
+
+// node writes n at the current position; indent is the indentation of the
+// line n starts on
+func (p *printer) node(n *parser.Node, indent int) {
+	switch n.Type {
+	case parser.ExpressionNode:
+		p.expression(n, indent)
+	case parser.ListNode:
+		p.list(n, indent)
+	default:
+		p.write(Canonical(n))
+	}
+}
+
+func (p *printer) expression(n *parser.Node, indent int) {
+	args := n.Children[1:]
+	flat := Canonical(n)
+	start, end := p.span(n)
+	if !p.hasComments(start, end) && (p.column()+len(flat) <= Width || allAtoms(args)) {
+		p.write(flat)
+		return
+	}
+
+	p.write("(" + n.Value)
+	// Leading atoms name the form, as in (resource.create "Account" ...),
+	// and stay on its first line
+	i := 0
+	for ; i < len(args) && isAtom(args[i]) && !p.hasComments(start, p.offset(args[i])); i++ {
+		p.write(" " + Canonical(args[i]))
+	}
+	// A trailing list hangs from the first line: (document_list [ ... ])
+	if i == len(args)-1 && args[i].Type == parser.ListNode && !p.hasComments(start, p.offset(args[i])) {
+		p.write(" ")
+		p.list(args[i], indent)
+		i++
+	}
+
+	inner := indent + 2
+	for ; i < len(args); i++ {
+		p.startArgument(args[i], inner)
+		if args[i].Type == parser.KeywordNode && i+1 < len(args) {
+			p.write(args[i].Value + " ")
+			i++
+		}
+		p.node(args[i], inner)
+	}
+	if p.flush(end-1, inner) {
+		p.ensureLine(indent)
+	}
+	p.write(")")
+}
+
+// list writes a list on one line when it fits, otherwise one element per
+// line with the closing bracket on a line of its own
+func (p *printer) list(n *parser.Node, indent int) {
+	flat := Canonical(n)
+	start, end := p.span(n)
+	if !p.hasComments(start, end) && p.column()+len(flat) <= Width {
+		p.write(flat)
+		return
+	}
+
+	p.write("[")
+	inner := indent + 2
+	for i, elem := range n.Children {
+		p.startArgument(elem, inner)
+		p.node(elem, inner)
+		if i < len(n.Children)-1 {
+			p.write(",")
+		}
+	}
+	p.flush(end-1, inner)
+	p.ensureLine(indent)
+	p.write("]")
+}
+
+// startArgument writes the comments preceding an argument of a broken form
+// and starts its line, keeping a blank line above it if the source had one
+func (p *printer) startArgument(arg *parser.Node, indent int) {
+	p.flush(p.offset(arg), indent)
+	if blankLineBefore(p.src, p.offset(arg)) {
+		p.blankLine(indent)
+	} else {
+		p.ensureLine(indent)
+	}
+}
+
+// flush writes the comments that start before offset and reports whether
+// there were any. Each comment ends its line.
+func (p *printer) flush(offset, indent int) bool {
+	flushed := false
+	for p.next < len(p.comments) && p.comments[p.next].offset < offset {
+		c := p.comments[p.next]
+		p.next++
+		switch {
+		case c.trailing && !p.atLineStart():
+			p.write(" ")
+		case c.blankBefore:
+			p.blankLine(indent)
+		default:
+			p.ensureLine(indent)
+		}
+		p.write(c.text)
+		p.newline(indent)
+		flushed = true
+	}
+	return flushed
+}
+
+// flushTrailing writes the trailing comments that follow the last code written
+func (p *printer) flushTrailing() {
+	for p.next < len(p.comments) && p.comments[p.next].trailing && !p.atLineStart() {
+		p.flush(p.comments[p.next].offset+1, 0)
+	}
+}
+
+func (p *printer) write(s string) {
+	if p.pad >= 0 {
+		p.out.WriteString(strings.Repeat(" ", p.pad))
+		p.col, p.pad = p.pad, -1
+	}
+	p.out.WriteString(s)
+	p.col += utf8.RuneCountInString(s)
+	p.newlines = 0
+}
+
+func (p *printer) newline(indent int) {
+	p.out.WriteByte('\n')
+	p.newlines++
+	p.col, p.pad = 0, indent
+}
+
+// column is the column the next write starts at, counting from 0
+func (p *printer) column() int {
+	if p.atLineStart() {
+		return p.pad
+	}
+	return p.col
+}
+
+func (p *printer) atLineStart() bool {
+	return p.pad >= 0
+}
+
+// ensureLine starts a new line unless the current one is still empty
+func (p *printer) ensureLine(indent int) {
+	if !p.atLineStart() {
+		p.newline(indent)
+	}
+	p.pad = indent
+}
+
+// blankLine starts a new line preceded by one empty line, except at the
+// start of the output
+func (p *printer) blankLine(indent int) {
+	p.ensureLine(indent)
+	for p.out.Len() > 0 && p.newlines < 2 {
+		p.newline(indent)
+	}
+}
+
+// offset converts a node's line and column to a byte offset in the source
+func (p *printer) offset(n *parser.Node) int {
+	if p.src == "" || n.Line < 1 || n.Line > len(p.lineStarts) {
+		return 0
+	}
+	return p.lineStarts[n.Line-1] + n.Column - 1
+}
+
+// span returns the byte range a node occupies in the source
+func (p *printer) span(n *parser.Node) (int, int) {
+	if p.src == "" {
+		return 0, 0
+	}
+	start := p.offset(n)
+	return start, nodeEnd(p.src, start)
+}
+
+// hasComments reports whether any unwritten comment lies in [start, end)
+func (p *printer) hasComments(start, end int) bool {
+	for _, c := range p.comments[p.next:] {
+		if c.offset >= end {
+			return false
+		}
+		if c.offset >= start {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeEnd returns the offset just past the node starting at start. The
+// source is known to parse, so brackets and quotes are balanced.
+func nodeEnd(src string, start int) int {
+	switch src[start] {
+	case '(', '[':
+		depth := 0
+		for i := start; i < len(src); i++ {
+			switch src[i] {
+			case '(', '[':
+				depth++
+			case ')', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			case '"':
+				i = stringEnd(src, i) - 1
+			case ';':
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+			}
+		}
+		return len(src)
+	case '"':
+		return stringEnd(src, start)
+	case '@':
+		if i := strings.IndexByte(src[start:], '}'); i >= 0 {
+			return start + i + 1
+		}
+		return len(src)
+	default:
+		i := start
+		for i < len(src) && !strings.ContainsRune(" \t\r\n()[],;", rune(src[i])) {
+			i++
+		}
+		return i
+	}
+}
+
+// stringEnd returns the offset just past the string literal starting at start
+func stringEnd(src string, start int) int {
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(src)
+}
+
+// scanComments finds the comments in src, skipping ';' inside strings
+func scanComments(src string) []comment {
+	var comments []comment
+	lineStart, code := 0, false
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\n':
+			lineStart, code = i+1, false
+		case c == '"':
+			i = stringEnd(src, i) - 1
+			code = true
+		case c == ';':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			comments = append(comments, comment{
+				offset:      i,
+				text:        strings.TrimRight(src[i:i+end], " \t\r"),
+				trailing:    code,
+				blankBefore: !code && blankLineBefore(src, lineStart),
+			})
+			i += end - 1
+		case c != ' ' && c != '\t' && c != '\r':
+			code = true
+		}
+	}
+	return comments
+}
+
+// blankLineBefore reports whether the line containing offset is preceded by
+// an empty line that itself follows some earlier content
+func blankLineBefore(src string, offset int) bool {
+	i := strings.LastIndexByte(src[:offset], '\n')
+	if i < 0 {
+		return false
+	}
+	blank := false
+	for i > 0 {
+		prev := strings.LastIndexByte(src[:i], '\n')
+		if strings.TrimSpace(src[prev+1:i]) != "" {
+			return blank
+		}
+		if prev < 0 {
+			return false
+		}
+		blank, i = true, prev
+	}
+	return false
+}
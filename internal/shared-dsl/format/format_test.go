@@ -0,0 +1,135 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dsl-ob-poc/internal/shared-dsl/parser"
+)
+
+func TestFormatCanonicalLayout(t *testing.T) {
+	src := `(case.create (cbu.id   "CBU-1")
+)
+(products.add "CUSTODY"    "FUND_ACCOUNTING")
+
+
+(services.discover
+  (for.product "CUSTODY"
+    (service "CustodyService")
+    (service "SettlementService")
+  )
+)
+(investor.start-opportunity :legal-name "Acme Capital Partners" :domicile "LU" :type "CORPORATE" :source "referral")
+(ubo.collect-entity-data (entity_name "Société Générale S.à r.l.") (document_list ["passport" "proof_of_address", "bank_statement", "certificate_of_incorporation", "utility_bill"]))`
+
+	want := `(case.create (cbu.id "CBU-1"))
+
+(products.add "CUSTODY" "FUND_ACCOUNTING")
+
+(services.discover (for.product "CUSTODY" (service "CustodyService") (service "SettlementService")))
+
+(investor.start-opportunity
+  :legal-name "Acme Capital Partners"
+  :domicile "LU"
+  :type "CORPORATE"
+  :source "referral")
+
+(ubo.collect-entity-data
+  (entity_name "Société Générale S.à r.l.")
+  (document_list [
+    "passport",
+    "proof_of_address",
+    "bank_statement",
+    "certificate_of_incorporation",
+    "utility_bill"
+  ]))
+`
+	got, err := Format(src)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected layout:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	src := `;; Onboarding case for CBU-1
+
+;; Step 1
+(case.create (cbu.id "CBU-1")) ; created from the CRM
+(kyc.start
+  ;; documents first
+  (documents (document "W8BEN-E"))
+
+  (jurisdictions (jurisdiction "LU")) ; EU only
+)
+; end`
+
+	want := `;; Onboarding case for CBU-1
+
+;; Step 1
+(case.create (cbu.id "CBU-1")) ; created from the CRM
+
+(kyc.start
+  ;; documents first
+  (documents (document "W8BEN-E"))
+
+  (jurisdictions (jurisdiction "LU")) ; EU only
+)
+; end
+`
+	got, err := Format(src)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected layout:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatRejectsInvalidDSL(t *testing.T) {
+	if _, err := Format(`(case.create (cbu.id "CBU-1")`); err == nil {
+		t.Error("expected a parse error for an unclosed form")
+	}
+}
+
+// TestFormatExamples checks that formatting the in-repo examples keeps
+// their meaning and is idempotent
+func TestFormatExamples(t *testing.T) {
+	files, err := filepath.Glob("../../../examples/*/*.dsl")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no example DSL found: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		formatted, err := Format(string(data))
+		if err != nil {
+			t.Errorf("%s: Format failed: %v", file, err)
+			continue
+		}
+		if again, _ := Format(formatted); again != formatted {
+			t.Errorf("%s: formatting is not idempotent", file)
+		}
+		if before, after := canonicalForms(t, string(data)), canonicalForms(t, formatted); before != after {
+			t.Errorf("%s: formatting changed the document", file)
+		}
+	}
+}
+
+func canonicalForms(t *testing.T, src string) string {
+	t.Helper()
+	ast, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	out := ""
+	for _, form := range ast.Root.Children {
+		out += Canonical(form) + "\n"
+	}
+	return out
+}
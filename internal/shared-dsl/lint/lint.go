@@ -0,0 +1,225 @@
+// Package lint checks DSL documents for problems the parser accepts but a
+// reviewer would reject: verbs outside the vocabulary, deprecated verbs,
+// template placeholders that were never filled in, and attribute values
+// bound without being referenced anywhere in the document.
+//
+// Issues carry a rule name, a severity and a source position, and marshal
+// to JSON so that CI can consume them directly.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"dsl-ob-poc/internal/shared-dsl/parser"
+)
+
+// Severity classifies an issue. Errors fail a lint run, warnings do not.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule names reported in Issue.Rule
+const (
+	RuleSyntax                = "syntax"
+	RuleUnknownVerb           = "unknown-verb"
+	RuleDeprecatedVerb        = "deprecated-verb"
+	RuleUnresolvedPlaceholder = "unresolved-placeholder"
+	RuleUnusedBinding         = "unused-binding"
+)
+
+// Issue is a single lint finding
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+}
+
+// String formats the issue as line:column: severity: message [rule]
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s [%s]", i.Line, i.Column, i.Severity, i.Message, i.Rule)
+}
+
+// Vocabulary reports whether a verb is approved; dsl.Vocabulary satisfies it
+type Vocabulary interface {
+	IsValidVerb(verb string) bool
+}
+
+// Config selects the reference data the rules check against
+type Config struct {
+	// Vocabulary of approved verbs; nil disables the unknown-verb rule
+	Vocabulary Vocabulary
+	// Deprecated maps deprecated verbs to their replacement, or to "" when
+	// there is none
+	Deprecated map[string]string
+}
+
+// placeholderPattern matches template placeholders left in generated DSL:
+// {{.Field}}, {name} and <name>
+var placeholderPattern = regexp.MustCompile(`\{\{[^}]*\}\}|\{[A-Za-z_][\w.-]*\}|<[A-Za-z_]\w*>`)
+
+// templateForms hold format strings whose placeholders are intentional, as
+// in (format "CUST-{client-id}-{sequence}")
+var templateForms = map[string]bool{
+	"format":   true,
+	"pattern":  true,
+	"template": true,
+}
+
+// Lint checks src and returns its issues ordered by position. A document
+// that does not parse yields a single syntax issue.
+func Lint(src string, cfg Config) []Issue {
+	ast, err := parser.Parse(src)
+	if err != nil {
+		issue := Issue{Rule: RuleSyntax, Severity: SeverityError, Message: err.Error()}
+		var msg string
+		if _, scanErr := fmt.Sscanf(err.Error(), "parse error at line %d, column %d: %s", &issue.Line, &issue.Column, &msg); scanErr != nil {
+			issue.Line, issue.Column = 1, 1
+		}
+		return []Issue{issue}
+	}
+
+	var issues []Issue
+	for _, form := range ast.Root.Children {
+		issues = append(issues, checkVerb(form, cfg)...)
+	}
+	issues = append(issues, checkPlaceholders(ast.Root, false)...)
+	issues = append(issues, checkBindings(ast.Root)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// HasErrors reports whether any issue is an error
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVerb checks a top-level form's verb against the deprecation list and
+// the vocabulary; a deprecated verb is not also reported as unknown
+func checkVerb(form *parser.Node, cfg Config) []Issue {
+	if replacement, ok := cfg.Deprecated[form.Value]; ok {
+		msg := fmt.Sprintf("verb %q is deprecated", form.Value)
+		if replacement != "" {
+			msg += fmt.Sprintf(", use %q instead", replacement)
+		}
+		return []Issue{{Rule: RuleDeprecatedVerb, Severity: SeverityWarning, Message: msg, Line: form.Line, Column: form.Column}}
+	}
+	if cfg.Vocabulary != nil && !cfg.Vocabulary.IsValidVerb(form.Value) {
+		return []Issue{{
+			Rule:     RuleUnknownVerb,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("verb %q is not in the vocabulary", form.Value),
+			Line:     form.Line,
+			Column:   form.Column,
+		}}
+	}
+	return nil
+}
+
+// checkPlaceholders reports placeholders in string and identifier values
+// outside template forms
+func checkPlaceholders(n *parser.Node, inTemplate bool) []Issue {
+	var issues []Issue
+	switch n.Type {
+	case parser.StringNode, parser.IdentifierNode:
+		if inTemplate {
+			return nil
+		}
+		for _, m := range placeholderPattern.FindAllString(n.Value, -1) {
+			issues = append(issues, Issue{
+				Rule:     RuleUnresolvedPlaceholder,
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("unresolved placeholder %s", m),
+				Line:     n.Line,
+				Column:   n.Column,
+			})
+		}
+		return issues
+	case parser.ExpressionNode:
+		inTemplate = inTemplate || templateForms[n.Value]
+	}
+	for _, c := range n.Children {
+		issues = append(issues, checkPlaceholders(c, inTemplate)...)
+	}
+	return issues
+}
+
+// checkBindings reports (bind (attr-id X) ...) forms whose attribute is not
+// referenced elsewhere in the document, either as @attr{X} or as
+// (var (attr-id X))
+func checkBindings(root *parser.Node) []Issue {
+	referenced := make(map[string]bool)
+	var bindings []*parser.Node
+	var walk func(n *parser.Node)
+	walk = func(n *parser.Node) {
+		if n.Type == parser.ExpressionNode && n.Value == "bind" && boundAttribute(n) != nil {
+			bindings = append(bindings, n)
+			// The bound attribute itself is not a reference
+			for _, c := range n.Children[1:] {
+				if c.Type != parser.ExpressionNode || c.Value != "attr-id" {
+					walk(c)
+				}
+			}
+			return
+		}
+		if n.Type == parser.AttributeNode {
+			referenced[n.AttributeID] = true
+		}
+		if n.Type == parser.ExpressionNode && n.Value == "attr-id" && len(n.Children) > 1 && n.Children[1].Type == parser.StringNode {
+			referenced[n.Children[1].Value] = true
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var issues []Issue
+	for _, b := range bindings {
+		attr := boundAttribute(b)
+		id := attr.Value
+		if attr.Type == parser.AttributeNode {
+			id = attr.AttributeID
+		}
+		if !referenced[id] {
+			issues = append(issues, Issue{
+				Rule:     RuleUnusedBinding,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("attribute %s is bound but never referenced", id),
+				Line:     b.Line,
+				Column:   b.Column,
+			})
+		}
+	}
+	return issues
+}
+
+// boundAttribute returns the X of (bind (attr-id X) ...), or nil
+func boundAttribute(bind *parser.Node) *parser.Node {
+	for _, c := range bind.Children[1:] {
+		if c.Type != parser.ExpressionNode || c.Value != "attr-id" || len(c.Children) < 2 {
+			continue
+		}
+		if v := c.Children[1]; v.Type == parser.StringNode || v.Type == parser.AttributeNode {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package lint
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// verbSet is a Vocabulary backed by a set of verbs
+type verbSet map[string]bool
+
+func (v verbSet) IsValidVerb(verb string) bool { return v[verb] }
+
+func TestLintRules(t *testing.T) {
+	src := `(case.create (cbu.id "{{.CBUID}}") (nature-purpose "Fund for <client_name>"))
+(kyc.start (documents (document "W8BEN-E")))
+(case.open (cbu.id "CBU-1"))
+(resources.plan
+  (resource.create "Account"
+    (var (attr-id "uuid-1") (format "ACC-{sequence}"))
+    (owner @attr{uuid-2:owner})))
+(values.bind
+  (bind (attr-id "uuid-1") (value "ACC-1"))
+  (bind (attr-id @attr{uuid-2}) (value "Ops"))
+  (bind (attr-id "uuid-3") (value "{region}")))`
+
+	cfg := Config{
+		Vocabulary: verbSet{"case.create": true, "resources.plan": true, "values.bind": true},
+		Deprecated: map[string]string{"case.open": "case.create"},
+	}
+	issues := Lint(src, cfg)
+
+	want := []string{
+		"1:22: error: unresolved placeholder {{.CBUID}} [unresolved-placeholder]",
+		"1:52: error: unresolved placeholder <client_name> [unresolved-placeholder]",
+		`2:1: error: verb "kyc.start" is not in the vocabulary [unknown-verb]`,
+		`3:1: warning: verb "case.open" is deprecated, use "case.create" instead [deprecated-verb]`,
+		"11:3: warning: attribute uuid-3 is bound but never referenced [unused-binding]",
+		"11:35: error: unresolved placeholder {region} [unresolved-placeholder]",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues, got %d: %v", len(want), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != want[i] {
+			t.Errorf("issue %d: got %q, want %q", i, issue.String(), want[i])
+		}
+	}
+	if !HasErrors(issues) {
+		t.Error("expected HasErrors to be true")
+	}
+}
+
+func TestLintSyntaxError(t *testing.T) {
+	issues := Lint("(case.create\n  (cbu.id \"CBU-1\")", Config{})
+	if len(issues) != 1 || issues[0].Rule != RuleSyntax {
+		t.Fatalf("expected a single syntax issue, got %v", issues)
+	}
+	if issues[0].Line != 2 || issues[0].Column != 19 {
+		t.Errorf("expected the syntax issue at 2:19, got %d:%d", issues[0].Line, issues[0].Column)
+	}
+}
+
+func TestLintCleanDocument(t *testing.T) {
+	issues := Lint(`(case.create (cbu.id "CBU-1"))`, Config{})
+	if len(issues) != 0 || HasErrors(issues) {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestIssueJSON(t *testing.T) {
+	out, err := json.Marshal(Issue{Rule: RuleUnknownVerb, Severity: SeverityError, Message: "m", Line: 1, Column: 2})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got := string(out); !strings.Contains(got, `"rule":"unknown-verb"`) || !strings.Contains(got, `"severity":"error"`) || !strings.Contains(got, `"line":1`) {
+		t.Errorf("unexpected JSON: %s", got)
+	}
+}
@@ -34,6 +34,10 @@ const (
 	BooleanNode
 	// AttributeNode is an attribute reference: @attr{uuid:name} or @attr{uuid}
 	AttributeNode
+	// KeywordNode is a keyword argument name: :legal-name, :investor
+	KeywordNode
+	// ListNode is a list literal: ["a", "b"]
+	ListNode
 )
 
 // String returns the string representation of a NodeType
@@ -55,6 +59,10 @@ func (nt NodeType) String() string {
 		return "Boolean"
 	case AttributeNode:
 		return "Attribute"
+	case KeywordNode:
+		return "Keyword"
+	case ListNode:
+		return "List"
 	default:
 		return "Unknown"
 	}
@@ -212,6 +220,8 @@ func (p *Parser) parseVerb() (*Node, error) {
 // - A number: 123, 45.67
 // - A boolean: true, false
 // - An identifier: attr-id, cbu.id, etc.
+// - A keyword: :legal-name (the following argument is its value)
+// - A list literal: ["a", "b"]
 func (p *Parser) parseArgument() (*Node, error) {
 	p.skipWhitespaceAndComments()
 
@@ -222,6 +232,11 @@ func (p *Parser) parseArgument() (*Node, error) {
 		return p.parseExpression()
 	}
 
+	// List literal
+	if p.match('[') {
+		return p.parseList()
+	}
+
 	// String literal
 	if p.match('"') {
 		return p.parseString()
@@ -232,6 +247,21 @@ func (p *Parser) parseArgument() (*Node, error) {
 		return p.parseAttribute()
 	}
 
+	// Keyword: :name
+	if p.match(':') {
+		p.advance() // consume ':'
+		name := p.readIdentifier()
+		if name == "" {
+			return nil, p.error("expected keyword name after ':'")
+		}
+		return &Node{
+			Type:   KeywordNode,
+			Value:  ":" + name,
+			Line:   line,
+			Column: column,
+		}, nil
+	}
+
 	// Number or identifier or boolean
 	if p.isDigit(p.peek()) || p.peek() == '-' {
 		// Could be a number or negative number
@@ -263,6 +293,42 @@ func (p *Parser) parseArgument() (*Node, error) {
 	}, nil
 }
 
+// parseList parses a list literal: [elem elem ...], with optional commas
+// after each element
+func (p *Parser) parseList() (*Node, error) {
+	node := &Node{
+		Type:     ListNode,
+		Children: make([]*Node, 0),
+		Line:     p.line,
+		Column:   p.column,
+	}
+	p.advance() // consume '['
+
+	for {
+		p.skipWhitespaceAndComments()
+
+		if p.match(']') {
+			p.advance() // consume ']'
+			return node, nil
+		}
+
+		if p.isEOF() {
+			return nil, p.error("unexpected EOF, expected ']' to close list")
+		}
+
+		elem, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, elem)
+
+		p.skipWhitespaceAndComments()
+		if p.match(',') {
+			p.advance() // consume ','
+		}
+	}
+}
+
 // parseString parses a quoted string literal
 func (p *Parser) parseString() (*Node, error) {
 	line, column := p.line, p.column
@@ -288,11 +354,11 @@ func (p *Parser) parseString() (*Node, error) {
 			case '\\':
 				sb.WriteRune('\\')
 			default:
-				sb.WriteRune(p.peek())
+				sb.WriteByte(p.input[p.pos])
 			}
 			p.advance()
 		} else {
-			sb.WriteRune(p.peek())
+			sb.WriteByte(p.input[p.pos])
 			p.advance()
 		}
 	}
@@ -337,7 +403,7 @@ func (p *Parser) parseNumberOrIdentifier() (*Node, error) {
 	value := p.input[start:p.pos]
 
 	// If we found digits and next char is whitespace or delimiter, it's a number
-	if hasDigits && (p.isEOF() || unicode.IsSpace(p.peek()) || p.match(')')) {
+	if hasDigits && (p.isEOF() || unicode.IsSpace(p.peek()) || p.match(')') || p.match(']') || p.match(',')) {
 		return &Node{
 			Type:   NumberNode,
 			Value:  value,
@@ -387,7 +453,7 @@ func (p *Parser) parseAttribute() (*Node, error) {
 	// Read until '}' or ':'
 	var attrID strings.Builder
 	for !p.isEOF() && !p.match('}') && !p.match(':') {
-		attrID.WriteRune(p.peek())
+		attrID.WriteByte(p.input[p.pos])
 		p.advance()
 	}
 
@@ -409,7 +475,7 @@ func (p *Parser) parseAttribute() (*Node, error) {
 
 		var name strings.Builder
 		for !p.isEOF() && !p.match('}') {
-			name.WriteRune(p.peek())
+			name.WriteByte(p.input[p.pos])
 			p.advance()
 		}
 
@@ -530,6 +530,76 @@ func TestParse_HedgeFundInvestorStart(t *testing.T) {
 	}
 }
 
+func TestParse_KeywordArguments(t *testing.T) {
+	dsl := `(investor.start-opportunity
+  :legal-name "Acme Capital Partners LP"
+  :ticket 5000000.00
+  :lei @attr{INV.LEI})`
+
+	ast, err := Parse(dsl)
+	if err != nil {
+		t.Fatalf("Parse failed for keyword DSL: %v", err)
+	}
+
+	expr := ast.Root.Children[0]
+	if len(expr.Children) != 7 {
+		t.Fatalf("Expected 7 children (verb + 3 keyword/value pairs), got %d", len(expr.Children))
+	}
+
+	keyword := expr.Children[1]
+	if keyword.Type != KeywordNode || keyword.Value != ":legal-name" {
+		t.Errorf("Expected keyword ':legal-name', got %s %q", keyword.Type, keyword.Value)
+	}
+	if keyword.Line != 2 || keyword.Column != 3 {
+		t.Errorf("Expected keyword at 2:3, got %d:%d", keyword.Line, keyword.Column)
+	}
+	if expr.Children[4].Type != NumberNode {
+		t.Errorf("Expected number value after :ticket, got %s", expr.Children[4].Type)
+	}
+
+	if _, err := Parse(`(kyc.begin : "x")`); err == nil {
+		t.Error("Expected error for keyword without a name")
+	}
+}
+
+func TestParse_ListLiterals(t *testing.T) {
+	dsl := `(ubo.calculate-indirect-ownership
+  (ownership-chains [["Holdco", 60.0], ["Topco" 25]])
+  (tags []))`
+
+	ast, err := Parse(dsl)
+	if err != nil {
+		t.Fatalf("Parse failed for list DSL: %v", err)
+	}
+
+	chains := ast.Root.Children[0].Children[1].Children[1]
+	if chains.Type != ListNode || len(chains.Children) != 2 {
+		t.Fatalf("Expected list of 2 elements, got %s with %d", chains.Type, len(chains.Children))
+	}
+	if chains.Line != 2 || chains.Column != 21 {
+		t.Errorf("Expected list at 2:21, got %d:%d", chains.Line, chains.Column)
+	}
+	first := chains.Children[0]
+	if first.Type != ListNode || len(first.Children) != 2 {
+		t.Fatalf("Expected nested list of 2 elements, got %s with %d", first.Type, len(first.Children))
+	}
+	if first.Children[0].Value != "Holdco" || first.Children[1].Type != NumberNode || first.Children[1].Value != "60.0" {
+		t.Errorf("Unexpected nested list elements: %s %q, %s %q",
+			first.Children[0].Type, first.Children[0].Value, first.Children[1].Type, first.Children[1].Value)
+	}
+	if chains.Children[1].Children[1].Value != "25" {
+		t.Errorf("Expected number 25 before ']', got %q", chains.Children[1].Children[1].Value)
+	}
+	if tags := ast.Root.Children[0].Children[2].Children[1]; tags.Type != ListNode || len(tags.Children) != 0 {
+		t.Errorf("Expected empty list, got %s with %d", tags.Type, len(tags.Children))
+	}
+
+	_, err = Parse(`(entity.tag (tags ["a" "b")`)
+	if err == nil || !strings.Contains(err.Error(), "expected argument value") {
+		t.Errorf("Expected error for unclosed list, got %v", err)
+	}
+}
+
 func TestParse_HedgeFundKYCBegin(t *testing.T) {
 	dsl := `(kyc.begin
   (investor "uuid-investor-123")
@@ -853,6 +923,21 @@ func TestParse_StringWithEscapes(t *testing.T) {
 	}
 }
 
+func TestParse_NonASCIIText(t *testing.T) {
+	ast, err := Parse(`(entity.register (name "Société Générale S.à r.l.") (id @attr{a1:Raison sociale é}))`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expr := ast.Root.Children[0]
+	if got := expr.Children[1].Children[1].Value; got != "Société Générale S.à r.l." {
+		t.Errorf("Expected UTF-8 string to be kept, got %q", got)
+	}
+	if got := expr.Children[2].Children[1].Name; got != "Raison sociale é" {
+		t.Errorf("Expected UTF-8 attribute name to be kept, got %q", got)
+	}
+}
+
 func TestParse_NumberTypes(t *testing.T) {
 	tests := []struct {
 		name     string
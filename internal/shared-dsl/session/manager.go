@@ -2,9 +2,14 @@
 //
 // This package is shared across ALL domains (onboarding, hedge-fund-investor, kyc, etc.)
 // and manages stateful DSL accumulation, context tracking, and session lifecycle.
+// Sessions live in memory unless the manager is given a Store (see
+// NewPersistentManager), in which case they survive restarts and can be resumed
+// by another instance.
 package session
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,6 +20,7 @@ import (
 // Manager manages multiple chat sessions with DSL accumulation
 type Manager struct {
 	sessions map[string]*Session
+	store    Store // nil keeps sessions in memory only
 	mu       sync.RWMutex
 }
 
@@ -27,40 +33,41 @@ type Session struct {
 	History   []Message
 	CreatedAt time.Time
 	LastUsed  time.Time
+	Version   int // stored version this copy was loaded at or saved as; 0 if never saved
 	mu        sync.RWMutex
 }
 
 // Context holds session context for entity references and state
 type Context struct {
 	// Common entity IDs (used across domains)
-	InvestorID string
-	FundID     string
-	ClassID    string
-	SeriesID   string
-	CBUID      string
+	InvestorID string `json:"investor_id,omitempty"`
+	FundID     string `json:"fund_id,omitempty"`
+	ClassID    string `json:"class_id,omitempty"`
+	SeriesID   string `json:"series_id,omitempty"`
+	CBUID      string `json:"cbu_id,omitempty"`
 
 	// Entity attributes
-	InvestorName string
-	InvestorType string
-	Domicile     string
-	LegalName    string
+	InvestorName string `json:"investor_name,omitempty"`
+	InvestorType string `json:"investor_type,omitempty"`
+	Domicile     string `json:"domicile,omitempty"`
+	LegalName    string `json:"legal_name,omitempty"`
 
 	// State machine
-	CurrentState string
+	CurrentState string `json:"current_state,omitempty"`
 
 	// Domain-specific data (flexible storage)
-	Data map[string]interface{}
+	Data map[string]interface{} `json:"data"`
 
 	mu sync.RWMutex
 }
 
 // Message represents a single message in the chat history
 type Message struct {
-	Role      string                 // "user" or "agent"
-	Content   string                 // Message text
-	DSL       string                 // Generated DSL (if any)
-	Timestamp time.Time              // When message was sent
-	Metadata  map[string]interface{} // Additional metadata
+	Role      string                 `json:"role"`               // "user" or "agent"
+	Content   string                 `json:"content"`            // Message text
+	DSL       string                 `json:"dsl,omitempty"`      // Generated DSL (if any)
+	Timestamp time.Time              `json:"timestamp"`          // When message was sent
+	Metadata  map[string]interface{} `json:"metadata,omitempty"` // Additional metadata
 }
 
 // NewManager creates a new session manager
@@ -70,6 +77,19 @@ func NewManager() *Manager {
 	}
 }
 
+// NewPersistentManager creates a session manager backed by store. Sessions
+// are loaded from the store on first use and cached; AccumulateDSL,
+// UpdateContext and SwitchDomain save them straight away, and changes made
+// directly on a Session are saved with Save. Every save is checked against
+// the stored version, so an instance working from a stale copy gets
+// ErrConflict instead of overwriting another instance's changes.
+func NewPersistentManager(store Store) *Manager {
+	return &Manager{
+		sessions: make(map[string]*Session),
+		store:    store,
+	}
+}
+
 // NewSession creates a new session with the given domain
 func NewSession(sessionID, domain string) *Session {
 	if sessionID == "" {
@@ -103,6 +123,21 @@ func (m *Manager) GetOrCreate(sessionID, domain string) *Session {
 			}
 			return session
 		}
+
+		// Resume a session saved by an earlier run or another instance. If
+		// it cannot be loaded a new one is started; saving that one fails
+		// with ErrConflict rather than overwriting the stored session.
+		if m.store != nil {
+			if rec, err := m.store.Load(context.Background(), sessionID); err == nil {
+				session := fromRecord(rec)
+				session.LastUsed = time.Now()
+				if domain != "" {
+					session.Domain = domain
+				}
+				m.sessions[sessionID] = session
+				return session
+			}
+		}
 	}
 
 	// Create new session
@@ -111,14 +146,28 @@ func (m *Manager) GetOrCreate(sessionID, domain string) *Session {
 	return session
 }
 
-// Get retrieves an existing session
+// Get retrieves an existing session, loading it from the store if it is
+// not cached
 func (m *Manager) Get(sessionID string) (*Session, error) {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	session, exists := m.sessions[sessionID]
+	m.mu.RUnlock()
+
 	if !exists {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
+		if m.store == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+		}
+		rec, err := m.store.Load(context.Background(), sessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		m.mu.Lock()
+		if session, exists = m.sessions[sessionID]; !exists {
+			session = fromRecord(rec)
+			m.sessions[sessionID] = session
+		}
+		m.mu.Unlock()
 	}
 
 	session.LastUsed = time.Now()
@@ -130,28 +179,62 @@ func (m *Manager) Delete(sessionID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.sessions[sessionID]; !exists {
-		return fmt.Errorf("session not found: %s", sessionID)
-	}
-
+	_, cached := m.sessions[sessionID]
 	delete(m.sessions, sessionID)
+
+	if m.store != nil {
+		err := m.store.Delete(context.Background(), sessionID)
+		if err == nil || (cached && errors.Is(err, ErrNotFound)) {
+			return nil
+		}
+		return err
+	}
+	if !cached {
+		return fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+	}
 	return nil
 }
 
-// List returns all session IDs
+// List returns all session IDs, including stored sessions that are not
+// cached. Use ListStored to see store errors.
 func (m *Manager) List() []string {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	ids, _ := m.ListStored(context.Background())
+	return ids
+}
 
+// ListStored returns all session IDs, including stored sessions that are
+// not cached
+func (m *Manager) ListStored(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
 	ids := make([]string, 0, len(m.sessions))
+	seen := make(map[string]bool, len(m.sessions))
 	for id := range m.sessions {
 		ids = append(ids, id)
+		seen[id] = true
 	}
-	return ids
+	m.mu.RUnlock()
+
+	if m.store == nil {
+		return ids, nil
+	}
+	stored, err := m.store.List(ctx)
+	if err != nil {
+		return ids, err
+	}
+	for _, id := range stored {
+		if !seen[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
 }
 
 // Count returns the number of active sessions
 func (m *Manager) Count() int {
+	if m.store != nil {
+		return len(m.List())
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return len(m.sessions)
@@ -164,8 +247,16 @@ func (m *Manager) AccumulateDSL(sessionID, newDSL string) error {
 	if err != nil {
 		return err
 	}
+	if m.store == nil {
+		return session.AccumulateDSL(newDSL)
+	}
+	if newDSL == "" {
+		return nil
+	}
 
-	return session.AccumulateDSL(newDSL)
+	return m.save(context.Background(), session, func(rec *Record) {
+		rec.BuiltDSL = appendDSL(rec.BuiltDSL, newDSL)
+	})
 }
 
 // UpdateContext updates the session context with new values
@@ -174,8 +265,13 @@ func (m *Manager) UpdateContext(sessionID string, updates map[string]interface{}
 	if err != nil {
 		return err
 	}
+	if m.store == nil {
+		return session.UpdateContext(updates)
+	}
 
-	return session.UpdateContext(updates)
+	return m.save(context.Background(), session, func(rec *Record) {
+		rec.Context.apply(updates)
+	})
 }
 
 // SwitchDomain switches the active domain for a session
@@ -184,6 +280,11 @@ func (m *Manager) SwitchDomain(sessionID, newDomain string) error {
 	if err != nil {
 		return err
 	}
+	if m.store != nil {
+		return m.save(context.Background(), session, func(rec *Record) {
+			rec.Domain = newDomain
+		})
+	}
 
 	session.mu.Lock()
 	defer session.mu.Unlock()
@@ -193,22 +294,71 @@ func (m *Manager) SwitchDomain(sessionID, newDomain string) error {
 	return nil
 }
 
-// CleanupExpired removes sessions older than the given duration
+// Save persists changes made directly on a cached session, such as
+// AddMessage. It is a no-op for a manager without a store.
+func (m *Manager) Save(ctx context.Context, sessionID string) error {
+	if m.store == nil {
+		return nil
+	}
+	session, err := m.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	return m.save(ctx, session, nil)
+}
+
+// save applies change to a copy of session and stores it. The session is
+// only updated once the store accepts the new version; on ErrConflict it is
+// refreshed from the store instead, so the caller can retry against the
+// latest state.
+func (m *Manager) save(ctx context.Context, session *Session, change func(rec *Record)) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	rec := session.record()
+	if change != nil {
+		change(rec)
+	}
+	rec.LastUsed = time.Now()
+
+	if err := m.store.Save(ctx, rec); err != nil {
+		if errors.Is(err, ErrConflict) {
+			if latest, loadErr := m.store.Load(ctx, session.SessionID); loadErr == nil {
+				session.restore(latest)
+			}
+		}
+		return err
+	}
+	session.restore(rec)
+	return nil
+}
+
+// CleanupExpired removes sessions older than the given duration. Use
+// Cleanup to see store errors.
 func (m *Manager) CleanupExpired(maxAge time.Duration) int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	removed, _ := m.Cleanup(context.Background(), maxAge)
+	return removed
+}
 
+// Cleanup removes sessions unused for longer than maxAge. With a store,
+// stored sessions are aged by their last save and the count is of stored
+// sessions removed; expired sessions are also dropped from the cache.
+func (m *Manager) Cleanup(ctx context.Context, maxAge time.Duration) (int, error) {
+	m.mu.Lock()
 	now := time.Now()
 	removed := 0
-
 	for id, session := range m.sessions {
 		if now.Sub(session.LastUsed) > maxAge {
 			delete(m.sessions, id)
 			removed++
 		}
 	}
+	m.mu.Unlock()
 
-	return removed
+	if m.store == nil {
+		return removed, nil
+	}
+	return m.store.DeleteExpired(ctx, now.Add(-maxAge))
 }
 
 // Session Methods
@@ -222,16 +372,19 @@ func (s *Session) AccumulateDSL(newDSL string) error {
 		return nil // Nothing to append
 	}
 
-	if s.BuiltDSL == "" {
-		s.BuiltDSL = newDSL
-	} else {
-		s.BuiltDSL = s.BuiltDSL + "\n\n" + newDSL
-	}
-
+	s.BuiltDSL = appendDSL(s.BuiltDSL, newDSL)
 	s.LastUsed = time.Now()
 	return nil
 }
 
+// appendDSL separates accumulated DSL fragments with a blank line
+func appendDSL(builtDSL, newDSL string) string {
+	if builtDSL == "" {
+		return newDSL
+	}
+	return builtDSL + "\n\n" + newDSL
+}
+
 // GetDSL returns the current accumulated DSL (read-only)
 func (s *Session) GetDSL() string {
 	s.mu.RLock()
@@ -244,56 +397,61 @@ func (s *Session) UpdateContext(updates map[string]interface{}) error {
 	s.Context.mu.Lock()
 	defer s.Context.mu.Unlock()
 
+	s.Context.apply(updates)
+	s.LastUsed = time.Now()
+	return nil
+}
+
+// apply sets the named context fields; unknown keys go to Data. The caller
+// holds c.mu or owns c.
+func (c *Context) apply(updates map[string]interface{}) {
 	for key, value := range updates {
 		switch key {
 		case "investor_id":
 			if v, ok := value.(string); ok {
-				s.Context.InvestorID = v
+				c.InvestorID = v
 			}
 		case "fund_id":
 			if v, ok := value.(string); ok {
-				s.Context.FundID = v
+				c.FundID = v
 			}
 		case "class_id":
 			if v, ok := value.(string); ok {
-				s.Context.ClassID = v
+				c.ClassID = v
 			}
 		case "series_id":
 			if v, ok := value.(string); ok {
-				s.Context.SeriesID = v
+				c.SeriesID = v
 			}
 		case "cbu_id":
 			if v, ok := value.(string); ok {
-				s.Context.CBUID = v
+				c.CBUID = v
 			}
 		case "investor_name":
 			if v, ok := value.(string); ok {
-				s.Context.InvestorName = v
+				c.InvestorName = v
 			}
 		case "investor_type":
 			if v, ok := value.(string); ok {
-				s.Context.InvestorType = v
+				c.InvestorType = v
 			}
 		case "domicile":
 			if v, ok := value.(string); ok {
-				s.Context.Domicile = v
+				c.Domicile = v
 			}
 		case "legal_name":
 			if v, ok := value.(string); ok {
-				s.Context.LegalName = v
+				c.LegalName = v
 			}
 		case "current_state":
 			if v, ok := value.(string); ok {
-				s.Context.CurrentState = v
+				c.CurrentState = v
 			}
 		default:
 			// Store in flexible data map for domain-specific values
-			s.Context.Data[key] = value
+			c.Data[key] = value
 		}
 	}
-
-	s.LastUsed = time.Now()
-	return nil
 }
 
 // GetContext returns a copy of the current context (read-only)
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PostgresStore keeps sessions in the dsl_sessions table, so any instance
+// connected to the same database can resume them. The version column makes
+// concurrent saves from several instances safe.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a store on an open database connection
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Load returns the stored session, or ErrNotFound
+func (p *PostgresStore) Load(ctx context.Context, sessionID string) (*Record, error) {
+	var rec Record
+	var contextJSON, historyJSON []byte
+	err := p.db.QueryRowContext(ctx, `
+		SELECT session_id, domain, built_dsl, context, history, version, created_at, last_used
+		FROM "dsl-ob-poc".dsl_sessions
+		WHERE session_id = $1`,
+		sessionID).Scan(&rec.SessionID, &rec.Domain, &rec.BuiltDSL, &contextJSON, &historyJSON,
+		&rec.Version, &rec.CreatedAt, &rec.LastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	if err := json.Unmarshal(contextJSON, &rec.Context); err != nil {
+		return nil, fmt.Errorf("failed to decode session context: %w", err)
+	}
+	if err := json.Unmarshal(historyJSON, &rec.History); err != nil {
+		return nil, fmt.Errorf("failed to decode session history: %w", err)
+	}
+	return &rec, nil
+}
+
+// Save writes rec if the stored version still equals rec.Version
+func (p *PostgresStore) Save(ctx context.Context, rec *Record) error {
+	contextJSON, err := json.Marshal(&rec.Context)
+	if err != nil {
+		return fmt.Errorf("failed to encode session context: %w", err)
+	}
+	historyJSON, err := json.Marshal(rec.History)
+	if err != nil {
+		return fmt.Errorf("failed to encode session history: %w", err)
+	}
+
+	var result sql.Result
+	if rec.Version == 0 {
+		result, err = p.db.ExecContext(ctx, `
+			INSERT INTO "dsl-ob-poc".dsl_sessions
+				(session_id, domain, built_dsl, context, history, version, created_at, last_used)
+			VALUES ($1, $2, $3, $4, $5, 1, $6, $7)
+			ON CONFLICT (session_id) DO NOTHING`,
+			rec.SessionID, rec.Domain, rec.BuiltDSL, contextJSON, historyJSON, rec.CreatedAt, rec.LastUsed)
+	} else {
+		result, err = p.db.ExecContext(ctx, `
+			UPDATE "dsl-ob-poc".dsl_sessions
+			SET domain = $2, built_dsl = $3, context = $4, history = $5,
+				version = version + 1, last_used = $6
+			WHERE session_id = $1 AND version = $7`,
+			rec.SessionID, rec.Domain, rec.BuiltDSL, contextJSON, historyJSON, rec.LastUsed, rec.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s is no longer at version %d", ErrConflict, rec.SessionID, rec.Version)
+	}
+	rec.Version++
+	return nil
+}
+
+// Delete removes a session, or returns ErrNotFound
+func (p *PostgresStore) Delete(ctx context.Context, sessionID string) error {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM "dsl-ob-poc".dsl_sessions WHERE session_id = $1`,
+		sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+	}
+	return nil
+}
+
+// List returns the IDs of all stored sessions, most recently used first
+func (p *PostgresStore) List(ctx context.Context) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT session_id FROM "dsl-ob-poc".dsl_sessions ORDER BY last_used DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan session ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+	return ids, nil
+}
+
+// DeleteExpired removes sessions last saved before cutoff
+func (p *PostgresStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int, error) {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM "dsl-ob-poc".dsl_sessions WHERE last_used < $1`,
+		cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,270 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is matched by errors.Is when a session does not exist
+	ErrNotFound = errors.New("session not found")
+	// ErrConflict is returned when a session was saved by another manager
+	// since it was loaded; the caller's copy is refreshed from the store
+	ErrConflict = errors.New("session was modified concurrently")
+)
+
+// Store persists sessions so they survive restarts and can be resumed by
+// another instance
+type Store interface {
+	// Load returns the stored session, or ErrNotFound
+	Load(ctx context.Context, sessionID string) (*Record, error)
+	// Save writes rec if the stored version still equals rec.Version (0 for
+	// a session that was never saved) and increments rec.Version. It returns
+	// ErrConflict when another save got there first.
+	Save(ctx context.Context, rec *Record) error
+	// Delete removes a session, or returns ErrNotFound
+	Delete(ctx context.Context, sessionID string) error
+	// List returns the IDs of all stored sessions
+	List(ctx context.Context) ([]string, error)
+	// DeleteExpired removes sessions last saved before cutoff and returns
+	// how many were removed
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Record is the stored form of a Session
+type Record struct {
+	SessionID string    `json:"session_id"`
+	Domain    string    `json:"domain"`
+	BuiltDSL  string    `json:"built_dsl"`
+	Context   Context   `json:"context"`
+	History   []Message `json:"history"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// record snapshots the session for saving. The caller holds s.mu.
+func (s *Session) record() *Record {
+	history := make([]Message, len(s.History))
+	copy(history, s.History)
+	rec := &Record{
+		SessionID: s.SessionID,
+		Domain:    s.Domain,
+		BuiltDSL:  s.BuiltDSL,
+		History:   history,
+		Version:   s.Version,
+		CreatedAt: s.CreatedAt,
+		LastUsed:  s.LastUsed,
+	}
+	s.Context.mu.RLock()
+	rec.Context.restore(&s.Context)
+	s.Context.mu.RUnlock()
+	return rec
+}
+
+// restore replaces the session's state with rec. The caller holds s.mu.
+func (s *Session) restore(rec *Record) {
+	s.Domain = rec.Domain
+	s.BuiltDSL = rec.BuiltDSL
+	s.History = rec.History
+	s.Version = rec.Version
+	s.CreatedAt = rec.CreatedAt
+	s.LastUsed = rec.LastUsed
+
+	s.Context.mu.Lock()
+	s.Context.restore(&rec.Context)
+	s.Context.mu.Unlock()
+}
+
+// fromRecord builds a cached session from a stored one
+func fromRecord(rec *Record) *Session {
+	s := NewSession(rec.SessionID, rec.Domain)
+	s.restore(rec)
+	if s.History == nil {
+		s.History = make([]Message, 0)
+	}
+	return s
+}
+
+// restore copies src's fields into c; the caller holds both locks or owns
+// the values. Context holds a mutex, so it is never assigned whole.
+func (c *Context) restore(src *Context) {
+	c.InvestorID = src.InvestorID
+	c.FundID = src.FundID
+	c.ClassID = src.ClassID
+	c.SeriesID = src.SeriesID
+	c.CBUID = src.CBUID
+	c.InvestorName = src.InvestorName
+	c.InvestorType = src.InvestorType
+	c.Domicile = src.Domicile
+	c.LegalName = src.LegalName
+	c.CurrentState = src.CurrentState
+	c.Data = make(map[string]interface{}, len(src.Data))
+	for k, v := range src.Data {
+		c.Data[k] = v
+	}
+}
+
+// FileStore keeps each session as a JSON file in a directory. Version checks
+// are serialized within one process, so a directory should not be shared by
+// several running instances; use PostgresStore for that.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a file store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (f *FileStore) path(sessionID string) string {
+	return filepath.Join(f.dir, url.PathEscape(sessionID)+".json")
+}
+
+// Load returns the stored session, or ErrNotFound
+func (f *FileStore) Load(ctx context.Context, sessionID string) (*Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read(sessionID)
+}
+
+func (f *FileStore) read(sessionID string) (*Record, error) {
+	data, err := os.ReadFile(f.path(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", sessionID, err)
+	}
+	return &rec, nil
+}
+
+// Save writes rec if the stored version still equals rec.Version
+func (f *FileStore) Save(ctx context.Context, rec *Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored := 0
+	existing, err := f.read(rec.SessionID)
+	switch {
+	case err == nil:
+		stored = existing.Version
+	case !errors.Is(err, ErrNotFound):
+		return err
+	}
+	if stored != rec.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, rec.SessionID, stored, rec.Version)
+	}
+
+	rec.Version++
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		rec.Version--
+		return fmt.Errorf("failed to encode session %s: %w", rec.SessionID, err)
+	}
+
+	// Write to a temporary file and rename it so a crash never leaves a
+	// truncated session behind
+	tmp, err := os.CreateTemp(f.dir, ".session-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), f.path(rec.SessionID))
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		rec.Version--
+		return fmt.Errorf("failed to write session %s: %w", rec.SessionID, err)
+	}
+	return nil
+}
+
+// Delete removes a session, or returns ErrNotFound
+func (f *FileStore) Delete(ctx context.Context, sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.path(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, sessionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// List returns the IDs of all stored sessions, sorted
+func (f *FileStore) List(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if id, err := url.PathUnescape(name); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// DeleteExpired removes sessions last saved before cutoff
+func (f *FileStore) DeleteExpired(ctx context.Context, cutoff time.Time) (int, error) {
+	ids, err := f.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	removed := 0
+	for _, id := range ids {
+		rec, err := f.read(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return removed, err
+		}
+		if rec.LastUsed.Before(cutoff) {
+			if err := os.Remove(f.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return removed, fmt.Errorf("failed to delete session %s: %w", id, err)
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,162 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPersistentManager_ResumesFromFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	mgr := NewPersistentManager(store)
+	mgr.GetOrCreate("session/1", "onboarding")
+	if err := mgr.AccumulateDSL("session/1", "(case.create)"); err != nil {
+		t.Fatalf("AccumulateDSL failed: %v", err)
+	}
+	if err := mgr.UpdateContext("session/1", map[string]interface{}{"cbu_id": "CBU-1", "region": "EU"}); err != nil {
+		t.Fatalf("UpdateContext failed: %v", err)
+	}
+	session, _ := mgr.Get("session/1")
+	session.AddMessage("user", "create a case", "(case.create)", nil)
+	if err := mgr.Save(context.Background(), "session/1"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A second manager, as after a restart, picks the session up from disk
+	restarted := NewPersistentManager(store)
+	resumed, err := restarted.Get("session/1")
+	if err != nil {
+		t.Fatalf("Get after restart failed: %v", err)
+	}
+	if resumed.BuiltDSL != "(case.create)" || resumed.Domain != "onboarding" {
+		t.Errorf("unexpected resumed session: domain %q, dsl %q", resumed.Domain, resumed.BuiltDSL)
+	}
+	if ctx := resumed.GetContext(); ctx.CBUID != "CBU-1" || ctx.Data["region"] != "EU" {
+		t.Errorf("context not resumed: cbu %q, data %v", ctx.CBUID, ctx.Data)
+	}
+	if len(resumed.History) != 1 || resumed.History[0].DSL != "(case.create)" {
+		t.Errorf("history not resumed: %+v", resumed.History)
+	}
+	if resumed.Version != 3 {
+		t.Errorf("expected version 3, got %d", resumed.Version)
+	}
+	if ids := restarted.List(); len(ids) != 1 || ids[0] != "session/1" {
+		t.Errorf("expected List to include stored session, got %v", ids)
+	}
+}
+
+func TestPersistentManager_ConflictingSaves(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	a := NewPersistentManager(store)
+	a.GetOrCreate("shared", "kyc")
+	if err := a.AccumulateDSL("shared", "(kyc.start)"); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewPersistentManager(store)
+	if _, err := b.Get("shared"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AccumulateDSL("shared", "(kyc.collect)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a's copy is stale, so its save is refused and the copy refreshed
+	err = a.AccumulateDSL("shared", "(kyc.approve)")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	session, _ := a.Get("shared")
+	if want := "(kyc.start)\n\n(kyc.collect)"; session.BuiltDSL != want {
+		t.Errorf("expected refreshed DSL %q, got %q", want, session.BuiltDSL)
+	}
+
+	// Retrying against the refreshed copy succeeds
+	if err := a.AccumulateDSL("shared", "(kyc.approve)"); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	rec, _ := store.Load(context.Background(), "shared")
+	if want := "(kyc.start)\n\n(kyc.collect)\n\n(kyc.approve)"; rec.BuiltDSL != want {
+		t.Errorf("expected stored DSL %q, got %q", want, rec.BuiltDSL)
+	}
+}
+
+func TestPersistentManager_CleanupAndDelete(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	ctx := context.Background()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, id := range []string{"stale", "fresh"} {
+		rec := &Record{SessionID: id, CreatedAt: old, LastUsed: old}
+		if id == "fresh" {
+			rec.LastUsed = time.Now()
+		}
+		if err := store.Save(ctx, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mgr := NewPersistentManager(store)
+	removed, err := mgr.Cleanup(ctx, time.Hour)
+	if err != nil || removed != 1 {
+		t.Fatalf("Cleanup = %d, %v; want 1 removed", removed, err)
+	}
+	if _, err := mgr.Get("stale"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected stale session to be gone, got %v", err)
+	}
+
+	if err := mgr.Delete("fresh"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := mgr.Delete("fresh"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestPostgresStore_SaveConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "dsl-ob-poc".dsl_sessions`)).
+		WithArgs("s-1", "onboarding", "(case.create)", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "dsl-ob-poc".dsl_sessions`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	store := NewPostgresStore(db)
+	rec := &Record{SessionID: "s-1", Domain: "onboarding", BuiltDSL: "(case.create)", Version: 4}
+	if err := store.Save(context.Background(), rec); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if rec.Version != 4 {
+		t.Errorf("version changed on conflict: %d", rec.Version)
+	}
+
+	fresh := &Record{SessionID: "s-2"}
+	if err := store.Save(context.Background(), fresh); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if fresh.Version != 1 {
+		t.Errorf("expected version 1 after first save, got %d", fresh.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
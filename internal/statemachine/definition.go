@@ -0,0 +1,111 @@
+// Package statemachine holds declarative state machines: states, allowed
+// transitions, guards and entry actions loaded from data, so every package
+// that reasons about a process's state shares one definition instead of its
+// own enum and transition table.
+package statemachine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Definition is the data form of a state machine
+type Definition struct {
+	Name        string       `json:"name"`
+	Initial     string       `json:"initial"`
+	States      []State      `json:"states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// State is one state of a machine. Names gives the state's name in other
+// vocabularies (e.g. the onboarding domain calls CREATED "CREATE") and
+// Projections maps it onto coarser state sets such as the persisted store
+// states; a state with no entry for a projection projects to itself.
+type State struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Names       map[string]string `json:"names,omitempty"`
+	Projections map[string]string `json:"projections,omitempty"`
+	Entry       []string          `json:"entry,omitempty"`    // actions run on entering the state
+	Terminal    bool              `json:"terminal,omitempty"` // no transitions may leave the state
+}
+
+// Transition is an allowed move between two states. Requires lists data keys
+// that must be present, and Guard names a registered guard, for the move to
+// be taken by Machine.Transition.
+type Transition struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Requires []string `json:"requires,omitempty"`
+	Guard    string   `json:"guard,omitempty"`
+}
+
+// Parse decodes a JSON definition and builds its machine
+func Parse(data []byte) (*Machine, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse state machine definition: %w", err)
+	}
+	return New(def)
+}
+
+// LoadFile reads a JSON definition from path and builds its machine
+func LoadFile(path string) (*Machine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state machine definition: %w", err)
+	}
+	machine, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return machine, nil
+}
+
+// validate checks that names are unique across states and vocabularies and
+// that the initial state and every transition refer to declared states
+func (d *Definition) validate() error {
+	if len(d.States) == 0 {
+		return fmt.Errorf("state machine %q has no states", d.Name)
+	}
+
+	names := make(map[string]string) // any name -> canonical state
+	for _, state := range d.States {
+		if state.Name == "" {
+			return fmt.Errorf("state machine %q has a state without a name", d.Name)
+		}
+		if owner, exists := names[state.Name]; exists {
+			return fmt.Errorf("state %q is declared twice (also names %q)", state.Name, owner)
+		}
+		names[state.Name] = state.Name
+	}
+	for _, state := range d.States {
+		for vocabulary, name := range state.Names {
+			if owner, exists := names[name]; exists && owner != state.Name {
+				return fmt.Errorf("%s name %q of state %q already names state %q", vocabulary, name, state.Name, owner)
+			}
+			names[name] = state.Name
+		}
+	}
+
+	if _, exists := names[d.Initial]; !exists || names[d.Initial] != d.Initial {
+		return fmt.Errorf("initial state %q is not declared", d.Initial)
+	}
+
+	terminal := make(map[string]bool)
+	for _, state := range d.States {
+		terminal[state.Name] = state.Terminal
+	}
+	for _, t := range d.Transitions {
+		for _, name := range []string{t.From, t.To} {
+			if names[name] != name {
+				return fmt.Errorf("transition %s -> %s refers to undeclared state %q", t.From, t.To, name)
+			}
+		}
+		if terminal[t.From] {
+			return fmt.Errorf("transition %s -> %s leaves terminal state %q", t.From, t.To, t.From)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package statemachine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mermaid renders the machine as a Mermaid state diagram
+func (m *Machine) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	fmt.Fprintf(&b, "    [*] --> %s\n", m.def.Initial)
+	for _, state := range m.def.States {
+		if state.Description != "" {
+			fmt.Fprintf(&b, "    %s : %s\n", state.Name, state.Description)
+		}
+	}
+	for _, t := range m.def.Transitions {
+		if label := transitionLabel(t); label != "" {
+			fmt.Fprintf(&b, "    %s --> %s : %s\n", t.From, t.To, label)
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", t.From, t.To)
+		}
+	}
+	for _, state := range m.def.States {
+		if state.Terminal {
+			fmt.Fprintf(&b, "    %s --> [*]\n", state.Name)
+		}
+	}
+	return b.String()
+}
+
+// Graphviz renders the machine as a Graphviz DOT digraph
+func (m *Machine) Graphviz() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", m.def.Name)
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, style=rounded];\n")
+	b.WriteString("    \"__start\" [shape=point];\n")
+	fmt.Fprintf(&b, "    \"__start\" -> %q;\n", m.def.Initial)
+	for _, state := range m.def.States {
+		attrs := []string{}
+		if state.Description != "" {
+			attrs = append(attrs, fmt.Sprintf("tooltip=%q", state.Description))
+		}
+		if state.Terminal {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "    %q [%s];\n", state.Name, strings.Join(attrs, ", "))
+		}
+	}
+	for _, t := range m.def.Transitions {
+		if label := transitionLabel(t); label != "" {
+			fmt.Fprintf(&b, "    %q -> %q [label=%q];\n", t.From, t.To, label)
+		} else {
+			fmt.Fprintf(&b, "    %q -> %q;\n", t.From, t.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// transitionLabel describes a transition's guard and required data
+func transitionLabel(t Transition) string {
+	var parts []string
+	if t.Guard != "" {
+		parts = append(parts, "["+t.Guard+"]")
+	}
+	if len(t.Requires) > 0 {
+		parts = append(parts, "requires "+strings.Join(t.Requires, ", "))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,322 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Guard decides whether a transition may be taken given the caller's data
+type Guard func(ctx context.Context, from, to string, data map[string]interface{}) error
+
+// Action runs when a state is entered through Machine.Transition
+type Action func(ctx context.Context, from, to string, data map[string]interface{}) error
+
+// Handlers binds the guard and action names used in a definition to code
+type Handlers struct {
+	Guards  map[string]Guard
+	Actions map[string]Action
+}
+
+// Machine is a validated, read-only state machine. Methods accept a state's
+// canonical name or any of its vocabulary names and return canonical names
+// unless documented otherwise.
+type Machine struct {
+	def      Definition
+	states   map[string]State        // canonical name -> state
+	names    map[string]string       // any name -> canonical name
+	edges    map[string][]Transition // from -> transitions in declaration order
+	handlers Handlers
+}
+
+// New validates a definition and builds its machine
+func New(def Definition) (*Machine, error) {
+	if err := def.validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Machine{
+		def:    def,
+		states: make(map[string]State, len(def.States)),
+		names:  make(map[string]string),
+		edges:  make(map[string][]Transition),
+	}
+	for _, state := range def.States {
+		m.states[state.Name] = state
+		m.names[state.Name] = state.Name
+		for _, name := range state.Names {
+			m.names[name] = state.Name
+		}
+	}
+	for _, t := range def.Transitions {
+		m.edges[t.From] = append(m.edges[t.From], t)
+	}
+	return m, nil
+}
+
+// WithHandlers returns a copy of the machine whose Transition calls use the
+// given guards and actions
+func (m *Machine) WithHandlers(handlers Handlers) *Machine {
+	bound := *m
+	bound.handlers = handlers
+	return &bound
+}
+
+// Name returns the machine's name
+func (m *Machine) Name() string {
+	return m.def.Name
+}
+
+// Initial returns the state new processes start in
+func (m *Machine) Initial() string {
+	return m.def.Initial
+}
+
+// Definition returns the definition the machine was built from
+func (m *Machine) Definition() Definition {
+	return m.def
+}
+
+// States returns the canonical state names in declaration order
+func (m *Machine) States() []string {
+	states := make([]string, 0, len(m.def.States))
+	for _, state := range m.def.States {
+		states = append(states, state.Name)
+	}
+	return states
+}
+
+// Resolve returns the canonical name of a state
+func (m *Machine) Resolve(name string) (string, error) {
+	canonical, ok := m.names[name]
+	if !ok {
+		return "", fmt.Errorf("unknown %s state: %s", m.def.Name, name)
+	}
+	return canonical, nil
+}
+
+// State returns the declaration of a state
+func (m *Machine) State(name string) (State, error) {
+	canonical, err := m.Resolve(name)
+	if err != nil {
+		return State{}, err
+	}
+	return m.states[canonical], nil
+}
+
+// NameIn returns a state's name in a vocabulary; ok is false when the
+// vocabulary has no name for it
+func (m *Machine) NameIn(state, vocabulary string) (string, bool) {
+	canonical, err := m.Resolve(state)
+	if err != nil {
+		return "", false
+	}
+	name, ok := m.states[canonical].Names[vocabulary]
+	return name, ok
+}
+
+// StatesIn returns the names a vocabulary uses, in declaration order
+func (m *Machine) StatesIn(vocabulary string) []string {
+	var names []string
+	for _, state := range m.def.States {
+		if name, ok := state.Names[vocabulary]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Project maps a state onto a projection such as the persisted store states
+func (m *Machine) Project(state, projection string) (string, error) {
+	canonical, err := m.Resolve(state)
+	if err != nil {
+		return "", err
+	}
+	if projected, ok := m.states[canonical].Projections[projection]; ok {
+		return projected, nil
+	}
+	return canonical, nil
+}
+
+// ProjectionValues returns the distinct values of a projection in
+// declaration order
+func (m *Machine) ProjectionValues(projection string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, state := range m.def.States {
+		value, _ := m.Project(state.Name, projection)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Allowed returns the states reachable from a state in one transition
+func (m *Machine) Allowed(from string) ([]string, error) {
+	canonical, err := m.Resolve(from)
+	if err != nil {
+		return nil, err
+	}
+	var states []string
+	for _, t := range m.edges[canonical] {
+		states = append(states, t.To)
+	}
+	return states, nil
+}
+
+// CanTransition reports whether the definition allows moving from one state
+// to another, without evaluating guards
+func (m *Machine) CanTransition(from, to string) error {
+	_, err := m.transition(from, to)
+	return err
+}
+
+// Transition checks that a move is allowed, that data carries every required
+// key and that the transition's guard passes, then runs the entry actions of
+// the target state. A guard without a handler fails the transition; an
+// action without a handler is skipped, so callers only bind the side effects
+// they own.
+func (m *Machine) Transition(ctx context.Context, from, to string, data map[string]interface{}) error {
+	t, err := m.transition(from, to)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, key := range t.Requires {
+		if !present(data[key]) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("transition from %s to %s requires %s", t.From, t.To, strings.Join(missing, ", "))
+	}
+
+	if t.Guard != "" {
+		guard, ok := m.handlers.Guards[t.Guard]
+		if !ok {
+			return fmt.Errorf("transition from %s to %s: guard %q is not registered", t.From, t.To, t.Guard)
+		}
+		if err := guard(ctx, t.From, t.To, data); err != nil {
+			return fmt.Errorf("transition from %s to %s rejected by %s: %w", t.From, t.To, t.Guard, err)
+		}
+	}
+
+	for _, name := range m.states[t.To].Entry {
+		action, ok := m.handlers.Actions[name]
+		if !ok {
+			continue
+		}
+		if err := action(ctx, t.From, t.To, data); err != nil {
+			return fmt.Errorf("entry action %s of %s failed: %w", name, t.To, err)
+		}
+	}
+	return nil
+}
+
+// transition finds the declared transition between two states
+func (m *Machine) transition(from, to string) (Transition, error) {
+	fromState, err := m.Resolve(from)
+	if err != nil {
+		return Transition{}, err
+	}
+	toState, err := m.Resolve(to)
+	if err != nil {
+		return Transition{}, err
+	}
+	for _, t := range m.edges[fromState] {
+		if t.To == toState {
+			return t, nil
+		}
+	}
+	return Transition{}, fmt.Errorf("invalid transition from %s to %s", from, to)
+}
+
+// Path returns a sequence of states leading from one state to another,
+// including both. Transitions are followed in declaration order, so a
+// definition that lists the main line first gets the main line back rather
+// than the shortest shortcut.
+func (m *Machine) Path(from, to string) ([]string, error) {
+	return m.path(from, to, "")
+}
+
+// PathIn is Path restricted to the states a vocabulary names, returned in
+// that vocabulary
+func (m *Machine) PathIn(vocabulary, from, to string) ([]string, error) {
+	path, err := m.path(from, to, vocabulary)
+	if err != nil {
+		return nil, err
+	}
+	for i, state := range path {
+		path[i] = m.states[state].Names[vocabulary]
+	}
+	return path, nil
+}
+
+func (m *Machine) path(from, to, vocabulary string) ([]string, error) {
+	start, err := m.Resolve(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.Resolve(to)
+	if err != nil {
+		return nil, err
+	}
+
+	included := func(state string) bool {
+		if vocabulary == "" {
+			return true
+		}
+		_, ok := m.states[state].Names[vocabulary]
+		return ok
+	}
+	if !included(start) || !included(target) {
+		return nil, fmt.Errorf("%s states have no %s name", m.def.Name, vocabulary)
+	}
+
+	visited := map[string]bool{start: true}
+	var walk func(state string) []string
+	walk = func(state string) []string {
+		if state == target {
+			return []string{state}
+		}
+		for _, t := range m.edges[state] {
+			if visited[t.To] || !included(t.To) {
+				continue
+			}
+			visited[t.To] = true
+			if rest := walk(t.To); rest != nil {
+				return append([]string{state}, rest...)
+			}
+		}
+		return nil
+	}
+
+	if start == target {
+		return nil, fmt.Errorf("already in state %s", from)
+	}
+	path := walk(start)
+	if path == nil {
+		return nil, fmt.Errorf("no path from %s to %s", from, to)
+	}
+	return path, nil
+}
+
+// present reports whether a required data value is set: not nil and, for
+// strings, slices and maps, not empty
+func present(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil()
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultOnboarding_Vocabularies(t *testing.T) {
+	m, err := DefaultOnboarding()
+	if err != nil {
+		t.Fatalf("built-in definition is invalid: %v", err)
+	}
+
+	want := []string{"CREATE", "PRODUCTS_ADDED", "KYC_STARTED", "SERVICES_DISCOVERED",
+		"RESOURCES_PLANNED", "ATTRIBUTES_BOUND", "WORKFLOW_ACTIVE", "COMPLETE"}
+	if got := m.StatesIn(VocabularyDomain); !reflect.DeepEqual(got, want) {
+		t.Errorf("domain states = %v, want %v", got, want)
+	}
+
+	// Every state the store persists is a canonical state of the machine
+	for _, value := range m.ProjectionValues(ProjectionStore) {
+		if canonical, err := m.Resolve(value); err != nil || canonical != value {
+			t.Errorf("store state %s resolves to %q, %v", value, canonical, err)
+		}
+	}
+
+	tests := []struct {
+		name, projection, want string
+	}{
+		{"ONBOARDING_REQUESTED", ProjectionStore, "CREATED"},
+		{"CBU_ASSOCIATED", ProjectionStore, "CREATED"},
+		{"PRODUCTS_SELECTED", ProjectionStore, "PRODUCTS_ADDED"},
+		{"VALUES_BOUND", ProjectionStore, "ATTRIBUTES_POPULATED"},
+		{"VALUES_BOUND", ProjectionDSLLifecycle, "READY"},
+		{"ONBOARDING_ARCHIVED", ProjectionStore, "COMPLETED"},
+	}
+	for _, tt := range tests {
+		if got, err := m.Project(tt.name, tt.projection); err != nil || got != tt.want {
+			t.Errorf("Project(%s, %s) = %q, %v; want %q", tt.name, tt.projection, got, err, tt.want)
+		}
+	}
+}
+
+func TestMachine_CanTransition(t *testing.T) {
+	m, _ := DefaultOnboarding()
+
+	allowed := [][2]string{
+		{"CREATE", "PRODUCTS_ADDED"},
+		{"ONBOARDING_REQUESTED", "CBU_ASSOCIATED"},
+		{"ATTRIBUTES_BOUND", "WORKFLOW_ACTIVE"},
+		{"ONBOARDING_FAILED", "ONBOARDING_REQUESTED"},
+	}
+	for _, tt := range allowed {
+		if err := m.CanTransition(tt[0], tt[1]); err != nil {
+			t.Errorf("%s -> %s: %v", tt[0], tt[1], err)
+		}
+	}
+
+	rejected := [][2]string{
+		{"CREATE", "KYC_STARTED"},
+		{"COMPLETE", "CREATE"},
+		{"ONBOARDING_ARCHIVED", "ONBOARDING_REQUESTED"},
+		{"CREATED", "NOT_A_STATE"},
+	}
+	for _, tt := range rejected {
+		if err := m.CanTransition(tt[0], tt[1]); err == nil {
+			t.Errorf("%s -> %s was allowed", tt[0], tt[1])
+		}
+	}
+}
+
+func TestMachine_TransitionGuardsAndActions(t *testing.T) {
+	m, err := New(Definition{
+		Name:    "review",
+		Initial: "DRAFT",
+		States: []State{
+			{Name: "DRAFT"},
+			{Name: "SUBMITTED", Entry: []string{"notify", "unbound"}},
+			{Name: "APPROVED", Terminal: true},
+		},
+		Transitions: []Transition{
+			{From: "DRAFT", To: "SUBMITTED", Requires: []string{"author"}},
+			{From: "SUBMITTED", To: "APPROVED", Guard: "four_eyes"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := m.Transition(ctx, "DRAFT", "SUBMITTED", map[string]interface{}{"author": ""}); err == nil ||
+		!strings.Contains(err.Error(), "requires author") {
+		t.Errorf("expected missing author to be rejected, got %v", err)
+	}
+	if err := m.Transition(ctx, "SUBMITTED", "APPROVED", nil); err == nil {
+		t.Error("expected an unregistered guard to reject the transition")
+	}
+
+	var notified []string
+	bound := m.WithHandlers(Handlers{
+		Guards: map[string]Guard{
+			"four_eyes": func(ctx context.Context, from, to string, data map[string]interface{}) error {
+				if data["approver"] == data["author"] {
+					return errors.New("approver must differ from author")
+				}
+				return nil
+			},
+		},
+		Actions: map[string]Action{
+			"notify": func(ctx context.Context, from, to string, data map[string]interface{}) error {
+				notified = append(notified, from+"->"+to)
+				return nil
+			},
+		},
+	})
+
+	if err := bound.Transition(ctx, "DRAFT", "SUBMITTED", map[string]interface{}{"author": "ana"}); err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	if !reflect.DeepEqual(notified, []string{"DRAFT->SUBMITTED"}) {
+		t.Errorf("entry actions ran %v", notified)
+	}
+	if err := bound.Transition(ctx, "SUBMITTED", "APPROVED", map[string]interface{}{"author": "ana", "approver": "ana"}); err == nil {
+		t.Error("expected the guard to reject self-approval")
+	}
+	if err := bound.Transition(ctx, "SUBMITTED", "APPROVED", map[string]interface{}{"author": "ana", "approver": "ben"}); err != nil {
+		t.Errorf("guarded transition failed: %v", err)
+	}
+}
+
+func TestMachine_Path(t *testing.T) {
+	m, _ := DefaultOnboarding()
+
+	path, err := m.PathIn(VocabularyDSLManager, "CBU_ASSOCIATED", "ONBOARDING_COMPLETED")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"CBU_ASSOCIATED", "PRODUCTS_SELECTED", "SERVICES_DISCOVERED", "RESOURCES_DISCOVERED",
+		"DATA_DICTIONARY_CREATED", "ATTRIBUTES_POPULATED", "RESOURCE_LIFECYCLES_READY",
+		"RESOURCES_PROVISIONED", "ONBOARDING_COMPLETED"}
+	if !reflect.DeepEqual(path, want) {
+		t.Errorf("PathIn = %v\nwant %v", path, want)
+	}
+
+	if path, err := m.Path("PRODUCTS_ADDED", "SERVICES_DISCOVERED"); err != nil || len(path) != 3 || path[1] != "KYC_DISCOVERED" {
+		t.Errorf("Path = %v, %v; want the main line through KYC_DISCOVERED", path, err)
+	}
+	if _, err := m.Path("COMPLETED", "CREATED"); err == nil {
+		t.Error("expected no path back from COMPLETED")
+	}
+}
+
+func TestParse_RejectsInvalidDefinitions(t *testing.T) {
+	tests := map[string]string{
+		"unknown initial":    `{"name": "x", "initial": "B", "states": [{"name": "A"}]}`,
+		"unknown state":      `{"name": "x", "initial": "A", "states": [{"name": "A"}], "transitions": [{"from": "A", "to": "B"}]}`,
+		"ambiguous name":     `{"name": "x", "initial": "A", "states": [{"name": "A"}, {"name": "B", "names": {"v": "A"}}]}`,
+		"leaves terminal":    `{"name": "x", "initial": "A", "states": [{"name": "A", "terminal": true}, {"name": "B"}], "transitions": [{"from": "A", "to": "B"}]}`,
+		"alias in edge":      `{"name": "x", "initial": "A", "states": [{"name": "A", "names": {"v": "AA"}}], "transitions": [{"from": "AA", "to": "A"}]}`,
+		"duplicate state":    `{"name": "x", "initial": "A", "states": [{"name": "A"}, {"name": "A"}]}`,
+		"malformed document": `{"name": `,
+	}
+	for name, doc := range tests {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDiagrams(t *testing.T) {
+	m, _ := DefaultOnboarding()
+
+	mermaid := m.Mermaid()
+	for _, want := range []string{"stateDiagram-v2", "[*] --> CREATED", "PRODUCTS_ADDED --> SERVICES_DISCOVERED : requires products", "ARCHIVED --> [*]"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output is missing %q", want)
+		}
+	}
+
+	dot := m.Graphviz()
+	for _, want := range []string{`digraph "onboarding" {`, `"__start" -> "CREATED";`, `"COMPLETED" -> "ARCHIVED";`, `peripheries=2`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Graphviz output is missing %q", want)
+		}
+	}
+}
+
+func TestOnboardingFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "onboarding.json")
+	doc := `{"name": "onboarding", "initial": "CREATED", "states": [{"name": "CREATED"}, {"name": "COMPLETED"}],
+		"transitions": [{"from": "CREATED", "to": "COMPLETED"}]}`
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DSL_STATE_MACHINE_FILE", path)
+
+	m, err := OnboardingFromEnv()
+	if err != nil {
+		t.Fatalf("OnboardingFromEnv failed: %v", err)
+	}
+	SetOnboarding(m)
+	defer SetOnboarding(nil)
+
+	if err := Onboarding().CanTransition("CREATED", "COMPLETED"); err != nil {
+		t.Errorf("file definition not in use: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package statemachine
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Vocabularies and projections used by the onboarding definition
+const (
+	VocabularyDSLManager = "dsl_manager" // dsl_manager.OnboardingState
+	VocabularyDomain     = "domain"      // states of the onboarding domain
+	VocabularyLifecycle  = "lifecycle"   // dsl_manager.DSLDomainState
+
+	ProjectionStore        = "store"         // store.OnboardingState persisted per session
+	ProjectionDSLLifecycle = "dsl_lifecycle" // dsl_manager.DSLLifecycleState
+)
+
+// onboardingDefinition is the built-in onboarding state machine
+//
+//go:embed onboarding.json
+var onboardingDefinition []byte
+
+var (
+	onboardingMu      sync.RWMutex
+	onboardingMachine *Machine
+)
+
+// Onboarding returns the shared onboarding state machine: the one set with
+// SetOnboarding, or the built-in definition
+func Onboarding() *Machine {
+	onboardingMu.RLock()
+	machine := onboardingMachine
+	onboardingMu.RUnlock()
+	if machine != nil {
+		return machine
+	}
+
+	onboardingMu.Lock()
+	defer onboardingMu.Unlock()
+	if onboardingMachine == nil {
+		onboardingMachine = mustDefaultOnboarding()
+	}
+	return onboardingMachine
+}
+
+// SetOnboarding replaces the shared onboarding state machine; nil restores
+// the built-in definition
+func SetOnboarding(machine *Machine) {
+	onboardingMu.Lock()
+	defer onboardingMu.Unlock()
+	onboardingMachine = machine
+}
+
+// DefaultOnboarding builds the built-in onboarding state machine
+func DefaultOnboarding() (*Machine, error) {
+	return Parse(onboardingDefinition)
+}
+
+// OnboardingFromEnv loads the onboarding state machine from the file named
+// by DSL_STATE_MACHINE_FILE, or the built-in definition when it is unset
+func OnboardingFromEnv() (*Machine, error) {
+	path := os.Getenv("DSL_STATE_MACHINE_FILE")
+	if path == "" {
+		return DefaultOnboarding()
+	}
+	return LoadFile(path)
+}
+
+func mustDefaultOnboarding() *Machine {
+	machine, err := DefaultOnboarding()
+	if err != nil {
+		panic(fmt.Sprintf("built-in onboarding state machine is invalid: %v", err))
+	}
+	return machine
+}
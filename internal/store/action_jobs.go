@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobStatus represents where an action job is in the runtime queue
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "QUEUED"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobDead      JobStatus = "DEAD"
+)
+
+// ErrLeaseLost is returned when a worker updates a job it no longer holds,
+// because its lease expired and another worker reclaimed the job
+var ErrLeaseLost = errors.New("job lease lost")
+
+// ActionJob is a queued runtime action execution. Payload is opaque to the
+// store (the runtime keeps the execution request and retry schedule there);
+// Attempts counts the leases taken so far, so a job whose worker died
+// mid-attempt still uses up that attempt.
+type ActionJob struct {
+	JobID          string          `json:"job_id"`
+	ActionID       string          `json:"action_id"`
+	CBUID          string          `json:"cbu_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         JobStatus       `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	RunAt          time.Time       `json:"run_at"`
+	LeaseOwner     *string         `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	ExecutionID    *string         `json:"execution_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+const actionJobColumns = `job_id, action_id, cbu_id, payload, status, attempts, max_attempts, run_at,
+		lease_owner, lease_expires_at, last_error, execution_id, created_at, updated_at`
+
+// EnqueueJob inserts a job that becomes due at job.RunAt (now if zero) and
+// returns its ID
+func (s *Store) EnqueueJob(ctx context.Context, job *ActionJob) (string, error) {
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	maxAttempts := job.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var jobID string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO action_jobs (action_id, cbu_id, payload, status, max_attempts, run_at)
+		VALUES ($1, $2, $3, 'QUEUED', $4, $5)
+		RETURNING job_id`,
+		job.ActionID, job.CBUID, []byte(job.Payload), maxAttempts, runAt,
+	).Scan(&jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return jobID, nil
+}
+
+// LeaseJob claims the oldest due job for owner until the lease expires,
+// counting it as an attempt. Queued jobs whose run_at has passed and running
+// jobs whose lease has expired are both due. It returns nil when no job is due.
+func (s *Store) LeaseJob(ctx context.Context, owner string, lease time.Duration) (*ActionJob, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE action_jobs
+		SET status = 'RUNNING', lease_owner = $1,
+			lease_expires_at = NOW() + $2 * INTERVAL '1 millisecond',
+			attempts = attempts + 1, updated_at = NOW()
+		WHERE job_id = (
+			SELECT job_id FROM action_jobs
+			WHERE (status = 'QUEUED' AND run_at <= NOW())
+			   OR (status = 'RUNNING' AND lease_expires_at < NOW())
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+actionJobColumns,
+		owner, lease.Milliseconds(),
+	)
+
+	job, err := scanActionJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease job: %w", err)
+	}
+	return job, nil
+}
+
+// HeartbeatJob extends owner's lease on a running job
+func (s *Store) HeartbeatJob(ctx context.Context, jobID, owner string, lease time.Duration) error {
+	return s.execLeased(ctx, "job", "heartbeat", `
+		UPDATE action_jobs
+		SET lease_expires_at = NOW() + $3 * INTERVAL '1 millisecond', updated_at = NOW()
+		WHERE job_id = $1 AND lease_owner = $2 AND status = 'RUNNING'`,
+		jobID, owner, lease.Milliseconds())
+}
+
+// CompleteJob marks owner's job as succeeded by the given execution
+func (s *Store) CompleteJob(ctx context.Context, jobID, owner, executionID string) error {
+	return s.execLeased(ctx, "job", "complete", `
+		UPDATE action_jobs
+		SET status = 'SUCCEEDED', execution_id = NULLIF($3, '')::uuid, last_error = NULL,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND lease_owner = $2 AND status = 'RUNNING'`,
+		jobID, owner, executionID)
+}
+
+// RetryJob releases owner's job back to the queue, due again at runAt
+func (s *Store) RetryJob(ctx context.Context, jobID, owner string, runAt time.Time, lastError string) error {
+	return s.execLeased(ctx, "job", "retry", `
+		UPDATE action_jobs
+		SET status = 'QUEUED', run_at = $3, last_error = $4,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND lease_owner = $2 AND status = 'RUNNING'`,
+		jobID, owner, runAt, lastError)
+}
+
+// DeadLetterJob parks owner's job as dead; it is not leased again unless
+// requeued
+func (s *Store) DeadLetterJob(ctx context.Context, jobID, owner, lastError string) error {
+	return s.execLeased(ctx, "job", "dead-letter", `
+		UPDATE action_jobs
+		SET status = 'DEAD', last_error = $3,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE job_id = $1 AND lease_owner = $2 AND status = 'RUNNING'`,
+		jobID, owner, lastError)
+}
+
+// RequeueJob returns a dead job to the queue with a fresh set of attempts
+func (s *Store) RequeueJob(ctx context.Context, jobID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE action_jobs
+		SET status = 'QUEUED', attempts = 0, run_at = NOW(), updated_at = NOW()
+		WHERE job_id = $1 AND status = 'DEAD'`,
+		jobID)
+	if err != nil {
+		return fmt.Errorf("failed to requeue job: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return NotFoundf("dead job not found: %s", jobID)
+	}
+	return nil
+}
+
+// ListJobs returns jobs in the given status (all jobs if empty), oldest first
+func (s *Store) ListJobs(ctx context.Context, status JobStatus) ([]ActionJob, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+actionJobColumns+`
+		FROM action_jobs
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at`,
+		string(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []ActionJob
+	for rows.Next() {
+		job, err := scanActionJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// execLeased runs an update guarded by the lease owner, returning
+// ErrLeaseLost when the guard matched no row. entity names what is updated
+// in errors, and args[0] must be its ID.
+func (s *Store) execLeased(ctx context.Context, entity, op, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", op, entity, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("failed to %s %s %s: %w", op, entity, args[0], ErrLeaseLost)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanActionJob(row rowScanner) (*ActionJob, error) {
+	var job ActionJob
+	var payload []byte
+	var status string
+	err := row.Scan(
+		&job.JobID, &job.ActionID, &job.CBUID, &payload, &status, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&job.LeaseOwner, &job.LeaseExpiresAt, &job.LastError, &job.ExecutionID, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = payload
+	job.Status = JobStatus(status)
+	return &job, nil
+}
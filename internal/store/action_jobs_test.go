@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLeaseJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+	ctx := context.Background()
+	now := time.Now()
+
+	columns := []string{"job_id", "action_id", "cbu_id", "payload", "status", "attempts", "max_attempts", "run_at",
+		"lease_owner", "lease_expires_at", "last_error", "execution_id", "created_at", "updated_at"}
+	mock.ExpectQuery(`UPDATE action_jobs .* FOR UPDATE SKIP LOCKED`).
+		WithArgs("worker-1", int64(30000)).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			"job-1", "action-1", "cbu-1", []byte(`{"request":{}}`), "RUNNING", 2, 3, now,
+			"worker-1", now.Add(30*time.Second), "HTTP 503", nil, now, now))
+	mock.ExpectQuery(`UPDATE action_jobs .* FOR UPDATE SKIP LOCKED`).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	job, err := store.LeaseJob(ctx, "worker-1", 30*time.Second)
+	if err != nil {
+		t.Fatalf("LeaseJob failed: %v", err)
+	}
+	if job.JobID != "job-1" || job.Status != JobRunning || job.Attempts != 2 || *job.LastError != "HTTP 503" || job.ExecutionID != nil {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	job, err = store.LeaseJob(ctx, "worker-1", 30*time.Second)
+	if err != nil || job != nil {
+		t.Errorf("expected no job when none is due, got %+v (%v)", job, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestHeartbeatJob_LeaseLost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+
+	mock.ExpectExec(`UPDATE action_jobs SET lease_expires_at = .* WHERE job_id = \$1 AND lease_owner = \$2`).
+		WithArgs("job-1", "worker-1", int64(60000)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = store.HeartbeatJob(context.Background(), "job-1", "worker-1", time.Minute)
+	if !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("expected ErrLeaseLost, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/shared-dsl/parser"
+
+	"github.com/lib/pq"
+)
+
+// DSLEventType identifies what kind of change a DSL change event records
+type DSLEventType string
+
+const (
+	// EventDSLVersionCreated is written by InsertDSLWithState and InsertDSLWithGrammar
+	EventDSLVersionCreated DSLEventType = "DSL_VERSION_CREATED"
+	// EventOnboardingStateChanged is written by UpdateOnboardingState
+	EventOnboardingStateChanged DSLEventType = "ONBOARDING_STATE_CHANGED"
+)
+
+// DSLChangeEvent is an outbox entry written in the same transaction as the
+// DSL or onboarding state change it describes. ChangedVerbs lists the verbs
+// used a different number of times than in the previous DSL version.
+type DSLChangeEvent struct {
+	EventID       string          `json:"event_id"`
+	EventType     DSLEventType    `json:"event_type"`
+	CBUID         string          `json:"cbu_id"`
+	VersionID     string          `json:"version_id"`
+	VersionNumber int             `json:"version_number"`
+	OldState      OnboardingState `json:"old_state,omitempty"`
+	NewState      OnboardingState `json:"new_state"`
+	ChangedVerbs  []string        `json:"changed_verbs"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// WebhookSubscription registers a URL that receives DSL change events.
+// An empty CBUID or EventTypes subscribes to every CBU or event type.
+type WebhookSubscription struct {
+	SubscriptionID string         `json:"subscription_id"`
+	URL            string         `json:"url"`
+	Secret         string         `json:"-"` // HMAC key for delivery signatures
+	CBUID          string         `json:"cbu_id,omitempty"`
+	EventTypes     []DSLEventType `json:"event_types,omitempty"`
+	MaxAttempts    int            `json:"max_attempts"`
+	Active         bool           `json:"active"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Matches reports whether event is in scope for the subscription
+func (s *WebhookSubscription) Matches(event *DSLChangeEvent) bool {
+	if !s.Active || (s.CBUID != "" && s.CBUID != event.CBUID) {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == event.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus represents where a webhook delivery is in the dispatch queue
+type DeliveryStatus string
+
+const (
+	DeliveryPending    DeliveryStatus = "PENDING"
+	DeliveryDelivering DeliveryStatus = "DELIVERING"
+	DeliveryDelivered  DeliveryStatus = "DELIVERED"
+	DeliveryDead       DeliveryStatus = "DEAD"
+)
+
+// WebhookDelivery is one event queued for one subscriber. Deliveries are
+// created alongside the event for every matching active subscription and
+// leased by the webhook dispatcher like action jobs are by runtime workers;
+// Event, URL and Secret are filled in when a delivery is leased or listed.
+type WebhookDelivery struct {
+	DeliveryID     string          `json:"delivery_id"`
+	EventID        string          `json:"event_id"`
+	SubscriptionID string          `json:"subscription_id"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LeaseOwner     *string         `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Event          *DSLChangeEvent `json:"event,omitempty"`
+	URL            string          `json:"url"`
+	Secret         string          `json:"-"`
+}
+
+// ChangedVerbs returns the dotted verbs (products.add, kyc.start, ...) whose
+// number of uses differs between two DSL documents, sorted. A document that
+// does not parse uses no verbs.
+func ChangedVerbs(oldDSL, newDSL string) []string {
+	counts := make(map[string]int)
+	countVerbs(oldDSL, counts, -1)
+	countVerbs(newDSL, counts, 1)
+
+	changed := make([]string, 0)
+	for verb, delta := range counts {
+		if delta != 0 {
+			changed = append(changed, verb)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func countVerbs(dsl string, counts map[string]int, sign int) {
+	if dsl == "" {
+		return
+	}
+	ast, err := parser.Parse(dsl)
+	if err != nil {
+		return
+	}
+	var walk func(n *parser.Node)
+	walk = func(n *parser.Node) {
+		if n.Type == parser.VerbNode && strings.Contains(n.Value, ".") {
+			counts[n.Value] += sign
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(ast.Root)
+}
+
+// recordDSLChange writes event to the outbox and queues a delivery of it for
+// every matching active webhook subscription, within tx
+func recordDSLChange(ctx context.Context, tx *sql.Tx, event *DSLChangeEvent) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH event AS (
+			INSERT INTO "dsl-ob-poc".dsl_change_events
+				(event_type, cbu_id, version_id, version_number, old_state, new_state, changed_verbs)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+			RETURNING event_id
+		)
+		INSERT INTO "dsl-ob-poc".webhook_deliveries (event_id, subscription_id, max_attempts)
+		SELECT event.event_id, s.subscription_id, s.max_attempts
+		FROM event, "dsl-ob-poc".webhook_subscriptions s
+		WHERE s.active
+		  AND (s.cbu_id IS NULL OR s.cbu_id = $2)
+		  AND (cardinality(s.event_types) = 0 OR $1 = ANY(s.event_types))`,
+		event.EventType, event.CBUID, event.VersionID, event.VersionNumber,
+		event.OldState, event.NewState, pq.Array(event.ChangedVerbs))
+	if err != nil {
+		return fmt.Errorf("failed to record DSL change event: %w", err)
+	}
+	return nil
+}
+
+// previousDSL returns the text and state of the CBU's latest DSL version
+// before versionNumber, or empty strings for the first version
+func previousDSL(ctx context.Context, tx *sql.Tx, cbuID string, versionNumber int) (string, OnboardingState, error) {
+	var text string
+	var state sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT dsl_text, onboarding_state
+		FROM "dsl-ob-poc".dsl_ob
+		WHERE cbu_id = $1 AND version_number < $2
+		ORDER BY version_number DESC
+		LIMIT 1`,
+		cbuID, versionNumber).Scan(&text, &state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get previous DSL version: %w", err)
+	}
+	return text, OnboardingState(state.String), nil
+}
+
+// ListDSLChangeEvents returns the most recent outbox events, newest first,
+// for one CBU or for all CBUs if cbuID is empty
+func (s *Store) ListDSLChangeEvents(ctx context.Context, cbuID string, limit int) ([]DSLChangeEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT event_id, event_type, cbu_id, version_id, version_number,
+			COALESCE(old_state, ''), new_state, changed_verbs, created_at
+		FROM "dsl-ob-poc".dsl_change_events
+		WHERE $1 = '' OR cbu_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		cbuID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DSL change events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []DSLChangeEvent
+	for rows.Next() {
+		var event DSLChangeEvent
+		var verbs []string
+		if err := rows.Scan(&event.EventID, &event.EventType, &event.CBUID, &event.VersionID, &event.VersionNumber,
+			&event.OldState, &event.NewState, pq.Array(&verbs), &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan DSL change event: %w", err)
+		}
+		event.ChangedVerbs = append([]string{}, verbs...)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating DSL change events: %w", err)
+	}
+	return events, nil
+}
+
+// CreateWebhookSubscription registers an active subscription and returns its
+// ID. It receives events written from now on.
+func (s *Store) CreateWebhookSubscription(ctx context.Context, sub *WebhookSubscription) (string, error) {
+	maxAttempts := sub.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, t := range sub.EventTypes {
+		eventTypes[i] = string(t)
+	}
+
+	var subscriptionID string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO "dsl-ob-poc".webhook_subscriptions (url, secret, cbu_id, event_types, max_attempts)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		RETURNING subscription_id`,
+		sub.URL, sub.Secret, sub.CBUID, pq.Array(eventTypes), maxAttempts,
+	).Scan(&subscriptionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return subscriptionID, nil
+}
+
+// ListWebhookSubscriptions returns all subscriptions, oldest first
+func (s *Store) ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT subscription_id, url, secret, COALESCE(cbu_id, ''), event_types, max_attempts, active, created_at
+		FROM "dsl-ob-poc".webhook_subscriptions
+		ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []WebhookSubscription
+	for rows.Next() {
+		var sub WebhookSubscription
+		var eventTypes []string
+		if err := rows.Scan(&sub.SubscriptionID, &sub.URL, &sub.Secret, &sub.CBUID, pq.Array(&eventTypes),
+			&sub.MaxAttempts, &sub.Active, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		for _, t := range eventTypes {
+			sub.EventTypes = append(sub.EventTypes, DSLEventType(t))
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// DeleteWebhookSubscription removes a subscription along with its deliveries
+func (s *Store) DeleteWebhookSubscription(ctx context.Context, subscriptionID string) error {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM "dsl-ob-poc".webhook_subscriptions WHERE subscription_id = $1`,
+		subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return NotFoundf("webhook subscription not found: %s", subscriptionID)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `d.delivery_id, d.event_id, d.subscription_id, d.status, d.attempts, d.max_attempts,
+		d.next_attempt_at, d.lease_owner, d.lease_expires_at, d.last_error, d.delivered_at, d.created_at, d.updated_at,
+		e.event_type, e.cbu_id, e.version_id, e.version_number, COALESCE(e.old_state, ''), e.new_state,
+		e.changed_verbs, e.created_at, s.url, s.secret`
+
+// LeaseWebhookDelivery claims the oldest due delivery for owner until the
+// lease expires, counting it as an attempt. Pending deliveries whose
+// next_attempt_at has passed and delivering ones whose lease has expired are
+// both due. It returns nil when no delivery is due.
+func (s *Store) LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*WebhookDelivery, error) {
+	row := s.db.QueryRowContext(ctx, `
+		WITH leased AS (
+			UPDATE "dsl-ob-poc".webhook_deliveries
+			SET status = 'DELIVERING', lease_owner = $1,
+				lease_expires_at = NOW() + $2 * INTERVAL '1 millisecond',
+				attempts = attempts + 1, updated_at = NOW()
+			WHERE delivery_id = (
+				SELECT delivery_id FROM "dsl-ob-poc".webhook_deliveries
+				WHERE (status = 'PENDING' AND next_attempt_at <= NOW())
+				   OR (status = 'DELIVERING' AND lease_expires_at < NOW())
+				ORDER BY next_attempt_at
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT `+webhookDeliveryColumns+`
+		FROM leased d
+		JOIN "dsl-ob-poc".dsl_change_events e ON e.event_id = d.event_id
+		JOIN "dsl-ob-poc".webhook_subscriptions s ON s.subscription_id = d.subscription_id`,
+		owner, lease.Milliseconds(),
+	)
+
+	delivery, err := scanWebhookDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lease webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// CompleteWebhookDelivery marks owner's delivery as delivered
+func (s *Store) CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error {
+	return s.execLeased(ctx, "webhook delivery", "complete", `
+		UPDATE "dsl-ob-poc".webhook_deliveries
+		SET status = 'DELIVERED', delivered_at = NOW(), last_error = NULL,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE delivery_id = $1 AND lease_owner = $2 AND status = 'DELIVERING'`,
+		deliveryID, owner)
+}
+
+// RetryWebhookDelivery releases owner's delivery back to the queue, due
+// again at nextAttemptAt
+func (s *Store) RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error {
+	return s.execLeased(ctx, "webhook delivery", "retry", `
+		UPDATE "dsl-ob-poc".webhook_deliveries
+		SET status = 'PENDING', next_attempt_at = $3, last_error = $4,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE delivery_id = $1 AND lease_owner = $2 AND status = 'DELIVERING'`,
+		deliveryID, owner, nextAttemptAt, lastError)
+}
+
+// DeadLetterWebhookDelivery parks owner's delivery as dead; it is not
+// attempted again
+func (s *Store) DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error {
+	return s.execLeased(ctx, "webhook delivery", "dead-letter", `
+		UPDATE "dsl-ob-poc".webhook_deliveries
+		SET status = 'DEAD', last_error = $3,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = NOW()
+		WHERE delivery_id = $1 AND lease_owner = $2 AND status = 'DELIVERING'`,
+		deliveryID, owner, lastError)
+}
+
+// ListWebhookDeliveries returns deliveries in the given status (all if
+// empty), oldest first
+func (s *Store) ListWebhookDeliveries(ctx context.Context, status DeliveryStatus) ([]WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM "dsl-ob-poc".webhook_deliveries d
+		JOIN "dsl-ob-poc".dsl_change_events e ON e.event_id = d.event_id
+		JOIN "dsl-ob-poc".webhook_subscriptions s ON s.subscription_id = d.subscription_id
+		WHERE $1 = '' OR d.status = $1
+		ORDER BY d.created_at`,
+		string(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var event DSLChangeEvent
+	var status string
+	var verbs []string
+	err := row.Scan(
+		&d.DeliveryID, &d.EventID, &d.SubscriptionID, &status, &d.Attempts, &d.MaxAttempts,
+		&d.NextAttemptAt, &d.LeaseOwner, &d.LeaseExpiresAt, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+		&event.EventType, &event.CBUID, &event.VersionID, &event.VersionNumber, &event.OldState, &event.NewState,
+		pq.Array(&verbs), &event.CreatedAt, &d.URL, &d.Secret,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Status = DeliveryStatus(status)
+	event.EventID = d.EventID
+	event.ChangedVerbs = append([]string{}, verbs...)
+	d.Event = &event
+	return &d, nil
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInsertDSLWithGrammar_WritesOutboxEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+	oldDSL := `(case.create (cbu.id "CBU-1"))`
+	newDSL := `(case.create (cbu.id "CBU-1")) (products.add "CUSTODY") (products.add "FUND_ACCOUNTING")`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT current_state FROM "dsl-ob-poc".onboarding_sessions WHERE cbu_id = \$1 FOR UPDATE`).
+		WithArgs("CBU-1").
+		WillReturnRows(sqlmock.NewRows([]string{"current_state"}).AddRow("CREATED"))
+	mock.ExpectQuery(`INSERT INTO "dsl-ob-poc".dsl_ob .* RETURNING version_id, version_number`).
+		WithArgs("CBU-1", newDSL, StateProductsAdded, "v2").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id", "version_number"}).AddRow("ver-2", 2))
+	mock.ExpectQuery(`SELECT dsl_text, onboarding_state FROM "dsl-ob-poc".dsl_ob WHERE cbu_id = \$1 AND version_number < \$2`).
+		WithArgs("CBU-1", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"dsl_text", "onboarding_state"}).AddRow(oldDSL, "CREATED"))
+	mock.ExpectExec(`WITH event AS \( INSERT INTO "dsl-ob-poc".dsl_change_events .* INSERT INTO "dsl-ob-poc".webhook_deliveries`).
+		WithArgs(EventDSLVersionCreated, "CBU-1", "ver-2", 2, StateCreated, StateProductsAdded, `{"products.add"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	versionID, err := store.InsertDSLWithGrammar(context.Background(), "CBU-1", newDSL, StateProductsAdded, "v2")
+	if err != nil {
+		t.Fatalf("InsertDSLWithGrammar failed: %v", err)
+	}
+	if versionID != "ver-2" {
+		t.Errorf("versionID = %q, want ver-2", versionID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestInsertDSLWithGrammar_RejectsInvalidTransition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT current_state FROM "dsl-ob-poc".onboarding_sessions`).
+		WithArgs("CBU-1").
+		WillReturnRows(sqlmock.NewRows([]string{"current_state"}).AddRow("PRODUCTS_ADDED"))
+	mock.ExpectRollback()
+
+	_, err = store.InsertDSLWithGrammar(context.Background(), "CBU-1", `(case.close)`, StateCompleted, "")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateOnboardingState_RollsBackWhenOutboxFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "dsl-ob-poc".onboarding_sessions s .* FOR UPDATE .* RETURNING old.current_state`).
+		WithArgs(StateKYCDiscovered, "ver-3", "CBU-1").
+		WillReturnRows(sqlmock.NewRows([]string{"current_state"}).AddRow("PRODUCTS_ADDED"))
+	mock.ExpectQuery(`SELECT version_number, dsl_text FROM "dsl-ob-poc".dsl_ob WHERE version_id = \$1`).
+		WithArgs("ver-3").
+		WillReturnRows(sqlmock.NewRows([]string{"version_number", "dsl_text"}).AddRow(3, `(kyc.start)`))
+	mock.ExpectQuery(`SELECT dsl_text, onboarding_state FROM "dsl-ob-poc".dsl_ob`).
+		WithArgs("CBU-1", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"dsl_text", "onboarding_state"}))
+	mock.ExpectExec(`WITH event AS`).
+		WithArgs(EventOnboardingStateChanged, "CBU-1", "ver-3", 3, StateProductsAdded, StateKYCDiscovered, `{"kyc.start"}`).
+		WillReturnError(errors.New("relation does not exist"))
+	mock.ExpectRollback()
+
+	err = store.UpdateOnboardingState(context.Background(), "CBU-1", StateKYCDiscovered, "ver-3")
+	if err == nil {
+		t.Fatal("expected the state change to fail with its outbox event")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateOnboardingState_RejectsInvalidTransition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "dsl-ob-poc".onboarding_sessions s`).
+		WithArgs(StateCompleted, "ver-2", "CBU-1").
+		WillReturnRows(sqlmock.NewRows([]string{"current_state"}).AddRow("PRODUCTS_ADDED"))
+	mock.ExpectQuery(`SELECT version_number, dsl_text FROM "dsl-ob-poc".dsl_ob WHERE version_id = \$1`).
+		WithArgs("ver-2").
+		WillReturnRows(sqlmock.NewRows([]string{"version_number", "dsl_text"}).AddRow(2, `(products.add "CUSTODY")`))
+	mock.ExpectRollback()
+
+	err = store.UpdateOnboardingState(context.Background(), "CBU-1", StateCompleted, "ver-2")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateOnboardingState_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "dsl-ob-poc".onboarding_sessions s`).
+		WillReturnRows(sqlmock.NewRows([]string{"current_state"}))
+	mock.ExpectRollback()
+
+	err = store.UpdateOnboardingState(context.Background(), "CBU-404", StateKYCDiscovered, "ver-1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestChangedVerbs(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{"first version", "", `(case.create (cbu.id "C"))`, []string{"case.create", "cbu.id"}},
+		{"added use of a verb", `(products.add "A")`, `(products.add "A") (products.add "B")`, []string{"products.add"}},
+		{"removed verb", `(kyc.start) (services.discover)`, `(kyc.start)`, []string{"services.discover"}},
+		{"unchanged", `(kyc.start (documents (document "W8BEN")))`, `(kyc.start (documents (document "W8BEN")))`, []string{}},
+		{"unparseable old version", `(kyc.start`, `(kyc.start)`, []string{"kyc.start"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangedVerbs(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChangedVerbs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// GetInvestorState returns the state document the IR plan interpreter keeps
+// for an investor of a CBU
+func (s *Store) GetInvestorState(ctx context.Context, cbuID, investorID string) (json.RawMessage, error) {
+	var state json.RawMessage
+	err := s.db.QueryRowContext(ctx, `
+		SELECT state FROM "dsl-ob-poc".ir_investor_states
+		WHERE cbu_id = $1 AND investor_id = $2`,
+		cbuID, investorID).Scan(&state)
+	if err == sql.ErrNoRows {
+		return nil, NotFoundf("investor %s has no state for CBU %s", investorID, cbuID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investor state: %w", err)
+	}
+	return state, nil
+}
+
+// SaveInvestorState stores an investor's state document, replacing the
+// previous one. source records what wrote it.
+func (s *Store) SaveInvestorState(ctx context.Context, cbuID, investorID string, state json.RawMessage, source map[string]any) error {
+	srcJSON, err := json.Marshal(source)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO "dsl-ob-poc".ir_investor_states (cbu_id, investor_id, state, source)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cbu_id, investor_id)
+		DO UPDATE SET state = EXCLUDED.state, source = EXCLUDED.source, updated_at = NOW()`,
+		cbuID, investorID, []byte(state), string(srcJSON))
+	if err != nil {
+		return fmt.Errorf("failed to save investor state: %w", err)
+	}
+	return nil
+}
@@ -3,8 +3,11 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
+
+	"dsl-ob-poc/internal/statemachine"
 )
 
 // OnboardingState represents the different stages of onboarding progression
@@ -20,6 +23,37 @@ const (
 	StateCompleted           OnboardingState = "COMPLETED"
 )
 
+// ErrInvalidTransition is matched by errors.Is when a session is moved to a
+// state the onboarding state machine does not allow from its current one
+var ErrInvalidTransition = errors.New("invalid onboarding state transition")
+
+// ParseOnboardingState maps a state name from any vocabulary of the shared
+// onboarding state machine to the state it is persisted as
+func ParseOnboardingState(name string) (OnboardingState, error) {
+	projected, err := statemachine.Onboarding().Project(name, statemachine.ProjectionStore)
+	if err != nil {
+		return "", err
+	}
+	return OnboardingState(projected), nil
+}
+
+// ValidateTransition reports whether a session may move from s to next.
+// Staying in the same state is always allowed; other moves must be
+// transitions of the shared onboarding state machine.
+func (s OnboardingState) ValidateTransition(next OnboardingState) error {
+	if s == next {
+		return nil
+	}
+	machine := statemachine.Onboarding()
+	if projected, err := machine.Project(string(next), statemachine.ProjectionStore); err != nil || projected != string(next) {
+		return fmt.Errorf("%w from %s to %s: %s is not a stored onboarding state", ErrInvalidTransition, s, next, next)
+	}
+	if err := machine.CanTransition(string(s), string(next)); err != nil {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
 // OnboardingSession represents an active onboarding session
 type OnboardingSession struct {
 	OnboardingID       string          `json:"onboarding_id"`
@@ -38,6 +72,7 @@ type DSLVersionWithState struct {
 	DSLText         string          `json:"dsl_text"`
 	OnboardingState OnboardingState `json:"onboarding_state"`
 	VersionNumber   int             `json:"version_number"`
+	GrammarVersion  string          `json:"grammar_version,omitempty"` // grammar that validated this version; empty if unvalidated
 	CreatedAt       time.Time       `json:"created_at"`
 }
 
@@ -81,7 +116,7 @@ func (s *Store) GetOnboardingSession(ctx context.Context, cbuID string) (*Onboar
 		&session.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no onboarding session found for CBU: %s", cbuID)
+		return nil, NotFoundf("no onboarding session found for CBU: %s", cbuID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get onboarding session: %w", err)
@@ -90,46 +125,152 @@ func (s *Store) GetOnboardingSession(ctx context.Context, cbuID string) (*Onboar
 	return &session, nil
 }
 
-// UpdateOnboardingState updates the state and version of an onboarding session
+// UpdateOnboardingState updates the state and version of an onboarding
+// session, writing an ONBOARDING_STATE_CHANGED outbox event in the same
+// transaction. Moves the onboarding state machine does not allow are rolled
+// back with ErrInvalidTransition.
 func (s *Store) UpdateOnboardingState(ctx context.Context, cbuID string, newState OnboardingState, dslVersionID string) error {
-	result, err := s.db.ExecContext(ctx, `
-		UPDATE "dsl-ob-poc".onboarding_sessions
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var oldState OnboardingState
+	err = tx.QueryRowContext(ctx, `
+		UPDATE "dsl-ob-poc".onboarding_sessions s
 		SET current_state = $1,
-		    current_version = current_version + 1,
+		    current_version = s.current_version + 1,
 		    latest_dsl_version_id = $2,
 		    updated_at = (now() at time zone 'utc')
-		WHERE cbu_id = $3`,
-		newState, dslVersionID, cbuID)
+		FROM (
+			SELECT cbu_id, current_state
+			FROM "dsl-ob-poc".onboarding_sessions
+			WHERE cbu_id = $3
+			FOR UPDATE
+		) old
+		WHERE s.cbu_id = old.cbu_id
+		RETURNING old.current_state`,
+		newState, dslVersionID, cbuID).Scan(&oldState)
 
+	if err == sql.ErrNoRows {
+		return NotFoundf("no onboarding session found for CBU: %s", cbuID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update onboarding state: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	var versionNumber int
+	var dslText string
+	err = tx.QueryRowContext(ctx, `
+		SELECT version_number, dsl_text
+		FROM "dsl-ob-poc".dsl_ob
+		WHERE version_id = $1`,
+		dslVersionID).Scan(&versionNumber, &dslText)
+	if err == sql.ErrNoRows {
+		return NotFoundf("DSL version not found: %s", dslVersionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get DSL version: %w", err)
+	}
+
+	if err := oldState.ValidateTransition(newState); err != nil {
+		return err
+	}
+
+	previousText, _, err := previousDSL(ctx, tx, cbuID, versionNumber)
 	if err != nil {
-		return fmt.Errorf("failed to check affected rows: %w", err)
+		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("no onboarding session found for CBU: %s", cbuID)
+	if err := recordDSLChange(ctx, tx, &DSLChangeEvent{
+		EventType:     EventOnboardingStateChanged,
+		CBUID:         cbuID,
+		VersionID:     dslVersionID,
+		VersionNumber: versionNumber,
+		OldState:      oldState,
+		NewState:      newState,
+		ChangedVerbs:  ChangedVerbs(previousText, dslText),
+	}); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit onboarding state: %w", err)
+	}
 	return nil
 }
 
 // InsertDSLWithState inserts a new DSL version with state information
 func (s *Store) InsertDSLWithState(ctx context.Context, cbuID, dslText string, state OnboardingState) (string, error) {
+	return s.InsertDSLWithGrammar(ctx, cbuID, dslText, state, "")
+}
+
+// InsertDSLWithGrammar inserts a new DSL version with state information and
+// the version of the grammar that validated it (empty stores NULL), writing a
+// DSL_VERSION_CREATED outbox event in the same transaction. When the CBU has
+// an onboarding session, the new state must be a valid transition from the
+// session's current state or nothing is written.
+func (s *Store) InsertDSLWithGrammar(ctx context.Context, cbuID, dslText string, state OnboardingState, grammarVersion string) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var currentState OnboardingState
+	err = tx.QueryRowContext(ctx, `
+		SELECT current_state
+		FROM "dsl-ob-poc".onboarding_sessions
+		WHERE cbu_id = $1
+		FOR UPDATE`,
+		cbuID).Scan(&currentState)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return "", fmt.Errorf("failed to get onboarding state: %w", err)
+	default:
+		if err := currentState.ValidateTransition(state); err != nil {
+			return "", err
+		}
+	}
+
 	var versionID string
-	err := s.db.QueryRowContext(ctx, `
-		INSERT INTO "dsl-ob-poc".dsl_ob (cbu_id, dsl_text, onboarding_state)
-		VALUES ($1, $2, $3)
-		RETURNING version_id`,
-		cbuID, dslText, state).Scan(&versionID)
+	var versionNumber int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO "dsl-ob-poc".dsl_ob (cbu_id, dsl_text, onboarding_state, grammar_version)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		RETURNING version_id, version_number`,
+		cbuID, dslText, state, grammarVersion).Scan(&versionID, &versionNumber)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to insert DSL with state: %w", err)
 	}
 
+	previousText, previousState, err := previousDSL(ctx, tx, cbuID, versionNumber)
+	if err != nil {
+		return "", err
+	}
+
+	if err := recordDSLChange(ctx, tx, &DSLChangeEvent{
+		EventType:     EventDSLVersionCreated,
+		CBUID:         cbuID,
+		VersionID:     versionID,
+		VersionNumber: versionNumber,
+		OldState:      previousState,
+		NewState:      state,
+		ChangedVerbs:  ChangedVerbs(previousText, dslText),
+	}); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit DSL version: %w", err)
+	}
 	return versionID, nil
 }
 
@@ -137,7 +278,7 @@ func (s *Store) InsertDSLWithState(ctx context.Context, cbuID, dslText string, s
 func (s *Store) GetLatestDSLWithState(ctx context.Context, cbuID string) (*DSLVersionWithState, error) {
 	var dslVersion DSLVersionWithState
 	err := s.db.QueryRowContext(ctx, `
-		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, created_at
+		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, COALESCE(grammar_version, ''), created_at
 		FROM "dsl-ob-poc".dsl_ob
 		WHERE cbu_id = $1
 		ORDER BY version_number DESC
@@ -148,10 +289,11 @@ func (s *Store) GetLatestDSLWithState(ctx context.Context, cbuID string) (*DSLVe
 		&dslVersion.DSLText,
 		&dslVersion.OnboardingState,
 		&dslVersion.VersionNumber,
+		&dslVersion.GrammarVersion,
 		&dslVersion.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no DSL found for CBU: %s", cbuID)
+		return nil, NotFoundf("no DSL found for CBU: %s", cbuID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest DSL with state: %w", err)
@@ -163,7 +305,7 @@ func (s *Store) GetLatestDSLWithState(ctx context.Context, cbuID string) (*DSLVe
 // GetDSLHistoryWithState returns all DSL versions with state information
 func (s *Store) GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]DSLVersionWithState, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, created_at
+		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, COALESCE(grammar_version, ''), created_at
 		FROM "dsl-ob-poc".dsl_ob
 		WHERE cbu_id = $1
 		ORDER BY version_number ASC`,
@@ -183,6 +325,7 @@ func (s *Store) GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]DSL
 			&version.DSLText,
 			&version.OnboardingState,
 			&version.VersionNumber,
+			&version.GrammarVersion,
 			&version.CreatedAt); scanErr != nil {
 			return nil, fmt.Errorf("failed to scan DSL version: %w", scanErr)
 		}
@@ -200,7 +343,7 @@ func (s *Store) GetDSLHistoryWithState(ctx context.Context, cbuID string) ([]DSL
 func (s *Store) GetDSLByVersion(ctx context.Context, cbuID string, versionNumber int) (*DSLVersionWithState, error) {
 	var dslVersion DSLVersionWithState
 	err := s.db.QueryRowContext(ctx, `
-		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, created_at
+		SELECT version_id, cbu_id, dsl_text, onboarding_state, version_number, COALESCE(grammar_version, ''), created_at
 		FROM "dsl-ob-poc".dsl_ob
 		WHERE cbu_id = $1 AND version_number = $2`,
 		cbuID, versionNumber).Scan(
@@ -209,10 +352,11 @@ func (s *Store) GetDSLByVersion(ctx context.Context, cbuID string, versionNumber
 		&dslVersion.DSLText,
 		&dslVersion.OnboardingState,
 		&dslVersion.VersionNumber,
+		&dslVersion.GrammarVersion,
 		&dslVersion.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no DSL version %d found for CBU: %s", versionNumber, cbuID)
+		return nil, NotFoundf("no DSL version %d found for CBU: %s", versionNumber, cbuID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get DSL version: %w", err)
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"dsl-ob-poc/internal/entities"
+
+	"github.com/google/uuid"
+)
+
+// LoadOwnershipGraph retrieves the entity registry together with every active
+// partnership interest, trust party and the UBO relationships registered for
+// the CBU. Rows written by the UBO engine itself (RECURSIVE_ANALYSIS) are
+// results, not inputs, and are left out. The UBO engine builds its ownership
+// graph from this snapshot.
+func (s *Store) LoadOwnershipGraph(ctx context.Context, cbuID string) (*entities.OwnershipGraphData, error) {
+	data := &entities.OwnershipGraphData{}
+
+	var err error
+	if data.Entities, err = s.loadGraphEntities(ctx); err != nil {
+		return nil, err
+	}
+	if data.PartnershipInterests, err = s.loadPartnershipInterests(ctx); err != nil {
+		return nil, err
+	}
+	if data.TrustParties, err = s.loadTrustParties(ctx); err != nil {
+		return nil, err
+	}
+	if data.UBORegistry, err = s.loadUBORegistry(ctx, cbuID); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// loadGraphEntities retrieves all registered entities with their entity type
+func (s *Store) loadGraphEntities(ctx context.Context) ([]entities.Entity, error) {
+	query := `SELECT e.entity_id, e.entity_type_id, e.external_id, e.name,
+	                 et.name, et.description, et.table_name
+	         FROM "dsl-ob-poc".entities e
+	         JOIN "dsl-ob-poc".entity_types et ON e.entity_type_id = et.entity_type_id
+	         ORDER BY e.name`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entities: %w", err)
+	}
+	defer rows.Close()
+
+	var result []entities.Entity
+	for rows.Next() {
+		var e entities.Entity
+		var externalID, typeDescription sql.NullString
+		entityType := &entities.EntityType{}
+
+		if scanErr := rows.Scan(&e.EntityID, &e.EntityTypeID, &externalID, &e.Name,
+			&entityType.Name, &typeDescription, &entityType.TableName); scanErr != nil {
+			return nil, fmt.Errorf("failed to scan entity: %w", scanErr)
+		}
+
+		if externalID.Valid {
+			e.ExternalID = &externalID.String
+		}
+		entityType.EntityTypeID = e.EntityTypeID
+		entityType.Description = typeDescription.String
+		e.EntityType = entityType
+
+		result = append(result, e)
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, fmt.Errorf("error iterating entities: %w", rowsErr)
+	}
+
+	return result, nil
+}
+
+// loadPartnershipInterests retrieves all active partnership interests
+func (s *Store) loadPartnershipInterests(ctx context.Context) ([]entities.PartnershipInterest, error) {
+	query := `SELECT interest_id, partnership_id, entity_id, partner_type,
+	                 capital_commitment, ownership_percentage, voting_rights,
+	                 profit_sharing_percentage, withdrawal_date, is_active
+	         FROM "dsl-ob-poc".partnership_interests
+	         WHERE is_active = TRUE`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load partnership interests: %w", err)
+	}
+	defer rows.Close()
+
+	var result []entities.PartnershipInterest
+	for rows.Next() {
+		var pi entities.PartnershipInterest
+		var capital, ownership, voting, profit sql.NullFloat64
+		var withdrawal sql.NullTime
+
+		if scanErr := rows.Scan(&pi.InterestID, &pi.PartnershipID, &pi.EntityID, &pi.PartnerType,
+			&capital, &ownership, &voting, &profit, &withdrawal, &pi.IsActive); scanErr != nil {
+			return nil, fmt.Errorf("failed to scan partnership interest: %w", scanErr)
+		}
+
+		pi.CapitalCommitment = nullFloatPtr(capital)
+		pi.OwnershipPercentage = nullFloatPtr(ownership)
+		pi.VotingRights = nullFloatPtr(voting)
+		pi.ProfitSharingPercentage = nullFloatPtr(profit)
+		if withdrawal.Valid {
+			pi.WithdrawalDate = &withdrawal.Time
+		}
+
+		result = append(result, pi)
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, fmt.Errorf("error iterating partnership interests: %w", rowsErr)
+	}
+
+	return result, nil
+}
+
+// loadTrustParties retrieves all active trust parties
+func (s *Store) loadTrustParties(ctx context.Context) ([]entities.TrustParty, error) {
+	query := `SELECT trust_party_id, trust_id, entity_id, party_role, party_type,
+	                 resignation_date, is_active
+	         FROM "dsl-ob-poc".trust_parties
+	         WHERE is_active = TRUE`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trust parties: %w", err)
+	}
+	defer rows.Close()
+
+	var result []entities.TrustParty
+	for rows.Next() {
+		var tp entities.TrustParty
+		var resignation sql.NullTime
+
+		if scanErr := rows.Scan(&tp.TrustPartyID, &tp.TrustID, &tp.EntityID, &tp.PartyRole,
+			&tp.PartyType, &resignation, &tp.IsActive); scanErr != nil {
+			return nil, fmt.Errorf("failed to scan trust party: %w", scanErr)
+		}
+
+		if resignation.Valid {
+			tp.ResignationDate = &resignation.Time
+		}
+
+		result = append(result, tp)
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, fmt.Errorf("error iterating trust parties: %w", rowsErr)
+	}
+
+	return result, nil
+}
+
+// loadUBORegistry retrieves the ownership and control relationships registered
+// for a CBU, excluding calculated UBO rows
+func (s *Store) loadUBORegistry(ctx context.Context, cbuID string) ([]entities.UBORegistry, error) {
+	query := `SELECT ubo_id, cbu_id, subject_entity_id, ubo_proper_person_id,
+	                 relationship_type, qualifying_reason, ownership_percentage,
+	                 control_type, workflow_type
+	         FROM "dsl-ob-poc".ubo_registry
+	         WHERE cbu_id = $1 AND workflow_type <> $2`
+
+	rows, err := s.db.QueryContext(ctx, query, cbuID, entities.UBOWorkflowRecursiveAnalysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load UBO registry: %w", err)
+	}
+	defer rows.Close()
+
+	var result []entities.UBORegistry
+	for rows.Next() {
+		var r entities.UBORegistry
+		var ownership sql.NullFloat64
+		var controlType sql.NullString
+
+		if scanErr := rows.Scan(&r.UBOID, &r.CBUID, &r.SubjectEntityID, &r.UBOProperPersonID,
+			&r.RelationshipType, &r.QualifyingReason, &ownership, &controlType, &r.WorkflowType); scanErr != nil {
+			return nil, fmt.Errorf("failed to scan UBO registry entry: %w", scanErr)
+		}
+
+		r.OwnershipPercentage = nullFloatPtr(ownership)
+		if controlType.Valid {
+			r.ControlType = &controlType.String
+		}
+
+		result = append(result, r)
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, fmt.Errorf("error iterating UBO registry: %w", rowsErr)
+	}
+
+	return result, nil
+}
+
+// SaveCalculatedUBOs replaces the calculated UBO rows of a CBU's subject entity.
+// A registered (input) relationship with the same subject, person and type is
+// left untouched: the relationship is already on record.
+func (s *Store) SaveCalculatedUBOs(ctx context.Context, cbuID string, subjectEntityID uuid.UUID, entries []entities.UBORegistry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM "dsl-ob-poc".ubo_registry
+		WHERE cbu_id = $1 AND subject_entity_id = $2 AND workflow_type = $3`,
+		cbuID, subjectEntityID, entities.UBOWorkflowRecursiveAnalysis); err != nil {
+		return fmt.Errorf("failed to clear calculated UBOs: %w", err)
+	}
+
+	for _, e := range entries {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO "dsl-ob-poc".ubo_registry
+			(ubo_id, cbu_id, subject_entity_id, ubo_proper_person_id, relationship_type,
+			 qualifying_reason, ownership_percentage, control_type, workflow_type,
+			 regulatory_framework, verification_status, screening_result, identified_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (subject_entity_id, ubo_proper_person_id, relationship_type) DO NOTHING`,
+			e.UBOID, cbuID, e.SubjectEntityID, e.UBOProperPersonID, e.RelationshipType,
+			e.QualifyingReason, e.OwnershipPercentage, e.ControlType, e.WorkflowType,
+			e.RegulatoryFramework, e.VerificationStatus, e.ScreeningResult, e.IdentifiedAt); err != nil {
+			return fmt.Errorf("failed to insert calculated UBO: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit calculated UBOs: %w", err)
+	}
+	return nil
+}
+
+// nullFloatPtr converts a nullable float column into an optional value
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
@@ -17,6 +17,19 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// ErrNotFound is matched by errors.Is for every lookup of a missing record
+var ErrNotFound = errors.New("not found")
+
+type notFoundError struct{ msg string }
+
+func (e *notFoundError) Error() string        { return e.msg }
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// NotFoundf formats a missing-record error that matches ErrNotFound
+func NotFoundf(format string, args ...any) error {
+	return &notFoundError{msg: fmt.Sprintf(format, args...)}
+}
+
 // Store represents the database connection and operations.
 type Store struct {
 	db *sql.DB
@@ -466,10 +479,17 @@ func (s *Store) SeedCatalog(ctx context.Context) error {
 
 // InsertDSL inserts a new DSL version and returns its version ID.
 func (s *Store) InsertDSL(ctx context.Context, cbuID, dslText string) (string, error) {
+	return s.InsertPlainDSL(ctx, cbuID, dslText, "")
+}
+
+// InsertPlainDSL inserts a new DSL version like InsertDSL, leaving the
+// onboarding state unset, and records the grammar version that validated it
+// (empty stores NULL).
+func (s *Store) InsertPlainDSL(ctx context.Context, cbuID, dslText, grammarVersion string) (string, error) {
 	var versionID string
 	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO "dsl-ob-poc".dsl_ob (cbu_id, dsl_text) VALUES ($1, $2) RETURNING version_id`,
-		cbuID, dslText).Scan(&versionID)
+		`INSERT INTO "dsl-ob-poc".dsl_ob (cbu_id, dsl_text, grammar_version) VALUES ($1, $2, NULLIF($3, '')) RETURNING version_id`,
+		cbuID, dslText, grammarVersion).Scan(&versionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to insert DSL: %w", err)
 	}
@@ -486,7 +506,7 @@ func (s *Store) GetLatestDSL(ctx context.Context, cbuID string) (string, error)
 		 LIMIT 1`,
 		cbuID).Scan(&dslText)
 	if err == sql.ErrNoRows {
-		return "", fmt.Errorf("no DSL found for CBU_ID: %s", cbuID)
+		return "", NotFoundf("no DSL found for CBU_ID: %s", cbuID)
 	}
 	if err != nil {
 		return "", fmt.Errorf("failed to get latest DSL: %w", err)
@@ -542,7 +562,7 @@ func (s *Store) getDictionaryAttribute(ctx context.Context, whereClause string,
 		&attr.Mask, &attr.Domain, &attr.Vector, &sourceJSON, &sinkJSON)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf(notFoundMsg, param)
+		return nil, NotFoundf(notFoundMsg, param)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get attribute: %w", err)
@@ -569,6 +589,27 @@ func (s *Store) GetDictionaryAttributeByID(ctx context.Context, id string) (*dic
 	return s.getDictionaryAttribute(ctx, "attribute_id = $1", id, "attribute with ID '%s' not found in dictionary")
 }
 
+// UpdateDictionaryAttributeVector stores the encoded semantic vector for an attribute
+func (s *Store) UpdateDictionaryAttributeVector(ctx context.Context, attributeID, vector string) error {
+	query := `UPDATE "dsl-ob-poc".dictionary SET vector = $2, updated_at = NOW() WHERE attribute_id = $1`
+
+	result, err := s.db.ExecContext(ctx, query, attributeID, vector)
+	if err != nil {
+		return fmt.Errorf("failed to update attribute vector: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return NotFoundf("attribute with ID '%s' not found in dictionary", attributeID)
+	}
+
+	return nil
+}
+
 // GetCBUByName retrieves a CBU by name from the catalog
 func (s *Store) GetCBUByName(ctx context.Context, name string) (*CBU, error) {
 	var cbu CBU
@@ -576,7 +617,7 @@ func (s *Store) GetCBUByName(ctx context.Context, name string) (*CBU, error) {
 		`SELECT cbu_id, name, description, nature_purpose FROM "dsl-ob-poc".cbus WHERE name = $1`,
 		name).Scan(&cbu.CBUID, &cbu.Name, &cbu.Description, &cbu.NaturePurpose)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("CBU '%s' not found in catalog", name)
+		return nil, NotFoundf("CBU '%s' not found in catalog", name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get CBU: %w", err)
@@ -584,8 +625,27 @@ func (s *Store) GetCBUByName(ctx context.Context, name string) (*CBU, error) {
 	return &cbu, nil
 }
 
-// ResolveValueFor resolves attribute values using source metadata
+// ResolveValueFor returns the value stored for the CBU's latest DSL version,
+// or otherwise resolves the attribute using its source metadata
 func (s *Store) ResolveValueFor(ctx context.Context, cbuID, attributeID string) (payload json.RawMessage, provenance map[string]any, status string, err error) {
+	var sourceText string
+	err = s.db.QueryRowContext(ctx, `
+		SELECT value, COALESCE(source::text, '{}'), state
+		FROM "dsl-ob-poc".attribute_values
+		WHERE cbu_id = $1 AND attribute_id = $2
+		ORDER BY dsl_version DESC
+		LIMIT 1`,
+		cbuID, attributeID).Scan(&payload, &sourceText, &status)
+	if err == nil {
+		if parseErr := json.Unmarshal([]byte(sourceText), &provenance); parseErr != nil {
+			return nil, nil, "", fmt.Errorf("failed to parse attribute value source: %w", parseErr)
+		}
+		return payload, provenance, status, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, nil, "", fmt.Errorf("failed to get attribute value: %w", err)
+	}
+
 	a, err := s.GetDictionaryAttributeByID(ctx, attributeID)
 	if err != nil {
 		return nil, nil, "", err
@@ -642,7 +702,7 @@ func (s *Store) GetProductByName(ctx context.Context, name string) (*Product, er
 		`SELECT product_id, name, description FROM "dsl-ob-poc".products WHERE name = $1`,
 		name).Scan(&p.ProductID, &p.Name, &p.Description)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("product '%s' not found in catalog", name)
+		return nil, NotFoundf("product '%s' not found in catalog", name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
@@ -686,7 +746,7 @@ func (s *Store) GetServiceByName(ctx context.Context, name string) (*Service, er
 		`SELECT service_id, name, description FROM "dsl-ob-poc".services WHERE name = $1`,
 		name).Scan(&srv.ServiceID, &srv.Name, &srv.Description)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("service '%s' not found in catalog", name)
+		return nil, NotFoundf("service '%s' not found in catalog", name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get service: %w", err)
@@ -848,7 +908,7 @@ func (s *Store) LoadOrchestrationSession(ctx context.Context, sessionID string)
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("orchestration session not found: %s", sessionID)
+			return nil, NotFoundf("orchestration session not found: %s", sessionID)
 		}
 		return nil, fmt.Errorf("failed to load orchestration session: %w", err)
 	}
@@ -935,7 +995,7 @@ func (s *Store) DeleteOrchestrationSession(ctx context.Context, sessionID string
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("session not found: %s", sessionID)
+		return NotFoundf("session not found: %s", sessionID)
 	}
 
 	return nil
@@ -977,7 +1037,7 @@ func (s *Store) UpdateOrchestrationSessionDSL(ctx context.Context, sessionID, ds
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("session not found: %s", sessionID)
+		return NotFoundf("session not found: %s", sessionID)
 	}
 
 	return nil
@@ -1277,7 +1337,7 @@ func (s *Store) GetCBUByID(ctx context.Context, cbuID string) (*CBU, error) {
 		&cbu.CBUID, &cbu.Name, &cbu.Description, &cbu.NaturePurpose)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("CBU not found: %s", cbuID)
+			return nil, NotFoundf("CBU not found: %s", cbuID)
 		}
 		return nil, fmt.Errorf("failed to get CBU: %w", err)
 	}
@@ -1331,7 +1391,7 @@ func (s *Store) UpdateCBU(ctx context.Context, cbuID, name, description, natureP
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("CBU not found: %s", cbuID)
+		return NotFoundf("CBU not found: %s", cbuID)
 	}
 
 	return nil
@@ -1352,7 +1412,7 @@ func (s *Store) DeleteCBU(ctx context.Context, cbuID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("CBU not found: %s", cbuID)
+		return NotFoundf("CBU not found: %s", cbuID)
 	}
 
 	return nil
@@ -1415,7 +1475,7 @@ func (s *Store) GetRoleByID(ctx context.Context, roleID string) (*Role, error) {
 		&role.RoleID, &role.Name, &role.Description)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("role not found: %s", roleID)
+			return nil, NotFoundf("role not found: %s", roleID)
 		}
 		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
@@ -1464,7 +1524,7 @@ func (s *Store) UpdateRole(ctx context.Context, roleID, name, description string
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("role not found: %s", roleID)
+		return NotFoundf("role not found: %s", roleID)
 	}
 
 	return nil
@@ -1485,7 +1545,7 @@ func (s *Store) DeleteRole(ctx context.Context, roleID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("role not found: %s", roleID)
+		return NotFoundf("role not found: %s", roleID)
 	}
 
 	return nil
@@ -1860,7 +1920,7 @@ func (s *Store) GetProductRequirements(ctx context.Context, productID string) (*
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product requirements not found for product %s", productID)
+			return nil, NotFoundf("product requirements not found for product %s", productID)
 		}
 		return nil, fmt.Errorf("failed to get product requirements: %w", err)
 	}
@@ -1906,7 +1966,7 @@ func (s *Store) GetEntityProductMapping(ctx context.Context, entityType, product
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("entity-product mapping not found for %s-%s", entityType, productID)
+			return nil, NotFoundf("entity-product mapping not found for %s-%s", entityType, productID)
 		}
 		return nil, fmt.Errorf("failed to get entity-product mapping: %w", err)
 	}
@@ -2064,7 +2124,7 @@ func (s *Store) UpdateProductRequirements(ctx context.Context, req *ProductRequi
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("product requirements not found for product %s", req.ProductID)
+		return NotFoundf("product requirements not found for product %s", req.ProductID)
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"testing"
 
@@ -206,6 +207,11 @@ func TestResolveValueFor_NoResolver(t *testing.T) {
 	store := &Store{db: db}
 	ctx := context.Background()
 
+	// No value has been stored for the CBU yet
+	mock.ExpectQuery(`SELECT value, .* FROM "dsl-ob-poc".attribute_values`).
+		WithArgs("CBU-1234", "123e4567-e89b-12d3-a456-426614174000").
+		WillReturnError(sql.ErrNoRows)
+
 	// Mock get attribute by ID - returns manual source (no table resolver)
 	attrRows := sqlmock.NewRows([]string{
 		"attribute_id", "name", "long_description", "group_id", "mask", "domain", "vector", "source", "sink",
@@ -250,3 +256,32 @@ func TestResolveValueFor_NoResolver(t *testing.T) {
 		t.Errorf("There were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestResolveValueFor_StoredValue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock DB: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+	ctx := context.Background()
+
+	// The value of the latest DSL version is returned without consulting the dictionary
+	mock.ExpectQuery(`SELECT value, .* FROM "dsl-ob-poc".attribute_values\s+WHERE cbu_id = \$1 AND attribute_id = \$2\s+ORDER BY dsl_version DESC`).
+		WithArgs("CBU-1234", "123e4567-e89b-12d3-a456-426614174000").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "source", "state"}).
+			AddRow([]byte(`"LU"`), `{"type": "agent"}`, "resolved"))
+
+	value, prov, state, err := store.ResolveValueFor(ctx, "CBU-1234", "123e4567-e89b-12d3-a456-426614174000")
+	if err != nil {
+		t.Fatalf("ResolveValueFor failed: %v", err)
+	}
+	if string(value) != `"LU"` || state != "resolved" || prov["type"] != "agent" {
+		t.Errorf("Expected stored value, got %s (%s, %v)", value, state, prov)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package webhooks delivers DSL change events from the outbox to registered
+// webhook subscribers.
+//
+// Each delivery is an HTTP POST of the event as JSON, signed with the
+// subscription's secret:
+//
+//	X-DSL-Timestamp: <unix seconds>
+//	X-DSL-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// Subscribers should recompute the signature with Verify and reject stale
+// timestamps. A 2xx response acknowledges the event; other responses and
+// transport errors are retried with exponential backoff until the
+// subscription's max attempts are used up, except for 4xx responses other
+// than 408 and 429, which dead-letter the delivery at once. Retries mean
+// events can arrive out of order; subscribers order them by version_number.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"dsl-ob-poc/internal/store"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEventID   = "X-DSL-Event-ID"
+	HeaderEventType = "X-DSL-Event-Type"
+	HeaderDelivery  = "X-DSL-Delivery-ID"
+	HeaderAttempt   = "X-DSL-Delivery-Attempt"
+	HeaderTimestamp = "X-DSL-Timestamp"
+	HeaderSignature = "X-DSL-Signature"
+)
+
+// DeliveryQueue is the part of the data store the dispatcher leases
+// deliveries from; datastore.DataStore satisfies it
+type DeliveryQueue interface {
+	LeaseWebhookDelivery(ctx context.Context, owner string, lease time.Duration) (*store.WebhookDelivery, error)
+	CompleteWebhookDelivery(ctx context.Context, deliveryID, owner string) error
+	RetryWebhookDelivery(ctx context.Context, deliveryID, owner string, nextAttemptAt time.Time, lastError string) error
+	DeadLetterWebhookDelivery(ctx context.Context, deliveryID, owner, lastError string) error
+}
+
+// Config configures a dispatcher; zero values select the defaults
+type Config struct {
+	// Owner identifies the dispatcher in delivery leases (default host-pid)
+	Owner string
+	// LeaseDuration is how long a leased delivery stays claimed (default 1m);
+	// it must exceed Timeout so a slow subscriber is not delivered to twice
+	LeaseDuration time.Duration
+	// PollInterval is how long an idle dispatcher waits before polling again
+	// (default 2s)
+	PollInterval time.Duration
+	// Timeout bounds each HTTP request (default 10s)
+	Timeout time.Duration
+	// InitialBackoff is the delay before the second attempt; each further
+	// attempt doubles it up to MaxBackoff (defaults 10s and 1h)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Client sends the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// Dispatcher leases pending webhook deliveries and POSTs them to their
+// subscribers
+type Dispatcher struct {
+	queue  DeliveryQueue
+	config Config
+	now    func() time.Time
+}
+
+// NewDispatcher creates a dispatcher that takes deliveries from queue
+func NewDispatcher(queue DeliveryQueue, config Config) *Dispatcher {
+	if config.Owner == "" {
+		host, _ := os.Hostname()
+		config.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 10 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Dispatcher{queue: queue, config: config, now: time.Now}
+}
+
+// Run delivers events until ctx is cancelled. Errors from individual
+// deliveries are logged and do not stop the dispatcher.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	for {
+		processed, err := d.RunOnce(ctx)
+		if err != nil {
+			log.Printf("webhook-dispatcher %s: %v", d.config.Owner, err)
+		}
+		if processed && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(d.config.PollInterval):
+		}
+	}
+}
+
+// RunOnce leases and attempts at most one due delivery, reporting whether
+// there was one
+func (d *Dispatcher) RunOnce(ctx context.Context) (bool, error) {
+	delivery, err := d.queue.LeaseWebhookDelivery(ctx, d.config.Owner, d.config.LeaseDuration)
+	if err != nil {
+		return false, err
+	}
+	if delivery == nil {
+		return false, nil
+	}
+	return true, d.process(ctx, delivery)
+}
+
+// process makes one attempt at a leased delivery and records the outcome
+func (d *Dispatcher) process(ctx context.Context, delivery *store.WebhookDelivery) error {
+	// A dispatcher that died on the final attempt leaves the delivery
+	// leased with no attempts to spare
+	if delivery.Attempts > delivery.MaxAttempts {
+		return d.queue.DeadLetterWebhookDelivery(ctx, delivery.DeliveryID, d.config.Owner,
+			fmt.Sprintf("lease expired on final attempt: %s", deref(delivery.LastError)))
+	}
+
+	err := d.send(ctx, delivery)
+	if err == nil {
+		return d.queue.CompleteWebhookDelivery(ctx, delivery.DeliveryID, d.config.Owner)
+	}
+
+	var rejected *rejectedError
+	if errors.As(err, &rejected) || delivery.Attempts >= delivery.MaxAttempts {
+		return d.queue.DeadLetterWebhookDelivery(ctx, delivery.DeliveryID, d.config.Owner, err.Error())
+	}
+	next := d.now().Add(d.backoff(delivery.Attempts))
+	return d.queue.RetryWebhookDelivery(ctx, delivery.DeliveryID, d.config.Owner, next, err.Error())
+}
+
+// rejectedError is a response that retrying will not change
+type rejectedError struct{ msg string }
+
+func (e *rejectedError) Error() string { return e.msg }
+
+// send POSTs the delivery's event to its subscriber
+func (d *Dispatcher) send(ctx context.Context, delivery *store.WebhookDelivery) error {
+	if delivery.Event == nil {
+		return &rejectedError{msg: "delivery has no event"}
+	}
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return &rejectedError{msg: fmt.Sprintf("failed to marshal event: %v", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return &rejectedError{msg: fmt.Sprintf("invalid webhook URL: %v", err)}
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dsl-ob-poc-webhooks/1.0")
+	req.Header.Set(HeaderEventID, delivery.Event.EventID)
+	req.Header.Set(HeaderEventType, string(delivery.Event.EventType))
+	req.Header.Set(HeaderDelivery, delivery.DeliveryID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(delivery.Attempts))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if s := strings.TrimSpace(string(snippet)); s != "" {
+		msg += ": " + s
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &rejectedError{msg: msg}
+	}
+	return errors.New(msg)
+}
+
+// backoff returns the delay after the given (1-based) failed attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.InitialBackoff
+	for i := 1; i < attempt && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.MaxBackoff)
+}
+
+// Sign returns the X-DSL-Signature value for body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for body sent at timestamp and
+// the timestamp is within tolerance of now
+func Verify(secret, signature string, timestamp int64, body []byte, tolerance time.Duration) bool {
+	sent := time.Unix(timestamp, 0)
+	if age := time.Since(sent); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,181 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"dsl-ob-poc/internal/memstore"
+	"dsl-ob-poc/internal/store"
+)
+
+// subscriber records the events it accepts and answers with the queued
+// status codes, then 200
+type subscriber struct {
+	t        *testing.T
+	secret   string
+	mu       sync.Mutex
+	statuses []int
+	received []store.DSLChangeEvent
+}
+
+func (s *subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify(s.secret, r.Header.Get(HeaderSignature), timestamp, body, time.Minute) {
+		s.t.Errorf("invalid signature %q", r.Header.Get(HeaderSignature))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.statuses) > 0 {
+		status := s.statuses[0]
+		s.statuses = s.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	var event store.DSLChangeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		s.t.Errorf("invalid event body: %v", err)
+	}
+	s.received = append(s.received, event)
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	ds := memstore.New()
+
+	crm := &subscriber{t: t, secret: "crm-secret", statuses: []int{http.StatusServiceUnavailable}}
+	ops := &subscriber{t: t, secret: "ops-secret", statuses: []int{http.StatusGone}}
+	crmServer := httptest.NewServer(crm)
+	defer crmServer.Close()
+	opsServer := httptest.NewServer(ops)
+	defer opsServer.Close()
+
+	if _, err := ds.CreateWebhookSubscription(ctx, &store.WebhookSubscription{URL: crmServer.URL, Secret: "crm-secret", MaxAttempts: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.CreateWebhookSubscription(ctx, &store.WebhookSubscription{
+		URL: opsServer.URL, Secret: "ops-secret", MaxAttempts: 3,
+		EventTypes: []store.DSLEventType{store.EventOnboardingStateChanged},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ds.CreateOnboardingSession(ctx, "CBU-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.InsertDSLWithState(ctx, "CBU-1", `(case.create (cbu.id "CBU-1"))`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+	versionID, err := ds.InsertDSLWithState(ctx, "CBU-1", `(case.create (cbu.id "CBU-1")) (products.add "CUSTODY")`, store.StateProductsAdded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.UpdateOnboardingState(ctx, "CBU-1", store.StateProductsAdded, versionID); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewDispatcher(ds, Config{Owner: "test", InitialBackoff: time.Nanosecond, MaxBackoff: time.Nanosecond})
+	for i := 0; i < 10; i++ {
+		processed, err := dispatcher.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if !processed {
+			break
+		}
+	}
+
+	// The CRM gets all three events despite one 503; ops rejects its only one
+	if len(crm.received) != 3 {
+		t.Fatalf("CRM received %d events, want 3", len(crm.received))
+	}
+	// The retried event arrives after later ones
+	if first := crm.received[2]; first.EventType != store.EventDSLVersionCreated || first.VersionNumber != 1 {
+		t.Errorf("expected the retried first version last, got %+v", first)
+	}
+	change := crm.received[1]
+	if change.EventType != store.EventOnboardingStateChanged || change.NewState != store.StateProductsAdded ||
+		len(change.ChangedVerbs) != 1 || change.ChangedVerbs[0] != "products.add" {
+		t.Errorf("unexpected state change event: %+v", change)
+	}
+	if len(ops.received) != 0 {
+		t.Errorf("ops received %d events after answering 410", len(ops.received))
+	}
+
+	delivered, _ := ds.ListWebhookDeliveries(ctx, store.DeliveryDelivered)
+	dead, _ := ds.ListWebhookDeliveries(ctx, store.DeliveryDead)
+	if len(delivered) != 3 || len(dead) != 1 {
+		t.Fatalf("got %d delivered and %d dead deliveries, want 3 and 1", len(delivered), len(dead))
+	}
+	if dead[0].Attempts != 1 || dead[0].LastError == nil || *dead[0].LastError != "HTTP 410" {
+		t.Errorf("unexpected dead delivery: %+v", dead[0])
+	}
+}
+
+func TestDispatcher_DeadLettersAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	ds := memstore.New()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	if _, err := ds.CreateWebhookSubscription(ctx, &store.WebhookSubscription{URL: down.URL, Secret: "s", MaxAttempts: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.InsertDSLWithState(ctx, "CBU-1", `(case.create)`, store.StateCreated); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewDispatcher(ds, Config{Owner: "test", InitialBackoff: time.Nanosecond})
+	for i := 0; i < 2; i++ {
+		if processed, err := dispatcher.RunOnce(ctx); err != nil || !processed {
+			t.Fatalf("attempt %d: processed %t, err %v", i+1, processed, err)
+		}
+	}
+	if processed, _ := dispatcher.RunOnce(ctx); processed {
+		t.Fatal("a dead delivery must not be attempted again")
+	}
+	dead, _ := ds.ListWebhookDeliveries(ctx, store.DeliveryDead)
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("expected one dead delivery after 2 attempts, got %+v", dead)
+	}
+}
+
+func TestBackoffAndVerify(t *testing.T) {
+	d := NewDispatcher(nil, Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 30: 5 * time.Second} {
+		if got := d.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+
+	body := []byte(`{"event_id":"e1"}`)
+	now := time.Now().Unix()
+	signature := Sign("secret", now, body)
+	if !Verify("secret", signature, now, body, time.Minute) {
+		t.Error("expected a fresh signature to verify")
+	}
+	if Verify("other", signature, now, body, time.Minute) {
+		t.Error("signature verified with the wrong secret")
+	}
+	if Verify("secret", signature, now, []byte(`{"event_id":"e2"}`), time.Minute) {
+		t.Error("signature verified for a different body")
+	}
+	old := now - 600
+	if Verify("secret", Sign("secret", old, body), old, body, time.Minute) {
+		t.Error("signature verified outside the tolerance")
+	}
+}
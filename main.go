@@ -5,15 +5,54 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"dsl-ob-poc/internal/agent"
 	"dsl-ob-poc/internal/cli"
 	"dsl-ob-poc/internal/config"
 	"dsl-ob-poc/internal/datastore"
+	"dsl-ob-poc/internal/grammar"
+	"dsl-ob-poc/internal/statemachine"
 )
 
-// getAPIKey looks for GEMINI_API_KEY first, then falls back to GOOGLE_API_KEY
-func getAPIKey() string {
+// aiCommands lists the commands that use the LLM provider. Commands mapped to
+// true cannot run without one; the others fall back to working without AI.
+var aiCommands = map[string]bool{
+	"discover-kyc":         true,
+	"agent-transform":      true,
+	"agent-validate":       true,
+	"agent-prompt-capture": false,
+	"discover-ubo":         false,
+}
+
+// agentConfigFromEnv returns the LLM provider configuration based on environment variables.
+// LLM_PROVIDER selects gemini (default), openai or mock; LLM_MODEL overrides the model.
+func agentConfigFromEnv() agent.ProviderConfig {
+	cfg := agent.ProviderConfig{
+		Type:  agent.ProviderType(strings.ToLower(os.Getenv("LLM_PROVIDER"))),
+		Model: os.Getenv("LLM_MODEL"),
+	}
+
+	switch cfg.Type {
+	case agent.OpenAIProvider:
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		cfg.BaseURL = os.Getenv("LLM_BASE_URL")
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = os.Getenv("OPENAI_BASE_URL")
+		}
+	case agent.MockProvider:
+	default:
+		if cfg.Type == "" {
+			cfg.Type = agent.GeminiProvider
+		}
+		cfg.APIKey = geminiAPIKey()
+	}
+
+	return cfg
+}
+
+// geminiAPIKey looks for GEMINI_API_KEY first, then falls back to GOOGLE_API_KEY
+func geminiAPIKey() string {
 	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
 		return apiKey
 	}
@@ -24,6 +63,14 @@ func getAPIKey() string {
 	return ""
 }
 
+// missingProviderMessage explains which environment variables the selected provider needs
+func missingProviderMessage(cfg agent.ProviderConfig) string {
+	if cfg.Type == agent.OpenAIProvider {
+		return "Error: OPENAI_API_KEY (or LLM_BASE_URL for a local OpenAI-compatible server) is not set."
+	}
+	return "Error: Neither GEMINI_API_KEY nor GOOGLE_API_KEY environment variable is set."
+}
+
 func main() {
 	os.Exit(run())
 }
@@ -43,9 +90,49 @@ func run() int {
 		return 0
 	}
 
+	// The onboarding state machine may be replaced by a definition file
+	machine, err := statemachine.OnboardingFromEnv()
+	if err != nil {
+		log.Printf("Failed to load onboarding state machine: %v", err)
+		return 1
+	}
+	statemachine.SetOnboarding(machine)
+
+	if command == "state-diagram" {
+		if err := cli.RunStateDiagram(args); err != nil {
+			log.Printf("Command failed: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	// Formatting and linting work on files and need no data store
+	if command == "dsl-fmt" || command == "dsl-lint" {
+		var err error
+		if command == "dsl-fmt" {
+			err = cli.RunDSLFmt(args)
+		} else {
+			err = cli.RunDSLLint(context.Background(), args)
+		}
+		if err != nil {
+			log.Printf("Command failed: %v", err)
+			return 1
+		}
+		return 0
+	}
+
 	// All other commands require data store connection
 	cfg := config.GetDataStoreConfig()
 
+	// Every DSL version is parsed against the active grammar before it is stored
+	gate, closeGate, err := grammar.GateForDataStore(cfg, config.GetGrammarDomain())
+	if err != nil {
+		log.Printf("Failed to initialize grammar validation: %v", err)
+		return 1
+	}
+	defer closeGate()
+	cfg.Validator = gate
+
 	dataStore, err := datastore.NewDataStore(cfg)
 	if err != nil {
 		log.Printf("Failed to initialize data store: %v", err)
@@ -54,14 +141,40 @@ func run() int {
 	defer dataStore.Close()
 
 	// Print mode information for clarity
-	if config.IsMockMode() {
+	switch {
+	case cfg.Type == datastore.MemoryStore && cfg.SnapshotPath != "":
+		fmt.Printf("Running in MEMORY mode (snapshot: %s)\n", cfg.SnapshotPath)
+	case cfg.Type == datastore.MemoryStore:
+		fmt.Printf("Running in MEMORY mode (seed data from: %s)\n", cfg.MockDataPath)
+	case config.IsMockMode():
 		fmt.Printf("Running in MOCK mode (data from: %s)\n", cfg.MockDataPath)
-	} else {
+	default:
 		fmt.Println("Running in DATABASE mode")
 	}
 
 	ctx := context.Background()
 
+	// The LLM provider is created once, and only for commands that use it
+	var aiAgent agent.Provider
+	if required, ok := aiCommands[command]; ok {
+		agentCfg := agentConfigFromEnv()
+		var agentErr error
+		aiAgent, agentErr = agent.NewProvider(ctx, agentCfg)
+		switch {
+		case agentErr != nil && required:
+			log.Printf("Failed to initialize AI agent: %v", agentErr)
+			return 1
+		case agentErr != nil:
+			log.Printf("Warning: Failed to initialize AI agent: %v", agentErr)
+		case aiAgent == nil && required:
+			log.Println(missingProviderMessage(agentCfg))
+			return 1
+		}
+		if aiAgent != nil {
+			defer aiAgent.Close()
+		}
+	}
+
 	switch command {
 	case "init-db":
 		err = dataStore.InitDB(ctx)
@@ -87,48 +200,12 @@ func run() int {
 		err = cli.RunAddProducts(ctx, dataStore, args)
 
 	case "discover-kyc":
-		apiKey := getAPIKey()
-		aiAgent, agentErr := agent.NewAgent(ctx, apiKey)
-		if agentErr != nil {
-			log.Printf("Failed to initialize AI agent: %v", agentErr)
-			return 1
-		}
-		if aiAgent == nil {
-			log.Println("Error: Neither GEMINI_API_KEY nor GOOGLE_API_KEY environment variable is set.")
-			return 1
-		}
-		defer aiAgent.Close()
-
 		err = cli.RunDiscoverKYC(ctx, dataStore, aiAgent, args)
 
 	case "agent-transform":
-		apiKey := getAPIKey()
-		aiAgent, agentErr := agent.NewAgent(ctx, apiKey)
-		if agentErr != nil {
-			log.Printf("Failed to initialize AI agent: %v", agentErr)
-			return 1
-		}
-		if aiAgent == nil {
-			log.Println("Error: Neither GEMINI_API_KEY nor GOOGLE_API_KEY environment variable is set.")
-			return 1
-		}
-		defer aiAgent.Close()
-
 		err = cli.RunAgentTransform(ctx, dataStore, aiAgent, args)
 
 	case "agent-validate":
-		apiKey := getAPIKey()
-		aiAgent, agentErr := agent.NewAgent(ctx, apiKey)
-		if agentErr != nil {
-			log.Printf("Failed to initialize AI agent: %v", agentErr)
-			return 1
-		}
-		if aiAgent == nil {
-			log.Println("Error: Neither GEMINI_API_KEY nor GOOGLE_API_KEY environment variable is set.")
-			return 1
-		}
-		defer aiAgent.Close()
-
 		err = cli.RunAgentValidate(ctx, dataStore, aiAgent, args)
 
 	case "agent-demo":
@@ -138,11 +215,6 @@ func run() int {
 		err = cli.RunAgentTest(ctx, dataStore, args)
 
 	case "agent-prompt-capture":
-		apiKey := getAPIKey()
-		aiAgent, agentErr := agent.NewAgent(ctx, apiKey)
-		if agentErr != nil {
-			log.Printf("Failed to initialize AI agent: %v", agentErr)
-		}
 		// Allow running with or without API key for prompt capture demonstration
 		err = cli.RunAgentPromptCapture(ctx, dataStore, aiAgent, args)
 
@@ -153,11 +225,6 @@ func run() int {
 		err = cli.RunDiscoverResources(ctx, dataStore, args)
 
 	case "discover-ubo":
-		apiKey := getAPIKey()
-		aiAgent, agentErr := agent.NewAgent(ctx, apiKey)
-		if agentErr != nil {
-			log.Printf("Warning: Failed to initialize AI agent: %v", agentErr)
-		}
 		// Allow running with or without AI agent for UBO discovery
 		err = cli.RunDiscoverUBO(ctx, dataStore, aiAgent, args)
 
@@ -170,6 +237,10 @@ func run() int {
 	// NEW COMMAND
 	case "history":
 		err = cli.RunHistory(ctx, dataStore, args)
+	case "dsl-diff":
+		err = cli.RunDSLDiff(ctx, dataStore, args)
+	case "dsl-merge":
+		err = cli.RunDSLMerge(ctx, dataStore, args)
 
 	// MULTI-DOMAIN ORCHESTRATION COMMANDS
 	case "orchestration-init-db":
@@ -178,6 +249,8 @@ func run() int {
 		err = cli.RunOrchestrationCreate(ctx, dataStore, args)
 	case "orchestrate-execute":
 		err = cli.RunOrchestrationExecute(ctx, dataStore, args)
+	case "orchestrate-run":
+		err = cli.RunOrchestrationRun(ctx, dataStore, args)
 	case "orchestrate-status":
 		err = cli.RunOrchestrationStatus(ctx, dataStore, args)
 	case "orchestrate-list":
@@ -220,6 +293,17 @@ func run() int {
 	case "validate-dsl":
 		err = cli.RunValidateDSL(ctx, dataStore, args)
 
+	// INVESTOR IR PLAN EXECUTION
+	case "run-plan":
+		err = cli.RunPlan(ctx, dataStore, args)
+
+	case "compile-plan":
+		err = cli.RunCompilePlan(ctx, args)
+
+	// HTTP API
+	case "serve":
+		err = cli.RunServe(ctx, dataStore, args)
+
 	// PHASE 6 COMPILE-TIME OPTIMIZATION
 	case "optimize":
 		err = cli.RunOptimize(ctx, dataStore, args)
@@ -233,9 +317,9 @@ func run() int {
 
 	// Vector and semantic search commands
 	case "regenerate-vectors":
-		err = cli.RegenerateVectorsCommand(args)
+		err = cli.RegenerateVectorsCommand(ctx, dataStore, args)
 	case "search-attributes":
-		err = cli.SearchAttributesCommand(args)
+		err = cli.SearchAttributesCommand(ctx, dataStore, args)
 
 	// Grammar and EBNF commands
 	case "init-grammar":
@@ -254,10 +338,18 @@ func run() int {
 		err = cli.ListExecutionsCommand(args)
 	case "trigger-workflow":
 		err = cli.TriggerWorkflowCommand(args)
+	case "runtime-worker":
+		err = cli.RuntimeWorkerCommand(args)
+	case "runtime-jobs":
+		err = cli.RuntimeJobsCommand(args)
 	case "create-action":
 		err = cli.CreateActionCommand(args)
 	case "manage-credentials":
 		err = cli.ManageCredentialsCommand(args)
+	case "webhook-dispatcher":
+		err = cli.WebhookDispatcherCommand(args)
+	case "manage-webhooks":
+		err = cli.ManageWebhooksCommand(args)
 
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
@@ -286,9 +378,18 @@ func printUsage() {
 	fmt.Println("  dsl-list-cases")
 	fmt.Println("                 List all active DSL case IDs")
 	fmt.Println("\nEnvironment Variables:")
-	fmt.Println("  DSL_STORE_TYPE         Set to 'mock' for disconnected mode, 'postgresql' for database mode (default)")
+	fmt.Println("  DSL_STORE_TYPE         Set to 'mock' for disconnected mode, 'memory' for an in-memory store that keeps writes, 'postgresql' for database mode (default)")
 	fmt.Println("  DSL_MOCK_DATA_PATH     Path to mock data directory (default: data/mocks)")
 	fmt.Println("  DB_CONN_STRING         PostgreSQL connection string (required for database mode)")
+	fmt.Println("  DSL_MEMORY_SNAPSHOT    Snapshot file the memory store is restored from and saved to on exit (optional)")
+	fmt.Println("  RUNTIME_HTTP_FIXTURES  Record or replay runtime HTTP actions: 'replay', or per environment 'test=replay,staging=record'")
+	fmt.Println("  RUNTIME_HTTP_FIXTURES_DIR  Directory of recorded HTTP fixtures (default: data/fixtures)")
+	fmt.Println("  CREDENTIALS_BACKEND    Where runtime credentials are kept: postgres (default), file or env")
+	fmt.Println("  CREDENTIALS_ENCRYPTION_KEYS  Versioned master keys '1:<old>,2:<new>' (or CREDENTIALS_ENCRYPTION_KEY for one)")
+	fmt.Println("  CREDENTIALS_VAULT_FILE Vault file for the file backend (default: data/credentials.vault)")
+	fmt.Println("  DSL_SESSION_STORE      Where orchestration chat sessions are kept: memory (default), file or postgres")
+	fmt.Println("  DSL_SESSION_DIR        Session directory for the file store (default: data/sessions)")
+	fmt.Println("  DSL_STATE_MACHINE_FILE JSON onboarding state machine replacing the built-in definition (optional)")
 	fmt.Println("\nSetup Commands:")
 	fmt.Println("  init-db                      (One-time) Initializes the PostgreSQL schema and all tables.")
 	fmt.Println("  seed-catalog                 (One-time) Populates catalog tables with mock data.")
@@ -307,8 +408,15 @@ func printUsage() {
 
 	fmt.Println("\nDSL Lifecycle Management Commands:")
 	fmt.Println("  validate-dsl <file_path>     Validates a DSL file.")
+	fmt.Println("  run-plan --file=<path> --cbu=<cbu-id> [--json]")
+	fmt.Println("                               Validates and executes an investor IR plan or runbook.")
+	fmt.Println("  compile-plan --file=<path> [--output=<path>]")
+	fmt.Println("                               Compiles .dsl into IR plan JSON, or plan JSON back into DSL.")
+
+	fmt.Println("\nAPI Server Commands:")
+	fmt.Println("  serve [--addr=<host:port>]   Serves the REST API under /api/v1 (default :8080).")
 
-	fmt.Println("\nAI Agent Commands (requires GEMINI_API_KEY):")
+	fmt.Println("\nAI Agent Commands (requires GEMINI_API_KEY, or LLM_PROVIDER=openai|mock):")
 	fmt.Println("  agent-transform --cbu=<cbu-id>   AI-powered DSL transformation with natural language instructions")
 	fmt.Println("                  --instruction=<text> [--target-state=<state>] [--save]")
 	fmt.Println("  agent-validate --cbu=<cbu-id>    AI-powered DSL validation and improvement suggestions")
@@ -331,13 +439,24 @@ func printUsage() {
 	fmt.Println("  role-delete --id=<role-id>   Delete role")
 	fmt.Println("\nUtility Commands:")
 	fmt.Println("  history --cbu=<cbu-id>       Views the full, versioned DSL evolution for a case.")
+	fmt.Println("  dsl-diff --cbu=<cbu-id> [--from=<n>] [--to=<n>] [--json]")
+	fmt.Println("                               Structural diff of two DSL versions (default: latest vs previous)")
+	fmt.Println("  dsl-merge --cbu=<cbu-id> --base=<n> [--ours=<n>] --theirs=<n> [--prefer=<ours|theirs>] [--save]")
+	fmt.Println("                               Three-way merge of DSL versions (--*-file reads a side from disk)")
+	fmt.Println("  dsl-fmt [-w|--check] [file...]")
+	fmt.Println("                               Rewrites DSL in canonical layout (stdin when no files are given)")
+	fmt.Println("  dsl-lint [--json] [file...]  Reports unknown or deprecated verbs, unresolved placeholders and unused bindings")
 	fmt.Println("  export-mock-data [--dir=<path>] Exports existing database records to JSON mock files")
+	fmt.Println("  state-diagram [--format=<mermaid|dot>] [--output=<path>]")
+	fmt.Println("                               Renders the onboarding state machine as a Mermaid or Graphviz diagram")
 	fmt.Println("\nMulti-Domain Orchestration Commands:")
 	fmt.Println("  orchestration-init-db        (One-time) Initialize orchestration session tables")
 	fmt.Println("  orchestrate-create --entity-name=<name> --entity-type=<type> [--products=<list>]")
 	fmt.Println("                     Create a new multi-domain orchestrated workflow")
 	fmt.Println("  orchestrate-execute --session-id=<id> --instruction=<text>")
 	fmt.Println("                     Execute an instruction across multiple domains")
+	fmt.Println("  orchestrate-run --session-id=<id> [--timeout=<duration>]")
+	fmt.Println("                     Run the session's execution plan, domains in parallel where dependencies allow")
 	fmt.Println("  orchestrate-status --session-id=<id> [--show-dsl]")
 	fmt.Println("                     Show status of an orchestration session")
 	fmt.Println("  orchestrate-list [--metrics]")
@@ -362,10 +481,12 @@ func printUsage() {
 	fmt.Println("                     Test database-backed vocabulary validation (Phase 4 verification)")
 
 	fmt.Println("\nVector Database Commands:")
-	fmt.Println("  regenerate-vectors [--attribute-id=<id>] [--validate] [--stats]")
+	fmt.Println("  regenerate-vectors [--attribute-id=<id>] [--validate] [--stats] [--index=<path>]")
 	fmt.Println("                     Regenerate semantic vectors for dictionary attributes")
-	fmt.Println("  search-attributes --query=<text> [--limit=<n>]")
+	fmt.Println("  search-attributes --query=<text> [--limit=<n>] [--index=<path>]")
 	fmt.Println("                     Search for similar attributes using semantic vectors")
+	fmt.Println("                     The index is a local file (default data/vector_index.json), not stored")
+	fmt.Println("                     in the database; without it search rebuilds one from the dictionary")
 
 	fmt.Println("\nGrammar and EBNF Commands:")
 	fmt.Println("  init-grammar [--force]")
@@ -387,15 +508,28 @@ func printUsage() {
 	fmt.Println("  list-actions --verb=<pattern> [--environment=<env>] [--verbose]")
 	fmt.Println("                     List action definitions for specific verb patterns")
 	fmt.Println("  execute-action --action-id=<id> --cbu-id=<cbu> [--dsl-version-id=<id>] [--environment=<env>] [--async] [--verbose]")
-	fmt.Println("                     Manually execute a specific action definition")
+	fmt.Println("                     Manually execute a specific action definition (--async enqueues it for runtime-worker)")
 	fmt.Println("  list-executions --cbu-id=<cbu> [--limit=<n>] [--status=<status>] [--verbose] [--show-payload]")
 	fmt.Println("                     List action execution history and results")
-	fmt.Println("  trigger-workflow --cbu-id=<cbu> [--environment=<env>] [--dry-run] [--verbose]")
-	fmt.Println("                     Trigger actions based on current DSL state")
+	fmt.Println("  trigger-workflow --cbu-id=<cbu> [--environment=<env>] [--dry-run] [--sync] [--verbose]")
+	fmt.Println("                     Enqueue actions based on current DSL state (--sync executes them in-process)")
+	fmt.Println("  runtime-worker [--owner=<name>] [--lease=<duration>] [--poll=<duration>] [--once]")
+	fmt.Println("                     Process queued action jobs with leases, backoff retries and dead-lettering")
+	fmt.Println("  runtime-jobs [--status=<QUEUED|RUNNING|SUCCEEDED|DEAD>] [--requeue=<job-id>]")
+	fmt.Println("                     List queued action jobs, or requeue a dead-lettered job")
 	fmt.Println("  create-action --name=<name> --verb=<pattern> --endpoint=<url> [--type=<type>] [--method=<method>] [--timeout=<sec>]")
-	fmt.Println("                     [--resource-type=<type>] [--environment=<env>] [--config-file=<file>]")
+	fmt.Println("                     [--resource-type=<type>] [--environment=<env>] [--trigger=<condition>] [--compensate=<action-id>] [--config-file=<file>]")
 	fmt.Println("                     Create new action definition for runtime execution")
-	fmt.Println("  manage-credentials --action=<list|create|delete|test> [--name=<name>] [--type=<type>] [--environment=<env>]")
-	fmt.Println("                     [--api-key=<key>] [--token=<token>] [--username=<user>] [--password=<pass>] [--verbose]")
-	fmt.Println("                     Manage encrypted credentials for API authentication")
+	fmt.Println("  manage-credentials --action=<list|create|delete|test|rotate-key|audit> [--name=<name>] [--type=<type>] [--environment=<env>]")
+	fmt.Println("                     [--api-key=<key>] [--token=<token>] [--username=<user>] [--password=<pass>]")
+	fmt.Println("                     [--token-url=<url> --client-id=<id> [--client-secret=<secret>] [--scope=<scope>]] [--verbose]")
+	fmt.Println("                     [--limit=<n>]")
+	fmt.Println("                     Manage encrypted credentials for API authentication; rotate-key re-encrypts them")
+	fmt.Println("                     under the newest CREDENTIALS_ENCRYPTION_KEYS version, audit shows recent accesses")
+	fmt.Println("  webhook-dispatcher [--owner=<name>] [--lease=<duration>] [--poll=<duration>] [--timeout=<duration>] [--once]")
+	fmt.Println("                     Deliver DSL change events to webhook subscribers with HMAC signatures and backoff retries")
+	fmt.Println("  manage-webhooks --action=<list|add|remove|events|deliveries> [--url=<url>] [--secret=<secret>] [--cbu-id=<cbu>]")
+	fmt.Println("                     [--events=<DSL_VERSION_CREATED,ONBOARDING_STATE_CHANGED>] [--max-attempts=<n>] [--id=<id>]")
+	fmt.Println("                     [--status=<PENDING|DELIVERING|DELIVERED|DEAD>] [--limit=<n>]")
+	fmt.Println("                     Manage webhook subscriptions and inspect the DSL change outbox and its deliveries")
 }
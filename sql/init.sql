@@ -13,6 +13,7 @@ CREATE TABLE IF NOT EXISTS "dsl-ob-poc".dsl_ob (
     version_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     cbu_id VARCHAR(255) NOT NULL,
     dsl_text TEXT NOT NULL,
+    grammar_version VARCHAR(64), -- Grammar that validated this version (see grammar_rules)
     created_at TIMESTAMPTZ DEFAULT (now() at time zone 'utc')
 );
 
@@ -0,0 +1,10 @@
+-- Migration 005: Record which grammar validated each DSL version
+-- Every DSL write is parsed against the active domain grammar (grammar_rules)
+-- before persistence; grammar_version identifies the rule set that accepted it.
+-- Rows written before this migration keep a NULL grammar_version.
+
+ALTER TABLE "dsl-ob-poc".dsl_ob
+    ADD COLUMN IF NOT EXISTS grammar_version VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_dsl_ob_grammar_version
+ON "dsl-ob-poc".dsl_ob (grammar_version);
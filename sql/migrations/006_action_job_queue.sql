@@ -0,0 +1,34 @@
+-- Migration 006: Durable job queue for runtime action executions
+-- Triggered actions are enqueued here instead of being executed in-line;
+-- runtime-worker leases jobs, heartbeats while the HTTP call runs
+-- and either completes the job, schedules a retry (run_at) with the action's
+-- RetryConfig backoff, or parks it as DEAD once max_attempts is used up.
+-- A RUNNING job whose lease_expires_at has passed is reclaimed by the next
+-- worker, so jobs survive a worker exiting mid-attempt.
+
+CREATE TABLE IF NOT EXISTS action_jobs (
+    job_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    action_id UUID NOT NULL REFERENCES actions_registry(action_id),
+    cbu_id UUID NOT NULL REFERENCES "dsl-ob-poc".cbus(cbu_id),
+    payload JSONB NOT NULL, -- execution request and retry schedule
+    status VARCHAR(16) NOT NULL DEFAULT 'QUEUED'
+        CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'DEAD')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 1 CHECK (max_attempts >= 1),
+    run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    lease_owner TEXT,
+    lease_expires_at TIMESTAMPTZ,
+    last_error TEXT,
+    execution_id UUID REFERENCES action_executions(execution_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Workers poll for due queued jobs and for expired leases
+CREATE INDEX IF NOT EXISTS idx_action_jobs_due
+ON action_jobs(run_at) WHERE status = 'QUEUED';
+
+CREATE INDEX IF NOT EXISTS idx_action_jobs_lease
+ON action_jobs(lease_expires_at) WHERE status = 'RUNNING';
+
+CREATE INDEX IF NOT EXISTS idx_action_jobs_status ON action_jobs(status);
@@ -0,0 +1,39 @@
+-- Migration 007: Saga log for multi-action provisioning
+-- An action may name a compensating action that undoes it (e.g. closing a
+-- custody account that a later step could not finish provisioning). When
+-- the actions triggered by one DSL change run together and one fails, the
+-- completed steps are compensated in reverse order. action_sagas records
+-- the run and action_saga_steps each forward execution and its
+-- compensation, alongside action_executions.
+
+ALTER TABLE actions_registry
+    ADD COLUMN IF NOT EXISTS compensating_action_id UUID REFERENCES actions_registry(action_id);
+
+CREATE TABLE IF NOT EXISTS action_sagas (
+    saga_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cbu_id UUID NOT NULL REFERENCES "dsl-ob-poc".cbus(cbu_id),
+    dsl_version_id UUID REFERENCES "dsl-ob-poc".dsl_ob(version_id),
+    status VARCHAR(24) NOT NULL DEFAULT 'RUNNING'
+        CHECK (status IN ('RUNNING', 'COMPLETED', 'COMPENSATING', 'COMPENSATED', 'COMPENSATION_FAILED')),
+    error TEXT, -- why the saga was compensated
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_sagas_cbu ON action_sagas(cbu_id);
+CREATE INDEX IF NOT EXISTS idx_action_sagas_status ON action_sagas(status);
+
+CREATE TABLE IF NOT EXISTS action_saga_steps (
+    saga_id UUID NOT NULL REFERENCES action_sagas(saga_id) ON DELETE CASCADE,
+    step_no INTEGER NOT NULL,
+    action_id UUID NOT NULL REFERENCES actions_registry(action_id),
+    execution_id UUID REFERENCES action_executions(execution_id),
+    compensating_action_id UUID REFERENCES actions_registry(action_id),
+    compensation_execution_id UUID REFERENCES action_executions(execution_id),
+    status VARCHAR(24) NOT NULL DEFAULT 'RUNNING'
+        CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED', 'COMPENSATED', 'COMPENSATION_FAILED', 'NOT_COMPENSABLE')),
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (saga_id, step_no)
+);
@@ -0,0 +1,27 @@
+-- Migration 008: Envelope encryption, master key versions and access audit
+-- for the credentials vault
+-- Each credential is encrypted with its own data key; wrapped_key is that
+-- data key encrypted with master key key_version. Rows written before this
+-- migration have no wrapped key and are encrypted with master key version 1
+-- directly; rotating the master key re-encrypts them like any other row.
+-- credential_access_log records every read of a credential by the runtime.
+
+ALTER TABLE credentials_vault
+    ADD COLUMN IF NOT EXISTS wrapped_key BYTEA,
+    ADD COLUMN IF NOT EXISTS key_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX IF NOT EXISTS idx_credentials_key_version ON credentials_vault(key_version);
+
+CREATE TABLE IF NOT EXISTS credential_access_log (
+    access_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    credential_name VARCHAR(255) NOT NULL,
+    backend VARCHAR(20) NOT NULL, -- postgres, file, env
+    accessor TEXT NOT NULL, -- e.g. action:<id> execution:<id>
+    outcome VARCHAR(20) NOT NULL
+        CHECK (outcome IN ('GRANTED', 'NOT_FOUND', 'EXPIRED', 'ERROR')),
+    error TEXT,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_credential_access_log_name ON credential_access_log(credential_name, accessed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_credential_access_log_accessed_at ON credential_access_log(accessed_at);
@@ -0,0 +1,27 @@
+-- Migration 009: Per-endpoint rate limits and circuit breakers
+-- rate_limit and circuit_breaker configure how the runtime engine calls a
+-- resource type endpoint, e.g.
+--
+--   UPDATE resource_type_endpoints
+--   SET rate_limit = '{"requests_per_second": 5, "burst": 10}',
+--       circuit_breaker = '{"failure_threshold": 5, "open_seconds": 60, "half_open_probes": 1}'
+--   WHERE endpoint_url = 'http://localhost:8080/api/v1/custody/accounts';
+--
+-- endpoint_circuit_breakers keeps each breaker's state so that every
+-- runtime process honours an open breaker and list-executions can show it.
+
+ALTER TABLE resource_type_endpoints
+    ADD COLUMN IF NOT EXISTS rate_limit JSONB,
+    ADD COLUMN IF NOT EXISTS circuit_breaker JSONB;
+
+CREATE TABLE IF NOT EXISTS endpoint_circuit_breakers (
+    endpoint_id UUID PRIMARY KEY REFERENCES resource_type_endpoints(endpoint_id) ON DELETE CASCADE,
+    state VARCHAR(16) NOT NULL DEFAULT 'CLOSED'
+        CHECK (state IN ('CLOSED', 'OPEN', 'HALF_OPEN')),
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    opened_at TIMESTAMPTZ, -- when the breaker last opened
+    last_error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_endpoint_circuit_breakers_state ON endpoint_circuit_breakers(state);
@@ -0,0 +1,63 @@
+-- Migration 010: Transactional outbox for DSL changes and webhook delivery
+-- InsertDSLWithState / InsertDSLWithGrammar and UpdateOnboardingState write a
+-- dsl_change_events row in the same transaction as the change, and queue a
+-- webhook_deliveries row for every matching active subscription.
+-- webhook-dispatcher leases due deliveries, POSTs the event signed with the
+-- subscription's secret (HMAC-SHA256), and either marks the delivery
+-- DELIVERED, schedules a retry (next_attempt_at) with exponential backoff,
+-- or parks it as DEAD once max_attempts is used up or the subscriber rejects
+-- the event outright. A DELIVERING row whose lease has expired is reclaimed.
+
+CREATE TABLE IF NOT EXISTS "dsl-ob-poc".dsl_change_events (
+    event_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type VARCHAR(32) NOT NULL
+        CHECK (event_type IN ('DSL_VERSION_CREATED', 'ONBOARDING_STATE_CHANGED')),
+    cbu_id VARCHAR(255) NOT NULL,
+    version_id UUID NOT NULL REFERENCES "dsl-ob-poc".dsl_ob(version_id),
+    version_number INTEGER NOT NULL,
+    old_state VARCHAR(50), -- NULL for a CBU's first DSL version
+    new_state VARCHAR(50) NOT NULL,
+    changed_verbs TEXT[] NOT NULL DEFAULT '{}', -- verbs used more or fewer times than in the previous version
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dsl_change_events_cbu
+ON "dsl-ob-poc".dsl_change_events (cbu_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS "dsl-ob-poc".webhook_subscriptions (
+    subscription_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL, -- HMAC key; subscribers verify X-DSL-Signature with it
+    cbu_id VARCHAR(255), -- NULL subscribes to every CBU
+    event_types TEXT[] NOT NULL DEFAULT '{}', -- empty subscribes to every event type
+    max_attempts INTEGER NOT NULL DEFAULT 8 CHECK (max_attempts >= 1),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "dsl-ob-poc".webhook_deliveries (
+    delivery_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id UUID NOT NULL REFERENCES "dsl-ob-poc".dsl_change_events(event_id) ON DELETE CASCADE,
+    subscription_id UUID NOT NULL REFERENCES "dsl-ob-poc".webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING', 'DELIVERING', 'DELIVERED', 'DEAD')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 8 CHECK (max_attempts >= 1),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    lease_owner TEXT,
+    lease_expires_at TIMESTAMPTZ,
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, subscription_id)
+);
+
+-- The dispatcher polls for due pending deliveries and for expired leases
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+ON "dsl-ob-poc".webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_lease
+ON "dsl-ob-poc".webhook_deliveries (lease_expires_at) WHERE status = 'DELIVERING';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON "dsl-ob-poc".webhook_deliveries (status);
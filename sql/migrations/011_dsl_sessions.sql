@@ -0,0 +1,20 @@
+-- Migration 011: Persistent shared-dsl chat sessions
+-- session.PostgresStore keeps one row per session so a session survives
+-- restarts and can be resumed by any instance. Every save bumps version and
+-- is conditional on the version the saver loaded, so concurrent saves from
+-- two instances cannot overwrite each other. Rows whose last_used is older
+-- than the session TTL are removed by Manager.Cleanup.
+
+CREATE TABLE IF NOT EXISTS "dsl-ob-poc".dsl_sessions (
+    session_id TEXT PRIMARY KEY,
+    domain VARCHAR(100) NOT NULL DEFAULT '',
+    built_dsl TEXT NOT NULL DEFAULT '',
+    context JSONB NOT NULL DEFAULT '{}',
+    history JSONB NOT NULL DEFAULT '[]',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dsl_sessions_last_used
+    ON "dsl-ob-poc".dsl_sessions (last_used);
@@ -0,0 +1,14 @@
+-- Migration 012: Investor state kept by the IR plan interpreter
+-- run-plan keeps one JSON document per investor and CBU holding the
+-- investor's lifecycle status, KYC, holdings and applied idempotency keys.
+-- It lives in its own table rather than attribute_values because it is not a
+-- dictionary attribute and is not tied to a DSL version.
+
+CREATE TABLE IF NOT EXISTS "dsl-ob-poc".ir_investor_states (
+    cbu_id UUID NOT NULL REFERENCES "dsl-ob-poc".cbus(cbu_id) ON DELETE CASCADE,
+    investor_id TEXT NOT NULL,
+    state JSONB NOT NULL,
+    source JSONB NOT NULL DEFAULT '{}', -- plan, step and op that last wrote the state
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cbu_id, investor_id)
+);